@@ -0,0 +1,53 @@
+// FullAdderの真理値表
+load FullAdder.hdl,
+output-file FullAdder.out,
+compare-to FullAdder.cmp,
+output-list a%B3.1.3 b%B3.1.3 c%B3.1.3 sum%B3.1.3 carry%B3.1.3;
+
+set a 0,
+set b 0,
+set c 0,
+eval,
+output;
+
+set a 0,
+set b 0,
+set c 1,
+eval,
+output;
+
+set a 0,
+set b 1,
+set c 0,
+eval,
+output;
+
+set a 0,
+set b 1,
+set c 1,
+eval,
+output;
+
+set a 1,
+set b 0,
+set c 0,
+eval,
+output;
+
+set a 1,
+set b 0,
+set c 1,
+eval,
+output;
+
+set a 1,
+set b 1,
+set c 0,
+eval,
+output;
+
+set a 1,
+set b 1,
+set c 1,
+eval,
+output;
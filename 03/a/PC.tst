@@ -0,0 +1,122 @@
+// PCはreset、load、incの順に優先して、次のクロックの値を決める
+load PC.hdl,
+output-file PC.out,
+compare-to PC.cmp,
+output-list time%S1.4.1 in%D1.6.1 reset%B2.1.2 load%B2.1.2 inc%B2.1.2 out%D1.6.1;
+
+set in 0,
+set reset 0,
+set load 0,
+set inc 0,
+tick,
+output,
+tock,
+output;
+
+set inc 1,
+tick,
+output,
+tock,
+output;
+
+set in -32123,
+tick,
+output,
+tock,
+output;
+
+set load 1,
+tick,
+output,
+tock,
+output;
+
+set load 0,
+tick,
+output,
+tock,
+output;
+
+tick,
+output,
+tock,
+output;
+
+set in 12345,
+set load 1,
+set inc 0,
+tick,
+output,
+tock,
+output;
+
+set reset 1,
+tick,
+output,
+tock,
+output;
+
+set reset 0,
+set inc 1,
+tick,
+output,
+tock,
+output;
+
+set reset 1,
+tick,
+output,
+tock,
+output;
+
+set reset 0,
+set load 0,
+tick,
+output,
+tock,
+output;
+
+set reset 1,
+tick,
+output,
+tock,
+output;
+
+set in 0,
+set reset 0,
+set load 1,
+tick,
+output,
+tock,
+output;
+
+set load 0,
+set inc 1,
+tick,
+output,
+tock,
+output;
+
+set in 22222,
+set reset 1,
+set inc 0,
+tick,
+output,
+tock,
+output;
+
+set in 32767,
+set reset 0,
+set load 1,
+tick,
+output,
+tock,
+output;
+
+// 32767の次は-32768になる
+set load 0,
+set inc 1,
+tick,
+output,
+tock,
+output;
@@ -0,0 +1,622 @@
+// RAM64は、loadが1のときだけ次のクロックでaddressの位置に書き込む
+// どのアドレスも読み書きできることと、他のアドレスを壊さないことを確かめる
+load RAM64.hdl,
+output-file RAM64.out,
+compare-to RAM64.cmp,
+output-list time%S1.4.1 in%D1.6.1 load%B2.1.2 address%D3.2.3 out%D1.6.1;
+
+set in 0,
+set load 0,
+set address 0,
+tick,
+output,
+tock,
+output;
+
+set load 1,
+tick,
+output,
+tock,
+output;
+
+set in 11111,
+set load 0,
+tick,
+output,
+tock,
+output;
+
+set load 1,
+set address 1,
+tick,
+output,
+tock,
+output;
+
+set load 0,
+set address 0,
+tick,
+output,
+tock,
+output;
+
+set in 3333,
+set address 33,
+tick,
+output,
+tock,
+output;
+
+set load 1,
+tick,
+output,
+tock,
+output;
+
+set load 0,
+tick,
+output,
+tock,
+output;
+
+set in 7777,
+set address 63,
+tick,
+output,
+tock,
+output;
+
+set load 1,
+tick,
+output,
+tock,
+output;
+
+set load 0,
+tick,
+output,
+tock,
+output;
+
+set address 1,
+eval,
+output;
+
+set address 33,
+eval,
+output;
+
+set address 63,
+eval,
+output;
+
+set load 1,
+set in %B0101010101010101,
+set address 0,
+tick,
+tock;
+
+set address 13,
+tick,
+tock;
+
+set address 18,
+tick,
+tock;
+
+set address 31,
+tick,
+tock;
+
+set address 36,
+tick,
+tock;
+
+set address 41,
+tick,
+tock;
+
+set address 54,
+tick,
+tock;
+
+set address 59,
+tick,
+tock;
+
+set load 0,
+set address 0,
+tick,
+output,
+tock,
+output;
+
+set address 13,
+tick,
+output,
+tock,
+output;
+
+set address 18,
+tick,
+output,
+tock,
+output;
+
+set address 31,
+tick,
+output,
+tock,
+output;
+
+set address 36,
+tick,
+output,
+tock,
+output;
+
+set address 41,
+tick,
+output,
+tock,
+output;
+
+set address 54,
+tick,
+output,
+tock,
+output;
+
+set address 59,
+tick,
+output,
+tock,
+output;
+
+set load 1,
+set address 0,
+set in %B1010101010101010,
+tick,
+output,
+tock,
+output;
+
+set load 0,
+set address 0,
+eval,
+output;
+
+set load 0,
+set address 13,
+eval,
+output;
+
+set load 0,
+set address 18,
+eval,
+output;
+
+set load 0,
+set address 31,
+eval,
+output;
+
+set load 0,
+set address 36,
+eval,
+output;
+
+set load 0,
+set address 41,
+eval,
+output;
+
+set load 0,
+set address 54,
+eval,
+output;
+
+set load 0,
+set address 59,
+eval,
+output;
+
+set load 1,
+set address 0,
+set in %B0101010101010101,
+tick,
+output,
+tock,
+output;
+
+set load 1,
+set address 13,
+set in %B1010101010101010,
+tick,
+output,
+tock,
+output;
+
+set load 0,
+set address 0,
+eval,
+output;
+
+set load 0,
+set address 13,
+eval,
+output;
+
+set load 0,
+set address 18,
+eval,
+output;
+
+set load 0,
+set address 31,
+eval,
+output;
+
+set load 0,
+set address 36,
+eval,
+output;
+
+set load 0,
+set address 41,
+eval,
+output;
+
+set load 0,
+set address 54,
+eval,
+output;
+
+set load 0,
+set address 59,
+eval,
+output;
+
+set load 1,
+set address 13,
+set in %B0101010101010101,
+tick,
+output,
+tock,
+output;
+
+set load 1,
+set address 18,
+set in %B1010101010101010,
+tick,
+output,
+tock,
+output;
+
+set load 0,
+set address 0,
+eval,
+output;
+
+set load 0,
+set address 13,
+eval,
+output;
+
+set load 0,
+set address 18,
+eval,
+output;
+
+set load 0,
+set address 31,
+eval,
+output;
+
+set load 0,
+set address 36,
+eval,
+output;
+
+set load 0,
+set address 41,
+eval,
+output;
+
+set load 0,
+set address 54,
+eval,
+output;
+
+set load 0,
+set address 59,
+eval,
+output;
+
+set load 1,
+set address 18,
+set in %B0101010101010101,
+tick,
+output,
+tock,
+output;
+
+set load 1,
+set address 31,
+set in %B1010101010101010,
+tick,
+output,
+tock,
+output;
+
+set load 0,
+set address 0,
+eval,
+output;
+
+set load 0,
+set address 13,
+eval,
+output;
+
+set load 0,
+set address 18,
+eval,
+output;
+
+set load 0,
+set address 31,
+eval,
+output;
+
+set load 0,
+set address 36,
+eval,
+output;
+
+set load 0,
+set address 41,
+eval,
+output;
+
+set load 0,
+set address 54,
+eval,
+output;
+
+set load 0,
+set address 59,
+eval,
+output;
+
+set load 1,
+set address 31,
+set in %B0101010101010101,
+tick,
+output,
+tock,
+output;
+
+set load 1,
+set address 36,
+set in %B1010101010101010,
+tick,
+output,
+tock,
+output;
+
+set load 0,
+set address 0,
+eval,
+output;
+
+set load 0,
+set address 13,
+eval,
+output;
+
+set load 0,
+set address 18,
+eval,
+output;
+
+set load 0,
+set address 31,
+eval,
+output;
+
+set load 0,
+set address 36,
+eval,
+output;
+
+set load 0,
+set address 41,
+eval,
+output;
+
+set load 0,
+set address 54,
+eval,
+output;
+
+set load 0,
+set address 59,
+eval,
+output;
+
+set load 1,
+set address 36,
+set in %B0101010101010101,
+tick,
+output,
+tock,
+output;
+
+set load 1,
+set address 41,
+set in %B1010101010101010,
+tick,
+output,
+tock,
+output;
+
+set load 0,
+set address 0,
+eval,
+output;
+
+set load 0,
+set address 13,
+eval,
+output;
+
+set load 0,
+set address 18,
+eval,
+output;
+
+set load 0,
+set address 31,
+eval,
+output;
+
+set load 0,
+set address 36,
+eval,
+output;
+
+set load 0,
+set address 41,
+eval,
+output;
+
+set load 0,
+set address 54,
+eval,
+output;
+
+set load 0,
+set address 59,
+eval,
+output;
+
+set load 1,
+set address 41,
+set in %B0101010101010101,
+tick,
+output,
+tock,
+output;
+
+set load 1,
+set address 54,
+set in %B1010101010101010,
+tick,
+output,
+tock,
+output;
+
+set load 0,
+set address 0,
+eval,
+output;
+
+set load 0,
+set address 13,
+eval,
+output;
+
+set load 0,
+set address 18,
+eval,
+output;
+
+set load 0,
+set address 31,
+eval,
+output;
+
+set load 0,
+set address 36,
+eval,
+output;
+
+set load 0,
+set address 41,
+eval,
+output;
+
+set load 0,
+set address 54,
+eval,
+output;
+
+set load 0,
+set address 59,
+eval,
+output;
+
+set load 1,
+set address 54,
+set in %B0101010101010101,
+tick,
+output,
+tock,
+output;
+
+set load 1,
+set address 59,
+set in %B1010101010101010,
+tick,
+output,
+tock,
+output;
+
+set load 0,
+set address 0,
+eval,
+output;
+
+set load 0,
+set address 13,
+eval,
+output;
+
+set load 0,
+set address 18,
+eval,
+output;
+
+set load 0,
+set address 31,
+eval,
+output;
+
+set load 0,
+set address 36,
+eval,
+output;
+
+set load 0,
+set address 41,
+eval,
+output;
+
+set load 0,
+set address 54,
+eval,
+output;
+
+set load 0,
+set address 59,
+eval,
+output;
+
+set load 1,
+set address 59,
+set in %B0101010101010101,
+tick,
+output,
+tock,
+output;
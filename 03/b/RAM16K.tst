@@ -0,0 +1,622 @@
+// RAM16Kは、loadが1のときだけ次のクロックでaddressの位置に書き込む
+// どのアドレスも読み書きできることと、他のアドレスを壊さないことを確かめる
+load RAM16K.hdl,
+output-file RAM16K.out,
+compare-to RAM16K.cmp,
+output-list time%S1.4.1 in%D1.6.1 load%B2.1.2 address%D2.5.2 out%D1.6.1;
+
+set in 0,
+set load 0,
+set address 0,
+tick,
+output,
+tock,
+output;
+
+set load 1,
+tick,
+output,
+tock,
+output;
+
+set in 11111,
+set load 0,
+tick,
+output,
+tock,
+output;
+
+set load 1,
+set address 1,
+tick,
+output,
+tock,
+output;
+
+set load 0,
+set address 0,
+tick,
+output,
+tock,
+output;
+
+set in 3333,
+set address 8193,
+tick,
+output,
+tock,
+output;
+
+set load 1,
+tick,
+output,
+tock,
+output;
+
+set load 0,
+tick,
+output,
+tock,
+output;
+
+set in 7777,
+set address 16383,
+tick,
+output,
+tock,
+output;
+
+set load 1,
+tick,
+output,
+tock,
+output;
+
+set load 0,
+tick,
+output,
+tock,
+output;
+
+set address 1,
+eval,
+output;
+
+set address 8193,
+eval,
+output;
+
+set address 16383,
+eval,
+output;
+
+set load 1,
+set in %B0101010101010101,
+set address 0,
+tick,
+tock;
+
+set address 2085,
+tick,
+tock;
+
+set address 4170,
+tick,
+tock;
+
+set address 6255,
+tick,
+tock;
+
+set address 8340,
+tick,
+tock;
+
+set address 10425,
+tick,
+tock;
+
+set address 12510,
+tick,
+tock;
+
+set address 14595,
+tick,
+tock;
+
+set load 0,
+set address 0,
+tick,
+output,
+tock,
+output;
+
+set address 2085,
+tick,
+output,
+tock,
+output;
+
+set address 4170,
+tick,
+output,
+tock,
+output;
+
+set address 6255,
+tick,
+output,
+tock,
+output;
+
+set address 8340,
+tick,
+output,
+tock,
+output;
+
+set address 10425,
+tick,
+output,
+tock,
+output;
+
+set address 12510,
+tick,
+output,
+tock,
+output;
+
+set address 14595,
+tick,
+output,
+tock,
+output;
+
+set load 1,
+set address 0,
+set in %B1010101010101010,
+tick,
+output,
+tock,
+output;
+
+set load 0,
+set address 0,
+eval,
+output;
+
+set load 0,
+set address 2085,
+eval,
+output;
+
+set load 0,
+set address 4170,
+eval,
+output;
+
+set load 0,
+set address 6255,
+eval,
+output;
+
+set load 0,
+set address 8340,
+eval,
+output;
+
+set load 0,
+set address 10425,
+eval,
+output;
+
+set load 0,
+set address 12510,
+eval,
+output;
+
+set load 0,
+set address 14595,
+eval,
+output;
+
+set load 1,
+set address 0,
+set in %B0101010101010101,
+tick,
+output,
+tock,
+output;
+
+set load 1,
+set address 2085,
+set in %B1010101010101010,
+tick,
+output,
+tock,
+output;
+
+set load 0,
+set address 0,
+eval,
+output;
+
+set load 0,
+set address 2085,
+eval,
+output;
+
+set load 0,
+set address 4170,
+eval,
+output;
+
+set load 0,
+set address 6255,
+eval,
+output;
+
+set load 0,
+set address 8340,
+eval,
+output;
+
+set load 0,
+set address 10425,
+eval,
+output;
+
+set load 0,
+set address 12510,
+eval,
+output;
+
+set load 0,
+set address 14595,
+eval,
+output;
+
+set load 1,
+set address 2085,
+set in %B0101010101010101,
+tick,
+output,
+tock,
+output;
+
+set load 1,
+set address 4170,
+set in %B1010101010101010,
+tick,
+output,
+tock,
+output;
+
+set load 0,
+set address 0,
+eval,
+output;
+
+set load 0,
+set address 2085,
+eval,
+output;
+
+set load 0,
+set address 4170,
+eval,
+output;
+
+set load 0,
+set address 6255,
+eval,
+output;
+
+set load 0,
+set address 8340,
+eval,
+output;
+
+set load 0,
+set address 10425,
+eval,
+output;
+
+set load 0,
+set address 12510,
+eval,
+output;
+
+set load 0,
+set address 14595,
+eval,
+output;
+
+set load 1,
+set address 4170,
+set in %B0101010101010101,
+tick,
+output,
+tock,
+output;
+
+set load 1,
+set address 6255,
+set in %B1010101010101010,
+tick,
+output,
+tock,
+output;
+
+set load 0,
+set address 0,
+eval,
+output;
+
+set load 0,
+set address 2085,
+eval,
+output;
+
+set load 0,
+set address 4170,
+eval,
+output;
+
+set load 0,
+set address 6255,
+eval,
+output;
+
+set load 0,
+set address 8340,
+eval,
+output;
+
+set load 0,
+set address 10425,
+eval,
+output;
+
+set load 0,
+set address 12510,
+eval,
+output;
+
+set load 0,
+set address 14595,
+eval,
+output;
+
+set load 1,
+set address 6255,
+set in %B0101010101010101,
+tick,
+output,
+tock,
+output;
+
+set load 1,
+set address 8340,
+set in %B1010101010101010,
+tick,
+output,
+tock,
+output;
+
+set load 0,
+set address 0,
+eval,
+output;
+
+set load 0,
+set address 2085,
+eval,
+output;
+
+set load 0,
+set address 4170,
+eval,
+output;
+
+set load 0,
+set address 6255,
+eval,
+output;
+
+set load 0,
+set address 8340,
+eval,
+output;
+
+set load 0,
+set address 10425,
+eval,
+output;
+
+set load 0,
+set address 12510,
+eval,
+output;
+
+set load 0,
+set address 14595,
+eval,
+output;
+
+set load 1,
+set address 8340,
+set in %B0101010101010101,
+tick,
+output,
+tock,
+output;
+
+set load 1,
+set address 10425,
+set in %B1010101010101010,
+tick,
+output,
+tock,
+output;
+
+set load 0,
+set address 0,
+eval,
+output;
+
+set load 0,
+set address 2085,
+eval,
+output;
+
+set load 0,
+set address 4170,
+eval,
+output;
+
+set load 0,
+set address 6255,
+eval,
+output;
+
+set load 0,
+set address 8340,
+eval,
+output;
+
+set load 0,
+set address 10425,
+eval,
+output;
+
+set load 0,
+set address 12510,
+eval,
+output;
+
+set load 0,
+set address 14595,
+eval,
+output;
+
+set load 1,
+set address 10425,
+set in %B0101010101010101,
+tick,
+output,
+tock,
+output;
+
+set load 1,
+set address 12510,
+set in %B1010101010101010,
+tick,
+output,
+tock,
+output;
+
+set load 0,
+set address 0,
+eval,
+output;
+
+set load 0,
+set address 2085,
+eval,
+output;
+
+set load 0,
+set address 4170,
+eval,
+output;
+
+set load 0,
+set address 6255,
+eval,
+output;
+
+set load 0,
+set address 8340,
+eval,
+output;
+
+set load 0,
+set address 10425,
+eval,
+output;
+
+set load 0,
+set address 12510,
+eval,
+output;
+
+set load 0,
+set address 14595,
+eval,
+output;
+
+set load 1,
+set address 12510,
+set in %B0101010101010101,
+tick,
+output,
+tock,
+output;
+
+set load 1,
+set address 14595,
+set in %B1010101010101010,
+tick,
+output,
+tock,
+output;
+
+set load 0,
+set address 0,
+eval,
+output;
+
+set load 0,
+set address 2085,
+eval,
+output;
+
+set load 0,
+set address 4170,
+eval,
+output;
+
+set load 0,
+set address 6255,
+eval,
+output;
+
+set load 0,
+set address 8340,
+eval,
+output;
+
+set load 0,
+set address 10425,
+eval,
+output;
+
+set load 0,
+set address 12510,
+eval,
+output;
+
+set load 0,
+set address 14595,
+eval,
+output;
+
+set load 1,
+set address 14595,
+set in %B0101010101010101,
+tick,
+output,
+tock,
+output;
@@ -0,0 +1,622 @@
+// RAM4Kは、loadが1のときだけ次のクロックでaddressの位置に書き込む
+// どのアドレスも読み書きできることと、他のアドレスを壊さないことを確かめる
+load RAM4K.hdl,
+output-file RAM4K.out,
+compare-to RAM4K.cmp,
+output-list time%S1.4.1 in%D1.6.1 load%B2.1.2 address%D2.4.2 out%D1.6.1;
+
+set in 0,
+set load 0,
+set address 0,
+tick,
+output,
+tock,
+output;
+
+set load 1,
+tick,
+output,
+tock,
+output;
+
+set in 11111,
+set load 0,
+tick,
+output,
+tock,
+output;
+
+set load 1,
+set address 1,
+tick,
+output,
+tock,
+output;
+
+set load 0,
+set address 0,
+tick,
+output,
+tock,
+output;
+
+set in 3333,
+set address 2049,
+tick,
+output,
+tock,
+output;
+
+set load 1,
+tick,
+output,
+tock,
+output;
+
+set load 0,
+tick,
+output,
+tock,
+output;
+
+set in 7777,
+set address 4095,
+tick,
+output,
+tock,
+output;
+
+set load 1,
+tick,
+output,
+tock,
+output;
+
+set load 0,
+tick,
+output,
+tock,
+output;
+
+set address 1,
+eval,
+output;
+
+set address 2049,
+eval,
+output;
+
+set address 4095,
+eval,
+output;
+
+set load 1,
+set in %B0101010101010101,
+set address 0,
+tick,
+tock;
+
+set address 549,
+tick,
+tock;
+
+set address 1098,
+tick,
+tock;
+
+set address 1647,
+tick,
+tock;
+
+set address 2196,
+tick,
+tock;
+
+set address 2745,
+tick,
+tock;
+
+set address 3294,
+tick,
+tock;
+
+set address 3843,
+tick,
+tock;
+
+set load 0,
+set address 0,
+tick,
+output,
+tock,
+output;
+
+set address 549,
+tick,
+output,
+tock,
+output;
+
+set address 1098,
+tick,
+output,
+tock,
+output;
+
+set address 1647,
+tick,
+output,
+tock,
+output;
+
+set address 2196,
+tick,
+output,
+tock,
+output;
+
+set address 2745,
+tick,
+output,
+tock,
+output;
+
+set address 3294,
+tick,
+output,
+tock,
+output;
+
+set address 3843,
+tick,
+output,
+tock,
+output;
+
+set load 1,
+set address 0,
+set in %B1010101010101010,
+tick,
+output,
+tock,
+output;
+
+set load 0,
+set address 0,
+eval,
+output;
+
+set load 0,
+set address 549,
+eval,
+output;
+
+set load 0,
+set address 1098,
+eval,
+output;
+
+set load 0,
+set address 1647,
+eval,
+output;
+
+set load 0,
+set address 2196,
+eval,
+output;
+
+set load 0,
+set address 2745,
+eval,
+output;
+
+set load 0,
+set address 3294,
+eval,
+output;
+
+set load 0,
+set address 3843,
+eval,
+output;
+
+set load 1,
+set address 0,
+set in %B0101010101010101,
+tick,
+output,
+tock,
+output;
+
+set load 1,
+set address 549,
+set in %B1010101010101010,
+tick,
+output,
+tock,
+output;
+
+set load 0,
+set address 0,
+eval,
+output;
+
+set load 0,
+set address 549,
+eval,
+output;
+
+set load 0,
+set address 1098,
+eval,
+output;
+
+set load 0,
+set address 1647,
+eval,
+output;
+
+set load 0,
+set address 2196,
+eval,
+output;
+
+set load 0,
+set address 2745,
+eval,
+output;
+
+set load 0,
+set address 3294,
+eval,
+output;
+
+set load 0,
+set address 3843,
+eval,
+output;
+
+set load 1,
+set address 549,
+set in %B0101010101010101,
+tick,
+output,
+tock,
+output;
+
+set load 1,
+set address 1098,
+set in %B1010101010101010,
+tick,
+output,
+tock,
+output;
+
+set load 0,
+set address 0,
+eval,
+output;
+
+set load 0,
+set address 549,
+eval,
+output;
+
+set load 0,
+set address 1098,
+eval,
+output;
+
+set load 0,
+set address 1647,
+eval,
+output;
+
+set load 0,
+set address 2196,
+eval,
+output;
+
+set load 0,
+set address 2745,
+eval,
+output;
+
+set load 0,
+set address 3294,
+eval,
+output;
+
+set load 0,
+set address 3843,
+eval,
+output;
+
+set load 1,
+set address 1098,
+set in %B0101010101010101,
+tick,
+output,
+tock,
+output;
+
+set load 1,
+set address 1647,
+set in %B1010101010101010,
+tick,
+output,
+tock,
+output;
+
+set load 0,
+set address 0,
+eval,
+output;
+
+set load 0,
+set address 549,
+eval,
+output;
+
+set load 0,
+set address 1098,
+eval,
+output;
+
+set load 0,
+set address 1647,
+eval,
+output;
+
+set load 0,
+set address 2196,
+eval,
+output;
+
+set load 0,
+set address 2745,
+eval,
+output;
+
+set load 0,
+set address 3294,
+eval,
+output;
+
+set load 0,
+set address 3843,
+eval,
+output;
+
+set load 1,
+set address 1647,
+set in %B0101010101010101,
+tick,
+output,
+tock,
+output;
+
+set load 1,
+set address 2196,
+set in %B1010101010101010,
+tick,
+output,
+tock,
+output;
+
+set load 0,
+set address 0,
+eval,
+output;
+
+set load 0,
+set address 549,
+eval,
+output;
+
+set load 0,
+set address 1098,
+eval,
+output;
+
+set load 0,
+set address 1647,
+eval,
+output;
+
+set load 0,
+set address 2196,
+eval,
+output;
+
+set load 0,
+set address 2745,
+eval,
+output;
+
+set load 0,
+set address 3294,
+eval,
+output;
+
+set load 0,
+set address 3843,
+eval,
+output;
+
+set load 1,
+set address 2196,
+set in %B0101010101010101,
+tick,
+output,
+tock,
+output;
+
+set load 1,
+set address 2745,
+set in %B1010101010101010,
+tick,
+output,
+tock,
+output;
+
+set load 0,
+set address 0,
+eval,
+output;
+
+set load 0,
+set address 549,
+eval,
+output;
+
+set load 0,
+set address 1098,
+eval,
+output;
+
+set load 0,
+set address 1647,
+eval,
+output;
+
+set load 0,
+set address 2196,
+eval,
+output;
+
+set load 0,
+set address 2745,
+eval,
+output;
+
+set load 0,
+set address 3294,
+eval,
+output;
+
+set load 0,
+set address 3843,
+eval,
+output;
+
+set load 1,
+set address 2745,
+set in %B0101010101010101,
+tick,
+output,
+tock,
+output;
+
+set load 1,
+set address 3294,
+set in %B1010101010101010,
+tick,
+output,
+tock,
+output;
+
+set load 0,
+set address 0,
+eval,
+output;
+
+set load 0,
+set address 549,
+eval,
+output;
+
+set load 0,
+set address 1098,
+eval,
+output;
+
+set load 0,
+set address 1647,
+eval,
+output;
+
+set load 0,
+set address 2196,
+eval,
+output;
+
+set load 0,
+set address 2745,
+eval,
+output;
+
+set load 0,
+set address 3294,
+eval,
+output;
+
+set load 0,
+set address 3843,
+eval,
+output;
+
+set load 1,
+set address 3294,
+set in %B0101010101010101,
+tick,
+output,
+tock,
+output;
+
+set load 1,
+set address 3843,
+set in %B1010101010101010,
+tick,
+output,
+tock,
+output;
+
+set load 0,
+set address 0,
+eval,
+output;
+
+set load 0,
+set address 549,
+eval,
+output;
+
+set load 0,
+set address 1098,
+eval,
+output;
+
+set load 0,
+set address 1647,
+eval,
+output;
+
+set load 0,
+set address 2196,
+eval,
+output;
+
+set load 0,
+set address 2745,
+eval,
+output;
+
+set load 0,
+set address 3294,
+eval,
+output;
+
+set load 0,
+set address 3843,
+eval,
+output;
+
+set load 1,
+set address 3843,
+set in %B0101010101010101,
+tick,
+output,
+tock,
+output;
@@ -0,0 +1,622 @@
+// RAM512は、loadが1のときだけ次のクロックでaddressの位置に書き込む
+// どのアドレスも読み書きできることと、他のアドレスを壊さないことを確かめる
+load RAM512.hdl,
+output-file RAM512.out,
+compare-to RAM512.cmp,
+output-list time%S1.4.1 in%D1.6.1 load%B2.1.2 address%D2.3.2 out%D1.6.1;
+
+set in 0,
+set load 0,
+set address 0,
+tick,
+output,
+tock,
+output;
+
+set load 1,
+tick,
+output,
+tock,
+output;
+
+set in 11111,
+set load 0,
+tick,
+output,
+tock,
+output;
+
+set load 1,
+set address 1,
+tick,
+output,
+tock,
+output;
+
+set load 0,
+set address 0,
+tick,
+output,
+tock,
+output;
+
+set in 3333,
+set address 257,
+tick,
+output,
+tock,
+output;
+
+set load 1,
+tick,
+output,
+tock,
+output;
+
+set load 0,
+tick,
+output,
+tock,
+output;
+
+set in 7777,
+set address 511,
+tick,
+output,
+tock,
+output;
+
+set load 1,
+tick,
+output,
+tock,
+output;
+
+set load 0,
+tick,
+output,
+tock,
+output;
+
+set address 1,
+eval,
+output;
+
+set address 257,
+eval,
+output;
+
+set address 511,
+eval,
+output;
+
+set load 1,
+set in %B0101010101010101,
+set address 0,
+tick,
+tock;
+
+set address 101,
+tick,
+tock;
+
+set address 138,
+tick,
+tock;
+
+set address 239,
+tick,
+tock;
+
+set address 276,
+tick,
+tock;
+
+set address 377,
+tick,
+tock;
+
+set address 414,
+tick,
+tock;
+
+set address 451,
+tick,
+tock;
+
+set load 0,
+set address 0,
+tick,
+output,
+tock,
+output;
+
+set address 101,
+tick,
+output,
+tock,
+output;
+
+set address 138,
+tick,
+output,
+tock,
+output;
+
+set address 239,
+tick,
+output,
+tock,
+output;
+
+set address 276,
+tick,
+output,
+tock,
+output;
+
+set address 377,
+tick,
+output,
+tock,
+output;
+
+set address 414,
+tick,
+output,
+tock,
+output;
+
+set address 451,
+tick,
+output,
+tock,
+output;
+
+set load 1,
+set address 0,
+set in %B1010101010101010,
+tick,
+output,
+tock,
+output;
+
+set load 0,
+set address 0,
+eval,
+output;
+
+set load 0,
+set address 101,
+eval,
+output;
+
+set load 0,
+set address 138,
+eval,
+output;
+
+set load 0,
+set address 239,
+eval,
+output;
+
+set load 0,
+set address 276,
+eval,
+output;
+
+set load 0,
+set address 377,
+eval,
+output;
+
+set load 0,
+set address 414,
+eval,
+output;
+
+set load 0,
+set address 451,
+eval,
+output;
+
+set load 1,
+set address 0,
+set in %B0101010101010101,
+tick,
+output,
+tock,
+output;
+
+set load 1,
+set address 101,
+set in %B1010101010101010,
+tick,
+output,
+tock,
+output;
+
+set load 0,
+set address 0,
+eval,
+output;
+
+set load 0,
+set address 101,
+eval,
+output;
+
+set load 0,
+set address 138,
+eval,
+output;
+
+set load 0,
+set address 239,
+eval,
+output;
+
+set load 0,
+set address 276,
+eval,
+output;
+
+set load 0,
+set address 377,
+eval,
+output;
+
+set load 0,
+set address 414,
+eval,
+output;
+
+set load 0,
+set address 451,
+eval,
+output;
+
+set load 1,
+set address 101,
+set in %B0101010101010101,
+tick,
+output,
+tock,
+output;
+
+set load 1,
+set address 138,
+set in %B1010101010101010,
+tick,
+output,
+tock,
+output;
+
+set load 0,
+set address 0,
+eval,
+output;
+
+set load 0,
+set address 101,
+eval,
+output;
+
+set load 0,
+set address 138,
+eval,
+output;
+
+set load 0,
+set address 239,
+eval,
+output;
+
+set load 0,
+set address 276,
+eval,
+output;
+
+set load 0,
+set address 377,
+eval,
+output;
+
+set load 0,
+set address 414,
+eval,
+output;
+
+set load 0,
+set address 451,
+eval,
+output;
+
+set load 1,
+set address 138,
+set in %B0101010101010101,
+tick,
+output,
+tock,
+output;
+
+set load 1,
+set address 239,
+set in %B1010101010101010,
+tick,
+output,
+tock,
+output;
+
+set load 0,
+set address 0,
+eval,
+output;
+
+set load 0,
+set address 101,
+eval,
+output;
+
+set load 0,
+set address 138,
+eval,
+output;
+
+set load 0,
+set address 239,
+eval,
+output;
+
+set load 0,
+set address 276,
+eval,
+output;
+
+set load 0,
+set address 377,
+eval,
+output;
+
+set load 0,
+set address 414,
+eval,
+output;
+
+set load 0,
+set address 451,
+eval,
+output;
+
+set load 1,
+set address 239,
+set in %B0101010101010101,
+tick,
+output,
+tock,
+output;
+
+set load 1,
+set address 276,
+set in %B1010101010101010,
+tick,
+output,
+tock,
+output;
+
+set load 0,
+set address 0,
+eval,
+output;
+
+set load 0,
+set address 101,
+eval,
+output;
+
+set load 0,
+set address 138,
+eval,
+output;
+
+set load 0,
+set address 239,
+eval,
+output;
+
+set load 0,
+set address 276,
+eval,
+output;
+
+set load 0,
+set address 377,
+eval,
+output;
+
+set load 0,
+set address 414,
+eval,
+output;
+
+set load 0,
+set address 451,
+eval,
+output;
+
+set load 1,
+set address 276,
+set in %B0101010101010101,
+tick,
+output,
+tock,
+output;
+
+set load 1,
+set address 377,
+set in %B1010101010101010,
+tick,
+output,
+tock,
+output;
+
+set load 0,
+set address 0,
+eval,
+output;
+
+set load 0,
+set address 101,
+eval,
+output;
+
+set load 0,
+set address 138,
+eval,
+output;
+
+set load 0,
+set address 239,
+eval,
+output;
+
+set load 0,
+set address 276,
+eval,
+output;
+
+set load 0,
+set address 377,
+eval,
+output;
+
+set load 0,
+set address 414,
+eval,
+output;
+
+set load 0,
+set address 451,
+eval,
+output;
+
+set load 1,
+set address 377,
+set in %B0101010101010101,
+tick,
+output,
+tock,
+output;
+
+set load 1,
+set address 414,
+set in %B1010101010101010,
+tick,
+output,
+tock,
+output;
+
+set load 0,
+set address 0,
+eval,
+output;
+
+set load 0,
+set address 101,
+eval,
+output;
+
+set load 0,
+set address 138,
+eval,
+output;
+
+set load 0,
+set address 239,
+eval,
+output;
+
+set load 0,
+set address 276,
+eval,
+output;
+
+set load 0,
+set address 377,
+eval,
+output;
+
+set load 0,
+set address 414,
+eval,
+output;
+
+set load 0,
+set address 451,
+eval,
+output;
+
+set load 1,
+set address 414,
+set in %B0101010101010101,
+tick,
+output,
+tock,
+output;
+
+set load 1,
+set address 451,
+set in %B1010101010101010,
+tick,
+output,
+tock,
+output;
+
+set load 0,
+set address 0,
+eval,
+output;
+
+set load 0,
+set address 101,
+eval,
+output;
+
+set load 0,
+set address 138,
+eval,
+output;
+
+set load 0,
+set address 239,
+eval,
+output;
+
+set load 0,
+set address 276,
+eval,
+output;
+
+set load 0,
+set address 377,
+eval,
+output;
+
+set load 0,
+set address 414,
+eval,
+output;
+
+set load 0,
+set address 451,
+eval,
+output;
+
+set load 1,
+set address 451,
+set in %B0101010101010101,
+tick,
+output,
+tock,
+output;
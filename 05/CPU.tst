@@ -0,0 +1,476 @@
+// CPUの命令を1つずつ実行し、出力ピンを確かめる
+// writeMが0のときのoutMは決まらないので比較しない
+load CPU.hdl,
+output-file CPU.out,
+compare-to CPU.cmp,
+output-list time%S0.4.0 inM%D0.6.0 instruction%B0.16.0 reset%B2.1.2 outM%D1.6.0 writeM%B3.1.3 addressM%D3.5.3 pc%D0.5.0;
+
+// @12345
+set instruction %B0011000000111001,
+tick,
+output,
+tock,
+output;
+
+// D=A
+set instruction %B1110110000010000,
+tick,
+output,
+tock,
+output;
+
+// @23456
+set instruction %B0101101110100000,
+tick,
+output,
+tock,
+output;
+
+// D=A-D
+set instruction %B1110000111010000,
+tick,
+output,
+tock,
+output;
+
+// @1000
+set instruction %B0000001111101000,
+tick,
+output,
+tock,
+output;
+
+// M=D
+set instruction %B1110001100001000,
+tick,
+output,
+tock,
+output;
+
+// @1001
+set instruction %B0000001111101001,
+tick,
+output,
+tock,
+output;
+
+// MD=D-1
+set instruction %B1110001110011000,
+tick,
+output,
+tock,
+output;
+
+// @1000
+set instruction %B0000001111101000,
+tick,
+output,
+tock,
+output;
+
+// D=D-M
+set inM 11111,
+set instruction %B1111010011010000,
+tick,
+output,
+tock,
+output;
+
+// @14
+set instruction %B0000000000001110,
+tick,
+output,
+tock,
+output;
+
+// D;JLT
+set instruction %B1110001100000100,
+tick,
+output,
+tock,
+output;
+
+// @999
+set instruction %B0000001111100111,
+tick,
+output,
+tock,
+output;
+
+// A=A+1
+set instruction %B1110110111100000,
+tick,
+output,
+tock,
+output;
+
+// AM=D
+set instruction %B1110001100101000,
+tick,
+output,
+tock,
+output;
+
+// @21
+set instruction %B0000000000010101,
+tick,
+output,
+tock,
+output;
+
+// D+1;JEQ
+set instruction %B1110011111000010,
+tick,
+output,
+tock,
+output;
+
+// @2
+set instruction %B0000000000000010,
+tick,
+output,
+tock,
+output;
+
+// D=D+A
+set instruction %B1110000010010000,
+tick,
+output,
+tock,
+output;
+
+// @1000
+set instruction %B0000001111101000,
+tick,
+output,
+tock,
+output;
+
+// D=-1
+set instruction %B1110111010010000,
+tick,
+output,
+tock,
+output;
+
+// D;JGT
+set instruction %B1110001100000001,
+tick,
+output,
+tock,
+output;
+
+// @1000
+set instruction %B0000001111101000,
+tick,
+output,
+tock,
+output;
+
+// D;JEQ
+set instruction %B1110001100000010,
+tick,
+output,
+tock,
+output;
+
+// @1000
+set instruction %B0000001111101000,
+tick,
+output,
+tock,
+output;
+
+// D;JGE
+set instruction %B1110001100000011,
+tick,
+output,
+tock,
+output;
+
+// @1000
+set instruction %B0000001111101000,
+tick,
+output,
+tock,
+output;
+
+// D;JLT
+set instruction %B1110001100000100,
+tick,
+output,
+tock,
+output;
+
+// @1000
+set instruction %B0000001111101000,
+tick,
+output,
+tock,
+output;
+
+// D;JNE
+set instruction %B1110001100000101,
+tick,
+output,
+tock,
+output;
+
+// @1000
+set instruction %B0000001111101000,
+tick,
+output,
+tock,
+output;
+
+// D;JLE
+set instruction %B1110001100000110,
+tick,
+output,
+tock,
+output;
+
+// @1000
+set instruction %B0000001111101000,
+tick,
+output,
+tock,
+output;
+
+// D;JMP
+set instruction %B1110001100000111,
+tick,
+output,
+tock,
+output;
+
+// @1000
+set instruction %B0000001111101000,
+tick,
+output,
+tock,
+output;
+
+// D=0
+set instruction %B1110101010010000,
+tick,
+output,
+tock,
+output;
+
+// D;JGT
+set instruction %B1110001100000001,
+tick,
+output,
+tock,
+output;
+
+// @1000
+set instruction %B0000001111101000,
+tick,
+output,
+tock,
+output;
+
+// D;JEQ
+set instruction %B1110001100000010,
+tick,
+output,
+tock,
+output;
+
+// @1000
+set instruction %B0000001111101000,
+tick,
+output,
+tock,
+output;
+
+// D;JGE
+set instruction %B1110001100000011,
+tick,
+output,
+tock,
+output;
+
+// @1000
+set instruction %B0000001111101000,
+tick,
+output,
+tock,
+output;
+
+// D;JLT
+set instruction %B1110001100000100,
+tick,
+output,
+tock,
+output;
+
+// @1000
+set instruction %B0000001111101000,
+tick,
+output,
+tock,
+output;
+
+// D;JNE
+set instruction %B1110001100000101,
+tick,
+output,
+tock,
+output;
+
+// @1000
+set instruction %B0000001111101000,
+tick,
+output,
+tock,
+output;
+
+// D;JLE
+set instruction %B1110001100000110,
+tick,
+output,
+tock,
+output;
+
+// @1000
+set instruction %B0000001111101000,
+tick,
+output,
+tock,
+output;
+
+// D;JMP
+set instruction %B1110001100000111,
+tick,
+output,
+tock,
+output;
+
+// @1000
+set instruction %B0000001111101000,
+tick,
+output,
+tock,
+output;
+
+// D=1
+set instruction %B1110111111010000,
+tick,
+output,
+tock,
+output;
+
+// D;JGT
+set instruction %B1110001100000001,
+tick,
+output,
+tock,
+output;
+
+// @1000
+set instruction %B0000001111101000,
+tick,
+output,
+tock,
+output;
+
+// D;JEQ
+set instruction %B1110001100000010,
+tick,
+output,
+tock,
+output;
+
+// @1000
+set instruction %B0000001111101000,
+tick,
+output,
+tock,
+output;
+
+// D;JGE
+set instruction %B1110001100000011,
+tick,
+output,
+tock,
+output;
+
+// @1000
+set instruction %B0000001111101000,
+tick,
+output,
+tock,
+output;
+
+// D;JLT
+set instruction %B1110001100000100,
+tick,
+output,
+tock,
+output;
+
+// @1000
+set instruction %B0000001111101000,
+tick,
+output,
+tock,
+output;
+
+// D;JNE
+set instruction %B1110001100000101,
+tick,
+output,
+tock,
+output;
+
+// @1000
+set instruction %B0000001111101000,
+tick,
+output,
+tock,
+output;
+
+// D;JLE
+set instruction %B1110001100000110,
+tick,
+output,
+tock,
+output;
+
+// @1000
+set instruction %B0000001111101000,
+tick,
+output,
+tock,
+output;
+
+// D;JMP
+set instruction %B1110001100000111,
+tick,
+output,
+tock,
+output;
+
+// @1000
+set instruction %B0000001111101000,
+tick,
+output,
+tock,
+output;
+
+set reset 1,
+tick,
+output,
+tock,
+output;
+
+// @32767
+set instruction %B0111111111111111,
+set reset 0,
+tick,
+output,
+tock,
+output;
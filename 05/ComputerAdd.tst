@@ -0,0 +1,25 @@
+// Add.hackは2と3を足してRAM[0]に書き込む
+// resetでPCが0に戻ることも確かめる
+load Computer.hdl,
+output-file ComputerAdd.out,
+compare-to ComputerAdd.cmp,
+output-list time%S1.4.1 reset%B2.1.2 ARegister[0]%D1.7.1 pc[]%D0.5.0 RAM16K[0]%D1.7.1 RAM16K[1]%D1.7.1 RAM16K[2]%D1.7.1;
+
+ROM32K load Add.hack,
+output;
+
+repeat 6 {
+    tick, tock, output;
+}
+
+set reset 1,
+set RAM16K[0] 0,
+tick,
+tock,
+output;
+
+set reset 0,
+
+repeat 6 {
+    tick, tock, output;
+}
@@ -0,0 +1,28 @@
+// Max.hackはRAM[0]とRAM[1]の大きい方をRAM[2]に書き込む
+load Computer.hdl,
+output-file ComputerMax.out,
+compare-to ComputerMax.cmp,
+output-list time%S1.4.1 reset%B2.1.2 ARegister[0]%D1.7.1 pc[]%D0.5.0 RAM16K[0]%D1.7.1 RAM16K[1]%D1.7.1 RAM16K[2]%D1.7.1;
+
+ROM32K load Max.hack,
+set RAM16K[0] 3,
+set RAM16K[1] 5,
+output;
+
+repeat 14 {
+    tick, tock, output;
+}
+
+set reset 1,
+tick,
+tock,
+output;
+
+set reset 0,
+set RAM16K[0] 23456,
+set RAM16K[1] 12345,
+output;
+
+repeat 14 {
+    tick, tock, output;
+}
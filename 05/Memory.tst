@@ -0,0 +1,367 @@
+// Memoryのアドレス空間（RAM16K、Screen、Keyboard）を確かめる
+// Keyboardはキーを押していない状態（0）だけを確かめる
+load Memory.hdl,
+output-file Memory.out,
+compare-to Memory.cmp,
+output-list in%D1.6.1 load%B2.1.2 address%B1.15.1 out%D1.6.1;
+
+// loadが0なら書き込まない
+set in -1,
+set load 0,
+set address %X0000,
+tick,
+output,
+tock,
+output;
+
+// RAM16K
+set load 1,
+tick,
+output,
+tock,
+output;
+
+set address %X2000,
+set in 9999,
+tick,
+output,
+tock,
+output;
+
+set load 0,
+set address %X0000,
+eval,
+output;
+
+set address %X2000,
+eval,
+output;
+
+// RAM16Kに書き込んでもScreenは変わらない
+set address %X4000,
+eval,
+output;
+
+// Screen
+set in 2222,
+set load 1,
+set address %X4000,
+tick,
+output,
+tock,
+output;
+
+// Screenに書き込んでもRAM16Kは変わらない
+set load 0,
+set address %X0000,
+eval,
+output;
+
+set address %X4000,
+eval,
+output;
+
+set in 1234,
+set load 1,
+set address %X0FCF,
+tick,
+output,
+tock,
+output;
+
+set in 4321,
+set address %X4FCF,
+tick,
+output,
+tock,
+output;
+
+set in -1,
+set address %X5FFF,
+tick,
+output,
+tock,
+output;
+
+set load 0,
+set address %X0FCF,
+eval,
+output;
+
+set address %X4FCF,
+eval,
+output;
+
+set address %X5FFF,
+eval,
+output;
+
+set in 1,
+set load 1,
+set address %X0001,
+tick,
+tock;
+
+set in 100,
+set address %X4001,
+tick,
+tock;
+
+set in 2,
+set load 1,
+set address %X0002,
+tick,
+tock;
+
+set in 101,
+set address %X4002,
+tick,
+tock;
+
+set in 3,
+set load 1,
+set address %X0004,
+tick,
+tock;
+
+set in 102,
+set address %X4004,
+tick,
+tock;
+
+set in 4,
+set load 1,
+set address %X0008,
+tick,
+tock;
+
+set in 103,
+set address %X4008,
+tick,
+tock;
+
+set in 5,
+set load 1,
+set address %X0010,
+tick,
+tock;
+
+set in 104,
+set address %X4010,
+tick,
+tock;
+
+set in 6,
+set load 1,
+set address %X0020,
+tick,
+tock;
+
+set in 105,
+set address %X4020,
+tick,
+tock;
+
+set in 7,
+set load 1,
+set address %X0040,
+tick,
+tock;
+
+set in 106,
+set address %X4040,
+tick,
+tock;
+
+set in 8,
+set load 1,
+set address %X0080,
+tick,
+tock;
+
+set in 107,
+set address %X4080,
+tick,
+tock;
+
+set in 9,
+set load 1,
+set address %X0100,
+tick,
+tock;
+
+set in 108,
+set address %X4100,
+tick,
+tock;
+
+set in 10,
+set load 1,
+set address %X0200,
+tick,
+tock;
+
+set in 109,
+set address %X4200,
+tick,
+tock;
+
+set in 11,
+set load 1,
+set address %X0400,
+tick,
+tock;
+
+set in 110,
+set address %X4400,
+tick,
+tock;
+
+set in 12,
+set load 1,
+set address %X0800,
+tick,
+tock;
+
+set in 111,
+set address %X4800,
+tick,
+tock;
+
+set in 13,
+set load 1,
+set address %X1000,
+tick,
+tock;
+
+set in 112,
+set address %X5000,
+tick,
+tock;
+
+set load 0,
+set address %X0FCF,
+eval,
+output;
+
+set address %X0001,
+eval,
+output;
+
+set address %X4001,
+eval,
+output;
+
+set address %X0002,
+eval,
+output;
+
+set address %X4002,
+eval,
+output;
+
+set address %X0004,
+eval,
+output;
+
+set address %X4004,
+eval,
+output;
+
+set address %X0008,
+eval,
+output;
+
+set address %X4008,
+eval,
+output;
+
+set address %X0010,
+eval,
+output;
+
+set address %X4010,
+eval,
+output;
+
+set address %X0020,
+eval,
+output;
+
+set address %X4020,
+eval,
+output;
+
+set address %X0040,
+eval,
+output;
+
+set address %X4040,
+eval,
+output;
+
+set address %X0080,
+eval,
+output;
+
+set address %X4080,
+eval,
+output;
+
+set address %X0100,
+eval,
+output;
+
+set address %X4100,
+eval,
+output;
+
+set address %X0200,
+eval,
+output;
+
+set address %X4200,
+eval,
+output;
+
+set address %X0400,
+eval,
+output;
+
+set address %X4400,
+eval,
+output;
+
+set address %X0800,
+eval,
+output;
+
+set address %X4800,
+eval,
+output;
+
+set address %X1000,
+eval,
+output;
+
+set address %X5000,
+eval,
+output;
+
+set in 8192,
+set load 1,
+set address %X2000,
+tick,
+output,
+tock,
+output;
+
+set load 0,
+set address %X4000,
+eval,
+output;
+
+// Keyboard
+set address %X6000,
+eval,
+output;
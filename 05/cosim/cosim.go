@@ -0,0 +1,271 @@
+// ゲートレベルのComputerと、12の命令セットレベルのエミュレータで同じプログラムを実行し、
+// クロックごとにPC、A、D、writeM、addressM（書き込む場合はoutMと書き込んだメモリ）を比較するパッケージ
+//
+// ComputerのHDLは、内部ピンpc、writeM、addressM、outMを持ち、
+// ROM32K、ARegister、DRegisterを1つずつ使っている必要がある
+// RAM16KはNandまで展開すると遅いので、組み込みのチップを使う
+package cosim
+
+import (
+	"../../12/emulator"
+	"../hdl"
+	"fmt"
+	"github.com/pkg/errors"
+	"strings"
+)
+
+// 1クロックの間に比較する値
+type State struct {
+	PC       int
+	A        int
+	D        int
+	WriteM   bool
+	AddressM int
+	OutM     int // WriteMがtrueの場合だけ比較する
+}
+
+// ゲートレベルとエミュレータで値が異なったクロック
+type Divergence struct {
+	Cycle       int
+	Instruction uint16 // エミュレータが実行した命令
+	Fields      []string
+	Got         *State // ゲートレベル
+	Want        *State // エミュレータ
+}
+
+func (d *Divergence) String() string {
+	differences := []string{}
+	for _, field := range d.Fields {
+		differences = append(differences, fmt.Sprintf("%s got %s, want %s", field, d.Got.value(field), d.Want.value(field)))
+	}
+	return fmt.Sprintf("cycle %d (PC=%d, instruction=%016b): %s", d.Cycle, d.Want.PC, d.Instruction, strings.Join(differences, "; "))
+}
+
+func (s *State) value(field string) string {
+	switch field {
+	case "PC":
+		return fmt.Sprint(s.PC)
+	case "A":
+		return fmt.Sprintf("0x%04x", s.A)
+	case "D":
+		return fmt.Sprintf("0x%04x", s.D)
+	case "writeM":
+		return fmt.Sprint(s.WriteM)
+	case "addressM":
+		return fmt.Sprint(s.AddressM)
+	case "outM":
+		return fmt.Sprintf("0x%04x", s.OutM)
+	}
+	// 書き込んだメモリ（RAM[address]）の値はOutMに入れる
+	return fmt.Sprintf("0x%04x", s.OutM)
+}
+
+type Cosimulator struct {
+	sim    *hdl.Simulator
+	emu    *emulator.Computer
+	rom    *hdl.Memory
+	a      *hdl.Memory
+	d      *hdl.Memory
+	ram    *hdl.Memory
+	screen *hdl.Memory
+	Cycle  int
+}
+
+func NewCosimulator(loader *hdl.Loader, chip *hdl.Chip, program []uint16) (*Cosimulator, error) {
+	if len(program) > 1<<15 {
+		return nil, errors.New(fmt.Sprintf("program is too large: %d instructions", len(program)))
+	}
+	if err := loader.UseBuiltin("RAM16K"); err != nil {
+		return nil, err
+	}
+	sim, err := hdl.NewSimulator(loader, chip)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Cosimulator{sim: sim, emu: emulator.NewComputer(program)}
+	memories := map[string]**hdl.Memory{
+		"ROM32K":    &c.rom,
+		"ARegister": &c.a,
+		"DRegister": &c.d,
+		"RAM16K":    &c.ram,
+		"Screen":    &c.screen,
+	}
+	for name, memory := range memories {
+		found := sim.Memories(name)
+		if len(found) != 1 {
+			return nil, errors.New(fmt.Sprintf("chip %s must contain one %s, but contains %d", chip.Name, name, len(found)))
+		}
+		*memory = found[0]
+	}
+	for _, name := range []string{"pc", "writeM", "addressM", "outM"} {
+		if _, err := sim.Get(name); err != nil {
+			return nil, err
+		}
+	}
+
+	copy(c.rom.Words, program)
+	if err := sim.Set("reset", 0); err != nil {
+		return nil, err
+	}
+	sim.Eval()
+	return c, nil
+}
+
+func (c *Cosimulator) Simulator() *hdl.Simulator {
+	return c.sim
+}
+
+func (c *Cosimulator) Emulator() *emulator.Computer {
+	return c.emu
+}
+
+// 両方のRAMに値を書き込む
+func (c *Cosimulator) SetRAM(address int, value int16) error {
+	word, err := c.gateWord(address)
+	if err != nil {
+		return err
+	}
+	*word = uint16(value)
+	c.emu.RAM[address] = value
+	c.sim.Eval()
+	return nil
+}
+
+// ゲートレベルのメモリのワード
+func (c *Cosimulator) gateWord(address int) (*uint16, error) {
+	switch {
+	case address >= 0 && address < emulator.ScreenAddress:
+		return &c.ram.Words[address], nil
+	case address >= emulator.ScreenAddress && address < emulator.KeyboardAddress:
+		return &c.screen.Words[address-emulator.ScreenAddress], nil
+	}
+	return nil, errors.New(fmt.Sprintf("address %d is out of RAM and screen", address))
+}
+
+func (c *Cosimulator) RAM(address int) (int16, error) {
+	word, err := c.gateWord(address)
+	if err != nil {
+		return 0, err
+	}
+	return int16(*word), nil
+}
+
+func (c *Cosimulator) gateState() (*State, error) {
+	values := map[string]int{}
+	for _, name := range []string{"pc", "writeM", "addressM", "outM"} {
+		value, err := c.sim.Get(name)
+		if err != nil {
+			return nil, err
+		}
+		values[name] = value
+	}
+	return &State{
+		PC:       values["pc"],
+		A:        int(c.a.Words[0]),
+		D:        int(c.d.Words[0]),
+		WriteM:   values["writeM"] == 1,
+		AddressM: values["addressM"],
+		OutM:     values["outM"],
+	}, nil
+}
+
+// エミュレータが次に実行する命令から、CPUの出力を求める
+func (c *Cosimulator) emulatorState() *State {
+	state := &State{
+		PC:       c.emu.PC,
+		A:        int(uint16(c.emu.A)),
+		D:        int(uint16(c.emu.D)),
+		AddressM: int(uint16(c.emu.A) & 0x7fff),
+	}
+	instruction := c.emu.ROM[c.emu.PC]
+	if instruction&0x8008 == 0x8008 {
+		state.WriteM = true
+		y := c.emu.A
+		if instruction&0x1000 != 0 {
+			y = c.emu.RAM[state.AddressM]
+		}
+		state.OutM = int(uint16(emulator.ALU(c.emu.D, y, (instruction>>6)&0x3f)))
+	}
+	return state
+}
+
+func compareStates(got *State, want *State) []string {
+	fields := []string{}
+	if got.PC != want.PC {
+		fields = append(fields, "PC")
+	}
+	if got.A != want.A {
+		fields = append(fields, "A")
+	}
+	if got.D != want.D {
+		fields = append(fields, "D")
+	}
+	if got.WriteM != want.WriteM {
+		fields = append(fields, "writeM")
+	}
+	if got.AddressM != want.AddressM {
+		fields = append(fields, "addressM")
+	}
+	if want.WriteM && got.WriteM && got.OutM != want.OutM {
+		fields = append(fields, "outM")
+	}
+	return fields
+}
+
+// 1命令（1クロック）実行する
+// 実行前の状態と、書き込んだメモリの値が異なれば、その違いを返す
+func (c *Cosimulator) Step() (*Divergence, error) {
+	if c.emu.IsHalted() {
+		return nil, errors.New(fmt.Sprintf("PC %d is out of the program", c.emu.PC))
+	}
+	got, err := c.gateState()
+	if err != nil {
+		return nil, err
+	}
+	want := c.emulatorState()
+	instruction := c.emu.ROM[c.emu.PC]
+	if fields := compareStates(got, want); len(fields) > 0 {
+		return &Divergence{Cycle: c.Cycle, Instruction: instruction, Fields: fields, Got: got, Want: want}, nil
+	}
+
+	if err := c.emu.Step(); err != nil {
+		return nil, err
+	}
+	c.sim.Step()
+	c.Cycle++
+
+	// キーボードへの書き込みは、どちらも無視する
+	if want.WriteM && want.AddressM < emulator.KeyboardAddress {
+		word, err := c.gateWord(want.AddressM)
+		if err != nil {
+			return nil, err
+		}
+		if int16(*word) != c.emu.RAM[want.AddressM] {
+			field := fmt.Sprintf("RAM[%d]", want.AddressM)
+			return &Divergence{
+				Cycle:       c.Cycle - 1,
+				Instruction: instruction,
+				Fields:      []string{field},
+				Got:         &State{PC: want.PC, OutM: int(*word)},
+				Want:        &State{PC: want.PC, OutM: int(uint16(c.emu.RAM[want.AddressM]))},
+			}, nil
+		}
+	}
+	return nil, nil
+}
+
+// エミュレータがプログラムの外に出るか、maxCyclesクロックまで実行する
+// 最初に見つかった違いを返す
+func (c *Cosimulator) Run(maxCycles int) (*Divergence, error) {
+	for i := 0; i < maxCycles && !c.emu.IsHalted(); i++ {
+		divergence, err := c.Step()
+		if err != nil {
+			return nil, err
+		}
+		if divergence != nil {
+			return divergence, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,221 @@
+package cosim
+
+import (
+	"../../12/emulator"
+	"../hdl"
+	"github.com/google/go-cmp/cmp"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// 06のアセンブラをビルドして、プログラムを.hackファイルに変換する
+// アセンブラはカレントディレクトリに.hackファイルを書き込む
+func assemble(t *testing.T, asmFile string) []uint16 {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "cosim")
+	if err != nil {
+		t.Fatalf("failed TempDir: %+v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	assembler := filepath.Join(dir, "assembler")
+	build := exec.Command("go", "build", "-o", assembler, "./06")
+	build.Dir = "../.."
+	if output, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("failed go build: %+v\n%s", err, output)
+	}
+
+	src, err := ioutil.ReadFile(asmFile)
+	if err != nil {
+		t.Fatalf("failed ReadFile: %+v", err)
+	}
+	name := filepath.Base(asmFile)
+	if err := ioutil.WriteFile(filepath.Join(dir, name), src, 0644); err != nil {
+		t.Fatalf("failed WriteFile: %+v", err)
+	}
+	run := exec.Command(assembler, name)
+	run.Dir = dir
+	if output, err := run.CombinedOutput(); err != nil {
+		t.Fatalf("failed assembler: %+v\n%s", err, output)
+	}
+
+	computer, err := emulator.LoadHackFile(filepath.Join(dir, strings.TrimSuffix(name, ".asm")+".hack"))
+	if err != nil {
+		t.Fatalf("failed LoadHackFile: %+v", err)
+	}
+	return computer.ROM
+}
+
+// 08のVMトランスレータでvmDirの.vmファイルをアセンブリに変換し、機械語にする
+// トランスレータはディレクトリ名の.asmファイルをディレクトリの中に書き込むので、一時ディレクトリにコピーしてから変換する
+func translate(t *testing.T, vmDir string) []uint16 {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "cosim")
+	if err != nil {
+		t.Fatalf("failed TempDir: %+v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	name := filepath.Base(vmDir)
+	vmFiles, err := filepath.Glob(filepath.Join(vmDir, "*.vm"))
+	if err != nil {
+		t.Fatalf("failed Glob: %+v", err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, name), 0755); err != nil {
+		t.Fatalf("failed Mkdir: %+v", err)
+	}
+	for _, vmFile := range vmFiles {
+		src, err := ioutil.ReadFile(vmFile)
+		if err != nil {
+			t.Fatalf("failed ReadFile: %+v", err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, name, filepath.Base(vmFile)), src, 0644); err != nil {
+			t.Fatalf("failed WriteFile: %+v", err)
+		}
+	}
+
+	translator := filepath.Join(dir, "translator")
+	build := exec.Command("go", "build", "-o", translator, "./08")
+	build.Dir = "../.."
+	if output, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("failed go build: %+v\n%s", err, output)
+	}
+	run := exec.Command(translator, name)
+	run.Dir = dir
+	if output, err := run.CombinedOutput(); err != nil {
+		t.Fatalf("failed translator: %+v\n%s", err, output)
+	}
+	return assemble(t, filepath.Join(dir, name, name+".asm"))
+}
+
+func newTestCosimulator(t *testing.T, program []uint16) *Cosimulator {
+	t.Helper()
+	loader := hdl.NewLoader("../../05", "../../03/b", "../../03/a", "../../02", "../../01")
+	chip, err := loader.Load("Computer")
+	if err != nil {
+		t.Fatalf("failed Load: %+v", err)
+	}
+	c, err := NewCosimulator(loader, chip, program)
+	if err != nil {
+		t.Fatalf("failed NewCosimulator: %+v", err)
+	}
+	return c
+}
+
+func TestCosimulatorMult(t *testing.T) {
+	program := assemble(t, "../../04/mult/mult.asm")
+	cases := []struct {
+		r0, r1 int16
+		want   int16
+	}{
+		{r0: 0, r1: 0, want: 0},
+		{r0: 3, r1: 5, want: 15},
+		{r0: 17, r1: 1, want: 17},
+		{r0: 123, r1: 45, want: 5535},
+	}
+	for _, tt := range cases {
+		c := newTestCosimulator(t, program)
+		if err := c.SetRAM(0, tt.r0); err != nil {
+			t.Fatalf("failed SetRAM: %+v", err)
+		}
+		if err := c.SetRAM(1, tt.r1); err != nil {
+			t.Fatalf("failed SetRAM: %+v", err)
+		}
+
+		// 最後は無限ループなので、十分なクロック数で止める
+		divergence, err := c.Run(2000)
+		if err != nil {
+			t.Fatalf("failed Run: %+v", err)
+		}
+		if divergence != nil {
+			t.Fatalf("failed Run: %s", divergence.String())
+		}
+
+		got, err := c.RAM(2)
+		if err != nil {
+			t.Fatalf("failed RAM: %+v", err)
+		}
+		if diff := cmp.Diff(got, tt.want); diff != "" {
+			t.Errorf("failed Mult %d*%d: diff (-got +want):\n%s", tt.r0, tt.r1, diff)
+		}
+	}
+}
+
+// VMトランスレータが出力するコードは「A=D+M」「A=D-A」「AM=M-1」のように
+// Aを保存先に持つC命令を多く含むので、Aレジスタの更新も比較できる
+func TestCosimulatorFibonacciElement(t *testing.T) {
+	program := translate(t, "../../08/FunctionCalls/FibonacciElement")
+	c := newTestCosimulator(t, program)
+
+	// 最後は無限ループなので、十分なクロック数で止める
+	divergence, err := c.Run(6000)
+	if err != nil {
+		t.Fatalf("failed Run: %+v", err)
+	}
+	if divergence != nil {
+		t.Fatalf("failed Run: %s", divergence.String())
+	}
+
+	// 4番目のフィボナッチ数がスタックの先頭に積まれている
+	got := []int16{}
+	for _, address := range []int{0, 261} {
+		value, err := c.RAM(address)
+		if err != nil {
+			t.Fatalf("failed RAM: %+v", err)
+		}
+		got = append(got, value)
+	}
+	if diff := cmp.Diff(got, []int16{262, 3}); diff != "" {
+		t.Errorf("failed FibonacciElement: diff (-got +want):\n%s", diff)
+	}
+}
+
+// キーを押した状態と離した状態で、スクリーンへの書き込みも比較する
+func TestCosimulatorFill(t *testing.T) {
+	program := assemble(t, "../../04/fill/Fill.asm")
+	c := newTestCosimulator(t, program)
+	keyboard := c.Simulator().Memories("Keyboard")[0]
+
+	for _, key := range []int16{0, 65, 0} {
+		keyboard.Words[0] = uint16(key)
+		c.Emulator().SetKey(key)
+		c.Simulator().Eval()
+
+		divergence, err := c.Run(3000)
+		if err != nil {
+			t.Fatalf("failed Run: %+v", err)
+		}
+		if divergence != nil {
+			t.Fatalf("failed Run: %s", divergence.String())
+		}
+	}
+}
+
+func TestCosimulatorDivergence(t *testing.T) {
+	program := assemble(t, "../../04/mult/mult.asm")
+	c := newTestCosimulator(t, program)
+	if divergence, err := c.Run(2); err != nil || divergence != nil {
+		t.Fatalf("failed Run: %v %v", divergence, err)
+	}
+
+	// ゲートレベルのDレジスタだけを書き換える
+	d := c.Simulator().Memories("DRegister")[0]
+	d.Words[0] = 7
+	c.Simulator().Eval()
+
+	divergence, err := c.Run(10)
+	if err != nil {
+		t.Fatalf("failed Run: %+v", err)
+	}
+	if divergence == nil {
+		t.Fatalf("failed Run: divergence is expected")
+	}
+	want := "cycle 2 (PC=2, instruction=0000000000000100): D got 0x0007, want 0x0000"
+	if diff := cmp.Diff(divergence.String(), want); diff != "" {
+		t.Errorf("failed Run: diff (-got +want):\n%s", diff)
+	}
+}
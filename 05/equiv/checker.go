@@ -0,0 +1,264 @@
+// HDLのチップのシミュレーション結果を、Goで書いた参照モデルと比較するパッケージ
+//
+// 入力ピンの合計の幅が小さいチップはすべての入力の組み合わせを試し、
+// 大きいチップは境界値とランダムな値を組み合わせた入力を試す
+// 違いが見つかった場合は、違いが残る範囲で入力のビットを0に近づけて、小さな反例にして返す
+package equiv
+
+import (
+	"../hdl"
+	"fmt"
+	"github.com/pkg/errors"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+// すべての組み合わせを試す入力の幅の上限（ビット数）
+const DefaultExhaustiveBits = 20
+
+// ランダムに試す入力の数
+const DefaultRandomVectors = 10000
+
+type Options struct {
+	ExhaustiveBits int
+	RandomVectors  int
+	Seed           int64
+}
+
+func DefaultOptions() *Options {
+	return &Options{
+		ExhaustiveBits: DefaultExhaustiveBits,
+		RandomVectors:  DefaultRandomVectors,
+		Seed:           1,
+	}
+}
+
+// シミュレーションと参照モデルの出力が異なる入力
+type Mismatch struct {
+	Chip   *hdl.Chip
+	Inputs map[string]int
+	Got    map[string]int // シミュレーションの出力
+	Want   map[string]int // 参照モデルの出力
+}
+
+// 入力と出力をピンごとに書く（違う出力ピンには「!」を付ける）
+//
+//	a=0x0001 b=0x0000 sel=1: out=0x0000 (want 0x0001)!
+func (m *Mismatch) String() string {
+	inputs := []string{}
+	for _, pin := range m.Chip.Inputs {
+		inputs = append(inputs, fmt.Sprintf("%s=%s", pin.Name, formatValue(m.Inputs[pin.Name], pin.Width)))
+	}
+	outputs := []string{}
+	for _, pin := range m.Chip.Outputs {
+		got, want := m.Got[pin.Name], m.Want[pin.Name]
+		if got == want {
+			outputs = append(outputs, fmt.Sprintf("%s=%s", pin.Name, formatValue(got, pin.Width)))
+		} else {
+			outputs = append(outputs, fmt.Sprintf("%s=%s (want %s)!", pin.Name, formatValue(got, pin.Width), formatValue(want, pin.Width)))
+		}
+	}
+	return fmt.Sprintf("%s: %s: %s", m.Chip.Name, strings.Join(inputs, " "), strings.Join(outputs, " "))
+}
+
+func formatValue(value int, width int) string {
+	if width == 1 {
+		return fmt.Sprint(value)
+	}
+	return fmt.Sprintf("0x%0*x", (width+3)/4, value)
+}
+
+// 比較した結果
+type Result struct {
+	Vectors    int  // 試した入力の数
+	Exhaustive bool // すべての組み合わせを試したか
+	Mismatch   *Mismatch
+}
+
+type Checker struct {
+	sim     *hdl.Simulator
+	chip    *hdl.Chip
+	model   Model
+	options *Options
+}
+
+func NewChecker(loader *hdl.Loader, chip *hdl.Chip, model Model, options *Options) (*Checker, error) {
+	sim, err := hdl.NewSimulator(loader, chip)
+	if err != nil {
+		return nil, err
+	}
+	if options == nil {
+		options = DefaultOptions()
+	}
+	return &Checker{sim: sim, chip: chip, model: model, options: options}, nil
+}
+
+// 参照モデルがあるチップを比較する
+func Check(loader *hdl.Loader, chip *hdl.Chip, options *Options) (*Result, error) {
+	model, ok := Models[chip.Name]
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("reference model of chip %s is not found", chip.Name))
+	}
+	checker, err := NewChecker(loader, chip, model, options)
+	if err != nil {
+		return nil, err
+	}
+	return checker.Check()
+}
+
+func (c *Checker) inputBits() int {
+	bits := 0
+	for _, pin := range c.chip.Inputs {
+		bits += pin.Width
+	}
+	return bits
+}
+
+func (c *Checker) Check() (*Result, error) {
+	if c.inputBits() <= c.options.ExhaustiveBits {
+		return c.checkExhaustive()
+	}
+	return c.checkRandom()
+}
+
+// 入力ピンを連結した値を、0から順にすべて試す
+func (c *Checker) checkExhaustive() (*Result, error) {
+	result := &Result{Exhaustive: true}
+	total := 1 << uint(c.inputBits())
+	for vector := 0; vector < total; vector++ {
+		inputs := map[string]int{}
+		shift := uint(0)
+		for _, pin := range c.chip.Inputs {
+			inputs[pin.Name] = (vector >> shift) & mask(pin.Width)
+			shift += uint(pin.Width)
+		}
+
+		result.Vectors++
+		mismatch, err := c.compare(inputs)
+		if err != nil {
+			return nil, err
+		}
+		if mismatch != nil {
+			result.Mismatch = mismatch
+			return result, nil
+		}
+	}
+	return result, nil
+}
+
+// 入力ピンごとに、境界値かランダムな値を選ぶ
+func (c *Checker) checkRandom() (*Result, error) {
+	result := &Result{}
+	random := rand.New(rand.NewSource(c.options.Seed))
+	for i := 0; i < c.options.RandomVectors; i++ {
+		inputs := map[string]int{}
+		for _, pin := range c.chip.Inputs {
+			inputs[pin.Name] = randomValue(random, pin.Width)
+		}
+
+		result.Vectors++
+		mismatch, err := c.compare(inputs)
+		if err != nil {
+			return nil, err
+		}
+		if mismatch != nil {
+			shrunk, err := c.shrink(mismatch)
+			if err != nil {
+				return nil, err
+			}
+			result.Mismatch = shrunk
+			return result, nil
+		}
+	}
+	return result, nil
+}
+
+func mask(width int) int {
+	return 1<<uint(width) - 1
+}
+
+// 桁あふれや符号の境目になりやすい値
+func boundaryValues(width int) []int {
+	m := mask(width)
+	msb := 1 << uint(width-1)
+	return []int{0, 1, m, msb, msb - 1, m - 1, 0x5555 & m, 0xaaaa & m}
+}
+
+func randomValue(random *rand.Rand, width int) int {
+	switch random.Intn(4) {
+	case 0:
+		values := boundaryValues(width)
+		return values[random.Intn(len(values))]
+	case 1:
+		// 1ビットだけ立てた値
+		return 1 << uint(random.Intn(width))
+	}
+	return random.Intn(mask(width) + 1)
+}
+
+// 違いが残る間、入力のビットを上位から順に0にしていく
+func (c *Checker) shrink(mismatch *Mismatch) (*Mismatch, error) {
+	result := mismatch
+	for _, pin := range c.chip.Inputs {
+		for i := pin.Width - 1; i >= 0; i-- {
+			value := result.Inputs[pin.Name]
+			if value&(1<<uint(i)) == 0 {
+				continue
+			}
+
+			inputs := map[string]int{}
+			for name, v := range result.Inputs {
+				inputs[name] = v
+			}
+			inputs[pin.Name] = value &^ (1 << uint(i))
+			m, err := c.compare(inputs)
+			if err != nil {
+				return nil, err
+			}
+			if m != nil {
+				result = m
+			}
+		}
+	}
+	return result, nil
+}
+
+// 入力をセットして、シミュレーションと参照モデルの出力を比較する
+func (c *Checker) compare(inputs map[string]int) (*Mismatch, error) {
+	for name, value := range inputs {
+		if err := c.sim.Set(name, value); err != nil {
+			return nil, err
+		}
+	}
+	c.sim.Eval()
+
+	want := c.model(inputs)
+	got := map[string]int{}
+	different := false
+	for _, pin := range c.chip.Outputs {
+		value, err := c.sim.Get(pin.Name)
+		if err != nil {
+			return nil, err
+		}
+		got[pin.Name] = value
+		want[pin.Name] &= mask(pin.Width)
+		if value != want[pin.Name] {
+			different = true
+		}
+	}
+	if !different {
+		return nil, nil
+	}
+	return &Mismatch{Chip: c.chip, Inputs: inputs, Got: got, Want: want}, nil
+}
+
+// 参照モデルがあるチップの名前
+func ModelNames() []string {
+	result := []string{}
+	for name := range Models {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result
+}
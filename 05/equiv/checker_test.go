@@ -0,0 +1,109 @@
+package equiv
+
+import (
+	"../hdl"
+	"github.com/google/go-cmp/cmp"
+	"testing"
+)
+
+// リポジトリのチップを探すディレクトリ
+func newTestLoader() *hdl.Loader {
+	return hdl.NewLoader("../../05", "../../03/b", "../../03/a", "../../02", "../../01")
+}
+
+// 参照モデルがあるすべてのチップが、モデルと一致する
+func TestCheckModels(t *testing.T) {
+	loader := newTestLoader()
+	options := DefaultOptions()
+	options.RandomVectors = 2000
+
+	for _, name := range ModelNames() {
+		t.Run(name, func(t *testing.T) {
+			chip, err := loader.Load(name)
+			if err != nil {
+				t.Fatalf("failed Load: %+v", err)
+			}
+			result, err := Check(loader, chip, options)
+			if err != nil {
+				t.Fatalf("failed Check: %+v", err)
+			}
+			if result.Mismatch != nil {
+				t.Errorf("failed Check: %s", result.Mismatch.String())
+			}
+		})
+	}
+}
+
+func TestCheckExhaustive(t *testing.T) {
+	loader := newTestLoader()
+	cases := []struct {
+		chip string
+		want *Result
+	}{
+		{chip: "Mux", want: &Result{Vectors: 8, Exhaustive: true}},
+		{chip: "Decode", want: &Result{Vectors: 1 << 16, Exhaustive: true}},
+	}
+	for _, tt := range cases {
+		chip, err := loader.Load(tt.chip)
+		if err != nil {
+			t.Fatalf("failed Load: %+v", err)
+		}
+		got, err := Check(loader, chip, nil)
+		if err != nil {
+			t.Fatalf("failed Check: %+v", err)
+		}
+		if diff := cmp.Diff(got, tt.want); diff != "" {
+			t.Errorf("failed Check %s: diff (-got +want):\n%s", tt.chip, diff)
+		}
+	}
+}
+
+func TestCheckMismatch(t *testing.T) {
+	cases := []struct {
+		desc    string
+		src     string
+		options *Options
+		want    string
+	}{
+		{
+			desc: "すべての組み合わせで最初に違う入力",
+			src: `CHIP Xor {
+    IN a, b;
+    OUT out;
+    PARTS:
+    Or(a=a, b=b, out=out);
+}`,
+			want: "Xor: a=1 b=1: out=1 (want 0)!",
+		},
+		{
+			desc: "ランダムな入力で見つけた違いを小さくする",
+			src: `CHIP Add16 {
+    IN a[16], b[16];
+    OUT out[16];
+    PARTS:
+    Or16(a=a, b=b, out=out);
+}`,
+			options: &Options{ExhaustiveBits: 16, RandomVectors: 100, Seed: 1},
+			want:    "Add16: a=0x0001 b=0x0001: out=0x0001 (want 0x0002)!",
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.desc, func(t *testing.T) {
+			chip, err := hdl.ParseString(tt.src)
+			if err != nil {
+				t.Fatalf("failed ParseString: %+v", err)
+			}
+			result, err := Check(newTestLoader(), chip, tt.options)
+			if err != nil {
+				t.Fatalf("failed Check: %+v", err)
+			}
+			if result.Mismatch == nil {
+				t.Fatalf("failed Check: mismatch is expected")
+			}
+			if diff := cmp.Diff(result.Mismatch.String(), tt.want); diff != "" {
+				t.Errorf("failed Check: diff (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package equiv
+
+// 組み合わせ回路のチップの、Goで書いた参照モデル
+// 入力ピンの値（ピンの幅の符号なし整数）から出力ピンの値を返す
+// 出力の値はピンの幅に切り詰めてから比較するので、モデルでは切り詰めなくてよい
+type Model func(in map[string]int) map[string]int
+
+func bit(value int, i uint) int {
+	return (value >> i) & 1
+}
+
+func mux(sel int, values ...int) int {
+	return values[sel]
+}
+
+func dmux(in int, sel int, n int) []int {
+	result := make([]int, n)
+	result[sel] = in
+	return result
+}
+
+func alu(in map[string]int) (out int) {
+	x, y := in["x"], in["y"]
+	if in["zx"] == 1 {
+		x = 0
+	}
+	if in["nx"] == 1 {
+		x = ^x
+	}
+	if in["zy"] == 1 {
+		y = 0
+	}
+	if in["ny"] == 1 {
+		y = ^y
+	}
+	if in["f"] == 1 {
+		out = x + y
+	} else {
+		out = x & y
+	}
+	if in["no"] == 1 {
+		out = ^out
+	}
+	return out & 0xffff
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// リポジトリのチップの参照モデル
+var Models = map[string]Model{
+	// 01
+	"Not": func(in map[string]int) map[string]int {
+		return map[string]int{"out": 1 - in["in"]}
+	},
+	"And": func(in map[string]int) map[string]int {
+		return map[string]int{"out": in["a"] & in["b"]}
+	},
+	"And3": func(in map[string]int) map[string]int {
+		return map[string]int{"out": in["a"] & in["b"] & in["c"]}
+	},
+	"Or": func(in map[string]int) map[string]int {
+		return map[string]int{"out": in["a"] | in["b"]}
+	},
+	"Xor": func(in map[string]int) map[string]int {
+		return map[string]int{"out": in["a"] ^ in["b"]}
+	},
+	"Mux": func(in map[string]int) map[string]int {
+		return map[string]int{"out": mux(in["sel"], in["a"], in["b"])}
+	},
+	"DMux": func(in map[string]int) map[string]int {
+		out := dmux(in["in"], in["sel"], 2)
+		return map[string]int{"a": out[0], "b": out[1]}
+	},
+	"DMux4Way": func(in map[string]int) map[string]int {
+		out := dmux(in["in"], in["sel"], 4)
+		return map[string]int{"a": out[0], "b": out[1], "c": out[2], "d": out[3]}
+	},
+	"DMux8Way": func(in map[string]int) map[string]int {
+		out := dmux(in["in"], in["sel"], 8)
+		return map[string]int{"a": out[0], "b": out[1], "c": out[2], "d": out[3], "e": out[4], "f": out[5], "g": out[6], "h": out[7]}
+	},
+	"Not16": func(in map[string]int) map[string]int {
+		return map[string]int{"out": ^in["in"]}
+	},
+	"And16": func(in map[string]int) map[string]int {
+		return map[string]int{"out": in["a"] & in["b"]}
+	},
+	"Or16": func(in map[string]int) map[string]int {
+		return map[string]int{"out": in["a"] | in["b"]}
+	},
+	"Mux16": func(in map[string]int) map[string]int {
+		return map[string]int{"out": mux(in["sel"], in["a"], in["b"])}
+	},
+	"Mux4Way16": func(in map[string]int) map[string]int {
+		return map[string]int{"out": mux(in["sel"], in["a"], in["b"], in["c"], in["d"])}
+	},
+	"Mux8Way16": func(in map[string]int) map[string]int {
+		return map[string]int{"out": mux(in["sel"], in["a"], in["b"], in["c"], in["d"], in["e"], in["f"], in["g"], in["h"])}
+	},
+	"Or4Way": func(in map[string]int) map[string]int {
+		return map[string]int{"out": boolInt(in["in"] != 0)}
+	},
+	"Or8Way": func(in map[string]int) map[string]int {
+		return map[string]int{"out": boolInt(in["in"] != 0)}
+	},
+
+	// 02
+	"HalfAdder": func(in map[string]int) map[string]int {
+		sum := in["a"] + in["b"]
+		return map[string]int{"sum": bit(sum, 0), "carry": bit(sum, 1)}
+	},
+	"FullAdder": func(in map[string]int) map[string]int {
+		sum := in["a"] + in["b"] + in["c"]
+		return map[string]int{"sum": bit(sum, 0), "carry": bit(sum, 1)}
+	},
+	"Add16": func(in map[string]int) map[string]int {
+		return map[string]int{"out": in["a"] + in["b"]}
+	},
+	"Inc16": func(in map[string]int) map[string]int {
+		return map[string]int{"out": in["in"] + 1}
+	},
+	"Zero16": func(in map[string]int) map[string]int {
+		return map[string]int{"out": mux(in["ze"], in["in"], 0)}
+	},
+	"Negate16": func(in map[string]int) map[string]int {
+		return map[string]int{"out": mux(in["ne"], in["in"], ^in["in"])}
+	},
+	"Input16": func(in map[string]int) map[string]int {
+		out := mux(in["ze"], in["in"], 0)
+		return map[string]int{"out": mux(in["ne"], out, ^out)}
+	},
+	"InputFunction16": func(in map[string]int) map[string]int {
+		return map[string]int{"out": mux(in["f"], in["a"]&in["b"], in["a"]+in["b"])}
+	},
+	"IsNegative16": func(in map[string]int) map[string]int {
+		return map[string]int{"out": bit(in["in"], 15)}
+	},
+	"IsZero16": func(in map[string]int) map[string]int {
+		return map[string]int{"out": boolInt(in["in"] == 0)}
+	},
+	"ALU": func(in map[string]int) map[string]int {
+		out := alu(in)
+		return map[string]int{"out": out, "zr": boolInt(out == 0), "ng": bit(out, 15)}
+	},
+
+	// 03/a, 05
+	"Buf": func(in map[string]int) map[string]int {
+		return map[string]int{"out": in["in"]}
+	},
+	"Buf3": func(in map[string]int) map[string]int {
+		return map[string]int{"out": in["in"]}
+	},
+	"Buf15": func(in map[string]int) map[string]int {
+		return map[string]int{"out": in["in"]}
+	},
+	"Buf16": func(in map[string]int) map[string]int {
+		return map[string]int{"out": in["in"]}
+	},
+	"Buf16To15": func(in map[string]int) map[string]int {
+		return map[string]int{"out": in["in"]}
+	},
+	"Decode": func(in map[string]int) map[string]int {
+		instruction := in["in"]
+		c := bit(instruction, 15)
+		out := map[string]int{
+			"addressInstruction": 1 - c,
+			"computeInstruction": c,
+			"value":              instruction,
+		}
+		if c == 1 {
+			out["value"] = 0xffff
+		}
+		// C命令でない場合は、compは1埋め、destとjumpは0埋め
+		names := []string{"j3", "j2", "j1", "d3", "d2", "d1", "c6", "c5", "c4", "c3", "c2", "c1", "a"}
+		for i, name := range names {
+			if c == 1 {
+				out[name] = bit(instruction, uint(i))
+			} else {
+				out[name] = boolInt(i >= 6)
+			}
+		}
+		return out
+	},
+}
@@ -0,0 +1,322 @@
+package export
+
+import (
+	"../hdl"
+	"bytes"
+	"encoding/json"
+	"github.com/google/go-cmp/cmp"
+	"testing"
+)
+
+// リポジトリのチップを探すディレクトリ
+func newTestLoader() *hdl.Loader {
+	return hdl.NewLoader("../../05", "../../03/b", "../../03/a", "../../02", "../../01")
+}
+
+func parseChip(t *testing.T, src string) *hdl.Chip {
+	t.Helper()
+	chip, err := hdl.ParseString(src)
+	if err != nil {
+		t.Fatalf("failed ParseString: %+v", err)
+	}
+	return chip
+}
+
+func TestModuleVerilog(t *testing.T) {
+	cases := []struct {
+		desc    string
+		src     string
+		flatten bool
+		want    string
+	}{
+		{
+			desc: "階層を残す",
+			src: `CHIP And2 {
+    IN a[2], b[2];
+    OUT out[2];
+    PARTS:
+    And(a=a[0], b=b[0], out=out[0]);
+    And(a=a[1], b=b[1], out=out[1]);
+}`,
+			want: `module And2(
+    input [1:0] a,
+    input [1:0] b,
+    output [1:0] out
+);
+    And And_0 (.a(a[0]), .b(b[0]), .out(out[0]));
+    And And_1 (.a(a[1]), .b(b[1]), .out(out[1]));
+endmodule
+
+`,
+		},
+		{
+			desc: "予約語の内部ピンと定数",
+			src: `CHIP Keyword {
+    IN a;
+    OUT out[3];
+    PARTS:
+    Not(in=a, out=if);
+    Not(in=if, out=out[2]);
+    Not(in=true, out=out[0]);
+    Not(in=false, out=out[1]);
+}`,
+			want: `module Keyword(
+    input a,
+    output [2:0] out
+);
+    wire \if ;
+    Not Not_0 (.in(a), .out(\if ));
+    Not Not_1 (.in(\if ), .out(out[2]));
+    Not Not_2 (.in(1'b1), .out(out[0]));
+    Not Not_3 (.in(1'b0), .out(out[1]));
+endmodule
+
+`,
+		},
+		{
+			desc: "同じ信号を出力する出力ピン",
+			src: `CHIP Fanout {
+    IN in;
+    OUT out1, out2;
+    PARTS:
+    Not(in=in, out=out1, out=out2);
+}`,
+			want: `module Fanout(
+    input in,
+    output out1,
+    output out2
+);
+    assign out2 = out1;
+    Not Not_0 (.in(in), .out(out1));
+endmodule
+
+`,
+		},
+		{
+			desc:    "展開すると名前のない信号線ができる",
+			src:     `CHIP AndFlat { IN a, b; OUT out; PARTS: And(a=a, b=b, out=out); }`,
+			flatten: true,
+			want: `module AndFlat(
+    input a,
+    input b,
+    output out
+);
+    wire _n;
+    Nand Nand_0 (.a(a), .b(b), .out(_n));
+    Nand Nand_1 (.a(_n), .b(_n), .out(out));
+endmodule
+
+`,
+		},
+		{
+			desc: "順序回路にはクロックを追加する",
+			src: `CHIP Delay {
+    IN in;
+    OUT out;
+    PARTS:
+    Bit(in=in, load=true, out=out);
+}`,
+			want: `module Delay(
+    input clk,
+    input in,
+    output out
+);
+    Bit Bit_0 (.clk(clk), .in(in), .load(1'b1), .out(out));
+endmodule
+
+`,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.desc, func(t *testing.T) {
+			loader := newTestLoader()
+			chip := parseChip(t, tt.src)
+			var modules []*Module
+			if tt.flatten {
+				m, err := Flat(loader, chip)
+				if err != nil {
+					t.Fatalf("failed Flat: %+v", err)
+				}
+				modules = []*Module{m}
+			} else {
+				var err error
+				modules, err = Hierarchy(loader, chip)
+				if err != nil {
+					t.Fatalf("failed Hierarchy: %+v", err)
+				}
+			}
+
+			got := modules[len(modules)-1].Verilog()
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("failed Verilog: diff (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestHierarchy(t *testing.T) {
+	loader := newTestLoader()
+	chip, err := loader.Load("Bit")
+	if err != nil {
+		t.Fatalf("failed Load: %+v", err)
+	}
+	modules, err := Hierarchy(loader, chip)
+	if err != nil {
+		t.Fatalf("failed Hierarchy: %+v", err)
+	}
+
+	got := map[string]bool{}
+	names := []string{}
+	for _, m := range modules {
+		names = append(names, m.Name)
+		got[m.Name] = m.Clocked
+	}
+	if diff := cmp.Diff(names, []string{"Not", "And", "Or", "Mux", "Buf", "Bit"}); diff != "" {
+		t.Errorf("failed Hierarchy: diff (-got +want):\n%s", diff)
+	}
+	want := map[string]bool{"Not": false, "And": false, "Or": false, "Mux": false, "Buf": false, "Bit": true}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("failed Clocked: diff (-got +want):\n%s", diff)
+	}
+
+	var b bytes.Buffer
+	if err := WriteVerilog(&b, modules); err != nil {
+		t.Fatalf("failed WriteVerilog: %+v", err)
+	}
+	for _, name := range []string{"DFF", "Nand", "Bit"} {
+		if !bytes.Contains(b.Bytes(), []byte("module "+name+"(")) {
+			t.Errorf("failed WriteVerilog: module %s is not written", name)
+		}
+	}
+}
+
+func TestJSONNetlist(t *testing.T) {
+	loader := newTestLoader()
+	chip := parseChip(t, `CHIP Const { IN a; OUT out[2]; PARTS: Nand(a=a, b=true, out=out[1], out=out[0]); }`)
+	m, err := Flat(loader, chip)
+	if err != nil {
+		t.Fatalf("failed Flat: %+v", err)
+	}
+
+	data, err := NewJSONNetlist([]*Module{m}).Marshal()
+	if err != nil {
+		t.Fatalf("failed Marshal: %+v", err)
+	}
+	got := map[string]interface{}{}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("failed Unmarshal: %+v", err)
+	}
+
+	module := got["modules"].(map[string]interface{})["Const"].(map[string]interface{})
+	cell := module["cells"].(map[string]interface{})["Nand_0"].(map[string]interface{})
+	a := m.FindPort("a").Bits[0]
+	out := m.FindPort("out").Bits[0]
+	want := map[string]interface{}{
+		"type":            "Nand",
+		"port_directions": map[string]interface{}{"a": "input", "b": "input", "out": "output"},
+		"connections": map[string]interface{}{
+			"a":   []interface{}{float64(a)},
+			"b":   []interface{}{"1"},
+			"out": []interface{}{float64(out)},
+		},
+	}
+	if diff := cmp.Diff(cell, want); diff != "" {
+		t.Errorf("failed JSON: diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestReports(t *testing.T) {
+	loader := newTestLoader()
+	chip, err := loader.Load("Bit")
+	if err != nil {
+		t.Fatalf("failed Load: %+v", err)
+	}
+	reports, err := Reports(loader, chip)
+	if err != nil {
+		t.Fatalf("failed Reports: %+v", err)
+	}
+
+	type summary struct {
+		Name  string
+		Nand  int
+		DFF   int
+		Depth int
+	}
+	got := []summary{}
+	for _, r := range reports {
+		got = append(got, summary{Name: r.Name, Nand: r.Nand(), DFF: r.DFF(), Depth: r.Depth})
+	}
+	want := []summary{
+		{Name: "Not", Nand: 1, DFF: 0, Depth: 1},
+		{Name: "And", Nand: 2, DFF: 0, Depth: 2},
+		{Name: "Or", Nand: 5, DFF: 0, Depth: 4},
+		{Name: "Mux", Nand: 10, DFF: 0, Depth: 7},
+		{Name: "Buf", Nand: 2, DFF: 0, Depth: 2},
+		// DFFの出力からMuxを通ってDFFの入力までと、Bufを通って出力まで
+		{Name: "Bit", Nand: 12, DFF: 1, Depth: 7},
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("failed Reports: diff (-got +want):\n%s", diff)
+	}
+}
+
+// Computerも組み込みのチップまで展開せずに、パーツのレポートを組み合わせて書き出せる
+func TestExportComputer(t *testing.T) {
+	loader := newTestLoader()
+	chip, err := loader.Load("Computer")
+	if err != nil {
+		t.Fatalf("failed Load: %+v", err)
+	}
+
+	modules, err := Hierarchy(loader, chip)
+	if err != nil {
+		t.Fatalf("failed Hierarchy: %+v", err)
+	}
+	var b bytes.Buffer
+	if err := WriteVerilog(&b, modules); err != nil {
+		t.Fatalf("failed WriteVerilog: %+v", err)
+	}
+	for _, name := range []string{"Computer", "CPU", "Memory", "RAM16K", "ROM32K"} {
+		if !bytes.Contains(b.Bytes(), []byte("module "+name+"(")) {
+			t.Errorf("failed WriteVerilog: module %s is not written", name)
+		}
+	}
+	if _, err := NewJSONNetlist(modules).Marshal(); err != nil {
+		t.Fatalf("failed Marshal: %+v", err)
+	}
+
+	reports, err := Reports(loader, chip)
+	if err != nil {
+		t.Fatalf("failed Reports: %+v", err)
+	}
+	got := reports[len(reports)-1]
+	want := &Report{
+		Name: "Computer",
+		Cells: map[string]int{
+			"Nand":      5225790,
+			"DFF":       262160, // RAM16KとPC
+			"ARegister": 1,
+			"DRegister": 1,
+			"ROM32K":    1,
+			"Screen":    1,
+			"Keyboard":  1,
+		},
+		Depth: 410,
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("failed Reports: diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestReportsLoop(t *testing.T) {
+	loader := newTestLoader()
+	chip := parseChip(t, `CHIP Loop { IN a; OUT out; PARTS: Nand(a=a, b=x, out=x); Nand(a=x, b=x, out=out); }`)
+	_, err := Reports(loader, chip)
+	if err == nil {
+		t.Fatalf("failed Reports: error is expected")
+	}
+	if diff := cmp.Diff(err.Error(), "Loop: combinational loop is detected"); diff != "" {
+		t.Errorf("failed Reports: diff (-got +want):\n%s", diff)
+	}
+}
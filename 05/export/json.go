@@ -0,0 +1,93 @@
+package export
+
+import (
+	"encoding/json"
+)
+
+// Yosysの「write_json」と同じ形式のネットリスト
+// ビットは2以上の番号で、定数は"0"と"1"の文字列で表す
+type JSONNetlist struct {
+	Creator string                 `json:"creator"`
+	Modules map[string]*JSONModule `json:"modules"`
+}
+
+type JSONModule struct {
+	Ports    map[string]*JSONPort    `json:"ports"`
+	Cells    map[string]*JSONCell    `json:"cells"`
+	Netnames map[string]*JSONNetname `json:"netnames"`
+}
+
+type JSONPort struct {
+	Direction string        `json:"direction"`
+	Bits      []interface{} `json:"bits"`
+}
+
+type JSONCell struct {
+	Type           string                   `json:"type"`
+	PortDirections map[string]string        `json:"port_directions"`
+	Connections    map[string][]interface{} `json:"connections"`
+}
+
+type JSONNetname struct {
+	HideName int           `json:"hide_name"`
+	Bits     []interface{} `json:"bits"`
+}
+
+func NewJSONNetlist(modules []*Module) *JSONNetlist {
+	result := &JSONNetlist{Creator: "hdlexport", Modules: map[string]*JSONModule{}}
+	for _, m := range modules {
+		result.Modules[m.Name] = m.JSON()
+	}
+	return result
+}
+
+func (n *JSONNetlist) Marshal() ([]byte, error) {
+	return json.MarshalIndent(n, "", "  ")
+}
+
+// 定数の0番と1番を避けるため、ビットの番号は2から始める
+func jsonBits(bits []int) []interface{} {
+	result := make([]interface{}, len(bits))
+	for i, bit := range bits {
+		switch bit {
+		case bitFalse:
+			result[i] = "0"
+		case bitTrue:
+			result[i] = "1"
+		default:
+			result[i] = bit
+		}
+	}
+	return result
+}
+
+func direction(input bool) string {
+	if input {
+		return "input"
+	}
+	return "output"
+}
+
+func (m *Module) JSON() *JSONModule {
+	result := &JSONModule{
+		Ports:    map[string]*JSONPort{},
+		Cells:    map[string]*JSONCell{},
+		Netnames: map[string]*JSONNetname{},
+	}
+	for _, port := range m.Ports {
+		result.Ports[port.Name] = &JSONPort{Direction: direction(port.Input), Bits: jsonBits(port.Bits)}
+		result.Netnames[port.Name] = &JSONNetname{Bits: jsonBits(port.Bits)}
+	}
+	for _, wire := range m.Wires {
+		result.Netnames[wire.Name] = &JSONNetname{Bits: jsonBits(wire.Bits)}
+	}
+	for _, cell := range m.Cells {
+		c := &JSONCell{Type: cell.Type, PortDirections: map[string]string{}, Connections: map[string][]interface{}{}}
+		for _, conn := range cell.Connections {
+			c.PortDirections[conn.Pin] = direction(conn.Input)
+			c.Connections[conn.Pin] = jsonBits(conn.Bits)
+		}
+		result.Cells[cell.Name] = c
+	}
+	return result
+}
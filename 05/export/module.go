@@ -0,0 +1,233 @@
+// HDLのチップを、FPGAの開発環境で扱える形式に書き出すパッケージ
+//   - 合成できるVerilogのモジュール
+//   - Yosysと同じ形式のJSONのネットリスト
+//   - チップごとのゲート数とクリティカルパスの段数のレポート
+//
+// パーツの階層をそのまま残すか、NandとDFFまで展開するかを選べる
+package export
+
+import (
+	"../hdl"
+	"fmt"
+	"github.com/pkg/errors"
+	"sort"
+)
+
+// 0番と1番のビットは定数
+const (
+	bitFalse = 0
+	bitTrue  = 1
+)
+
+// 書き出すモジュール
+// 信号はビット単位の番号で表し、同じ番号のビットは同じ信号線になる
+type Module struct {
+	Name    string
+	Ports   []*Signal // 入力、出力の順（順序回路の場合は先頭にclk）
+	Wires   []*Signal // 内部ピン
+	Cells   []*Cell
+	Clocked bool
+	Bits    int // ビットの数（定数を含む）
+}
+
+type Signal struct {
+	Name   string
+	Input  bool
+	Bits   []int // 下位ビットから順に並べる
+	Hidden bool  // 他の信号と同じビットで、宣言しなくてよい内部ピン
+}
+
+type Cell struct {
+	Name        string
+	Type        string
+	Connections []*Connection
+}
+
+type Connection struct {
+	Pin   string
+	Input bool
+	Bits  []int
+}
+
+func (m *Module) FindPort(name string) *Signal {
+	for _, port := range m.Ports {
+		if port.Name == name {
+			return port
+		}
+	}
+	return nil
+}
+
+// パーツの階層を残して書き出すモジュールの一覧
+// 最上位のチップから使っているチップをたどり、組み込みのチップは含めない
+func Hierarchy(loader *hdl.Loader, chip *hdl.Chip) ([]*Module, error) {
+	exporter := newExporter(loader)
+	if err := exporter.collect(chip); err != nil {
+		return nil, err
+	}
+
+	result := []*Module{}
+	for _, c := range exporter.order {
+		netlist, err := hdl.Elaborate(loader, c)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, exporter.module(netlist, true))
+	}
+	return result, nil
+}
+
+// NandとDFFなどの組み込みのチップまで展開した1つのモジュール
+func Flat(loader *hdl.Loader, chip *hdl.Chip) (*Module, error) {
+	exporter := newExporter(loader)
+	netlist, err := hdl.Flatten(loader, chip)
+	if err != nil {
+		return nil, err
+	}
+	return exporter.module(netlist, false), nil
+}
+
+type exporter struct {
+	loader  *hdl.Loader
+	order   []*hdl.Chip     // パーツが先になるように並べたチップ
+	clocked map[string]bool // チップが順序回路か
+}
+
+func newExporter(loader *hdl.Loader) *exporter {
+	return &exporter{
+		loader:  loader,
+		order:   []*hdl.Chip{},
+		clocked: map[string]bool{},
+	}
+}
+
+// 組み込みのチップは、CLOCKED宣言があれば順序回路
+func (e *exporter) isClocked(chip *hdl.Chip) (bool, error) {
+	if clocked, ok := e.clocked[chip.Name]; ok {
+		return clocked, nil
+	}
+	if chip.Builtin {
+		e.clocked[chip.Name] = len(chip.Clocked) > 0
+		return e.clocked[chip.Name], nil
+	}
+	if err := e.collect(chip); err != nil {
+		return false, err
+	}
+	return e.clocked[chip.Name], nil
+}
+
+// 使っているチップを深さ優先でたどる
+func (e *exporter) collect(chip *hdl.Chip) error {
+	if _, ok := e.clocked[chip.Name]; ok {
+		return nil
+	}
+	e.clocked[chip.Name] = false
+
+	clocked := false
+	for _, part := range chip.Parts {
+		partChip, err := e.loader.Load(part.Name)
+		if err != nil {
+			return errors.WithMessage(err, fmt.Sprintf("%s line %d", chip.Name, part.Line))
+		}
+		partClocked, err := e.isClocked(partChip)
+		if err != nil {
+			return err
+		}
+		clocked = clocked || partClocked
+	}
+	e.clocked[chip.Name] = clocked
+	e.order = append(e.order, chip)
+	return nil
+}
+
+func (e *exporter) module(netlist *hdl.Netlist, hierarchy bool) *Module {
+	chip := netlist.Chip
+	m := &Module{
+		Name:  chip.Name,
+		Ports: []*Signal{},
+		Wires: []*Signal{},
+		Cells: []*Cell{},
+		Bits:  netlist.NetCount,
+	}
+
+	// 順序回路のセルにつなぐクロック
+	clk := -1
+	for _, instance := range netlist.Instances {
+		if clocked, _ := e.isClocked(instance.Chip); clocked {
+			m.Clocked = true
+		}
+	}
+	if m.Clocked {
+		clk = m.Bits
+		m.Bits++
+		m.Ports = append(m.Ports, &Signal{Name: "clk", Input: true, Bits: []int{clk}})
+	}
+
+	for _, pin := range chip.Inputs {
+		m.Ports = append(m.Ports, &Signal{Name: pin.Name, Input: true, Bits: netlist.Pins[pin.Name]})
+	}
+	for _, pin := range chip.Outputs {
+		m.Ports = append(m.Ports, &Signal{Name: pin.Name, Input: false, Bits: netlist.Pins[pin.Name]})
+	}
+
+	// 内部ピンは宣言順に並べる
+	names := []string{}
+	for name := range netlist.Internals {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return internalLine(chip, names[i]) < internalLine(chip, names[j]) || (internalLine(chip, names[i]) == internalLine(chip, names[j]) && names[i] < names[j])
+	})
+	for _, name := range names {
+		m.Wires = append(m.Wires, &Signal{Name: name, Bits: netlist.Internals[name]})
+	}
+	hideAliases(m)
+
+	for _, instance := range netlist.Instances {
+		cell := &Cell{Name: instance.Name, Type: instance.Chip.Name, Connections: []*Connection{}}
+		if clocked, _ := e.isClocked(instance.Chip); clocked {
+			cell.Connections = append(cell.Connections, &Connection{Pin: "clk", Input: true, Bits: []int{clk}})
+		}
+		for _, pin := range instance.Chip.Inputs {
+			cell.Connections = append(cell.Connections, &Connection{Pin: pin.Name, Input: true, Bits: instance.Pins[pin.Name]})
+		}
+		for _, pin := range instance.Chip.Outputs {
+			cell.Connections = append(cell.Connections, &Connection{Pin: pin.Name, Input: false, Bits: instance.Pins[pin.Name]})
+		}
+		m.Cells = append(m.Cells, cell)
+	}
+	return m
+}
+
+// 内部ピンを最初に駆動するパーツの行
+func internalLine(chip *hdl.Chip, name string) int {
+	for _, part := range chip.Parts {
+		for _, conn := range part.Connections {
+			if conn.External.Name == name {
+				return part.Line
+			}
+		}
+	}
+	return 0
+}
+
+// 入出力ピンや先に宣言した内部ピンとビットがすべて同じ内部ピンは宣言しない
+func hideAliases(m *Module) {
+	named := map[int]bool{}
+	for _, port := range m.Ports {
+		for _, bit := range port.Bits {
+			named[bit] = true
+		}
+	}
+	for _, wire := range m.Wires {
+		wire.Hidden = true
+		for _, bit := range wire.Bits {
+			if !named[bit] {
+				wire.Hidden = false
+			}
+		}
+		for _, bit := range wire.Bits {
+			named[bit] = true
+		}
+	}
+}
@@ -0,0 +1,320 @@
+package export
+
+import (
+	"../hdl"
+	"fmt"
+	"github.com/pkg/errors"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// チップの規模と速さの目安
+// 組み込みのチップまで展開して数え、段数はNandを通る数で数える
+type Report struct {
+	Name  string
+	Cells map[string]int // 組み込みのチップごとの数
+	Depth int            // 入力ピンかDFFの出力から、出力ピンかDFFの入力までの最大の段数
+}
+
+func (r *Report) Nand() int {
+	return r.Cells["Nand"]
+}
+
+func (r *Report) DFF() int {
+	return r.Cells["DFF"]
+}
+
+// NandとDFF以外の組み込みのチップの数
+func (r *Report) Others() int {
+	result := 0
+	for name, count := range r.Cells {
+		if name != "Nand" && name != "DFF" {
+			result += count
+		}
+	}
+	return result
+}
+
+func NewReport(loader *hdl.Loader, chip *hdl.Chip) (*Report, error) {
+	return newReporter(loader).report(chip)
+}
+
+// 最上位のチップと、使っているすべてのチップのレポート（パーツが先）
+func Reports(loader *hdl.Loader, chip *hdl.Chip) ([]*Report, error) {
+	exporter := newExporter(loader)
+	if err := exporter.collect(chip); err != nil {
+		return nil, err
+	}
+
+	reporter := newReporter(loader)
+	result := []*Report{}
+	for _, c := range exporter.order {
+		report, err := reporter.report(c)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, report)
+	}
+	return result, nil
+}
+
+// チップの入力ピンの各ビットから、出力ピンの各ビットと内部のクロックに同期する入力までの段数
+// 各行の0番目は入力ピン以外（定数やDFFの出力）からの段数で、i+1番目は入力ピンのi番目のビットからの段数（経路がなければ-1）
+// 入力ピンのビットは、ピンの宣言順に並べる
+type timing struct {
+	outputs [][]int // 出力ピンの各ビットまでの段数
+	sinks   []int   // クロックに同期する入力までの最大の段数
+}
+
+// 組み込みのチップの出力は、CLOCKED宣言されていない入力に依存する
+// Nandだけが1段と数え、他の組み込みのチップは0段とする
+func builtinTiming(chip *hdl.Chip) *timing {
+	delay := 0
+	if chip.Name == "Nand" {
+		delay = 1
+	}
+
+	combinational := []int{0}
+	sinks := []int{0}
+	for _, pin := range chip.Inputs {
+		for i := 0; i < pin.Width; i++ {
+			if chip.IsClocked(pin.Name) {
+				combinational = append(combinational, -1)
+				sinks = append(sinks, 0)
+			} else {
+				combinational = append(combinational, delay)
+				sinks = append(sinks, -1)
+			}
+		}
+	}
+
+	result := &timing{outputs: [][]int{}, sinks: sinks}
+	for _, pin := range chip.Outputs {
+		for i := 0; i < pin.Width; i++ {
+			result.outputs = append(result.outputs, combinational)
+		}
+	}
+	return result
+}
+
+// ピンの宣言順に並べた各ビットのネット
+func pinNets(pins []*hdl.PinDec, nets map[string][]int) []int {
+	result := []int{}
+	for _, pin := range pins {
+		result = append(result, nets[pin.Name]...)
+	}
+	return result
+}
+
+// ネットまでの各段数にdelayを足して、resultの大きいほうを残す
+func addPath(result []int, delay int, net []int) {
+	for i, d := range net {
+		if d >= 0 && delay+d > result[i] {
+			result[i] = delay + d
+		}
+	}
+}
+
+func maxDepth(paths ...[]int) int {
+	result := 0
+	for _, path := range paths {
+		for _, d := range path {
+			if d > result {
+				result = d
+			}
+		}
+	}
+	return result
+}
+
+// チップごとのレポートと段数を覚えておき、パーツを展開せずに組み合わせる
+// 組み込みのチップまで展開すると、RAM16KやComputerではNandの数が多すぎて時間もメモリも足りない
+type reporter struct {
+	loader  *hdl.Loader
+	reports map[string]*Report
+	timings map[string]*timing
+	stack   []string // 計算中のチップ名（自分自身を含むチップの検出用）
+}
+
+func newReporter(loader *hdl.Loader) *reporter {
+	return &reporter{
+		loader:  loader,
+		reports: map[string]*Report{},
+		timings: map[string]*timing{},
+		stack:   []string{},
+	}
+}
+
+func (r *reporter) report(chip *hdl.Chip) (*Report, error) {
+	if report, ok := r.reports[chip.Name]; ok {
+		return report, nil
+	}
+	if err := r.build(chip); err != nil {
+		return nil, err
+	}
+	return r.reports[chip.Name], nil
+}
+
+func (r *reporter) timing(chip *hdl.Chip) (*timing, error) {
+	if t, ok := r.timings[chip.Name]; ok {
+		return t, nil
+	}
+	if err := r.build(chip); err != nil {
+		return nil, err
+	}
+	return r.timings[chip.Name], nil
+}
+
+// パーツのレポートと段数から、チップのレポートと段数を求める
+func (r *reporter) build(chip *hdl.Chip) error {
+	if chip.Builtin {
+		r.reports[chip.Name] = &Report{Name: chip.Name, Cells: map[string]int{chip.Name: 1}}
+		r.timings[chip.Name] = builtinTiming(chip)
+		return nil
+	}
+
+	for _, name := range r.stack {
+		if name == chip.Name {
+			return errors.New(fmt.Sprintf("chip %s contains itself: %s", chip.Name, strings.Join(append(r.stack, chip.Name), " > ")))
+		}
+	}
+	r.stack = append(r.stack, chip.Name)
+	defer func() { r.stack = r.stack[:len(r.stack)-1] }()
+
+	netlist, err := hdl.Elaborate(r.loader, chip)
+	if err != nil {
+		return err
+	}
+
+	report := &Report{Name: chip.Name, Cells: map[string]int{}}
+	partTimings := make([]*timing, len(netlist.Instances))
+	for i, instance := range netlist.Instances {
+		partReport, err := r.report(instance.Chip)
+		if err != nil {
+			return err
+		}
+		for name, count := range partReport.Cells {
+			report.Cells[name] += count
+		}
+		if partTimings[i], err = r.timing(instance.Chip); err != nil {
+			return err
+		}
+	}
+
+	t, err := chipTiming(netlist, partTimings)
+	if err != nil {
+		return errors.WithMessage(err, chip.Name)
+	}
+	report.Depth = maxDepth(append(t.outputs, t.sinks)...)
+	r.reports[chip.Name] = report
+	r.timings[chip.Name] = t
+	return nil
+}
+
+// パーツの段数をつないで、チップの入力ピンの各ビットからの段数を求める
+func chipTiming(netlist *hdl.Netlist, partTimings []*timing) (*timing, error) {
+	inputs := pinNets(netlist.Chip.Inputs, netlist.Pins)
+	width := len(inputs) + 1
+	inputIndex := map[int]int{}
+	for i, net := range inputs {
+		inputIndex[net] = i
+	}
+
+	// ネットを駆動するパーツと、その出力ピンのビットの番号
+	type driver struct {
+		part   int
+		output int
+	}
+	drivers := map[int]driver{}
+	partInputs := make([][]int, len(netlist.Instances))
+	for i, instance := range netlist.Instances {
+		partInputs[i] = pinNets(instance.Chip.Inputs, instance.Pins)
+		for j, net := range pinNets(instance.Chip.Outputs, instance.Pins) {
+			drivers[net] = driver{part: i, output: j}
+		}
+	}
+
+	depths := map[int][]int{}
+	visiting := map[int]bool{}
+	var depthOf func(net int) ([]int, error)
+	depthOf = func(net int) ([]int, error) {
+		if visiting[net] {
+			return nil, errors.New("combinational loop is detected")
+		}
+		if depth, ok := depths[net]; ok {
+			return depth, nil
+		}
+
+		depth := make([]int, width)
+		for i := 1; i < width; i++ {
+			depth[i] = -1
+		}
+		if i, ok := inputIndex[net]; ok {
+			depth[i+1] = 0
+		}
+		if d, ok := drivers[net]; ok {
+			visiting[net] = true
+			path := partTimings[d.part].outputs[d.output]
+			depth[0] = path[0]
+			for j, delay := range path[1:] {
+				if delay < 0 {
+					continue
+				}
+				input, err := depthOf(partInputs[d.part][j])
+				if err != nil {
+					return nil, err
+				}
+				addPath(depth, delay, input)
+			}
+			visiting[net] = false
+		}
+		depths[net] = depth
+		return depth, nil
+	}
+
+	result := &timing{outputs: [][]int{}, sinks: make([]int, width)}
+	for _, net := range pinNets(netlist.Chip.Outputs, netlist.Pins) {
+		depth, err := depthOf(net)
+		if err != nil {
+			return nil, err
+		}
+		result.outputs = append(result.outputs, depth)
+	}
+	for i := 1; i < width; i++ {
+		result.sinks[i] = -1
+	}
+	for i, t := range partTimings {
+		addPath(result.sinks, 0, t.sinks[:1])
+		for j, delay := range t.sinks[1:] {
+			if delay < 0 {
+				continue
+			}
+			input, err := depthOf(partInputs[i][j])
+			if err != nil {
+				return nil, err
+			}
+			addPath(result.sinks, delay, input)
+		}
+	}
+	return result, nil
+}
+
+// レポートを表にして書き出す
+// NandとDFF以外の組み込みのチップは、名前と数をまとめて最後の列に書く
+func WriteReports(w io.Writer, reports []*Report) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "chip\tnand\tdff\tdepth\tothers\t")
+	for _, report := range reports {
+		others := []string{}
+		for name, count := range report.Cells {
+			if name != "Nand" && name != "DFF" {
+				others = append(others, fmt.Sprintf("%s:%d", name, count))
+			}
+		}
+		sort.Strings(others)
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%s\t\n", report.Name, report.Nand(), report.DFF(), report.Depth, strings.Join(others, " "))
+	}
+	return errors.WithStack(tw.Flush())
+}
@@ -0,0 +1,257 @@
+package export
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Verilogの予約語と同じ名前のピンはエスケープする
+var verilogKeywords = map[string]bool{
+	"always": true, "and": true, "assign": true, "begin": true, "buf": true, "case": true,
+	"default": true, "else": true, "end": true, "endcase": true, "endmodule": true, "for": true,
+	"function": true, "if": true, "initial": true, "inout": true, "input": true, "integer": true,
+	"module": true, "nand": true, "nor": true, "not": true, "or": true, "output": true,
+	"parameter": true, "reg": true, "wire": true, "while": true, "xnor": true, "xor": true,
+}
+
+func verilogName(name string) string {
+	if verilogKeywords[name] {
+		return "\\" + name + " "
+	}
+	return name
+}
+
+// 組み込みのチップのVerilog
+// Hackの順序回路はクロックの立ち上がりで値を取り込み、初期値は0
+var builtinVerilog = map[string]string{
+	"Nand": `module Nand(input a, input b, output out);
+    assign out = ~(a & b);
+endmodule
+`,
+	"DFF": `module DFF(input clk, input in, output reg out);
+    initial out = 1'b0;
+    always @(posedge clk) out <= in;
+endmodule
+`,
+	"ARegister": `module ARegister(input clk, input [15:0] in, input load, output reg [15:0] out);
+    initial out = 16'd0;
+    always @(posedge clk) if (load) out <= in;
+endmodule
+`,
+	"DRegister": `module DRegister(input clk, input [15:0] in, input load, output reg [15:0] out);
+    initial out = 16'd0;
+    always @(posedge clk) if (load) out <= in;
+endmodule
+`,
+	"ROM32K": `module ROM32K(input [14:0] address, output [15:0] out);
+    // プログラムは06のアセンブラが出力した.hackファイルを読み込む
+    reg [15:0] memory [0:32767];
+    initial $readmemb("ROM32K.hack", memory);
+    assign out = memory[address];
+endmodule
+`,
+	"Screen": `module Screen(input clk, input [15:0] in, input load, input [12:0] address, output [15:0] out);
+    reg [15:0] memory [0:8191];
+    always @(posedge clk) if (load) memory[address] <= in;
+    assign out = memory[address];
+endmodule
+`,
+	"Keyboard": `module Keyboard(output [15:0] out);
+    // 実機のキーボードをつなぐまでは、何も押されていない状態にする
+    assign out = 16'd0;
+endmodule
+`,
+}
+
+// モジュールと、使っている組み込みのチップのモジュールを書き出す
+func WriteVerilog(w io.Writer, modules []*Module) error {
+	defined := map[string]bool{}
+	for _, m := range modules {
+		defined[m.Name] = true
+	}
+
+	builtins := []string{}
+	for _, m := range modules {
+		for _, cell := range m.Cells {
+			if !defined[cell.Type] {
+				defined[cell.Type] = true
+				builtins = append(builtins, cell.Type)
+			}
+		}
+	}
+	sort.Strings(builtins)
+
+	for _, name := range builtins {
+		src, ok := builtinVerilog[name]
+		if !ok {
+			return errors.New(fmt.Sprintf("verilog of builtin chip %s is not defined", name))
+		}
+		if _, err := fmt.Fprintln(w, src); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	for _, m := range modules {
+		if _, err := io.WriteString(w, m.Verilog()); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+func (m *Module) Verilog() string {
+	names := m.bitNames()
+	var b strings.Builder
+
+	ports := []string{}
+	for _, port := range m.Ports {
+		direction := "output"
+		if port.Input {
+			direction = "input"
+		}
+		ports = append(ports, fmt.Sprintf("    %s %s%s", direction, widthDeclaration(len(port.Bits)), verilogName(port.Name)))
+	}
+	fmt.Fprintf(&b, "module %s(\n%s\n);\n", m.Name, strings.Join(ports, ",\n"))
+
+	for _, wire := range m.Wires {
+		if !wire.Hidden {
+			fmt.Fprintf(&b, "    wire %s%s;\n", widthDeclaration(len(wire.Bits)), verilogName(wire.Name))
+		}
+	}
+	if names.anonymous > 0 {
+		fmt.Fprintf(&b, "    wire %s_n;\n", widthDeclaration(names.anonymous))
+	}
+
+	// 他の出力ピンと同じビットの出力ピン
+	for _, port := range m.Ports {
+		if port.Input {
+			continue
+		}
+		for i, bit := range port.Bits {
+			if owner := names.owners[bit]; owner.signal != port && owner.signal != nil {
+				fmt.Fprintf(&b, "    assign %s = %s;\n", bitReference(port, i), names.expression([]int{bit}))
+			}
+		}
+	}
+
+	for _, cell := range m.Cells {
+		connections := []string{}
+		for _, conn := range cell.Connections {
+			connections = append(connections, fmt.Sprintf(".%s(%s)", verilogName(conn.Pin), names.expression(conn.Bits)))
+		}
+		fmt.Fprintf(&b, "    %s %s (%s);\n", cell.Type, cell.Name, strings.Join(connections, ", "))
+	}
+	b.WriteString("endmodule\n\n")
+	return b.String()
+}
+
+func widthDeclaration(width int) string {
+	if width == 1 {
+		return ""
+	}
+	return fmt.Sprintf("[%d:0] ", width-1)
+}
+
+// ビットを参照するときの名前
+type bitOwner struct {
+	signal *Signal
+	index  int
+}
+
+type bitNames struct {
+	owners    map[int]bitOwner
+	anonymous int // 名前のないビットの数（_n[i]で参照する）
+}
+
+// 入出力ピン、内部ピンの順に、最初にビットを含む信号の名前で参照する
+func (m *Module) bitNames() *bitNames {
+	result := &bitNames{owners: map[int]bitOwner{}}
+	signals := append([]*Signal{}, m.Ports...)
+	for _, wire := range m.Wires {
+		if !wire.Hidden {
+			signals = append(signals, wire)
+		}
+	}
+	for _, signal := range signals {
+		for i, bit := range signal.Bits {
+			if _, ok := result.owners[bit]; !ok && bit != bitFalse && bit != bitTrue {
+				result.owners[bit] = bitOwner{signal: signal, index: i}
+			}
+		}
+	}
+
+	for _, cell := range m.Cells {
+		for _, conn := range cell.Connections {
+			for _, bit := range conn.Bits {
+				if _, ok := result.owners[bit]; !ok && bit != bitFalse && bit != bitTrue {
+					result.owners[bit] = bitOwner{index: result.anonymous}
+					result.anonymous++
+				}
+			}
+		}
+	}
+	return result
+}
+
+func bitReference(signal *Signal, index int) string {
+	if len(signal.Bits) == 1 {
+		return verilogName(signal.Name)
+	}
+	return fmt.Sprintf("%s[%d]", verilogName(signal.Name), index)
+}
+
+// 上位ビットから順に、同じ信号の連続したビットをまとめて連結する
+func (n *bitNames) expression(bits []int) string {
+	parts := []string{}
+	for i := len(bits) - 1; i >= 0; {
+		bit := bits[i]
+		if bit == bitFalse || bit == bitTrue {
+			digits := ""
+			for i >= 0 && (bits[i] == bitFalse || bits[i] == bitTrue) {
+				digits += fmt.Sprint(bits[i])
+				i--
+			}
+			parts = append(parts, fmt.Sprintf("%d'b%s", len(digits), digits))
+			continue
+		}
+
+		owner := n.owners[bit]
+		high := owner.index
+		low := high
+		i--
+		for i >= 0 {
+			next, ok := n.owners[bits[i]]
+			if !ok || next.signal != owner.signal || next.index != low-1 || bits[i] == bitFalse || bits[i] == bitTrue {
+				break
+			}
+			low--
+			i--
+		}
+		parts = append(parts, n.rangeReference(owner.signal, high, low))
+	}
+
+	if len(parts) == 1 {
+		return parts[0]
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+func (n *bitNames) rangeReference(signal *Signal, high int, low int) string {
+	name := "_n"
+	width := n.anonymous
+	if signal != nil {
+		name = verilogName(signal.Name)
+		width = len(signal.Bits)
+	}
+	switch {
+	case width == 1:
+		return name
+	case high == width-1 && low == 0:
+		return name
+	case high == low:
+		return fmt.Sprintf("%s[%d]", name, high)
+	}
+	return fmt.Sprintf("%s[%d:%d]", name, high, low)
+}
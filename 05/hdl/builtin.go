@@ -0,0 +1,114 @@
+package hdl
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+)
+
+// 組み込みのチップのインターフェース
+// HDLファイルが見つからない場合に使う
+// NandとDFF以外はJavaのHardwareSimulatorの組み込みチップで、ワード単位でシミュレートする
+// RAM16KはHDLファイルがあるので、Loader.UseBuiltinを指定した場合だけ使う
+var builtinInterfaces = map[string]string{
+	"Nand":      "CHIP Nand { IN a, b; OUT out; BUILTIN Nand; }",
+	"DFF":       "CHIP DFF { IN in; OUT out; BUILTIN DFF; CLOCKED in; }",
+	"ARegister": "CHIP ARegister { IN in[16], load; OUT out[16]; BUILTIN ARegister; CLOCKED in, load; }",
+	"DRegister": "CHIP DRegister { IN in[16], load; OUT out[16]; BUILTIN DRegister; CLOCKED in, load; }",
+	"ROM32K":    "CHIP ROM32K { IN address[15]; OUT out[16]; BUILTIN ROM32K; }",
+	"RAM16K":    "CHIP RAM16K { IN in[16], load, address[14]; OUT out[16]; BUILTIN RAM16K; CLOCKED in, load; }",
+	"Screen":    "CHIP Screen { IN in[16], load, address[13]; OUT out[16]; BUILTIN Screen; CLOCKED in, load; }",
+	"Keyboard":  "CHIP Keyboard { OUT out[16]; BUILTIN Keyboard; }",
+}
+
+// シミュレーションの最小単位
+// 値はネットの番号で読み書きする
+type element interface {
+	// 組み合わせ回路として依存するネット（これらが決まってからevalする）
+	dependencies() []int
+	outputs() []int
+	eval(values []bool)
+}
+
+// クロックに同期して状態が変わる要素
+type clockedElement interface {
+	element
+	// クロックの立ち上がりで入力を取り込む
+	tick(values []bool)
+	// クロックの立ち下がりで取り込んだ値を出力に反映する
+	tock()
+}
+
+// 組み込みのチップを、ピン名とネットの対応から作る
+var builtinElements = map[string]func(instance *Instance) element{
+	"Nand": func(instance *Instance) element {
+		return &nand{a: instance.Pins["a"][0], b: instance.Pins["b"][0], out: instance.Pins["out"][0]}
+	},
+	"DFF": func(instance *Instance) element {
+		return &dff{in: instance.Pins["in"][0], out: instance.Pins["out"][0]}
+	},
+	"ARegister": newRegister,
+	"DRegister": newRegister,
+	"ROM32K": func(instance *Instance) element {
+		return &rom{Memory: newMemory(instance, 1<<15), address: instance.Pins["address"], out: instance.Pins["out"]}
+	},
+	"RAM16K": func(instance *Instance) element {
+		return newRAM(instance, 1<<14)
+	},
+	"Screen": func(instance *Instance) element {
+		return newRAM(instance, 1<<13)
+	},
+	"Keyboard": func(instance *Instance) element {
+		return &rom{Memory: newMemory(instance, 1), address: []int{}, out: instance.Pins["out"]}
+	},
+}
+
+func newBuiltinElement(instance *Instance) (element, error) {
+	factory, ok := builtinElements[instance.Chip.Name]
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("builtin chip %s is not implemented", instance.Chip.Name))
+	}
+	return factory(instance), nil
+}
+
+type nand struct {
+	a, b, out int
+}
+
+func (n *nand) dependencies() []int {
+	return []int{n.a, n.b}
+}
+
+func (n *nand) outputs() []int {
+	return []int{n.out}
+}
+
+func (n *nand) eval(values []bool) {
+	values[n.out] = !(values[n.a] && values[n.b])
+}
+
+// 出力は前のクロックで取り込んだ値なので、組み合わせ回路としての依存はない
+type dff struct {
+	in, out int
+	state   bool
+	next    bool
+}
+
+func (d *dff) dependencies() []int {
+	return []int{}
+}
+
+func (d *dff) outputs() []int {
+	return []int{d.out}
+}
+
+func (d *dff) eval(values []bool) {
+	values[d.out] = d.state
+}
+
+func (d *dff) tick(values []bool) {
+	d.next = values[d.in]
+}
+
+func (d *dff) tock() {
+	d.state = d.next
+}
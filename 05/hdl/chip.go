@@ -0,0 +1,126 @@
+// HDLで書いたチップを読み込み、ゲートレベルでシミュレートするパッケージ
+// 01から05のチップをJavaのHardwareSimulatorを使わずに動かせる
+//
+//	loader := hdl.NewLoader("02", "01")
+//	chip, err := loader.Load("ALU")
+//	sim, err := hdl.NewSimulator(loader, chip)
+//	sim.Set("x", 3)
+//	sim.Eval()
+//	out, err := sim.Get("out")
+package hdl
+
+import (
+	"fmt"
+)
+
+// バスの最大幅
+const MaxWidth = 32
+
+// CHIP Name { IN ...; OUT ...; PARTS: ... } をパースした結果
+type Chip struct {
+	Name     string
+	Filename string
+	Inputs   []*PinDec
+	Outputs  []*PinDec
+	Parts    []*Part
+	Builtin  bool     // BUILTIN宣言のあるチップはGoで実装する
+	Clocked  []string // CLOCKED宣言されたピン
+}
+
+// IN a[16], b; のような入出力ピンの宣言
+type PinDec struct {
+	Name  string
+	Width int
+	Line  int
+}
+
+// And(a=x, b=y, out=z); のようなパーツの宣言
+type Part struct {
+	Name        string
+	Connections []*Connection
+	Line        int
+}
+
+// パーツのピン（左辺）とチップ側の信号（右辺）の接続
+type Connection struct {
+	Internal *PinRef // パーツのピン
+	External *PinRef // チップの入出力ピン、内部ピン、またはtrue/false
+	Line     int
+}
+
+// a, a[3], a[0..7] のようなピンの参照
+// 範囲を指定しない場合、StartとEndは-1
+type PinRef struct {
+	Name  string
+	Start int
+	End   int
+}
+
+func NewPinRef(name string) *PinRef {
+	return &PinRef{Name: name, Start: -1, End: -1}
+}
+
+func (p *PinRef) HasRange() bool {
+	return p.Start >= 0
+}
+
+func (p *PinRef) IsConstant() bool {
+	return p.Name == "true" || p.Name == "false"
+}
+
+// 範囲指定した場合の幅
+// 範囲を指定していない場合は、ピンの幅をそのまま使うので0を返す
+func (p *PinRef) Width() int {
+	if !p.HasRange() {
+		return 0
+	}
+	return p.End - p.Start + 1
+}
+
+func (p *PinRef) String() string {
+	switch {
+	case !p.HasRange():
+		return p.Name
+	case p.Start == p.End:
+		return fmt.Sprintf("%s[%d]", p.Name, p.Start)
+	}
+	return fmt.Sprintf("%s[%d..%d]", p.Name, p.Start, p.End)
+}
+
+func (c *Connection) String() string {
+	return c.Internal.String() + "=" + c.External.String()
+}
+
+func (c *Chip) FindInput(name string) *PinDec {
+	return findPinDec(c.Inputs, name)
+}
+
+func (c *Chip) FindOutput(name string) *PinDec {
+	return findPinDec(c.Outputs, name)
+}
+
+// 入力ピンと出力ピンの両方から探す
+func (c *Chip) FindPin(name string) *PinDec {
+	if pin := c.FindInput(name); pin != nil {
+		return pin
+	}
+	return c.FindOutput(name)
+}
+
+func findPinDec(pins []*PinDec, name string) *PinDec {
+	for _, pin := range pins {
+		if pin.Name == name {
+			return pin
+		}
+	}
+	return nil
+}
+
+func (c *Chip) IsClocked(name string) bool {
+	for _, pin := range c.Clocked {
+		if pin == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+package hdl
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"os"
+	"path/filepath"
+)
+
+// チップ名からHDLファイルを探して読み込む
+// 指定したディレクトリを順に探し、見つからなければ組み込みのチップ（NandやDFFなど）を使う
+type Loader struct {
+	dirs     []string
+	chips    map[string]*Chip
+	builtins map[string]bool // HDLファイルがあっても組み込みのチップを使うチップ
+}
+
+func NewLoader(dirs ...string) *Loader {
+	return &Loader{
+		dirs:     dirs,
+		chips:    map[string]*Chip{},
+		builtins: map[string]bool{},
+	}
+}
+
+// HDLファイルがあっても組み込みのチップを使う
+// RAM16KなどをNandまで展開すると遅すぎる場合に指定する
+func (l *Loader) UseBuiltin(names ...string) error {
+	for _, name := range names {
+		if _, ok := builtinInterfaces[name]; !ok {
+			return errors.New(fmt.Sprintf("builtin chip %s is not found", name))
+		}
+		l.builtins[name] = true
+	}
+	return nil
+}
+
+func (l *Loader) Dirs() []string {
+	return l.dirs
+}
+
+// ファイルを指定して読み込む
+// パーツはまずそのファイルと同じディレクトリから探す
+func (l *Loader) LoadFile(filename string) (*Chip, error) {
+	chip, err := ParseFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Dir(filename)
+	found := false
+	for _, d := range l.dirs {
+		if filepath.Clean(d) == filepath.Clean(dir) {
+			found = true
+		}
+	}
+	if !found {
+		l.dirs = append([]string{dir}, l.dirs...)
+	}
+
+	l.chips[chip.Name] = chip
+	return chip, nil
+}
+
+func (l *Loader) Load(name string) (*Chip, error) {
+	if chip, ok := l.chips[name]; ok {
+		return chip, nil
+	}
+
+	dirs := l.dirs
+	if l.builtins[name] {
+		dirs = []string{}
+	}
+	for _, dir := range dirs {
+		filename := filepath.Join(dir, name+".hdl")
+		if _, err := os.Stat(filename); err != nil {
+			continue
+		}
+		chip, err := ParseFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		if chip.Name != name {
+			return nil, errors.New(fmt.Sprintf("%s: chip name %s does not match the file name", filename, chip.Name))
+		}
+		l.chips[name] = chip
+		return chip, nil
+	}
+
+	if src, ok := builtinInterfaces[name]; ok {
+		chip, err := ParseString(src)
+		if err != nil {
+			return nil, err
+		}
+		l.chips[name] = chip
+		return chip, nil
+	}
+	return nil, errors.New(fmt.Sprintf("chip %s is not found in %v", name, l.dirs))
+}
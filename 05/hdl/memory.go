@@ -0,0 +1,99 @@
+package hdl
+
+// 組み込みのレジスタとメモリの中身
+// ARegisterとDRegisterは1ワード、Keyboardは押されているキーのコードの1ワードとして扱う
+// Wordsを書き換えた場合は、Simulator.Evalで出力に反映する
+type Memory struct {
+	Name  string // インスタンス名（ROM32K_0など）
+	Chip  string
+	Words []uint16
+}
+
+func newMemory(instance *Instance, size int) *Memory {
+	return &Memory{Name: instance.Name, Chip: instance.Chip.Name, Words: make([]uint16, size)}
+}
+
+// 中身を読み書きできる要素
+type memoryElement interface {
+	element
+	memory() *Memory
+}
+
+func readWord(values []bool, nets []int) int {
+	result := 0
+	for i, net := range nets {
+		if values[net] {
+			result |= 1 << uint(i)
+		}
+	}
+	return result
+}
+
+func writeWord(values []bool, nets []int, word uint16) {
+	for i, net := range nets {
+		values[net] = word&(1<<uint(i)) != 0
+	}
+}
+
+// アドレスで選んだワードを出力する、書き込めないメモリ
+type rom struct {
+	*Memory
+	address []int
+	out     []int
+}
+
+func (r *rom) memory() *Memory {
+	return r.Memory
+}
+
+func (r *rom) dependencies() []int {
+	return r.address
+}
+
+func (r *rom) outputs() []int {
+	return r.out
+}
+
+func (r *rom) eval(values []bool) {
+	writeWord(values, r.out, r.Words[readWord(values, r.address)])
+}
+
+// loadが1なら、クロックの立ち上がりでアドレスのワードに入力を書き込む
+// 出力はアドレスで選んだワードで、アドレスには組み合わせ回路として依存する
+type ram struct {
+	rom
+	in      []int
+	load    int
+	writing bool
+	next    int // 書き込むアドレス
+	word    uint16
+}
+
+func newRAM(instance *Instance, size int) element {
+	return &ram{
+		rom:  rom{Memory: newMemory(instance, size), address: instance.Pins["address"], out: instance.Pins["out"]},
+		in:   instance.Pins["in"],
+		load: instance.Pins["load"][0],
+	}
+}
+
+func (r *ram) tick(values []bool) {
+	r.writing = values[r.load]
+	r.next = readWord(values, r.address)
+	r.word = uint16(readWord(values, r.in))
+}
+
+func (r *ram) tock() {
+	if r.writing {
+		r.Words[r.next] = r.word
+	}
+}
+
+// アドレスのない1ワードのメモリ
+func newRegister(instance *Instance) element {
+	return &ram{
+		rom:  rom{Memory: newMemory(instance, 1), address: []int{}, out: instance.Pins["out"]},
+		in:   instance.Pins["in"],
+		load: instance.Pins["load"][0],
+	}
+}
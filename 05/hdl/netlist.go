@@ -0,0 +1,345 @@
+package hdl
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"strings"
+)
+
+// 0番と1番のネットは定数
+const (
+	netFalse = 0
+	netTrue  = 1
+)
+
+// チップを組み込みのチップだけからなる回路に展開する
+// 展開しないチップはインスタンスとして残す
+// ピンの接続はネット（1ビットの信号線）の統合として扱い、最後にまとめて番号を振り直す
+type netlistBuilder struct {
+	loader    *Loader
+	parent    []int // Union-Find
+	instances []*Instance
+	expand    bool     // 最上位のチップのパーツも展開するか
+	stack     []string // 展開中のチップ名（自分自身を含むチップの検出用）
+}
+
+func newNetlistBuilder(loader *Loader) *netlistBuilder {
+	return &netlistBuilder{
+		loader:    loader,
+		parent:    []int{netFalse, netTrue},
+		instances: []*Instance{},
+		expand:    true,
+		stack:     []string{},
+	}
+}
+
+func (b *netlistBuilder) newNets(width int) []int {
+	result := make([]int, width)
+	for i := range result {
+		result[i] = len(b.parent)
+		b.parent = append(b.parent, len(b.parent))
+	}
+	return result
+}
+
+func (b *netlistBuilder) find(net int) int {
+	for b.parent[net] != net {
+		b.parent[net] = b.parent[b.parent[net]]
+		net = b.parent[net]
+	}
+	return net
+}
+
+// 定数のネットが代表になるようにする
+func (b *netlistBuilder) union(x int, y int) {
+	x, y = b.find(x), b.find(y)
+	if x == y {
+		return
+	}
+	if y < x {
+		x, y = y, x
+	}
+	b.parent[y] = x
+}
+
+func constantNets(name string, width int) []int {
+	net := netFalse
+	if name == "true" {
+		net = netTrue
+	}
+	result := make([]int, width)
+	for i := range result {
+		result[i] = net
+	}
+	return result
+}
+
+// pinsには、チップの入出力ピンのネットを呼び出し側で用意して渡す
+// 戻り値はチップの内部ピンのネット
+func (b *netlistBuilder) instantiate(chip *Chip, pins map[string][]int) (map[string][]int, error) {
+	for _, name := range b.stack {
+		if name == chip.Name {
+			return nil, errors.New(fmt.Sprintf("chip %s contains itself: %s", chip.Name, strings.Join(append(b.stack, chip.Name), " > ")))
+		}
+	}
+	b.stack = append(b.stack, chip.Name)
+	defer func() { b.stack = b.stack[:len(b.stack)-1] }()
+
+	if chip.Builtin || (len(b.stack) > 1 && !b.expand) {
+		name := fmt.Sprintf("%s_%d", chip.Name, len(b.instances))
+		b.instances = append(b.instances, &Instance{Name: name, Chip: chip, Pins: pins})
+		return map[string][]int{}, nil
+	}
+
+	parts := make([]*Chip, len(chip.Parts))
+	for i, part := range chip.Parts {
+		partChip, err := b.loader.Load(part.Name)
+		if err != nil {
+			return nil, errors.WithMessage(err, fmt.Sprintf("%s line %d", chip.Name, part.Line))
+		}
+		parts[i] = partChip
+	}
+
+	// パーツの出力につながる内部ピンを先に用意しておく
+	// 内部ピンはパーツの宣言より前で参照できる
+	internals := map[string][]int{}
+	for i, part := range chip.Parts {
+		for _, conn := range part.Connections {
+			if err := b.declareInternal(chip, parts[i], conn, internals); err != nil {
+				return nil, errors.WithMessage(err, fmt.Sprintf("%s line %d", chip.Name, conn.Line))
+			}
+		}
+	}
+
+	// チップの出力ピンと内部ピンの各ビットを、どのパーツの出力が駆動しているか
+	driven := map[string]bool{}
+	for i, part := range chip.Parts {
+		partPins, err := b.connectPart(chip, parts[i], part, pins, internals, driven)
+		if err != nil {
+			return nil, errors.WithMessage(err, fmt.Sprintf("%s line %d", chip.Name, part.Line))
+		}
+		if _, err := b.instantiate(parts[i], partPins); err != nil {
+			return nil, err
+		}
+	}
+	return internals, nil
+}
+
+// パーツのピンの範囲を、ピンの幅と照らし合わせて返す
+func partPinRange(partChip *Chip, ref *PinRef) (*PinDec, int, int, error) {
+	pin := partChip.FindPin(ref.Name)
+	if pin == nil {
+		return nil, 0, 0, errors.New(fmt.Sprintf("chip %s has no pin %s", partChip.Name, ref.Name))
+	}
+	if !ref.HasRange() {
+		return pin, 0, pin.Width - 1, nil
+	}
+	if ref.End >= pin.Width {
+		return nil, 0, 0, errors.New(fmt.Sprintf("sub bus %s is out of range of %s[%d]", ref.String(), pin.Name, pin.Width))
+	}
+	return pin, ref.Start, ref.End, nil
+}
+
+func (b *netlistBuilder) declareInternal(chip *Chip, partChip *Chip, conn *Connection, internals map[string][]int) error {
+	pin, start, end, err := partPinRange(partChip, conn.Internal)
+	if err != nil {
+		return err
+	}
+	if partChip.FindOutput(pin.Name) == nil {
+		return nil
+	}
+
+	ext := conn.External
+	switch {
+	case ext.IsConstant():
+		return errors.New(fmt.Sprintf("output pin %s cannot be connected to constant %s", pin.Name, ext.Name))
+	case chip.FindInput(ext.Name) != nil:
+		return errors.New(fmt.Sprintf("output pin %s cannot be connected to input pin %s", pin.Name, ext.Name))
+	case chip.FindOutput(ext.Name) != nil:
+		return nil
+	case ext.HasRange():
+		return errors.New(fmt.Sprintf("sub bus of internal pin %s cannot be used", ext.String()))
+	}
+
+	width := end - start + 1
+	if nets, ok := internals[ext.Name]; ok {
+		if len(nets) != width {
+			return errors.New(fmt.Sprintf("width of internal pin %s is %d, but connected to %s with width %d", ext.Name, len(nets), conn.Internal.String(), width))
+		}
+		return nil
+	}
+	internals[ext.Name] = b.newNets(width)
+	return nil
+}
+
+// チップ側の信号のネット
+func (b *netlistBuilder) externalNets(chip *Chip, ext *PinRef, pins map[string][]int, internals map[string][]int) ([]int, error) {
+	nets, ok := pins[ext.Name]
+	if !ok {
+		nets, ok = internals[ext.Name]
+	}
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("pin %s is not defined", ext.Name))
+	}
+	if !ext.HasRange() {
+		return nets, nil
+	}
+	if ext.End >= len(nets) {
+		return nil, errors.New(fmt.Sprintf("sub bus %s is out of range of %s[%d]", ext.String(), ext.Name, len(nets)))
+	}
+	return nets[ext.Start : ext.End+1], nil
+}
+
+// パーツの入出力ピンのネットを用意する
+// 接続していない入力ピンはfalseになる
+func (b *netlistBuilder) connectPart(chip *Chip, partChip *Chip, part *Part, pins map[string][]int, internals map[string][]int, driven map[string]bool) (map[string][]int, error) {
+	result := map[string][]int{}
+	for _, pin := range partChip.Inputs {
+		result[pin.Name] = constantNets("false", pin.Width)
+	}
+	for _, pin := range partChip.Outputs {
+		result[pin.Name] = b.newNets(pin.Width)
+	}
+
+	for _, conn := range part.Connections {
+		pin, start, end, err := partPinRange(partChip, conn.Internal)
+		if err != nil {
+			return nil, err
+		}
+		width := end - start + 1
+		output := partChip.FindOutput(pin.Name) != nil
+
+		var nets []int
+		switch {
+		case conn.External.IsConstant():
+			nets = constantNets(conn.External.Name, width)
+		case !output && chip.FindOutput(conn.External.Name) != nil:
+			return nil, errors.New(fmt.Sprintf("output pin %s cannot be used as an input of %s", conn.External.Name, partChip.Name))
+		default:
+			if nets, err = b.externalNets(chip, conn.External, pins, internals); err != nil {
+				return nil, err
+			}
+		}
+		if len(nets) != width {
+			return nil, errors.New(fmt.Sprintf("width mismatch: %s is %d bits, but %s is %d bits", conn.Internal.String(), width, conn.External.String(), len(nets)))
+		}
+
+		offset := 0
+		if conn.External.HasRange() {
+			offset = conn.External.Start
+		}
+		for i, net := range nets {
+			if output {
+				key := fmt.Sprintf("%s[%d]", conn.External.Name, offset+i)
+				if driven[key] {
+					return nil, errors.New(fmt.Sprintf("%s is driven by multiple outputs", conn.External.String()))
+				}
+				driven[key] = true
+				b.union(result[pin.Name][start+i], net)
+			} else {
+				result[pin.Name][start+i] = net
+			}
+		}
+	}
+	return result, nil
+}
+
+// 展開した回路
+// ネットは統合後の番号に0から振り直す（0番はfalse、1番はtrue）
+type Netlist struct {
+	Chip      *Chip
+	Pins      map[string][]int // 入出力ピンのネット
+	Internals map[string][]int // 最上位のチップの内部ピンのネット
+	Instances []*Instance
+	NetCount  int
+}
+
+// 展開した回路の中のチップ
+type Instance struct {
+	Name string
+	Chip *Chip
+	Pins map[string][]int
+}
+
+// 組み込みのチップ（NandやDFFなど）だけからなる回路に展開する
+func Flatten(loader *Loader, chip *Chip) (*Netlist, error) {
+	return elaborate(loader, chip, true)
+}
+
+// パーツを展開せずに、最上位のチップのパーツをそのままインスタンスにする
+func Elaborate(loader *Loader, chip *Chip) (*Netlist, error) {
+	return elaborate(loader, chip, false)
+}
+
+func elaborate(loader *Loader, chip *Chip, expand bool) (*Netlist, error) {
+	builder := newNetlistBuilder(loader)
+	builder.expand = expand
+	pins := map[string][]int{}
+	for _, pin := range chip.Inputs {
+		pins[pin.Name] = builder.newNets(pin.Width)
+	}
+	for _, pin := range chip.Outputs {
+		pins[pin.Name] = builder.newNets(pin.Width)
+	}
+
+	internals, err := builder.instantiate(chip, pins)
+	if err != nil {
+		return nil, err
+	}
+	count, err := builder.build(pins, internals)
+	if err != nil {
+		return nil, errors.WithMessage(err, chip.Name)
+	}
+	return &Netlist{
+		Chip:      chip,
+		Pins:      pins,
+		Internals: internals,
+		Instances: builder.instances,
+		NetCount:  count,
+	}, nil
+}
+
+// インスタンスと、signalsに渡したピンのネットを振り直した番号に書き換えて、ネットの数を返す
+func (b *netlistBuilder) build(signals ...map[string][]int) (int, error) {
+	// 統合後の代表のネットに、0から連番を振る
+	numbers := map[int]int{netFalse: netFalse, netTrue: netTrue}
+	find := func(net int) int {
+		root := b.find(net)
+		if number, ok := numbers[root]; ok {
+			return number
+		}
+		numbers[root] = len(numbers)
+		return numbers[root]
+	}
+	remap := func(pins map[string][]int) {
+		for name, nets := range pins {
+			remapped := make([]int, len(nets))
+			for i, net := range nets {
+				remapped[i] = find(net)
+			}
+			pins[name] = remapped
+		}
+	}
+	for _, instance := range b.instances {
+		remap(instance.Pins)
+	}
+	for _, pins := range signals {
+		remap(pins)
+	}
+
+	driven := map[int]bool{}
+	for _, instance := range b.instances {
+		for _, pin := range instance.Chip.Outputs {
+			for _, net := range instance.Pins[pin.Name] {
+				if net == netFalse || net == netTrue {
+					return 0, errors.New("constant is driven by a part")
+				}
+				if driven[net] {
+					return 0, errors.New(fmt.Sprintf("net %d has multiple drivers", net))
+				}
+				driven[net] = true
+			}
+		}
+	}
+	return len(numbers), nil
+}
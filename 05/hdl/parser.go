@@ -0,0 +1,366 @@
+package hdl
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type tokenType int
+
+const (
+	tokenIdentifier tokenType = iota
+	tokenNumber
+	tokenSymbol
+	tokenEOF
+)
+
+type hdlToken struct {
+	tokenType tokenType
+	value     string
+	line      int
+}
+
+// コメントを読み飛ばしながら、識別子、数値、記号に分ける
+// 記号は1文字だが、範囲指定の「..」だけは2文字で1つの記号にする
+func tokenize(src string) ([]*hdlToken, error) {
+	result := []*hdlToken{}
+	runes := []rune(src)
+	line := 1
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case r == '\n':
+			line++
+			i++
+		case unicode.IsSpace(r):
+			i++
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '/':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			start := line
+			i += 2
+			for i+1 < len(runes) && !(runes[i] == '*' && runes[i+1] == '/') {
+				if runes[i] == '\n' {
+					line++
+				}
+				i++
+			}
+			if i+1 >= len(runes) {
+				return nil, errors.New(fmt.Sprintf("line %d: comment is not closed", start))
+			}
+			i += 2
+		case r == '.' && i+1 < len(runes) && runes[i+1] == '.':
+			result = append(result, &hdlToken{tokenType: tokenSymbol, value: "..", line: line})
+			i += 2
+		case strings.ContainsRune("{}()[],;=:", r):
+			result = append(result, &hdlToken{tokenType: tokenSymbol, value: string(r), line: line})
+			i++
+		case unicode.IsDigit(r):
+			start := i
+			for i < len(runes) && unicode.IsDigit(runes[i]) {
+				i++
+			}
+			result = append(result, &hdlToken{tokenType: tokenNumber, value: string(runes[start:i]), line: line})
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			result = append(result, &hdlToken{tokenType: tokenIdentifier, value: string(runes[start:i]), line: line})
+		default:
+			return nil, errors.New(fmt.Sprintf("line %d: unexpected character %q", line, r))
+		}
+	}
+	result = append(result, &hdlToken{tokenType: tokenEOF, line: line})
+	return result, nil
+}
+
+type Parser struct {
+	tokens   []*hdlToken
+	position int
+	filename string
+}
+
+func NewParser(filename string) *Parser {
+	return &Parser{filename: filename}
+}
+
+func ParseFile(filename string) (*Chip, error) {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return NewParser(filename).Parse(string(content))
+}
+
+func ParseString(src string) (*Chip, error) {
+	return NewParser("").Parse(src)
+}
+
+func (p *Parser) Parse(src string) (*Chip, error) {
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, p.wrap(err)
+	}
+	p.tokens = tokens
+	p.position = 0
+
+	chip, err := p.parseChip()
+	if err != nil {
+		return nil, p.wrap(err)
+	}
+	return chip, nil
+}
+
+func (p *Parser) wrap(err error) error {
+	if p.filename == "" {
+		return err
+	}
+	return errors.WithMessage(err, p.filename)
+}
+
+func (p *Parser) current() *hdlToken {
+	return p.tokens[p.position]
+}
+
+func (p *Parser) advance() *hdlToken {
+	t := p.tokens[p.position]
+	if t.tokenType != tokenEOF {
+		p.position++
+	}
+	return t
+}
+
+func (p *Parser) errorf(format string, args ...interface{}) error {
+	return errors.New(fmt.Sprintf("line %d: ", p.current().line) + fmt.Sprintf(format, args...))
+}
+
+func (p *Parser) expect(value string) error {
+	if p.current().value != value || p.current().tokenType == tokenEOF {
+		return p.errorf("expected %q, but got %q", value, p.current().value)
+	}
+	p.advance()
+	return nil
+}
+
+func (p *Parser) expectIdentifier() (*hdlToken, error) {
+	if p.current().tokenType != tokenIdentifier {
+		return nil, p.errorf("expected identifier, but got %q", p.current().value)
+	}
+	return p.advance(), nil
+}
+
+func (p *Parser) expectNumber() (int, error) {
+	if p.current().tokenType != tokenNumber {
+		return 0, p.errorf("expected number, but got %q", p.current().value)
+	}
+	value, err := strconv.Atoi(p.advance().value)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	return value, nil
+}
+
+func (p *Parser) parseChip() (*Chip, error) {
+	if err := p.expect("CHIP"); err != nil {
+		return nil, err
+	}
+	name, err := p.expectIdentifier()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+
+	chip := &Chip{Name: name.value, Filename: p.filename, Inputs: []*PinDec{}, Outputs: []*PinDec{}, Parts: []*Part{}, Clocked: []string{}}
+	if p.current().value == "IN" {
+		p.advance()
+		if chip.Inputs, err = p.parsePinDecs(); err != nil {
+			return nil, err
+		}
+	}
+	if p.current().value == "OUT" {
+		p.advance()
+		if chip.Outputs, err = p.parsePinDecs(); err != nil {
+			return nil, err
+		}
+	}
+
+	switch p.current().value {
+	case "PARTS":
+		p.advance()
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		for p.current().value != "}" && p.current().tokenType != tokenEOF {
+			part, err := p.parsePart()
+			if err != nil {
+				return nil, err
+			}
+			chip.Parts = append(chip.Parts, part)
+		}
+	case "BUILTIN":
+		if err := p.parseBuiltin(chip); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, p.errorf("expected PARTS or BUILTIN, but got %q", p.current().value)
+	}
+
+	if err := p.expect("}"); err != nil {
+		return nil, err
+	}
+	if p.current().tokenType != tokenEOF {
+		return nil, p.errorf("unexpected %q after chip definition", p.current().value)
+	}
+	return chip, nil
+}
+
+// a[16], b, c; のようなピンの宣言
+func (p *Parser) parsePinDecs() ([]*PinDec, error) {
+	result := []*PinDec{}
+	for {
+		name, err := p.expectIdentifier()
+		if err != nil {
+			return nil, err
+		}
+		pin := &PinDec{Name: name.value, Width: 1, Line: name.line}
+		if p.current().value == "[" {
+			p.advance()
+			if pin.Width, err = p.expectNumber(); err != nil {
+				return nil, err
+			}
+			if pin.Width < 1 || pin.Width > MaxWidth {
+				return nil, errors.New(fmt.Sprintf("line %d: width of %s must be 1 to %d: %d", name.line, name.value, MaxWidth, pin.Width))
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+		}
+		result = append(result, pin)
+
+		if p.current().value == ";" {
+			p.advance()
+			return result, nil
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// BUILTIN Name; CLOCKED a, b;
+func (p *Parser) parseBuiltin(chip *Chip) error {
+	p.advance()
+	chip.Builtin = true
+	if _, err := p.expectIdentifier(); err != nil {
+		return err
+	}
+	if err := p.expect(";"); err != nil {
+		return err
+	}
+
+	if p.current().value != "CLOCKED" {
+		return nil
+	}
+	p.advance()
+	for {
+		name, err := p.expectIdentifier()
+		if err != nil {
+			return err
+		}
+		chip.Clocked = append(chip.Clocked, name.value)
+		if p.current().value == ";" {
+			p.advance()
+			return nil
+		}
+		if err := p.expect(","); err != nil {
+			return err
+		}
+	}
+}
+
+// Name(pin=signal, ...);
+func (p *Parser) parsePart() (*Part, error) {
+	name, err := p.expectIdentifier()
+	if err != nil {
+		return nil, err
+	}
+	part := &Part{Name: name.value, Connections: []*Connection{}, Line: name.line}
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+
+	for {
+		line := p.current().line
+		internal, err := p.parsePinRef()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect("="); err != nil {
+			return nil, err
+		}
+		external, err := p.parsePinRef()
+		if err != nil {
+			return nil, err
+		}
+		if internal.IsConstant() {
+			return nil, errors.New(fmt.Sprintf("line %d: constant %s cannot be used as a pin of %s", line, internal.Name, part.Name))
+		}
+		if external.IsConstant() && external.HasRange() {
+			return nil, errors.New(fmt.Sprintf("line %d: constant %s cannot have a sub bus", line, external.Name))
+		}
+		part.Connections = append(part.Connections, &Connection{Internal: internal, External: external, Line: line})
+
+		if p.current().value == ")" {
+			p.advance()
+			break
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := p.expect(";"); err != nil {
+		return nil, err
+	}
+	return part, nil
+}
+
+// a, a[3], a[0..7]
+func (p *Parser) parsePinRef() (*PinRef, error) {
+	name, err := p.expectIdentifier()
+	if err != nil {
+		return nil, err
+	}
+	ref := NewPinRef(name.value)
+	if p.current().value != "[" {
+		return ref, nil
+	}
+	p.advance()
+
+	if ref.Start, err = p.expectNumber(); err != nil {
+		return nil, err
+	}
+	ref.End = ref.Start
+	if p.current().value == ".." {
+		p.advance()
+		if ref.End, err = p.expectNumber(); err != nil {
+			return nil, err
+		}
+	}
+	if ref.End < ref.Start {
+		return nil, errors.New(fmt.Sprintf("line %d: invalid sub bus %s", name.line, ref.String()))
+	}
+	if err := p.expect("]"); err != nil {
+		return nil, err
+	}
+	return ref, nil
+}
@@ -0,0 +1,135 @@
+package hdl
+
+import (
+	"github.com/google/go-cmp/cmp"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	src := `// コメント
+/**
+ * 複数行のコメント
+ */
+CHIP Sample {
+    IN a[16], b,   // 行末のコメント
+       sel;
+    OUT out[16], lsb;
+
+    PARTS:
+    Mux16(a=a, b[0..7]=false, b[8]=b, b[9..15]=true, sel=sel, out=out, out[0]=lsb);
+}`
+
+	got, err := ParseString(src)
+	if err != nil {
+		t.Fatalf("failed Parse: %+v", err)
+	}
+
+	ref := func(name string, start int, end int) *PinRef {
+		return &PinRef{Name: name, Start: start, End: end}
+	}
+	want := &Chip{
+		Name: "Sample",
+		Inputs: []*PinDec{
+			{Name: "a", Width: 16, Line: 6},
+			{Name: "b", Width: 1, Line: 6},
+			{Name: "sel", Width: 1, Line: 7},
+		},
+		Outputs: []*PinDec{
+			{Name: "out", Width: 16, Line: 8},
+			{Name: "lsb", Width: 1, Line: 8},
+		},
+		Parts: []*Part{
+			{
+				Name: "Mux16",
+				Line: 11,
+				Connections: []*Connection{
+					{Internal: NewPinRef("a"), External: NewPinRef("a"), Line: 11},
+					{Internal: ref("b", 0, 7), External: NewPinRef("false"), Line: 11},
+					{Internal: ref("b", 8, 8), External: NewPinRef("b"), Line: 11},
+					{Internal: ref("b", 9, 15), External: NewPinRef("true"), Line: 11},
+					{Internal: NewPinRef("sel"), External: NewPinRef("sel"), Line: 11},
+					{Internal: NewPinRef("out"), External: NewPinRef("out"), Line: 11},
+					{Internal: ref("out", 0, 0), External: NewPinRef("lsb"), Line: 11},
+				},
+			},
+		},
+		Clocked: []string{},
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("failed Parse: diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestParseBuiltin(t *testing.T) {
+	got, err := ParseString("CHIP DFF { IN in; OUT out; BUILTIN DFF; CLOCKED in; }")
+	if err != nil {
+		t.Fatalf("failed Parse: %+v", err)
+	}
+	if diff := cmp.Diff([]interface{}{got.Builtin, got.Clocked, len(got.Parts)}, []interface{}{true, []string{"in"}, 0}); diff != "" {
+		t.Errorf("failed Parse: diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestParseError(t *testing.T) {
+	cases := []struct {
+		desc string
+		src  string
+		want string
+	}{
+		{
+			desc: "PARTSがない",
+			src:  "CHIP A { IN a; OUT out; }",
+			want: `line 1: expected PARTS or BUILTIN, but got "}"`,
+		},
+		{
+			desc: "セミコロンがない",
+			src:  "CHIP A {\n IN a;\n OUT out;\n PARTS:\n Not(in=a, out=out)\n}",
+			want: `line 6: expected ";", but got "}"`,
+		},
+		{
+			desc: "範囲が逆",
+			src:  "CHIP A { IN a[4]; OUT out; PARTS: Or4(in=a[3..0], out=out); }",
+			want: "line 1: invalid sub bus a[3..0]",
+		},
+		{
+			desc: "定数の部分バス",
+			src:  "CHIP A { IN a; OUT out; PARTS: Not(in=true[0], out=out); }",
+			want: "line 1: constant true cannot have a sub bus",
+		},
+		{
+			desc: "閉じていないコメント",
+			src:  "CHIP A { /* IN a; }",
+			want: "line 1: comment is not closed",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			_, err := ParseString(tc.src)
+			if err == nil {
+				t.Fatalf("failed Parse: error is expected")
+			}
+			if diff := cmp.Diff(err.Error(), tc.want); diff != "" {
+				t.Errorf("failed Parse: diff (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+// リポジトリのHDLファイルはすべてパースできる
+func TestParseFiles(t *testing.T) {
+	filenames := []string{
+		"../../01/And16.hdl",
+		"../../01/Mux8Way16.hdl",
+		"../../02/ALU.hdl",
+		"../../03/a/PC.hdl",
+		"../../03/b/RAM16K.hdl",
+		"../../05/CPU.hdl",
+		"../../05/Computer.hdl",
+	}
+	for _, filename := range filenames {
+		if _, err := ParseFile(filename); err != nil {
+			t.Errorf("failed ParseFile: %+v", err)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package hdl
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"sort"
+)
+
+// 展開した回路をシミュレートする
+// 入力ピンに値をセットしてEvalすると、組み合わせ回路の出力が決まる
+// 順序回路はTickで入力を取り込み、Tockで出力に反映する
+type Simulator struct {
+	chip      *Chip
+	elements  []element // 評価できる順に並べた要素
+	clocked   []clockedElement
+	memories  []*Memory
+	values    []bool
+	pins      map[string][]int // 入出力ピン
+	internals map[string][]int // 内部ピン（最上位のチップのみ）
+	Time      int              // Tick、Tockのたびに1進む
+	observers []func()
+}
+
+func NewSimulator(loader *Loader, chip *Chip) (*Simulator, error) {
+	netlist, err := Flatten(loader, chip)
+	if err != nil {
+		return nil, err
+	}
+
+	elements := []element{}
+	for _, instance := range netlist.Instances {
+		e, err := newBuiltinElement(instance)
+		if err != nil {
+			return nil, errors.WithMessage(err, chip.Name)
+		}
+		elements = append(elements, e)
+	}
+	sorted, err := sortElements(elements)
+	if err != nil {
+		return nil, errors.WithMessage(err, chip.Name)
+	}
+
+	s := &Simulator{
+		chip:      chip,
+		elements:  sorted,
+		clocked:   []clockedElement{},
+		memories:  []*Memory{},
+		observers: []func(){},
+		values:    make([]bool, netlist.NetCount),
+		pins:      netlist.Pins,
+		internals: netlist.Internals,
+	}
+	for _, e := range elements {
+		if c, ok := e.(clockedElement); ok {
+			s.clocked = append(s.clocked, c)
+		}
+		if m, ok := e.(memoryElement); ok {
+			s.memories = append(s.memories, m.memory())
+		}
+	}
+	s.values[netTrue] = true
+	s.eval()
+	return s, nil
+}
+
+// 依存するネットを出力する要素が先になるように並べる
+func sortElements(elements []element) ([]element, error) {
+	drivers := map[int]int{}
+	for i, e := range elements {
+		for _, net := range e.outputs() {
+			drivers[net] = i
+		}
+	}
+
+	result := []element{}
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	states := make([]int, len(elements))
+	var visit func(i int) error
+	visit = func(i int) error {
+		switch states[i] {
+		case visiting:
+			return errors.New("combinational loop is detected")
+		case visited:
+			return nil
+		}
+		states[i] = visiting
+		for _, net := range elements[i].dependencies() {
+			if driver, ok := drivers[net]; ok {
+				if err := visit(driver); err != nil {
+					return err
+				}
+			}
+		}
+		states[i] = visited
+		result = append(result, elements[i])
+		return nil
+	}
+	for i := range elements {
+		if err := visit(i); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func (s *Simulator) Chip() *Chip {
+	return s.chip
+}
+
+// 展開後のNandやDFFなどの要素の数
+func (s *Simulator) ElementCount() int {
+	return len(s.elements)
+}
+
+// 組み込みのチップ（ROM32KやARegisterなど）の中身を、展開した順に返す
+func (s *Simulator) Memories(chip string) []*Memory {
+	result := []*Memory{}
+	for _, m := range s.memories {
+		if m.Chip == chip {
+			result = append(result, m)
+		}
+	}
+	return result
+}
+
+// 最上位のチップの内部ピンの名前
+func (s *Simulator) Internals() []string {
+	result := []string{}
+	for name := range s.internals {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// 入力ピンに値をセットする
+// 値はピンの幅に切り詰める（負の値は2の補数になる）
+func (s *Simulator) Set(name string, value int) error {
+	if s.chip.FindInput(name) == nil {
+		return errors.New(fmt.Sprintf("chip %s has no input pin %s", s.chip.Name, name))
+	}
+	for i, net := range s.pins[name] {
+		s.values[net] = value&(1<<uint(i)) != 0
+	}
+	return nil
+}
+
+// 入出力ピンと内部ピンの値を、符号なしの整数として返す
+func (s *Simulator) Get(name string) (int, error) {
+	nets, ok := s.pins[name]
+	if !ok {
+		nets, ok = s.internals[name]
+	}
+	if !ok {
+		return 0, errors.New(fmt.Sprintf("chip %s has no pin %s", s.chip.Name, name))
+	}
+
+	result := 0
+	for i, net := range nets {
+		if s.values[net] {
+			result |= 1 << uint(i)
+		}
+	}
+	return result, nil
+}
+
+// ピンの幅（内部ピンも含む）
+func (s *Simulator) Width(name string) (int, error) {
+	nets, ok := s.pins[name]
+	if !ok {
+		nets, ok = s.internals[name]
+	}
+	if !ok {
+		return 0, errors.New(fmt.Sprintf("chip %s has no pin %s", s.chip.Name, name))
+	}
+	return len(nets), nil
+}
+
+// Eval、Tick、Tockの後に呼ぶ関数を登録する（波形の記録など）
+func (s *Simulator) Observe(f func()) {
+	s.observers = append(s.observers, f)
+}
+
+func (s *Simulator) notify() {
+	for _, f := range s.observers {
+		f()
+	}
+}
+
+// 組み合わせ回路を評価する
+func (s *Simulator) Eval() {
+	s.eval()
+	s.notify()
+}
+
+// 要素は依存する順に並んでいるので、先頭から1回ずつ評価すればよい
+func (s *Simulator) eval() {
+	for _, e := range s.elements {
+		e.eval(s.values)
+	}
+}
+
+// クロックの立ち上がり
+// 順序回路は今の入力を取り込むが、出力はまだ変わらない
+func (s *Simulator) Tick() {
+	s.eval()
+	for _, c := range s.clocked {
+		c.tick(s.values)
+	}
+	s.Time++
+	s.notify()
+}
+
+// クロックの立ち下がり
+// 取り込んだ値を出力に反映して、組み合わせ回路を評価し直す
+func (s *Simulator) Tock() {
+	for _, c := range s.clocked {
+		c.tock()
+	}
+	s.eval()
+	s.Time++
+	s.notify()
+}
+
+// 1クロック進める
+func (s *Simulator) Step() {
+	s.Tick()
+	s.Tock()
+}
@@ -0,0 +1,310 @@
+package hdl
+
+import (
+	"github.com/google/go-cmp/cmp"
+	"testing"
+)
+
+// リポジトリのチップを探すディレクトリ
+func newTestLoader() *Loader {
+	return NewLoader("../../05", "../../03/b", "../../03/a", "../../02", "../../01")
+}
+
+func newTestSimulator(t *testing.T, name string) *Simulator {
+	t.Helper()
+	loader := newTestLoader()
+	chip, err := loader.Load(name)
+	if err != nil {
+		t.Fatalf("failed Load: %+v", err)
+	}
+	sim, err := NewSimulator(loader, chip)
+	if err != nil {
+		t.Fatalf("failed NewSimulator: %+v", err)
+	}
+	return sim
+}
+
+func set(t *testing.T, sim *Simulator, inputs map[string]int) {
+	t.Helper()
+	for name, value := range inputs {
+		if err := sim.Set(name, value); err != nil {
+			t.Fatalf("failed Set: %+v", err)
+		}
+	}
+}
+
+func get(t *testing.T, sim *Simulator, names ...string) map[string]int {
+	t.Helper()
+	result := map[string]int{}
+	for _, name := range names {
+		value, err := sim.Get(name)
+		if err != nil {
+			t.Fatalf("failed Get: %+v", err)
+		}
+		result[name] = value
+	}
+	return result
+}
+
+func TestSimulatorCombinational(t *testing.T) {
+	cases := []struct {
+		desc   string
+		chip   string
+		inputs map[string]int
+		want   map[string]int
+	}{
+		{
+			desc:   "And16",
+			chip:   "And16",
+			inputs: map[string]int{"a": 0x0ff0, "b": 0x3c3c},
+			want:   map[string]int{"out": 0x0c30},
+		},
+		{
+			desc:   "Mux8Way16: 部分バスのsel",
+			chip:   "Mux8Way16",
+			inputs: map[string]int{"a": 1, "b": 2, "c": 3, "d": 4, "e": 5, "f": 6, "g": 7, "h": 8, "sel": 5},
+			want:   map[string]int{"out": 6},
+		},
+		{
+			desc:   "Inc16: 定数の接続",
+			chip:   "Inc16",
+			inputs: map[string]int{"in": 0xffff},
+			want:   map[string]int{"out": 0},
+		},
+		{
+			desc:   "ALU: x-y",
+			chip:   "ALU",
+			inputs: map[string]int{"x": 3, "y": 5, "zx": 0, "nx": 1, "zy": 0, "ny": 0, "f": 1, "no": 1},
+			want:   map[string]int{"out": 0xfffe, "zr": 0, "ng": 1},
+		},
+		{
+			desc:   "ALU: 0",
+			chip:   "ALU",
+			inputs: map[string]int{"x": 3, "y": 5, "zx": 1, "nx": 0, "zy": 1, "ny": 0, "f": 1, "no": 0},
+			want:   map[string]int{"out": 0, "zr": 1, "ng": 0},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			sim := newTestSimulator(t, tc.chip)
+			set(t, sim, tc.inputs)
+			sim.Eval()
+
+			names := []string{}
+			for name := range tc.want {
+				names = append(names, name)
+			}
+			if diff := cmp.Diff(get(t, sim, names...), tc.want); diff != "" {
+				t.Errorf("failed Eval: diff (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSimulatorClocked(t *testing.T) {
+	sim := newTestSimulator(t, "RAM8")
+
+	// 書き込みはTockで出力に反映される
+	set(t, sim, map[string]int{"in": 1234, "load": 1, "address": 3})
+	sim.Tick()
+	if diff := cmp.Diff(get(t, sim, "out"), map[string]int{"out": 0}); diff != "" {
+		t.Errorf("failed Tick: diff (-got +want):\n%s", diff)
+	}
+	sim.Tock()
+	if diff := cmp.Diff(get(t, sim, "out"), map[string]int{"out": 1234}); diff != "" {
+		t.Errorf("failed Tock: diff (-got +want):\n%s", diff)
+	}
+
+	set(t, sim, map[string]int{"in": 99, "load": 1, "address": 5})
+	sim.Step()
+	set(t, sim, map[string]int{"load": 0, "address": 3})
+	sim.Eval()
+	if diff := cmp.Diff(get(t, sim, "out"), map[string]int{"out": 1234}); diff != "" {
+		t.Errorf("failed Step: diff (-got +want):\n%s", diff)
+	}
+	if diff := cmp.Diff(sim.Time, 4); diff != "" {
+		t.Errorf("failed Time: diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestSimulatorPC(t *testing.T) {
+	sim := newTestSimulator(t, "PC")
+
+	steps := []struct {
+		inputs map[string]int
+		want   int
+	}{
+		{inputs: map[string]int{"inc": 1}, want: 1},
+		{inputs: map[string]int{"inc": 1}, want: 2},
+		{inputs: map[string]int{"in": 100, "load": 1}, want: 100},
+		{inputs: map[string]int{"load": 0, "inc": 0}, want: 100},
+		{inputs: map[string]int{"inc": 1, "reset": 1}, want: 0},
+		{inputs: map[string]int{"reset": 0, "in": -1, "load": 1}, want: 0xffff},
+	}
+	got := []int{}
+	want := []int{}
+	for _, step := range steps {
+		set(t, sim, step.inputs)
+		sim.Step()
+		got = append(got, get(t, sim, "out")["out"])
+		want = append(want, step.want)
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("failed Step: diff (-got +want):\n%s", diff)
+	}
+}
+
+// Aを保存先に持つC命令は、comp領域のビット（c1など）に関係なくAレジスタを更新する
+func TestSimulatorCPU(t *testing.T) {
+	sim := newTestSimulator(t, "CPU")
+
+	steps := []struct {
+		desc        string
+		instruction int
+		inM         int
+		want        int // 実行後のaddressM
+	}{
+		{desc: "@1000", instruction: 0x03e8, want: 1000},
+		{desc: "D=A", instruction: 0xec10, want: 1000},
+		{desc: "@7", instruction: 0x0007, want: 7},
+		{desc: "A=D", instruction: 0xe320, want: 1000},
+		{desc: "A=D-1", instruction: 0xe3a0, want: 999},
+		{desc: "AM=D", instruction: 0xe328, want: 1000},
+		{desc: "AM=M-1", instruction: 0xfca8, inM: 10, want: 9},
+	}
+	for _, step := range steps {
+		set(t, sim, map[string]int{"instruction": step.instruction, "inM": step.inM})
+		sim.Step()
+		if diff := cmp.Diff(get(t, sim, "addressM")["addressM"], step.want); diff != "" {
+			t.Errorf("failed Step %s: diff (-got +want):\n%s", step.desc, diff)
+		}
+	}
+}
+
+func TestSimulatorInternalPin(t *testing.T) {
+	sim := newTestSimulator(t, "ALU")
+	set(t, sim, map[string]int{"x": 7, "zx": 0, "nx": 1})
+	sim.Eval()
+	// ALUの内部ピンixは!x
+	if diff := cmp.Diff(get(t, sim, "ix"), map[string]int{"ix": 0xfff8}); diff != "" {
+		t.Errorf("failed Get: diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestSimulatorError(t *testing.T) {
+	cases := []struct {
+		desc string
+		src  string
+		want string
+	}{
+		{
+			desc: "存在しないパーツ",
+			src:  "CHIP A { IN a; OUT out; PARTS: Missing(in=a, out=out); }",
+			want: "A line 1: chip Missing is not found in [../../01]",
+		},
+		{
+			desc: "存在しないピン",
+			src:  "CHIP A { IN a; OUT out; PARTS: Not(x=a, out=out); }",
+			want: "A line 1: chip Not has no pin x",
+		},
+		{
+			desc: "幅が合わない",
+			src:  "CHIP A { IN a[2]; OUT out; PARTS: Not(in=a, out=out); }",
+			want: "A line 1: width mismatch: in is 1 bits, but a is 2 bits",
+		},
+		{
+			desc: "定義されていない内部ピン",
+			src:  "CHIP A { IN a; OUT out; PARTS: And(a=a, b=x, out=out); }",
+			want: "A line 1: pin x is not defined",
+		},
+		{
+			desc: "内部ピンの部分バス",
+			src:  "CHIP A { IN a[16]; OUT out; PARTS: Not16(in=a, out=x[0..7]); }",
+			want: "A line 1: sub bus of internal pin x[0..7] cannot be used",
+		},
+		{
+			desc: "組み合わせ回路のループ",
+			src:  "CHIP A { IN a; OUT out; PARTS: And(a=a, b=y, out=x); Not(in=x, out=y); Not(in=y, out=out); }",
+			want: "A: combinational loop is detected",
+		},
+		{
+			desc: "複数の出力がつながる",
+			src:  "CHIP A { IN a; OUT out; PARTS: Not(in=a, out=out); Not(in=a, out=out); }",
+			want: "A line 1: out is driven by multiple outputs",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			chip, err := ParseString(tc.src)
+			if err != nil {
+				t.Fatalf("failed Parse: %+v", err)
+			}
+			loader := NewLoader("../../01")
+			if _, err := NewSimulator(loader, chip); err == nil {
+				t.Fatalf("failed NewSimulator: error is expected")
+			} else if diff := cmp.Diff(err.Error(), tc.want); diff != "" {
+				t.Errorf("failed NewSimulator: diff (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+// 順序回路のループはDFFで切れるのでエラーにならない
+func TestSimulatorSequentialLoop(t *testing.T) {
+	chip, err := ParseString("CHIP Toggle { OUT out; PARTS: Not(in=q, out=d); DFF(in=d, out=q, out=out); }")
+	if err != nil {
+		t.Fatalf("failed Parse: %+v", err)
+	}
+	sim, err := NewSimulator(NewLoader("../../01"), chip)
+	if err != nil {
+		t.Fatalf("failed NewSimulator: %+v", err)
+	}
+
+	got := []int{}
+	for i := 0; i < 4; i++ {
+		sim.Step()
+		got = append(got, get(t, sim, "out")["out"])
+	}
+	if diff := cmp.Diff(got, []int{1, 0, 1, 0}); diff != "" {
+		t.Errorf("failed Step: diff (-got +want):\n%s", diff)
+	}
+}
+
+// RAM16Kを組み込みのチップにして、Memoryの読み書きを確認する
+func TestSimulatorMemory(t *testing.T) {
+	loader := newTestLoader()
+	if err := loader.UseBuiltin("RAM16K"); err != nil {
+		t.Fatalf("failed UseBuiltin: %+v", err)
+	}
+	chip, err := loader.Load("Memory")
+	if err != nil {
+		t.Fatalf("failed Load: %+v", err)
+	}
+	sim, err := NewSimulator(loader, chip)
+	if err != nil {
+		t.Fatalf("failed NewSimulator: %+v", err)
+	}
+
+	// RAMとスクリーンに書き込み、キーボードは中身を直接書き換える
+	set(t, sim, map[string]int{"in": 1234, "load": 1, "address": 100})
+	sim.Step()
+	set(t, sim, map[string]int{"in": 0x5555, "load": 1, "address": 0x4001})
+	sim.Step()
+	sim.Memories("Keyboard")[0].Words[0] = 65
+
+	got := map[int]int{}
+	for _, address := range []int{100, 0x4001, 0x6000, 0} {
+		set(t, sim, map[string]int{"load": 0, "address": address})
+		sim.Eval()
+		got[address] = get(t, sim, "out")["out"]
+	}
+	want := map[int]int{100: 1234, 0x4001: 0x5555, 0x6000: 65, 0: 0}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("failed Memory: diff (-got +want):\n%s", diff)
+	}
+	if diff := cmp.Diff(sim.Memories("RAM16K")[0].Words[100], uint16(1234)); diff != "" {
+		t.Errorf("failed Memories: diff (-got +want):\n%s", diff)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"../../12/emulator"
+	"../cosim"
+	"../hdl"
+	"flag"
+	"fmt"
+	"github.com/pkg/errors"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// 06のアセンブラが出力した.hackファイルを、ゲートレベルのComputerとエミュレータで実行し、
+// クロックごとにPC、A、D、writeM、addressMを比較する
+// 違いが見つかった場合は、そのクロックと値を表示して終了コード1で終了する
+//
+//	hdlcosim -path 03/b,03/a,02,01 -ram 0=3,1=5 05/Computer.hdl 04/mult/mult.hack
+func main() {
+	path := flag.String("path", "", "パーツを探すディレクトリ（カンマ区切り）")
+	cycles := flag.Int("cycles", 100000, "実行する最大のクロック数")
+	ram := flag.String("ram", "", "実行前にRAMにセットする値（address=value、カンマ区切り）")
+	flag.Parse()
+	if flag.NArg() != 2 {
+		log.Fatalln("usage: hdlcosim [-path dirs] [-cycles n] [-ram address=value,...] Computer.hdl program.hack")
+	}
+
+	dirs := []string{}
+	if *path != "" {
+		dirs = strings.Split(*path, ",")
+	}
+	loader := hdl.NewLoader(dirs...)
+	chip, err := loader.LoadFile(flag.Arg(0))
+	if err != nil {
+		log.Fatalf("%+v\n", err)
+	}
+	computer, err := emulator.LoadHackFile(flag.Arg(1))
+	if err != nil {
+		log.Fatalf("%+v\n", err)
+	}
+	c, err := cosim.NewCosimulator(loader, chip, computer.ROM)
+	if err != nil {
+		log.Fatalf("%+v\n", err)
+	}
+
+	if *ram != "" {
+		for _, assignment := range strings.Split(*ram, ",") {
+			address, value, err := parseAssignment(assignment)
+			if err != nil {
+				log.Fatalf("%+v\n", err)
+			}
+			if err := c.SetRAM(address, value); err != nil {
+				log.Fatalf("%+v\n", err)
+			}
+		}
+	}
+
+	divergence, err := c.Run(*cycles)
+	if err != nil {
+		log.Fatalf("%+v\n", err)
+	}
+	if divergence != nil {
+		fmt.Println(divergence.String())
+		os.Exit(1)
+	}
+	fmt.Printf("ok (%d cycles)\n", c.Cycle)
+}
+
+func parseAssignment(s string) (int, int16, error) {
+	fields := strings.SplitN(s, "=", 2)
+	if len(fields) != 2 {
+		return 0, 0, errors.New(fmt.Sprintf("invalid RAM assignment %s", s))
+	}
+	address, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return 0, 0, errors.New(fmt.Sprintf("invalid RAM address %s", fields[0]))
+	}
+	value, err := strconv.ParseInt(fields[1], 10, 16)
+	if err != nil {
+		return 0, 0, errors.New(fmt.Sprintf("invalid RAM value %s", fields[1]))
+	}
+	return address, int16(value), nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"../equiv"
+	"../hdl"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// HDLのチップを、Goで書いた参照モデルと比較する
+// 入力の幅が-exhaustive以下のチップはすべての組み合わせを、それより大きいチップは-random個の入力を試す
+// 違いが見つかった場合は、その入力とピンごとの値を表示して終了コード1で終了する
+//
+//	hdlequiv -path 01 02/ALU.hdl 02/Add16.hdl
+//	hdlequiv -path 01,02,03/a 05/Decode.hdl
+func main() {
+	path := flag.String("path", "", "パーツを探すディレクトリ（カンマ区切り）")
+	exhaustive := flag.Int("exhaustive", equiv.DefaultExhaustiveBits, "すべての組み合わせを試す入力の幅の上限")
+	random := flag.Int("random", equiv.DefaultRandomVectors, "ランダムに試す入力の数")
+	seed := flag.Int64("seed", 1, "乱数のシード")
+	flag.Parse()
+
+	dirs := []string{}
+	if *path != "" {
+		dirs = strings.Split(*path, ",")
+	}
+	options := &equiv.Options{ExhaustiveBits: *exhaustive, RandomVectors: *random, Seed: *seed}
+
+	failed := false
+	for _, file := range flag.Args() {
+		loader := hdl.NewLoader(dirs...)
+		chip, err := loader.LoadFile(file)
+		if err != nil {
+			log.Fatalf("%+v\n", err)
+		}
+		result, err := equiv.Check(loader, chip, options)
+		if err != nil {
+			log.Fatalf("%+v\n", err)
+		}
+
+		if result.Mismatch != nil {
+			failed = true
+			fmt.Printf("%s: mismatch after %d vectors\n  %s\n", file, result.Vectors, result.Mismatch.String())
+			continue
+		}
+		kind := "random"
+		if result.Exhaustive {
+			kind = "exhaustive"
+		}
+		fmt.Printf("%s: ok (%d %s vectors)\n", file, result.Vectors, kind)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
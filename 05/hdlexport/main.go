@@ -0,0 +1,88 @@
+package main
+
+import (
+	"../export"
+	"../hdl"
+	"bytes"
+	"flag"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"strings"
+)
+
+// HDLのチップを、FPGAの開発環境で使うVerilogとJSONのネットリストに書き出す
+// 出力先のディレクトリに、チップ名.v、チップ名.json、チップ名.report.txtを作る
+// -flattenを指定すると、NandとDFFなどの組み込みのチップまで展開した1つのモジュールにする
+//
+//	hdlexport -path 03/a,03/b,02,01 -o build 05/Computer.hdl
+//	hdlexport -flatten -path 01 02/ALU.hdl
+func main() {
+	path := flag.String("path", "", "パーツを探すディレクトリ（カンマ区切り）")
+	flatten := flag.Bool("flatten", false, "組み込みのチップまで展開する")
+	output := flag.String("o", ".", "出力先のディレクトリ")
+	flag.Parse()
+
+	dirs := []string{}
+	if *path != "" {
+		dirs = strings.Split(*path, ",")
+	}
+	for _, file := range flag.Args() {
+		if err := run(file, dirs, *flatten, *output); err != nil {
+			log.Fatalf("%+v\n", err)
+		}
+	}
+}
+
+func run(file string, dirs []string, flatten bool, output string) error {
+	loader := hdl.NewLoader(dirs...)
+	chip, err := loader.LoadFile(file)
+	if err != nil {
+		return err
+	}
+
+	var modules []*export.Module
+	if flatten {
+		m, err := export.Flat(loader, chip)
+		if err != nil {
+			return err
+		}
+		modules = []*export.Module{m}
+	} else {
+		modules, err = export.Hierarchy(loader, chip)
+		if err != nil {
+			return err
+		}
+	}
+
+	verilog := &bytes.Buffer{}
+	if err := export.WriteVerilog(verilog, modules); err != nil {
+		return err
+	}
+	netlist, err := export.NewJSONNetlist(modules).Marshal()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	reports, err := export.Reports(loader, chip)
+	if err != nil {
+		return err
+	}
+	report := &bytes.Buffer{}
+	if err := export.WriteReports(report, reports); err != nil {
+		return err
+	}
+
+	base := filepath.Join(output, chip.Name)
+	files := map[string][]byte{
+		base + ".v":          verilog.Bytes(),
+		base + ".json":       netlist,
+		base + ".report.txt": report.Bytes(),
+	}
+	for filename, content := range files {
+		if err := ioutil.WriteFile(filename, content, 0644); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
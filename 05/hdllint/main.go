@@ -0,0 +1,78 @@
+package main
+
+import (
+	"../hdl"
+	"../lint"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// HDLのチップを静的にチェックして、接続の間違いを警告する
+// 引数にはhdlファイルかディレクトリを指定する（複数指定可）
+// パーツはチップと同じディレクトリと、-pathで指定したディレクトリから探す
+// 警告があった場合は終了コード1で終了する
+//
+//	hdllint -path 03/a,02,01 05/CPU.hdl
+//	hdllint 01
+func main() {
+	path := flag.String("path", "", "パーツを探すディレクトリ（カンマ区切り）")
+	flag.Parse()
+
+	warnings, err := run(flag.Args(), *path)
+	if err != nil {
+		log.Fatalf("%+v\n", err)
+	}
+
+	for _, warning := range warnings {
+		fmt.Println(warning.String())
+	}
+	if len(warnings) > 0 {
+		os.Exit(1)
+	}
+}
+
+func run(args []string, path string) ([]*lint.Warning, error) {
+	files, err := hdlFiles(args)
+	if err != nil {
+		return nil, err
+	}
+
+	dirs := []string{}
+	if path != "" {
+		dirs = strings.Split(path, ",")
+	}
+
+	result := []*lint.Warning{}
+	for _, file := range files {
+		// ファイルごとに探す順番が変わるので、Loaderは使い回さない
+		linter := lint.NewLinter(hdl.NewLoader(dirs...))
+		warnings, err := linter.LintFile(file)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, warnings...)
+	}
+	return result, nil
+}
+
+// ディレクトリが指定された場合は、直下のhdlファイルを対象にする
+func hdlFiles(args []string) ([]string, error) {
+	files := []string{}
+	for _, arg := range args {
+		if filepath.Ext(arg) == ".hdl" {
+			files = append(files, arg)
+			continue
+		}
+
+		matches, err := filepath.Glob(filepath.Join(arg, "*.hdl"))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	return files, nil
+}
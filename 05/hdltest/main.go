@@ -0,0 +1,91 @@
+package main
+
+import (
+	"../tst"
+	"flag"
+	"fmt"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ハードウェアシミュレータのテストスクリプトを実行する
+// output-fileで指定されたファイルに結果を書き出し、compare-toで指定されたファイルと比較する
+// 比較に失敗した場合は終了コード1で終了する
+//
+// -vcdを指定すると、スクリプトと同じ名前の.vcdファイルに波形を書き出す
+// -waveを指定すると、テキストの波形を表示する
+// 記録するピンは-traceで指定する（省略した場合はすべての入出力ピンと内部ピン）
+//
+//	hdltest -path 03/a,02,01 05/CPU.tst
+//	hdltest -path 02,01 -wave -trace inc,load,out 03/a/PC.tst
+func main() {
+	path := flag.String("path", "", "パーツを探すディレクトリ（カンマ区切り）")
+	vcd := flag.Bool("vcd", false, "波形をVCDファイルに書き出す")
+	wave := flag.Bool("wave", false, "テキストの波形を表示する")
+	trace := flag.String("trace", "", "波形を記録するピン（カンマ区切り）")
+	flag.Parse()
+
+	dirs := []string{}
+	if *path != "" {
+		dirs = strings.Split(*path, ",")
+	}
+	for _, file := range flag.Args() {
+		runner := tst.NewRunner(filepath.Dir(file), dirs...)
+		if *vcd || *wave {
+			names := []string{}
+			if *trace != "" {
+				names = strings.Split(*trace, ",")
+			}
+			runner.Trace(names...)
+		}
+
+		err := run(runner, file)
+		if err := writeResults(runner, file, *vcd, *wave); err != nil {
+			log.Fatalf("%+v\n", err)
+		}
+		if err != nil {
+			log.Fatalf("%+v\n", err)
+		}
+		fmt.Printf("%s: End of script - Comparison ended successfully\n", file)
+	}
+}
+
+func run(runner *tst.Runner, file string) error {
+	src, err := ioutil.ReadFile(file)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithMessage(runner.Run(string(src)), file)
+}
+
+// 比較に失敗した場合も、そこまでの結果と波形は書き出す
+func writeResults(runner *tst.Runner, file string, vcd bool, wave bool) error {
+	if err := runner.WriteOutputFile(); err != nil {
+		return err
+	}
+	tracer := runner.Tracer()
+	if tracer == nil {
+		return nil
+	}
+
+	if vcd {
+		f, err := os.Create(strings.TrimSuffix(file, filepath.Ext(file)) + ".vcd")
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		defer f.Close()
+		if err := tracer.WriteVCD(f); err != nil {
+			return err
+		}
+	}
+	if wave {
+		if err := tracer.WriteText(os.Stdout); err != nil {
+			return err
+		}
+	}
+	return nil
+}
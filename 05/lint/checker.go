@@ -0,0 +1,399 @@
+package lint
+
+import (
+	"../hdl"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// 信号（チップの入出力ピンと内部ピン）の組み合わせ回路としての依存関係
+// 信号fromをパーツの入力につなぎ、その出力が信号toになる
+type edge struct {
+	from string
+	to   string
+	line int
+}
+
+type checker struct {
+	linter    *Linter
+	chip      *hdl.Chip
+	parts     []*hdl.Chip    // パーツのチップ（見つからない場合はnil）
+	internals map[string]int // 内部ピンの幅
+	driven    map[string]int // 「名前[ビット]」を駆動した接続の行
+	edges     []*edge
+	warnings  []*Warning
+}
+
+func newChecker(linter *Linter, chip *hdl.Chip) *checker {
+	return &checker{
+		linter:    linter,
+		chip:      chip,
+		parts:     make([]*hdl.Chip, len(chip.Parts)),
+		internals: map[string]int{},
+		driven:    map[string]int{},
+		edges:     []*edge{},
+		warnings:  []*Warning{},
+	}
+}
+
+func (c *checker) warn(line int, rule Rule, format string, args ...interface{}) {
+	c.warnings = append(c.warnings, &Warning{Filename: c.chip.Filename, Line: line, Rule: rule, Message: fmt.Sprintf(format, args...)})
+}
+
+func (c *checker) check() {
+	if c.chip.Builtin {
+		return
+	}
+
+	for i, part := range c.chip.Parts {
+		partChip, err := c.linter.loader.Load(part.Name)
+		if err != nil {
+			c.warn(part.Line, RuleUndefined, "chip %s is not found", part.Name)
+			continue
+		}
+		c.parts[i] = partChip
+	}
+
+	// 内部ピンはパーツの宣言より前で参照できるので、先に出力の接続をすべて見る
+	for i, part := range c.chip.Parts {
+		if c.parts[i] == nil {
+			continue
+		}
+		for _, conn := range part.Connections {
+			c.checkOutput(c.parts[i], conn)
+		}
+	}
+	for i, part := range c.chip.Parts {
+		if c.parts[i] == nil {
+			continue
+		}
+		c.checkInputs(c.parts[i], part)
+	}
+
+	c.checkUndrivenOutputs()
+	c.checkLoops()
+}
+
+// パーツのピンと、そのうち接続した範囲
+// ピンが存在しない場合や範囲が幅を超える場合はnilを返す
+func (c *checker) partPin(partChip *hdl.Chip, conn *hdl.Connection) (*hdl.PinDec, int, int) {
+	ref := conn.Internal
+	pin := partChip.FindPin(ref.Name)
+	if pin == nil {
+		return nil, 0, 0
+	}
+	if !ref.HasRange() {
+		return pin, 0, pin.Width - 1
+	}
+	if ref.End >= pin.Width {
+		return nil, 0, 0
+	}
+	return pin, ref.Start, ref.End
+}
+
+// チップの入出力ピンの、接続した範囲の先頭と幅
+func chipPinRange(pin *hdl.PinDec, ref *hdl.PinRef) (int, int) {
+	if ref.HasRange() {
+		return ref.Start, ref.Width()
+	}
+	return 0, pin.Width
+}
+
+func (c *checker) checkOutput(partChip *hdl.Chip, conn *hdl.Connection) {
+	pin, start, end := c.partPin(partChip, conn)
+	if pin == nil {
+		if partChip.FindPin(conn.Internal.Name) == nil {
+			c.warn(conn.Line, RuleUndefined, "chip %s has no pin %s", partChip.Name, conn.Internal.Name)
+		} else {
+			c.warn(conn.Line, RuleWidthMismatch, "sub bus %s is out of range of %s", conn.Internal.String(), partChip.Name)
+		}
+		return
+	}
+	if partChip.FindOutput(pin.Name) == nil {
+		return
+	}
+
+	width := end - start + 1
+	ext := conn.External
+	offset := 0
+	switch {
+	case ext.IsConstant():
+		c.warn(conn.Line, RuleInvalidConnection, "output pin %s of %s is connected to constant %s", pin.Name, partChip.Name, ext.Name)
+		return
+	case c.chip.FindInput(ext.Name) != nil:
+		c.warn(conn.Line, RuleInvalidConnection, "output pin %s of %s is connected to input pin %s", pin.Name, partChip.Name, ext.Name)
+		return
+	case c.chip.FindOutput(ext.Name) != nil:
+		output := c.chip.FindOutput(ext.Name)
+		if ext.HasRange() && ext.End >= output.Width {
+			c.warn(conn.Line, RuleWidthMismatch, "sub bus %s is out of range of %s[%d]", ext.String(), output.Name, output.Width)
+			return
+		}
+		var extWidth int
+		offset, extWidth = chipPinRange(output, ext)
+		if extWidth != width {
+			c.warn(conn.Line, RuleWidthMismatch, "%s is %d bits, but %s is %d bits", conn.Internal.String(), width, ext.String(), extWidth)
+			return
+		}
+	case ext.HasRange():
+		c.warn(conn.Line, RuleInvalidConnection, "sub bus of internal pin %s cannot be used", ext.String())
+		return
+	default:
+		if declared, ok := c.internals[ext.Name]; ok && declared != width {
+			c.warn(conn.Line, RuleWidthMismatch, "internal pin %s is %d bits, but %s is %d bits", ext.Name, declared, conn.Internal.String(), width)
+			return
+		}
+		c.internals[ext.Name] = width
+	}
+
+	for i := 0; i < width; i++ {
+		key := fmt.Sprintf("%s[%d]", ext.Name, offset+i)
+		if line, ok := c.driven[key]; ok {
+			c.warn(conn.Line, RuleMultipleDrivers, "%s is already driven at line %d", ext.String(), line)
+			return
+		}
+	}
+	for i := 0; i < width; i++ {
+		c.driven[fmt.Sprintf("%s[%d]", ext.Name, offset+i)] = conn.Line
+	}
+}
+
+func (c *checker) checkInputs(partChip *hdl.Chip, part *hdl.Part) {
+	dependencies := c.linter.dependenciesOf(partChip)
+	connected := map[string][]bool{}
+	for _, pin := range partChip.Inputs {
+		connected[pin.Name] = make([]bool, pin.Width)
+	}
+
+	sources := map[string][]string{} // パーツの入力ピン -> つながっている信号
+	for _, conn := range part.Connections {
+		pin, start, end := c.partPin(partChip, conn)
+		if pin == nil || partChip.FindInput(pin.Name) == nil {
+			continue
+		}
+		for i := start; i <= end; i++ {
+			connected[pin.Name][i] = true
+		}
+		if name, ok := c.checkInput(partChip, conn, end-start+1); ok {
+			sources[pin.Name] = append(sources[pin.Name], name)
+		}
+	}
+
+	for _, pin := range partChip.Inputs {
+		if ranges := unsetRanges(pin.Name, connected[pin.Name]); len(ranges) > 0 {
+			c.warn(part.Line, RuleUnconnectedPin, "input pin %s of %s is not connected", strings.Join(ranges, ", "), partChip.Name)
+		}
+	}
+
+	// パーツの出力が依存する入力につながっている信号から、出力につながる内部ピンへの依存
+	for _, conn := range part.Connections {
+		pin, _, _ := c.partPin(partChip, conn)
+		if pin == nil || partChip.FindOutput(pin.Name) == nil || conn.External.IsConstant() || c.chip.FindInput(conn.External.Name) != nil {
+			continue
+		}
+		for input := range dependencies[pin.Name] {
+			for _, source := range sources[input] {
+				c.edges = append(c.edges, &edge{from: source, to: conn.External.Name, line: part.Line})
+			}
+		}
+	}
+}
+
+// 入力ピンの接続を確認して、つながっている信号の名前を返す
+func (c *checker) checkInput(partChip *hdl.Chip, conn *hdl.Connection, width int) (string, bool) {
+	ext := conn.External
+	if ext.IsConstant() {
+		return "", false
+	}
+	if c.chip.FindOutput(ext.Name) != nil {
+		c.warn(conn.Line, RuleInvalidConnection, "output pin %s cannot be used as an input of %s", ext.Name, partChip.Name)
+		return "", false
+	}
+
+	var extWidth int
+	if input := c.chip.FindInput(ext.Name); input != nil {
+		if ext.HasRange() && ext.End >= input.Width {
+			c.warn(conn.Line, RuleWidthMismatch, "sub bus %s is out of range of %s[%d]", ext.String(), input.Name, input.Width)
+			return "", false
+		}
+		_, extWidth = chipPinRange(input, ext)
+	} else {
+		declared, ok := c.internals[ext.Name]
+		if !ok {
+			c.warn(conn.Line, RuleUndefined, "internal pin %s is never driven", ext.Name)
+			return "", false
+		}
+		if ext.HasRange() {
+			c.warn(conn.Line, RuleInvalidConnection, "sub bus of internal pin %s cannot be used", ext.String())
+			return "", false
+		}
+		extWidth = declared
+	}
+
+	if extWidth != width {
+		c.warn(conn.Line, RuleWidthMismatch, "%s is %d bits, but %s is %d bits", conn.Internal.String(), width, ext.String(), extWidth)
+	}
+	return ext.Name, true
+}
+
+func (c *checker) checkUndrivenOutputs() {
+	for _, pin := range c.chip.Outputs {
+		driven := make([]bool, pin.Width)
+		for i := range driven {
+			_, driven[i] = c.driven[fmt.Sprintf("%s[%d]", pin.Name, i)]
+		}
+		if ranges := unsetRanges(pin.Name, driven); len(ranges) > 0 {
+			c.warn(pin.Line, RuleUndrivenOutput, "output pin %s is never driven", strings.Join(ranges, ", "))
+		}
+	}
+}
+
+// falseのビットの範囲を「a」「a[3]」「a[0..7]」の形で返す
+// すべてfalseの場合はピン名だけにする
+func unsetRanges(name string, bits []bool) []string {
+	result := []string{}
+	for i := 0; i < len(bits); {
+		if bits[i] {
+			i++
+			continue
+		}
+		start := i
+		for i < len(bits) && !bits[i] {
+			i++
+		}
+		ref := &hdl.PinRef{Name: name, Start: start, End: i - 1}
+		if start == 0 && i == len(bits) {
+			ref = hdl.NewPinRef(name)
+		}
+		result = append(result, ref.String())
+	}
+	return result
+}
+
+// 信号の依存関係の強連結成分を求め、複数の信号からなる成分（と自分自身への依存）をループとして報告する
+func (c *checker) checkLoops() {
+	graph := map[string][]*edge{}
+	nodes := []string{}
+	for _, e := range c.edges {
+		if _, ok := graph[e.from]; !ok {
+			nodes = append(nodes, e.from)
+		}
+		graph[e.from] = append(graph[e.from], e)
+	}
+
+	index := map[string]int{}
+	lowlink := map[string]int{}
+	onStack := map[string]bool{}
+	stack := []string{}
+	var strongConnect func(node string)
+	strongConnect = func(node string) {
+		index[node] = len(index)
+		lowlink[node] = index[node]
+		stack = append(stack, node)
+		onStack[node] = true
+
+		for _, e := range graph[node] {
+			if _, ok := index[e.to]; !ok {
+				strongConnect(e.to)
+				if lowlink[e.to] < lowlink[node] {
+					lowlink[node] = lowlink[e.to]
+				}
+			} else if onStack[e.to] && index[e.to] < lowlink[node] {
+				lowlink[node] = index[e.to]
+			}
+		}
+
+		if lowlink[node] != index[node] {
+			return
+		}
+		component := map[string]bool{}
+		for {
+			last := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[last] = false
+			component[last] = true
+			if last == node {
+				break
+			}
+		}
+		c.reportLoop(component, graph)
+	}
+
+	for _, node := range nodes {
+		if _, ok := index[node]; !ok {
+			strongConnect(node)
+		}
+	}
+}
+
+func (c *checker) reportLoop(component map[string]bool, graph map[string][]*edge) {
+	line := 0
+	for node := range component {
+		for _, e := range graph[node] {
+			if component[e.to] && (line == 0 || e.line < line) {
+				line = e.line
+			}
+		}
+	}
+	if line == 0 {
+		return
+	}
+
+	names := []string{}
+	for node := range component {
+		names = append(names, node)
+	}
+	sort.Strings(names)
+	c.warn(line, RuleCombinationalLoop, "combinational loop through %s", strings.Join(names, ", "))
+}
+
+// 出力ピンごとに、組み合わせ回路として依存する入力ピン
+// 組み込みのチップは、CLOCKED宣言されていない入力にすべての出力が依存するとみなす
+func (l *Linter) dependenciesOf(chip *hdl.Chip) map[string]map[string]bool {
+	if result, ok := l.dependencies[chip.Name]; ok {
+		return result
+	}
+	result := map[string]map[string]bool{}
+	for _, output := range chip.Outputs {
+		result[output.Name] = map[string]bool{}
+	}
+	// 自分自身を含むチップで無限に再帰しないように、先に登録しておく
+	l.dependencies[chip.Name] = result
+
+	if chip.Builtin {
+		for _, output := range chip.Outputs {
+			for _, input := range chip.Inputs {
+				if !chip.IsClocked(input.Name) {
+					result[output.Name][input.Name] = true
+				}
+			}
+		}
+		return result
+	}
+
+	checker := newChecker(l, chip)
+	checker.check()
+	graph := map[string][]string{}
+	for _, e := range checker.edges {
+		graph[e.from] = append(graph[e.from], e.to)
+	}
+	for _, input := range chip.Inputs {
+		visited := map[string]bool{input.Name: true}
+		queue := []string{input.Name}
+		for len(queue) > 0 {
+			node := queue[0]
+			queue = queue[1:]
+			if _, ok := result[node]; ok {
+				result[node][input.Name] = true
+			}
+			for _, next := range graph[node] {
+				if !visited[next] {
+					visited[next] = true
+					queue = append(queue, next)
+				}
+			}
+		}
+	}
+	return result
+}
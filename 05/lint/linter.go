@@ -0,0 +1,75 @@
+// HDLのチップを静的にチェックするパッケージ
+// シミュレータで動かす前に、接続の間違いをHDLファイルと行番号付きで報告する
+package lint
+
+import (
+	"../hdl"
+	"fmt"
+	"sort"
+)
+
+type Rule string
+
+const (
+	RuleUnconnectedPin    Rule = "unconnected-pin"    // どこにも接続していないパーツの入力ピン
+	RuleMultipleDrivers   Rule = "multiple-drivers"   // 複数のパーツの出力につながった内部ピンや出力ピン
+	RuleWidthMismatch     Rule = "width-mismatch"     // 接続したピンとバスの幅が違う
+	RuleUndrivenOutput    Rule = "undriven-output"    // どのパーツの出力にもつながっていない出力ピン
+	RuleCombinationalLoop Rule = "combinational-loop" // DFFを通らずに自分自身に戻る信号
+	RuleUndefined         Rule = "undefined"          // 存在しないパーツ、ピン、一度も駆動されない内部ピン
+	RuleInvalidConnection Rule = "invalid-connection" // 入力ピンや定数を駆動する、出力ピンを読み出すなどの接続
+)
+
+// チップの間違いの可能性が高い箇所
+type Warning struct {
+	Filename string
+	Line     int
+	Rule
+	Message string
+}
+
+func (w *Warning) String() string {
+	return fmt.Sprintf("%s:%d: %s (%s)", w.Filename, w.Line, w.Message, w.Rule)
+}
+
+type Linter struct {
+	loader       *hdl.Loader
+	dependencies map[string]map[string]map[string]bool // チップ名 -> 出力ピン -> 組み合わせ回路として依存する入力ピン
+}
+
+// パーツはloaderで探す
+func NewLinter(loader *hdl.Loader) *Linter {
+	return &Linter{
+		loader:       loader,
+		dependencies: map[string]map[string]map[string]bool{},
+	}
+}
+
+func (l *Linter) LintFile(filename string) ([]*Warning, error) {
+	chip, err := l.loader.LoadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return l.LintChip(chip), nil
+}
+
+// ファイルを読み込まずに、メモリ上のソースをチェックする
+func (l *Linter) LintSource(filename string, src string) ([]*Warning, error) {
+	chip, err := hdl.NewParser(filename).Parse(src)
+	if err != nil {
+		return nil, err
+	}
+	return l.LintChip(chip), nil
+}
+
+// パースできない場合以外は、見つかった問題をすべて警告として返す
+func (l *Linter) LintChip(chip *hdl.Chip) []*Warning {
+	checker := newChecker(l, chip)
+	checker.check()
+
+	result := checker.warnings
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Line < result[j].Line
+	})
+	return result
+}
@@ -0,0 +1,199 @@
+package lint
+
+import (
+	"../hdl"
+	"github.com/google/go-cmp/cmp"
+	"path/filepath"
+	"testing"
+)
+
+func newTestLinter() *Linter {
+	return NewLinter(hdl.NewLoader("../../05", "../../03/b", "../../03/a", "../../02", "../../01"))
+}
+
+func TestLinterLintSource(t *testing.T) {
+	cases := []struct {
+		desc string
+		src  string
+		want []string
+	}{
+		{
+			desc: "警告なし",
+			src: `CHIP A {
+    IN a, b;
+    OUT out;
+    PARTS:
+    Nand(a=a, b=b, out=x);
+    Not(in=x, out=out);
+}`,
+			want: []string{},
+		},
+		{
+			desc: "接続していない入力ピン",
+			src: `CHIP A {
+    IN a[16];
+    OUT out[16];
+    PARTS:
+    Mux16(a=a, b[0..7]=a[0..7], out=out);
+}`,
+			want: []string{
+				"A.hdl:5: input pin b[8..15] of Mux16 is not connected (unconnected-pin)",
+				"A.hdl:5: input pin sel of Mux16 is not connected (unconnected-pin)",
+			},
+		},
+		{
+			desc: "複数回駆動される内部ピン",
+			src: `CHIP A {
+    IN a;
+    OUT out;
+    PARTS:
+    Not(in=a, out=x);
+    Not(in=a, out=x);
+    Not(in=x, out=out);
+}`,
+			want: []string{
+				"A.hdl:6: x is already driven at line 5 (multiple-drivers)",
+			},
+		},
+		{
+			desc: "幅が合わない",
+			src: `CHIP A {
+    IN a[8], b;
+    OUT out[16];
+    PARTS:
+    Not16(in=a, out=x);
+    Not(in=b, out[0..1]=y);
+    Not16(in=x, out[0..7]=out);
+}`,
+			want: []string{
+				"A.hdl:3: output pin out is never driven (undriven-output)",
+				"A.hdl:5: in is 16 bits, but a is 8 bits (width-mismatch)",
+				"A.hdl:6: sub bus out[0..1] is out of range of Not (width-mismatch)",
+				"A.hdl:7: out[0..7] is 8 bits, but out is 16 bits (width-mismatch)",
+			},
+		},
+		{
+			desc: "駆動されない出力ピン",
+			src: `CHIP A {
+    IN a;
+    OUT out[4],
+        flag;
+    PARTS:
+    Not(in=a, out=out[1]);
+}`,
+			want: []string{
+				"A.hdl:3: output pin out[0], out[2..3] is never driven (undriven-output)",
+				"A.hdl:4: output pin flag is never driven (undriven-output)",
+			},
+		},
+		{
+			desc: "組み合わせ回路のループ",
+			src: `CHIP A {
+    IN a;
+    OUT out;
+    PARTS:
+    And(a=a, b=z, out=x);
+    Not(in=x, out=y);
+    Or(a=y, b=a, out=z);
+    Not(in=z, out=out);
+}`,
+			want: []string{
+				"A.hdl:5: combinational loop through x, y, z (combinational-loop)",
+			},
+		},
+		{
+			desc: "DFFを通るループは警告しない",
+			src: `CHIP A {
+    IN in, load;
+    OUT out;
+    PARTS:
+    Mux(a=q, b=in, sel=load, out=d);
+    DFF(in=d, out=q);
+    Not(in=q, out=out);
+}`,
+			want: []string{},
+		},
+		{
+			desc: "チップの中のDFFを通るループは警告しない",
+			src: `CHIP A {
+    IN in;
+    OUT out[16];
+    PARTS:
+    Inc16(in=q, out=next);
+    Register(in=next, load=true, out=q);
+    Not16(in=q, out=out);
+}`,
+			want: []string{},
+		},
+		{
+			desc: "存在しないパーツ、ピン、内部ピン",
+			src: `CHIP A {
+    IN a;
+    OUT out;
+    PARTS:
+    Missing(in=a, out=x);
+    Not(inn=a, out=y);
+    And(a=a, b=z, out=out);
+}`,
+			want: []string{
+				"A.hdl:5: chip Missing is not found (undefined)",
+				"A.hdl:6: chip Not has no pin inn (undefined)",
+				"A.hdl:6: input pin in of Not is not connected (unconnected-pin)",
+				"A.hdl:7: internal pin z is never driven (undefined)",
+			},
+		},
+		{
+			desc: "入力ピンや定数の駆動、出力ピンの読み出し",
+			src: `CHIP A {
+    IN a;
+    OUT out, out2;
+    PARTS:
+    Not(in=a, out=a);
+    Not(in=a, out=true);
+    Not(in=a, out=out);
+    Not(in=out, out=out2);
+}`,
+			want: []string{
+				"A.hdl:5: output pin out of Not is connected to input pin a (invalid-connection)",
+				"A.hdl:6: output pin out of Not is connected to constant true (invalid-connection)",
+				"A.hdl:8: output pin out cannot be used as an input of Not (invalid-connection)",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			warnings, err := newTestLinter().LintSource("A.hdl", tc.src)
+			if err != nil {
+				t.Fatalf("failed LintSource: %+v", err)
+			}
+			got := []string{}
+			for _, warning := range warnings {
+				got = append(got, warning.String())
+			}
+			if diff := cmp.Diff(got, tc.want); diff != "" {
+				t.Errorf("failed LintSource: diff (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+// リポジトリのチップには警告がない
+func TestLinterLintFile(t *testing.T) {
+	patterns := []string{"../../01/*.hdl", "../../02/*.hdl", "../../03/*/*.hdl", "../../05/*.hdl"}
+	for _, pattern := range patterns {
+		filenames, err := filepath.Glob(pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, filename := range filenames {
+			warnings, err := newTestLinter().LintFile(filename)
+			if err != nil {
+				t.Fatalf("failed LintFile: %+v", err)
+			}
+			for _, warning := range warnings {
+				t.Errorf("failed LintFile: %s", warning.String())
+			}
+		}
+	}
+}
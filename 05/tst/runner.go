@@ -0,0 +1,418 @@
+package tst
+
+import (
+	"../hdl"
+	"../wave"
+	"bufio"
+	"fmt"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// output-listの1列
+// %B1.16.1は、左に1文字、16桁の2進数、右に1文字の空白で書くことを表す
+type Column struct {
+	Name   string
+	Format byte // B, D, X, S
+	Left   int
+	Length int
+	Right  int
+}
+
+func ParseColumn(spec string) (*Column, error) {
+	i := strings.Index(spec, "%")
+	if i < 0 {
+		// 書式を省略した場合は、ピンの幅の2進数
+		return &Column{Name: spec, Format: 'B', Left: 1, Length: -1, Right: 1}, nil
+	}
+
+	c := &Column{Name: spec[:i]}
+	format := spec[i+1:]
+	if len(format) == 0 || !strings.ContainsRune("BDXS", rune(format[0])) {
+		return nil, errors.New(fmt.Sprintf("invalid format %s", spec))
+	}
+	c.Format = format[0]
+	numbers := strings.Split(format[1:], ".")
+	if len(numbers) != 3 {
+		return nil, errors.New(fmt.Sprintf("invalid format %s", spec))
+	}
+	values := make([]int, 3)
+	for j, number := range numbers {
+		value, err := strconv.Atoi(number)
+		if err != nil || value < 0 {
+			return nil, errors.New(fmt.Sprintf("invalid format %s", spec))
+		}
+		values[j] = value
+	}
+	c.Left, c.Length, c.Right = values[0], values[1], values[2]
+	return c, nil
+}
+
+// 列の見出し
+// 名前を列の幅の中央に置き、入りきらない場合は切り詰める
+func (c *Column) Header(width int) string {
+	space := c.Left + c.length(width) + c.Right
+	name := c.Name
+	if len(name) > space {
+		name = name[:space]
+	}
+	left := (space - len(name)) / 2
+	return strings.Repeat(" ", left) + name + strings.Repeat(" ", space-len(name)-left)
+}
+
+func (c *Column) length(width int) int {
+	if c.Length < 0 {
+		return width
+	}
+	return c.Length
+}
+
+// ピンの値を書式に合わせて書く
+// 16ビットのピンを10進数で書く場合は、符号付きの値として扱う
+func (c *Column) Value(value int, width int) string {
+	length := c.length(width)
+	var s string
+	switch c.Format {
+	case 'B':
+		s = fmt.Sprintf("%0*b", length, value)
+		s = s[len(s)-length:]
+	case 'X':
+		s = fmt.Sprintf("%0*X", length, value)
+		s = s[len(s)-length:]
+	case 'D':
+		if width == 16 && value&0x8000 != 0 {
+			value -= 0x10000
+		}
+		s = fmt.Sprintf("%*d", length, value)
+	case 'S':
+		s = fmt.Sprintf("%-*d", length, value)
+	}
+	return strings.Repeat(" ", c.Left) + s + strings.Repeat(" ", c.Right)
+}
+
+// setコマンドの値
+// %B0101、%X0F、%D-1、-1のように書く
+func ParseValue(s string) (int, error) {
+	base := 10
+	if strings.HasPrefix(s, "%") && len(s) > 1 {
+		switch s[1] {
+		case 'B':
+			base = 2
+		case 'X':
+			base = 16
+		case 'D':
+			base = 10
+		default:
+			return 0, errors.New(fmt.Sprintf("invalid value %s", s))
+		}
+		s = s[2:]
+	}
+	value, err := strconv.ParseInt(s, base, 64)
+	if err != nil {
+		return 0, errors.New(fmt.Sprintf("invalid value %s", s))
+	}
+	return int(value), nil
+}
+
+// テストスクリプトを実行する
+// スクリプトのあるディレクトリと、NewRunnerで指定したディレクトリからチップを探す
+type Runner struct {
+	dir        string
+	dirs       []string
+	sim        *hdl.Simulator
+	columns    []*Column
+	output     []string
+	outputFile string
+	compareTo  string
+	expected   []string
+	trace      []string // nilなら波形を記録しない
+	tracer     *wave.Tracer
+	Echo       []string
+}
+
+func NewRunner(dir string, dirs ...string) *Runner {
+	return &Runner{
+		dir:     dir,
+		dirs:    dirs,
+		columns: []*Column{},
+		output:  []string{},
+		Echo:    []string{},
+	}
+}
+
+// スクリプトのファイルを読み込んで実行する
+func RunFile(filename string, dirs ...string) (*Runner, error) {
+	src, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	r := NewRunner(filepath.Dir(filename), dirs...)
+	if err := r.Run(string(src)); err != nil {
+		return r, errors.WithMessage(err, filename)
+	}
+	return r, nil
+}
+
+// loadしたチップのピンの波形を記録する
+// ピンを指定しない場合は、すべての入出力ピンと内部ピンを記録する
+func (r *Runner) Trace(names ...string) {
+	r.trace = append([]string{}, names...)
+}
+
+// 記録した波形（Traceを指定していない場合はnil）
+func (r *Runner) Tracer() *wave.Tracer {
+	return r.tracer
+}
+
+// 出力した行（見出しを含む）
+func (r *Runner) Output() []string {
+	return r.output
+}
+
+// output-fileで指定されたファイルのパス（指定されていない場合は空）
+func (r *Runner) OutputFile() string {
+	return r.outputFile
+}
+
+func (r *Runner) Simulator() *hdl.Simulator {
+	return r.sim
+}
+
+// スクリプトを実行する
+// compare-toが指定されている場合は、出力のたびに比較し、最初の違いでエラーにする
+func (r *Runner) Run(src string) error {
+	commands, err := ParseScript(src)
+	if err != nil {
+		return err
+	}
+	return r.execute(commands)
+}
+
+func (r *Runner) execute(commands []*Command) error {
+	for _, command := range commands {
+		if err := r.executeCommand(command); err != nil {
+			return errors.WithMessage(err, fmt.Sprintf("line %d", command.Line))
+		}
+	}
+	return nil
+}
+
+func (r *Runner) executeCommand(command *Command) error {
+	switch command.Name {
+	case "set", "eval", "tick", "tock", "output", "output-list":
+		if r.sim == nil {
+			return errors.New(fmt.Sprintf("%s before load", command.Name))
+		}
+	}
+
+	switch command.Name {
+	case "load":
+		if err := r.expectArgs(command, 1); err != nil {
+			return err
+		}
+		return r.load(command.Args[0])
+	case "output-file":
+		if err := r.expectArgs(command, 1); err != nil {
+			return err
+		}
+		r.outputFile = filepath.Join(r.dir, command.Args[0])
+	case "compare-to":
+		if err := r.expectArgs(command, 1); err != nil {
+			return err
+		}
+		r.compareTo = filepath.Join(r.dir, command.Args[0])
+		expected, err := readLines(r.compareTo)
+		if err != nil {
+			return err
+		}
+		r.expected = expected
+	case "output-list":
+		return r.outputList(command.Args)
+	case "set":
+		if err := r.expectArgs(command, 2); err != nil {
+			return err
+		}
+		value, err := ParseValue(command.Args[1])
+		if err != nil {
+			return err
+		}
+		return r.sim.Set(command.Args[0], value)
+	case "eval":
+		r.sim.Eval()
+	case "tick":
+		r.sim.Tick()
+	case "tock":
+		r.sim.Tock()
+	case "output":
+		return r.outputValues()
+	case "echo":
+		r.Echo = append(r.Echo, strings.Join(command.Args, " "))
+	case "clear-echo":
+		r.Echo = []string{}
+	case "repeat":
+		count := 1
+		if len(command.Args) > 0 {
+			n, err := strconv.Atoi(command.Args[0])
+			if err != nil {
+				return errors.New(fmt.Sprintf("invalid repeat count %s", command.Args[0]))
+			}
+			count = n
+		}
+		for i := 0; i < count; i++ {
+			if err := r.execute(command.Body); err != nil {
+				return err
+			}
+		}
+	default:
+		return errors.New(fmt.Sprintf("unknown command %s", command.Name))
+	}
+	return nil
+}
+
+func (r *Runner) expectArgs(command *Command, n int) error {
+	if len(command.Args) != n {
+		return errors.New(fmt.Sprintf("%s expects %d arguments, but got %d", command.Name, n, len(command.Args)))
+	}
+	return nil
+}
+
+func (r *Runner) load(name string) error {
+	// パーツはスクリプトのディレクトリを先に探す
+	loader := hdl.NewLoader(append([]string{r.dir}, r.dirs...)...)
+	chip, err := loader.LoadFile(filepath.Join(r.dir, name))
+	if err != nil {
+		return err
+	}
+	sim, err := hdl.NewSimulator(loader, chip)
+	if err != nil {
+		return err
+	}
+	r.sim = sim
+	if r.trace != nil {
+		tracer, err := wave.NewTracer(sim, r.trace...)
+		if err != nil {
+			return err
+		}
+		r.tracer = tracer
+	}
+	return nil
+}
+
+// 列を設定して、見出しを出力する
+func (r *Runner) outputList(specs []string) error {
+	columns := []*Column{}
+	headers := []string{}
+	for _, spec := range specs {
+		column, err := ParseColumn(spec)
+		if err != nil {
+			return err
+		}
+		width, err := r.width(column.Name)
+		if err != nil {
+			return err
+		}
+		columns = append(columns, column)
+		headers = append(headers, column.Header(width))
+	}
+	r.columns = columns
+	return r.write("|" + strings.Join(headers, "|") + "|")
+}
+
+func (r *Runner) width(name string) (int, error) {
+	if name == "time" {
+		return 0, nil
+	}
+	return r.sim.Width(name)
+}
+
+func (r *Runner) outputValues() error {
+	values := []string{}
+	for _, column := range r.columns {
+		if column.Name == "time" {
+			values = append(values, column.time(r.sim.Time))
+			continue
+		}
+		value, err := r.sim.Get(column.Name)
+		if err != nil {
+			return err
+		}
+		width, err := r.sim.Width(column.Name)
+		if err != nil {
+			return err
+		}
+		values = append(values, column.Value(value, width))
+	}
+	return r.write("|" + strings.Join(values, "|") + "|")
+}
+
+// 経過したクロックの数を書く
+// Tickの後（クロックの立ち上がりの後）は「+」を付ける
+func (c *Column) time(time int) string {
+	s := strconv.Itoa(time / 2)
+	if time%2 == 1 {
+		s += "+"
+	}
+	return strings.Repeat(" ", c.Left) + fmt.Sprintf("%-*s", c.length(0), s) + strings.Repeat(" ", c.Right)
+}
+
+// 行を出力し、compare-toで指定されたファイルの同じ行と比較する
+// 期待する行の「*」は、どの文字とも一致する
+func (r *Runner) write(line string) error {
+	r.output = append(r.output, line)
+	if r.expected == nil {
+		return nil
+	}
+
+	n := len(r.output)
+	if n > len(r.expected) {
+		return errors.New(fmt.Sprintf("comparison failure at line %d: got %s, want nothing", n, line))
+	}
+	if !matchLine(line, r.expected[n-1]) {
+		return errors.New(fmt.Sprintf("comparison failure at line %d: got %s, want %s", n, line, r.expected[n-1]))
+	}
+	return nil
+}
+
+func matchLine(got string, want string) bool {
+	got = strings.TrimRight(got, " ")
+	want = strings.TrimRight(want, " ")
+	if len(got) != len(want) {
+		return false
+	}
+	for i := 0; i < len(got); i++ {
+		if want[i] != '*' && got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// output-fileで指定されたファイルに、出力した行を書き出す
+func (r *Runner) WriteOutputFile() error {
+	if r.outputFile == "" {
+		return nil
+	}
+	return errors.WithStack(ioutil.WriteFile(r.outputFile, []byte(strings.Join(r.output, "\n")+"\n"), 0644))
+}
+
+func readLines(filename string) ([]string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer f.Close()
+
+	lines := []string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines = append(lines, strings.TrimRight(scanner.Text(), "\r"))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return lines, nil
+}
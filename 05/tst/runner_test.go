@@ -0,0 +1,250 @@
+package tst
+
+import (
+	"github.com/google/go-cmp/cmp"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// チップを探すディレクトリ
+var chipDirs = []string{"../../05", "../../03/b", "../../03/a", "../../02", "../../01"}
+
+func TestParseScript(t *testing.T) {
+	src := `// コメント
+load And.hdl, /* 複数行の
+コメント */ output-list a%B3.1.3 out%B3.1.3;
+repeat 2 {
+    set a %B1, tick, tock;
+}
+echo "two words"!`
+
+	got, err := ParseScript(src)
+	if err != nil {
+		t.Fatalf("failed ParseScript: %+v", err)
+	}
+	want := []*Command{
+		{Name: "load", Args: []string{"And.hdl"}, Line: 2},
+		{Name: "output-list", Args: []string{"a%B3.1.3", "out%B3.1.3"}, Line: 3},
+		{Name: "repeat", Args: []string{"2"}, Line: 4, Body: []*Command{
+			{Name: "set", Args: []string{"a", "%B1"}, Line: 5},
+			{Name: "tick", Args: []string{}, Line: 5},
+			{Name: "tock", Args: []string{}, Line: 5},
+		}},
+		{Name: "echo", Args: []string{"two words"}, Line: 7},
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("failed ParseScript: diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestParseScriptError(t *testing.T) {
+	cases := []struct {
+		desc string
+		src  string
+		want string
+	}{
+		{desc: "閉じていないブロック", src: "repeat 2 { tick,", want: "line 1: block is not closed"},
+		{desc: "repeat以外のブロック", src: "eval {", want: "line 1: unexpected {"},
+		{desc: "閉じていないコメント", src: "eval,\n/* tick", want: "line 2: comment is not closed"},
+	}
+	for _, tt := range cases {
+		t.Run(tt.desc, func(t *testing.T) {
+			_, err := ParseScript(tt.src)
+			if err == nil {
+				t.Fatalf("failed ParseScript: error is expected")
+			}
+			if diff := cmp.Diff(err.Error(), tt.want); diff != "" {
+				t.Errorf("failed ParseScript: diff (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestColumn(t *testing.T) {
+	cases := []struct {
+		desc   string
+		spec   string
+		width  int
+		value  int
+		header string
+		want   string
+	}{
+		{desc: "1ビットの2進数", spec: "a%B3.1.3", width: 1, value: 1, header: "   a   ", want: "   1   "},
+		{desc: "16ビットの2進数", spec: "out%B1.16.1", width: 16, value: 0x00ff, header: "       out        ", want: " 0000000011111111 "},
+		{desc: "符号付きの10進数", spec: "out%D1.6.1", width: 16, value: 0xffff, header: "  out   ", want: "     -1 "},
+		{desc: "16ビット以外は符号なし", spec: "sel%D2.1.2", width: 3, value: 7, header: " sel ", want: "  7  "},
+		{desc: "16進数", spec: "in%X1.4.1", width: 16, value: 0x0a5f, header: "  in  ", want: " 0A5F "},
+		{desc: "長い名前は切り詰める", spec: "address%B0.3.0", width: 3, value: 5, header: "add", want: "101"},
+		{desc: "書式の省略", spec: "in", width: 4, value: 3, header: "  in  ", want: " 0011 "},
+	}
+	for _, tt := range cases {
+		t.Run(tt.desc, func(t *testing.T) {
+			column, err := ParseColumn(tt.spec)
+			if err != nil {
+				t.Fatalf("failed ParseColumn: %+v", err)
+			}
+			if diff := cmp.Diff(column.Header(tt.width), tt.header); diff != "" {
+				t.Errorf("failed Header: diff (-got +want):\n%s", diff)
+			}
+			if diff := cmp.Diff(column.Value(tt.value, tt.width), tt.want); diff != "" {
+				t.Errorf("failed Value: diff (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseValue(t *testing.T) {
+	cases := []struct {
+		src  string
+		want int
+	}{
+		{src: "5", want: 5},
+		{src: "-1", want: -1},
+		{src: "%B0101", want: 5},
+		{src: "%X0F", want: 15},
+		{src: "%D-32768", want: -32768},
+	}
+	for _, tt := range cases {
+		got, err := ParseValue(tt.src)
+		if err != nil {
+			t.Fatalf("failed ParseValue: %+v", err)
+		}
+		if diff := cmp.Diff(got, tt.want); diff != "" {
+			t.Errorf("failed ParseValue %s: diff (-got +want):\n%s", tt.src, diff)
+		}
+	}
+}
+
+// スクリプトと.cmpファイルを一時ディレクトリに置いて実行する
+func runScript(t *testing.T, script string, expected string) (*Runner, error) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "tst")
+	if err != nil {
+		t.Fatalf("failed TempDir: %+v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"Test.tst": script,
+		"Test.cmp": expected,
+		"Toggle.hdl": `CHIP Toggle {
+    IN enable;
+    OUT out;
+    PARTS:
+    Xor(a=enable, b=state, out=next);
+    DFF(in=next, out=state, out=out);
+}`,
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed WriteFile: %+v", err)
+		}
+	}
+
+	dirs := []string{}
+	for _, d := range chipDirs {
+		abs, err := filepath.Abs(d)
+		if err != nil {
+			t.Fatalf("failed Abs: %+v", err)
+		}
+		dirs = append(dirs, abs)
+	}
+	return RunFile(filepath.Join(dir, "Test.tst"), dirs...)
+}
+
+func TestRunner(t *testing.T) {
+	script := `load Toggle.hdl, compare-to Test.cmp,
+output-list time%S1.4.1 enable%B3.1.3 out%B3.1.3;
+set enable 1, eval, output;
+repeat 2 {
+    tick, output, tock, output;
+}
+set enable 0, tick, tock, output;`
+	expected := `| time |enable |  out  |
+| 0    |   1   |   0   |
+| 0+   |   1   |   0   |
+| 1    |   1   |   1   |
+| 1+   |   1   |   1   |
+| 2    |   1   |   0   |
+| 3    |   0   |   *   |
+`
+	runner, err := runScript(t, script, expected)
+	if err != nil {
+		t.Fatalf("failed RunFile: %+v", err)
+	}
+	want := strings.Split(strings.Replace(expected, "*", "0", 1), "\n")
+	if diff := cmp.Diff(runner.Output(), want[:len(want)-1]); diff != "" {
+		t.Errorf("failed Output: diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestRunnerComparisonFailure(t *testing.T) {
+	script := `load Toggle.hdl, compare-to Test.cmp,
+output-list enable%B3.1.3 out%B3.1.3;
+set enable 1, tick, tock, output;
+tick, tock, output;`
+	expected := `|enable |  out  |
+|   1   |   1   |
+|   1   |   1   |
+`
+	_, err := runScript(t, script, expected)
+	if err == nil {
+		t.Fatalf("failed RunFile: error is expected")
+	}
+	want := "comparison failure at line 3: got |   1   |   0   |, want |   1   |   1   |"
+	if !strings.Contains(err.Error(), want) {
+		t.Errorf("failed RunFile: %q does not contain %q", err.Error(), want)
+	}
+}
+
+// このランナーでは実行できないテストスクリプトと、その理由
+var skipChips = map[string]string{
+	"RAM16K.tst":      "RAM16KをHDLから展開するとメモリが足りない",
+	"Memory.tst":      "RAM16KをHDLから展開するとメモリが足りない",
+	"ComputerAdd.tst": "ROM32K loadと内部状態（ARegister[0]など）の出力に対応していない",
+	"ComputerMax.tst": "ROM32K loadと内部状態（ARegister[0]など）の出力に対応していない",
+}
+
+// リポジトリのチップと同じディレクトリにあるテストスクリプトをすべて実行する
+func TestChips(t *testing.T) {
+	for _, dir := range chipDirs {
+		files, err := filepath.Glob(filepath.Join(dir, "*.tst"))
+		if err != nil {
+			t.Fatalf("failed Glob: %+v", err)
+		}
+		for _, file := range files {
+			t.Run(file, func(t *testing.T) {
+				if reason, ok := skipChips[filepath.Base(file)]; ok {
+					t.Skip(reason)
+				}
+				if _, err := RunFile(file, chipDirs...); err != nil {
+					t.Errorf("failed RunFile: %+v", err)
+				}
+			})
+		}
+	}
+}
+
+func TestRunnerTrace(t *testing.T) {
+	dir, err := filepath.Abs("../../03/a")
+	if err != nil {
+		t.Fatalf("failed Abs: %+v", err)
+	}
+	runner := NewRunner(dir, chipDirs...)
+	runner.Trace("load", "out")
+	if err := runner.Run("load Bit.hdl, set in 1, set load 1, tick, tock, set load 0, tick, tock;"); err != nil {
+		t.Fatalf("failed Run: %+v", err)
+	}
+
+	got := [][]int{}
+	for _, sample := range runner.Tracer().Samples {
+		got = append(got, append([]int{sample.Time}, sample.Values...))
+	}
+	want := [][]int{{0, 0, 0}, {1, 1, 0}, {2, 1, 1}, {3, 0, 1}, {4, 0, 1}}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("failed Trace: diff (-got +want):\n%s", diff)
+	}
+}
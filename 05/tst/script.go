@@ -0,0 +1,171 @@
+// ハードウェアシミュレータのテストスクリプト（.tst）を実行し、結果を.cmpファイルと比較するパッケージ
+//
+//	load And.hdl, output-file And.out, compare-to And.cmp,
+//	output-list a%B3.1.3 b%B3.1.3 out%B3.1.3;
+//	set a 0, set b 0, eval, output;
+//
+// 使えるコマンドは load, output-file, compare-to, output-list, set, eval, tick, tock,
+// output, echo, clear-echo と repeat n { ... }
+package tst
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"strings"
+)
+
+// スクリプトの1つのコマンド
+// repeatの場合は、繰り返すコマンドをBodyに持つ
+type Command struct {
+	Name string
+	Args []string
+	Line int
+	Body []*Command
+}
+
+type scanner struct {
+	src  []rune
+	pos  int
+	line int
+}
+
+func (s *scanner) errorf(format string, args ...interface{}) error {
+	return errors.New(fmt.Sprintf("line %d: %s", s.line, fmt.Sprintf(format, args...)))
+}
+
+func (s *scanner) peek() rune {
+	if s.pos >= len(s.src) {
+		return 0
+	}
+	return s.src[s.pos]
+}
+
+func (s *scanner) hasPrefix(prefix string) bool {
+	return strings.HasPrefix(string(s.src[s.pos:]), prefix)
+}
+
+// 空白とコメントを読み飛ばす
+func (s *scanner) skip() error {
+	for s.pos < len(s.src) {
+		switch {
+		case s.peek() == '\n':
+			s.line++
+			s.pos++
+		case s.peek() == ' ' || s.peek() == '\t' || s.peek() == '\r':
+			s.pos++
+		case s.hasPrefix("//"):
+			for s.pos < len(s.src) && s.peek() != '\n' {
+				s.pos++
+			}
+		case s.hasPrefix("/*"):
+			line := s.line
+			s.pos += 2
+			for !s.hasPrefix("*/") {
+				if s.pos >= len(s.src) {
+					s.line = line
+					return s.errorf("comment is not closed")
+				}
+				if s.peek() == '\n' {
+					s.line++
+				}
+				s.pos++
+			}
+			s.pos += 2
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+// 区切り文字（, ; ! { }）以外の1語
+// ダブルクォートで囲んだ文字列は1語として、クォートを外して返す
+func (s *scanner) word() (string, error) {
+	if s.peek() == '"' {
+		s.pos++
+		start := s.pos
+		for s.pos < len(s.src) && s.peek() != '"' && s.peek() != '\n' {
+			s.pos++
+		}
+		if s.peek() != '"' {
+			return "", s.errorf("string is not closed")
+		}
+		s.pos++
+		return string(s.src[start : s.pos-1]), nil
+	}
+
+	start := s.pos
+	for s.pos < len(s.src) && !strings.ContainsRune(" \t\r\n,;!{}\"", s.peek()) && !s.hasPrefix("//") && !s.hasPrefix("/*") {
+		s.pos++
+	}
+	return string(s.src[start:s.pos]), nil
+}
+
+func ParseScript(src string) ([]*Command, error) {
+	s := &scanner{src: []rune(src), line: 1}
+	commands, err := s.parseCommands(false)
+	if err != nil {
+		return nil, err
+	}
+	return commands, nil
+}
+
+// コマンドを区切り文字まで読み、repeatのブロックは再帰的に読む
+func (s *scanner) parseCommands(block bool) ([]*Command, error) {
+	result := []*Command{}
+	var current *Command
+	for {
+		if err := s.skip(); err != nil {
+			return nil, err
+		}
+
+		switch s.peek() {
+		case 0:
+			if block {
+				return nil, s.errorf("block is not closed")
+			}
+			if current != nil {
+				result = append(result, current)
+			}
+			return result, nil
+		case ',', ';', '!':
+			s.pos++
+			if current != nil {
+				result = append(result, current)
+				current = nil
+			}
+		case '{':
+			if current == nil || current.Name != "repeat" {
+				return nil, s.errorf("unexpected {")
+			}
+			s.pos++
+			body, err := s.parseCommands(true)
+			if err != nil {
+				return nil, err
+			}
+			current.Body = body
+			result = append(result, current)
+			current = nil
+		case '}':
+			if !block {
+				return nil, s.errorf("unexpected }")
+			}
+			s.pos++
+			if current != nil {
+				result = append(result, current)
+			}
+			return result, nil
+		default:
+			line := s.line
+			w, err := s.word()
+			if err != nil {
+				return nil, err
+			}
+			if current == nil {
+				current = &Command{Name: w, Args: []string{}, Line: line}
+			} else {
+				current.Args = append(current.Args, w)
+			}
+		}
+	}
+}
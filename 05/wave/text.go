@@ -0,0 +1,93 @@
+package wave
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/pkg/errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// 端末で読めるテキストの波形を書き出す
+// 1ビットの信号は線で、複数ビットの信号は値が変わった時刻に「|」と10進数の値を書く
+// 時刻は.tstの出力と同じく、Tickの後に「+」を付ける
+//
+//	time  0    0+   1    1+
+//	load  _____/‾‾‾‾‾‾‾‾‾\____
+//	out   |0        |5
+func (t *Tracer) WriteText(w io.Writer) error {
+	times := make([]string, len(t.Samples))
+	cell := 2
+	for i, sample := range t.Samples {
+		times[i] = timeLabel(sample.Time)
+		if len(times[i])+1 > cell {
+			cell = len(times[i]) + 1
+		}
+		for j, signal := range t.Signals {
+			if signal.Width > 1 {
+				if n := len(strconv.Itoa(sample.Values[j])) + 2; n > cell {
+					cell = n
+				}
+			}
+		}
+	}
+	label := len("time")
+	for _, signal := range t.Signals {
+		if len(signal.Name) > label {
+			label = len(signal.Name)
+		}
+	}
+
+	b := bufio.NewWriter(w)
+	row := []string{}
+	for _, time := range times {
+		row = append(row, padRight(time, cell))
+	}
+	fmt.Fprintln(b, strings.TrimRight(padRight("time", label+2)+strings.Join(row, ""), " "))
+
+	for j, signal := range t.Signals {
+		row := []string{}
+		for i, sample := range t.Samples {
+			first := i == 0
+			changed := first || t.Samples[i-1].Values[j] != sample.Values[j]
+			value := sample.Values[j]
+			if signal.Width == 1 {
+				row = append(row, bitCell(value, changed && !first, cell))
+			} else if changed {
+				row = append(row, padRight("|"+strconv.Itoa(value), cell))
+			} else {
+				row = append(row, strings.Repeat(" ", cell))
+			}
+		}
+		fmt.Fprintln(b, strings.TrimRight(padRight(signal.Name, label+2)+strings.Join(row, ""), " "))
+	}
+	return errors.WithStack(b.Flush())
+}
+
+func timeLabel(time int) string {
+	label := strconv.Itoa(time / 2)
+	if time%2 == 1 {
+		label += "+"
+	}
+	return label
+}
+
+func padRight(s string, width int) string {
+	if len(s) >= width {
+		return s
+	}
+	return s + strings.Repeat(" ", width-len(s))
+}
+
+// 値が変わった時刻は、立ち上がりを「/」、立ち下がりを「\」で書く
+func bitCell(value int, changed bool, width int) string {
+	line, edge := "_", "\\"
+	if value == 1 {
+		line, edge = "‾", "/"
+	}
+	if changed {
+		return edge + strings.Repeat(line, width-1)
+	}
+	return strings.Repeat(line, width)
+}
@@ -0,0 +1,85 @@
+// HDLのシミュレーションで、ピンの値の変化を記録するパッケージ
+// 記録した波形は、VCD（Value Change Dump）ファイルか、端末で読めるテキストに書き出す
+//
+// 時刻はSimulator.Timeで、Tick、Tockのたびに1進む（1クロックが2単位）
+package wave
+
+import (
+	"../hdl"
+	"fmt"
+	"github.com/pkg/errors"
+)
+
+// 記録する信号
+type Signal struct {
+	Name  string
+	Width int
+}
+
+// ある時刻のすべての信号の値
+type Sample struct {
+	Time   int
+	Values []int
+}
+
+type Tracer struct {
+	sim     *hdl.Simulator
+	Signals []*Signal
+	Samples []*Sample
+}
+
+// 指定したピンを記録する
+// ピンを指定しない場合は、入力ピン、出力ピン、内部ピンをすべて記録する
+// 記録はシミュレータのEval、Tick、Tockのたびに行う
+func NewTracer(sim *hdl.Simulator, names ...string) (*Tracer, error) {
+	if len(names) == 0 {
+		for _, pin := range sim.Chip().Inputs {
+			names = append(names, pin.Name)
+		}
+		for _, pin := range sim.Chip().Outputs {
+			names = append(names, pin.Name)
+		}
+		names = append(names, sim.Internals()...)
+	}
+
+	t := &Tracer{sim: sim, Signals: []*Signal{}, Samples: []*Sample{}}
+	for _, name := range names {
+		width, err := sim.Width(name)
+		if err != nil {
+			return nil, err
+		}
+		t.Signals = append(t.Signals, &Signal{Name: name, Width: width})
+	}
+	if len(t.Signals) == 0 {
+		return nil, errors.New(fmt.Sprintf("chip %s has no pins to trace", sim.Chip().Name))
+	}
+
+	if err := t.Sample(); err != nil {
+		return nil, err
+	}
+	sim.Observe(func() {
+		// 記録するピンは存在を確認済みなので、エラーにはならない
+		t.Sample()
+	})
+	return t, nil
+}
+
+// 今の値を記録する
+// 同じ時刻に何度も記録した場合は、最後の値だけを残す
+func (t *Tracer) Sample() error {
+	sample := &Sample{Time: t.sim.Time, Values: make([]int, len(t.Signals))}
+	for i, signal := range t.Signals {
+		value, err := t.sim.Get(signal.Name)
+		if err != nil {
+			return err
+		}
+		sample.Values[i] = value
+	}
+
+	if n := len(t.Samples); n > 0 && t.Samples[n-1].Time == sample.Time {
+		t.Samples[n-1] = sample
+		return nil
+	}
+	t.Samples = append(t.Samples, sample)
+	return nil
+}
@@ -0,0 +1,130 @@
+package wave
+
+import (
+	"../hdl"
+	"bytes"
+	"github.com/google/go-cmp/cmp"
+	"testing"
+)
+
+func newTestSimulator(t *testing.T, name string) *hdl.Simulator {
+	t.Helper()
+	loader := hdl.NewLoader("../../05", "../../03/b", "../../03/a", "../../02", "../../01")
+	chip, err := loader.Load(name)
+	if err != nil {
+		t.Fatalf("failed Load: %+v", err)
+	}
+	sim, err := hdl.NewSimulator(loader, chip)
+	if err != nil {
+		t.Fatalf("failed NewSimulator: %+v", err)
+	}
+	return sim
+}
+
+func set(t *testing.T, sim *hdl.Simulator, inputs map[string]int) {
+	t.Helper()
+	for name, value := range inputs {
+		if err := sim.Set(name, value); err != nil {
+			t.Fatalf("failed Set: %+v", err)
+		}
+	}
+}
+
+// PCを2クロックインクリメントして、1クロックでロードする
+func tracePC(t *testing.T) *Tracer {
+	t.Helper()
+	sim := newTestSimulator(t, "PC")
+	tracer, err := NewTracer(sim, "inc", "load", "in", "out")
+	if err != nil {
+		t.Fatalf("failed NewTracer: %+v", err)
+	}
+
+	set(t, sim, map[string]int{"inc": 1})
+	sim.Eval()
+	sim.Step()
+	sim.Step()
+	set(t, sim, map[string]int{"in": 100, "load": 1})
+	sim.Step()
+	return tracer
+}
+
+func TestTracerSamples(t *testing.T) {
+	tracer := tracePC(t)
+	got := []Sample{}
+	for _, sample := range tracer.Samples {
+		got = append(got, *sample)
+	}
+	want := []Sample{
+		{Time: 0, Values: []int{1, 0, 0, 0}},
+		{Time: 1, Values: []int{1, 0, 0, 0}},
+		{Time: 2, Values: []int{1, 0, 0, 1}},
+		{Time: 3, Values: []int{1, 0, 0, 1}},
+		{Time: 4, Values: []int{1, 0, 0, 2}},
+		{Time: 5, Values: []int{1, 1, 100, 2}},
+		{Time: 6, Values: []int{1, 1, 100, 100}},
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("failed Samples: diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestTracerWriteVCD(t *testing.T) {
+	tracer := tracePC(t)
+	var b bytes.Buffer
+	if err := tracer.WriteVCD(&b); err != nil {
+		t.Fatalf("failed WriteVCD: %+v", err)
+	}
+	want := `$version hdltrace $end
+$timescale 1ns $end
+$scope module PC $end
+$var wire 1 ! inc $end
+$var wire 1 " load $end
+$var wire 16 # in [15:0] $end
+$var wire 16 $ out [15:0] $end
+$upscope $end
+$enddefinitions $end
+#0
+$dumpvars
+1!
+0"
+b0000000000000000 #
+b0000000000000000 $
+$end
+#2
+b0000000000000001 $
+#4
+b0000000000000010 $
+#5
+1"
+b0000000001100100 #
+#6
+b0000000001100100 $
+`
+	if diff := cmp.Diff(b.String(), want); diff != "" {
+		t.Errorf("failed WriteVCD: diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestTracerWriteText(t *testing.T) {
+	tracer := tracePC(t)
+	var b bytes.Buffer
+	if err := tracer.WriteText(&b); err != nil {
+		t.Fatalf("failed WriteText: %+v", err)
+	}
+	want := `time  0    0+   1    1+   2    2+   3
+inc   ‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾
+load  _________________________/‾‾‾‾‾‾‾‾‾
+in    |0                       |100
+out   |0        |1        |2        |100
+`
+	if diff := cmp.Diff(b.String(), want); diff != "" {
+		t.Errorf("failed WriteText: diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestVCDIdentifier(t *testing.T) {
+	got := []string{vcdIdentifier(0), vcdIdentifier(93), vcdIdentifier(94), vcdIdentifier(95)}
+	if diff := cmp.Diff(got, []string{"!", "~", "!!", "\"!"}); diff != "" {
+		t.Errorf("failed vcdIdentifier: diff (-got +want):\n%s", diff)
+	}
+}
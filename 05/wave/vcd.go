@@ -0,0 +1,71 @@
+package wave
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/pkg/errors"
+	"io"
+	"strings"
+)
+
+// VCDの識別子は、!から~までの印字可能な文字を94進数のように並べる
+func vcdIdentifier(index int) string {
+	const first, count = '!', '~' - '!' + 1
+	result := ""
+	for {
+		result += string(rune(first + index%count))
+		index /= count
+		if index == 0 {
+			return result
+		}
+		index--
+	}
+}
+
+func vcdValue(value int, width int, id string) string {
+	if width == 1 {
+		return fmt.Sprintf("%d%s", value&1, id)
+	}
+	return fmt.Sprintf("b%0*b %s", width, value, id)
+}
+
+// VCDファイルを書き出す
+// 時刻の単位は1nsとする
+func (t *Tracer) WriteVCD(w io.Writer) error {
+	b := bufio.NewWriter(w)
+	ids := make([]string, len(t.Signals))
+	for i := range t.Signals {
+		ids[i] = vcdIdentifier(i)
+	}
+
+	fmt.Fprintln(b, "$version hdltrace $end")
+	fmt.Fprintln(b, "$timescale 1ns $end")
+	fmt.Fprintf(b, "$scope module %s $end\n", t.sim.Chip().Name)
+	for i, signal := range t.Signals {
+		reference := signal.Name
+		if signal.Width > 1 {
+			reference = fmt.Sprintf("%s [%d:0]", signal.Name, signal.Width-1)
+		}
+		fmt.Fprintf(b, "$var wire %d %s %s $end\n", signal.Width, ids[i], reference)
+	}
+	fmt.Fprintln(b, "$upscope $end")
+	fmt.Fprintln(b, "$enddefinitions $end")
+
+	for i, sample := range t.Samples {
+		changes := []string{}
+		for j, signal := range t.Signals {
+			if i == 0 || t.Samples[i-1].Values[j] != sample.Values[j] {
+				changes = append(changes, vcdValue(sample.Values[j], signal.Width, ids[j]))
+			}
+		}
+
+		// 最初の時刻はすべての値を$dumpvarsで書き、それ以降は値が変わった時刻だけを書く
+		switch {
+		case i == 0:
+			fmt.Fprintf(b, "#%d\n$dumpvars\n%s\n$end\n", sample.Time, strings.Join(changes, "\n"))
+		case len(changes) > 0:
+			fmt.Fprintf(b, "#%d\n%s\n", sample.Time, strings.Join(changes, "\n"))
+		}
+	}
+	return errors.WithStack(b.Flush())
+}
@@ -0,0 +1,18 @@
+package assembler
+
+// アセンブリの行をHackの機械語（16桁の2進数の文字列）に変換する
+// 変換に使ったシンボルテーブルも返すので、ラベルや変数のアドレスを調べられる
+func Assemble(lines []*string) ([]string, *SymbolTable, error) {
+	symbolTable := NewSymbolTable()
+	commands := NewParser(lines, symbolTable).Parse()
+
+	assembled := []string{}
+	for _, command := range commands {
+		line, err := command.Assemble()
+		if err != nil {
+			return nil, nil, err
+		}
+		assembled = append(assembled, line)
+	}
+	return assembled, symbolTable, nil
+}
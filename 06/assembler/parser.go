@@ -1,7 +1,9 @@
-package main
+package assembler
 
 import (
 	"fmt"
+	"io"
+	"io/ioutil"
 	"strconv"
 	"strings"
 )
@@ -10,10 +12,16 @@ type Parser struct {
 	raw         []*string
 	symbolTable *SymbolTable
 	nextAddress int
+	log         io.Writer
 }
 
 func NewParser(raw []*string, symbolTable *SymbolTable) *Parser {
-	return &Parser{raw: raw, symbolTable: symbolTable, nextAddress: 0}
+	return &Parser{raw: raw, symbolTable: symbolTable, nextAddress: 0, log: ioutil.Discard}
+}
+
+// パースしたコマンドの経過を書き出す先（デフォルトでは書き出さない）
+func (p *Parser) SetLog(log io.Writer) {
+	p.log = log
 }
 
 func (p *Parser) Parse() []Command {
@@ -52,19 +60,19 @@ func (p *Parser) parseLine(line string) Command {
 	// ラベルシンボルを見つけたら、シンボルテーブルに追加
 	if prefix == '(' {
 		symbol := trimmed[1 : len(trimmed)-1]
-		p.symbolTable.AddEntry(symbol, p.nextAddress)
-		fmt.Printf("parse LCommand: %s, symbol: %s, address: %d\n", trimmed, symbol, p.symbolTable.Address(symbol))
+		p.symbolTable.AddLabel(symbol, p.nextAddress)
+		fmt.Fprintf(p.log, "parse LCommand: %s, symbol: %s, address: %d\n", trimmed, symbol, p.symbolTable.Address(symbol))
 		return nil
 	}
 
 	// AコマンドとCコマンドをパース
 	address := p.nextAddress
 	if prefix == '@' {
-		fmt.Printf("parse ACommand[%d]: %s\n", address, trimmed)
+		fmt.Fprintf(p.log, "parse ACommand[%d]: %s\n", address, trimmed)
 		p.incrementAddress()
 		return &ACommand{mnemonic: trimmed, address: address, symbolTable: p.symbolTable}
 	} else {
-		fmt.Printf("parse CCommand[%d]: %s\n", address, trimmed)
+		fmt.Fprintf(p.log, "parse CCommand[%d]: %s\n", address, trimmed)
 		p.incrementAddress()
 		return &CCommand{mnemonic: trimmed, dest: "", comp: "", jump: "", address: address}
 	}
@@ -78,7 +86,7 @@ type CCommand struct {
 	address  int
 }
 
-func (c *CCommand) assemble() (string, error) {
+func (c *CCommand) Assemble() (string, error) {
 	c.parseMnemonic()
 
 	dest := c.assembleDest()
@@ -119,6 +127,13 @@ func (c *CCommand) assembleComp() string {
 		"D&M": "1000000",
 		"D|A": "0010101",
 		"D|M": "1010101",
+		// 加算と論理演算は順序を入れ替えた表記も受け付ける
+		"A+D": "0000010",
+		"M+D": "1000010",
+		"A&D": "0000000",
+		"M&D": "1000000",
+		"A|D": "0010101",
+		"M|D": "1010101",
 	}
 	return compMap[c.comp]
 }
@@ -192,7 +207,7 @@ type ACommand struct {
 	symbolTable *SymbolTable
 }
 
-func (a *ACommand) assemble() (string, error) {
+func (a *ACommand) Assemble() (string, error) {
 	withoutPrefix := a.mnemonic[1:]
 
 	// 数値か判定し、数値じゃなければ変数シンボルなのでアドレスに変換
@@ -208,5 +223,5 @@ func (a *ACommand) assemble() (string, error) {
 }
 
 type Command interface {
-	assemble() (string, error)
+	Assemble() (string, error)
 }
@@ -0,0 +1,70 @@
+package assembler
+
+import "fmt"
+
+type SymbolTable struct {
+	entries     map[string]int
+	labels      map[string]int // ラベルシンボルとROMアドレスの対応
+	nextAddress int
+}
+
+const InitialAddress = 16
+
+func NewSymbolTable() *SymbolTable {
+	entries := map[string]int{}
+	entries["SP"] = 0
+	entries["LCL"] = 1
+	entries["ARG"] = 2
+	entries["THIS"] = 3
+	entries["THAT"] = 4
+	entries["SCREEN"] = 16384
+	entries["KBD"] = 24576
+
+	for i := 0; i < 16; i++ {
+		key := fmt.Sprintf("R%d", i)
+		entries[key] = i
+	}
+
+	return &SymbolTable{entries: entries, labels: map[string]int{}, nextAddress: InitialAddress}
+}
+
+func (s *SymbolTable) AddEntry(symbol string, address int) {
+	s.entries[symbol] = address
+}
+
+// ラベルシンボルを追加する
+// ラベルはROMのアドレスを指すので、変数とは別にも記録しておく
+func (s *SymbolTable) AddLabel(symbol string, address int) {
+	s.AddEntry(symbol, address)
+	s.labels[symbol] = address
+}
+
+func (s *SymbolTable) AddVariableEntry(symbol string) int {
+	s.entries[symbol] = s.nextAddress
+	s.nextAddress += 1
+	return s.entries[symbol]
+}
+
+func (s *SymbolTable) Address(symbol string) int {
+	result, ok := s.entries[symbol]
+	if !ok {
+		result = s.AddVariableEntry(symbol)
+	}
+	return result
+}
+
+// シンボルのアドレスを返す
+// Addressと違い、見つからない場合に変数として追加しない
+func (s *SymbolTable) Lookup(symbol string) (int, bool) {
+	result, ok := s.entries[symbol]
+	return result, ok
+}
+
+// ラベルシンボルとROMアドレスの対応
+func (s *SymbolTable) Labels() map[string]int {
+	labels := map[string]int{}
+	for symbol, address := range s.labels {
+		labels[symbol] = address
+	}
+	return labels
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"./assembler"
 	"bufio"
 	"fmt"
 	"log"
@@ -15,15 +16,16 @@ func run() error {
 		return err
 	}
 
-	symbolTable := NewSymbolTable()
+	symbolTable := assembler.NewSymbolTable()
 
-	parser := NewParser(lines, symbolTable)
+	parser := assembler.NewParser(lines, symbolTable)
+	parser.SetLog(os.Stdout)
 	commands := parser.Parse()
 	fmt.Printf("%#v\n", symbolTable)
 
 	var assembledLines []*string
 	for _, command := range commands {
-		assembled, err := command.assemble()
+		assembled, err := command.Assemble()
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,171 @@
+// 生成したXMLと期待するXML（フィクスチャ）を比較するパッケージ
+// テキストの差分ではなくツリーとして比較し、最初に見つかった違いの場所を要素のパス（class/subroutineDec[2]/subroutineBody）で返す
+//
+// 要素の間の空白は無視する
+// トークンの要素（<stringConstant>など）の中の空白は、トークンの一部なので無視しない
+package compare
+
+import (
+	"encoding/xml"
+	"fmt"
+	"github.com/pkg/errors"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// 最初に見つかった違い
+type Difference struct {
+	Path string // 要素のパス
+	Got  string
+	Want string
+}
+
+func (d *Difference) String() string {
+	return fmt.Sprintf("%s: got %s, want %s", d.Path, d.Got, d.Want)
+}
+
+// 違いがない場合はnilを返す
+func CompareFiles(gotFile string, wantFile string) (*Difference, error) {
+	got, err := os.Open(gotFile)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer got.Close()
+
+	want, err := os.Open(wantFile)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer want.Close()
+
+	diff, err := CompareXML(got, want)
+	if err != nil {
+		return nil, errors.WithMessage(err, fmt.Sprintf("%s %s", gotFile, wantFile))
+	}
+	return diff, nil
+}
+
+// XMLの要素
+// トークンの要素以外では、要素の間の空白とテキストの前後の空白は捨てる
+type node struct {
+	name     string
+	text     string
+	children []*node
+}
+
+// テキストをそのまま比較するトークンの要素
+var tokenElements = map[string]bool{
+	"keyword":         true,
+	"symbol":          true,
+	"integerConstant": true,
+	"stringConstant":  true,
+	"identifier":      true,
+}
+
+func parseXML(r io.Reader) (*node, error) {
+	decoder := xml.NewDecoder(r)
+	root := &node{}
+	stack := []*node{root}
+	for {
+		t, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		current := stack[len(stack)-1]
+		switch e := t.(type) {
+		case xml.StartElement:
+			child := &node{name: e.Name.Local, children: []*node{}}
+			current.children = append(current.children, child)
+			stack = append(stack, child)
+		case xml.EndElement:
+			if !tokenElements[current.name] {
+				current.text = strings.TrimSpace(current.text)
+			}
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			current.text += string(e)
+		}
+	}
+
+	if len(root.children) != 1 {
+		return nil, errors.New(fmt.Sprintf("root element must be exactly one: %d", len(root.children)))
+	}
+	return root.children[0], nil
+}
+
+func CompareXML(got io.Reader, want io.Reader) (*Difference, error) {
+	gotRoot, err := parseXML(got)
+	if err != nil {
+		return nil, errors.WithMessage(err, "got")
+	}
+	wantRoot, err := parseXML(want)
+	if err != nil {
+		return nil, errors.WithMessage(err, "want")
+	}
+
+	if gotRoot.name != wantRoot.name {
+		return &Difference{Path: wantRoot.name, Got: describe(gotRoot), Want: describe(wantRoot)}, nil
+	}
+	return compareNode(wantRoot.name, gotRoot, wantRoot), nil
+}
+
+// 子要素を先頭から順に比較し、文書順で最初の違いを返す
+func compareNode(path string, got *node, want *node) *Difference {
+	if got.text != want.text {
+		return &Difference{Path: path, Got: strconv.Quote(got.text), Want: strconv.Quote(want.text)}
+	}
+
+	for i := 0; i < len(got.children) || i < len(want.children); i++ {
+		switch {
+		case i >= len(want.children):
+			// 余分な要素がある
+			return &Difference{Path: childPath(path, got, i), Got: describe(got.children[i]), Want: "nothing"}
+		case i >= len(got.children):
+			// 要素が足りない
+			return &Difference{Path: childPath(path, want, i), Got: "nothing", Want: describe(want.children[i])}
+		case got.children[i].name != want.children[i].name:
+			return &Difference{Path: childPath(path, want, i), Got: describe(got.children[i]), Want: describe(want.children[i])}
+		}
+
+		if diff := compareNode(childPath(path, want, i), got.children[i], want.children[i]); diff != nil {
+			return diff
+		}
+	}
+	return nil
+}
+
+// 同じ名前の兄弟要素が複数ある場合だけ、1から始まる番号を付ける
+func childPath(path string, parent *node, index int) string {
+	name := parent.children[index].name
+	count, position := 0, 0
+	for i, child := range parent.children {
+		if child.name != name {
+			continue
+		}
+		count++
+		if i == index {
+			position = count
+		}
+	}
+
+	if count == 1 {
+		return path + "/" + name
+	}
+	return fmt.Sprintf("%s/%s[%d]", path, name, position)
+}
+
+func describe(n *node) string {
+	switch {
+	case tokenElements[n.name]:
+		return fmt.Sprintf("<%s>%s</%s>", n.name, n.text, n.name)
+	case n.text == "":
+		return fmt.Sprintf("<%s>", n.name)
+	}
+	return fmt.Sprintf("<%s> %s </%s>", n.name, n.text, n.name)
+}
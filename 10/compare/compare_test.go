@@ -0,0 +1,118 @@
+package compare
+
+import (
+	"github.com/google/go-cmp/cmp"
+	"strings"
+	"testing"
+)
+
+func TestCompareXML(t *testing.T) {
+	want := `<class>
+  <keyword> class </keyword>
+  <subroutineDec>
+    <identifier> a </identifier>
+  </subroutineDec>
+  <subroutineDec>
+    <identifier> b </identifier>
+    <subroutineBody>
+      <statements>
+        <letStatement>
+          <symbol> &lt; </symbol>
+        </letStatement>
+        <letStatement>
+          <identifier> x </identifier>
+        </letStatement>
+      </statements>
+    </subroutineBody>
+  </subroutineDec>
+</class>`
+
+	cases := []struct {
+		desc string
+		got  string
+		want *Difference
+	}{
+		{
+			desc: "要素の間の空白と改行の違いは無視する",
+			got:  "<class><keyword> class </keyword><subroutineDec><identifier> a </identifier></subroutineDec><subroutineDec>\n\t<identifier> b </identifier><subroutineBody>  <statements><letStatement><symbol> &lt; </symbol></letStatement><letStatement><identifier> x </identifier></letStatement></statements></subroutineBody></subroutineDec></class>",
+			want: nil,
+		},
+		{
+			desc: "テキストの違いは要素のパスで示す",
+			got:  strings.Replace(want, "<identifier> x </identifier>", "<identifier> y </identifier>", 1),
+			want: &Difference{Path: "class/subroutineDec[2]/subroutineBody/statements/letStatement[2]/identifier", Got: `" y "`, Want: `" x "`},
+		},
+		{
+			desc: "トークンの要素の中の空白は無視しない",
+			got:  strings.Replace(want, "<identifier> x </identifier>", "<identifier>x</identifier>", 1),
+			want: &Difference{Path: "class/subroutineDec[2]/subroutineBody/statements/letStatement[2]/identifier", Got: `"x"`, Want: `" x "`},
+		},
+		{
+			desc: "要素名の違い",
+			got:  strings.Replace(want, "<symbol> &lt; </symbol>", "<keyword> &lt; </keyword>", 1),
+			want: &Difference{Path: "class/subroutineDec[2]/subroutineBody/statements/letStatement[1]/symbol", Got: "<keyword> < </keyword>", Want: "<symbol> < </symbol>"},
+		},
+		{
+			desc: "要素が足りない",
+			got:  strings.Replace(want, "<identifier> a </identifier>", "", 1),
+			want: &Difference{Path: "class/subroutineDec[1]/identifier", Got: "nothing", Want: "<identifier> a </identifier>"},
+		},
+		{
+			desc: "余分な要素がある",
+			got:  strings.Replace(want, "</class>", "<symbol> } </symbol></class>", 1),
+			want: &Difference{Path: "class/symbol", Got: "<symbol> } </symbol>", Want: "nothing"},
+		},
+		{
+			desc: "ルート要素の違い",
+			got:  "<tokens></tokens>",
+			want: &Difference{Path: "class", Got: "<tokens>", Want: "<class>"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := CompareXML(strings.NewReader(tc.got), strings.NewReader(want))
+			if err != nil {
+				t.Fatalf("failed CompareXML: %+v", err)
+			}
+			if diff := cmp.Diff(got, tc.want); diff != "" {
+				t.Errorf("failed CompareXML: diff (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCompareXMLInvalid(t *testing.T) {
+	if _, err := CompareXML(strings.NewReader("<class>"), strings.NewReader("<class></class>")); err == nil {
+		t.Errorf("failed CompareXML: error is expected for broken XML")
+	}
+}
+
+// 文字列定値の中の空白は文字列の一部
+func TestCompareXMLStringConstant(t *testing.T) {
+	got := "<expression>\n  <stringConstant> a  b </stringConstant>\n</expression>"
+	want := "<expression><stringConstant> a b </stringConstant></expression>"
+
+	diff, err := CompareXML(strings.NewReader(got), strings.NewReader(want))
+	if err != nil {
+		t.Fatalf("failed CompareXML: %+v", err)
+	}
+	wantDiff := &Difference{Path: "expression/stringConstant", Got: `" a  b "`, Want: `" a b "`}
+	if d := cmp.Diff(diff, wantDiff); d != "" {
+		t.Errorf("failed CompareXML: diff (-got +want):\n%s", d)
+	}
+}
+
+func TestCompareFiles(t *testing.T) {
+	got, err := CompareFiles("../Fixture/Square/cmp/Square.xml", "../Fixture/Square/cmp/Square.xml")
+	if err != nil {
+		t.Fatalf("failed CompareFiles: %+v", err)
+	}
+	if got != nil {
+		t.Errorf("failed CompareFiles: %s", got.String())
+	}
+
+	if _, err := CompareFiles("../Fixture/Square/cmp/Square.xml", "../Fixture/Square/cmp/NotFound.xml"); err == nil {
+		t.Errorf("failed CompareFiles: error is expected for missing file")
+	}
+}
@@ -1,10 +1,8 @@
 package main
 
 import (
-	"bufio"
-	"github.com/google/go-cmp/cmp"
+	"./compare"
 	"os"
-	"strings"
 	"testing"
 )
 
@@ -106,23 +104,13 @@ func TestIntegratorIntegrate(t *testing.T) {
 			integrator.Integrate()
 
 			for i, dest := range tc.destTokenizedXML {
-				got := readFileQuietly(dest)
-				want := readFileQuietly(tc.wantTokenizedXML[i])
-
-				if diff := cmp.Diff(got, want); diff != "" {
-					t.Errorf("failed: diff %s %s: (-got +want):\n%s\n", dest, tc.wantTokenizedXML[i], diff)
-				} else {
+				if compareFixture(t, dest, tc.wantTokenizedXML[i]) {
 					os.Remove(dest)
 				}
 			}
 
 			for i, dest := range tc.destParsedXML {
-				got := readFileQuietly(dest)
-				want := readFileQuietly(tc.wantParsedXML[i])
-
-				if diff := cmp.Diff(got, want); diff != "" {
-					t.Errorf("failed: diff %s %s: (-got +want):\n%s\n", dest, tc.wantTokenizedXML[i], diff)
-				} else {
+				if compareFixture(t, dest, tc.wantParsedXML[i]) {
 					os.Remove(dest)
 				}
 			}
@@ -157,37 +145,30 @@ func TestIntegratorIntegrateFile(t *testing.T) {
 			integrator := NewIntegrator([]string{})
 			integrator.integrateFile(tc.src)
 
-			gotTokenizedXML := readFileQuietly(tc.destTokenizedXML)
-			wantTokenizedXML := readFileQuietly(tc.wantTokenizedXML)
-
-			if diff := cmp.Diff(gotTokenizedXML, wantTokenizedXML); diff != "" {
-				t.Errorf("failed: diff %s %s: (-got +want):\n%s\n", tc.destTokenizedXML, tc.wantTokenizedXML, diff)
-			} else {
+			if compareFixture(t, tc.destTokenizedXML, tc.wantTokenizedXML) {
 				os.Remove(tc.destTokenizedXML)
 			}
 
-			gotParsedXML := readFileQuietly(tc.destParsedXML)
-			wantParsedXML := readFileQuietly(tc.wantParsedXML)
-
-			if diff := cmp.Diff(gotParsedXML, wantParsedXML); diff != "" {
-				t.Errorf("failed: diff %s %s: (-got +want):\n%s\n", tc.destParsedXML, tc.wantParsedXML, diff)
-			} else {
+			if compareFixture(t, tc.destParsedXML, tc.wantParsedXML) {
 				os.Remove(tc.destParsedXML)
 			}
 		})
 	}
 }
 
-func readFileQuietly(filename string) []string {
-	file, _ := os.Open(filename)
-	defer file.Close()
-
-	scanner := bufio.NewScanner(file)
+// 生成したファイルとフィクスチャを構造で比較する
+// 一致した場合はtrueを返す
+func compareFixture(t *testing.T, got string, want string) bool {
+	t.Helper()
 
-	lines := []string{}
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		lines = append(lines, line)
+	diff, err := compare.CompareFiles(got, want)
+	if err != nil {
+		t.Errorf("failed: compare %s %s: %v", got, want, err)
+		return false
+	}
+	if diff != nil {
+		t.Errorf("failed: diff %s %s: %s", got, want, diff.String())
+		return false
 	}
-	return lines
+	return true
 }
@@ -1,36 +1,96 @@
 package main
 
 import (
+	"./parsing"
+	"flag"
+	"fmt"
+	"github.com/pkg/errors"
 	"path/filepath"
 	"strings"
 )
 
 // コマンドの入力パラメータをパースして、変換対象のvmファイル名を管理
 type Arg struct {
-	raw   string
-	files []string
+	raw               string
+	files             []string
+	foldConstants     bool
+	optimizationLevel int
+	extended          bool
+	precedence        bool
+	json              bool
+	debugInfo         bool
 }
 
 const DefaultArg = "Fixture/Manual/"
 
-func NewArg(args []string) *Arg {
-	arg := DefaultArg
-	if len(args) >= 2 {
-		arg = args[1]
+// -fold-constants を指定すると、リテラルだけからなる式をコンパイル時に計算する
+const FoldConstantsOption = "fold-constants"
+
+// -extended を指定すると、for文、break文、continue文、else ifを使える拡張文法でコンパイルする
+const ExtendedOption = "extended"
+
+// -precedence を指定すると、二項演算子を優先順位に従って計算する
+const PrecedenceOption = "precedence"
+
+// -json を指定すると、位置とシンボルを含むASTをjsonファイルに出力する
+const JSONOption = "json"
+
+// -g を指定すると、デバッガ向けにソースの行と変数名の情報をjsonファイルに出力する
+const DebugInfoOption = "g"
+
+// -O1 のように最適化レベルを指定する（省略時は-O0で最適化しない）
+// 複数指定した場合は一番高いレベルを使う
+const OptimizeOption = "O"
+
+// 未知のオプションや、2つ以上のファイルの指定はエラーにする
+func NewArg(args []string) (*Arg, error) {
+	flags := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	foldConstants := flags.Bool(FoldConstantsOption, false, "リテラルだけからなる式をコンパイル時に計算する")
+	extended := flags.Bool(ExtendedOption, false, "拡張文法でコンパイルする")
+	precedence := flags.Bool(PrecedenceOption, false, "二項演算子を優先順位に従って計算する")
+	json := flags.Bool(JSONOption, false, "ASTをjsonファイルに出力する")
+	debugInfo := flags.Bool(DebugInfoOption, false, "デバッグ情報をjsonファイルに出力する")
+	levels := []*bool{}
+	for level := parsing.OptimizeNone; level <= parsing.OptimizeSpeed; level++ {
+		levels = append(levels, flags.Bool(fmt.Sprintf("%s%d", OptimizeOption, level), false, fmt.Sprintf("最適化レベル%dでコンパイルする", level)))
+	}
+	if err := flags.Parse(args[1:]); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if flags.NArg() > 1 {
+		return nil, errors.New(fmt.Sprintf("error NewArg: expected one file or directory: got = %s", strings.Join(flags.Args(), " ")))
+	}
+
+	result := &Arg{
+		raw:           DefaultArg,
+		foldConstants: *foldConstants,
+		extended:      *extended,
+		precedence:    *precedence,
+		json:          *json,
+		debugInfo:     *debugInfo,
+	}
+	for level, enabled := range levels {
+		if *enabled {
+			result.optimizationLevel = level
+		}
+	}
+	if flags.NArg() == 1 {
+		result.raw = flags.Arg(0)
 	}
 
-	if filepath.Ext(arg) == ".jack" {
-		return &Arg{raw: arg, files: []string{arg}}
+	if filepath.Ext(result.raw) == ".jack" {
+		result.files = []string{result.raw}
+		return result, nil
 	}
 
 	// jackファイルを指定していない場合は、ディレクトリが指定されたとみなす
 	// ただしファイル名にIgnoreが含まれる場合は除外する
-	files, _ := filepath.Glob(arg + "/*.jack")
-	ignoreTestFiles := []string{}
+	files, _ := filepath.Glob(result.raw + "/*.jack")
+	result.files = []string{}
 	for _, file := range files {
 		if !strings.Contains(file, "Ignore") {
-			ignoreTestFiles = append(ignoreTestFiles, file)
+			result.files = append(result.files, file)
 		}
 	}
-	return &Arg{raw: arg, files: ignoreTestFiles}
+	return result, nil
 }
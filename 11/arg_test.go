@@ -29,10 +29,124 @@ func TestNewArg(t *testing.T) {
 
 	for _, tc := range cases {
 		t.Run(tc.desc, func(t *testing.T) {
-			arg := NewArg(tc.args)
+			arg, err := NewArg(tc.args)
+			if err != nil {
+				t.Fatalf("failed NewArg: %+v", err)
+			}
 			if diff := cmp.Diff(arg.files, tc.want); diff != "" {
 				t.Errorf("failed arg.files: diff (-got +want):\n%s", diff)
 			}
 		})
 	}
 }
+
+func TestNewArgDoubleDash(t *testing.T) {
+	arg, err := NewArg([]string{"dummy", "--json", "-O0", "-O1", "foo.jack"})
+	if err != nil {
+		t.Fatalf("failed NewArg: %+v", err)
+	}
+	if !arg.json {
+		t.Errorf("failed arg.json: got = false")
+	}
+	if arg.optimizationLevel != 1 {
+		t.Errorf("failed arg.optimizationLevel: got = %d, want = 1", arg.optimizationLevel)
+	}
+}
+
+func TestNewArgFoldConstants(t *testing.T) {
+	arg, err := NewArg([]string{"dummy", "-fold-constants", "foo.jack"})
+	if err != nil {
+		t.Fatalf("failed NewArg: %+v", err)
+	}
+	if !arg.foldConstants {
+		t.Errorf("failed arg.foldConstants: got = false")
+	}
+	if diff := cmp.Diff(arg.files, []string{"foo.jack"}); diff != "" {
+		t.Errorf("failed arg.files: diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestNewArgOptimize(t *testing.T) {
+	arg, err := NewArg([]string{"dummy", "-O1", "foo.jack"})
+	if err != nil {
+		t.Fatalf("failed NewArg: %+v", err)
+	}
+	if arg.optimizationLevel != 1 {
+		t.Errorf("failed arg.optimizationLevel: got = %d, want = 1", arg.optimizationLevel)
+	}
+	if diff := cmp.Diff(arg.files, []string{"foo.jack"}); diff != "" {
+		t.Errorf("failed arg.files: diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestNewArgExtended(t *testing.T) {
+	arg, err := NewArg([]string{"dummy", "-extended", "foo.jack"})
+	if err != nil {
+		t.Fatalf("failed NewArg: %+v", err)
+	}
+	if !arg.extended {
+		t.Errorf("failed arg.extended: got = false")
+	}
+	if diff := cmp.Diff(arg.files, []string{"foo.jack"}); diff != "" {
+		t.Errorf("failed arg.files: diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestNewArgPrecedence(t *testing.T) {
+	arg, err := NewArg([]string{"dummy", "-precedence", "foo.jack"})
+	if err != nil {
+		t.Fatalf("failed NewArg: %+v", err)
+	}
+	if !arg.precedence {
+		t.Errorf("failed arg.precedence: got = false")
+	}
+	if diff := cmp.Diff(arg.files, []string{"foo.jack"}); diff != "" {
+		t.Errorf("failed arg.files: diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestNewArgJSON(t *testing.T) {
+	arg, err := NewArg([]string{"dummy", "-json", "foo.jack"})
+	if err != nil {
+		t.Fatalf("failed NewArg: %+v", err)
+	}
+	if !arg.json {
+		t.Errorf("failed arg.json: got = false")
+	}
+	if diff := cmp.Diff(arg.files, []string{"foo.jack"}); diff != "" {
+		t.Errorf("failed arg.files: diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestNewArgDebugInfo(t *testing.T) {
+	arg, err := NewArg([]string{"dummy", "-g", "foo.jack"})
+	if err != nil {
+		t.Fatalf("failed NewArg: %+v", err)
+	}
+	if !arg.debugInfo {
+		t.Errorf("failed arg.debugInfo: got = false")
+	}
+	if diff := cmp.Diff(arg.files, []string{"foo.jack"}); diff != "" {
+		t.Errorf("failed arg.files: diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestNewArgError(t *testing.T) {
+	cases := []struct {
+		desc string
+		args []string
+	}{
+		{desc: "綴りの間違ったオプション", args: []string{"dummy", "-extnded", "foo.jack"}},
+		{desc: "数ではない最適化レベル", args: []string{"dummy", "-Ox", "foo.jack"}},
+		{desc: "存在しない最適化レベル", args: []string{"dummy", "-O9", "foo.jack"}},
+		{desc: "2つ以上のファイル", args: []string{"dummy", "foo.jack", "bar.jack"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			if _, err := NewArg(tc.args); err == nil {
+				t.Errorf("failed NewArg: expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,241 @@
+// 生成したファイルと期待するファイル（フィクスチャ）を比較するパッケージ
+// テキストの差分ではなく構造で比較し、最初に見つかった違いの場所を返す
+//
+//   - XMLはツリーとして読み込み、要素のパス（class/subroutineDec[2]/subroutineBody）で場所を示す
+//   - VMコードやアセンブリ（.vm, .asm, .cmp）は、コメントと空行を除いた命令の並びとして比較し、行番号で場所を示す
+//
+// XMLでは要素の間の空白を、行単位の比較では各行の前後の空白を無視する
+// トークンの要素（<stringConstant>など）の中の空白は、トークンの一部なので無視しない
+package compare
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"github.com/pkg/errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// 最初に見つかった違い
+type Difference struct {
+	Path string // XMLでは要素のパス、行単位の比較では「line 3」のような期待する側の行番号（余分な行の場合は生成した側）
+	Got  string
+	Want string
+}
+
+func (d *Difference) String() string {
+	return fmt.Sprintf("%s: got %s, want %s", d.Path, d.Got, d.Want)
+}
+
+// 拡張子がxmlのファイルはXMLとして、それ以外は行単位で比較する
+// 違いがない場合はnilを返す
+func CompareFiles(gotFile string, wantFile string) (*Difference, error) {
+	got, err := os.Open(gotFile)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer got.Close()
+
+	want, err := os.Open(wantFile)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer want.Close()
+
+	if filepath.Ext(wantFile) == ".xml" {
+		diff, err := CompareXML(got, want)
+		if err != nil {
+			return nil, errors.WithMessage(err, fmt.Sprintf("%s %s", gotFile, wantFile))
+		}
+		return diff, nil
+	}
+
+	gotLines, err := readLines(got)
+	if err != nil {
+		return nil, err
+	}
+	wantLines, err := readLines(want)
+	if err != nil {
+		return nil, err
+	}
+	return CompareLines(gotLines, wantLines), nil
+}
+
+func readLines(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	lines := []string{}
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return lines, nil
+}
+
+// XMLの要素
+// トークンの要素以外では、要素の間の空白とテキストの前後の空白は捨てる
+type node struct {
+	name     string
+	text     string
+	children []*node
+}
+
+// テキストをそのまま比較するトークンの要素
+var tokenElements = map[string]bool{
+	"keyword":         true,
+	"symbol":          true,
+	"integerConstant": true,
+	"stringConstant":  true,
+	"charConstant":    true,
+	"identifier":      true,
+}
+
+func parseXML(r io.Reader) (*node, error) {
+	decoder := xml.NewDecoder(r)
+	root := &node{}
+	stack := []*node{root}
+	for {
+		t, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		current := stack[len(stack)-1]
+		switch e := t.(type) {
+		case xml.StartElement:
+			child := &node{name: e.Name.Local, children: []*node{}}
+			current.children = append(current.children, child)
+			stack = append(stack, child)
+		case xml.EndElement:
+			if !tokenElements[current.name] {
+				current.text = strings.TrimSpace(current.text)
+			}
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			current.text += string(e)
+		}
+	}
+
+	if len(root.children) != 1 {
+		return nil, errors.New(fmt.Sprintf("root element must be exactly one: %d", len(root.children)))
+	}
+	return root.children[0], nil
+}
+
+func CompareXML(got io.Reader, want io.Reader) (*Difference, error) {
+	gotRoot, err := parseXML(got)
+	if err != nil {
+		return nil, errors.WithMessage(err, "got")
+	}
+	wantRoot, err := parseXML(want)
+	if err != nil {
+		return nil, errors.WithMessage(err, "want")
+	}
+
+	if gotRoot.name != wantRoot.name {
+		return &Difference{Path: wantRoot.name, Got: describe(gotRoot), Want: describe(wantRoot)}, nil
+	}
+	return compareNode(wantRoot.name, gotRoot, wantRoot), nil
+}
+
+// 子要素を先頭から順に比較し、文書順で最初の違いを返す
+func compareNode(path string, got *node, want *node) *Difference {
+	if got.text != want.text {
+		return &Difference{Path: path, Got: strconv.Quote(got.text), Want: strconv.Quote(want.text)}
+	}
+
+	for i := 0; i < len(got.children) || i < len(want.children); i++ {
+		switch {
+		case i >= len(want.children):
+			// 余分な要素がある
+			return &Difference{Path: childPath(path, got, i), Got: describe(got.children[i]), Want: "nothing"}
+		case i >= len(got.children):
+			// 要素が足りない
+			return &Difference{Path: childPath(path, want, i), Got: "nothing", Want: describe(want.children[i])}
+		case got.children[i].name != want.children[i].name:
+			return &Difference{Path: childPath(path, want, i), Got: describe(got.children[i]), Want: describe(want.children[i])}
+		}
+
+		if diff := compareNode(childPath(path, want, i), got.children[i], want.children[i]); diff != nil {
+			return diff
+		}
+	}
+	return nil
+}
+
+// 同じ名前の兄弟要素が複数ある場合だけ、1から始まる番号を付ける
+func childPath(path string, parent *node, index int) string {
+	name := parent.children[index].name
+	count, position := 0, 0
+	for i, child := range parent.children {
+		if child.name != name {
+			continue
+		}
+		count++
+		if i == index {
+			position = count
+		}
+	}
+
+	if count == 1 {
+		return path + "/" + name
+	}
+	return fmt.Sprintf("%s/%s[%d]", path, name, position)
+}
+
+func describe(n *node) string {
+	switch {
+	case tokenElements[n.name]:
+		return fmt.Sprintf("<%s>%s</%s>", n.name, n.text, n.name)
+	case n.text == "":
+		return fmt.Sprintf("<%s>", n.name)
+	}
+	return fmt.Sprintf("<%s> %s </%s>", n.name, n.text, n.name)
+}
+
+// 比較する命令と、元のファイルでの行番号
+type line struct {
+	number int
+	text   string
+}
+
+// コメントと空行を取り除き、前後の空白を捨てる
+func canonicalLines(lines []string) []line {
+	result := []line{}
+	for i, text := range lines {
+		if index := strings.Index(text, "//"); index >= 0 {
+			text = text[:index]
+		}
+		text = strings.TrimSpace(text)
+		if text == "" {
+			continue
+		}
+		result = append(result, line{number: i + 1, text: text})
+	}
+	return result
+}
+
+func CompareLines(got []string, want []string) *Difference {
+	gotLines := canonicalLines(got)
+	wantLines := canonicalLines(want)
+
+	for i := 0; i < len(gotLines) || i < len(wantLines); i++ {
+		switch {
+		case i >= len(wantLines):
+			return &Difference{Path: fmt.Sprintf("line %d", gotLines[i].number), Got: strconv.Quote(gotLines[i].text), Want: "EOF"}
+		case i >= len(gotLines):
+			return &Difference{Path: fmt.Sprintf("line %d", wantLines[i].number), Got: "EOF", Want: strconv.Quote(wantLines[i].text)}
+		case gotLines[i].text != wantLines[i].text:
+			return &Difference{Path: fmt.Sprintf("line %d", wantLines[i].number), Got: strconv.Quote(gotLines[i].text), Want: strconv.Quote(wantLines[i].text)}
+		}
+	}
+	return nil
+}
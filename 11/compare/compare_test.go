@@ -0,0 +1,191 @@
+package compare
+
+import (
+	"github.com/google/go-cmp/cmp"
+	"strings"
+	"testing"
+)
+
+func TestCompareXML(t *testing.T) {
+	want := `<class>
+  <keyword> class </keyword>
+  <subroutineDec>
+    <identifier> a </identifier>
+  </subroutineDec>
+  <subroutineDec>
+    <identifier> b </identifier>
+    <subroutineBody>
+      <statements>
+        <letStatement>
+          <symbol> &lt; </symbol>
+        </letStatement>
+        <letStatement>
+          <identifier> x </identifier>
+        </letStatement>
+      </statements>
+    </subroutineBody>
+  </subroutineDec>
+</class>`
+
+	cases := []struct {
+		desc string
+		got  string
+		want *Difference
+	}{
+		{
+			desc: "要素の間の空白と改行の違いは無視する",
+			got:  "<class><keyword> class </keyword><subroutineDec><identifier> a </identifier></subroutineDec><subroutineDec>\n\t<identifier> b </identifier><subroutineBody>  <statements><letStatement><symbol> &lt; </symbol></letStatement><letStatement><identifier> x </identifier></letStatement></statements></subroutineBody></subroutineDec></class>",
+			want: nil,
+		},
+		{
+			desc: "テキストの違いは要素のパスで示す",
+			got:  strings.Replace(want, "<identifier> x </identifier>", "<identifier> y </identifier>", 1),
+			want: &Difference{Path: "class/subroutineDec[2]/subroutineBody/statements/letStatement[2]/identifier", Got: `" y "`, Want: `" x "`},
+		},
+		{
+			desc: "トークンの要素の中の空白は無視しない",
+			got:  strings.Replace(want, "<identifier> x </identifier>", "<identifier>x</identifier>", 1),
+			want: &Difference{Path: "class/subroutineDec[2]/subroutineBody/statements/letStatement[2]/identifier", Got: `"x"`, Want: `" x "`},
+		},
+		{
+			desc: "要素名の違い",
+			got:  strings.Replace(want, "<symbol> &lt; </symbol>", "<keyword> &lt; </keyword>", 1),
+			want: &Difference{Path: "class/subroutineDec[2]/subroutineBody/statements/letStatement[1]/symbol", Got: "<keyword> < </keyword>", Want: "<symbol> < </symbol>"},
+		},
+		{
+			desc: "要素が足りない",
+			got:  strings.Replace(want, "<identifier> a </identifier>", "", 1),
+			want: &Difference{Path: "class/subroutineDec[1]/identifier", Got: "nothing", Want: "<identifier> a </identifier>"},
+		},
+		{
+			desc: "余分な要素がある",
+			got:  strings.Replace(want, "</class>", "<symbol> } </symbol></class>", 1),
+			want: &Difference{Path: "class/symbol", Got: "<symbol> } </symbol>", Want: "nothing"},
+		},
+		{
+			desc: "ルート要素の違い",
+			got:  "<tokens></tokens>",
+			want: &Difference{Path: "class", Got: "<tokens>", Want: "<class>"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := CompareXML(strings.NewReader(tc.got), strings.NewReader(want))
+			if err != nil {
+				t.Fatalf("failed CompareXML: %+v", err)
+			}
+			if diff := cmp.Diff(got, tc.want); diff != "" {
+				t.Errorf("failed CompareXML: diff (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCompareXMLInvalid(t *testing.T) {
+	if _, err := CompareXML(strings.NewReader("<class>"), strings.NewReader("<class></class>")); err == nil {
+		t.Errorf("failed CompareXML: error is expected for broken XML")
+	}
+}
+
+// 文字列定値の中の空白は文字列の一部
+func TestCompareXMLStringConstant(t *testing.T) {
+	got := "<expression>\n  <stringConstant> a  b </stringConstant>\n</expression>"
+	want := "<expression><stringConstant> a b </stringConstant></expression>"
+
+	diff, err := CompareXML(strings.NewReader(got), strings.NewReader(want))
+	if err != nil {
+		t.Fatalf("failed CompareXML: %+v", err)
+	}
+	wantDiff := &Difference{Path: "expression/stringConstant", Got: `" a  b "`, Want: `" a b "`}
+	if d := cmp.Diff(diff, wantDiff); d != "" {
+		t.Errorf("failed CompareXML: diff (-got +want):\n%s", d)
+	}
+}
+
+func TestCompareLines(t *testing.T) {
+	want := []string{
+		"// SimpleAdd",
+		"@7",
+		"D=A",
+		"",
+		"@SP  // スタックポインタ",
+		"M=M+1",
+	}
+
+	cases := []struct {
+		desc string
+		got  []string
+		want *Difference
+	}{
+		{
+			desc: "コメント、空行、前後の空白は無視する",
+			got:  []string{"  @7", "D=A", "@SP", "\tM=M+1 "},
+			want: nil,
+		},
+		{
+			desc: "違う行は期待する側の行番号で示す",
+			got:  []string{"@7", "D=A", "@LCL", "M=M+1"},
+			want: &Difference{Path: "line 5", Got: `"@LCL"`, Want: `"@SP"`},
+		},
+		{
+			desc: "行が足りない",
+			got:  []string{"@7", "D=A", "@SP"},
+			want: &Difference{Path: "line 6", Got: "EOF", Want: `"M=M+1"`},
+		},
+		{
+			desc: "余分な行がある",
+			got:  []string{"@7", "D=A", "@SP", "M=M+1", "", "0;JMP"},
+			want: &Difference{Path: "line 6", Got: `"0;JMP"`, Want: "EOF"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := CompareLines(tc.got, want)
+			if diff := cmp.Diff(got, tc.want); diff != "" {
+				t.Errorf("failed CompareLines: diff (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCompareFiles(t *testing.T) {
+	cases := []struct {
+		desc string
+		got  string
+		want string
+	}{
+		{
+			desc: "XMLのフィクスチャ",
+			got:  "../Fixture/SquareVersion10/cmp/Square.xml",
+			want: "../Fixture/SquareVersion10/cmp/Square.xml",
+		},
+		{
+			desc: "VMコードのフィクスチャ",
+			got:  "../Fixture/Seven/cmp/Main.vm",
+			want: "../Fixture/Seven/cmp/Main.vm",
+		},
+		{
+			desc: "アセンブリのフィクスチャ",
+			got:  "../../08/StackArithmetic/SimpleAdd/SimpleAdd.asm.cmp",
+			want: "../../08/StackArithmetic/SimpleAdd/SimpleAdd.asm.cmp",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := CompareFiles(tc.got, tc.want)
+			if err != nil {
+				t.Fatalf("failed CompareFiles: %+v", err)
+			}
+			if got != nil {
+				t.Errorf("failed CompareFiles: %s", got.String())
+			}
+		})
+	}
+
+	if _, err := CompareFiles("../Fixture/Seven/cmp/Main.vm", "../Fixture/Seven/cmp/NotFound.vm"); err == nil {
+		t.Errorf("failed CompareFiles: error is expected for missing file")
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"../compare"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+)
+
+// 生成したファイルとフィクスチャを構造で比較して、最初の違いの場所を表示する
+// XML（.xml）は要素のパス、VMコードやアセンブリ（.vm, .asm.cmpなど）は行番号で場所を示す
+// 違いがあった場合は終了コード1で終了する
+//
+//	fixturecmp Fixture/Square/Main.xml Fixture/Square/cmp/Main.xml
+//	fixturecmp SimpleAdd.asm SimpleAdd.asm.cmp
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: fixturecmp got want\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	diff, err := compare.CompareFiles(flag.Arg(0), flag.Arg(1))
+	if err != nil {
+		log.Fatalf("%+v\n", err)
+	}
+	if diff != nil {
+		fmt.Println(diff.String())
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,168 @@
+package format
+
+import (
+	"../io"
+	"../parsing"
+	"../token"
+	"fmt"
+	"github.com/pkg/errors"
+)
+
+// Jackのソースコードを正規化したスタイルに整形する
+type Formatter struct {
+	options  *parsing.SourceOptions
+	extended bool
+}
+
+func NewFormatter(options *parsing.SourceOptions) *Formatter {
+	return &Formatter{options: options, extended: false}
+}
+
+// 拡張文法のソースとして解析する
+func (f *Formatter) SetExtended(extended bool) {
+	f.extended = extended
+}
+
+// 整形結果と元のソースを保持する
+type Result struct {
+	Filename  string
+	Org       []string
+	Formatted []string
+}
+
+// 整形済みならtrueを返す
+// 整形後の改行コードはLFなので、CRLFのファイルは未整形とみなす
+func (r *Result) IsFormatted() bool {
+	if len(r.Org) != len(r.Formatted) {
+		return false
+	}
+
+	for i := range r.Org {
+		if r.Org[i] != r.Formatted[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *Formatter) FormatFile(filename string) (*Result, error) {
+	src := io.NewSrc(filename)
+	if err := src.Setup(); err != nil {
+		return nil, err
+	}
+	return f.format(src)
+}
+
+func (f *Formatter) FormatLines(filename string, lines []string) (*Result, error) {
+	src := io.NewSrc(filename)
+	src.SetupLines(lines)
+	return f.format(src)
+}
+
+func (f *Formatter) format(src *io.Src) (*Result, error) {
+	class, ctx, err := f.parse(src)
+	if err != nil {
+		return nil, err
+	}
+
+	printer := parsing.NewSourcePrinter(f.options, ctx.Positions, src.Comments)
+	formatted := class.ToSource(printer)
+
+	// 整形によってプログラムが変わっていないことを確認する
+	// 宣言を分割する場合はトークン列が変わるので、再パースした結果の比較だけ行う
+	if !f.options.SplitDeclarations {
+		if err := f.verifyTokens(src, formatted); err != nil {
+			return nil, err
+		}
+	}
+	if err := f.verifyClass(src, class, formatted); err != nil {
+		return nil, err
+	}
+
+	return &Result{Filename: src.Filename, Org: src.Org, Formatted: formatted}, nil
+}
+
+func (f *Formatter) parse(src *io.Src) (*parsing.Class, *parsing.Context, error) {
+	tokens := f.tokenizer(token.NewTokenizerWithPositions(src.Lines, src.Positions)).Tokenize()
+
+	ctx := parsing.NewContext(src.ClassName())
+	ctx.Extended = f.extended
+	parser := parsing.NewParserWithContext(tokens, ctx)
+	class, err := parser.Parse()
+	if err != nil {
+		return nil, nil, errors.WithMessage(err, src.Filename)
+	}
+
+	// クラス定義の後ろにトークンが残っている場合、整形するとコードが消えてしまうのでエラーにする
+	if rest := tokens.First(); rest != nil {
+		message := fmt.Sprintf("%s:%d: unexpected token after class: %s", src.Filename, rest.Line, rest.Debug())
+		return nil, nil, errors.New(message)
+	}
+
+	return class, ctx, nil
+}
+
+func (f *Formatter) tokenizer(tokenizer *token.Tokenizer) *token.Tokenizer {
+	tokenizer.SetExtended(f.extended)
+	return tokenizer
+}
+
+func (f *Formatter) verifyTokens(src *io.Src, formatted []string) error {
+	before := f.tokenizer(token.NewTokenizer(src.Lines)).Tokenize()
+
+	formattedSrc := io.NewSrc(src.Filename)
+	formattedSrc.SetupLines(formatted)
+	after := f.tokenizer(token.NewTokenizer(formattedSrc.Lines)).Tokenize()
+
+	if len(before.Items) != len(after.Items) {
+		message := fmt.Sprintf("%s: formatting changed the number of tokens: before = %d, after = %d", src.Filename, len(before.Items), len(after.Items))
+		return errors.New(message)
+	}
+
+	for i := range before.Items {
+		if !before.Items[i].Equals(after.Items[i]) {
+			message := fmt.Sprintf("%s: formatting changed the token: before = %s, after = %s", src.Filename, before.Items[i].Debug(), after.Items[i].Debug())
+			return errors.New(message)
+		}
+	}
+	return nil
+}
+
+// 整形結果を再パースして、宣言を分割した正規形が元のクラスと一致するか確認する
+func (f *Formatter) verifyClass(src *io.Src, class *parsing.Class, formatted []string) error {
+	formattedSrc := io.NewSrc(src.Filename)
+	formattedSrc.SetupLines(formatted)
+	formattedClass, _, err := f.parse(formattedSrc)
+	if err != nil {
+		return errors.WithMessage(err, "formatted source")
+	}
+
+	before := canonical(class)
+	after := canonical(formattedClass)
+	if len(before) != len(after) {
+		message := fmt.Sprintf("%s: formatting changed the program: before = %d lines, after = %d lines", src.Filename, len(before), len(after))
+		return errors.New(message)
+	}
+
+	for i := range before {
+		if before[i] != after[i] {
+			message := fmt.Sprintf("%s: formatting changed the program: before = '%s', after = '%s'", src.Filename, before[i], after[i])
+			return errors.New(message)
+		}
+	}
+	return nil
+}
+
+// コメントと空行を除いて、宣言を一行一宣言に分割したソース
+func canonical(class *parsing.Class) []string {
+	options := parsing.NewSourceOptions()
+	options.SplitDeclarations = true
+	printer := parsing.NewSourcePrinter(options, nil, nil)
+	return class.ToSource(printer)
+}
+
+// 整形結果を元のファイルに書き込む
+func (r *Result) Write() error {
+	dest := io.NewDest(r.Filename)
+	return dest.WriteSource(r.Formatted)
+}
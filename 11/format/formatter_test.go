@@ -0,0 +1,252 @@
+package format
+
+import (
+	"../parsing"
+	"github.com/google/go-cmp/cmp"
+	"path/filepath"
+	"testing"
+)
+
+func TestFormatterFormatLines(t *testing.T) {
+	cases := []struct {
+		desc  string
+		split bool
+		lines []string
+		want  []string
+	}{
+		{
+			desc:  "インデントとスペースを正規化",
+			split: false,
+			lines: []string{
+				"class Main{",
+				"  field int x,y;",
+				"function void main(int a,int b){",
+				"\tvar int i;",
+				"\tlet i=a+(b*2);",
+				"if(i<0){let i=-i;}",
+				"else{do Output.printInt(i);}",
+				"while(~(i=0)){let i=i-1;}",
+				"return;}",
+				"}",
+			},
+			want: []string{
+				"class Main {",
+				"    field int x, y;",
+				"",
+				"    function void main(int a, int b) {",
+				"        var int i;",
+				"        let i = a + (b * 2);",
+				"        if (i < 0) {",
+				"            let i = -i;",
+				"        } else {",
+				"            do Output.printInt(i);",
+				"        }",
+				"        while (~(i = 0)) {",
+				"            let i = i - 1;",
+				"        }",
+				"        return;",
+				"    }",
+				"}",
+			},
+		},
+		{
+			desc:  "コメントを保持し、連続する空行は一行にまとめる",
+			split: false,
+			lines: []string{
+				"// ファイルの先頭のコメント",
+				"",
+				"/**",
+				"* クラスのコメント",
+				"*/",
+				"class Main { // クラスの行のコメント",
+				"",
+				"",
+				"    /** メソッドのコメント */",
+				"    function void main() {",
+				"        let x = 1; // 行末のコメント",
+				"",
+				"",
+				"        // 文の前のコメント",
+				"        return;",
+				"        // ブロック末尾のコメント",
+				"    }",
+				"}",
+				"// ファイルの末尾のコメント",
+			},
+			want: []string{
+				"// ファイルの先頭のコメント",
+				"",
+				"/**",
+				" * クラスのコメント",
+				" */",
+				"class Main { // クラスの行のコメント",
+				"    /** メソッドのコメント */",
+				"    function void main() {",
+				"        let x = 1; // 行末のコメント",
+				"",
+				"        // 文の前のコメント",
+				"        return;",
+				"        // ブロック末尾のコメント",
+				"    }",
+				"}",
+				"// ファイルの末尾のコメント",
+			},
+		},
+		{
+			desc:  "オプションで変数宣言を一行一宣言に分割",
+			split: true,
+			lines: []string{
+				"class Main {",
+				"    static int a, b; // 分割前のコメント",
+				"    function void main() {",
+				"        var Array x, y;",
+				"        return;",
+				"    }",
+				"}",
+			},
+			want: []string{
+				"class Main {",
+				"    static int a;",
+				"    static int b; // 分割前のコメント",
+				"",
+				"    function void main() {",
+				"        var Array x;",
+				"        var Array y;",
+				"        return;",
+				"    }",
+				"}",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			options := parsing.NewSourceOptions()
+			options.SplitDeclarations = tc.split
+			formatter := NewFormatter(options)
+
+			result, err := formatter.FormatLines("Main.jack", tc.lines)
+			if err != nil {
+				t.Fatalf("failed FormatLines: %+v", err)
+			}
+
+			if diff := cmp.Diff(result.Formatted, tc.want); diff != "" {
+				t.Errorf("failed FormatLines: diff (-got +want):\n%s", diff)
+			}
+
+			// 整形済みのソースを再度整形しても変わらない
+			again, err := formatter.FormatLines("Main.jack", result.Formatted)
+			if err != nil {
+				t.Fatalf("failed FormatLines again: %+v", err)
+			}
+
+			if !again.IsFormatted() {
+				t.Errorf("failed IsFormatted: diff (-got +want):\n%s", cmp.Diff(again.Formatted, result.Formatted))
+			}
+		})
+	}
+}
+
+func TestFormatterFormatLinesError(t *testing.T) {
+	cases := []struct {
+		desc  string
+		lines []string
+	}{
+		{
+			desc:  "構文エラー",
+			lines: []string{"class Main {", "    function void main() {", "        let x = ;", "    }", "}"},
+		},
+		{
+			desc:  "クラス定義の後ろにトークンが残っている",
+			lines: []string{"class Main {", "}", "class Sub {", "}"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			formatter := NewFormatter(parsing.NewSourceOptions())
+			if _, err := formatter.FormatLines("Main.jack", tc.lines); err == nil {
+				t.Errorf("failed FormatLines: expected error")
+			}
+		})
+	}
+}
+
+// Fixtureのjackファイルがすべて整形でき、整形結果が冪等であること
+func TestFormatterFormatFileFixture(t *testing.T) {
+	files, _ := filepath.Glob("../Fixture/*/*.jack")
+	if len(files) == 0 {
+		t.Fatalf("failed Glob: no fixture")
+	}
+
+	for _, split := range []bool{false, true} {
+		options := parsing.NewSourceOptions()
+		options.SplitDeclarations = split
+		formatter := NewFormatter(options)
+
+		for _, file := range files {
+			result, err := formatter.FormatFile(file)
+			if err != nil {
+				t.Fatalf("failed FormatFile: %+v", err)
+			}
+
+			again, err := formatter.FormatLines(file, result.Formatted)
+			if err != nil {
+				t.Fatalf("failed FormatLines: %+v", err)
+			}
+
+			if !again.IsFormatted() {
+				t.Errorf("failed IsFormatted %s: diff (-got +want):\n%s", file, cmp.Diff(again.Formatted, result.Formatted))
+			}
+		}
+	}
+}
+
+// 拡張文法は SetExtended(true) の場合だけ整形できる
+func TestFormatterFormatLinesExtended(t *testing.T) {
+	lines := []string{
+		"class Main{",
+		"const int SIZE=4;",
+		"enum{RED,GREEN=3}",
+		"function int main(){",
+		"var int i,sum;",
+		"for(i=0;i<Main.SIZE;i=i+1){",
+		"if(i=1){continue;}else if(i=GREEN){break;}",
+		"let sum=sum+i;}",
+		"return sum;}",
+		"}",
+	}
+	want := []string{
+		"class Main {",
+		"    const int SIZE = 4;",
+		"    enum { RED, GREEN = 3 }",
+		"",
+		"    function int main() {",
+		"        var int i, sum;",
+		"        for (i = 0; i < Main.SIZE; i = i + 1) {",
+		"            if (i = 1) {",
+		"                continue;",
+		"            } else if (i = GREEN) {",
+		"                break;",
+		"            }",
+		"            let sum = sum + i;",
+		"        }",
+		"        return sum;",
+		"    }",
+		"}",
+	}
+
+	formatter := NewFormatter(parsing.NewSourceOptions())
+	if _, err := formatter.FormatLines("Main.jack", lines); err == nil {
+		t.Errorf("failed FormatLines: expected error without extended")
+	}
+
+	formatter.SetExtended(true)
+	result, err := formatter.FormatLines("Main.jack", lines)
+	if err != nil {
+		t.Fatalf("failed FormatLines: %+v", err)
+	}
+	if diff := cmp.Diff(result.Formatted, want); diff != "" {
+		t.Errorf("failed FormatLines: diff (-got +want):\n%s", diff)
+	}
+}
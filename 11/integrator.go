@@ -2,68 +2,242 @@ package main
 
 import (
 	"./io"
-	"./parsing"
-	"./token"
+	"./jack"
+	"bytes"
+	gojson "encoding/json"
+	"fmt"
+	goio "io"
+	"os"
+	"runtime"
+	"sync"
 )
 
 type Integrator struct {
-	filenames []string
+	filenames         []string
+	workers           int
+	debug             bool
+	foldConstants     bool
+	optimizationLevel int
+	extended          bool
+	precedence        bool
+	json              bool
+	debugInfo         bool
+	constants         map[string]int // 拡張文法で、すべてのクラスから参照できる定数
+	warnWriter        goio.Writer
 }
 
 func NewIntegrator(filenames []string) *Integrator {
-	return &Integrator{filenames: filenames}
+	return &Integrator{filenames: filenames, workers: runtime.NumCPU(), debug: true, warnWriter: os.Stderr}
 }
 
+func (i *Integrator) SetWorkers(workers int) {
+	i.workers = workers
+}
+
+func (i *Integrator) SetDebug(debug bool) {
+	i.debug = debug
+}
+
+func (i *Integrator) SetFoldConstants(foldConstants bool) {
+	i.foldConstants = foldConstants
+}
+
+func (i *Integrator) SetOptimizationLevel(optimizationLevel int) {
+	i.optimizationLevel = optimizationLevel
+}
+
+func (i *Integrator) SetExtended(extended bool) {
+	i.extended = extended
+}
+
+func (i *Integrator) SetPrecedence(precedence bool) {
+	i.precedence = precedence
+}
+
+func (i *Integrator) SetJSON(json bool) {
+	i.json = json
+}
+
+func (i *Integrator) SetDebugInfo(debugInfo bool) {
+	i.debugInfo = debugInfo
+}
+
+func (i *Integrator) SetWarnWriter(warnWriter goio.Writer) {
+	i.warnWriter = warnWriter
+}
+
+// クラスごとにコンテキストを分けているので、ワーカープールで並行してコンパイルする
+// デバッグ出力とエラーはファイルの指定順に並べ直すので、実行結果は並行度に依存しない
+// 逐次コンパイルと同じく、エラーになったファイル以降はコンパイルを始めない
+// ただし、エラーの時点で他のワーカーがコンパイル中のファイルは最後まで書き込む
 func (i *Integrator) Integrate() error {
-	for _, filename := range i.filenames {
-		err := i.integrateFile(filename)
-		if err != nil {
+	if i.extended {
+		if err := i.collectConstants(); err != nil {
 			return err
 		}
 	}
+
+	results := make([]*integrateResult, len(i.filenames))
+	jobs := make(chan int)
+
+	// エラーになったファイルの番号の最小値（エラーがなければファイル数）
+	// これより後ろのファイルは、逐次コンパイルなら実行されないのでコンパイルしない
+	var mutex sync.Mutex
+	failedIndex := len(i.filenames)
+	canceled := func(index int) bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return index > failedIndex
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < i.workerCount(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range jobs {
+				if canceled(index) {
+					continue
+				}
+
+				result := &integrateResult{}
+				result.err = i.compileFile(i.filenames[index], &result.debug, &result.warnings)
+				results[index] = result
+				if result.err != nil {
+					mutex.Lock()
+					if index < failedIndex {
+						failedIndex = index
+					}
+					mutex.Unlock()
+				}
+			}
+		}()
+	}
+
+	for index := range i.filenames {
+		if canceled(index) {
+			break
+		}
+		jobs <- index
+	}
+	close(jobs)
+	wg.Wait()
+
+	// 最初にエラーになったファイルより前のファイルはすべてコンパイル済みで、
+	// コンパイルしなかったファイル（resultsがnil）はすべてそれより後ろにある
+	// 返すのは逐次コンパイルと同じく、指定順で最初にエラーになったファイルのエラー
+	for _, result := range results {
+		os.Stdout.Write(result.debug.Bytes())
+		i.warnWriter.Write(result.warnings.Bytes())
+		if result.err != nil {
+			return result.err
+		}
+	}
 	return nil
 }
 
-func (i *Integrator) integrateFile(file string) error {
-	// ソースファイルの読み込み
-	src := io.NewSrc(file)
-	err := src.Setup()
+// 他のクラスの定数を参照できるように、コンパイルの前にすべてのクラスの定数宣言を集めておく
+func (i *Integrator) collectConstants() error {
+	options := jack.NewOptions()
+	options.Precedence = i.precedence
+	constants, err := jack.CollectConstants(i.filenames, options)
 	if err != nil {
 		return err
 	}
+	i.constants = constants
+	return nil
+}
+
+func (i *Integrator) workerCount() int {
+	if i.workers < 1 {
+		return 1
+	}
+	if i.workers > len(i.filenames) {
+		return len(i.filenames)
+	}
+	return i.workers
+}
+
+type integrateResult struct {
+	debug    bytes.Buffer
+	warnings bytes.Buffer
+	err      error
+}
 
-	// トークンに分割
-	tokenizer := token.NewTokenizer(src.Lines)
-	tokens := tokenizer.Tokenize()
-	tokenizedXML := tokens.ToXML()
+func (i *Integrator) integrateFile(file string) error {
+	return i.compileFile(file, os.Stdout, i.warnWriter)
+}
 
-	// トークンをパース
-	parser := parsing.NewParser(tokens, src.ClassName())
-	class, err := parser.Parse()
+func (i *Integrator) compileFile(file string, debugWriter goio.Writer, warnWriter goio.Writer) error {
+	// トークナイズからコード生成まではライブラリと同じ手順で行う
+	unit, err := jack.ParseFile(file, i.options(debugWriter))
 	if err != nil {
 		return err
 	}
 
+	// 警告はコンパイルを止めずに出力だけする
+	for _, warning := range unit.Context.Warnings {
+		fmt.Fprintf(warnWriter, "%s:%d:%d: warning: %s\n", unit.Filename, warning.Start.Line, warning.Start.Column, warning.Message)
+	}
+
 	// XMLファイルへ書き込み
-	dest := io.NewDest(src.Filename)
-	err = dest.WriteTokenizedXML(tokenizedXML)
+	dest := io.NewDest(unit.Filename)
+	err = dest.WriteTokenizedXML(unit.Tokens.ToXML())
 	if err != nil {
 		return err
 	}
 
-	err = dest.WriteParsedXML(class.ToXML())
+	err = dest.WriteParsedXML(unit.Class.ToXML())
 	if err != nil {
 		return err
 	}
 
-	// デバッグしやすいように生成したコードを標準出力
-	parser.PrintDebugCode()
+	// 位置とシンボルを含むASTをJSONファイルへ書き込み
+	if i.json {
+		content, err := unit.ToJSON()
+		if err != nil {
+			return err
+		}
+		err = dest.WriteJSON(content)
+		if err != nil {
+			return err
+		}
+	}
+
+	// デバッガ向けの情報をJSONファイルへ書き込み
+	if i.debugInfo {
+		content, err := gojson.MarshalIndent(unit.DebugInfo, "", "  ")
+		if err != nil {
+			return err
+		}
+		err = dest.WriteDebugInfo(content)
+		if err != nil {
+			return err
+		}
+	}
 
 	// コード生成をして書き込み
-	err = dest.WriteCode(parser.CodeLines())
+	err = dest.WriteCode(unit.Code)
 	if err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// コンパイラのフラグをライブラリのオプションに変換する
+// デバッグ出力はワーカーごとのバッファに書き込むので、ファイルごとに作る
+func (i *Integrator) options(debugWriter goio.Writer) *jack.Options {
+	options := jack.NewOptions()
+	options.FoldConstants = i.foldConstants
+	options.OptimizationLevel = i.optimizationLevel
+	options.Extended = i.extended
+	options.Precedence = i.precedence
+	options.DebugInfo = i.debugInfo
+	options.Debug = i.debug
+	options.DebugWriter = debugWriter
+	if i.constants != nil {
+		options.Constants = i.constants
+	}
+	return options
+}
@@ -1,26 +1,20 @@
 package main
 
 import (
-	"./parsing"
-	"./symbol"
-	"bufio"
-	"github.com/google/go-cmp/cmp"
+	"./compare"
+	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 )
 
-func SetupTestForIntegrator() {
-	// デバッグフラグの無効化
-	parsing.DebugCode = false
-	symbol.DebugSymbolTables = false
-
-	// シンボルテーブルの初期化
-	symbol.GlobalSymbolTables.Reset("Testing")
-	symbol.GlobalSymbolTables.ResetSubroutine("TestRun")
-
-	// ID生成器の初期化
-	symbol.GlobalIdGenerator.Reset()
+func SetupTestForIntegrator(filenames []string) *Integrator {
+	// デバッグ出力を無効化したIntegratorを生成
+	integrator := NewIntegrator(filenames)
+	integrator.SetDebug(false)
+	return integrator
 }
 
 func TestIntegratorGenerate(t *testing.T) {
@@ -105,6 +99,27 @@ func TestIntegratorGenerate(t *testing.T) {
 				"Fixture/Pong/cmp/PongGame.vm",
 			},
 		},
+		{
+			desc: "Pong: ファイルの指定順に依存しない",
+			src: []string{
+				"Fixture/Pong/PongGame.jack",
+				"Fixture/Pong/Main.jack",
+				"Fixture/Pong/Bat.jack",
+				"Fixture/Pong/Ball.jack",
+			},
+			dest: []string{
+				"Fixture/Pong/PongGame.vm",
+				"Fixture/Pong/Main.vm",
+				"Fixture/Pong/Bat.vm",
+				"Fixture/Pong/Ball.vm",
+			},
+			want: []string{
+				"Fixture/Pong/cmp/PongGame.vm",
+				"Fixture/Pong/cmp/Main.vm",
+				"Fixture/Pong/cmp/Bat.vm",
+				"Fixture/Pong/cmp/Ball.vm",
+			},
+		},
 		{
 			desc: "ComplexArrays",
 			src: []string{
@@ -126,18 +141,11 @@ func TestIntegratorGenerate(t *testing.T) {
 
 		t.Run(tc.desc, func(t *testing.T) {
 			// いろいろ初期化
-			SetupTestForIntegrator()
-
-			integrator := NewIntegrator(tc.src)
+			integrator := SetupTestForIntegrator(tc.src)
 			integrator.Integrate()
 
 			for i, dest := range tc.dest {
-				got := readFileQuietly(dest)
-				want := readFileQuietly(tc.want[i])
-
-				if diff := cmp.Diff(got, want); diff != "" {
-					t.Errorf("failed: diff %s %s: (-got +want):\n%s\n", dest, tc.want[i], diff)
-				} else {
+				if compareFixture(t, dest, tc.want[i]) {
 					os.Remove(dest)
 				}
 			}
@@ -243,23 +251,13 @@ func TestIntegratorIntegrate(t *testing.T) {
 			integrator.Integrate()
 
 			for i, dest := range tc.destTokenizedXML {
-				got := readFileQuietly(dest)
-				want := readFileQuietly(tc.wantTokenizedXML[i])
-
-				if diff := cmp.Diff(got, want); diff != "" {
-					t.Errorf("failed: diff %s %s: (-got +want):\n%s\n", dest, tc.wantTokenizedXML[i], diff)
-				} else {
+				if compareFixture(t, dest, tc.wantTokenizedXML[i]) {
 					os.Remove(dest)
 				}
 			}
 
 			for i, dest := range tc.destParsedXML {
-				got := readFileQuietly(dest)
-				want := readFileQuietly(tc.wantParsedXML[i])
-
-				if diff := cmp.Diff(got, want); diff != "" {
-					t.Errorf("failed: diff %s %s: (-got +want):\n%s\n", dest, tc.wantTokenizedXML[i], diff)
-				} else {
+				if compareFixture(t, dest, tc.wantParsedXML[i]) {
 					os.Remove(dest)
 				}
 			}
@@ -294,37 +292,105 @@ func TestIntegratorIntegrateFile(t *testing.T) {
 			integrator := NewIntegrator([]string{})
 			integrator.integrateFile(tc.src)
 
-			gotTokenizedXML := readFileQuietly(tc.destTokenizedXML)
-			wantTokenizedXML := readFileQuietly(tc.wantTokenizedXML)
-
-			if diff := cmp.Diff(gotTokenizedXML, wantTokenizedXML); diff != "" {
-				t.Errorf("failed: diff %s %s: (-got +want):\n%s\n", tc.destTokenizedXML, tc.wantTokenizedXML, diff)
-			} else {
+			if compareFixture(t, tc.destTokenizedXML, tc.wantTokenizedXML) {
 				os.Remove(tc.destTokenizedXML)
 			}
 
-			gotParsedXML := readFileQuietly(tc.destParsedXML)
-			wantParsedXML := readFileQuietly(tc.wantParsedXML)
-
-			if diff := cmp.Diff(gotParsedXML, wantParsedXML); diff != "" {
-				t.Errorf("failed: diff %s %s: (-got +want):\n%s\n", tc.destParsedXML, tc.wantParsedXML, diff)
-			} else {
+			if compareFixture(t, tc.destParsedXML, tc.wantParsedXML) {
 				os.Remove(tc.destParsedXML)
 			}
 		})
 	}
 }
 
-func readFileQuietly(filename string) []string {
-	file, _ := os.Open(filename)
-	defer file.Close()
+// エラーになったファイル以降はコンパイルを始めず、指定順で最初のエラーを返す
+func TestIntegratorIntegrateError(t *testing.T) {
+	cases := []struct {
+		desc    string
+		workers int
+		invalid []int // 構文エラーにするファイルの番号
+		want    string
+	}{
+		{
+			desc:    "逐次コンパイル",
+			workers: 1,
+			invalid: []int{2},
+			want:    "Class2.jack",
+		},
+		{
+			desc:    "並行コンパイルで複数のファイルがエラー",
+			workers: 4,
+			invalid: []int{1, 2, 3},
+			want:    "Class1.jack",
+		},
+	}
 
-	scanner := bufio.NewScanner(file)
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "integrator")
+			if err != nil {
+				t.Fatalf("failed TempDir: %+v", err)
+			}
+			defer os.RemoveAll(dir)
+
+			invalid := map[int]bool{}
+			for _, index := range tc.invalid {
+				invalid[index] = true
+			}
 
-	lines := []string{}
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		lines = append(lines, line)
+			filenames := []string{}
+			for index := 0; index < 20; index++ {
+				className := fmt.Sprintf("Class%d", index)
+				body := "    function void run() {\n        return;\n    }\n"
+				if invalid[index] {
+					body = "    function void run() {\n        let x = ;\n    }\n"
+				}
+				filename := filepath.Join(dir, className+".jack")
+				if err := ioutil.WriteFile(filename, []byte("class "+className+" {\n"+body+"}\n"), 0644); err != nil {
+					t.Fatalf("failed WriteFile: %+v", err)
+				}
+				filenames = append(filenames, filename)
+			}
+
+			integrator := SetupTestForIntegrator(filenames)
+			integrator.SetWorkers(tc.workers)
+			err = integrator.Integrate()
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("failed Integrate: want error in %s, got = %v", tc.want, err)
+			}
+
+			// 最初のエラーより前のファイルはすべて書き込む
+			for index := 0; index < tc.invalid[0]; index++ {
+				if _, err := os.Stat(filepath.Join(dir, fmt.Sprintf("Class%d.vm", index))); err != nil {
+					t.Errorf("failed Integrate: Class%d.vm is not written: %v", index, err)
+				}
+			}
+
+			// 逐次コンパイルではエラー以降のファイルを書き込まない
+			if tc.workers == 1 {
+				for index := tc.invalid[0]; index < len(filenames); index++ {
+					if _, err := os.Stat(filepath.Join(dir, fmt.Sprintf("Class%d.vm", index))); err == nil {
+						t.Errorf("failed Integrate: Class%d.vm is written after the error", index)
+					}
+				}
+			}
+		})
+	}
+}
+
+// 生成したファイルとフィクスチャを構造で比較する
+// 一致した場合はtrueを返す
+func compareFixture(t *testing.T, got string, want string) bool {
+	t.Helper()
+
+	diff, err := compare.CompareFiles(got, want)
+	if err != nil {
+		t.Errorf("failed: compare %s %s: %v", got, want, err)
+		return false
+	}
+	if diff != nil {
+		t.Errorf("failed: diff %s %s: %s", got, want, diff.String())
+		return false
 	}
-	return lines
+	return true
 }
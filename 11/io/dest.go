@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 type Dest struct {
@@ -25,11 +26,26 @@ func (d *Dest) WriteParsedXML(lines []string) error {
 	return d.write(filename, lines)
 }
 
+func (d *Dest) WriteJSON(content []byte) error {
+	filename := d.jsonFilename()
+	return d.write(filename, strings.Split(string(content), "\n"))
+}
+
+func (d *Dest) WriteDebugInfo(content []byte) error {
+	filename := d.debugInfoFilename()
+	return d.write(filename, strings.Split(string(content), "\n"))
+}
+
 func (d *Dest) WriteCode(lines []string) error {
 	filename := d.codeFilename()
 	return d.write(filename, lines)
 }
 
+// 整形したソースコードで元のファイルを上書きする
+func (d *Dest) WriteSource(lines []string) error {
+	return d.write(d.src, lines)
+}
+
 func (d *Dest) write(filename string, lines []string) error {
 	file, err := os.Create(filename)
 	if err != nil {
@@ -58,6 +74,16 @@ func (d *Dest) parsedXMLFilename() string {
 	return fmt.Sprintf("%s.xml", withoutExt)
 }
 
+func (d *Dest) jsonFilename() string {
+	withoutExt := d.src[:len(d.src)-len(filepath.Ext(d.src))]
+	return fmt.Sprintf("%s.json", withoutExt)
+}
+
+func (d *Dest) debugInfoFilename() string {
+	withoutExt := d.src[:len(d.src)-len(filepath.Ext(d.src))]
+	return fmt.Sprintf("%s.dbg.json", withoutExt)
+}
+
 func (d *Dest) codeFilename() string {
 	withoutExt := d.src[:len(d.src)-len(filepath.Ext(d.src))]
 	return fmt.Sprintf("%s.vm", withoutExt)
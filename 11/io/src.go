@@ -1,6 +1,7 @@
 package io
 
 import (
+	"../token"
 	"bufio"
 	"os"
 	"path/filepath"
@@ -9,10 +10,13 @@ import (
 
 // コンパイル対象のソースファイルを読み込む
 type Src struct {
-	Filename  string
-	Org       []string
-	Lines     []string
-	isComment bool
+	Filename   string
+	Org        []string
+	Lines      []string
+	Positions  []token.Position // Linesの各行の先頭位置
+	Comments   []*token.Comment // 除外したコメント
+	isComment  bool
+	lineNumber int
 }
 
 func NewSrc(filename string) *Src {
@@ -37,6 +41,12 @@ func (s *Src) Setup() error {
 	return nil
 }
 
+// ファイルを読み込まずに、メモリ上の行をソースとしてセットアップする
+func (s *Src) SetupLines(lines []string) {
+	s.Org = lines
+	s.setupLines()
+}
+
 func (s *Src) readFile(filename string) error {
 	file, err := os.Open(filename)
 	if err != nil {
@@ -57,10 +67,13 @@ func (s *Src) readFile(filename string) error {
 }
 
 func (s *Src) setupLines() {
-	for _, line := range s.Org {
+	for i, line := range s.Org {
+		s.lineNumber = i + 1
 		withoutComment := s.deleteCommentAndWhitespace(line)
 		if withoutComment != "" {
 			s.Lines = append(s.Lines, withoutComment)
+			column := strings.Index(line, withoutComment) + 1
+			s.Positions = append(s.Positions, token.NewPosition(s.lineNumber, column))
 		}
 	}
 }
@@ -74,11 +87,13 @@ func (s *Src) deleteCommentAndWhitespace(line string) string {
 	// 複数行コメントの終了文字列を見つけたらフラグを反転して空文字を返す
 	if s.isComment && strings.Contains(line, "*/") {
 		s.isComment = false
+		s.addComment(line, strings.TrimSpace(line), false)
 		return ""
 	}
 
 	// 複数行コメントの途中なら空文字を返す
 	if s.isComment {
+		s.addComment(line, strings.TrimSpace(line), false)
 		return ""
 	}
 
@@ -86,8 +101,17 @@ func (s *Src) deleteCommentAndWhitespace(line string) string {
 	deletedComment := line
 	if strings.Contains(line, "//") {
 		deletedComment = line[:strings.Index(line, "//")]
+		trailing := strings.TrimSpace(deletedComment) != ""
+		s.addComment(line, strings.TrimSpace(line[strings.Index(line, "//"):]), trailing)
 	}
 
 	// 空白を除去
 	return strings.TrimSpace(deletedComment)
 }
+
+// 除外したコメントを、元の行での位置とともに記録する
+func (s *Src) addComment(line string, comment string, trailing bool) {
+	column := strings.Index(line, comment) + 1
+	position := token.NewPosition(s.lineNumber, column)
+	s.Comments = append(s.Comments, token.NewComment(comment, trailing, position))
+}
@@ -1,6 +1,7 @@
 package io
 
 import (
+	"../token"
 	"github.com/google/go-cmp/cmp"
 	"testing"
 )
@@ -133,3 +134,48 @@ func TestSrcDeleteCommentAndWhitespace(t *testing.T) {
 		})
 	}
 }
+
+func TestSrcSetupLinesComments(t *testing.T) {
+	cases := []struct {
+		desc      string
+		lines     []string
+		positions []token.Position
+		comments  []*token.Comment
+	}{
+		{
+			desc: "コメントと行の位置を記録",
+			lines: []string{
+				"/** クラスのコメント */",
+				"class Main {",
+				"    field int x; // 行末のコメント",
+				"    // 行のコメント",
+				"}",
+			},
+			positions: []token.Position{
+				token.NewPosition(2, 1),
+				token.NewPosition(3, 5),
+				token.NewPosition(5, 1),
+			},
+			comments: []*token.Comment{
+				token.NewComment("/** クラスのコメント */", false, token.NewPosition(1, 1)),
+				token.NewComment("// 行末のコメント", true, token.NewPosition(3, 18)),
+				token.NewComment("// 行のコメント", false, token.NewPosition(4, 5)),
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			src := NewSrc(testSrcFilename)
+			src.SetupLines(tc.lines)
+
+			if diff := cmp.Diff(src.Positions, tc.positions); diff != "" {
+				t.Errorf("failed src.Positions: diff (-got +want):\n%s", diff)
+			}
+
+			if diff := cmp.Diff(src.Comments, tc.comments); diff != "" {
+				t.Errorf("failed src.Comments: diff (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
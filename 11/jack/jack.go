@@ -0,0 +1,242 @@
+// Jackのソースコードを解析するツール向けのライブラリ
+// コンパイラと同じ手順でトークナイズとパースをして、AST、シンボルテーブル、生成したVMコードを返す
+//
+//	units, err := jack.ParseFiles([]string{"Main.jack", "Game.jack"}, jack.NewOptions())
+//	for _, unit := range units {
+//		data, err := unit.ToJSON()
+//		...
+//	}
+package jack
+
+import (
+	"../io"
+	"../parsing"
+	"../token"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	goio "io"
+	"os"
+	"strings"
+)
+
+// コンパイラのオプションと同じ
+type Options struct {
+	FoldConstants     bool
+	OptimizationLevel int
+	Extended          bool
+	Precedence        bool
+	DebugInfo         bool           // デバッガ向けに、VMコマンドとソースの行の対応を記録する
+	Constants         map[string]int // 他のクラスの定数（ParseFilesでは自動で集める）
+	Debug             bool           // シンボルテーブルと生成したコードをDebugWriterへ出力する
+	DebugWriter       goio.Writer
+}
+
+func NewOptions() *Options {
+	return &Options{
+		FoldConstants:     false,
+		OptimizationLevel: parsing.OptimizeNone,
+		Extended:          false,
+		Precedence:        false,
+		DebugInfo:         false,
+		Constants:         map[string]int{},
+		Debug:             false,
+		DebugWriter:       os.Stdout,
+	}
+}
+
+// パースしたクラス
+type Unit struct {
+	Filename  string
+	Src       *io.Src
+	Tokens    *token.Tokens
+	Class     *parsing.Class
+	Context   *parsing.Context
+	Code      []string           // 生成したVMコード
+	DebugInfo *parsing.DebugInfo // Options.DebugInfoを指定しない場合は、サブルーチンの情報を持たない
+}
+
+func ParseFile(filename string, options *Options) (*Unit, error) {
+	src := io.NewSrc(filename)
+	if err := src.Setup(); err != nil {
+		return nil, err
+	}
+	return parse(src, options)
+}
+
+// ファイルを読み込まずに、メモリ上の行をパースする
+// クラス名はファイル名から決まるので、filenameはクラス名と同じ名前にする
+func ParseLines(filename string, lines []string, options *Options) (*Unit, error) {
+	src := io.NewSrc(filename)
+	src.SetupLines(lines)
+	return parse(src, options)
+}
+
+// 複数のファイルをパースする
+// 拡張文法では、他のクラスの定数を参照できるように先にすべてのクラスの定数を集める
+func ParseFiles(filenames []string, options *Options) ([]*Unit, error) {
+	if options.Extended {
+		constants, err := CollectConstants(filenames, options)
+		if err != nil {
+			return nil, err
+		}
+		copied := *options
+		copied.Constants = constants
+		options = &copied
+	}
+
+	result := []*Unit{}
+	for _, filename := range filenames {
+		unit, err := ParseFile(filename, options)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, unit)
+	}
+	return result, nil
+}
+
+// すべてのクラスの定数宣言を集める
+// 返すマップのキーは「Class.NAME」
+// 定数の初期値は他のクラスの定数を参照できるので、値が決まらないクラスは後回しにして、
+// 新しく値が決まる定数がなくなるまで繰り返す
+func CollectConstants(filenames []string, options *Options) (map[string]int, error) {
+	pending := []*io.Src{}
+	for _, filename := range filenames {
+		src := io.NewSrc(filename)
+		if err := src.Setup(); err != nil {
+			return nil, err
+		}
+		pending = append(pending, src)
+	}
+
+	result := map[string]int{}
+	for len(pending) > 0 {
+		blocked := []*io.Src{}
+		undefined := map[string]*parsing.UndefinedConstantError{} // クラス名ごとに、まだ値が決まらない参照先
+		errs := map[string]error{}
+		progress := false
+		for _, src := range pending {
+			constants, err := parseConstants(src, options, result)
+			for key, value := range constants {
+				if _, ok := result[key]; !ok {
+					result[key] = value
+					progress = true
+				}
+			}
+			if err == nil {
+				progress = true
+				continue
+			}
+
+			// 同じクラスの定数は宣言した順に決まるので、見つからなければ後回しにしても解決しない
+			cause, ok := errors.Cause(err).(*parsing.UndefinedConstantError)
+			if !ok || cause.ClassName == src.ClassName() {
+				return nil, parsing.WithFilename(err, src.Filename)
+			}
+			blocked = append(blocked, src)
+			undefined[src.ClassName()] = cause
+			errs[src.ClassName()] = parsing.WithFilename(err, src.Filename)
+		}
+		if !progress {
+			return nil, unresolvedConstantsError(blocked, undefined, errs)
+		}
+		pending = blocked
+	}
+	return result, nil
+}
+
+// ctx.Constantsにconstantsを渡して、srcの定数宣言をパースする
+func parseConstants(src *io.Src, options *Options, constants map[string]int) (map[string]int, error) {
+	tokenizer := token.NewTokenizerWithPositions(src.Lines, src.Positions)
+	tokenizer.SetExtended(true)
+	ctx := parsing.NewContext(src.ClassName())
+	ctx.Extended = true
+	ctx.Precedence = options.Precedence
+	ctx.Constants = constants
+
+	return parsing.NewParserWithContext(tokenizer.Tokenize(), ctx).ParseConstants()
+}
+
+// 値が決まらなくなったクラスのエラー
+// 参照先のクラスも値が決まらないまま残っていれば循環参照、そうでなければ存在しない定数を参照している
+func unresolvedConstantsError(blocked []*io.Src, undefined map[string]*parsing.UndefinedConstantError, errs map[string]error) error {
+	for _, src := range blocked {
+		if _, ok := undefined[undefined[src.ClassName()].ClassName]; !ok {
+			return errs[src.ClassName()]
+		}
+	}
+
+	// 残ったクラスはどれも残ったクラスの定数を参照しているので、たどると必ず循環する
+	visited := map[string]int{}
+	path := []string{}
+	className := blocked[0].ClassName()
+	for {
+		if index, ok := visited[className]; ok {
+			path = path[index:]
+			break
+		}
+		visited[className] = len(path)
+		path = append(path, className)
+		className = undefined[className].ClassName
+	}
+
+	needs := []string{}
+	for _, className := range path {
+		needs = append(needs, fmt.Sprintf("%s needs %s", className, undefined[className].Key()))
+	}
+	message := fmt.Sprintf("error CollectConstants: circular constant reference: %s", strings.Join(needs, ", "))
+	return errors.New(message)
+}
+
+func parse(src *io.Src, options *Options) (*Unit, error) {
+	tokenizer := token.NewTokenizerWithPositions(src.Lines, src.Positions)
+	tokenizer.SetExtended(options.Extended)
+	tokens := tokenizer.Tokenize()
+
+	ctx := NewContext(src.ClassName(), options)
+	parser := parsing.NewParserWithContext(tokens, ctx)
+	class, err := parser.Parse()
+	if err != nil {
+		return nil, parsing.WithFilename(err, src.Filename)
+	}
+	parser.PrintDebugCode()
+
+	return &Unit{
+		Filename:  src.Filename,
+		Src:       src,
+		Tokens:    tokens,
+		Class:     class,
+		Context:   ctx,
+		Code:      parser.CodeLines(),
+		DebugInfo: parser.DebugInfo(),
+	}, nil
+}
+
+// オプションを反映したコンテキスト
+// デバッグ出力はOptions.Debugを指定した場合だけ行う
+func NewContext(className string, options *Options) *parsing.Context {
+	ctx := parsing.NewContext(className)
+	ctx.SetDebug(options.Debug)
+	if options.DebugWriter != nil {
+		ctx.DebugWriter = options.DebugWriter
+	}
+	ctx.FoldConstants = options.FoldConstants
+	ctx.OptimizationLevel = options.OptimizationLevel
+	ctx.Extended = options.Extended
+	ctx.Precedence = options.Precedence
+	ctx.DebugInfo = options.DebugInfo
+	if options.Constants != nil {
+		ctx.Constants = options.Constants
+	}
+	return ctx
+}
+
+// ソースファイル上の位置、解決したシンボル、呼び出し先を含むAST
+func (u *Unit) JSON() *parsing.JSONNode {
+	return parsing.ExportJSON(u.Class, u.Context)
+}
+
+func (u *Unit) ToJSON() ([]byte, error) {
+	return json.MarshalIndent(u.JSON(), "", "  ")
+}
@@ -0,0 +1,322 @@
+package jack
+
+import (
+	"../parsing"
+	"bytes"
+	"encoding/json"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseFiles(t *testing.T) {
+	filenames := []string{
+		"../Fixture/Square/Main.jack",
+		"../Fixture/Square/Square.jack",
+		"../Fixture/Square/SquareGame.jack",
+	}
+	units, err := ParseFiles(filenames, NewOptions())
+	if err != nil {
+		t.Fatalf("failed ParseFiles: %+v", err)
+	}
+
+	got := []string{}
+	for _, unit := range units {
+		got = append(got, unit.Class.ClassName.Value)
+	}
+	if diff := cmp.Diff(got, []string{"Main", "Square", "SquareGame"}); diff != "" {
+		t.Errorf("failed ParseFiles: diff (-got +want):\n%s", diff)
+	}
+
+	// ライブラリとしてパースしても、コンパイラと同じVMコードが生成される
+	if diff := cmp.Diff(units[0].Code[0], "function Main.main 1"); diff != "" {
+		t.Errorf("failed Unit.Code: diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestParseFilesConstants(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jack")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	sources := map[string]string{
+		"Main.jack":   "class Main { function int main() { return Config.SIZE; } }",
+		"Config.jack": "class Config { const int SIZE = 8 * 4; }",
+	}
+	filenames := []string{}
+	for name, source := range sources {
+		filename := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(filename, []byte(source), 0644); err != nil {
+			t.Fatal(err)
+		}
+		filenames = append(filenames, filename)
+	}
+
+	options := NewOptions()
+	options.Extended = true
+	units, err := ParseFiles(filenames, options)
+	if err != nil {
+		t.Fatalf("failed ParseFiles: %+v", err)
+	}
+
+	for _, unit := range units {
+		if unit.Class.ClassName.Value != "Main" {
+			continue
+		}
+		want := []string{"function Main.main 0", "push constant 32", "return", ""}
+		if diff := cmp.Diff(unit.Code, want); diff != "" {
+			t.Errorf("failed Unit.Code: diff (-got +want):\n%s", diff)
+		}
+	}
+}
+
+// 他のクラスの定数を参照する定数は、参照先の値が決まってから計算する
+func TestCollectConstants(t *testing.T) {
+	cases := []struct {
+		desc    string
+		sources [][]string // ファイル名とソース
+		want    map[string]int
+		wantErr string
+	}{
+		{
+			desc: "後ろのファイルのクラスの定数を参照する",
+			sources: [][]string{
+				{"Main.jack", "class Main { const int M = Keys.LEFT + 1; }"},
+				{"Keys.jack", "class Keys { enum { UP, LEFT = 130 } }"},
+			},
+			want: map[string]int{"Main.M": 131, "Keys.UP": 0, "Keys.LEFT": 130},
+		},
+		{
+			desc: "参照が複数のクラスにまたがる",
+			sources: [][]string{
+				{"A.jack", "class A { const int X = B.Y * 2; }"},
+				{"B.jack", "class B { const int Y = C.Z + 1; }"},
+				{"C.jack", "class C { const int Z = 3; }"},
+			},
+			want: map[string]int{"A.X": 8, "B.Y": 4, "C.Z": 3},
+		},
+		{
+			desc: "値が決まっている定数だけを参照していれば、同じクラスどうしで参照し合える",
+			sources: [][]string{
+				{"A.jack", "class A { const int X = 1; const int Z = B.Y + 1; }"},
+				{"B.jack", "class B { const int Y = A.X + 1; }"},
+			},
+			want: map[string]int{"A.X": 1, "A.Z": 3, "B.Y": 2},
+		},
+		{
+			desc: "循環参照",
+			sources: [][]string{
+				{"A.jack", "class A { const int X = B.Y; }"},
+				{"B.jack", "class B { const int Y = A.X; }"},
+				{"C.jack", "class C { const int Z = 1; }"},
+			},
+			wantErr: "error CollectConstants: circular constant reference: A needs B.Y, B needs A.X",
+		},
+		{
+			desc: "存在しない定数",
+			sources: [][]string{
+				{"A.jack", "class A { const int X = B.Z; }"},
+				{"B.jack", "class B { const int Y = 1; }"},
+			},
+			wantErr: "error parseClassConstant: undefined constant: got = B.Z",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "jack")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+
+			filenames := []string{}
+			for _, source := range tc.sources {
+				filename := filepath.Join(dir, source[0])
+				if err := ioutil.WriteFile(filename, []byte(source[1]), 0644); err != nil {
+					t.Fatal(err)
+				}
+				filenames = append(filenames, filename)
+			}
+
+			got, err := CollectConstants(filenames, NewOptions())
+			if tc.wantErr != "" {
+				if err == nil {
+					t.Fatal("failed CollectConstants: expected error")
+				}
+				if diff := cmp.Diff(errors.Cause(err).Error(), tc.wantErr); diff != "" {
+					t.Errorf("failed CollectConstants: diff (-got +want):\n%s", diff)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed CollectConstants: %+v", err)
+			}
+			if diff := cmp.Diff(got, tc.want); diff != "" {
+				t.Errorf("failed CollectConstants: diff (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+// 範囲外の整数は、警告と同じようにファイル名と位置を付けて報告する
+func TestParseLinesIntegerOutOfRange(t *testing.T) {
+	lines := []string{
+		"class Main {",
+		"  function int main() {",
+		"    return 40000;",
+		"  }",
+		"}",
+	}
+	_, err := ParseLines("Main.jack", lines, NewOptions())
+	if err == nil {
+		t.Fatal("failed ParseLines: expected error")
+	}
+	want := "Main.jack:3:12: error: integer constant 40000 out of range 0..32767"
+	if diff := cmp.Diff(err.Error(), want); diff != "" {
+		t.Errorf("failed ParseLines: diff (-got +want):\n%s", diff)
+	}
+}
+
+// Hackの文字セットにない文字を含む文字列定値は、コンパイル時にエラーにする
+func TestParseLinesStringOutsideCharset(t *testing.T) {
+	lines := []string{
+		"class Main {",
+		"  function void main() {",
+		"    do Output.printString(\"Aあ\");",
+		"    return;",
+		"  }",
+		"}",
+	}
+	_, err := ParseLines("Main.jack", lines, NewOptions())
+	if err == nil {
+		t.Fatal("failed ParseLines: expected error")
+	}
+	want := "Main.jack:3:27: error: character 'あ' in string constant is not in the Hack character set"
+	if diff := cmp.Diff(err.Error(), want); diff != "" {
+		t.Errorf("failed ParseLines: diff (-got +want):\n%s", diff)
+	}
+}
+
+// 拡張文法でなければ、文字列定値の「\」はエスケープシーケンスではなく書かれたままの文字
+func TestParseLinesRawString(t *testing.T) {
+	lines := []string{
+		"class Main {",
+		"  function void main() {",
+		`    do Output.printString("C:\dir");`,
+		"    return;",
+		"  }",
+		"}",
+	}
+	unit, err := ParseLines("Main.jack", lines, NewOptions())
+	if err != nil {
+		t.Fatalf("failed ParseLines: %+v", err)
+	}
+	want := []string{
+		"function Main.main 0",
+		"push constant 6",
+		"call String.new 1",
+		"pop temp 0",
+		"push constant 67",
+		"push temp 0",
+		"call String.appendChar 2",
+		"pop temp 0",
+		"push constant 58",
+		"push temp 0",
+		"call String.appendChar 2",
+		"pop temp 0",
+		"push constant 92",
+		"push temp 0",
+		"call String.appendChar 2",
+		"pop temp 0",
+		"push constant 100",
+		"push temp 0",
+		"call String.appendChar 2",
+		"pop temp 0",
+		"push constant 105",
+		"push temp 0",
+		"call String.appendChar 2",
+		"pop temp 0",
+		"push constant 114",
+		"push temp 0",
+		"call String.appendChar 2",
+		"call Output.printString 1",
+		"pop temp 0",
+		"push constant 0",
+		"return",
+		"",
+	}
+	if diff := cmp.Diff(unit.Code, want); diff != "" {
+		t.Errorf("failed ParseLines: diff (-got +want):\n%s", diff)
+	}
+}
+
+// デバッグ出力はOptions.Debugを指定した場合だけ、DebugWriterへ書き込む
+func TestParseLinesDebug(t *testing.T) {
+	lines := []string{
+		"class Main {",
+		"  function void main() {",
+		"    return;",
+		"  }",
+		"}",
+	}
+
+	for _, debug := range []bool{false, true} {
+		var buffer bytes.Buffer
+		options := NewOptions()
+		options.Debug = debug
+		options.DebugWriter = &buffer
+		if _, err := ParseLines("Main.jack", lines, options); err != nil {
+			t.Fatalf("failed ParseLines: %+v", err)
+		}
+
+		if got := strings.Contains(buffer.String(), "function Main.main 0"); got != debug {
+			t.Errorf("failed ParseLines: debug = %v, output = %q", debug, buffer.String())
+		}
+	}
+}
+
+func TestUnitToJSON(t *testing.T) {
+	lines := []string{
+		"class Main {",
+		"  function void main() {",
+		"    var Array a;",
+		"    let a = Array.new(1);",
+		"    return;",
+		"  }",
+		"}",
+	}
+	unit, err := ParseLines("Main.jack", lines, NewOptions())
+	if err != nil {
+		t.Fatalf("failed ParseLines: %+v", err)
+	}
+
+	content, err := unit.ToJSON()
+	if err != nil {
+		t.Fatalf("failed ToJSON: %+v", err)
+	}
+
+	// JSONとして読み戻せて、変数と呼び出し先が解決されている
+	node := &parsing.JSONNode{}
+	if err := json.Unmarshal(content, node); err != nil {
+		t.Fatalf("failed json.Unmarshal: %+v", err)
+	}
+	let := node.Children[0].Children[1].Children[0]
+	got := []interface{}{let.Children[0].Symbol, let.Children[1].Children[0].Target}
+	want := []interface{}{
+		&parsing.JSONSymbol{Kind: "local", Type: "Array", Index: 0},
+		&parsing.JSONCallTarget{ClassName: "Array", SubroutineName: "new", Method: false},
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("failed ToJSON: diff (-got +want):\n%s", diff)
+	}
+	if !strings.Contains(string(content), `"line": 4`) {
+		t.Errorf("failed ToJSON: positions are missing: %s", content)
+	}
+}
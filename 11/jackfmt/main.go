@@ -0,0 +1,88 @@
+package main
+
+import (
+	"../format"
+	"../parsing"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// Jackのソースコードを整形する
+// 引数にはjackファイルかディレクトリを指定する（複数指定可）
+//
+//	jackfmt Fixture/Square            ファイルを整形して上書きする
+//	jackfmt -check Fixture/Square     未整形のファイルを表示して、あれば終了コード1で終了する
+//	jackfmt -split-decls Main.jack    「var int x, y;」を一行一宣言に分割する
+//	jackfmt -extended Main.jack       拡張文法のソースを整形する
+func main() {
+	check := flag.Bool("check", false, "未整形のファイルを表示して、あれば終了コード1で終了する")
+	split := flag.Bool("split-decls", false, "変数宣言を一行一宣言に分割する")
+	extended := flag.Bool("extended", false, "拡張文法のソースとして解析する")
+	flag.Parse()
+
+	options := parsing.NewSourceOptions()
+	options.SplitDeclarations = *split
+
+	unformatted, err := run(flag.Args(), options, *extended, *check)
+	if err != nil {
+		log.Fatalf("%+v\n", err)
+	}
+
+	if *check && len(unformatted) > 0 {
+		os.Exit(1)
+	}
+}
+
+func run(args []string, options *parsing.SourceOptions, extended bool, check bool) ([]string, error) {
+	files, err := jackFiles(args)
+	if err != nil {
+		return nil, err
+	}
+
+	formatter := format.NewFormatter(options)
+	formatter.SetExtended(extended)
+	unformatted := []string{}
+	for _, file := range files {
+		result, err := formatter.FormatFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		if result.IsFormatted() {
+			continue
+		}
+		unformatted = append(unformatted, file)
+
+		if check {
+			fmt.Println(file)
+			continue
+		}
+
+		err = result.Write()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return unformatted, nil
+}
+
+// ディレクトリが指定された場合は、直下のjackファイルを対象にする
+func jackFiles(args []string) ([]string, error) {
+	files := []string{}
+	for _, arg := range args {
+		if filepath.Ext(arg) == ".jack" {
+			files = append(files, arg)
+			continue
+		}
+
+		matches, err := filepath.Glob(filepath.Join(arg, "*.jack"))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	return files, nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"../lint"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// Jackのソースコードを静的にチェックして、間違いの可能性が高いコードを警告する
+// 引数にはjackファイルかディレクトリを指定する（複数指定可）
+// 警告があった場合は終了コード1で終了する
+//
+//	jacklint Fixture/Square
+//	jacklint -config jacklint.json Fixture/Square/Main.jack
+//	jacklint -extended Main.jack
+func main() {
+	configFile := flag.String("config", "", "ルールの有効・無効を設定するJSONファイル（省略時はカレントディレクトリのjacklint.json）")
+	extended := flag.Bool("extended", false, "拡張文法のソースとして解析する")
+	flag.Parse()
+
+	warnings, err := run(flag.Args(), *configFile, *extended)
+	if err != nil {
+		log.Fatalf("%+v\n", err)
+	}
+
+	for _, warning := range warnings {
+		fmt.Println(warning.String())
+	}
+	if len(warnings) > 0 {
+		os.Exit(1)
+	}
+}
+
+func run(args []string, configFile string, extended bool) ([]*lint.Warning, error) {
+	config, err := loadConfig(configFile)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := jackFiles(args)
+	if err != nil {
+		return nil, err
+	}
+
+	linter := lint.NewLinter(config)
+	linter.SetExtended(extended)
+	result := []*lint.Warning{}
+	for _, file := range files {
+		warnings, err := linter.LintFile(file)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, warnings...)
+	}
+	return result, nil
+}
+
+const defaultConfigFile = "jacklint.json"
+
+func loadConfig(configFile string) (*lint.Config, error) {
+	if configFile != "" {
+		return lint.LoadConfig(configFile)
+	}
+
+	if _, err := os.Stat(defaultConfigFile); err == nil {
+		return lint.LoadConfig(defaultConfigFile)
+	}
+	return lint.NewConfig(), nil
+}
+
+// ディレクトリが指定された場合は、直下のjackファイルを対象にする
+func jackFiles(args []string) ([]string, error) {
+	files := []string{}
+	for _, arg := range args {
+		if filepath.Ext(arg) == ".jack" {
+			files = append(files, arg)
+			continue
+		}
+
+		matches, err := filepath.Glob(filepath.Join(arg, "*.jack"))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	return files, nil
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"../lsp"
+	"flag"
+	"log"
+	"os"
+)
+
+// JackのLanguage Serverを標準入出力で起動する
+// ログは標準出力に混ざるとプロトコルが壊れるので、標準エラー出力に出す
+//
+//	jacklsp              標準の文法で解析する
+//	jacklsp -extended    拡張文法のソースとして解析する
+func main() {
+	extended := flag.Bool("extended", false, "拡張文法のソースとして解析する")
+	flag.Parse()
+	log.SetOutput(os.Stderr)
+
+	server := lsp.NewServer(os.Stdin, os.Stdout)
+	server.SetExtended(*extended)
+	if err := server.Serve(); err != nil {
+		log.Fatalf("%+v\n", err)
+	}
+}
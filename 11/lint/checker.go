@@ -0,0 +1,448 @@
+package lint
+
+import (
+	"../parsing"
+	"../symbol"
+	"../token"
+	"fmt"
+)
+
+// 宣言した変数と、宣言している名前のトークン
+type declaration struct {
+	item  *symbol.SymbolItem
+	token *token.Token
+}
+
+// クラス単位で警告を集める
+// 変数の参照はシンボルテーブルのSymbolItemで識別する
+type checker struct {
+	ctx        *parsing.Context
+	warnings   []*Warning
+	reads      map[*symbol.SymbolItem]int // 値を読み出した回数
+	references map[*symbol.SymbolItem]int // 読み出しと代入を合わせた回数
+	loops      []*loop                    // 生存解析中の、内側のループほど後ろ
+}
+
+func newChecker(ctx *parsing.Context) *checker {
+	return &checker{
+		ctx:        ctx,
+		warnings:   []*Warning{},
+		reads:      map[*symbol.SymbolItem]int{},
+		references: map[*symbol.SymbolItem]int{},
+	}
+}
+
+func (c *checker) warn(position token.Position, rule Rule, message string) {
+	c.warnings = append(c.warnings, &Warning{Position: position, Rule: rule, Message: message})
+}
+
+func (c *checker) checkClass(class *parsing.Class) {
+	fields := []*declaration{}
+	for _, classVarDec := range class.ClassVarDecs.Items {
+		for _, varName := range classVarDec.VarNameItems() {
+			item, err := c.ctx.ClassSymbolTable.Find(varName.Value)
+			if err != nil {
+				continue
+			}
+			fields = append(fields, &declaration{item: item, token: varName.Token})
+		}
+	}
+
+	for _, subroutineDec := range class.SubroutineDecs.Items {
+		c.checkSubroutine(subroutineDec)
+	}
+
+	for _, field := range fields {
+		if c.reads[field.item] == 0 {
+			c.warn(field.token.Position, RuleUnreadField, fmt.Sprintf("%s %s is never read", field.item.ScopeKind, field.token.Value))
+		}
+	}
+}
+
+func (c *checker) checkSubroutine(subroutineDec *parsing.SubroutineDec) {
+	// パース時と同じ手順でサブルーチンのシンボルテーブルを作り直す
+	c.ctx.ResetSubroutine(subroutineDec.SubroutineName.Value)
+	subroutineDec.ParameterList.UpdateSymbolTable(c.ctx)
+	for _, varDec := range subroutineDec.SubroutineBody.VarDecs.Items {
+		varDec.UpdateSymbolTable(c.ctx)
+	}
+
+	parameters := []*declaration{}
+	for _, parameter := range subroutineDec.ParameterList.ParameterItems() {
+		parameters = append(parameters, c.localDeclaration(parameter.VarName))
+	}
+
+	locals := []*declaration{}
+	for _, varDec := range subroutineDec.SubroutineBody.VarDecs.Items {
+		for _, varName := range varDec.VarNameItems() {
+			locals = append(locals, c.localDeclaration(varName))
+		}
+	}
+
+	for _, parameter := range parameters {
+		c.checkShadowing(parameter, "parameter")
+	}
+	for _, local := range locals {
+		c.checkShadowing(local, "local variable")
+	}
+
+	statements := subroutineDec.SubroutineBody.Statements
+	c.countReferences(statements)
+
+	for _, parameter := range parameters {
+		if c.references[parameter.item] == 0 {
+			c.warn(parameter.token.Position, RuleUnusedParameter, fmt.Sprintf("parameter %s is never used", parameter.token.Value))
+		}
+	}
+	for _, local := range locals {
+		if c.references[local.item] == 0 {
+			c.warn(local.token.Position, RuleUnusedVariable, fmt.Sprintf("local variable %s is never used", local.token.Value))
+		}
+	}
+
+	c.liveIn(statements, liveSet{}, true)
+	c.checkPrecedence(statements)
+
+	if !c.checkReachability(statements, true) {
+		end := c.ctx.FindSpan(subroutineDec.SubroutineBody).End
+		c.warn(end, RuleMissingReturn, fmt.Sprintf("missing return at end of subroutine %s", subroutineDec.SubroutineName.Value))
+	}
+}
+
+func (c *checker) checkShadowing(local *declaration, kind string) {
+	if item, err := c.ctx.ClassSymbolTable.Find(local.token.Value); err == nil {
+		c.warn(local.token.Position, RuleShadowedField, fmt.Sprintf("%s %s shadows %s %s", kind, local.token.Value, item.ScopeKind, local.token.Value))
+	}
+}
+
+func (c *checker) localDeclaration(varName *parsing.VarName) *declaration {
+	// 同じ名前の変数が重複して宣言されている場合は、最初の宣言に対応するSymbolItemになる
+	item, _ := c.ctx.SubroutineSymbolTable.Find(varName.Value)
+	return &declaration{item: item, token: varName.Token}
+}
+
+// 変数の参照を数える
+// let文の代入先は読み出しに含めない
+func (c *checker) countReferences(statements *parsing.Statements) {
+	var visit func(node interface{}) bool
+	visit = func(node interface{}) bool {
+		switch n := node.(type) {
+		case *parsing.LetStatement:
+			return c.countAssignment(n.VarName, n.Expression, visit)
+		case *parsing.Assignment:
+			return c.countAssignment(n.VarName, n.Expression, visit)
+		case *parsing.VarName:
+			c.read(n.Value)
+		case *parsing.SubroutineCall:
+			if n.CallerName != nil {
+				c.read(n.CallerName.Value)
+			}
+		}
+		return true
+	}
+	parsing.Walk(statements, visit)
+}
+
+// 代入先の変数は参照だけ数え、右辺を続けてたどる
+// 配列への代入は添字を読み出すので、falseを返さずに子ノードをたどらせる
+func (c *checker) countAssignment(varName *parsing.VarName, expression *parsing.Expression, visit func(node interface{}) bool) bool {
+	if varName == nil {
+		return true
+	}
+	if item, err := c.ctx.FindSymbolItem(varName.Value); err == nil {
+		c.references[item]++
+	}
+	parsing.Walk(expression, visit)
+	return false
+}
+
+func (c *checker) read(name string) {
+	item, err := c.ctx.FindSymbolItem(name)
+	if err != nil {
+		return
+	}
+	c.reads[item]++
+	c.references[item]++
+}
+
+// 生存している（この後で値が読み出される）ローカル変数と引数の集合
+type liveSet map[*symbol.SymbolItem]bool
+
+// break文、continue文の後ろで生存している変数
+type loop struct {
+	exit liveSet // ループの後ろ
+	next liveSet // 次の繰り返しの先頭（for文ではstepの前）
+}
+
+func (l liveSet) copy() liveSet {
+	result := liveSet{}
+	for item := range l {
+		result[item] = true
+	}
+	return result
+}
+
+func (l liveSet) union(other liveSet) liveSet {
+	result := l.copy()
+	for item := range other {
+		result[item] = true
+	}
+	return result
+}
+
+func (l liveSet) equals(other liveSet) bool {
+	if len(l) != len(other) {
+		return false
+	}
+	for item := range l {
+		if !other[item] {
+			return false
+		}
+	}
+	return true
+}
+
+// サブルーチンのスコープの変数だけを返す
+// フィールドやスタティック変数は他のサブルーチンから読まれるので、生存解析の対象外
+func (c *checker) local(name string) *symbol.SymbolItem {
+	item, err := c.ctx.SubroutineSymbolTable.Find(name)
+	if err != nil {
+		return nil
+	}
+	return item
+}
+
+// ノードの中で読み出しているローカル変数と引数
+func (c *checker) usedLocals(node interface{}) liveSet {
+	result := liveSet{}
+	parsing.Walk(node, func(node interface{}) bool {
+		switch n := node.(type) {
+		case *parsing.VarName:
+			if item := c.local(n.Value); item != nil {
+				result[item] = true
+			}
+		case *parsing.SubroutineCall:
+			if n.CallerName != nil {
+				if item := c.local(n.CallerName.Value); item != nil {
+					result[item] = true
+				}
+			}
+		}
+		return true
+	})
+	return result
+}
+
+// 文の並びを後ろからたどり、先頭で生存している変数を求める
+// reportがtrueなら、代入した値が読み出されないlet文を警告する
+func (c *checker) liveIn(statements *parsing.Statements, out liveSet, report bool) liveSet {
+	live := out.copy()
+	for i := len(statements.Items) - 1; i >= 0; i-- {
+		live = c.liveInStatement(statements.Items[i], live, report)
+	}
+	return live
+}
+
+func (c *checker) liveInStatement(statement parsing.Statement, live liveSet, report bool) liveSet {
+	switch s := statement.(type) {
+	case *parsing.LetStatement:
+		return c.liveInAssignment(s.VarName, s.Array, s.Expression, live, report)
+	case *parsing.DoStatement:
+		return live.union(c.usedLocals(s.SubroutineCall))
+	case *parsing.ReturnStatement:
+		// return文の後ろで読み出される変数はない
+		if s.Expression == nil {
+			return liveSet{}
+		}
+		return c.usedLocals(s.Expression)
+	case *parsing.IfStatement:
+		result := c.liveIn(s.Statements, live, report)
+		if s.ElseBlock != nil {
+			result = result.union(c.liveIn(s.ElseBlock.Statements, live, report))
+		} else {
+			result = result.union(live)
+		}
+		return result.union(c.usedLocals(s.Expression))
+	case *parsing.WhileStatement:
+		// ループの先頭で生存している変数が収束するまで繰り返す
+		condition := c.usedLocals(s.Expression)
+		head := live.union(condition)
+		for {
+			next := live.union(condition).union(c.liveInLoop(s.Statements, head, head, live, false))
+			if next.equals(head) {
+				break
+			}
+			head = next
+		}
+		if report {
+			c.liveInLoop(s.Statements, head, head, live, true)
+		}
+		return head
+	case *parsing.ForStatement:
+		// 条件を省略した場合はbreak文でしかループを抜けない
+		exit := liveSet{}
+		condition := liveSet{}
+		if s.Condition != nil {
+			exit = live
+			condition = c.usedLocals(s.Condition)
+		}
+		head := exit.union(condition)
+		for {
+			step := c.liveInStep(s.Step, head, false)
+			next := exit.union(condition).union(c.liveInLoop(s.Statements, step, step, live, false))
+			if next.equals(head) {
+				break
+			}
+			head = next
+		}
+		if report {
+			step := c.liveInStep(s.Step, head, true)
+			c.liveInLoop(s.Statements, step, step, live, true)
+		}
+		if s.Init == nil {
+			return head
+		}
+		return c.liveInAssignment(s.Init.VarName, s.Init.Array, s.Init.Expression, head, report)
+	case *parsing.BreakStatement:
+		if len(c.loops) > 0 {
+			return c.loops[len(c.loops)-1].exit.copy()
+		}
+	case *parsing.ContinueStatement:
+		if len(c.loops) > 0 {
+			return c.loops[len(c.loops)-1].next.copy()
+		}
+	}
+	return live
+}
+
+// let文とfor文の代入
+func (c *checker) liveInAssignment(varName *parsing.VarName, array *parsing.Array, expression *parsing.Expression, live liveSet, report bool) liveSet {
+	result := live.copy()
+	if varName != nil {
+		if item := c.local(varName.Value); item != nil {
+			if report && !live[item] {
+				c.warn(varName.Position, RuleDeadStore, fmt.Sprintf("value assigned to %s is never read", varName.Value))
+			}
+			delete(result, item)
+		}
+	} else {
+		result = result.union(c.usedLocals(array))
+	}
+	return result.union(c.usedLocals(expression))
+}
+
+func (c *checker) liveInStep(step *parsing.Assignment, live liveSet, report bool) liveSet {
+	if step == nil {
+		return live
+	}
+	return c.liveInAssignment(step.VarName, step.Array, step.Expression, live, report)
+}
+
+// ループの本体をたどる
+// nextはcontinue文、exitはbreak文の後ろで生存している変数
+func (c *checker) liveInLoop(statements *parsing.Statements, out liveSet, next liveSet, exit liveSet, report bool) liveSet {
+	c.loops = append(c.loops, &loop{exit: exit, next: next})
+	defer func() { c.loops = c.loops[:len(c.loops)-1] }()
+	return c.liveIn(statements, out, report)
+}
+
+// Jackは二項演算子を左から順に計算するので、「a + b * c」は「(a + b) * c」になる
+// 一般的な優先順位で読むと結果が変わる式を、実際の計算順が分かるカッコ付きの形で警告する
+func (c *checker) checkPrecedence(statements *parsing.Statements) {
+	parsing.Walk(statements, func(node interface{}) bool {
+		if expression, ok := node.(*parsing.Expression); ok && parsing.HasPrecedenceConflict(expression) {
+			message := fmt.Sprintf("%s is evaluated left to right as %s", expression.ToSource(), leftToRight(expression))
+			c.warn(c.ctx.FindSpan(expression).Start, RulePrecedence, message)
+		}
+		return true
+	})
+}
+
+// 左から順に計算する順番を、優先順位に従って読んでも同じになるようにカッコを付けて表す
+// 優先順位の低い演算子を含む左側の部分に、優先順位の高い演算子が続く場合だけカッコで囲む
+func leftToRight(expression *parsing.Expression) string {
+	result := expression.Term.ToSource()
+	lowest := -1 // resultに含まれる演算子の優先順位の最小値（演算子がなければ-1）
+	for _, item := range expression.BinaryOpTerms.Items {
+		precedence := parsing.Precedence(item.BinaryOp)
+		if lowest >= 0 && lowest < precedence {
+			result = "(" + result + ")"
+			lowest = precedence
+		}
+		if lowest < 0 || precedence < lowest {
+			lowest = precedence
+		}
+		result += fmt.Sprintf(" %s %s", item.BinaryOp.ToSource(), item.Term.ToSource())
+	}
+	return result
+}
+
+// 文の並びの最後まで実行されずに必ずreturnするならtrueを返す
+// return文の後ろの文は到達できないので警告する
+// break文、continue文の後ろの文も到達できないが、サブルーチンからは戻らない
+// bodyはサブルーチン本体の文の並びならtrue
+func (c *checker) checkReachability(statements *parsing.Statements, body bool) bool {
+	terminated := false
+	jumped := false
+	infinite := false // 直前の文が抜けられない無限ループ
+	for i, item := range statements.Items {
+		if terminated || jumped {
+			// Jackはサブルーチンの最後にreturn文が必要なので、
+			// Sys.haltのように無限ループの後ろに置いた最後のreturn文は警告しない
+			if _, ok := item.(*parsing.ReturnStatement); ok && body && infinite && i == len(statements.Items)-1 {
+				return true
+			}
+			c.warn(c.ctx.FindSpan(item).Start, RuleUnreachable, "unreachable statement")
+			return true
+		}
+
+		infinite = false
+		switch s := item.(type) {
+		case *parsing.ReturnStatement:
+			terminated = true
+		case *parsing.IfStatement:
+			then := c.checkReachability(s.Statements, false)
+			otherwise := false
+			if s.ElseBlock != nil {
+				otherwise = c.checkReachability(s.ElseBlock.Statements, false)
+			}
+			terminated = then && otherwise
+		case *parsing.WhileStatement:
+			c.checkReachability(s.Statements, false)
+			terminated = isTrue(s.Expression) && !breaks(s.Statements)
+			infinite = terminated
+		case *parsing.ForStatement:
+			c.checkReachability(s.Statements, false)
+			terminated = (s.Condition == nil || isTrue(s.Condition)) && !breaks(s.Statements)
+			infinite = terminated
+		case *parsing.BreakStatement, *parsing.ContinueStatement:
+			jumped = true
+		}
+	}
+	return terminated
+}
+
+// 文の並びがこのループを抜けるbreak文を含むならtrueを返す
+// 内側のループのbreak文は、そのループを抜けるだけなので含めない
+func breaks(statements *parsing.Statements) bool {
+	for _, item := range statements.Items {
+		switch s := item.(type) {
+		case *parsing.BreakStatement:
+			return true
+		case *parsing.IfStatement:
+			if breaks(s.Statements) || (s.ElseBlock != nil && breaks(s.ElseBlock.Statements)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// while (true) のように条件が定数のtrueならtrueを返す
+func isTrue(expression *parsing.Expression) bool {
+	if expression.BinaryOpTerms != nil && len(expression.BinaryOpTerms.Items) > 0 {
+		return false
+	}
+	_, ok := expression.Term.(*parsing.TrueKeywordConstant)
+	return ok
+}
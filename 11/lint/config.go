@@ -0,0 +1,76 @@
+package lint
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"io/ioutil"
+)
+
+type Rule string
+
+const (
+	RuleUnusedVariable  Rule = "unused-variable"     // 一度も参照されないローカル変数
+	RuleUnusedParameter Rule = "unused-parameter"    // 一度も参照されない引数
+	RuleUnreadField     Rule = "unread-field"        // 一度も読み出されないフィールドとスタティック変数
+	RuleDeadStore       Rule = "dead-store"          // 代入した値がその後読み出されないlet文
+	RuleUnreachable     Rule = "unreachable-code"    // return文の後ろにあって実行されない文
+	RuleMissingReturn   Rule = "missing-return"      // return文を通らずにサブルーチンの末尾に到達する
+	RuleShadowedField   Rule = "shadowed-field"      // フィールドと同じ名前のローカル変数や引数
+	RulePrecedence      Rule = "operator-precedence" // 左から順に計算すると、一般的な演算子の優先順位と結果が変わる式
+)
+
+var AllRules = []Rule{
+	RuleUnusedVariable,
+	RuleUnusedParameter,
+	RuleUnreadField,
+	RuleDeadStore,
+	RuleUnreachable,
+	RuleMissingReturn,
+	RuleShadowedField,
+	RulePrecedence,
+}
+
+// ルールごとの有効・無効の設定
+// 設定ファイルはJSONで、指定しなかったルールは有効になる
+//
+//	{"rules": {"unused-parameter": false}}
+type Config struct {
+	Rules map[Rule]bool `json:"rules"`
+}
+
+func NewConfig() *Config {
+	rules := map[Rule]bool{}
+	for _, rule := range AllRules {
+		rules[rule] = true
+	}
+	return &Config{Rules: rules}
+}
+
+func LoadConfig(filename string) (*Config, error) {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return ParseConfig(content)
+}
+
+func ParseConfig(content []byte) (*Config, error) {
+	loaded := &Config{}
+	if err := json.Unmarshal(content, loaded); err != nil {
+		return nil, errors.Wrap(err, "invalid config")
+	}
+
+	config := NewConfig()
+	for rule, enabled := range loaded.Rules {
+		if _, ok := config.Rules[rule]; !ok {
+			return nil, errors.New(fmt.Sprintf("invalid config: unknown rule = %s", rule))
+		}
+		config.Rules[rule] = enabled
+	}
+	return config, nil
+}
+
+func (c *Config) IsEnabled(rule Rule) bool {
+	return c.Rules[rule]
+}
@@ -0,0 +1,139 @@
+package lint
+
+import (
+	"../io"
+	"../parsing"
+	"../token"
+	"fmt"
+	"github.com/pkg/errors"
+	"sort"
+	"strings"
+)
+
+// コンパイルは通るが、間違いの可能性が高いコードへの警告
+type Warning struct {
+	Filename string
+	token.Position
+	Rule
+	Message string
+}
+
+func (w *Warning) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s (%s)", w.Filename, w.Line, w.Column, w.Message, w.Rule)
+}
+
+type Linter struct {
+	config   *Config
+	extended bool
+}
+
+func NewLinter(config *Config) *Linter {
+	return &Linter{config: config, extended: false}
+}
+
+// 拡張文法のソースとして解析する
+func (l *Linter) SetExtended(extended bool) {
+	l.extended = extended
+}
+
+func (l *Linter) LintFile(filename string) ([]*Warning, error) {
+	src := io.NewSrc(filename)
+	if err := src.Setup(); err != nil {
+		return nil, err
+	}
+	return l.lint(src)
+}
+
+func (l *Linter) LintLines(filename string, lines []string) ([]*Warning, error) {
+	src := io.NewSrc(filename)
+	src.SetupLines(lines)
+	return l.lint(src)
+}
+
+func (l *Linter) lint(src *io.Src) ([]*Warning, error) {
+	tokenizer := token.NewTokenizerWithPositions(src.Lines, src.Positions)
+	tokenizer.SetExtended(l.extended)
+	tokens := tokenizer.Tokenize()
+	ctx := parsing.NewContext(src.ClassName())
+	ctx.Extended = l.extended
+	parser := parsing.NewParserWithContext(tokens, ctx)
+	class, err := parser.Parse()
+	if err != nil {
+		return nil, errors.WithMessage(err, src.Filename)
+	}
+
+	checker := newChecker(ctx)
+	checker.checkClass(class)
+
+	ignores := newIgnores(src.Comments)
+	result := []*Warning{}
+	for _, warning := range checker.warnings {
+		if !l.config.IsEnabled(warning.Rule) || ignores.isIgnored(warning) {
+			continue
+		}
+		warning.Filename = src.Filename
+		result = append(result, warning)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Line != result[j].Line {
+			return result[i].Line < result[j].Line
+		}
+		return result[i].Column < result[j].Column
+	})
+	return result, nil
+}
+
+const ignoreDirective = "jacklint:ignore"
+
+// 「// jacklint:ignore」コメントで警告を抑制する行
+// 「//jacklint:ignore」のように空白がなくてもよいが、「// jacklint:ignored」は対象外
+// コードの後ろに書いた場合はその行、単独の行に書いた場合は次の行が対象になる
+// 「// jacklint:ignore dead-store, unused-variable」のようにルールを指定することもできる
+type ignores struct {
+	lines map[int][]Rule // nilならすべてのルールを抑制する
+}
+
+func newIgnores(comments []*token.Comment) *ignores {
+	lines := map[int][]Rule{}
+	for _, comment := range comments {
+		body := strings.TrimSpace(strings.TrimPrefix(comment.Value, "//"))
+		fields := strings.Fields(body)
+		if len(fields) == 0 || fields[0] != ignoreDirective {
+			continue
+		}
+
+		line := comment.Line + 1
+		if comment.Trailing {
+			line = comment.Line
+		}
+
+		var rules []Rule
+		for _, field := range strings.FieldsFunc(body[len(ignoreDirective):], isRuleSeparator) {
+			rules = append(rules, Rule(field))
+		}
+		lines[line] = rules
+	}
+	return &ignores{lines: lines}
+}
+
+func isRuleSeparator(r rune) bool {
+	return r == ',' || r == ' ' || r == '\t'
+}
+
+func (i *ignores) isIgnored(warning *Warning) bool {
+	rules, ok := i.lines[warning.Line]
+	if !ok {
+		return false
+	}
+	if rules == nil {
+		return true
+	}
+
+	for _, rule := range rules {
+		if rule == warning.Rule {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,442 @@
+package lint
+
+import (
+	"github.com/google/go-cmp/cmp"
+	"testing"
+)
+
+func TestLinterLintLines(t *testing.T) {
+	cases := []struct {
+		desc  string
+		lines []string
+		want  []string
+	}{
+		{
+			desc: "警告なし",
+			lines: []string{
+				"class Main {",
+				"    field int x;",
+				"    method int get(int step) {",
+				"        var int i;",
+				"        let i = 0;",
+				"        while (i < step) {",
+				"            let i = i + 1;",
+				"        }",
+				"        return x + i;",
+				"    }",
+				"}",
+			},
+			want: []string{},
+		},
+		{
+			desc: "使われていないローカル変数と引数",
+			lines: []string{
+				"class Main {",
+				"    function void run(int a, int b) {",
+				"        var int i, j;",
+				"        do Output.printInt(a + j);",
+				"        return;",
+				"    }",
+				"}",
+			},
+			want: []string{
+				"Main.jack:2:34: parameter b is never used (unused-parameter)",
+				"Main.jack:3:17: local variable i is never used (unused-variable)",
+			},
+		},
+		{
+			desc: "読み出されないフィールド",
+			lines: []string{
+				"class Main {",
+				"    field int x, y;",
+				"    static int count;",
+				"    method void set(int v) {",
+				"        let x = v;",
+				"        let count = y;",
+				"        return;",
+				"    }",
+				"}",
+			},
+			want: []string{
+				"Main.jack:2:15: field x is never read (unread-field)",
+				"Main.jack:3:16: static count is never read (unread-field)",
+			},
+		},
+		{
+			desc: "代入した値が読み出されないlet文",
+			lines: []string{
+				"class Main {",
+				"    function int run(int a) {",
+				"        var int i, j;",
+				"        let i = 1;",
+				"        let i = 2;",
+				"        if (a) { let j = i; } else { let j = 0; }",
+				"        let a = j;",
+				"        return j;",
+				"    }",
+				"}",
+			},
+			want: []string{
+				"Main.jack:4:13: value assigned to i is never read (dead-store)",
+				"Main.jack:7:13: value assigned to a is never read (dead-store)",
+			},
+		},
+		{
+			desc: "ループの次の周回で読み出される代入は警告しない",
+			lines: []string{
+				"class Main {",
+				"    function void run() {",
+				"        var int i, last;",
+				"        let i = 0;",
+				"        while (i < 10) {",
+				"            do Output.printInt(last);",
+				"            let last = i;",
+				"            let i = i + 1;",
+				"        }",
+				"        return;",
+				"    }",
+				"}",
+			},
+			want: []string{},
+		},
+		{
+			desc: "return文の後ろの文と、returnのないサブルーチン",
+			lines: []string{
+				"class Main {",
+				"    function int run(boolean a) {",
+				"        if (a) {",
+				"            return 1;",
+				"            do Output.println();",
+				"        } else {",
+				"            return 2;",
+				"        }",
+				"        return 3;",
+				"    }",
+				"    function void loop() {",
+				"        while (true) {",
+				"            do Output.println();",
+				"        }",
+				"    }",
+				"    function void missing(boolean a) {",
+				"        if (a) {",
+				"            return;",
+				"        }",
+				"    }",
+				"}",
+			},
+			want: []string{
+				"Main.jack:5:13: unreachable statement (unreachable-code)",
+				"Main.jack:9:9: unreachable statement (unreachable-code)",
+				"Main.jack:20:5: missing return at end of subroutine missing (missing-return)",
+			},
+		},
+		{
+			desc: "無限ループの後ろの最後のreturn文は、Jackの文法上必要なので警告しない",
+			lines: []string{
+				"class Main {",
+				"    function void halt() {",
+				"        while (true) {",
+				"        }",
+				"        return;",
+				"    }",
+				"    function void error(int code) {",
+				"        do Output.printInt(code);",
+				"        while (true) {",
+				"        }",
+				"        do Output.println();",
+				"        return;",
+				"    }",
+				"    function int run(boolean a) {",
+				"        if (a) {",
+				"            while (true) {",
+				"            }",
+				"            return 1;",
+				"        }",
+				"        return 2;",
+				"    }",
+				"}",
+			},
+			want: []string{
+				"Main.jack:11:9: unreachable statement (unreachable-code)",
+				"Main.jack:18:13: unreachable statement (unreachable-code)",
+			},
+		},
+		{
+			desc: "フィールドと同じ名前のローカル変数と引数",
+			lines: []string{
+				"class Main {",
+				"    field int x, y;",
+				"    method int run(int x) {",
+				"        var int y;",
+				"        let y = x;",
+				"        return y;",
+				"    }",
+				"}",
+			},
+			want: []string{
+				"Main.jack:2:15: field x is never read (unread-field)",
+				"Main.jack:2:18: field y is never read (unread-field)",
+				"Main.jack:3:24: parameter x shadows field x (shadowed-field)",
+				"Main.jack:4:17: local variable y shadows field y (shadowed-field)",
+			},
+		},
+		{
+			desc: "優先順位で読むと計算順が変わる式",
+			lines: []string{
+				"class Main {",
+				"    function int run(int a, int b, int c) {",
+				"        if ((a + b * c) < 10) {",
+				"            return a * b + c;",
+				"        }",
+				"        return a < b & b < c;",
+				"    }",
+				"}",
+			},
+			want: []string{
+				"Main.jack:3:14: a + b * c is evaluated left to right as (a + b) * c (operator-precedence)",
+				"Main.jack:6:16: a < b & b < c is evaluated left to right as (a < b & b) < c (operator-precedence)",
+			},
+		},
+		{
+			desc: "jacklint:ignoreコメントで警告を抑制",
+			lines: []string{
+				"class Main {",
+				"    function void run(int a) { // jacklint:ignore",
+				"        var int i, j;",
+				"        // jacklint:ignore unused-variable",
+				"        var int k;",
+				"        // jacklint:ignore dead-store",
+				"        var int l;",
+				"        return;",
+				"    }",
+				"}",
+			},
+			want: []string{
+				"Main.jack:3:17: local variable i is never used (unused-variable)",
+				"Main.jack:3:20: local variable j is never used (unused-variable)",
+				"Main.jack:7:17: local variable l is never used (unused-variable)",
+			},
+		},
+		{
+			desc: "//の後ろに空白がなくても抑制し、jacklint:ignoredは抑制しない",
+			lines: []string{
+				"class Main {",
+				"    function void run() {",
+				"        //jacklint:ignore unused-variable",
+				"        var int i;",
+				"        // jacklint:ignored",
+				"        var int j;",
+				"        var int k; //jacklint:ignore",
+				"        return;",
+				"    }",
+				"}",
+			},
+			want: []string{
+				"Main.jack:6:17: local variable j is never used (unused-variable)",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			linter := NewLinter(NewConfig())
+			warnings, err := linter.LintLines("Main.jack", tc.lines)
+			if err != nil {
+				t.Fatalf("failed LintLines: %+v", err)
+			}
+
+			got := []string{}
+			for _, warning := range warnings {
+				got = append(got, warning.String())
+			}
+			if diff := cmp.Diff(got, tc.want); diff != "" {
+				t.Errorf("failed LintLines: diff (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseConfig(t *testing.T) {
+	cases := []struct {
+		desc    string
+		content string
+		want    map[Rule]bool
+		isError bool
+	}{
+		{
+			desc:    "指定したルールだけ無効になる",
+			content: `{"rules": {"unused-parameter": false, "dead-store": false}}`,
+			want: map[Rule]bool{
+				RuleUnusedVariable:  true,
+				RuleUnusedParameter: false,
+				RuleUnreadField:     true,
+				RuleDeadStore:       false,
+				RuleUnreachable:     true,
+				RuleMissingReturn:   true,
+				RuleShadowedField:   true,
+				RulePrecedence:      true,
+			},
+		},
+		{
+			desc:    "存在しないルール",
+			content: `{"rules": {"unknown-rule": false}}`,
+			isError: true,
+		},
+		{
+			desc:    "JSONではない",
+			content: `rules`,
+			isError: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			config, err := ParseConfig([]byte(tc.content))
+			if tc.isError {
+				if err == nil {
+					t.Errorf("failed ParseConfig: expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed ParseConfig: %+v", err)
+			}
+
+			if diff := cmp.Diff(config.Rules, tc.want); diff != "" {
+				t.Errorf("failed ParseConfig: diff (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestLinterDisabledRule(t *testing.T) {
+	lines := []string{
+		"class Main {",
+		"    function void run(int a) {",
+		"        return;",
+		"    }",
+		"}",
+	}
+
+	config := NewConfig()
+	config.Rules[RuleUnusedParameter] = false
+	warnings, err := NewLinter(config).LintLines("Main.jack", lines)
+	if err != nil {
+		t.Fatalf("failed LintLines: %+v", err)
+	}
+
+	if len(warnings) != 0 {
+		t.Errorf("failed LintLines: got = %s", warnings[0].String())
+	}
+}
+
+func TestLinterLintLinesExtended(t *testing.T) {
+	cases := []struct {
+		desc  string
+		lines []string
+		want  []string
+	}{
+		{
+			desc: "for文の変数は代入だけでは使ったことにならない",
+			lines: []string{
+				"class Main {",
+				"    const int SIZE = 4;",
+				"    function int sum() {",
+				"        var int i, j, sum;",
+				"        for (i = 0; i < Main.SIZE; i = i + 1) {",
+				"            let sum = sum + i;",
+				"        }",
+				"        for (j = 0; ; ) {",
+				"            break;",
+				"        }",
+				"        return sum;",
+				"    }",
+				"}",
+			},
+			want: []string{
+				"Main.jack:8:14: value assigned to j is never read (dead-store)",
+			},
+		},
+		{
+			desc: "continue文で次の繰り返しに進む場合、stepで読み出す値は生存している",
+			lines: []string{
+				"class Main {",
+				"    function int count(int n) {",
+				"        var int i, step, result;",
+				"        let step = 1;",
+				"        for (i = 0; i < n; i = i + step) {",
+				"            if (i = 2) {",
+				"                let step = 2;",
+				"                continue;",
+				"            }",
+				"            let result = result + 1;",
+				"        }",
+				"        return result;",
+				"    }",
+				"}",
+			},
+			want: []string{},
+		},
+		{
+			desc: "break文で抜けた後の代入は、ループの後ろで読み出すかで判定する",
+			lines: []string{
+				"class Main {",
+				"    function int find(int n) {",
+				"        var int i, found;",
+				"        let found = -1;",
+				"        while (true) {",
+				"            if (i = n) {",
+				"                let found = i;",
+				"                break;",
+				"            }",
+				"            let i = i + 1;",
+				"        }",
+				"        return found;",
+				"    }",
+				"}",
+			},
+			want: []string{},
+		},
+		{
+			desc: "break文の後ろは到達できず、break文で抜ける無限ループの後ろにはreturnが必要",
+			lines: []string{
+				"class Main {",
+				"    function void run() {",
+				"        while (true) {",
+				"            break;",
+				"            do Output.println();",
+				"        }",
+				"    }",
+				"    function void loop() {",
+				"        for (;;) {",
+				"            continue;",
+				"        }",
+				"    }",
+				"}",
+			},
+			want: []string{
+				"Main.jack:5:13: unreachable statement (unreachable-code)",
+				"Main.jack:7:5: missing return at end of subroutine run (missing-return)",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			linter := NewLinter(NewConfig())
+			linter.SetExtended(true)
+			warnings, err := linter.LintLines("Main.jack", tc.lines)
+			if err != nil {
+				t.Fatalf("failed LintLines: %+v", err)
+			}
+
+			got := []string{}
+			for _, warning := range warnings {
+				got = append(got, warning.String())
+			}
+			if diff := cmp.Diff(got, tc.want); diff != "" {
+				t.Errorf("failed LintLines: diff (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,404 @@
+package lsp
+
+import (
+	"../io"
+	"../parsing"
+	"../symbol"
+	"../token"
+	"fmt"
+	"github.com/pkg/errors"
+	"strings"
+)
+
+type DefinitionKind int
+
+const (
+	_ DefinitionKind = iota
+	DefinitionClass
+	DefinitionVariable
+	DefinitionSubroutine
+)
+
+// ソースファイル内で名前を宣言している箇所
+type Definition struct {
+	Kind           DefinitionKind
+	Name           string
+	URI            string
+	Range          Range              // 宣言全体の範囲
+	SelectionRange Range              // 宣言している名前の範囲
+	Item           *symbol.SymbolItem // 変数の場合のみ
+	Subroutine     *Subroutine        // サブルーチンの場合のみ
+}
+
+// サブルーチンのシグネチャとローカル変数
+type Subroutine struct {
+	ClassName  string
+	Keyword    string // constructor, function, method
+	ReturnType string
+	Name       string
+	Parameters []*symbol.SymbolItem
+	Locals     []*Definition // 引数とローカル変数
+	StartLine  int           // スコープの判定に使う1始まりの行番号
+	EndLine    int
+}
+
+func NewSubroutine(className string, keyword string, returnType string, name string) *Subroutine {
+	return &Subroutine{
+		ClassName:  className,
+		Keyword:    keyword,
+		ReturnType: returnType,
+		Name:       name,
+		Parameters: []*symbol.SymbolItem{},
+		Locals:     []*Definition{},
+	}
+}
+
+// method void moveUp(int x)
+func (s *Subroutine) Signature() string {
+	parameters := []string{}
+	for _, parameter := range s.Parameters {
+		parameters = append(parameters, fmt.Sprintf("%s %s", parameter.SymbolType.Value, parameter.SymbolName.Value))
+	}
+	return fmt.Sprintf("%s %s %s(%s)", s.Keyword, s.ReturnType, s.Name, strings.Join(parameters, ", "))
+}
+
+func (s *Subroutine) Contains(line int) bool {
+	return s.StartLine <= line && line <= s.EndLine
+}
+
+// ソースファイル中で名前が使われている箇所
+// 同じファイル内で解決できた場合はDefinitionを持ち、他のクラスを参照する場合はクラス名とサブルーチン名を持つ
+type Occurrence struct {
+	Token          *token.Token
+	Definition     *Definition
+	ClassName      string
+	SubroutineName string
+}
+
+// 一つのソースファイルを解析した結果
+type Analysis struct {
+	URI         string
+	Lines       []string
+	ClassName   string
+	Class       *Definition
+	Variables   []*Definition // staticとfield
+	Subroutines []*Definition
+	Occurrences []*Occurrence
+}
+
+func (a *Analysis) FindSubroutine(name string) *Definition {
+	for _, subroutine := range a.Subroutines {
+		if subroutine.Name == name {
+			return subroutine
+		}
+	}
+	return nil
+}
+
+// 行を含むサブルーチンを返す
+func (a *Analysis) SubroutineAt(line int) *Subroutine {
+	for _, definition := range a.Subroutines {
+		if definition.Subroutine.Contains(line) {
+			return definition.Subroutine
+		}
+	}
+	return nil
+}
+
+// スコープの内側から順に変数を探す
+func (a *Analysis) FindVariable(subroutine *Subroutine, name string) *Definition {
+	if subroutine != nil {
+		for _, local := range subroutine.Locals {
+			if local.Name == name {
+				return local
+			}
+		}
+	}
+
+	for _, variable := range a.Variables {
+		if variable.Name == name {
+			return variable
+		}
+	}
+	return nil
+}
+
+func (a *Analysis) OccurrenceAt(position token.Position) *Occurrence {
+	for _, occurrence := range a.Occurrences {
+		if contains(occurrence.Token, position) {
+			return occurrence
+		}
+	}
+	return nil
+}
+
+// ソースを解析して、解析結果と診断結果を返す
+// パースに失敗した場合、解析結果はnilになる
+// extendedがtrueなら拡張文法のソースとして解析する
+func Analyze(uri string, lines []string, extended bool) (*Analysis, []*Diagnostic) {
+	src := io.NewSrc(uriToPath(uri))
+	src.SetupLines(lines)
+	tokenizer := token.NewTokenizerWithPositions(src.Lines, src.Positions)
+	tokenizer.SetExtended(extended)
+	tokens := tokenizer.Tokenize()
+
+	ctx := parsing.NewContext(src.ClassName())
+	ctx.Extended = extended
+	parser := parsing.NewParserWithContext(tokens, ctx)
+	class, err := parse(parser)
+	if err != nil {
+		return nil, []*Diagnostic{newErrorDiagnostic(lines, errorToken(tokens), errors.Cause(err).Error())}
+	}
+
+	analyzer := newAnalyzer(uri, lines, ctx)
+	analyzer.analyzeClass(class)
+
+	if rest := tokens.First(); rest != nil {
+		analyzer.addError(rest, fmt.Sprintf("unexpected token after class: %s", rest.Value))
+	}
+	return analyzer.analysis, analyzer.diagnostics
+}
+
+// 編集途中のソースではトークンが途中で尽きてパーサーがpanicすることがあるので、エラーに変換する
+func parse(parser *parsing.Parser) (class *parsing.Class, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			class = nil
+			err = errors.New("unexpected end of file")
+		}
+	}()
+	return parser.Parse()
+}
+
+// パースに失敗したときに最後に読んだトークン
+func errorToken(tokens *token.Tokens) *token.Token {
+	if len(tokens.Items) == 0 {
+		return nil
+	}
+
+	index := tokens.HeadIndex - 1
+	if index < 0 {
+		index = 0
+	}
+	if index >= len(tokens.Items) {
+		index = len(tokens.Items) - 1
+	}
+	return tokens.Items[index]
+}
+
+func newErrorDiagnostic(lines []string, t *token.Token, message string) *Diagnostic {
+	r := Range{}
+	if t != nil {
+		r = tokenRange(lines, t)
+	}
+	return &Diagnostic{Range: r, Severity: SeverityError, Source: "jack", Message: message}
+}
+
+type analyzer struct {
+	ctx         *parsing.Context
+	analysis    *Analysis
+	diagnostics []*Diagnostic
+}
+
+func newAnalyzer(uri string, lines []string, ctx *parsing.Context) *analyzer {
+	return &analyzer{
+		ctx:         ctx,
+		analysis:    &Analysis{URI: uri, Lines: lines},
+		diagnostics: []*Diagnostic{},
+	}
+}
+
+func (a *analyzer) addError(t *token.Token, message string) {
+	a.diagnostics = append(a.diagnostics, newErrorDiagnostic(a.analysis.Lines, t, message))
+}
+
+func (a *analyzer) addOccurrence(t *token.Token, definition *Definition) {
+	a.analysis.Occurrences = append(a.analysis.Occurrences, &Occurrence{Token: t, Definition: definition})
+}
+
+func (a *analyzer) addExternal(t *token.Token, className string, subroutineName string) {
+	occurrence := &Occurrence{Token: t, ClassName: className, SubroutineName: subroutineName}
+	a.analysis.Occurrences = append(a.analysis.Occurrences, occurrence)
+}
+
+// 型がクラスの場合は、クラスへの参照として記録する
+func (a *analyzer) addType(t *token.Token) {
+	if t.TokenType == token.TokenIdentifier {
+		a.addExternal(t, t.Value, "")
+	}
+}
+
+func (a *analyzer) newDefinition(kind DefinitionKind, t *token.Token, node interface{}) *Definition {
+	span := a.ctx.FindSpan(node)
+	r := tokenRange(a.analysis.Lines, t)
+	if span.Start.Line != 0 {
+		r = spanRange(a.analysis.Lines, span.Start, span.End)
+	}
+
+	return &Definition{
+		Kind:           kind,
+		Name:           t.Value,
+		URI:            a.analysis.URI,
+		Range:          r,
+		SelectionRange: tokenRange(a.analysis.Lines, t),
+	}
+}
+
+func (a *analyzer) analyzeClass(class *parsing.Class) {
+	a.analysis.ClassName = class.ClassName.Value
+	a.analysis.Class = a.newDefinition(DefinitionClass, class.ClassName.Token, class)
+	a.addOccurrence(class.ClassName.Token, a.analysis.Class)
+
+	// 拡張文法の定数は、クラスの変数と同じように参照できる
+	if class.ConstDecs != nil {
+		for _, item := range class.ConstDecs.Items {
+			switch dec := item.(type) {
+			case *parsing.ConstDec:
+				a.addConstant(dec.VarName, dec)
+			case *parsing.EnumDec:
+				for _, enumItem := range dec.Items {
+					a.addConstant(enumItem.VarName, enumItem)
+				}
+			}
+		}
+	}
+
+	for _, classVarDec := range class.ClassVarDecs.Items {
+		a.addType(classVarDec.VarType.Token)
+		for _, varName := range classVarDec.VarNameItems() {
+			item, err := a.ctx.ClassSymbolTable.Find(varName.Value)
+			if err != nil {
+				continue
+			}
+
+			definition := a.newDefinition(DefinitionVariable, varName.Token, classVarDec)
+			definition.Item = item
+			a.analysis.Variables = append(a.analysis.Variables, definition)
+			a.addOccurrence(varName.Token, definition)
+		}
+	}
+
+	// サブルーチンの呼び出しを解決できるように、先にすべてのサブルーチンを登録する
+	for _, subroutineDec := range class.SubroutineDecs.Items {
+		a.addSubroutine(subroutineDec)
+	}
+
+	for index, subroutineDec := range class.SubroutineDecs.Items {
+		a.analyzeSubroutine(a.analysis.Subroutines[index], subroutineDec)
+	}
+}
+
+func (a *analyzer) addConstant(varName *parsing.VarName, node interface{}) {
+	item, err := a.ctx.ClassSymbolTable.Find(varName.Value)
+	if err != nil {
+		return
+	}
+
+	definition := a.newDefinition(DefinitionVariable, varName.Token, node)
+	definition.Item = item
+	a.analysis.Variables = append(a.analysis.Variables, definition)
+	a.addOccurrence(varName.Token, definition)
+}
+
+func (a *analyzer) addSubroutine(subroutineDec *parsing.SubroutineDec) {
+	subroutine := NewSubroutine(a.analysis.ClassName, subroutineDec.Subroutine.Value, subroutineDec.SubroutineType.Value, subroutineDec.SubroutineName.Value)
+	span := a.ctx.FindSpan(subroutineDec)
+	subroutine.StartLine = span.Start.Line
+	subroutine.EndLine = span.End.Line
+
+	definition := a.newDefinition(DefinitionSubroutine, subroutineDec.SubroutineName.Token, subroutineDec)
+	definition.Subroutine = subroutine
+	a.analysis.Subroutines = append(a.analysis.Subroutines, definition)
+	a.addOccurrence(subroutineDec.SubroutineName.Token, definition)
+	a.addType(subroutineDec.SubroutineType.Token)
+}
+
+func (a *analyzer) analyzeSubroutine(definition *Definition, subroutineDec *parsing.SubroutineDec) {
+	subroutine := definition.Subroutine
+
+	// パース時と同じ手順でサブルーチンのシンボルテーブルを作り直す
+	a.ctx.ResetSubroutine(subroutine.Name)
+	subroutineDec.ParameterList.UpdateSymbolTable(a.ctx)
+	for _, varDec := range subroutineDec.SubroutineBody.VarDecs.Items {
+		varDec.UpdateSymbolTable(a.ctx)
+	}
+
+	for _, parameter := range subroutineDec.ParameterList.ParameterItems() {
+		a.addType(parameter.VarType.Token)
+		item, err := a.ctx.SubroutineSymbolTable.Find(parameter.VarName.Value)
+		if err != nil {
+			continue
+		}
+
+		subroutine.Parameters = append(subroutine.Parameters, item)
+		a.addLocal(subroutine, parameter.VarName, item, parameter.VarName)
+	}
+
+	for _, varDec := range subroutineDec.SubroutineBody.VarDecs.Items {
+		a.addType(varDec.VarType.Token)
+		for _, varName := range varDec.VarNameItems() {
+			item, err := a.ctx.SubroutineSymbolTable.Find(varName.Value)
+			if err != nil {
+				continue
+			}
+			a.addLocal(subroutine, varName, item, varDec)
+		}
+	}
+
+	parsing.Walk(subroutineDec.SubroutineBody.Statements, func(node interface{}) bool {
+		switch n := node.(type) {
+		case *parsing.VarName:
+			a.resolveVarName(subroutine, n)
+		case *parsing.SubroutineCall:
+			a.resolveSubroutineCall(subroutine, n)
+		}
+		return true
+	})
+}
+
+func (a *analyzer) addLocal(subroutine *Subroutine, varName *parsing.VarName, item *symbol.SymbolItem, node interface{}) {
+	definition := a.newDefinition(DefinitionVariable, varName.Token, node)
+	definition.Item = item
+	subroutine.Locals = append(subroutine.Locals, definition)
+	a.addOccurrence(varName.Token, definition)
+}
+
+func (a *analyzer) resolveVarName(subroutine *Subroutine, varName *parsing.VarName) {
+	definition := a.analysis.FindVariable(subroutine, varName.Value)
+	if definition == nil {
+		a.addError(varName.Token, fmt.Sprintf("undefined variable: %s", varName.Value))
+		return
+	}
+	a.addOccurrence(varName.Token, definition)
+}
+
+// foo()、foo.bar()、Foo.bar()のいずれかの呼び出しを解決する
+func (a *analyzer) resolveSubroutineCall(subroutine *Subroutine, subroutineCall *parsing.SubroutineCall) {
+	callName := subroutineCall.SubroutineCallName
+	className := a.analysis.ClassName
+
+	if callName.CallerName != nil {
+		caller := callName.CallerName
+		if variable := a.analysis.FindVariable(subroutine, caller.Value); variable != nil {
+			// 変数経由の呼び出しは、変数の型のクラスのメソッドを呼び出す
+			a.addOccurrence(caller.Token, variable)
+			className = variable.Item.SymbolType.Value
+		} else {
+			a.addExternal(caller.Token, caller.Value, "")
+			className = caller.Value
+		}
+	}
+
+	subroutineName := callName.SubroutineName
+	if className != a.analysis.ClassName {
+		a.addExternal(subroutineName.Token, className, subroutineName.Value)
+		return
+	}
+
+	definition := a.analysis.FindSubroutine(subroutineName.Value)
+	if definition == nil {
+		a.addError(subroutineName.Token, fmt.Sprintf("undefined subroutine: %s.%s", className, subroutineName.Value))
+		return
+	}
+	a.addOccurrence(subroutineName.Token, definition)
+}
@@ -0,0 +1,108 @@
+package lsp
+
+import (
+	"../symbol"
+	"strings"
+)
+
+// Jack OSのクラスはソースがワークスペースにないので、APIのシグネチャだけを持っておく
+var builtinSignatures = map[string][]string{
+	"Math": {
+		"function void init()",
+		"function int abs(int x)",
+		"function int multiply(int x, int y)",
+		"function int divide(int x, int y)",
+		"function int min(int x, int y)",
+		"function int max(int x, int y)",
+		"function int sqrt(int x)",
+	},
+	"String": {
+		"constructor String new(int maxLength)",
+		"method void dispose()",
+		"method int length()",
+		"method char charAt(int j)",
+		"method void setCharAt(int j, char c)",
+		"method String appendChar(char c)",
+		"method void eraseLastChar()",
+		"method int intValue()",
+		"method void setInt(int val)",
+		"function char backSpace()",
+		"function char doubleQuote()",
+		"function char newLine()",
+	},
+	"Array": {
+		"function Array new(int size)",
+		"method void dispose()",
+	},
+	"Output": {
+		"function void init()",
+		"function void moveCursor(int i, int j)",
+		"function void printChar(char c)",
+		"function void printString(String s)",
+		"function void printInt(int i)",
+		"function void println()",
+		"function void backSpace()",
+	},
+	"Screen": {
+		"function void init()",
+		"function void clearScreen()",
+		"function void setColor(boolean b)",
+		"function void drawPixel(int x, int y)",
+		"function void drawLine(int x1, int y1, int x2, int y2)",
+		"function void drawRectangle(int x1, int y1, int x2, int y2)",
+		"function void drawCircle(int x, int y, int r)",
+	},
+	"Keyboard": {
+		"function void init()",
+		"function char keyPressed()",
+		"function char readChar()",
+		"function String readLine(String message)",
+		"function int readInt(String message)",
+	},
+	"Memory": {
+		"function void init()",
+		"function int peek(int address)",
+		"function void poke(int address, int value)",
+		"function Array alloc(int size)",
+		"function void deAlloc(Array o)",
+	},
+	"Sys": {
+		"function void init()",
+		"function void halt()",
+		"function void error(int errorCode)",
+		"function void wait(int duration)",
+	},
+}
+
+// OSのクラスのサブルーチン一覧を返す
+// OSのクラスでない場合はnilを返す
+func builtinSubroutines(className string) []*Subroutine {
+	signatures, ok := builtinSignatures[className]
+	if !ok {
+		return nil
+	}
+
+	result := []*Subroutine{}
+	for _, signature := range signatures {
+		result = append(result, parseSignature(className, signature))
+	}
+	return result
+}
+
+// 「function int max(int x, int y)」の形式のシグネチャを分解する
+func parseSignature(className string, signature string) *Subroutine {
+	open := strings.Index(signature, "(")
+	header := strings.Fields(signature[:open])
+	subroutine := NewSubroutine(className, header[0], header[1], header[2])
+
+	parameters := strings.TrimSuffix(signature[open+1:], ")")
+	for index, parameter := range strings.Split(parameters, ",") {
+		fields := strings.Fields(parameter)
+		if len(fields) != 2 {
+			continue
+		}
+		scope := symbol.NewSymbolScope(symbol.ArgScope, index)
+		subroutine.Parameters = append(subroutine.Parameters, symbol.NewSymbolItem(fields[1], fields[0], scope))
+	}
+	return subroutine
+}
@@ -0,0 +1,161 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// JSON-RPC 2.0のエラーコード
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+)
+
+// 受信したリクエストまたは通知
+// IDがない場合は通知
+type Message struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+}
+
+func (m *Message) IsNotification() bool {
+	return m.ID == nil
+}
+
+type response struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id"`
+	Result  interface{}      `json:"result"`
+}
+
+type errorResponse struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id"`
+	Error   *ResponseError   `json:"error"`
+}
+
+type notification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+}
+
+type ResponseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func NewResponseError(code int, message string) *ResponseError {
+	return &ResponseError{Code: code, Message: message}
+}
+
+func (r *ResponseError) Error() string {
+	return fmt.Sprintf("jsonrpc error %d: %s", r.Code, r.Message)
+}
+
+// Content-Lengthヘッダで区切られたJSON-RPCのメッセージを読み書きする
+type Conn struct {
+	reader *bufio.Reader
+	writer io.Writer
+	mutex  sync.Mutex
+}
+
+func NewConn(reader io.Reader, writer io.Writer) *Conn {
+	return &Conn{reader: bufio.NewReader(reader), writer: writer}
+}
+
+// 次のメッセージの本文を読み込む
+// 入力が終わった場合はio.EOFを返す
+func (c *Conn) ReadRaw() ([]byte, error) {
+	length := -1
+	for {
+		line, err := c.reader.ReadString('\n')
+		if err != nil {
+			if err == io.EOF && line == "" && length < 0 {
+				return nil, io.EOF
+			}
+			return nil, errors.Wrap(err, "failed to read header")
+		}
+
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+
+		split := strings.SplitN(line, ":", 2)
+		if len(split) != 2 {
+			return nil, errors.New("invalid header: " + line)
+		}
+
+		if strings.EqualFold(strings.TrimSpace(split[0]), "Content-Length") {
+			length, err = strconv.Atoi(strings.TrimSpace(split[1]))
+			if err != nil {
+				return nil, errors.Wrap(err, "invalid Content-Length")
+			}
+		}
+	}
+
+	if length < 0 {
+		return nil, errors.New("missing Content-Length header")
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(c.reader, body); err != nil {
+		return nil, errors.Wrap(err, "failed to read body")
+	}
+	return body, nil
+}
+
+func (c *Conn) Read() (*Message, error) {
+	body, err := c.ReadRaw()
+	if err != nil {
+		return nil, err
+	}
+
+	message := &Message{}
+	if err := json.Unmarshal(body, message); err != nil {
+		return nil, NewResponseError(CodeParseError, err.Error())
+	}
+	return message, nil
+}
+
+func (c *Conn) Reply(id *json.RawMessage, result interface{}) error {
+	return c.Write(&response{JSONRPC: "2.0", ID: id, Result: result})
+}
+
+func (c *Conn) ReplyError(id *json.RawMessage, err *ResponseError) error {
+	return c.Write(&errorResponse{JSONRPC: "2.0", ID: id, Error: err})
+}
+
+func (c *Conn) Notify(method string, params interface{}) error {
+	return c.Write(&notification{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+// 任意のメッセージを送信する
+// クライアント側からリクエストを送る場合にも使う
+func (c *Conn) Write(message interface{}) error {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal message")
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if _, err := fmt.Fprintf(c.writer, "Content-Length: %d\r\n\r\n", len(body)); err != nil {
+		return err
+	}
+	_, err = c.writer.Write(body)
+	return err
+}
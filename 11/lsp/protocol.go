@@ -0,0 +1,175 @@
+package lsp
+
+// Language Server Protocolのうち、このサーバーで使う型だけを定義する
+// https://microsoft.github.io/language-server-protocol/specification
+
+// 行・文字ともに0始まりで、文字はUTF-16のコード単位で数える
+type Position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+type Location struct {
+	URI   string `json:"uri"`
+	Range Range  `json:"range"`
+}
+
+type DiagnosticSeverity int
+
+const (
+	_ DiagnosticSeverity = iota
+	SeverityError
+	SeverityWarning
+	SeverityInformation
+	SeverityHint
+)
+
+type Diagnostic struct {
+	Range    Range              `json:"range"`
+	Severity DiagnosticSeverity `json:"severity"`
+	Source   string             `json:"source"`
+	Message  string             `json:"message"`
+}
+
+type PublishDiagnosticsParams struct {
+	URI         string        `json:"uri"`
+	Diagnostics []*Diagnostic `json:"diagnostics"`
+}
+
+type TextDocumentIdentifier struct {
+	URI string `json:"uri"`
+}
+
+type TextDocumentItem struct {
+	URI        string `json:"uri"`
+	LanguageID string `json:"languageId"`
+	Version    int    `json:"version"`
+	Text       string `json:"text"`
+}
+
+type TextDocumentPositionParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Position     Position               `json:"position"`
+}
+
+type DidOpenTextDocumentParams struct {
+	TextDocument TextDocumentItem `json:"textDocument"`
+}
+
+// 差分同期はサポートしないので、変更内容は常にファイル全体
+type TextDocumentContentChangeEvent struct {
+	Text string `json:"text"`
+}
+
+type DidChangeTextDocumentParams struct {
+	TextDocument   TextDocumentIdentifier           `json:"textDocument"`
+	ContentChanges []TextDocumentContentChangeEvent `json:"contentChanges"`
+}
+
+type DidSaveTextDocumentParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Text         *string                `json:"text,omitempty"`
+}
+
+type DidCloseTextDocumentParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+type DocumentSymbolParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+type InitializeResult struct {
+	Capabilities ServerCapabilities `json:"capabilities"`
+	ServerInfo   ServerInfo         `json:"serverInfo"`
+}
+
+type ServerInfo struct {
+	Name string `json:"name"`
+}
+
+type ServerCapabilities struct {
+	TextDocumentSync       TextDocumentSyncOptions `json:"textDocumentSync"`
+	DefinitionProvider     bool                    `json:"definitionProvider"`
+	HoverProvider          bool                    `json:"hoverProvider"`
+	CompletionProvider     CompletionOptions       `json:"completionProvider"`
+	DocumentSymbolProvider bool                    `json:"documentSymbolProvider"`
+}
+
+type TextDocumentSyncKind int
+
+const (
+	SyncNone TextDocumentSyncKind = iota
+	SyncFull
+)
+
+type TextDocumentSyncOptions struct {
+	OpenClose bool                 `json:"openClose"`
+	Change    TextDocumentSyncKind `json:"change"`
+	Save      SaveOptions          `json:"save"`
+}
+
+type SaveOptions struct {
+	IncludeText bool `json:"includeText"`
+}
+
+type CompletionOptions struct {
+	TriggerCharacters []string `json:"triggerCharacters"`
+}
+
+type MarkupContent struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+type Hover struct {
+	Contents MarkupContent `json:"contents"`
+	Range    *Range        `json:"range,omitempty"`
+}
+
+type CompletionItemKind int
+
+const (
+	CompletionMethod      CompletionItemKind = 2
+	CompletionFunction    CompletionItemKind = 3
+	CompletionConstructor CompletionItemKind = 4
+	CompletionField       CompletionItemKind = 5
+	CompletionVariable    CompletionItemKind = 6
+	CompletionClass       CompletionItemKind = 7
+)
+
+type CompletionItem struct {
+	Label  string             `json:"label"`
+	Kind   CompletionItemKind `json:"kind"`
+	Detail string             `json:"detail"`
+}
+
+type CompletionList struct {
+	IsIncomplete bool              `json:"isIncomplete"`
+	Items        []*CompletionItem `json:"items"`
+}
+
+type SymbolKind int
+
+const (
+	SymbolKindClass       SymbolKind = 5
+	SymbolKindMethod      SymbolKind = 6
+	SymbolKindField       SymbolKind = 8
+	SymbolKindConstructor SymbolKind = 9
+	SymbolKindFunction    SymbolKind = 12
+	SymbolKindVariable    SymbolKind = 13
+)
+
+type DocumentSymbol struct {
+	Name           string            `json:"name"`
+	Detail         string            `json:"detail"`
+	Kind           SymbolKind        `json:"kind"`
+	Range          Range             `json:"range"`
+	SelectionRange Range             `json:"selectionRange"`
+	Children       []*DocumentSymbol `json:"children,omitempty"`
+}
@@ -0,0 +1,431 @@
+package lsp
+
+import (
+	"../symbol"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"io"
+	"regexp"
+	"sort"
+)
+
+type handler func(params json.RawMessage) (interface{}, error)
+
+// 標準入出力などのストリーム上で動くJackのLanguage Server
+type Server struct {
+	conn      *Conn
+	workspace *Workspace
+	handlers  map[string]handler
+	shutdown  bool
+	exited    bool
+}
+
+func NewServer(reader io.Reader, writer io.Writer) *Server {
+	s := &Server{
+		conn:      NewConn(reader, writer),
+		workspace: NewWorkspace(),
+	}
+	s.handlers = map[string]handler{
+		"initialize":                  s.initialize,
+		"initialized":                 s.ignore,
+		"shutdown":                    s.shutdownRequest,
+		"exit":                        s.exit,
+		"textDocument/didOpen":        s.didOpen,
+		"textDocument/didChange":      s.didChange,
+		"textDocument/didSave":        s.didSave,
+		"textDocument/didClose":       s.didClose,
+		"textDocument/definition":     s.definition,
+		"textDocument/hover":          s.hover,
+		"textDocument/completion":     s.completion,
+		"textDocument/documentSymbol": s.documentSymbol,
+	}
+	return s
+}
+
+// 拡張文法のソースとして解析する
+func (s *Server) SetExtended(extended bool) {
+	s.workspace.SetExtended(extended)
+}
+
+// exit通知を受け取るか、入力が終わるまでメッセージを処理する
+func (s *Server) Serve() error {
+	for !s.exited {
+		message, err := s.conn.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if responseError, ok := err.(*ResponseError); ok {
+			if err := s.conn.ReplyError(nil, responseError); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := s.handle(message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Server) handle(message *Message) error {
+	h, ok := s.handlers[message.Method]
+	if !ok {
+		// 未対応の通知は無視する
+		if message.IsNotification() {
+			return nil
+		}
+		return s.conn.ReplyError(message.ID, NewResponseError(CodeMethodNotFound, "method not found: "+message.Method))
+	}
+
+	if s.shutdown && message.Method != "exit" && !message.IsNotification() {
+		return s.conn.ReplyError(message.ID, NewResponseError(CodeInvalidRequest, "server is shutting down"))
+	}
+
+	result, err := h(message.Params)
+	if message.IsNotification() {
+		return nil
+	}
+
+	if err != nil {
+		responseError, ok := err.(*ResponseError)
+		if !ok {
+			responseError = NewResponseError(CodeInternalError, err.Error())
+		}
+		return s.conn.ReplyError(message.ID, responseError)
+	}
+	return s.conn.Reply(message.ID, result)
+}
+
+func decodeParams(params json.RawMessage, v interface{}) error {
+	if err := json.Unmarshal(params, v); err != nil {
+		return NewResponseError(CodeInvalidParams, err.Error())
+	}
+	return nil
+}
+
+func (s *Server) ignore(params json.RawMessage) (interface{}, error) {
+	return nil, nil
+}
+
+func (s *Server) initialize(params json.RawMessage) (interface{}, error) {
+	return &InitializeResult{
+		Capabilities: ServerCapabilities{
+			TextDocumentSync: TextDocumentSyncOptions{
+				OpenClose: true,
+				Change:    SyncFull,
+				Save:      SaveOptions{IncludeText: true},
+			},
+			DefinitionProvider:     true,
+			HoverProvider:          true,
+			CompletionProvider:     CompletionOptions{TriggerCharacters: []string{"."}},
+			DocumentSymbolProvider: true,
+		},
+		ServerInfo: ServerInfo{Name: "jacklsp"},
+	}, nil
+}
+
+func (s *Server) shutdownRequest(params json.RawMessage) (interface{}, error) {
+	s.shutdown = true
+	return nil, nil
+}
+
+func (s *Server) exit(params json.RawMessage) (interface{}, error) {
+	s.exited = true
+	return nil, nil
+}
+
+func (s *Server) didOpen(params json.RawMessage) (interface{}, error) {
+	p := &DidOpenTextDocumentParams{}
+	if err := decodeParams(params, p); err != nil {
+		return nil, err
+	}
+
+	document := s.workspace.Open(p.TextDocument.URI, p.TextDocument.Text)
+	return nil, s.publishDiagnostics(document)
+}
+
+// 診断結果は保存時にだけ通知するので、ここでは解析結果の更新だけ行う
+func (s *Server) didChange(params json.RawMessage) (interface{}, error) {
+	p := &DidChangeTextDocumentParams{}
+	if err := decodeParams(params, p); err != nil {
+		return nil, err
+	}
+
+	document := s.workspace.Document(p.TextDocument.URI)
+	if document == nil || len(p.ContentChanges) == 0 {
+		return nil, nil
+	}
+	document.Update(p.ContentChanges[len(p.ContentChanges)-1].Text)
+	return nil, nil
+}
+
+func (s *Server) didSave(params json.RawMessage) (interface{}, error) {
+	p := &DidSaveTextDocumentParams{}
+	if err := decodeParams(params, p); err != nil {
+		return nil, err
+	}
+
+	document := s.workspace.Document(p.TextDocument.URI)
+	if document == nil {
+		return nil, nil
+	}
+	if p.Text != nil {
+		document.Update(*p.Text)
+	}
+	return nil, s.publishDiagnostics(document)
+}
+
+func (s *Server) didClose(params json.RawMessage) (interface{}, error) {
+	p := &DidCloseTextDocumentParams{}
+	if err := decodeParams(params, p); err != nil {
+		return nil, err
+	}
+
+	s.workspace.Close(p.TextDocument.URI)
+	return nil, s.conn.Notify("textDocument/publishDiagnostics", &PublishDiagnosticsParams{URI: p.TextDocument.URI, Diagnostics: []*Diagnostic{}})
+}
+
+func (s *Server) publishDiagnostics(document *Document) error {
+	diagnostics := document.Diagnostics
+	if diagnostics == nil {
+		diagnostics = []*Diagnostic{}
+	}
+	return s.conn.Notify("textDocument/publishDiagnostics", &PublishDiagnosticsParams{URI: document.URI, Diagnostics: diagnostics})
+}
+
+// 位置にある名前の宣言を探す
+// OSのクラスなど、ソースがない場合はnilを返す
+func (s *Server) findDefinition(params json.RawMessage) (*Document, *Occurrence, *Definition, error) {
+	p := &TextDocumentPositionParams{}
+	if err := decodeParams(params, p); err != nil {
+		return nil, nil, nil, err
+	}
+
+	document := s.workspace.Document(p.TextDocument.URI)
+	if document == nil || document.Analysis == nil {
+		return document, nil, nil, nil
+	}
+
+	position := fromPosition(document.Lines, p.Position)
+	occurrence := document.Analysis.OccurrenceAt(position)
+	if occurrence == nil {
+		return document, nil, nil, nil
+	}
+	return document, occurrence, s.resolve(document, occurrence), nil
+}
+
+func (s *Server) resolve(document *Document, occurrence *Occurrence) *Definition {
+	if occurrence.Definition != nil {
+		return occurrence.Definition
+	}
+
+	if occurrence.SubroutineName == "" {
+		if analysis := s.workspace.FindClass(document.URI, occurrence.ClassName); analysis != nil {
+			return analysis.Class
+		}
+		return nil
+	}
+
+	for _, subroutine := range s.workspace.Subroutines(document.URI, occurrence.ClassName) {
+		if subroutine.Name == occurrence.SubroutineName {
+			return subroutine
+		}
+	}
+	return nil
+}
+
+func (s *Server) definition(params json.RawMessage) (interface{}, error) {
+	_, _, definition, err := s.findDefinition(params)
+	if err != nil || definition == nil || definition.URI == "" {
+		return nil, err
+	}
+	return &Location{URI: definition.URI, Range: definition.SelectionRange}, nil
+}
+
+func (s *Server) hover(params json.RawMessage) (interface{}, error) {
+	document, occurrence, definition, err := s.findDefinition(params)
+	if err != nil || occurrence == nil {
+		return nil, err
+	}
+
+	value := ""
+	switch {
+	case definition == nil && occurrence.SubroutineName == "":
+		value = fmt.Sprintf("```jack\nclass %s\n```", occurrence.ClassName)
+		if builtinSubroutines(occurrence.ClassName) != nil {
+			value += "\n\nJack OS"
+		}
+	case definition == nil:
+		return nil, nil
+	case definition.Kind == DefinitionClass:
+		value = fmt.Sprintf("```jack\nclass %s\n```", definition.Name)
+	case definition.Kind == DefinitionVariable:
+		item := definition.Item
+		value = fmt.Sprintf("```jack\n%s %s %s\n```\n\nkind: %s, type: %s, index: %d (%s)",
+			item.ScopeKind, item.SymbolType.Value, item.SymbolName.Value,
+			item.ScopeKind, item.SymbolType.Value, item.ScopeIndex, item.ToCode())
+	case definition.Kind == DefinitionSubroutine:
+		value = fmt.Sprintf("```jack\n%s\n```\n\nclass: %s", definition.Subroutine.Signature(), definition.Subroutine.ClassName)
+	}
+
+	r := tokenRange(document.Lines, occurrence.Token)
+	return &Hover{Contents: MarkupContent{Kind: "markdown", Value: value}, Range: &r}, nil
+}
+
+// カーソルの直前が「名前.」または「名前.途中の名前」ならメンバーを補完する
+var memberPattern = regexp.MustCompile(`([A-Za-z_][A-Za-z0-9_]*)\.[A-Za-z0-9_]*$`)
+
+func (s *Server) completion(params json.RawMessage) (interface{}, error) {
+	p := &TextDocumentPositionParams{}
+	if err := decodeParams(params, p); err != nil {
+		return nil, err
+	}
+
+	list := &CompletionList{IsIncomplete: false, Items: []*CompletionItem{}}
+	document := s.workspace.Document(p.TextDocument.URI)
+	if document == nil || document.Analysis == nil {
+		return list, nil
+	}
+
+	position := fromPosition(document.Lines, p.Position)
+	subroutine := document.Analysis.SubroutineAt(position.Line)
+
+	before := ""
+	if position.Line-1 < len(document.Lines) {
+		line := document.Lines[position.Line-1]
+		if position.Column-1 <= len(line) {
+			before = line[:position.Column-1]
+		}
+	}
+
+	if match := memberPattern.FindStringSubmatch(before); match != nil {
+		list.Items = s.memberCompletion(document, subroutine, match[1])
+	} else {
+		list.Items = s.scopeCompletion(document, subroutine)
+	}
+	return list, nil
+}
+
+// 変数ならその型のメソッド、クラスならコンストラクタと関数を返す
+func (s *Server) memberCompletion(document *Document, subroutine *Subroutine, receiver string) []*CompletionItem {
+	className := receiver
+	instance := false
+	if variable := document.Analysis.FindVariable(subroutine, receiver); variable != nil {
+		className = variable.Item.SymbolType.Value
+		instance = true
+	}
+
+	items := []*CompletionItem{}
+	for _, definition := range s.workspace.Subroutines(document.URI, className) {
+		isMethod := definition.Subroutine.Keyword == "method"
+		if isMethod != instance {
+			continue
+		}
+		items = append(items, subroutineCompletion(definition.Subroutine))
+	}
+	return items
+}
+
+// ローカル変数、引数、フィールド、自クラスのサブルーチン、クラス名を返す
+func (s *Server) scopeCompletion(document *Document, subroutine *Subroutine) []*CompletionItem {
+	items := []*CompletionItem{}
+	if subroutine != nil {
+		for _, local := range subroutine.Locals {
+			items = append(items, variableCompletion(local.Item, CompletionVariable))
+		}
+	}
+
+	for _, variable := range document.Analysis.Variables {
+		items = append(items, variableCompletion(variable.Item, CompletionField))
+	}
+
+	for _, definition := range document.Analysis.Subroutines {
+		items = append(items, subroutineCompletion(definition.Subroutine))
+	}
+
+	classNames := s.workspace.ClassNames(document.URI)
+	for name := range builtinSignatures {
+		classNames = append(classNames, name)
+	}
+	sort.Strings(classNames)
+	for _, name := range classNames {
+		items = append(items, &CompletionItem{Label: name, Kind: CompletionClass, Detail: "class " + name})
+	}
+	return items
+}
+
+func variableCompletion(item *symbol.SymbolItem, kind CompletionItemKind) *CompletionItem {
+	detail := fmt.Sprintf("%s %s", item.ScopeKind, item.SymbolType.Value)
+	return &CompletionItem{Label: item.SymbolName.Value, Kind: kind, Detail: detail}
+}
+
+func subroutineCompletion(subroutine *Subroutine) *CompletionItem {
+	kind := CompletionFunction
+	switch subroutine.Keyword {
+	case "method":
+		kind = CompletionMethod
+	case "constructor":
+		kind = CompletionConstructor
+	}
+	return &CompletionItem{Label: subroutine.Name, Kind: kind, Detail: subroutine.Signature()}
+}
+
+func (s *Server) documentSymbol(params json.RawMessage) (interface{}, error) {
+	p := &DocumentSymbolParams{}
+	if err := decodeParams(params, p); err != nil {
+		return nil, err
+	}
+
+	document := s.workspace.Document(p.TextDocument.URI)
+	if document == nil {
+		return nil, errors.New("document is not opened: " + p.TextDocument.URI)
+	}
+
+	analysis := document.Analysis
+	if analysis == nil {
+		return []*DocumentSymbol{}, nil
+	}
+
+	class := &DocumentSymbol{
+		Name:           analysis.ClassName,
+		Detail:         "class",
+		Kind:           SymbolKindClass,
+		Range:          analysis.Class.Range,
+		SelectionRange: analysis.Class.SelectionRange,
+		Children:       []*DocumentSymbol{},
+	}
+
+	for _, variable := range analysis.Variables {
+		class.Children = append(class.Children, &DocumentSymbol{
+			Name:           variable.Name,
+			Detail:         fmt.Sprintf("%s %s", variable.Item.ScopeKind, variable.Item.SymbolType.Value),
+			Kind:           SymbolKindField,
+			Range:          variable.Range,
+			SelectionRange: variable.SelectionRange,
+		})
+	}
+
+	for _, definition := range analysis.Subroutines {
+		kind := SymbolKindFunction
+		switch definition.Subroutine.Keyword {
+		case "method":
+			kind = SymbolKindMethod
+		case "constructor":
+			kind = SymbolKindConstructor
+		}
+
+		class.Children = append(class.Children, &DocumentSymbol{
+			Name:           definition.Name,
+			Detail:         definition.Subroutine.Signature(),
+			Kind:           kind,
+			Range:          definition.Range,
+			SelectionRange: definition.SelectionRange,
+		})
+	}
+	return []*DocumentSymbol{class}, nil
+}
@@ -0,0 +1,527 @@
+package lsp
+
+import (
+	"encoding/json"
+	"github.com/google/go-cmp/cmp"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// サーバーとパイプでつないで、スクリプトどおりにリクエストを送るテスト用のクライアント
+type testClient struct {
+	t      *testing.T
+	conn   *Conn
+	nextID int
+	done   chan error
+}
+
+func newTestClient(t *testing.T) *testClient {
+	return newTestClientWithExtended(t, false)
+}
+
+func newTestClientWithExtended(t *testing.T, extended bool) *testClient {
+	clientReader, serverWriter := io.Pipe()
+	serverReader, clientWriter := io.Pipe()
+
+	client := &testClient{t: t, conn: NewConn(clientReader, clientWriter), done: make(chan error, 1)}
+	server := NewServer(serverReader, serverWriter)
+	server.SetExtended(extended)
+	go func() {
+		client.done <- server.Serve()
+		serverWriter.Close()
+	}()
+	return client
+}
+
+type testRequest struct {
+	JSONRPC string      `json:"jsonrpc"`
+	ID      *int        `json:"id,omitempty"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+}
+
+type testResponse struct {
+	ID     *int            `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *ResponseError  `json:"error"`
+}
+
+func (c *testClient) read() *testResponse {
+	body, err := c.conn.ReadRaw()
+	if err != nil {
+		c.t.Fatalf("failed ReadRaw: %+v", err)
+	}
+
+	response := &testResponse{}
+	if err := json.Unmarshal(body, response); err != nil {
+		c.t.Fatalf("failed Unmarshal: %+v", err)
+	}
+	return response
+}
+
+// リクエストを送り、レスポンスのresultをvにデコードする
+func (c *testClient) request(method string, params interface{}, v interface{}) {
+	c.nextID++
+	id := c.nextID
+	if err := c.conn.Write(&testRequest{JSONRPC: "2.0", ID: &id, Method: method, Params: params}); err != nil {
+		c.t.Fatalf("failed Write: %+v", err)
+	}
+
+	response := c.read()
+	if response.ID == nil || *response.ID != id {
+		c.t.Fatalf("failed %s: unexpected message: %+v", method, response)
+	}
+	if response.Error != nil {
+		c.t.Fatalf("failed %s: %+v", method, response.Error)
+	}
+	if err := json.Unmarshal(response.Result, v); err != nil {
+		c.t.Fatalf("failed %s: Unmarshal result: %+v", method, err)
+	}
+}
+
+func (c *testClient) notify(method string, params interface{}) {
+	if err := c.conn.Write(&testRequest{JSONRPC: "2.0", Method: method, Params: params}); err != nil {
+		c.t.Fatalf("failed Write: %+v", err)
+	}
+}
+
+// 通知を一つ読み込み、paramsをvにデコードする
+func (c *testClient) notification(method string, v interface{}) {
+	response := c.read()
+	if response.Method != method {
+		c.t.Fatalf("failed notification: expected = %s, got = %+v", method, response)
+	}
+	if err := json.Unmarshal(response.Params, v); err != nil {
+		c.t.Fatalf("failed notification: Unmarshal params: %+v", err)
+	}
+}
+
+func (c *testClient) diagnostics() []*Diagnostic {
+	params := &PublishDiagnosticsParams{}
+	c.notification("textDocument/publishDiagnostics", params)
+	return params.Diagnostics
+}
+
+func (c *testClient) close() {
+	var result interface{}
+	c.request("shutdown", nil, &result)
+	c.notify("exit", nil)
+	if err := <-c.done; err != nil {
+		c.t.Fatalf("failed Serve: %+v", err)
+	}
+}
+
+func readFixture(t *testing.T, filename string) string {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("failed ReadFile: %+v", err)
+	}
+	return string(content)
+}
+
+func at(uri string, line int, character int) *TextDocumentPositionParams {
+	return &TextDocumentPositionParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+		Position:     Position{Line: line, Character: character},
+	}
+}
+
+func TestServerInitialize(t *testing.T) {
+	client := newTestClient(t)
+	defer client.close()
+
+	result := &InitializeResult{}
+	client.request("initialize", map[string]interface{}{}, result)
+
+	want := ServerCapabilities{
+		TextDocumentSync:       TextDocumentSyncOptions{OpenClose: true, Change: SyncFull, Save: SaveOptions{IncludeText: true}},
+		DefinitionProvider:     true,
+		HoverProvider:          true,
+		CompletionProvider:     CompletionOptions{TriggerCharacters: []string{"."}},
+		DocumentSymbolProvider: true,
+	}
+	if diff := cmp.Diff(result.Capabilities, want); diff != "" {
+		t.Errorf("failed initialize: diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestServerDefinition(t *testing.T) {
+	uri := pathToURI("../Fixture/Square/SquareGame.jack")
+	squareURI := pathToURI("../Fixture/Square/Square.jack")
+
+	cases := []struct {
+		desc     string
+		position *TextDocumentPositionParams
+		want     *Location
+	}{
+		{
+			desc:     "フィールド",
+			position: at(uri, 34, 10),
+			want:     &Location{URI: uri, Range: Range{Start: Position{19, 16}, End: Position{19, 22}}},
+		},
+		{
+			desc:     "ローカル変数",
+			position: at(uri, 58, 16),
+			want:     &Location{URI: uri, Range: Range{Start: Position{51, 15}, End: Position{51, 18}}},
+		},
+		{
+			desc:     "自クラスのサブルーチン",
+			position: at(uri, 59, 15),
+			want:     &Location{URI: uri, Range: Range{Start: Position{40, 15}, End: Position{40, 25}}},
+		},
+		{
+			desc:     "変数の型のクラスのメソッド",
+			position: at(uri, 41, 38),
+			want:     &Location{URI: squareURI, Range: Range{Start: Position{63, 15}, End: Position{63, 21}}},
+		},
+		{
+			desc:     "クラス",
+			position: at(uri, 27, 20),
+			want:     &Location{URI: squareURI, Range: Range{Start: Position{8, 6}, End: Position{8, 12}}},
+		},
+		{
+			desc:     "フィールドの型",
+			position: at(uri, 19, 10),
+			want:     &Location{URI: squareURI, Range: Range{Start: Position{8, 6}, End: Position{8, 12}}},
+		},
+		{
+			desc:     "OSのクラスは定義がない",
+			position: at(uri, 35, 12),
+			want:     nil,
+		},
+	}
+
+	client := newTestClient(t)
+	defer client.close()
+
+	client.notify("textDocument/didOpen", &DidOpenTextDocumentParams{
+		TextDocument: TextDocumentItem{URI: uri, LanguageID: "jack", Version: 1, Text: readFixture(t, "../Fixture/Square/SquareGame.jack")},
+	})
+	if diagnostics := client.diagnostics(); len(diagnostics) != 0 {
+		t.Fatalf("failed didOpen: diagnostics = %+v", diagnostics[0])
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			var got *Location
+			client.request("textDocument/definition", tc.position, &got)
+			if diff := cmp.Diff(got, tc.want); diff != "" {
+				t.Errorf("failed definition: diff (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestServerHover(t *testing.T) {
+	uri := pathToURI("../Fixture/Square/SquareGame.jack")
+
+	cases := []struct {
+		desc     string
+		position *TextDocumentPositionParams
+		want     string
+	}{
+		{
+			desc:     "フィールド",
+			position: at(uri, 41, 11),
+			want:     "```jack\nfield int direction\n```\n\nkind: field, type: int, index: 1 (this 1)",
+		},
+		{
+			desc:     "ローカル変数",
+			position: at(uri, 53, 11),
+			want:     "```jack\nlocal boolean exit\n```\n\nkind: local, type: boolean, index: 1 (local 1)",
+		},
+		{
+			desc:     "他のクラスのメソッド",
+			position: at(uri, 41, 38),
+			want:     "```jack\nmethod void moveUp()\n```\n\nclass: Square",
+		},
+		{
+			desc:     "OSの関数",
+			position: at(uri, 45, 14),
+			want:     "```jack\nfunction void wait(int duration)\n```\n\nclass: Sys",
+		},
+		{
+			desc:     "OSのクラス",
+			position: at(uri, 45, 10),
+			want:     "```jack\nclass Sys\n```\n\nJack OS",
+		},
+	}
+
+	client := newTestClient(t)
+	defer client.close()
+
+	client.notify("textDocument/didOpen", &DidOpenTextDocumentParams{
+		TextDocument: TextDocumentItem{URI: uri, LanguageID: "jack", Version: 1, Text: readFixture(t, "../Fixture/Square/SquareGame.jack")},
+	})
+	client.diagnostics()
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := &Hover{}
+			client.request("textDocument/hover", tc.position, got)
+			if diff := cmp.Diff(got.Contents.Value, tc.want); diff != "" {
+				t.Errorf("failed hover: diff (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestServerCompletion(t *testing.T) {
+	uri := pathToURI("../Fixture/Square/SquareGame.jack")
+	lines := []string{
+		"class SquareGame {",
+		"    field Square square;",
+		"    method void moveSquare(int step) {",
+		"        var int count;",
+		"        do square.",
+		"        do Square.",
+		"        do ",
+		"    }",
+		"}",
+	}
+
+	cases := []struct {
+		desc     string
+		position *TextDocumentPositionParams
+		want     []string
+	}{
+		{
+			desc:     "変数の型のクラスのメソッド",
+			position: at(uri, 4, 18),
+			want:     []string{"dispose", "draw", "erase", "incSize", "decSize", "moveUp", "moveDown", "moveLeft", "moveRight"},
+		},
+		{
+			desc:     "クラスのコンストラクタと関数",
+			position: at(uri, 5, 18),
+			want:     []string{"new"},
+		},
+		{
+			desc:     "スコープ内の変数とサブルーチンとクラス",
+			position: at(uri, 6, 11),
+			want:     []string{"step", "count", "square", "moveSquare", "Array", "Keyboard", "Main", "Math", "Memory", "Output", "Screen", "Square", "SquareGame", "String", "Sys"},
+		},
+	}
+
+	client := newTestClient(t)
+	defer client.close()
+
+	// 解析できる状態で開いてから、編集途中のパースできないソースに変更する
+	valid := strings.Join([]string{lines[0], lines[1], lines[2], lines[3], "        return;", "", "", lines[7], lines[8]}, "\n")
+	client.notify("textDocument/didOpen", &DidOpenTextDocumentParams{
+		TextDocument: TextDocumentItem{URI: uri, LanguageID: "jack", Version: 1, Text: valid},
+	})
+	client.diagnostics()
+	client.notify("textDocument/didChange", &DidChangeTextDocumentParams{
+		TextDocument:   TextDocumentIdentifier{URI: uri},
+		ContentChanges: []TextDocumentContentChangeEvent{{Text: strings.Join(lines, "\n")}},
+	})
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := &CompletionList{}
+			client.request("textDocument/completion", tc.position, got)
+
+			labels := []string{}
+			for _, item := range got.Items {
+				labels = append(labels, item.Label)
+			}
+			if diff := cmp.Diff(labels, tc.want); diff != "" {
+				t.Errorf("failed completion: diff (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestServerDiagnostics(t *testing.T) {
+	uri := pathToURI("../Fixture/Square/Main.jack")
+
+	cases := []struct {
+		desc  string
+		lines []string
+		want  []*Diagnostic
+	}{
+		{
+			desc:  "エラーなし",
+			lines: []string{"class Main {", "    function void main() {", "        return;", "    }", "}"},
+			want:  []*Diagnostic{},
+		},
+		{
+			desc:  "構文エラー",
+			lines: []string{"class Main {", "    function void main() {", "        let x = 1", "    }", "}"},
+			want: []*Diagnostic{
+				{
+					Range:    Range{Start: Position{3, 4}, End: Position{3, 5}},
+					Severity: SeverityError,
+					Source:   "jack",
+					Message:  "Symbol expected values [;]: got = &Token{Value: '}', TokenType: symbol}",
+				},
+			},
+		},
+		{
+			desc:  "未定義の変数とサブルーチン",
+			lines: []string{"class Main {", "    function void main() {", "        let x = 1;", "        do run();", "        return;", "    }", "}"},
+			want: []*Diagnostic{
+				{
+					Range:    Range{Start: Position{2, 12}, End: Position{2, 13}},
+					Severity: SeverityError,
+					Source:   "jack",
+					Message:  "undefined variable: x",
+				},
+				{
+					Range:    Range{Start: Position{3, 11}, End: Position{3, 14}},
+					Severity: SeverityError,
+					Source:   "jack",
+					Message:  "undefined subroutine: Main.run",
+				},
+			},
+		},
+		{
+			desc:  "ファイルの途中で終わっている",
+			lines: []string{"class Main {", "    function void main() {"},
+			want: []*Diagnostic{
+				{
+					Range:    Range{Start: Position{1, 25}, End: Position{1, 26}},
+					Severity: SeverityError,
+					Source:   "jack",
+					Message:  "unexpected end of file",
+				},
+			},
+		},
+	}
+
+	client := newTestClient(t)
+	defer client.close()
+
+	client.notify("textDocument/didOpen", &DidOpenTextDocumentParams{
+		TextDocument: TextDocumentItem{URI: uri, LanguageID: "jack", Version: 1, Text: ""},
+	})
+	client.diagnostics()
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			text := strings.Join(tc.lines, "\n")
+			client.notify("textDocument/didSave", &DidSaveTextDocumentParams{TextDocument: TextDocumentIdentifier{URI: uri}, Text: &text})
+
+			if diff := cmp.Diff(client.diagnostics(), tc.want); diff != "" {
+				t.Errorf("failed diagnostics: diff (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestServerDocumentSymbol(t *testing.T) {
+	uri := pathToURI("../Fixture/Square/Main.jack")
+	lines := []string{
+		"class Main {",
+		"    static int count;",
+		"    function void main() {",
+		"        return;",
+		"    }",
+		"}",
+	}
+
+	want := []*DocumentSymbol{
+		{
+			Name:           "Main",
+			Detail:         "class",
+			Kind:           SymbolKindClass,
+			Range:          Range{Start: Position{0, 0}, End: Position{5, 1}},
+			SelectionRange: Range{Start: Position{0, 6}, End: Position{0, 10}},
+			Children: []*DocumentSymbol{
+				{
+					Name:           "count",
+					Detail:         "static int",
+					Kind:           SymbolKindField,
+					Range:          Range{Start: Position{1, 4}, End: Position{1, 21}},
+					SelectionRange: Range{Start: Position{1, 15}, End: Position{1, 20}},
+				},
+				{
+					Name:           "main",
+					Detail:         "function void main()",
+					Kind:           SymbolKindFunction,
+					Range:          Range{Start: Position{2, 4}, End: Position{4, 5}},
+					SelectionRange: Range{Start: Position{2, 18}, End: Position{2, 22}},
+				},
+			},
+		},
+	}
+
+	client := newTestClient(t)
+	defer client.close()
+
+	client.notify("textDocument/didOpen", &DidOpenTextDocumentParams{
+		TextDocument: TextDocumentItem{URI: uri, LanguageID: "jack", Version: 1, Text: strings.Join(lines, "\n")},
+	})
+	client.diagnostics()
+
+	got := []*DocumentSymbol{}
+	client.request("textDocument/documentSymbol", &DocumentSymbolParams{TextDocument: TextDocumentIdentifier{URI: uri}}, &got)
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("failed documentSymbol: diff (-got +want):\n%s", diff)
+	}
+}
+
+// 拡張文法の構文は SetExtended(true) の場合だけエラーにならない
+func TestServerDiagnosticsExtended(t *testing.T) {
+	uri := pathToURI("../Fixture/Square/Main.jack")
+	lines := []string{
+		"class Main {",
+		"    const int SIZE = 4;",
+		"    enum { RED, GREEN }",
+		"    function int main() {",
+		"        var int i, sum;",
+		"        for (i = 0; i < Main.SIZE; i = i + 1) {",
+		"            if (i = RED) {",
+		"                continue;",
+		"            } else if (i = GREEN) {",
+		"                break;",
+		"            }",
+		"            let sum = sum + i;",
+		"        }",
+		"        return sum;",
+		"    }",
+		"}",
+	}
+
+	cases := []struct {
+		desc     string
+		extended bool
+		want     []*Diagnostic
+	}{
+		{
+			desc:     "拡張文法",
+			extended: true,
+			want:     []*Diagnostic{},
+		},
+		{
+			desc:     "標準の文法",
+			extended: false,
+			want: []*Diagnostic{
+				{
+					Range:    Range{Start: Position{1, 4}, End: Position{1, 9}},
+					Severity: SeverityError,
+					Source:   "jack",
+					Message:  "Symbol expected values [}]: got = &Token{Value: 'const', TokenType: identifier}",
+				},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			client := newTestClientWithExtended(t, tc.extended)
+			defer client.close()
+
+			client.notify("textDocument/didOpen", &DidOpenTextDocumentParams{
+				TextDocument: TextDocumentItem{URI: uri, LanguageID: "jack", Version: 1, Text: strings.Join(lines, "\n")},
+			})
+			if diff := cmp.Diff(client.diagnostics(), tc.want); diff != "" {
+				t.Errorf("failed diagnostics: diff (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package lsp
+
+import (
+	"../token"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+)
+
+func uriToPath(uri string) string {
+	parsed, err := url.Parse(uri)
+	if err != nil || parsed.Scheme != "file" {
+		return uri
+	}
+	return filepath.FromSlash(parsed.Path)
+}
+
+func pathToURI(path string) string {
+	absolute, err := filepath.Abs(path)
+	if err != nil {
+		absolute = path
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(absolute)}).String()
+}
+
+// 改行コードはLFとCRLFのどちらも受け付ける
+func splitLines(text string) []string {
+	lines := strings.Split(strings.Replace(text, "\r\n", "\n", -1), "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+func utf16Length(value string) int {
+	return len(utf16.Encode([]rune(value)))
+}
+
+// トークンの位置（1始まりの行とバイト単位の列）をLSPの位置に変換する
+func toPosition(lines []string, position token.Position) Position {
+	line := position.Line - 1
+	if line < 0 || line >= len(lines) {
+		return Position{Line: 0, Character: 0}
+	}
+
+	column := position.Column - 1
+	if column > len(lines[line]) {
+		column = len(lines[line])
+	}
+	return Position{Line: line, Character: utf16Length(lines[line][:column])}
+}
+
+// LSPの位置をトークンの位置（1始まりの行とバイト単位の列）に変換する
+func fromPosition(lines []string, position Position) token.Position {
+	if position.Line < 0 || position.Line >= len(lines) {
+		return token.NewPosition(position.Line+1, position.Character+1)
+	}
+
+	units := 0
+	for index, r := range lines[position.Line] {
+		if units >= position.Character {
+			return token.NewPosition(position.Line+1, index+1)
+		}
+		units += len(utf16.Encode([]rune{r}))
+	}
+	return token.NewPosition(position.Line+1, len(lines[position.Line])+1)
+}
+
+func tokenRange(lines []string, t *token.Token) Range {
+	start := toPosition(lines, t.Position)
+	end := start
+	end.Character += utf16Length(t.Value)
+	return Range{Start: start, End: end}
+}
+
+func spanRange(lines []string, start token.Position, end token.Position) Range {
+	endPosition := toPosition(lines, end)
+	// 終了位置は末尾のトークン（閉じカッコやセミコロン）の直後にする
+	endPosition.Character++
+	return Range{Start: toPosition(lines, start), End: endPosition}
+}
+
+// 位置がトークンの範囲内ならtrueを返す
+// 識別子の直後にカーソルがある場合も含める
+func contains(t *token.Token, position token.Position) bool {
+	if t == nil || t.Line != position.Line {
+		return false
+	}
+	return t.Column <= position.Column && position.Column <= t.Column+len(t.Value)
+}
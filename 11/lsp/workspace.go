@@ -0,0 +1,117 @@
+package lsp
+
+import (
+	"../io"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// エディタで開いているソースファイル
+type Document struct {
+	URI         string
+	Lines       []string
+	Analysis    *Analysis // 最後にパースに成功したときの解析結果
+	Diagnostics []*Diagnostic
+	extended    bool
+}
+
+func NewDocument(uri string, text string, extended bool) *Document {
+	document := &Document{URI: uri, extended: extended}
+	document.Update(text)
+	return document
+}
+
+// 編集途中でパースに失敗した場合は、補完などに使えるように直前の解析結果を残す
+func (d *Document) Update(text string) {
+	d.Lines = splitLines(text)
+	analysis, diagnostics := Analyze(d.URI, d.Lines, d.extended)
+	if analysis != nil {
+		d.Analysis = analysis
+	}
+	d.Diagnostics = diagnostics
+}
+
+// 開いているファイルと、同じディレクトリにあるjackファイルを管理する
+type Workspace struct {
+	documents map[string]*Document
+	extended  bool
+}
+
+func NewWorkspace() *Workspace {
+	return &Workspace{documents: map[string]*Document{}, extended: false}
+}
+
+// 拡張文法のソースとして解析する
+func (w *Workspace) SetExtended(extended bool) {
+	w.extended = extended
+}
+
+func (w *Workspace) Open(uri string, text string) *Document {
+	document := NewDocument(uri, text, w.extended)
+	w.documents[uri] = document
+	return document
+}
+
+func (w *Workspace) Document(uri string) *Document {
+	return w.documents[uri]
+}
+
+func (w *Workspace) Close(uri string) {
+	delete(w.documents, uri)
+}
+
+// クラス名から解析結果を探す
+// 開いているファイルを優先し、なければ参照元と同じディレクトリの「クラス名.jack」を読み込む
+func (w *Workspace) FindClass(fromURI string, className string) *Analysis {
+	for _, document := range w.documents {
+		if document.Analysis != nil && document.Analysis.ClassName == className {
+			return document.Analysis
+		}
+	}
+
+	path := filepath.Join(filepath.Dir(uriToPath(fromURI)), className+".jack")
+	src := io.NewSrc(path)
+	if err := src.Setup(); err != nil {
+		return nil
+	}
+
+	analysis, _ := Analyze(pathToURI(path), src.Org, w.extended)
+	return analysis
+}
+
+// 参照元と同じディレクトリにあるクラスと、開いているファイルのクラスの名前
+func (w *Workspace) ClassNames(fromURI string) []string {
+	names := map[string]bool{}
+	for _, document := range w.documents {
+		if document.Analysis != nil {
+			names[document.Analysis.ClassName] = true
+		}
+	}
+
+	files, _ := filepath.Glob(filepath.Join(filepath.Dir(uriToPath(fromURI)), "*.jack"))
+	for _, file := range files {
+		names[strings.TrimSuffix(filepath.Base(file), ".jack")] = true
+	}
+
+	result := []string{}
+	for name := range names {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// クラスのサブルーチン一覧を返す
+// ワークスペースにないクラスはOSのクラスとして探す
+func (w *Workspace) Subroutines(fromURI string, className string) []*Definition {
+	if analysis := w.FindClass(fromURI, className); analysis != nil {
+		return analysis.Subroutines
+	}
+
+	result := []*Definition{}
+	for _, subroutine := range builtinSubroutines(className) {
+		result = append(result, &Definition{Kind: DefinitionSubroutine, Name: subroutine.Name, Subroutine: subroutine})
+	}
+	return result
+}
@@ -14,8 +14,17 @@ func main() {
 }
 
 func run() error {
-	arg := NewArg(os.Args)
+	arg, err := NewArg(os.Args)
+	if err != nil {
+		return err
+	}
 	fmt.Printf("コンパイル開始：%s\n", arg.raw)
 	integrator := NewIntegrator(arg.files)
+	integrator.SetFoldConstants(arg.foldConstants)
+	integrator.SetOptimizationLevel(arg.optimizationLevel)
+	integrator.SetExtended(arg.extended)
+	integrator.SetPrecedence(arg.precedence)
+	integrator.SetJSON(arg.json)
+	integrator.SetDebugInfo(arg.debugInfo)
 	return integrator.Integrate()
 }
@@ -1,7 +1,6 @@
 package parsing
 
 import (
-	"../symbol"
 	"../token"
 	"fmt"
 )
@@ -11,6 +10,7 @@ type Class struct {
 	*ClassName
 	*OpeningCurlyBracket
 	*ClosingCurlyBracket
+	*ConstDecs // 拡張文法でなければnil
 	*ClassVarDecs
 	*SubroutineDecs
 }
@@ -51,6 +51,10 @@ func (c *Class) SetClassName(token *token.Token) error {
 	return nil
 }
 
+func (c *Class) SetConstDecs(constDecs *ConstDecs) {
+	c.ConstDecs = constDecs
+}
+
 func (c *Class) SetClassVarDecs(classVarDecs *ClassVarDecs) {
 	c.ClassVarDecs = classVarDecs
 }
@@ -65,6 +69,9 @@ func (c *Class) ToXML() []string {
 	result = append(result, c.Keyword.ToXML())
 	result = append(result, c.ClassName.ToXML())
 	result = append(result, c.OpeningCurlyBracket.ToXML())
+	if c.ConstDecs != nil {
+		result = append(result, c.ConstDecs.ToXML()...)
+	}
 	result = append(result, c.ClassVarDecs.ToXML()...)
 	result = append(result, c.SubroutineDecs.ToXML()...)
 	result = append(result, c.ClosingCurlyBracket.ToXML())
@@ -72,19 +79,32 @@ func (c *Class) ToXML() []string {
 	return result
 }
 
-func (c *Class) ToCode() []string {
+// class Main { ... }
+func (c *Class) ToSource(p *SourcePrinter) []string {
 	result := []string{}
-	//result = append(result, c.ClassVarDecs.ToCode()...)
-	result = append(result, c.SubroutineDecs.ToCode()...)
+	result = append(result, p.Leading(c)...)
+	result = append(result, fmt.Sprintf("%s %s %s", c.Keyword.Value, c.ClassName.Value, c.OpeningCurlyBracket.Value)+p.Opening(c))
+
+	p.OpenBlock()
+	body := []string{}
+	if c.ConstDecs != nil {
+		body = append(body, c.ConstDecs.ToSource(p)...)
+	}
+	body = append(body, c.ClassVarDecs.ToSource(p)...)
+	body = append(body, c.SubroutineDecs.ToSource(p, len(body) > 0)...)
+	body = append(body, p.Closing(c)...)
+	result = append(result, p.Indented(body)...)
+
+	result = append(result, c.ClosingCurlyBracket.Value+p.Trailing(c))
+	result = append(result, p.Rest()...)
 	return result
 }
 
-func (c *Class) PrintCode() {
-	if DebugCode {
-		for _, line := range c.ToDebugCode() {
-			fmt.Println(line)
-		}
-	}
+func (c *Class) ToCode(ctx *Context) []string {
+	result := []string{}
+	//result = append(result, c.ClassVarDecs.ToCode()...)
+	result = append(result, c.SubroutineDecs.ToCode(ctx)...)
+	return result
 }
 
 type ClassVarDecs struct {
@@ -109,6 +129,14 @@ func (c *ClassVarDecs) ToXML() []string {
 	return result
 }
 
+func (c *ClassVarDecs) ToSource(p *SourcePrinter) []string {
+	result := []string{}
+	for _, item := range c.Items {
+		result = append(result, item.ToSource(p)...)
+	}
+	return result
+}
+
 func (c *ClassVarDecs) HasClassVarDec(token *token.Token) bool {
 	if token == nil {
 		return false
@@ -130,17 +158,17 @@ func NewClassVarDec() *ClassVarDec {
 	}
 }
 
-func (c *ClassVarDec) UpdateSymbolTable() {
+func (c *ClassVarDec) UpdateSymbolTable(ctx *Context) {
 	symbolType := c.VarType.Value
 	if c.Keyword.Value == "static" {
-		symbol.GlobalSymbolTables.AddStaticSymbol(c.First.Value, symbolType)
+		ctx.AddStaticSymbol(c.First.Value, symbolType)
 		for _, commaAndVarName := range c.CommaAndVarNames {
-			symbol.GlobalSymbolTables.AddStaticSymbol(commaAndVarName.VarName.Value, symbolType)
+			ctx.AddStaticSymbol(commaAndVarName.VarName.Value, symbolType)
 		}
 	} else if c.Keyword.Value == "field" {
-		symbol.GlobalSymbolTables.AddFieldSymbol(c.First.Value, symbolType)
+		ctx.AddFieldSymbol(c.First.Value, symbolType)
 		for _, commaAndVarName := range c.CommaAndVarNames {
-			symbol.GlobalSymbolTables.AddFieldSymbol(commaAndVarName.VarName.Value, symbolType)
+			ctx.AddFieldSymbol(commaAndVarName.VarName.Value, symbolType)
 		}
 	}
 }
@@ -169,6 +197,16 @@ func (c *ClassVarDec) SetVarType(token *token.Token) error {
 	return nil
 }
 
+// field int x, y;
+func (c *ClassVarDec) ToSource(p *SourcePrinter) []string {
+	result := []string{}
+	result = append(result, p.Leading(c)...)
+	declarations := c.VarNames.ToSourceDeclarations(p, c.Keyword.Value, c.VarType.Value)
+	declarations[len(declarations)-1] += p.Trailing(c)
+	result = append(result, declarations...)
+	return result
+}
+
 func (c *ClassVarDec) ToXML() []string {
 	result := []string{}
 	result = append(result, "<classVarDec>")
@@ -1,12 +1,14 @@
 package parsing
 
-import "fmt"
-
-var DebugCode = true
+import (
+	"fmt"
+	"io"
+)
 
 type Code struct {
-	Lines      []string
-	DebugLines []string
+	Lines       []string
+	DebugLines  []string
+	Subroutines []*DebugSubroutine // Context.DebugInfoが有効な場合の、サブルーチンごとのデバッグ情報
 }
 
 func NewCode() *Code {
@@ -15,8 +17,13 @@ func NewCode() *Code {
 	}
 }
 
-func (c *Code) AddCode(subroutineDec *SubroutineDec) {
-	lines := subroutineDec.ToCode()
+func (c *Code) AddCode(ctx *Context, subroutineDec *SubroutineDec) {
+	lines := subroutineDec.ToCode(ctx)
+	if ctx.DebugInfo {
+		var debugLines []*DebugLine
+		lines, debugLines = stripLineMarkers(lines, ctx.FindSpan(subroutineDec).Start.Line)
+		c.Subroutines = append(c.Subroutines, newDebugSubroutine(ctx, subroutineDec, debugLines))
+	}
 	c.Lines = append(c.Lines, lines...)
 	c.Lines = append(c.Lines, "")
 	c.addDebugCode(lines)
@@ -28,11 +35,9 @@ func (c *Code) addDebugCode(lines []string) {
 	c.DebugLines = append(c.DebugLines, lines...)
 }
 
-func (c *Code) PrintDebugCode() {
-	if DebugCode {
-		for _, line := range c.DebugLines {
-			fmt.Println(line)
-		}
+func (c *Code) WriteDebugCode(writer io.Writer) {
+	for _, line := range c.DebugLines {
+		fmt.Fprintln(writer, line)
 	}
 }
 
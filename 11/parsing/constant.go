@@ -0,0 +1,290 @@
+package parsing
+
+import (
+	"../token"
+	"fmt"
+	"github.com/pkg/errors"
+	"strings"
+)
+
+// 拡張文法のクラス定数の宣言
+// 宣言はクラス版シンボルテーブルに定数として登録され、参照するとpush constantに置き換わる
+type ConstDecs struct {
+	Items []ConstDecItem
+}
+
+// const宣言かenum宣言
+type ConstDecItem interface {
+	ToXML() []string
+	ToSource(p *SourcePrinter) []string
+}
+
+func NewConstDecs() *ConstDecs {
+	return &ConstDecs{
+		Items: []ConstDecItem{},
+	}
+}
+
+func (c *ConstDecs) Add(item ConstDecItem) {
+	c.Items = append(c.Items, item)
+}
+
+func (c *ConstDecs) HasConstDec(token *token.Token) bool {
+	if token == nil {
+		return false
+	}
+	return token.Value == "const" || token.Value == "enum"
+}
+
+func (c *ConstDecs) ToXML() []string {
+	result := []string{}
+	for _, item := range c.Items {
+		result = append(result, item.ToXML()...)
+	}
+	return result
+}
+
+func (c *ConstDecs) ToSource(p *SourcePrinter) []string {
+	result := []string{}
+	for _, item := range c.Items {
+		result = append(result, item.ToSource(p)...)
+	}
+	return result
+}
+
+// 'const' varType varName '=' expression ';'
+type ConstDec struct {
+	Keyword *Keyword
+	*VarType
+	*VarName
+	*Equal
+	*Expression
+	*Semicolon
+	Value int // 定数式を計算した値
+}
+
+func NewConstDec() *ConstDec {
+	return &ConstDec{
+		Keyword:   NewKeywordByValue("const"),
+		Equal:     ConstEqual,
+		Semicolon: ConstSemicolon,
+	}
+}
+
+func (c *ConstDec) SetVarType(token *token.Token) error {
+	varType := NewVarType(token)
+	if err := token.CheckKeywordValue("int", "char", "boolean"); err != nil {
+		message := fmt.Sprintf("error ConstDec: constant must be int, char or boolean: got = %s", token.Debug())
+		return errors.New(message)
+	}
+
+	c.VarType = varType
+	return nil
+}
+
+func (c *ConstDec) SetVarName(token *token.Token) error {
+	varName, err := NewVarNameOrError(token)
+	if err != nil {
+		return err
+	}
+
+	c.VarName = varName
+	return nil
+}
+
+func (c *ConstDec) SetExpression(expression *Expression, value int) {
+	c.Expression = expression
+	c.Value = value
+}
+
+func (c *ConstDec) ToXML() []string {
+	result := []string{}
+	result = append(result, "<constDec>")
+	result = append(result, c.Keyword.ToXML())
+	result = append(result, c.VarType.ToXML())
+	result = append(result, c.VarName.ToXML()...)
+	result = append(result, c.Equal.ToXML())
+	result = append(result, c.Expression.ToXML()...)
+	result = append(result, c.Semicolon.ToXML())
+	result = append(result, "</constDec>")
+	return result
+}
+
+// const int MAX = 10;
+func (c *ConstDec) ToSource(p *SourcePrinter) []string {
+	result := []string{}
+	result = append(result, p.Leading(c)...)
+	line := fmt.Sprintf("%s %s %s %s %s%s", c.Keyword.Value, c.VarType.Value, c.VarName.Value, c.Equal.Value, c.Expression.ToSource(), c.Semicolon.Value)
+	result = append(result, line+p.Trailing(c))
+	return result
+}
+
+// 'enum' enumName? '{' enumItem (',' enumItem)* '}'
+// 値を省略した要素は、ひとつ前の要素の値に1を足した値になる（先頭は0）
+// 名前は宣言の意味を表すためだけのもので、名前を付けても要素は名前のない場合と同じクラスの定数になる
+// （enum Color { RED } の要素は Color.RED ではなく、RED か Main.RED で参照する）
+type EnumDec struct {
+	Keyword  *Keyword
+	EnumName *Identifier // 名前を省略した場合はnil
+	*OpeningCurlyBracket
+	Items []*EnumItem
+	*ClosingCurlyBracket
+}
+
+func NewEnumDec() *EnumDec {
+	return &EnumDec{
+		Keyword:             NewKeywordByValue("enum"),
+		OpeningCurlyBracket: ConstOpeningCurlyBracket,
+		Items:               []*EnumItem{},
+		ClosingCurlyBracket: ConstClosingCurlyBracket,
+	}
+}
+
+func (e *EnumDec) SetEnumName(enumName *Identifier) {
+	e.EnumName = enumName
+}
+
+func (e *EnumDec) Add(item *EnumItem) {
+	e.Items = append(e.Items, item)
+}
+
+// 次に値を省略した要素の値
+func (e *EnumDec) nextValue() int {
+	if len(e.Items) == 0 {
+		return 0
+	}
+	return e.Items[len(e.Items)-1].Value + 1
+}
+
+func (e *EnumDec) ToXML() []string {
+	result := []string{}
+	result = append(result, "<enumDec>")
+	result = append(result, e.Keyword.ToXML())
+	if e.EnumName != nil {
+		result = append(result, e.EnumName.ToXML())
+	}
+	result = append(result, e.OpeningCurlyBracket.ToXML())
+	for i, item := range e.Items {
+		if i > 0 {
+			result = append(result, ConstComma.ToXML())
+		}
+		result = append(result, item.ToXML()...)
+	}
+	result = append(result, e.ClosingCurlyBracket.ToXML())
+	result = append(result, "</enumDec>")
+	return result
+}
+
+// enum { UP, DOWN, LEFT = 10 }
+// enum Direction { UP, DOWN, LEFT = 10 }
+func (e *EnumDec) ToSource(p *SourcePrinter) []string {
+	items := []string{}
+	for _, item := range e.Items {
+		items = append(items, item.ToSource())
+	}
+
+	keyword := e.Keyword.Value
+	if e.EnumName != nil {
+		keyword += " " + e.EnumName.Value
+	}
+
+	result := []string{}
+	result = append(result, p.Leading(e)...)
+	line := fmt.Sprintf("%s %s %s %s", keyword, e.OpeningCurlyBracket.Value, strings.Join(items, ", "), e.ClosingCurlyBracket.Value)
+	result = append(result, line+p.Trailing(e))
+	return result
+}
+
+// varName ('=' expression)?
+type EnumItem struct {
+	*VarName
+	*Expression // 値を省略した場合はnil
+	Value       int
+}
+
+func NewEnumItem(varName *VarName) *EnumItem {
+	return &EnumItem{
+		VarName: varName,
+	}
+}
+
+func (e *EnumItem) SetExpression(expression *Expression) {
+	e.Expression = expression
+}
+
+func (e *EnumItem) ToXML() []string {
+	result := []string{}
+	result = append(result, e.VarName.ToXML()...)
+	if e.Expression != nil {
+		result = append(result, ConstEqual.ToXML())
+		result = append(result, e.Expression.ToXML()...)
+	}
+	return result
+}
+
+func (e *EnumItem) ToSource() string {
+	if e.Expression == nil {
+		return e.VarName.Value
+	}
+	return fmt.Sprintf("%s %s %s", e.VarName.Value, ConstEqual.Value, e.Expression.ToSource())
+}
+
+// className '.' varName
+// 他のクラスの定数の参照で、パース時に値を解決しておく
+type ClassConstant struct {
+	*ClassName
+	*Period
+	*VarName
+	Value int
+}
+
+var _ Term = (*ClassConstant)(nil)
+
+func NewClassConstant(className *ClassName, varName *VarName, value int) *ClassConstant {
+	return &ClassConstant{
+		ClassName: className,
+		Period:    ConstPeriod,
+		VarName:   varName,
+		Value:     value,
+	}
+}
+
+func (c *ClassConstant) TermType() TermType {
+	return TermClassConstant
+}
+
+func (c *ClassConstant) ToXML() []string {
+	return []string{c.ClassName.Token.ToXML(), c.Period.ToXML(), c.VarName.Token.ToXML()}
+}
+
+func (c *ClassConstant) ToSource() string {
+	return fmt.Sprintf("%s%s%s", c.ClassName.Value, c.Period.Value, c.VarName.Value)
+}
+
+func (c *ClassConstant) ToCode(ctx *Context) []string {
+	return ConstantCode(c.Value)
+}
+
+func (c *ClassConstant) Debug() string {
+	return fmt.Sprintf("&ClassConstant{%s = %d}", c.ToSource(), c.Value)
+}
+
+// 定数を参照するときのキー
+func ConstantKey(className string, name string) string {
+	return className + "." + name
+}
+
+// 参照した定数が見つからないときのエラー
+// 定数を集めるときは、まだ値が決まっていない他のクラスの定数を後回しにするのに使う
+type UndefinedConstantError struct {
+	ClassName string
+	Name      string
+}
+
+func (e *UndefinedConstantError) Key() string {
+	return ConstantKey(e.ClassName, e.Name)
+}
+
+func (e *UndefinedConstantError) Error() string {
+	return fmt.Sprintf("error parseClassConstant: undefined constant: got = %s", e.Key())
+}
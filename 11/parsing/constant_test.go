@@ -0,0 +1,265 @@
+package parsing
+
+import (
+	"../token"
+	"github.com/google/go-cmp/cmp"
+	"strings"
+	"testing"
+)
+
+func newConstantTestParser(source string, constants map[string]int) *Parser {
+	tokenizer := token.NewTokenizer([]string{source})
+	tokenizer.SetExtended(true)
+	ctx := NewContext("Test")
+	ctx.Extended = true
+	ctx.Constants = constants
+	return NewParserWithContext(tokenizer.Tokenize(), ctx)
+}
+
+func TestConstantToCode(t *testing.T) {
+	cases := []struct {
+		desc      string
+		source    string
+		constants map[string]int
+		want      []string
+	}{
+		{
+			desc:   "定数はスタティック変数の領域を使わずにpush constantになる",
+			source: "class Test { const int SIZE = 4 * 8; static int x; function int f() { return SIZE + x; } }",
+			want: []string{
+				"function Test.f 0",
+				"push constant 32",
+				"push static 0",
+				"add",
+				"return",
+				"",
+			},
+		},
+		{
+			desc:   "負の定数はnegで作る",
+			source: "class Test { const int MAX = 10; const int MIN = -MAX; function int f() { return MIN; } }",
+			want: []string{
+				"function Test.f 0",
+				"push constant 10",
+				"neg",
+				"return",
+				"",
+			},
+		},
+		{
+			desc:   "名前のあるenumの要素も、名前のないenumと同じクラスの定数になる",
+			source: "class Test { enum Color { RED, GREEN = 5, BLUE } function int f() { return RED + Test.BLUE; } }",
+			want: []string{
+				"function Test.f 0",
+				"push constant 0",
+				"push constant 6",
+				"add",
+				"return",
+				"",
+			},
+		},
+		{
+			desc:   "enumの値は省略すると1つ前の値に1を足した値になる",
+			source: "class Test { enum { UP, DOWN, LEFT = 'A', RIGHT } function int f() { return UP + DOWN + RIGHT; } }",
+			want: []string{
+				"function Test.f 0",
+				"push constant 0",
+				"push constant 1",
+				"add",
+				"push constant 66",
+				"add",
+				"return",
+				"",
+			},
+		},
+		{
+			desc:      "他のクラスの定数をクラス名を付けて参照する",
+			source:    "class Test { const boolean DEBUG = true; function int f() { return Other.SIZE + Test.DEBUG; } }",
+			constants: map[string]int{"Other.SIZE": 5},
+			want: []string{
+				"function Test.f 0",
+				"push constant 5",
+				"push constant 1",
+				"neg",
+				"add",
+				"return",
+				"",
+			},
+		},
+		{
+			desc:   "クラス名の後ろに括弧があればサブルーチン呼び出し",
+			source: "class Test { function int f() { return Other.size(); } }",
+			want: []string{
+				"function Test.f 0",
+				"call Other.size 0",
+				"return",
+				"",
+			},
+		},
+		{
+			desc:   "ローカル変数は同じ名前の定数を隠す",
+			source: "class Test { const int x = 1; function int f() { var int x; return x; } }",
+			want: []string{
+				"function Test.f 1",
+				"push local 0",
+				"return",
+				"",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			parser := newConstantTestParser(tc.source, tc.constants)
+			if _, err := parser.Parse(); err != nil {
+				t.Fatalf("failed Parse: %+v", err)
+			}
+
+			if diff := cmp.Diff(parser.CodeLines(), tc.want); diff != "" {
+				t.Errorf("failed ToCode: diff (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestConstantError(t *testing.T) {
+	cases := []struct {
+		desc   string
+		source string
+		want   string
+	}{
+		{
+			desc:   "定数式に変数は使えない",
+			source: "class Test { const int SIZE = Other.size(); }",
+			want:   "error evaluateConstant: not a constant expression: got = &Token{Value: 'SIZE', TokenType: identifier}",
+		},
+		{
+			desc:   "定数に代入できない",
+			source: "class Test { const int SIZE = 1; function void f() { let SIZE = 2; return; } }",
+			want:   "error checkAssignable: cannot assign to constant: got = &Token{Value: 'SIZE', TokenType: identifier}",
+		},
+		{
+			desc:   "enumの名前は識別子だけ",
+			source: "class Test { enum 1 { A } }",
+			want:   "error TokenType: expected = Identifier EnumName: got = &Token{Value: '1', TokenType: integerConstant}",
+		},
+		{
+			desc:   "同じ名前の定数",
+			source: "class Test { const int A = 1; enum { B, A } }",
+			want:   "error addConstSymbol: duplicate constant: got = &Token{Value: 'A', TokenType: identifier}",
+		},
+		{
+			desc:   "宣言されていない定数",
+			source: "class Test { function int f() { return Other.SIZE; } }",
+			want:   "error parseClassConstant: undefined constant: got = Other.SIZE",
+		},
+		{
+			desc:   "定数の型はint、char、booleanだけ",
+			source: "class Test { const Array A = 1; }",
+			want:   "error ConstDec: constant must be int, char or boolean: got = &Token{Value: 'Array', TokenType: identifier}",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			parser := newConstantTestParser(tc.source, nil)
+			_, err := parser.parseClass()
+			if err == nil {
+				t.Fatal("failed parseClass: expected error")
+			}
+			if diff := cmp.Diff(err.Error(), tc.want); diff != "" {
+				t.Errorf("failed parseClass: diff (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestConstantToXML(t *testing.T) {
+	parser := newConstantTestParser("class Test { const char A = 'a'; enum { B, C = 2 } enum Color { D } }", nil)
+	class, err := parser.Parse()
+	if err != nil {
+		t.Fatalf("failed Parse: %+v", err)
+	}
+
+	want := []string{
+		"<class>",
+		"<keyword> class </keyword>",
+		"<identifier> Test </identifier>",
+		"<symbol> { </symbol>",
+		"<constDec>",
+		"<keyword> const </keyword>",
+		"<keyword> char </keyword>",
+		"<identifier> A </identifier>",
+		"<symbol> = </symbol>",
+		"<expression>",
+		"<term>",
+		"<charConstant> a </charConstant>",
+		"</term>",
+		"</expression>",
+		"<symbol> ; </symbol>",
+		"</constDec>",
+		"<enumDec>",
+		"<keyword> enum </keyword>",
+		"<symbol> { </symbol>",
+		"<identifier> B </identifier>",
+		"<symbol> , </symbol>",
+		"<identifier> C </identifier>",
+		"<symbol> = </symbol>",
+		"<expression>",
+		"<term>",
+		"<integerConstant> 2 </integerConstant>",
+		"</term>",
+		"</expression>",
+		"<symbol> } </symbol>",
+		"</enumDec>",
+		"<enumDec>",
+		"<keyword> enum </keyword>",
+		"<identifier> Color </identifier>",
+		"<symbol> { </symbol>",
+		"<identifier> D </identifier>",
+		"<symbol> } </symbol>",
+		"</enumDec>",
+		"<symbol> } </symbol>",
+		"</class>",
+	}
+	if diff := cmp.Diff(class.ToXML(), want); diff != "" {
+		t.Errorf("failed ToXML: diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestConstantToSource(t *testing.T) {
+	source := strings.Join([]string{
+		"class Test {",
+		"    const int SIZE = 4 * 8;",
+		"    enum { UP, DOWN, LEFT = 10 }",
+		"    enum Color { RED, GREEN }",
+		"    static int x;",
+		"}",
+	}, "\n")
+
+	parser := newConstantTestParser(strings.Replace(source, "\n", " ", -1), nil)
+	class, err := parser.Parse()
+	if err != nil {
+		t.Fatalf("failed Parse: %+v", err)
+	}
+
+	printer := NewSourcePrinter(NewSourceOptions(), nil, nil)
+	got := strings.Join(class.ToSource(printer), "\n")
+	if diff := cmp.Diff(got, source); diff != "" {
+		t.Errorf("failed ToSource: diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestParserParseConstants(t *testing.T) {
+	parser := newConstantTestParser("class Test { const int A = 3; enum { B = A + 1, C } function void f() { return Other.x; } }", nil)
+	got, err := parser.ParseConstants()
+	if err != nil {
+		t.Fatalf("failed ParseConstants: %+v", err)
+	}
+
+	// サブルーチンはパースしないので、未定義の定数を参照していてもエラーにならない
+	want := map[string]int{"Test.A": 3, "Test.B": 4, "Test.C": 5}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("failed ParseConstants: diff (-got +want):\n%s", diff)
+	}
+}
@@ -0,0 +1,110 @@
+package parsing
+
+import (
+	"../symbol"
+	"fmt"
+	"io"
+	"os"
+)
+
+// コンパイル単位（クラス）ごとの状態を管理
+// シンボルテーブルやID生成器をグローバル変数で共有しないことで、クラスごとに並行してコンパイルできる
+// またラベルのIDはクラスごとに採番されるため、コンパイルするファイルの順番に依存しない
+type Context struct {
+	*symbol.SymbolTables
+	*symbol.IdGenerator
+	*Positions
+	Warnings          []*Warning
+	FoldConstants     bool           // リテラルだけからなる式をコンパイル時に計算する
+	OptimizationLevel int            // OptimizeNoneかOptimizeSpeed
+	Extended          bool           // for文、break文、continue文、else if、const宣言、enum宣言を使える拡張文法を有効にする
+	Precedence        bool           // 二項演算子を左から順ではなく、優先順位に従って計算する
+	Constants         map[string]int // 他のクラスの定数（キーはConstantKeyで作る）
+	DebugInfo         bool           // デバッガ向けに、VMコマンドとソースの行の対応を記録する
+	DebugCode         bool
+	DebugSymbolTables bool
+	DebugWriter       io.Writer
+	loops             []*loopLabels // コード生成中のループ（内側のループほど後ろ）
+
+	// コード生成が終わったサブルーチンのシンボルテーブル
+	// パース後にASTの変数を解決できるように、サブルーチンごとに残しておく
+	subroutineSymbolTables map[*SubroutineDec]*symbol.SubroutineSymbolTable
+}
+
+// break文とcontinue文のジャンプ先
+type loopLabels struct {
+	breakLabel    string
+	continueLabel string
+}
+
+func NewContext(className string) *Context {
+	return &Context{
+		SymbolTables:      symbol.NewSymbolTables(className),
+		IdGenerator:       symbol.NewIdGenerator(),
+		Positions:         NewPositions(),
+		Warnings:          []*Warning{},
+		FoldConstants:     false,
+		OptimizationLevel: OptimizeNone,
+		Extended:          false,
+		Precedence:        false,
+		Constants:         map[string]int{},
+		DebugInfo:         false,
+		DebugCode:         false,
+		DebugSymbolTables: false,
+		DebugWriter:       os.Stdout,
+
+		subroutineSymbolTables: map[*SubroutineDec]*symbol.SubroutineSymbolTable{},
+	}
+}
+
+func (c *Context) SetDebug(debug bool) {
+	c.DebugCode = debug
+	c.DebugSymbolTables = debug
+}
+
+func (c *Context) PrintClassSymbolTable() {
+	if c.DebugSymbolTables {
+		fmt.Fprintln(c.DebugWriter, c.ClassSymbolTable.String())
+	}
+}
+
+func (c *Context) PrintSubroutineSymbolTable() {
+	if c.DebugSymbolTables {
+		fmt.Fprintln(c.DebugWriter, c.SubroutineSymbolTable.String())
+	}
+}
+
+func (c *Context) saveSubroutineSymbolTable(subroutineDec *SubroutineDec) {
+	c.subroutineSymbolTables[subroutineDec] = c.SubroutineSymbolTable
+}
+
+// サブルーチンの中で使うシンボルテーブル
+// 最適化で使われないローカル変数を取り除いた場合は、取り除いた後のシンボルテーブルになる
+func (c *Context) SymbolTablesOf(subroutineDec *SubroutineDec) *symbol.SymbolTables {
+	subroutineSymbolTable, ok := c.subroutineSymbolTables[subroutineDec]
+	if !ok {
+		subroutineSymbolTable = symbol.NewSubroutineSymbolTable("Uninitialized")
+	}
+	return &symbol.SymbolTables{
+		ClassSymbolTable:      c.ClassSymbolTable,
+		SubroutineSymbolTable: subroutineSymbolTable,
+	}
+}
+
+// ループの本体のコード生成を始める前に、break文とcontinue文のジャンプ先を登録する
+func (c *Context) PushLoop(breakLabel string, continueLabel string) {
+	c.loops = append(c.loops, &loopLabels{breakLabel: breakLabel, continueLabel: continueLabel})
+}
+
+func (c *Context) PopLoop() {
+	c.loops = c.loops[:len(c.loops)-1]
+}
+
+// 一番内側のループのジャンプ先
+// ループの外ではnilを返す
+func (c *Context) currentLoop() *loopLabels {
+	if len(c.loops) == 0 {
+		return nil
+	}
+	return c.loops[len(c.loops)-1]
+}
@@ -0,0 +1,137 @@
+package parsing
+
+// 到達できない文と分岐、使われないローカル変数を取り除いたサブルーチン本体を返す
+// パース結果のASTはXMLとソースコードの出力にも使うので書き換えず、取り除いた後の本体を新しく作る
+// ローカル変数を取り除いた場合は、残ったローカル変数の番号をシンボルテーブルで振り直す
+func (s *SubroutineBody) eliminateDeadCode(ctx *Context) *SubroutineBody {
+	statements := eliminateDeadStatements(ctx, s.Statements)
+
+	used := usedNames(statements)
+	varDecs := NewVarDecs()
+	for _, varDec := range s.VarDecs.Items {
+		for _, varName := range varDec.VarNameItems() {
+			if !used[varName.Value] {
+				continue
+			}
+			item := NewVarDec()
+			item.VarType = varDec.VarType
+			item.VarNames.First = varName
+			varDecs.AddVarDec(item)
+		}
+	}
+	ctx.RetainVarSymbols(used)
+
+	body := NewSubroutineBody()
+	body.VarDecs = varDecs
+	body.SetStatements(statements)
+	return body
+}
+
+// return文、break文、continue文の後ろの文と、条件がリテラルで実行されない分岐を取り除く
+func eliminateDeadStatements(ctx *Context, statements *Statements) *Statements {
+	result := NewStatements()
+	for _, item := range statements.Items {
+		for _, statement := range eliminateDeadStatement(ctx, item) {
+			result.AddStatement(statement)
+		}
+
+		// 必ずジャンプする文より後ろには到達しない
+		if len(result.Items) > 0 && alwaysJumps(result.Items[len(result.Items)-1]) {
+			break
+		}
+	}
+	return result
+}
+
+// 文を取り除いた結果、0個以上の文に置き換わる
+func eliminateDeadStatement(ctx *Context, statement Statement) []Statement {
+	switch s := statement.(type) {
+	case *IfStatement:
+		if value, ok := literalCondition(ctx, s.Expression); ok {
+			// 実行される側の分岐だけを残す
+			if value {
+				return eliminateDeadStatements(ctx, s.Statements).Items
+			}
+			if s.ElseBlock == nil {
+				return []Statement{}
+			}
+			return eliminateDeadStatements(ctx, s.ElseBlock.Statements).Items
+		}
+
+		copied := *s
+		ctx.CopySpan(&copied, s)
+		copied.Statements = eliminateDeadStatements(ctx, s.Statements)
+		if s.ElseBlock != nil {
+			elseBlock := *s.ElseBlock
+			elseBlock.Statements = eliminateDeadStatements(ctx, s.ElseBlock.Statements)
+			copied.ElseBlock = &elseBlock
+		}
+		return []Statement{&copied}
+	case *WhileStatement:
+		if value, ok := literalCondition(ctx, s.Expression); ok && !value {
+			return []Statement{}
+		}
+
+		copied := *s
+		ctx.CopySpan(&copied, s)
+		copied.Statements = eliminateDeadStatements(ctx, s.Statements)
+		return []Statement{&copied}
+	case *ForStatement:
+		if s.Condition != nil {
+			if value, ok := literalCondition(ctx, s.Condition); ok && !value {
+				// 初期化だけは実行される
+				if s.Init == nil {
+					return []Statement{}
+				}
+				return []Statement{s.Init.letStatement()}
+			}
+		}
+
+		copied := *s
+		ctx.CopySpan(&copied, s)
+		copied.Statements = eliminateDeadStatements(ctx, s.Statements)
+		return []Statement{&copied}
+	}
+	return []Statement{statement}
+}
+
+// 文の後ろに制御が移らないかどうか
+// if文は両方の分岐が必ずジャンプする場合だけ
+func alwaysJumps(statement Statement) bool {
+	switch s := statement.(type) {
+	case *ReturnStatement, *BreakStatement, *ContinueStatement:
+		return true
+	case *IfStatement:
+		if s.ElseBlock == nil {
+			return false
+		}
+		return endsWithJump(s.Statements) && endsWithJump(s.ElseBlock.Statements)
+	}
+	return false
+}
+
+func endsWithJump(statements *Statements) bool {
+	if len(statements.Items) == 0 {
+		return false
+	}
+	return alwaysJumps(statements.Items[len(statements.Items)-1])
+}
+
+// 文の中で参照される変数名
+// 代入されるだけの変数も、代入の副作用を残すために使われているとみなす
+func usedNames(statements *Statements) map[string]bool {
+	result := map[string]bool{}
+	Walk(statements, func(node interface{}) bool {
+		switch n := node.(type) {
+		case *VarName:
+			result[n.Value] = true
+		case *SubroutineCall:
+			// メソッド呼び出しのレシーバーはVarNameではないので個別に調べる
+			if n.CallerName != nil {
+				result[n.CallerName.Value] = true
+			}
+		}
+		return true
+	})
+	return result
+}
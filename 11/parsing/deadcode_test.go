@@ -0,0 +1,163 @@
+package parsing
+
+import (
+	"../token"
+	"github.com/google/go-cmp/cmp"
+	"testing"
+)
+
+func TestEliminateDeadCode(t *testing.T) {
+	cases := []struct {
+		desc     string
+		source   string
+		extended bool
+		want     []string
+	}{
+		{
+			desc:   "return文の後ろの文は取り除く",
+			source: "function int f() { var int x; return 1; let x = 2; }",
+			want: []string{
+				"function Test.f 0",
+				"push constant 1",
+				"return",
+				"",
+			},
+		},
+		{
+			desc:   "条件が偽のif文はelse句だけを残す",
+			source: "function int f() { var int x, y; if (false) { let x = 1; } else { let y = 2; } return y; }",
+			want: []string{
+				"function Test.f 1",
+				"push constant 2",
+				"pop local 0",
+				"push local 0",
+				"return",
+				"",
+			},
+		},
+		{
+			desc:   "条件が真のif文はif句だけを残し、その後ろのreturn文より後ろは取り除く",
+			source: "function int f() { if (~false) { return 1; } else { return 2; } return 3; }",
+			want: []string{
+				"function Test.f 0",
+				"push constant 1",
+				"return",
+				"",
+			},
+		},
+		{
+			desc:   "両方の分岐がreturn文で終わるif文の後ろは取り除く",
+			source: "function int f(int a) { if (a) { return 1; } else { return 2; } return 3; }",
+			want: []string{
+				"function Test.f 0",
+				"push argument 0",
+				"not",
+				"if-goto ELSE_START_ID_1",
+				"push constant 1",
+				"return",
+				"goto IF_END_ID_1",
+				"label ELSE_START_ID_1",
+				"push constant 2",
+				"return",
+				"label IF_END_ID_1",
+				"",
+			},
+		},
+		{
+			desc:   "条件が偽のwhile文は取り除き、その中でしか使わないローカル変数も取り除く",
+			source: "function void f() { var int i, j; while (false) { let i = i + 1; } let j = 0; return; }",
+			want: []string{
+				"function Test.f 1",
+				"push constant 0",
+				"pop local 0",
+				"push constant 0",
+				"return",
+				"",
+			},
+		},
+		{
+			desc:   "メソッド呼び出しのレシーバーだけに使うローカル変数は残す",
+			source: "function void f() { var Foo foo; var int unused; do foo.bar(); return; }",
+			want: []string{
+				"function Test.f 1",
+				"push local 0",
+				"call Foo.bar 1",
+				"pop temp 0",
+				"push constant 0",
+				"return",
+				"",
+			},
+		},
+		{
+			desc:     "条件が偽のfor文は初期化だけを残す",
+			source:   "function int f() { var int i; for (i = 3; false; i = i + 1) { } return i; }",
+			extended: true,
+			want: []string{
+				"function Test.f 1",
+				"push constant 3",
+				"pop local 0",
+				"push local 0",
+				"return",
+				"",
+			},
+		},
+		{
+			desc:     "break文の後ろの文は取り除く",
+			source:   "function void f() { var int x; while (x) { break; let x = 1; } return; }",
+			extended: true,
+			want: []string{
+				"function Test.f 1",
+				"label WHILE_START_ID_1",
+				"push local 0",
+				"not",
+				"if-goto WHILE_END_ID_1",
+				"goto WHILE_END_ID_1",
+				"goto WHILE_START_ID_1",
+				"label WHILE_END_ID_1",
+				"push constant 0",
+				"return",
+				"",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			tokenizer := token.NewTokenizer([]string{"class Test { " + tc.source + " }"})
+			tokenizer.SetExtended(tc.extended)
+			ctx := NewContext("Test")
+			ctx.Extended = tc.extended
+			ctx.OptimizationLevel = OptimizeSpeed
+			parser := NewParserWithContext(tokenizer.Tokenize(), ctx)
+			if _, err := parser.Parse(); err != nil {
+				t.Fatalf("failed Parse: %+v", err)
+			}
+
+			if diff := cmp.Diff(parser.CodeLines(), tc.want); diff != "" {
+				t.Errorf("failed ToCode: diff (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+// 取り除くのはコード生成だけで、XMLの出力には元の文が残る
+func TestEliminateDeadCodeKeepsXML(t *testing.T) {
+	source := "class Test { function void f() { var int x; return; let x = 1; } }"
+	want := NewParser(token.NewTokenizer([]string{source}).Tokenize(), "Test")
+	wantClass, err := want.Parse()
+	if err != nil {
+		t.Fatalf("failed Parse: %+v", err)
+	}
+
+	ctx := NewContext("Test")
+	ctx.OptimizationLevel = OptimizeSpeed
+	got := NewParserWithContext(token.NewTokenizer([]string{source}).Tokenize(), ctx)
+	gotClass, err := got.Parse()
+	if err != nil {
+		t.Fatalf("failed Parse: %+v", err)
+	}
+
+	if diff := cmp.Diff(gotClass.ToXML(), wantClass.ToXML()); diff != "" {
+		t.Errorf("failed ToXML: diff (-got +want):\n%s", diff)
+	}
+}
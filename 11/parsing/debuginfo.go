@@ -0,0 +1,123 @@
+package parsing
+
+import (
+	"../symbol"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// デバッガ向けの情報
+// VMのコマンドとJackのソースの行の対応と、各セグメントのインデックスに対応する変数名を持つ
+type DebugInfo struct {
+	Class       string             `json:"class"`
+	Statics     []string           `json:"statics"` // static iの変数名
+	Fields      []string           `json:"fields"`  // this iの変数名
+	Subroutines []*DebugSubroutine `json:"subroutines"`
+}
+
+type DebugSubroutine struct {
+	Name      string       `json:"name"` // Main.mainのようなVMの関数名
+	Kind      string       `json:"kind"` // function、method、constructor
+	Line      int          `json:"line"`
+	Arguments []string     `json:"arguments"` // argument iの変数名（methodでは最後がthis）
+	Locals    []string     `json:"locals"`    // local iの変数名
+	Lines     []*DebugLine `json:"lines"`
+}
+
+// functionコマンドから数えたVMコマンドの位置と、そのコマンドから始まるJackのソースの行
+type DebugLine struct {
+	Command int `json:"command"`
+	Line    int `json:"line"`
+}
+
+// 文のコードの前に置く目印
+// VMのコメントの形にしておき、サブルーチンのコード生成が終わったら取り除いて位置を記録する
+const lineMarkerPrefix = "// line "
+
+func lineMarker(line int) string {
+	return fmt.Sprintf("%s%d", lineMarkerPrefix, line)
+}
+
+// 目印を取り除いたコードと、VMコマンドとソースの行の対応を返す
+// 同じ位置に目印が続く場合（中身のない文など）は、最後の目印を使う
+// 位置のわからない文（最適化で作り直した文など）の目印は無視する
+func stripLineMarkers(lines []string, firstLine int) ([]string, []*DebugLine) {
+	code := []string{}
+	debugLines := []*DebugLine{{Command: 0, Line: firstLine}}
+	for _, line := range lines {
+		if !strings.HasPrefix(line, lineMarkerPrefix) {
+			code = append(code, line)
+			continue
+		}
+		number, err := strconv.Atoi(line[len(lineMarkerPrefix):])
+		if err != nil || number == 0 {
+			continue
+		}
+		last := debugLines[len(debugLines)-1]
+		switch {
+		case last.Command == len(code):
+			last.Line = number
+		case last.Line != number:
+			debugLines = append(debugLines, &DebugLine{Command: len(code), Line: number})
+		}
+	}
+	return code, debugLines
+}
+
+func newDebugSubroutine(ctx *Context, subroutineDec *SubroutineDec, lines []*DebugLine) *DebugSubroutine {
+	arguments := make([]string, ctx.ArgLength())
+	locals := make([]string, ctx.VarLength())
+	for _, item := range ctx.SubroutineSymbolTable.Items {
+		switch item.ScopeKind {
+		case symbol.ArgScope:
+			arguments[item.ScopeIndex] = item.SymbolName.Value
+		case symbol.VarScope:
+			locals[item.ScopeIndex] = item.SymbolName.Value
+		}
+	}
+	// methodの隠れ引数thisは、宣言した引数の後ろに渡される
+	if subroutineDec.Subroutine.Value == "method" {
+		arguments = append(arguments, "this")
+	}
+
+	name := subroutineDec.SubroutineName.Value
+	if subroutineDec.ClassName != nil {
+		name = fmt.Sprintf("%s.%s", subroutineDec.ClassName.Value, name)
+	}
+	return &DebugSubroutine{
+		Name:      name,
+		Kind:      subroutineDec.Subroutine.Value,
+		Line:      ctx.FindSpan(subroutineDec).Start.Line,
+		Arguments: arguments,
+		Locals:    locals,
+		Lines:     lines,
+	}
+}
+
+// クラスのデバッグ情報
+// Context.DebugInfoを有効にしてパースした場合だけ、サブルーチンの情報を持つ
+func (p *Parser) DebugInfo() *DebugInfo {
+	ctx := p.ctx
+	statics := make([]string, ctx.StaticLength())
+	fields := make([]string, ctx.FieldLength())
+	for _, item := range ctx.ClassSymbolTable.Items {
+		switch item.ScopeKind {
+		case symbol.StaticScope:
+			statics[item.ScopeIndex] = item.SymbolName.Value
+		case symbol.FieldScope:
+			fields[item.ScopeIndex] = item.SymbolName.Value
+		}
+	}
+
+	subroutines := p.Code.Subroutines
+	if subroutines == nil {
+		subroutines = []*DebugSubroutine{}
+	}
+	return &DebugInfo{
+		Class:       p.Class.ClassName.Value,
+		Statics:     statics,
+		Fields:      fields,
+		Subroutines: subroutines,
+	}
+}
@@ -0,0 +1,144 @@
+package parsing
+
+import (
+	"../token"
+	"github.com/google/go-cmp/cmp"
+	"testing"
+)
+
+func TestParserDebugInfo(t *testing.T) {
+	lines := []string{
+		"class Counter {",
+		"static int total;",
+		"field int count, step;",
+		"constructor Counter new(int s) {",
+		"let step = s;",
+		"return this;",
+		"}",
+		"method int add(int n) {",
+		"var int i, unused;",
+		"let i = 0;",
+		"while (i < n) {",
+		"let count = count + step;",
+		"let i = i + 1;",
+		"}",
+		"let total = total + n;",
+		"return count;",
+		"}",
+		"}",
+	}
+
+	cases := []struct {
+		desc              string
+		optimizationLevel int
+		want              *DebugInfo
+	}{
+		{
+			desc:              "最適化なし",
+			optimizationLevel: OptimizeNone,
+			want: &DebugInfo{
+				Class:   "Counter",
+				Statics: []string{"total"},
+				Fields:  []string{"count", "step"},
+				Subroutines: []*DebugSubroutine{
+					{
+						Name:      "Counter.new",
+						Kind:      "constructor",
+						Line:      4,
+						Arguments: []string{"s"},
+						Locals:    []string{},
+						Lines: []*DebugLine{
+							{Command: 0, Line: 4},
+							{Command: 4, Line: 5},
+							{Command: 6, Line: 6},
+						},
+					},
+					{
+						Name:      "Counter.add",
+						Kind:      "method",
+						Line:      8,
+						Arguments: []string{"n", "this"},
+						Locals:    []string{"i", "unused"},
+						Lines: []*DebugLine{
+							{Command: 0, Line: 8},
+							{Command: 3, Line: 10},
+							{Command: 5, Line: 11},
+							{Command: 11, Line: 12},
+							{Command: 15, Line: 13},
+							{Command: 21, Line: 15},
+							{Command: 25, Line: 16},
+						},
+					},
+				},
+			},
+		},
+		{
+			desc:              "最適化で作り直した文と、取り除いたローカル変数",
+			optimizationLevel: OptimizeSpeed,
+			want: &DebugInfo{
+				Class:   "Counter",
+				Statics: []string{"total"},
+				Fields:  []string{"count", "step"},
+				Subroutines: []*DebugSubroutine{
+					{
+						Name:      "Counter.new",
+						Kind:      "constructor",
+						Line:      4,
+						Arguments: []string{"s"},
+						Locals:    []string{},
+						Lines: []*DebugLine{
+							{Command: 0, Line: 4},
+							{Command: 4, Line: 5},
+							{Command: 6, Line: 6},
+						},
+					},
+					{
+						Name:      "Counter.add",
+						Kind:      "method",
+						Line:      8,
+						Arguments: []string{"n", "this"},
+						Locals:    []string{"i"},
+						Lines: []*DebugLine{
+							{Command: 0, Line: 8},
+							{Command: 3, Line: 10},
+							{Command: 5, Line: 11},
+							{Command: 7, Line: 12},
+							{Command: 11, Line: 13},
+							{Command: 21, Line: 15},
+							{Command: 25, Line: 16},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			parse := func(debugInfo bool) *Parser {
+				positions := []token.Position{}
+				for i := range lines {
+					positions = append(positions, token.NewPosition(i+1, 1))
+				}
+				tokens := token.NewTokenizerWithPositions(lines, positions).Tokenize()
+				ctx := NewContext("Counter")
+				ctx.OptimizationLevel = tc.optimizationLevel
+				ctx.DebugInfo = debugInfo
+				parser := NewParserWithContext(tokens, ctx)
+				if _, err := parser.Parse(); err != nil {
+					t.Fatalf("failed Parse: %+v", err)
+				}
+				return parser
+			}
+
+			parser := parse(true)
+			if diff := cmp.Diff(parser.DebugInfo(), tc.want); diff != "" {
+				t.Errorf("failed DebugInfo: diff (-got +want):\n%s", diff)
+			}
+			// デバッグ情報の有無で、生成するコードは変わらない
+			if diff := cmp.Diff(parser.CodeLines(), parse(false).CodeLines()); diff != "" {
+				t.Errorf("failed CodeLines: diff (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
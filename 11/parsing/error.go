@@ -0,0 +1,37 @@
+package parsing
+
+import (
+	"../token"
+	"fmt"
+	"github.com/pkg/errors"
+)
+
+// ソースの位置がわかるコンパイルエラー
+// 呼び出し側でファイル名を付けて、警告と同じ「file:line:col」の形で出力する
+type CompileError struct {
+	token.Position
+	Message string
+}
+
+func (e *CompileError) Error() string {
+	if e.Line == 0 {
+		return e.Message
+	}
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Message)
+}
+
+// 「file:line:col: error: message」の形にする
+func (e *CompileError) Format(filename string) string {
+	if e.Line == 0 {
+		return fmt.Sprintf("%s: error: %s", filename, e.Message)
+	}
+	return fmt.Sprintf("%s:%d:%d: error: %s", filename, e.Line, e.Column, e.Message)
+}
+
+// errの原因がCompileErrorなら、ファイル名を付けたエラーにする
+func WithFilename(err error, filename string) error {
+	if compileError, ok := errors.Cause(err).(*CompileError); ok {
+		return errors.New(compileError.Format(filename))
+	}
+	return errors.WithMessage(err, filename)
+}
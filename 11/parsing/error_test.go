@@ -0,0 +1,41 @@
+package parsing
+
+import (
+	"../token"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	"testing"
+)
+
+func TestWithFilename(t *testing.T) {
+	cases := []struct {
+		desc string
+		err  error
+		want string
+	}{
+		{
+			desc: "位置のあるコンパイルエラー",
+			err:  errors.WithStack(&CompileError{Position: token.Position{Line: 3, Column: 9}, Message: "integer constant 40000 is out of range"}),
+			want: "Main.jack:3:9: error: integer constant 40000 is out of range",
+		},
+		{
+			desc: "位置のないコンパイルエラー",
+			err:  errors.WithStack(&CompileError{Message: "undefined constant"}),
+			want: "Main.jack: error: undefined constant",
+		},
+		{
+			desc: "コンパイルエラー以外",
+			err:  errors.New("Invalid Statement"),
+			want: "Main.jack: Invalid Statement",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := WithFilename(tc.err, "Main.jack").Error()
+			if diff := cmp.Diff(got, tc.want); diff != "" {
+				t.Errorf("failed WithFilename: diff (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
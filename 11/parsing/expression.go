@@ -41,17 +41,21 @@ func (s *SubroutineCall) ToXML() []string {
 	return result
 }
 
-func (s *SubroutineCall) ToCode() []string {
+func (s *SubroutineCall) ToSource() string {
+	return fmt.Sprintf("%s%s%s%s", s.SubroutineCallName.ToSource(), s.OpeningRoundBracket.Value, s.ExpressionList.ToSource(), s.ClosingRoundBracket.Value)
+}
+
+func (s *SubroutineCall) ToCode(ctx *Context) []string {
 	length := s.ExpressionListLength()
-	callName := fmt.Sprintf("call %s", s.SubroutineCallName.ToCode(length))
+	callName := fmt.Sprintf("call %s", s.SubroutineCallName.ToCode(ctx, length))
 
 	result := []string{}
-	result = append(result, s.ExpressionList.ToCode()...)
+	result = append(result, s.ExpressionList.ToCode(ctx)...)
 
 	// TODO 二回もシンボルテーブルを参照しててわりとヒドい
 	// オブジェクトのメソッドコールの場合、隠れ引数をpushしておく
 	if s.SubroutineCallName.CallerName != nil {
-		symbolItem, _ := symbol.GlobalSymbolTables.FindSymbolItem(s.SubroutineCallName.CallerName.Value)
+		symbolItem, _ := ctx.FindSymbolItem(s.SubroutineCallName.CallerName.Value)
 		if symbolItem != nil {
 			code := fmt.Sprintf("push %s", symbolItem.ToCode())
 			result = append(result, code)
@@ -132,11 +136,44 @@ func (s *SubroutineCallName) ToXML() []string {
 	return result
 }
 
-func (s *SubroutineCallName) ToCode(length int) string {
+func (s *SubroutineCallName) ToSource() string {
+	if s.CallerName == nil {
+		return s.SubroutineName.Value
+	}
+	return fmt.Sprintf("%s%s%s", s.CallerName.Value, s.Period.Value, s.SubroutineName.Value)
+}
+
+func (s *SubroutineCallName) ToCode(ctx *Context, length int) string {
+	target := s.CallTarget(ctx)
+	if target.Method {
+		// 隠れ引数のthisかオブジェクトのベースアドレスの分、引数が一個多くなる
+		length++
+	}
+	return fmt.Sprintf("%s %d", target.Name(), length)
+}
+
+// シンボルテーブルから変数を探す
+// Contextのほか、コード生成後に保存したサブルーチンのシンボルテーブルでも探せるようにする
+type SymbolFinder interface {
+	FindSymbolItem(name string) (*symbol.SymbolItem, error)
+}
+
+// 呼び出すサブルーチン
+type CallTarget struct {
+	ClassName      string
+	SubroutineName string
+	Method         bool // thisかオブジェクトを隠れ引数として渡す
+}
+
+func (c *CallTarget) Name() string {
+	return fmt.Sprintf("%s.%s", c.ClassName, c.SubroutineName)
+}
+
+func (s *SubroutineCallName) CallTarget(symbols SymbolFinder) *CallTarget {
 	if s.CallerName == nil {
 		// s.CallerNameがnilの場合、自身のクラスに定義されているメソッドを呼び出そうとしていると判定
 		// その場合はClassNameをCallerNameだとみなす
-		return fmt.Sprintf("%s.%s %d", s.ClassName.Value, s.SubroutineName.Value, length+1)
+		return &CallTarget{ClassName: s.ClassName.Value, SubroutineName: s.SubroutineName.Value, Method: true}
 	}
 
 	// CallerNameに値が設定されている場合、二パターン存在する
@@ -146,17 +183,15 @@ func (s *SubroutineCallName) ToCode(length int) string {
 	//
 	// そこでCallerNameをシンボルテーブルで検索し、
 	// シンボルテーブルに値が存在するか否かで、クラス名かオブジェクト名か判定する
-	symbolItem, err := symbol.GlobalSymbolTables.FindSymbolItem(s.CallerName.Value)
+	symbolItem, err := symbols.FindSymbolItem(s.CallerName.Value)
 	if err != nil {
 		// CallerNameがシンボルテーブルに存在しない場合は、クラス名と判定
-		return fmt.Sprintf("%s.%s %d", s.CallerName.Value, s.SubroutineName.Value, length)
-	} else {
-		// CallerNameがシンボルテーブルに存在する場合は、オブジェクト名と判定
-		// シンボルテーブルからそのオブジェクトの型名（＝クラス名）を取得して、サブルーチンを呼べるようにする
-		// 隠れ引数として、オブジェクトのベースアドレスをサブルーチンに渡すことに注意
-		// そのためcall実行時に渡す引数は、function定義より一個多くなる
-		return fmt.Sprintf("%s.%s %d", symbolItem.SymbolType.Value, s.SubroutineName.Value, length+1)
+		return &CallTarget{ClassName: s.CallerName.Value, SubroutineName: s.SubroutineName.Value, Method: false}
 	}
+
+	// CallerNameがシンボルテーブルに存在する場合は、オブジェクト名と判定
+	// シンボルテーブルからそのオブジェクトの型名（＝クラス名）を取得して、サブルーチンを呼べるようにする
+	return &CallTarget{ClassName: symbolItem.SymbolType.Value, SubroutineName: s.SubroutineName.Value, Method: true}
 }
 
 func (s *SubroutineCallName) Debug(baseIndent int) string {
@@ -218,6 +253,19 @@ func (e *ExpressionList) ExpressionListLength() int {
 	return 1 + len(e.CommaAndExpressions)
 }
 
+func (e *ExpressionList) ExpressionItems() []*Expression {
+	result := []*Expression{}
+	if e.First == nil {
+		return result
+	}
+
+	result = append(result, e.First)
+	for _, item := range e.CommaAndExpressions {
+		result = append(result, item.Expression)
+	}
+	return result
+}
+
 func (e *ExpressionList) ToXML() []string {
 	result := []string{}
 	result = append(result, "<expressionList>")
@@ -233,14 +281,26 @@ func (e *ExpressionList) ToXML() []string {
 	return result
 }
 
-func (e *ExpressionList) ToCode() []string {
+func (e *ExpressionList) ToSource() string {
+	if e.First == nil {
+		return ""
+	}
+
+	result := e.First.ToSource()
+	for _, item := range e.CommaAndExpressions {
+		result += fmt.Sprintf("%s %s", item.Comma.Value, item.Expression.ToSource())
+	}
+	return result
+}
+
+func (e *ExpressionList) ToCode(ctx *Context) []string {
 	result := []string{}
 	if e.First != nil {
-		result = append(result, e.First.ToCode()...)
+		result = append(result, e.First.ToCode(ctx)...)
 	}
 
 	for _, item := range e.CommaAndExpressions {
-		result = append(result, item.ToCode()...)
+		result = append(result, item.ToCode(ctx)...)
 	}
 	return result
 }
@@ -269,6 +329,7 @@ type GroupingExpression struct {
 	*Expression
 	*OpeningRoundBracket
 	*ClosingRoundBracket
+	Implicit bool // 優先順位モードで補った、ソースコードに書かれていないカッコ
 }
 
 var _ Term = (*GroupingExpression)(nil)
@@ -286,6 +347,10 @@ func (g *GroupingExpression) TermType() TermType {
 }
 
 func (g *GroupingExpression) ToXML() []string {
+	if g.Implicit {
+		return g.Expression.ToXML()
+	}
+
 	result := []string{}
 	result = append(result, g.OpeningRoundBracket.ToXML())
 	result = append(result, g.Expression.ToXML()...)
@@ -293,9 +358,16 @@ func (g *GroupingExpression) ToXML() []string {
 	return result
 }
 
-func (g *GroupingExpression) ToCode() []string {
+func (g *GroupingExpression) ToSource() string {
+	if g.Implicit {
+		return g.Expression.ToSource()
+	}
+	return fmt.Sprintf("%s%s%s", g.OpeningRoundBracket.Value, g.Expression.ToSource(), g.ClosingRoundBracket.Value)
+}
+
+func (g *GroupingExpression) ToCode(ctx *Context) []string {
 	result := []string{}
-	result = append(result, g.Expression.ToCode()...)
+	result = append(result, g.Expression.ToCode(ctx)...)
 	return result
 }
 
@@ -342,10 +414,14 @@ func (a *Array) ToXML() []string {
 	return result
 }
 
-func (a *Array) ToCode() []string {
+func (a *Array) ToSource() string {
+	return fmt.Sprintf("%s%s%s%s", a.VarName.ToSource(), a.OpeningSquareBracket.Value, a.Expression.ToSource(), a.ClosingSquareBracket.Value)
+}
+
+func (a *Array) ToCode(ctx *Context) []string {
 	result := []string{}
-	result = append(result, a.VarName.ToCode()...)
-	result = append(result, a.Expression.ToCode()...)
+	result = append(result, a.VarName.ToCode(ctx)...)
+	result = append(result, a.Expression.ToCode(ctx)...)
 	result = append(result, "add")
 	result = append(result, "pop pointer 1")
 	result = append(result, "push that 0")
@@ -378,15 +454,86 @@ func (e *Expression) ToXML() []string {
 	return result
 }
 
-func (e *Expression) ToCode() []string {
+// 二項演算子の前後には半角空白を一つ入れる
+func (e *Expression) ToSource() string {
+	result := e.Term.ToSource()
+	if e.BinaryOpTerms != nil {
+		for _, item := range e.BinaryOpTerms.Items {
+			result += fmt.Sprintf(" %s %s", item.BinaryOp.ToSource(), item.Term.ToSource())
+		}
+	}
+	return result
+}
+
+func (e *Expression) ToCode(ctx *Context) []string {
+	if ctx.FoldConstants {
+		if result, ok := e.foldedCode(ctx); ok {
+			return result
+		}
+	}
+
+	if ctx.OptimizationLevel >= OptimizeSpeed {
+		if result, ok := e.leadingMultiplyCode(ctx); ok {
+			return result
+		}
+	}
+
 	result := []string{}
-	result = append(result, e.Term.ToCode()...)
+	result = append(result, e.Term.ToCode(ctx)...)
 	if e.BinaryOpTerms != nil {
-		result = append(result, e.BinaryOpTerms.ToCode()...)
+		result = append(result, e.BinaryOpTerms.ToCode(ctx)...)
 	}
 	return result
 }
 
+// 掛け算は交換できるので、8 * x のように左辺が定数でも x * 8 と同じように計算する
+func (e *Expression) leadingMultiplyCode(ctx *Context) ([]string, bool) {
+	if e.binaryOpTermsLength() == 0 {
+		return nil, false
+	}
+	first := e.BinaryOpTerms.Items[0]
+	if first.BinaryOp.OpType() != AsteriskType {
+		return nil, false
+	}
+	code, ok := multiplyCode(e.Term)
+	if !ok {
+		return nil, false
+	}
+
+	result := first.Term.ToCode(ctx)
+	result = append(result, code...)
+	for _, item := range e.BinaryOpTerms.Items[1:] {
+		result = append(result, item.ToCode(ctx)...)
+	}
+	return result, true
+}
+
+// 式の先頭からリテラルだけで計算できる部分を一つの定数にまとめる
+// 2 * 8 + x なら、2 * 8 を計算して push constant 16 にする
+func (e *Expression) foldedCode(ctx *Context) ([]string, bool) {
+	folder := newFolder(ctx, e)
+	value, length, ok := folder.prefix(e)
+	folder.commit()
+	if !ok || folder.operations == 0 {
+		return nil, false
+	}
+
+	result := ConstantCode(value)
+	if e.BinaryOpTerms != nil {
+		for _, item := range e.BinaryOpTerms.Items[length:] {
+			result = append(result, item.ToCode(ctx)...)
+		}
+	}
+	return result, true
+}
+
+func (e *Expression) binaryOpTermsLength() int {
+	if e.BinaryOpTerms == nil {
+		return 0
+	}
+	return len(e.BinaryOpTerms.Items)
+}
+
 type BinaryOpTerms struct {
 	Items []*BinaryOpTerm
 }
@@ -409,10 +556,10 @@ func (b *BinaryOpTerms) ToXML() []string {
 	return result
 }
 
-func (b *BinaryOpTerms) ToCode() []string {
+func (b *BinaryOpTerms) ToCode(ctx *Context) []string {
 	result := []string{}
 	for _, item := range b.Items {
-		result = append(result, item.ToCode()...)
+		result = append(result, item.ToCode(ctx)...)
 	}
 	return result
 }
@@ -436,9 +583,15 @@ func (b *BinaryOpTerm) ToXML() []string {
 	return result
 }
 
-func (b *BinaryOpTerm) ToCode() []string {
+func (b *BinaryOpTerm) ToCode(ctx *Context) []string {
+	if ctx.OptimizationLevel >= OptimizeSpeed {
+		if result, ok := b.optimizedCode(ctx); ok {
+			return result
+		}
+	}
+
 	result := []string{}
-	result = append(result, b.Term.ToCode()...)
+	result = append(result, b.Term.ToCode(ctx)...)
 	result = append(result, b.BinaryOp.ToCode()...)
 	return result
 }
@@ -652,6 +805,7 @@ func (e *Equals) ToCode() []string {
 
 type BinaryOp interface {
 	OpType() BinaryOpType
+	ToSource() string
 	ToXML() string
 	ToCode() []string
 }
@@ -699,9 +853,21 @@ func (u *UnaryOpTerm) ToXML() []string {
 	return result
 }
 
-func (u *UnaryOpTerm) ToCode() []string {
+func (u *UnaryOpTerm) ToSource() string {
+	return u.UnaryOp.ToSource() + u.Term.ToSource()
+}
+
+func (u *UnaryOpTerm) ToCode(ctx *Context) []string {
+	if ctx.FoldConstants {
+		folder := newFolder(ctx, u)
+		if value, ok := folder.term(u); ok {
+			folder.commit()
+			return ConstantCode(value)
+		}
+	}
+
 	result := []string{}
-	result = append(result, u.Term.ToCode()...)
+	result = append(result, u.Term.ToCode(ctx)...)
 	result = append(result, u.UnaryOp.ToCode()...)
 	return result
 }
@@ -771,6 +937,7 @@ func (t *UnaryTilde) ToCode() []string {
 
 type UnaryOp interface {
 	OpType() UnaryOpType
+	ToSource() string
 	ToXML() string
 	ToCode() []string
 }
@@ -834,7 +1001,11 @@ func (k *KeywordConstant) ToXML() []string {
 	return []string{k.Token.ToXML()}
 }
 
-func (k *KeywordConstant) ToCode() []string {
+func (k *KeywordConstant) ToSource() string {
+	return k.Value
+}
+
+func (k *KeywordConstant) ToCode(ctx *Context) []string {
 	code := fmt.Sprintf("KeywordConstant_not_implemented, %s", k.Value)
 	return []string{code}
 }
@@ -843,7 +1014,7 @@ type TrueKeywordConstant struct {
 	*KeywordConstant
 }
 
-func (t *TrueKeywordConstant) ToCode() []string {
+func (t *TrueKeywordConstant) ToCode(ctx *Context) []string {
 	result := []string{}
 	result = append(result, "push constant 1")
 	result = append(result, "neg")
@@ -858,7 +1029,7 @@ type FalseKeywordConstant struct {
 	*KeywordConstant
 }
 
-func (f *FalseKeywordConstant) ToCode() []string {
+func (f *FalseKeywordConstant) ToCode(ctx *Context) []string {
 	return []string{"push constant 0"}
 }
 
@@ -870,7 +1041,7 @@ type NullKeywordConstant struct {
 	*KeywordConstant
 }
 
-func (n *NullKeywordConstant) ToCode() []string {
+func (n *NullKeywordConstant) ToCode(ctx *Context) []string {
 	return []string{"push constant 0"}
 }
 
@@ -886,7 +1057,7 @@ var ConstThis = &ThisKeywordConstant{
 	KeywordConstant: NewKeywordConstant("this"),
 }
 
-func (t *ThisKeywordConstant) ToCode() []string {
+func (t *ThisKeywordConstant) ToCode(ctx *Context) []string {
 	return []string{"push pointer 0"}
 }
 
@@ -910,6 +1081,32 @@ func (s *StringConstant) Check() error {
 	return s.Token.CheckStringConstant()
 }
 
+// 文字列定値の中身をHackの文字コードの列に変換する
+// エスケープシーケンスは拡張文法のときだけ解釈し、それ以外は「\」も書かれたままの文字として扱う
+// Hackの文字セットにない文字はコンパイルエラーにする
+func (s *StringConstant) Chars(ctx *Context) ([]int, error) {
+	result := []int{}
+	if ctx.Extended {
+		chars, err := token.DecodeChars(s.Value)
+		if err != nil {
+			return nil, err
+		}
+		result = chars
+	} else {
+		for _, rune := range s.Value {
+			result = append(result, int(rune))
+		}
+	}
+
+	for _, char := range result {
+		if !token.IsHackChar(char) {
+			message := fmt.Sprintf("character %q in string constant is not in the Hack character set", rune(char))
+			return nil, errors.WithStack(&CompileError{Position: s.Token.Position, Message: message})
+		}
+	}
+	return result, nil
+}
+
 func (s *StringConstant) TermType() TermType {
 	return TermStringConstant
 }
@@ -918,21 +1115,34 @@ func (s *StringConstant) ToXML() []string {
 	return []string{s.Token.ToXML()}
 }
 
-func (s *StringConstant) ToCode() []string {
+func (s *StringConstant) ToSource() string {
+	return fmt.Sprintf("\"%s\"", s.Value)
+}
+
+// メソッド呼び出しでは隠れ引数thisを最後の引数として渡すので、appendCharも「文字, Stringオブジェクト」の順に積む
+// Stringオブジェクトは文字ごとにtemp 0へ退避し、文字を積んでから積み直す
+// appendCharの返り値をそのまま次の文字で退避するので、appendCharの中でtemp 0が使われても問題ない
+func (s *StringConstant) ToCode(ctx *Context) []string {
+	chars, err := s.Chars(ctx)
+	if err != nil {
+		message := fmt.Sprintf("error StringConstant.ToCode(): %v", err)
+		return []string{message}
+	}
+
 	result := []string{}
 	// 文字列の最大長maxLengthを計算してスタックに積む
-	result = append(result, fmt.Sprintf("push constant %d", len(s.Value)))
+	result = append(result, fmt.Sprintf("push constant %d", len(chars)))
 	// スタックの一番上にある値（maxLength）を引数にして、Stringオブジェクトを生成
 	// 「call String.new」を実行したあとに、スタックの一番上には作成したStringオブジェクトのアドレスが積まれる
 	result = append(result, "call String.new 1")
 	// 一文字ずつ文字をStringにセットしていく
-	for _, rune := range s.Value {
-		// runeにはint32の文字コードが入っているので、それをそのままスタックに積む
-		result = append(result, fmt.Sprintf("push constant %d", rune))
-		// 「call String.appendChar」実行時には、隠れ引数としてStringオブジェクトのアドレスを渡す必要がある
-		// 最初は「call String.new」が生成したアドレスを使用する
-		// 二回目以降は「call String.appendChar」の返り値として、
-		// Stringオブジェクトのアドレスがスタックに積まれるのでそれを使う
+	for _, char := range chars {
+		// スタックの一番上にあるStringオブジェクトを退避して、文字の後ろに積み直す
+		result = append(result, "pop temp 0")
+		result = append(result, fmt.Sprintf("push constant %d", char))
+		result = append(result, "push temp 0")
+		// 「call String.appendChar」の返り値としてStringオブジェクトのアドレスがスタックに積まれるので、
+		// 次の文字もそれを使う
 		result = append(result, "call String.appendChar 2")
 	}
 	return result
@@ -954,8 +1164,23 @@ func NewIntegerConstantByValue(value string) *IntegerConstant {
 	return NewIntegerConstant(token.NewToken(value, token.TokenIntConst))
 }
 
+// Hackのpush constantで扱える0..32767の範囲かどうかも確認する
 func (i *IntegerConstant) Check() error {
-	return i.Token.CheckIntegerConstant()
+	if err := i.Token.CheckIntegerConstant(); err != nil {
+		return err
+	}
+
+	value, err := i.IntValue()
+	if err != nil || value < 0 || value > MaxInteger {
+		message := fmt.Sprintf("integer constant %s out of range 0..%d", i.Token.Value, MaxInteger)
+		return errors.WithStack(&CompileError{Position: i.Token.Position, Message: message})
+	}
+	return nil
+}
+
+// 16進数と2進数で書かれた定値も数値に変換する
+func (i *IntegerConstant) IntValue() (int, error) {
+	return token.ParseInteger(i.Value)
 }
 
 func (i *IntegerConstant) TermType() TermType {
@@ -966,8 +1191,86 @@ func (i *IntegerConstant) ToXML() []string {
 	return []string{i.Token.ToXML()}
 }
 
-func (i *IntegerConstant) ToCode() []string {
-	code := fmt.Sprintf("push constant %s", i.Value)
+func (i *IntegerConstant) ToSource() string {
+	return i.Value
+}
+
+// VMのpush constantは10進数しか受け付けないので、16進数と2進数は10進数に直す
+func (i *IntegerConstant) ToCode(ctx *Context) []string {
+	value, err := i.IntValue()
+	if err != nil {
+		message := fmt.Sprintf("error IntegerConstant.ToCode(): %v", err)
+		return []string{message}
+	}
+	code := fmt.Sprintf("push constant %d", value)
+	return []string{code}
+}
+
+type CharConstant struct {
+	*token.Token
+}
+
+var _ Term = (*CharConstant)(nil)
+
+func NewCharConstant(token *token.Token) *CharConstant {
+	return &CharConstant{
+		Token: token,
+	}
+}
+
+func NewCharConstantByValue(value string) *CharConstant {
+	return NewCharConstant(token.NewToken(value, token.TokenCharConst))
+}
+
+// エスケープシーケンスを解釈して、ちょうど1文字になるかどうかも確認する
+func (c *CharConstant) Check() error {
+	if err := c.Token.CheckCharConstant(); err != nil {
+		return err
+	}
+
+	if _, err := c.IntValue(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// 文字定値の文字コード
+func (c *CharConstant) IntValue() (int, error) {
+	chars, err := token.DecodeChars(c.Value)
+	if err != nil {
+		return 0, err
+	}
+	if len(chars) != 1 {
+		message := fmt.Sprintf("error CharConstant: must be a single character: got = %s", c.Token.Debug())
+		return 0, errors.New(message)
+	}
+	if !token.IsHackChar(chars[0]) {
+		message := fmt.Sprintf("character %q in char constant is not in the Hack character set", rune(chars[0]))
+		return 0, errors.WithStack(&CompileError{Position: c.Token.Position, Message: message})
+	}
+	return chars[0], nil
+}
+
+func (c *CharConstant) TermType() TermType {
+	return TermCharConstant
+}
+
+func (c *CharConstant) ToXML() []string {
+	return []string{c.Token.ToXML()}
+}
+
+func (c *CharConstant) ToSource() string {
+	return fmt.Sprintf("'%s'", c.Value)
+}
+
+// 文字定値は文字コードの整数定値と同じ
+func (c *CharConstant) ToCode(ctx *Context) []string {
+	value, err := c.IntValue()
+	if err != nil {
+		message := fmt.Sprintf("error CharConstant.ToCode(): %v", err)
+		return []string{message}
+	}
+	code := fmt.Sprintf("push constant %d", value)
 	return []string{code}
 }
 
@@ -985,7 +1288,8 @@ func (t *TermXMLConverter) ToTermXML(contents ...string) []string {
 
 type Term interface {
 	TermType() TermType
-	ToCode() []string
+	ToCode(ctx *Context) []string
+	ToSource() string
 	ToXML() []string
 	Debug() string
 }
@@ -1002,4 +1306,6 @@ const (
 	TermArray              // varName '[' expression ']'
 	TermGroupingExpression // '(' expression ')'
 	TermUnaryOpTerm        // unaryOp term
+	TermCharConstant       // 'A'
+	TermClassConstant      // className '.' varName
 )
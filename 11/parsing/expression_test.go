@@ -1,7 +1,6 @@
 package parsing
 
 import (
-	"../symbol"
 	"../token"
 	"github.com/google/go-cmp/cmp"
 	"testing"
@@ -103,15 +102,25 @@ func TestExpressionToCode(t *testing.T) {
 			want: []string{
 				"push constant 5",          // 文字列長maxLength
 				"call String.new 1",        // maxLengthを指定してStringオブジェクトを生成
+				"pop temp 0",               // Stringオブジェクトを退避
 				"push constant 72",         // 'H'
+				"push temp 0",              // thisは最後の引数
 				"call String.appendChar 2", // 'H' を引数にappendCharを実行
+				"pop temp 0",               // Stringオブジェクトを退避
 				"push constant 101",        // 'e'
+				"push temp 0",              // thisは最後の引数
 				"call String.appendChar 2", // 'e' を引数にappendCharを実行
+				"pop temp 0",               // Stringオブジェクトを退避
 				"push constant 108",        // 'l'
+				"push temp 0",              // thisは最後の引数
 				"call String.appendChar 2", // 'l' を引数にappendCharを実行
+				"pop temp 0",               // Stringオブジェクトを退避
 				"push constant 108",        // 'l'
+				"push temp 0",              // thisは最後の引数
 				"call String.appendChar 2", // 'l' を引数にappendCharを実行
+				"pop temp 0",               // Stringオブジェクトを退避
 				"push constant 111",        // 'o'
+				"push temp 0",              // thisは最後の引数
 				"call String.appendChar 2", // 'o' を引数にappendCharを実行
 			},
 		},
@@ -438,18 +447,18 @@ func TestExpressionToCode(t *testing.T) {
 	}
 
 	// いろいろ初期化
-	SetupTestForToCode()
+	ctx := SetupTestForToCode()
 	// シンボルテーブルのセットアップ
-	symbol.GlobalSymbolTables.AddVarSymbol("foo", "int")
-	symbol.GlobalSymbolTables.AddVarSymbol("obj", "Square")
-	symbol.GlobalSymbolTables.AddVarSymbol("array", "Array")
-	symbol.GlobalSymbolTables.AddArgSymbol("bar", "int")
-	symbol.GlobalSymbolTables.AddFieldSymbol("fieldA", "int")
-	symbol.GlobalSymbolTables.AddStaticSymbol("staticA", "int")
+	ctx.AddVarSymbol("foo", "int")
+	ctx.AddVarSymbol("obj", "Square")
+	ctx.AddVarSymbol("array", "Array")
+	ctx.AddArgSymbol("bar", "int")
+	ctx.AddFieldSymbol("fieldA", "int")
+	ctx.AddStaticSymbol("staticA", "int")
 
 	for _, tc := range cases {
 		t.Run(tc.desc, func(t *testing.T) {
-			got := tc.expression.ToCode()
+			got := tc.expression.ToCode(ctx)
 
 			if diff := cmp.Diff(got, tc.want); diff != "" {
 				t.Errorf("failed: diff (-got +want):\n%s", diff)
@@ -457,3 +466,139 @@ func TestExpressionToCode(t *testing.T) {
 		})
 	}
 }
+
+func TestLiteralToCode(t *testing.T) {
+	cases := []struct {
+		desc     string
+		term     Term
+		extended bool
+		want     []string
+	}{
+		{
+			desc: "16進数の整数定値は10進数で積む",
+			term: NewIntegerConstantByValue("0x4000"),
+			want: []string{"push constant 16384"},
+		},
+		{
+			desc: "2進数の整数定値は10進数で積む",
+			term: NewIntegerConstantByValue("0b101"),
+			want: []string{"push constant 5"},
+		},
+		{
+			desc: "文字定値は文字コードを積む",
+			term: NewCharConstantByValue("A"),
+			want: []string{"push constant 65"},
+		},
+		{
+			desc: "改行の文字定値はHackの文字コードを積む",
+			term: NewCharConstantByValue(`\n`),
+			want: []string{"push constant 128"},
+		},
+		{
+			desc:     "拡張文法ではエスケープシーケンスを含む文字列定値を変換する",
+			term:     NewStringConstantByValue(`\"\n`),
+			extended: true,
+			want: []string{
+				"push constant 2",
+				"call String.new 1",
+				"pop temp 0",
+				"push constant 34",
+				"push temp 0",
+				"call String.appendChar 2",
+				"pop temp 0",
+				"push constant 128",
+				"push temp 0",
+				"call String.appendChar 2",
+			},
+		},
+		{
+			desc: "拡張文法でなければ「\\\\」は書かれたままの2文字",
+			term: NewStringConstantByValue(`\\`),
+			want: []string{
+				"push constant 2",
+				"call String.new 1",
+				"pop temp 0",
+				"push constant 92",
+				"push temp 0",
+				"call String.appendChar 2",
+				"pop temp 0",
+				"push constant 92",
+				"push temp 0",
+				"call String.appendChar 2",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			ctx := SetupTestForToCode()
+			ctx.Extended = tc.extended
+			got := tc.term.ToCode(ctx)
+			if diff := cmp.Diff(got, tc.want); diff != "" {
+				t.Errorf("failed: diff (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestLiteralCheck(t *testing.T) {
+	cases := []struct {
+		desc  string
+		check func() error
+		want  string
+	}{
+		{
+			desc:  "範囲外の16進数",
+			check: NewIntegerConstantByValue("0x8000").Check,
+			want:  "integer constant 0x8000 out of range 0..32767",
+		},
+		{
+			desc:  "2文字の文字定値",
+			check: NewCharConstantByValue("AB").Check,
+			want:  "error CharConstant: must be a single character: got = &Token{Value: 'AB', TokenType: charConstant}",
+		},
+		{
+			desc: "拡張文法での不明なエスケープシーケンス",
+			check: func() error {
+				ctx := SetupTestForToCode()
+				ctx.Extended = true
+				_, err := NewStringConstantByValue(`\x`).Chars(ctx)
+				return err
+			},
+			want: `error DecodeChars: unknown escape sequence \x: \x`,
+		},
+		{
+			desc: "Hackの文字セットにない文字を含む文字列定値",
+			check: func() error {
+				_, err := NewStringConstantByValue("Aあ").Chars(SetupTestForToCode())
+				return err
+			},
+			want: "character 'あ' in string constant is not in the Hack character set",
+		},
+		{
+			desc: "Hackの文字セットにない制御文字を含む文字列定値",
+			check: func() error {
+				_, err := NewStringConstantByValue("A\tB").Chars(SetupTestForToCode())
+				return err
+			},
+			want: `character '\t' in string constant is not in the Hack character set`,
+		},
+		{
+			desc:  "Hackの文字セットにない文字定値",
+			check: NewCharConstantByValue("é").Check,
+			want:  "character 'é' in char constant is not in the Hack character set",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := tc.check()
+			if err == nil {
+				t.Fatal("failed Check: expected error")
+			}
+			if diff := cmp.Diff(err.Error(), tc.want); diff != "" {
+				t.Errorf("failed Check: diff (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
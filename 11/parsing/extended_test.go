@@ -0,0 +1,298 @@
+package parsing
+
+import (
+	"../token"
+	"github.com/google/go-cmp/cmp"
+	"strings"
+	"testing"
+)
+
+func newExtendedTestParser(source string) *Parser {
+	tokenizer := token.NewTokenizer([]string{source})
+	tokenizer.SetExtended(true)
+	parser := NewParser(tokenizer.Tokenize(), "Test")
+	parser.ctx.Extended = true
+	parser.ctx.AddVarSymbol("i", "int")
+	parser.ctx.AddVarSymbol("x", "int")
+	return parser
+}
+
+func TestExtendedStatementToCode(t *testing.T) {
+	cases := []struct {
+		desc   string
+		source string
+		want   []string
+	}{
+		{
+			desc:   "for文は初期化してから条件を判定し、本体の後で更新する",
+			source: "for (i = 0; i < 3; i = i + 1) { let x = x + i; }",
+			want: []string{
+				"push constant 0",
+				"pop local 0",
+				"label FOR_START_ID_1",
+				"push local 0",
+				"push constant 3",
+				"lt",
+				"not",
+				"if-goto FOR_END_ID_1",
+				"push local 1",
+				"push local 0",
+				"add",
+				"pop local 1",
+				"label FOR_STEP_ID_1",
+				"push local 0",
+				"push constant 1",
+				"add",
+				"pop local 0",
+				"goto FOR_START_ID_1",
+				"label FOR_END_ID_1",
+			},
+		},
+		{
+			desc:   "省略したfor文は無限ループになる",
+			source: "for (;;) { break; }",
+			want: []string{
+				"label FOR_START_ID_1",
+				"goto FOR_END_ID_1",
+				"label FOR_STEP_ID_1",
+				"goto FOR_START_ID_1",
+				"label FOR_END_ID_1",
+			},
+		},
+		{
+			desc:   "for文のcontinueは更新へ、breakはループの外へジャンプする",
+			source: "for (; x; ) { if (i) { continue; } break; }",
+			want: []string{
+				"label FOR_START_ID_1",
+				"push local 1",
+				"not",
+				"if-goto FOR_END_ID_1",
+				"push local 0",
+				"not",
+				"if-goto ELSE_START_ID_2",
+				"goto FOR_STEP_ID_1",
+				"goto IF_END_ID_2",
+				"label ELSE_START_ID_2",
+				"label IF_END_ID_2",
+				"goto FOR_END_ID_1",
+				"label FOR_STEP_ID_1",
+				"goto FOR_START_ID_1",
+				"label FOR_END_ID_1",
+			},
+		},
+		{
+			desc:   "入れ子のループでは一番内側のループに対してジャンプする",
+			source: "while (x) { while (i) { break; } continue; }",
+			want: []string{
+				"label WHILE_START_ID_1",
+				"push local 1",
+				"not",
+				"if-goto WHILE_END_ID_1",
+				"label WHILE_START_ID_2",
+				"push local 0",
+				"not",
+				"if-goto WHILE_END_ID_2",
+				"goto WHILE_END_ID_2",
+				"goto WHILE_START_ID_2",
+				"label WHILE_END_ID_2",
+				"goto WHILE_START_ID_1",
+				"goto WHILE_START_ID_1",
+				"label WHILE_END_ID_1",
+			},
+		},
+		{
+			desc:   "else ifはelse句の中のif文と同じコードになる",
+			source: "if (x) { let i = 1; } else if (i) { let i = 2; } else { let i = 3; }",
+			want: []string{
+				"push local 1",
+				"not",
+				"if-goto ELSE_START_ID_1",
+				"push constant 1",
+				"pop local 0",
+				"goto IF_END_ID_1",
+				"label ELSE_START_ID_1",
+				"push local 0",
+				"not",
+				"if-goto ELSE_START_ID_2",
+				"push constant 2",
+				"pop local 0",
+				"goto IF_END_ID_2",
+				"label ELSE_START_ID_2",
+				"push constant 3",
+				"pop local 0",
+				"label IF_END_ID_2",
+				"label IF_END_ID_1",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			// else句の有無を調べるために、if文の後ろにトークンが必要
+			parser := newExtendedTestParser(tc.source + " }")
+			statement, err := parser.parseStatement()
+			if err != nil {
+				t.Fatalf("failed parseStatement: %+v", err)
+			}
+
+			got := statement.ToCode(parser.ctx)
+			if diff := cmp.Diff(got, tc.want); diff != "" {
+				t.Errorf("failed ToCode: diff (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestExtendedStatementToXML(t *testing.T) {
+	cases := []struct {
+		desc   string
+		source string
+		want   []string
+	}{
+		{
+			desc:   "for文",
+			source: "for (i = 0; ; ) { break; }",
+			want: []string{
+				"<forStatement>",
+				"<keyword> for </keyword>",
+				"<symbol> ( </symbol>",
+				"<assignment>",
+				"<identifier> i </identifier>",
+				"<symbol> = </symbol>",
+				"<expression>",
+				"<term>",
+				"<integerConstant> 0 </integerConstant>",
+				"</term>",
+				"</expression>",
+				"</assignment>",
+				"<symbol> ; </symbol>",
+				"<symbol> ; </symbol>",
+				"<symbol> ) </symbol>",
+				"<symbol> { </symbol>",
+				"<statements>",
+				"<breakStatement>",
+				"<keyword> break </keyword>",
+				"<symbol> ; </symbol>",
+				"</breakStatement>",
+				"</statements>",
+				"<symbol> } </symbol>",
+				"</forStatement>",
+			},
+		},
+		{
+			desc:   "else ifは波カッコを挟まずにif文を続ける",
+			source: "if (x) { } else if (i) { }",
+			want: []string{
+				"<ifStatement>",
+				"<keyword> if </keyword>",
+				"<symbol> ( </symbol>",
+				"<expression>",
+				"<term>",
+				"<identifier> x </identifier>",
+				"</term>",
+				"</expression>",
+				"<symbol> ) </symbol>",
+				"<symbol> { </symbol>",
+				"<statements>",
+				"</statements>",
+				"<symbol> } </symbol>",
+				"<keyword> else </keyword>",
+				"<ifStatement>",
+				"<keyword> if </keyword>",
+				"<symbol> ( </symbol>",
+				"<expression>",
+				"<term>",
+				"<identifier> i </identifier>",
+				"</term>",
+				"</expression>",
+				"<symbol> ) </symbol>",
+				"<symbol> { </symbol>",
+				"<statements>",
+				"</statements>",
+				"<symbol> } </symbol>",
+				"</ifStatement>",
+				"</ifStatement>",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			parser := newExtendedTestParser(tc.source + " }")
+			statement, err := parser.parseStatement()
+			if err != nil {
+				t.Fatalf("failed parseStatement: %+v", err)
+			}
+
+			if diff := cmp.Diff(statement.ToXML(), tc.want); diff != "" {
+				t.Errorf("failed ToXML: diff (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestExtendedStatementToSource(t *testing.T) {
+	source := strings.Join([]string{
+		"for (i = 0; i < 3; i = i + 1) {",
+		"    if (x) {",
+		"        continue;",
+		"    } else if (i) {",
+		"        break;",
+		"    } else {",
+		"        let x = 1;",
+		"    }",
+		"}",
+	}, "\n")
+
+	parser := newExtendedTestParser(strings.Replace(source, "\n", " ", -1))
+	statement, err := parser.parseStatement()
+	if err != nil {
+		t.Fatalf("failed parseStatement: %+v", err)
+	}
+
+	printer := NewSourcePrinter(NewSourceOptions(), nil, nil)
+	got := strings.Join(statement.ToSource(printer), "\n")
+	if diff := cmp.Diff(got, source); diff != "" {
+		t.Errorf("failed ToSource: diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestExtendedStatementError(t *testing.T) {
+	cases := []struct {
+		desc     string
+		source   string
+		extended bool
+		want     string
+	}{
+		{
+			desc:     "ループの外のbreak",
+			source:   "if (x) { break; }",
+			extended: true,
+			want:     "error parseLoopJumpStatement: outside loop: got = &Token{Value: 'break', TokenType: keyword}",
+		},
+		{
+			desc:     "拡張文法でなければfor文は書けない",
+			source:   "for (;;) { }",
+			extended: false,
+			want:     "Invalid Statement: got = &Token{Value: 'for', TokenType: identifier}",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			parser := newExtendedTestParser(tc.source + " }")
+			if !tc.extended {
+				tokens := token.NewTokenizer([]string{tc.source + " }"}).Tokenize()
+				parser = NewParser(tokens, "Test")
+			}
+
+			_, err := parser.parseStatement()
+			if err == nil {
+				t.Fatal("failed parseStatement: expected error")
+			}
+			if diff := cmp.Diff(err.Error(), tc.want); diff != "" {
+				t.Errorf("failed parseStatement: diff (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
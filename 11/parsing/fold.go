@@ -0,0 +1,188 @@
+package parsing
+
+import (
+	"../symbol"
+	"fmt"
+)
+
+// Hackの整数は16ビットの2の補数
+const (
+	MinInteger = -32768
+	MaxInteger = 32767
+)
+
+// コンパイル時の警告
+// 警告があってもコンパイルは続行する
+type Warning struct {
+	Span
+	Message string
+}
+
+func (c *Context) Warn(node interface{}, message string) {
+	c.Warnings = append(c.Warnings, &Warning{Span: *c.FindSpan(node), Message: message})
+}
+
+// リテラルだけからなる式をコンパイル時に計算する
+// Jackの二項演算子に優先順位はないので、左から順番に計算する
+// 実行時と同じ結果になるように、計算結果は16ビットに丸めてオーバーフローを警告する
+type folder struct {
+	ctx        *Context
+	node       interface{} // 警告を出す位置
+	operations int         // 計算した演算子の数
+	warnings   []string
+}
+
+func newFolder(ctx *Context, node interface{}) *folder {
+	return &folder{ctx: ctx, node: node, warnings: []string{}}
+}
+
+// 計算中に見つかった警告をコンテキストに登録する
+func (f *folder) commit() {
+	for _, message := range f.warnings {
+		f.ctx.Warn(f.node, message)
+	}
+}
+
+func (f *folder) term(term Term) (int, bool) {
+	switch t := term.(type) {
+	case *IntegerConstant:
+		value, err := t.IntValue()
+		return value, err == nil
+	case *CharConstant:
+		value, err := t.IntValue()
+		return value, err == nil
+	case *ClassConstant:
+		return t.Value, true
+	case *VarName:
+		// 計算できるのは定数だけで、変数は実行時まで値が分からない
+		item, err := f.ctx.FindSymbolItem(t.Value)
+		if err != nil || item.ScopeKind != symbol.ConstScope {
+			return 0, false
+		}
+		return item.ScopeIndex, true
+	case *TrueKeywordConstant:
+		return -1, true
+	case *FalseKeywordConstant:
+		return 0, true
+	case *GroupingExpression:
+		// 計算できなかった部分式は、そのToCodeで改めて計算するので警告を取り消しておく
+		mark := len(f.warnings)
+		value, ok := f.expression(t.Expression)
+		if !ok {
+			f.warnings = f.warnings[:mark]
+		}
+		return value, ok
+	case *UnaryOpTerm:
+		mark := len(f.warnings)
+		value, ok := f.term(t.Term)
+		if !ok {
+			f.warnings = f.warnings[:mark]
+			return 0, false
+		}
+		return f.unary(t.UnaryOp, value), true
+	}
+	return 0, false
+}
+
+func (f *folder) expression(expression *Expression) (int, bool) {
+	value, length, ok := f.prefix(expression)
+	return value, ok && length == expression.binaryOpTermsLength()
+}
+
+// 式の先頭から計算できるところまで計算して、計算できた二項演算の数を返す
+func (f *folder) prefix(expression *Expression) (int, int, bool) {
+	value, ok := f.term(expression.Term)
+	if !ok {
+		return 0, 0, false
+	}
+
+	length := 0
+	if expression.BinaryOpTerms != nil {
+		for _, item := range expression.BinaryOpTerms.Items {
+			right, ok := f.term(item.Term)
+			if !ok {
+				break
+			}
+			result, ok := f.binary(item.BinaryOp, value, right)
+			if !ok {
+				break
+			}
+			value = result
+			length++
+		}
+	}
+	return value, length, true
+}
+
+func (f *folder) unary(op UnaryOp, value int) int {
+	f.operations++
+	switch op.OpType() {
+	case UnaryMinusType:
+		return f.wrap(-value, fmt.Sprintf("-(%d)", value))
+	default:
+		return ^value
+	}
+}
+
+func (f *folder) binary(op BinaryOp, left int, right int) (int, bool) {
+	operation := fmt.Sprintf("%d %s %d", left, op.ToSource(), right)
+	switch op.OpType() {
+	case PlusType:
+		return f.wrap(left+right, operation), f.count()
+	case MinusType:
+		return f.wrap(left-right, operation), f.count()
+	case AsteriskType:
+		return f.wrap(left*right, operation), f.count()
+	case SlashType:
+		// ゼロ除算は実行時にMath.divideがエラーにするので、計算せずにそのまま残す
+		if right == 0 {
+			f.warnings = append(f.warnings, fmt.Sprintf("division by zero in constant expression: %s", operation))
+			return 0, false
+		}
+		return f.wrap(left/right, operation), f.count()
+	case AmpersandType:
+		return left & right, f.count()
+	case VerticalLineType:
+		return left | right, f.count()
+	case LessThanType:
+		return boolValue(left < right), f.count()
+	case GreaterThanType:
+		return boolValue(left > right), f.count()
+	case EqualsType:
+		return boolValue(left == right), f.count()
+	}
+	return 0, false
+}
+
+func (f *folder) count() bool {
+	f.operations++
+	return true
+}
+
+// 16ビットに収まらない場合は、実行時と同じく下位16ビットに丸めて警告する
+func (f *folder) wrap(value int, operation string) int {
+	wrapped := int(int16(value))
+	if wrapped != value {
+		f.warnings = append(f.warnings, fmt.Sprintf("integer overflow in constant expression: %s = %d wraps to %d", operation, value, wrapped))
+	}
+	return wrapped
+}
+
+func boolValue(value bool) int {
+	if value {
+		return -1
+	}
+	return 0
+}
+
+// push constantは0..32767しか扱えないので、負の数はnegかnotで作る
+func ConstantCode(value int) []string {
+	switch {
+	case value >= 0:
+		return []string{fmt.Sprintf("push constant %d", value)}
+	case value == MinInteger:
+		return []string{fmt.Sprintf("push constant %d", ^value), "not"}
+	default:
+		return []string{fmt.Sprintf("push constant %d", -value), "neg"}
+	}
+}
@@ -0,0 +1,143 @@
+package parsing
+
+import (
+	"../token"
+	"github.com/google/go-cmp/cmp"
+	"testing"
+)
+
+func TestExpressionToCodeWithFoldConstants(t *testing.T) {
+	cases := []struct {
+		desc     string
+		source   string
+		want     []string
+		warnings []string
+	}{
+		{
+			desc:   "左から順番に計算する: 2 * 8 + 1",
+			source: "2 * 8 + 1",
+			want: []string{
+				"push constant 17",
+			},
+			warnings: []string{},
+		},
+		{
+			desc:   "カッコとマイナス: -(3)",
+			source: "-(3)",
+			want: []string{
+				"push constant 3",
+				"neg",
+			},
+			warnings: []string{},
+		},
+		{
+			desc:   "カッコの中だけ計算する: 1 + (2 * 3)",
+			source: "1 + (2 * 3)",
+			want: []string{
+				"push constant 7",
+			},
+			warnings: []string{},
+		},
+		{
+			desc:   "先頭のリテラルだけ計算する: 2 * 8 + x",
+			source: "2 * 8 + x",
+			want: []string{
+				"push constant 16",
+				"push local 0",
+				"add",
+			},
+			warnings: []string{},
+		},
+		{
+			desc:   "変数の後ろのカッコの中を計算する: x * (4 - 1)",
+			source: "x * (4 - 1)",
+			want: []string{
+				"push local 0",
+				"push constant 3",
+				"call Math.multiply 2",
+			},
+			warnings: []string{},
+		},
+		{
+			desc:   "比較演算とブール値: ~(1 < 2) | true",
+			source: "~(1 < 2) | true",
+			want: []string{
+				"push constant 1",
+				"neg",
+			},
+			warnings: []string{},
+		},
+		{
+			desc:   "リテラルがひとつだけなら何もしない",
+			source: "true",
+			want: []string{
+				"push constant 1",
+				"neg",
+			},
+			warnings: []string{},
+		},
+		{
+			desc:   "オーバーフローは16ビットに丸めて警告する",
+			source: "32767 + 1",
+			want: []string{
+				"push constant 32767",
+				"not",
+			},
+			warnings: []string{
+				"integer overflow in constant expression: 32767 + 1 = 32768 wraps to -32768",
+			},
+		},
+		{
+			desc:   "ゼロ除算は計算せずに警告する",
+			source: "4 / 0",
+			want: []string{
+				"push constant 4",
+				"push constant 0",
+				"call Math.divide 2",
+			},
+			warnings: []string{
+				"division by zero in constant expression: 4 / 0",
+			},
+		},
+		{
+			desc:   "カッコの中のオーバーフローは一度だけ警告する",
+			source: "x + (200 * 200)",
+			want: []string{
+				"push local 0",
+				"push constant 25536",
+				"neg",
+				"add",
+			},
+			warnings: []string{
+				"integer overflow in constant expression: 200 * 200 = 40000 wraps to -25536",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			tokens := token.NewTokenizer([]string{tc.source + ";"}).Tokenize()
+			parser := NewParser(tokens, "Test")
+			parser.ctx.FoldConstants = true
+			parser.ctx.AddVarSymbol("x", "int")
+
+			expression, err := parser.parseExpression()
+			if err != nil {
+				t.Fatalf("failed parseExpression: %+v", err)
+			}
+
+			got := expression.ToCode(parser.ctx)
+			if diff := cmp.Diff(got, tc.want); diff != "" {
+				t.Errorf("failed ToCode: diff (-got +want):\n%s", diff)
+			}
+
+			warnings := []string{}
+			for _, warning := range parser.ctx.Warnings {
+				warnings = append(warnings, warning.Message)
+			}
+			if diff := cmp.Diff(warnings, tc.warnings); diff != "" {
+				t.Errorf("failed Warnings: diff (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
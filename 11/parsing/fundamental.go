@@ -5,6 +5,7 @@ import (
 	"../token"
 	"fmt"
 	"github.com/pkg/errors"
+	"strings"
 )
 
 type VarType struct {
@@ -80,6 +81,48 @@ func (v *VarNames) VarNameLength() int {
 	return 1 + len(v.CommaAndVarNames)
 }
 
+func (v *VarNames) VarNameItems() []*VarName {
+	result := []*VarName{}
+	if v.First == nil {
+		return result
+	}
+
+	result = append(result, v.First)
+	for _, commaAndVarName := range v.CommaAndVarNames {
+		result = append(result, commaAndVarName.VarName)
+	}
+	return result
+}
+
+func (v *VarNames) Names() []string {
+	result := []string{}
+	if v.First == nil {
+		return result
+	}
+
+	result = append(result, v.First.Value)
+	for _, commaAndVarName := range v.CommaAndVarNames {
+		result = append(result, commaAndVarName.VarName.Value)
+	}
+	return result
+}
+
+// var int x, y;
+// SplitDeclarationsが有効な場合は「var int x;」「var int y;」に分割する
+func (v *VarNames) ToSourceDeclarations(p *SourcePrinter, keyword string, varType string) []string {
+	names := v.Names()
+	if p.SplitDeclarations {
+		result := []string{}
+		for _, name := range names {
+			result = append(result, fmt.Sprintf("%s %s %s%s", keyword, varType, name, ConstSemicolon.Value))
+		}
+		return result
+	}
+
+	joined := strings.Join(names, ConstComma.Value+" ")
+	return []string{fmt.Sprintf("%s %s %s%s", keyword, varType, joined, ConstSemicolon.Value)}
+}
+
 func (v *VarNames) ToXML() []string {
 	result := []string{}
 	result = append(result, v.First.ToXML()...)
@@ -162,14 +205,23 @@ func (v *VarName) TermType() TermType {
 	return TermVarName
 }
 
+func (v *VarName) ToSource() string {
+	return v.Value
+}
+
 func (v *VarName) ToXML() []string {
 	return []string{v.Token.ToXML()}
 }
 
-func (v *VarName) ToCode() []string {
-	findSymbol, err := symbol.GlobalSymbolTables.Find(v.Value)
+func (v *VarName) ToCode(ctx *Context) []string {
+	// 定数は負の数もあるので、ConstantCodeで値を積む
+	if item, err := ctx.FindSymbolItem(v.Value); err == nil && item.ScopeKind == symbol.ConstScope {
+		return ConstantCode(item.ScopeIndex)
+	}
+
+	findSymbol, err := ctx.Find(v.Value)
 	if err != nil {
-		message := fmt.Sprintf("error GlobalSymbolTables.Find: %v", err)
+		message := fmt.Sprintf("error SymbolTables.Find: %v", err)
 		fmt.Println(message)
 		return []string{message}
 	}
@@ -210,6 +262,10 @@ func NewSymbolByValue(value string) *Symbol {
 	return NewSymbol(token.NewToken(value, token.TokenSymbol))
 }
 
+func (s *Symbol) ToSource() string {
+	return s.Value
+}
+
 func (s *Symbol) IsCheck(token *token.Token) bool {
 	return s.Check(token) == nil
 }
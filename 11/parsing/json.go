@@ -0,0 +1,319 @@
+package parsing
+
+import (
+	"../token"
+	"strconv"
+)
+
+// ASTをJSONに変換するためのノード
+// XMLと違って、ソースファイル上の位置、変数が解決されたシンボル、サブルーチン呼び出しの呼び出し先も出力する
+type JSONNode struct {
+	Kind     string          `json:"kind"`
+	Name     string          `json:"name,omitempty"`  // 宣言や参照の識別子
+	Value    string          `json:"value,omitempty"` // リテラル、演算子、キーワード
+	Type     string          `json:"type,omitempty"`  // 宣言の型、サブルーチンの戻り値の型
+	Start    *JSONPosition   `json:"start,omitempty"`
+	End      *JSONPosition   `json:"end,omitempty"`
+	Symbol   *JSONSymbol     `json:"symbol,omitempty"`
+	Target   *JSONCallTarget `json:"target,omitempty"`
+	Children []*JSONNode     `json:"children,omitempty"`
+}
+
+type JSONPosition struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// 変数が解決されたシンボル
+// 定数の場合、indexは定数の値になる
+type JSONSymbol struct {
+	Kind  string `json:"kind"`
+	Type  string `json:"type"`
+	Index int    `json:"index"`
+}
+
+type JSONCallTarget struct {
+	ClassName      string `json:"className"`
+	SubroutineName string `json:"subroutineName"`
+	Method         bool   `json:"method"`
+}
+
+// パースしたクラスをJSONのノードに変換する
+// 変数の解決には、パース時にサブルーチンごとに残したシンボルテーブルを使う
+func ExportJSON(class *Class, ctx *Context) *JSONNode {
+	exporter := &jsonExporter{ctx: ctx, symbols: ctx.SymbolTablesOf(nil)}
+	return exporter.class(class)
+}
+
+type jsonExporter struct {
+	ctx     *Context
+	symbols SymbolFinder // 今変換しているサブルーチンのシンボルテーブル
+}
+
+// 位置情報はASTノードに登録された範囲を使う
+func (e *jsonExporter) node(kind string, node interface{}) *JSONNode {
+	result := &JSONNode{Kind: kind, Children: []*JSONNode{}}
+	span := e.ctx.FindSpan(node)
+	if span.Start.Line > 0 {
+		result.Start = &JSONPosition{Line: span.Start.Line, Column: span.Start.Column}
+	}
+	if span.End.Line > 0 {
+		result.End = &JSONPosition{Line: span.End.Line, Column: span.End.Column}
+	}
+	return result
+}
+
+// 識別子やリテラルのように、1つのトークンからなるノードは位置をトークンから取る
+func (e *jsonExporter) leaf(kind string, t *token.Token) *JSONNode {
+	result := &JSONNode{Kind: kind, Value: t.Value, Children: []*JSONNode{}}
+	if t.Line > 0 {
+		result.Start = &JSONPosition{Line: t.Line, Column: t.Column}
+	}
+	return result
+}
+
+func (e *jsonExporter) add(parent *JSONNode, children ...*JSONNode) {
+	parent.Children = append(parent.Children, children...)
+}
+
+// 変数の宣言と参照のノードに、解決したシンボルを付ける
+func (e *jsonExporter) varName(kind string, varName *VarName) *JSONNode {
+	result := e.leaf(kind, varName.Token)
+	result.Name = varName.Value
+	result.Value = ""
+	result.Symbol = e.symbol(varName.Value)
+	return result
+}
+
+func (e *jsonExporter) symbol(name string) *JSONSymbol {
+	item, err := e.symbols.FindSymbolItem(name)
+	if err != nil {
+		return nil
+	}
+	return &JSONSymbol{Kind: item.ScopeKind.String(), Type: item.SymbolType.Value, Index: item.ScopeIndex}
+}
+
+func (e *jsonExporter) class(class *Class) *JSONNode {
+	result := e.node("class", class)
+	result.Name = class.ClassName.Value
+
+	if class.ConstDecs != nil {
+		for _, item := range class.ConstDecs.Items {
+			switch d := item.(type) {
+			case *ConstDec:
+				e.add(result, e.constDec(d))
+			case *EnumDec:
+				e.add(result, e.enumDec(d))
+			}
+		}
+	}
+	for _, classVarDec := range class.ClassVarDecs.Items {
+		e.add(result, e.declaration("classVarDec", classVarDec, classVarDec.Keyword.Value, classVarDec.VarType.Value, classVarDec.VarNameItems()))
+	}
+	for _, subroutineDec := range class.SubroutineDecs.Items {
+		e.add(result, e.subroutineDec(subroutineDec))
+	}
+	return result
+}
+
+func (e *jsonExporter) constDec(constDec *ConstDec) *JSONNode {
+	result := e.node("constDec", constDec)
+	result.Name = constDec.VarName.Value
+	result.Type = constDec.VarType.Value
+	result.Value = strconv.Itoa(constDec.Value)
+	result.Symbol = e.symbol(constDec.VarName.Value)
+	e.add(result, e.expression(constDec.Expression))
+	return result
+}
+
+func (e *jsonExporter) enumDec(enumDec *EnumDec) *JSONNode {
+	result := e.node("enumDec", enumDec)
+	if enumDec.EnumName != nil {
+		result.Name = enumDec.EnumName.Value
+	}
+	for _, item := range enumDec.Items {
+		child := e.varName("enumItem", item.VarName)
+		child.Value = strconv.Itoa(item.Value)
+		if item.Expression != nil {
+			e.add(child, e.expression(item.Expression))
+		}
+		e.add(result, child)
+	}
+	return result
+}
+
+// static int x, y; や var int x, y; のような宣言
+func (e *jsonExporter) declaration(kind string, node interface{}, keyword string, varType string, varNames []*VarName) *JSONNode {
+	result := e.node(kind, node)
+	result.Value = keyword
+	result.Type = varType
+	for _, varName := range varNames {
+		e.add(result, e.varName("varName", varName))
+	}
+	return result
+}
+
+func (e *jsonExporter) subroutineDec(subroutineDec *SubroutineDec) *JSONNode {
+	e.symbols = e.ctx.SymbolTablesOf(subroutineDec)
+	defer func() { e.symbols = e.ctx.SymbolTablesOf(nil) }()
+
+	result := e.node("subroutineDec", subroutineDec)
+	result.Name = subroutineDec.SubroutineName.Value
+	result.Value = subroutineDec.Subroutine.Value
+	result.Type = subroutineDec.SubroutineType.Value
+
+	for _, parameter := range subroutineDec.ParameterList.ParameterItems() {
+		child := e.varName("parameter", parameter.VarName)
+		child.Type = parameter.VarType.Value
+		e.add(result, child)
+	}
+	for _, varDec := range subroutineDec.SubroutineBody.VarDecs.Items {
+		e.add(result, e.declaration("varDec", varDec, varDec.Keyword.Value, varDec.VarType.Value, varDec.VarNameItems()))
+	}
+	e.add(result, e.statements(subroutineDec.SubroutineBody.Statements))
+	return result
+}
+
+func (e *jsonExporter) statements(statements *Statements) *JSONNode {
+	result := e.node("statements", statements)
+	for _, item := range statements.Items {
+		e.add(result, e.statement(item))
+	}
+	return result
+}
+
+func (e *jsonExporter) statement(statement Statement) *JSONNode {
+	switch s := statement.(type) {
+	case *LetStatement:
+		result := e.node("letStatement", s)
+		e.add(result, e.assignTarget(s.VarName, s.Array), e.expression(s.Expression))
+		return result
+	case *IfStatement:
+		result := e.node("ifStatement", s)
+		e.add(result, e.expression(s.Expression), e.statements(s.Statements))
+		if s.ElseBlock != nil {
+			e.add(result, e.statements(s.ElseBlock.Statements))
+		}
+		return result
+	case *WhileStatement:
+		result := e.node("whileStatement", s)
+		e.add(result, e.expression(s.Expression), e.statements(s.Statements))
+		return result
+	case *ForStatement:
+		// 省略できる部分があるので、初期化と更新はvalueで区別する
+		result := e.node("forStatement", s)
+		if s.Init != nil {
+			e.add(result, e.assignment("init", s.Init))
+		}
+		if s.Condition != nil {
+			e.add(result, e.expression(s.Condition))
+		}
+		if s.Step != nil {
+			e.add(result, e.assignment("step", s.Step))
+		}
+		e.add(result, e.statements(s.Statements))
+		return result
+	case *DoStatement:
+		result := e.node("doStatement", s)
+		e.add(result, e.subroutineCall(s.SubroutineCall))
+		return result
+	case *ReturnStatement:
+		result := e.node("returnStatement", s)
+		if s.Expression != nil {
+			e.add(result, e.expression(s.Expression))
+		}
+		return result
+	case *BreakStatement:
+		return e.node("breakStatement", s)
+	case *ContinueStatement:
+		return e.node("continueStatement", s)
+	}
+	return e.node("unknownStatement", statement)
+}
+
+func (e *jsonExporter) assignment(value string, assignment *Assignment) *JSONNode {
+	result := e.node("assignment", assignment)
+	result.Value = value
+	e.add(result, e.assignTarget(assignment.VarName, assignment.Array), e.expression(assignment.Expression))
+	return result
+}
+
+func (e *jsonExporter) assignTarget(varName *VarName, array *Array) *JSONNode {
+	if array != nil {
+		return e.term(array)
+	}
+	return e.varName("varName", varName)
+}
+
+func (e *jsonExporter) expression(expression *Expression) *JSONNode {
+	result := e.node("expression", expression)
+	e.add(result, e.term(expression.Term))
+	if expression.BinaryOpTerms != nil {
+		for _, item := range expression.BinaryOpTerms.Items {
+			op := &JSONNode{Kind: "binaryOp", Value: item.BinaryOp.ToSource(), Children: []*JSONNode{}}
+			e.add(result, op, e.term(item.Term))
+		}
+	}
+	return result
+}
+
+func (e *jsonExporter) term(term Term) *JSONNode {
+	switch t := term.(type) {
+	case *IntegerConstant:
+		return e.leaf("integerConstant", t.Token)
+	case *StringConstant:
+		return e.leaf("stringConstant", t.Token)
+	case *CharConstant:
+		return e.leaf("charConstant", t.Token)
+	case *VarName:
+		return e.varName("varName", t)
+	case *ClassConstant:
+		result := e.leaf("classConstant", t.ClassName.Token)
+		result.Name = t.ToSource()
+		result.Value = strconv.Itoa(t.Value)
+		return result
+	case *Array:
+		result := e.varName("array", t.VarName)
+		e.add(result, e.expression(t.Expression))
+		return result
+	case *SubroutineCall:
+		return e.subroutineCall(t)
+	case *GroupingExpression:
+		result := e.node("groupingExpression", t)
+		if t.Implicit {
+			result.Value = "implicit"
+		}
+		e.add(result, e.expression(t.Expression))
+		return result
+	case *UnaryOpTerm:
+		result := e.node("unaryOpTerm", t)
+		result.Value = t.UnaryOp.ToSource()
+		e.add(result, e.term(t.Term))
+		return result
+	}
+
+	if term.TermType() == TermKeywordConstant {
+		return &JSONNode{Kind: "keywordConstant", Value: term.ToSource(), Children: []*JSONNode{}}
+	}
+	return &JSONNode{Kind: "unknownTerm", Value: term.ToSource(), Children: []*JSONNode{}}
+}
+
+// オブジェクト経由の呼び出しでは、レシーバーの変数も解決する
+func (e *jsonExporter) subroutineCall(subroutineCall *SubroutineCall) *JSONNode {
+	callName := subroutineCall.SubroutineCallName
+	result := e.leaf("subroutineCall", callName.SubroutineName.Token)
+	if callName.CallerName != nil {
+		result = e.leaf("subroutineCall", callName.CallerName.Token)
+		result.Symbol = e.symbol(callName.CallerName.Value)
+	}
+	result.Value = ""
+	result.Name = callName.ToSource()
+
+	target := callName.CallTarget(e.symbols)
+	result.Target = &JSONCallTarget{ClassName: target.ClassName, SubroutineName: target.SubroutineName, Method: target.Method}
+
+	for _, expression := range subroutineCall.ExpressionList.ExpressionItems() {
+		e.add(result, e.expression(expression))
+	}
+	return result
+}
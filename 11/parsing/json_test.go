@@ -0,0 +1,159 @@
+package parsing
+
+import (
+	"../token"
+	"github.com/google/go-cmp/cmp"
+	"testing"
+)
+
+func TestExportJSON(t *testing.T) {
+	lines := []string{
+		"class Test {",
+		"field Test next;",
+		"method void run(int n) {",
+		"var int i;",
+		"let i = n + 1;",
+		"do next.run(i);",
+		"do Output.printInt(i);",
+		"return;",
+		"}",
+		"}",
+	}
+	positions := []token.Position{}
+	for i := range lines {
+		positions = append(positions, token.NewPosition(i+1, 1))
+	}
+	tokens := token.NewTokenizerWithPositions(lines, positions).Tokenize()
+	parser := NewParser(tokens, "Test")
+	class, err := parser.Parse()
+	if err != nil {
+		t.Fatalf("failed Parse: %+v", err)
+	}
+
+	position := func(line int, column int) *JSONPosition {
+		return &JSONPosition{Line: line, Column: column}
+	}
+	local := &JSONSymbol{Kind: "local", Type: "int", Index: 0}
+	argument := &JSONSymbol{Kind: "argument", Type: "int", Index: 0}
+	field := &JSONSymbol{Kind: "field", Type: "Test", Index: 0}
+	varName := func(name string, line int, column int, symbol *JSONSymbol) *JSONNode {
+		return &JSONNode{Kind: "varName", Name: name, Start: position(line, column), Symbol: symbol, Children: []*JSONNode{}}
+	}
+	expression := func(line int, column int, children ...*JSONNode) *JSONNode {
+		return &JSONNode{Kind: "expression", Start: position(line, column), Children: children}
+	}
+
+	want := &JSONNode{
+		Kind:  "class",
+		Name:  "Test",
+		Start: position(1, 1),
+		End:   position(10, 1),
+		Children: []*JSONNode{
+			{
+				Kind:     "classVarDec",
+				Value:    "field",
+				Type:     "Test",
+				Start:    position(2, 1),
+				End:      position(2, 16),
+				Children: []*JSONNode{varName("next", 2, 12, field)},
+			},
+			{
+				Kind:  "subroutineDec",
+				Name:  "run",
+				Value: "method",
+				Type:  "void",
+				Start: position(3, 1),
+				End:   position(9, 1),
+				Children: []*JSONNode{
+					{Kind: "parameter", Name: "n", Type: "int", Start: position(3, 21), Symbol: argument, Children: []*JSONNode{}},
+					{
+						Kind:     "varDec",
+						Value:    "var",
+						Type:     "int",
+						Start:    position(4, 1),
+						End:      position(4, 10),
+						Children: []*JSONNode{varName("i", 4, 9, local)},
+					},
+					{
+						Kind:  "statements",
+						Start: position(3, 24),
+						End:   position(9, 1),
+						Children: []*JSONNode{
+							{
+								Kind:  "letStatement",
+								Start: position(5, 1),
+								End:   position(5, 14),
+								Children: []*JSONNode{
+									varName("i", 5, 5, local),
+									expression(5, 9,
+										varName("n", 5, 9, argument),
+										&JSONNode{Kind: "binaryOp", Value: "+", Children: []*JSONNode{}},
+										&JSONNode{Kind: "integerConstant", Value: "1", Start: position(5, 13), Children: []*JSONNode{}},
+									),
+								},
+							},
+							{
+								Kind:  "doStatement",
+								Start: position(6, 1),
+								End:   position(6, 15),
+								Children: []*JSONNode{
+									{
+										Kind:     "subroutineCall",
+										Name:     "next.run",
+										Start:    position(6, 4),
+										Symbol:   field,
+										Target:   &JSONCallTarget{ClassName: "Test", SubroutineName: "run", Method: true},
+										Children: []*JSONNode{expression(6, 13, varName("i", 6, 13, local))},
+									},
+								},
+							},
+							{
+								Kind:  "doStatement",
+								Start: position(7, 1),
+								End:   position(7, 22),
+								Children: []*JSONNode{
+									{
+										Kind:     "subroutineCall",
+										Name:     "Output.printInt",
+										Start:    position(7, 4),
+										Target:   &JSONCallTarget{ClassName: "Output", SubroutineName: "printInt", Method: false},
+										Children: []*JSONNode{expression(7, 20, varName("i", 7, 20, local))},
+									},
+								},
+							},
+							{
+								Kind:     "returnStatement",
+								Start:    position(8, 1),
+								End:      position(8, 7),
+								Children: []*JSONNode{},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	got := ExportJSON(class, parser.ctx)
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("failed ExportJSON: diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestExportJSONEnum(t *testing.T) {
+	parser := newConstantTestParser("class Test { enum Color { RED, GREEN = 5 } }", nil)
+	class, err := parser.Parse()
+	if err != nil {
+		t.Fatalf("failed Parse: %+v", err)
+	}
+
+	enumDec := ExportJSON(class, parser.ctx).Children[0]
+	got := []string{enumDec.Kind, enumDec.Name}
+	for _, child := range enumDec.Children {
+		got = append(got, child.Name+"="+child.Value)
+	}
+	want := []string{"enumDec", "Color", "RED=0", "GREEN=5"}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("failed ExportJSON: diff (-got +want):\n%s", diff)
+	}
+}
@@ -0,0 +1,251 @@
+package parsing
+
+import (
+	"fmt"
+)
+
+// 最適化レベル
+// 0では最適化せず、1以上では次の変換をする
+//   - 2の累乗の定数による掛け算と割り算を、Math.multiplyとMath.divideを呼ばない加算とビット演算にする
+//   - if文とwhile文の条件が比較演算なら、notを挟まずに条件が成り立つ側へジャンプする
+//   - if文とwhile文の条件がリテラルなら、条件を計算せずにジャンプする
+//   - 実行されない分岐とreturn文などの後ろの文、使われないローカル変数を取り除く
+const (
+	OptimizeNone = iota
+	OptimizeSpeed
+)
+
+// 定数が2の累乗（1, 2, 4, ..., 16384）なら、その指数を返す
+func powerOfTwo(term Term) (int, bool) {
+	constant, ok := term.(*IntegerConstant)
+	if !ok {
+		return 0, false
+	}
+	value, err := constant.IntValue()
+	if err != nil || value <= 0 || value&(value-1) != 0 {
+		return 0, false
+	}
+	exponent := 0
+	for value > 1 {
+		value >>= 1
+		exponent++
+	}
+	return exponent, true
+}
+
+// スタックの先頭の値にtermを掛けるコードを返す
+// termが0か2の累乗の定数でなければfalseを返す
+func multiplyCode(term Term) ([]string, bool) {
+	if constant, ok := term.(*IntegerConstant); ok {
+		if value, err := constant.IntValue(); err == nil && value == 0 {
+			return []string{"pop temp 0", "push constant 0"}, true
+		}
+	}
+	exponent, ok := powerOfTwo(term)
+	if !ok {
+		return nil, false
+	}
+
+	// VMには複製の命令がないので、一度tempに退避して2回積んでから足す
+	result := []string{}
+	for i := 0; i < exponent; i++ {
+		result = append(result, "pop temp 0", "push temp 0", "push temp 0", "add")
+	}
+	return result, true
+}
+
+// スタックの先頭の値をtermで割るコードを返す
+// termが2の累乗の定数でなければfalseを返す
+//
+// VMにはシフト命令がないので、絶対値の上位のビットを1ビットずつ調べて、
+// 立っていれば右にずらした位置のビットを商に足す
+// Math.divideと同じく0に近い方に切り捨てるため、負の数は絶対値で割ってから符号を戻す
+// -32768は符号を反転しても-32768のままだが、ビット15だけが立っているので同じ方法で割り切れる
+func divideCode(ctx *Context, term Term) ([]string, bool) {
+	exponent, ok := powerOfTwo(term)
+	if !ok {
+		return nil, false
+	}
+	if exponent == 0 {
+		return []string{}, true
+	}
+
+	id := ctx.Generate()
+	absLabel := fmt.Sprintf("DIVIDE_ABS_%s", id)
+	endLabel := fmt.Sprintf("DIVIDE_END_%s", id)
+
+	// temp 0に被除数の絶対値、temp 1に被除数が負かどうかを入れる
+	result := []string{
+		"pop temp 0",
+		"push temp 0",
+		"push constant 0",
+		"lt",
+		"pop temp 1",
+		"push temp 1",
+		"not",
+		fmt.Sprintf("if-goto %s", absLabel),
+		"push temp 0",
+		"neg",
+		"pop temp 0",
+		fmt.Sprintf("label %s", absLabel),
+		"push constant 0",
+	}
+	for bit := exponent; bit < 16; bit++ {
+		setLabel := fmt.Sprintf("DIVIDE_BIT_%s_%d", id, bit)
+		nextLabel := fmt.Sprintf("DIVIDE_NEXT_%s_%d", id, bit)
+		result = append(result, "push temp 0")
+		result = append(result, ConstantCode(int(int16(uint16(1)<<uint(bit))))...)
+		result = append(result,
+			"and",
+			fmt.Sprintf("if-goto %s", setLabel),
+			fmt.Sprintf("goto %s", nextLabel),
+			fmt.Sprintf("label %s", setLabel),
+			fmt.Sprintf("push constant %d", 1<<uint(bit-exponent)),
+			"add",
+			fmt.Sprintf("label %s", nextLabel),
+		)
+	}
+	result = append(result,
+		"push temp 1",
+		"not",
+		fmt.Sprintf("if-goto %s", endLabel),
+		"neg",
+		fmt.Sprintf("label %s", endLabel),
+	)
+	return result, true
+}
+
+// 二項演算子の右辺が定数なら、掛け算と割り算をMath.multiplyとMath.divideを呼ばずに計算する
+func (b *BinaryOpTerm) optimizedCode(ctx *Context) ([]string, bool) {
+	switch b.BinaryOp.OpType() {
+	case AsteriskType:
+		return multiplyCode(b.Term)
+	case SlashType:
+		return divideCode(ctx, b.Term)
+	}
+	return nil, false
+}
+
+// 式の値が必ず-1か0になるなら、if-gotoで条件が成り立つ側へ直接ジャンプできる
+// 最後の演算が比較演算か、比較演算の結果を「~」で反転した式がこれにあたる
+func isComparison(e *Expression) bool {
+	if e.BinaryOpTerms != nil && len(e.BinaryOpTerms.Items) > 0 {
+		items := e.BinaryOpTerms.Items
+		switch items[len(items)-1].BinaryOp.OpType() {
+		case LessThanType, GreaterThanType, EqualsType:
+			return true
+		}
+		return false
+	}
+
+	switch t := e.Term.(type) {
+	case *GroupingExpression:
+		return isComparison(t.Expression)
+	case *UnaryOpTerm:
+		if t.UnaryOp.OpType() != UnaryTildeType {
+			return false
+		}
+		if grouping, ok := t.Term.(*GroupingExpression); ok {
+			return isComparison(grouping.Expression)
+		}
+	}
+	return false
+}
+
+// 条件がリテラルだけからなる式なら、その真偽を返す
+// 最適化しない場合と同じく、-1だけを真とみなす
+func literalCondition(ctx *Context, e *Expression) (bool, bool) {
+	value, ok := newFolder(ctx, e).expression(e)
+	if !ok {
+		return false, false
+	}
+	return int16(value) == -1, true
+}
+
+// 条件が偽のときにlabelへジャンプするコードを返す
+// 条件の最後がnotなら、反転のnotと打ち消し合うので両方とも省く
+func jumpIfFalseCode(ctx *Context, e *Expression, label string) []string {
+	result := e.ToCode(ctx)
+	if len(result) > 0 && result[len(result)-1] == "not" {
+		result = result[:len(result)-1]
+	} else {
+		result = append(result, "not")
+	}
+	return append(result, fmt.Sprintf("if-goto %s", label))
+}
+
+func (i *IfStatement) optimizedCode(ctx *Context) []string {
+	id := ctx.Generate()
+	elseLabel := fmt.Sprintf("ELSE_START_%s", id)
+	endLabel := fmt.Sprintf("IF_END_%s", id)
+
+	result := []string{}
+	if value, ok := literalCondition(ctx, i.Expression); ok {
+		// 条件を計算せず、偽ならelse句へジャンプするだけにする
+		if !value {
+			result = append(result, fmt.Sprintf("goto %s", elseLabel))
+		}
+	} else if isComparison(i.Expression) {
+		// 条件が成り立つならif句へジャンプし、成り立たなければそのままelse句を実行する
+		trueLabel := fmt.Sprintf("IF_TRUE_%s", id)
+		result = append(result, i.Expression.ToCode(ctx)...)
+		result = append(result, fmt.Sprintf("if-goto %s", trueLabel))
+		if i.ElseBlock != nil {
+			result = append(result, i.ElseBlock.Statements.ToCode(ctx)...)
+		}
+		result = append(result, fmt.Sprintf("goto %s", endLabel))
+		result = append(result, fmt.Sprintf("label %s", trueLabel))
+		result = append(result, i.Statements.ToCode(ctx)...)
+		result = append(result, fmt.Sprintf("label %s", endLabel))
+		return result
+	} else {
+		result = append(result, jumpIfFalseCode(ctx, i.Expression, elseLabel)...)
+	}
+
+	result = append(result, i.Statements.ToCode(ctx)...)
+	result = append(result, fmt.Sprintf("goto %s", endLabel))
+	result = append(result, fmt.Sprintf("label %s", elseLabel))
+	if i.ElseBlock != nil {
+		result = append(result, i.ElseBlock.Statements.ToCode(ctx)...)
+	}
+	result = append(result, fmt.Sprintf("label %s", endLabel))
+	return result
+}
+
+func (w *WhileStatement) optimizedCode(ctx *Context) []string {
+	id := ctx.Generate()
+	startLabel := fmt.Sprintf("WHILE_START_%s", id)
+	endLabel := fmt.Sprintf("WHILE_END_%s", id)
+
+	result := []string{}
+	if value, ok := literalCondition(ctx, w.Expression); ok {
+		// 条件を計算せず、偽ならすぐにループを抜ける
+		result = append(result, fmt.Sprintf("label %s", startLabel))
+		if !value {
+			result = append(result, fmt.Sprintf("goto %s", endLabel))
+		}
+	} else if isComparison(w.Expression) {
+		// 条件をループの末尾に置いて、条件が成り立つ間はループの先頭へジャンプする
+		conditionLabel := fmt.Sprintf("WHILE_CONDITION_%s", id)
+		result = append(result, fmt.Sprintf("goto %s", conditionLabel))
+		result = append(result, fmt.Sprintf("label %s", startLabel))
+		ctx.PushLoop(endLabel, conditionLabel)
+		result = append(result, w.Statements.ToCode(ctx)...)
+		ctx.PopLoop()
+		result = append(result, fmt.Sprintf("label %s", conditionLabel))
+		result = append(result, w.Expression.ToCode(ctx)...)
+		result = append(result, fmt.Sprintf("if-goto %s", startLabel))
+		result = append(result, fmt.Sprintf("label %s", endLabel))
+		return result
+	} else {
+		result = append(result, fmt.Sprintf("label %s", startLabel))
+		result = append(result, jumpIfFalseCode(ctx, w.Expression, endLabel)...)
+	}
+
+	ctx.PushLoop(endLabel, startLabel)
+	result = append(result, w.Statements.ToCode(ctx)...)
+	ctx.PopLoop()
+	result = append(result, fmt.Sprintf("goto %s", startLabel))
+	result = append(result, fmt.Sprintf("label %s", endLabel))
+	return result
+}
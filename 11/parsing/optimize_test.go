@@ -0,0 +1,255 @@
+package parsing
+
+import (
+	"../token"
+	"github.com/google/go-cmp/cmp"
+	"testing"
+)
+
+func newOptimizeTestParser(source string) *Parser {
+	tokens := token.NewTokenizer([]string{source}).Tokenize()
+	parser := NewParser(tokens, "Test")
+	parser.ctx.OptimizationLevel = OptimizeSpeed
+	parser.ctx.AddVarSymbol("x", "int")
+	parser.ctx.AddVarSymbol("y", "int")
+	return parser
+}
+
+func TestExpressionToCodeWithOptimization(t *testing.T) {
+	cases := []struct {
+		desc   string
+		source string
+		want   []string
+	}{
+		{
+			desc:   "2の累乗の掛け算は足し算にする: x * 4",
+			source: "x * 4",
+			want: []string{
+				"push local 0",
+				"pop temp 0",
+				"push temp 0",
+				"push temp 0",
+				"add",
+				"pop temp 0",
+				"push temp 0",
+				"push temp 0",
+				"add",
+			},
+		},
+		{
+			desc:   "左辺が定数でも足し算にする: 2 * x + 1",
+			source: "2 * x + 1",
+			want: []string{
+				"push local 0",
+				"pop temp 0",
+				"push temp 0",
+				"push temp 0",
+				"add",
+				"push constant 1",
+				"add",
+			},
+		},
+		{
+			desc:   "0を掛けると左辺を捨てて0にする: x * 0",
+			source: "x * 0",
+			want: []string{
+				"push local 0",
+				"pop temp 0",
+				"push constant 0",
+			},
+		},
+		{
+			desc:   "1を掛けたり1で割ったりしても何もしない: x * 1 / 1",
+			source: "x * 1 / 1",
+			want: []string{
+				"push local 0",
+			},
+		},
+		{
+			desc:   "2の累乗でなければMath.multiplyを呼ぶ: x * 3",
+			source: "x * 3",
+			want: []string{
+				"push local 0",
+				"push constant 3",
+				"call Math.multiply 2",
+			},
+		},
+		{
+			desc:   "2の累乗の割り算はビットを調べて商を求める: x / 16384",
+			source: "x / 16384",
+			want: []string{
+				"push local 0",
+				"pop temp 0",
+				"push temp 0",
+				"push constant 0",
+				"lt",
+				"pop temp 1",
+				"push temp 1",
+				"not",
+				"if-goto DIVIDE_ABS_ID_1",
+				"push temp 0",
+				"neg",
+				"pop temp 0",
+				"label DIVIDE_ABS_ID_1",
+				"push constant 0",
+
+				"push temp 0",
+				"push constant 16384",
+				"and",
+				"if-goto DIVIDE_BIT_ID_1_14",
+				"goto DIVIDE_NEXT_ID_1_14",
+				"label DIVIDE_BIT_ID_1_14",
+				"push constant 1",
+				"add",
+				"label DIVIDE_NEXT_ID_1_14",
+
+				"push temp 0",
+				"push constant 32767",
+				"not",
+				"and",
+				"if-goto DIVIDE_BIT_ID_1_15",
+				"goto DIVIDE_NEXT_ID_1_15",
+				"label DIVIDE_BIT_ID_1_15",
+				"push constant 2",
+				"add",
+				"label DIVIDE_NEXT_ID_1_15",
+
+				"push temp 1",
+				"not",
+				"if-goto DIVIDE_END_ID_1",
+				"neg",
+				"label DIVIDE_END_ID_1",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			parser := newOptimizeTestParser(tc.source + ";")
+			expression, err := parser.parseExpression()
+			if err != nil {
+				t.Fatalf("failed parseExpression: %+v", err)
+			}
+
+			got := expression.ToCode(parser.ctx)
+			if diff := cmp.Diff(got, tc.want); diff != "" {
+				t.Errorf("failed ToCode: diff (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestStatementToCodeWithOptimization(t *testing.T) {
+	cases := []struct {
+		desc   string
+		source string
+		want   []string
+	}{
+		{
+			desc:   "比較演算のif文は条件が成り立つ側へジャンプする",
+			source: "if (x < y) { let x = 1; } else { let x = 2; }",
+			want: []string{
+				"push local 0",
+				"push local 1",
+				"lt",
+				"if-goto IF_TRUE_ID_1",
+				"push constant 2",
+				"pop local 0",
+				"goto IF_END_ID_1",
+				"label IF_TRUE_ID_1",
+				"push constant 1",
+				"pop local 0",
+				"label IF_END_ID_1",
+			},
+		},
+		{
+			desc:   "比較演算のwhile文は条件をループの末尾に置く",
+			source: "while (~(x = 0)) { let x = x - 1; }",
+			want: []string{
+				"goto WHILE_CONDITION_ID_1",
+				"label WHILE_START_ID_1",
+				"push local 0",
+				"push constant 1",
+				"sub",
+				"pop local 0",
+				"label WHILE_CONDITION_ID_1",
+				"push local 0",
+				"push constant 0",
+				"eq",
+				"not",
+				"if-goto WHILE_START_ID_1",
+				"label WHILE_END_ID_1",
+			},
+		},
+		{
+			desc:   "リテラルが条件のwhile文は条件を計算しない",
+			source: "while (true) { let x = 1; }",
+			want: []string{
+				"label WHILE_START_ID_1",
+				"push constant 1",
+				"pop local 0",
+				"goto WHILE_START_ID_1",
+				"label WHILE_END_ID_1",
+			},
+		},
+		{
+			desc:   "偽のリテラルが条件のif文はelse句へジャンプする",
+			source: "if (false) { let x = 1; } else { let x = 2; }",
+			want: []string{
+				"goto ELSE_START_ID_1",
+				"push constant 1",
+				"pop local 0",
+				"goto IF_END_ID_1",
+				"label ELSE_START_ID_1",
+				"push constant 2",
+				"pop local 0",
+				"label IF_END_ID_1",
+			},
+		},
+		{
+			desc:   "比較演算でない条件は-1だけを真とするためにnotを残す",
+			source: "if (x & y) { let x = 1; }",
+			want: []string{
+				"push local 0",
+				"push local 1",
+				"and",
+				"not",
+				"if-goto ELSE_START_ID_1",
+				"push constant 1",
+				"pop local 0",
+				"goto IF_END_ID_1",
+				"label ELSE_START_ID_1",
+				"label IF_END_ID_1",
+			},
+		},
+		{
+			desc:   "条件を反転するnotは打ち消し合う",
+			source: "if (~x) { let x = 1; }",
+			want: []string{
+				"push local 0",
+				"if-goto ELSE_START_ID_1",
+				"push constant 1",
+				"pop local 0",
+				"goto IF_END_ID_1",
+				"label ELSE_START_ID_1",
+				"label IF_END_ID_1",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			// else句の有無を調べるために、if文の後ろにトークンが必要
+			parser := newOptimizeTestParser(tc.source + " }")
+			statement, err := parser.parseStatement()
+			if err != nil {
+				t.Fatalf("failed parseStatement: %+v", err)
+			}
+
+			got := statement.ToCode(parser.ctx)
+			if diff := cmp.Diff(got, tc.want); diff != "" {
+				t.Errorf("failed ToCode: diff (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
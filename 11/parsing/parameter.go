@@ -1,8 +1,8 @@
 package parsing
 
 import (
-	"../symbol"
 	"../token"
+	"fmt"
 )
 
 type ParameterList struct {
@@ -16,14 +16,14 @@ func NewParameterList() *ParameterList {
 	}
 }
 
-func (p *ParameterList) UpdateSymbolTable() {
+func (p *ParameterList) UpdateSymbolTable(ctx *Context) {
 	if p.First == nil {
 		return
 	}
 
-	symbol.GlobalSymbolTables.AddArgSymbol(p.First.VarName.Value, p.First.VarType.Value)
+	ctx.AddArgSymbol(p.First.VarName.Value, p.First.VarType.Value)
 	for _, commaAndParameter := range p.CommaAndParameters {
-		symbol.GlobalSymbolTables.AddArgSymbol(commaAndParameter.VarName.Value, commaAndParameter.VarType.Value)
+		ctx.AddArgSymbol(commaAndParameter.VarName.Value, commaAndParameter.VarType.Value)
 	}
 }
 
@@ -41,6 +41,32 @@ func (p *ParameterList) Add(varTypeToken *token.Token, varNameToken *token.Token
 	return nil
 }
 
+func (p *ParameterList) ParameterItems() []*Parameter {
+	result := []*Parameter{}
+	if p.First == nil {
+		return result
+	}
+
+	result = append(result, p.First)
+	for _, commaAndParameter := range p.CommaAndParameters {
+		result = append(result, commaAndParameter.Parameter)
+	}
+	return result
+}
+
+// int x, int y
+func (p *ParameterList) ToSource() string {
+	if p.First == nil {
+		return ""
+	}
+
+	result := p.First.ToSource()
+	for _, commaAndParameter := range p.CommaAndParameters {
+		result += fmt.Sprintf("%s %s", commaAndParameter.Comma.Value, commaAndParameter.Parameter.ToSource())
+	}
+	return result
+}
+
 func (p *ParameterList) ToXML() []string {
 	result := []string{}
 	result = append(result, "<parameterList>")
@@ -108,6 +134,10 @@ func (p *Parameter) Check() error {
 	return nil
 }
 
+func (p *Parameter) ToSource() string {
+	return fmt.Sprintf("%s %s", p.VarType.Value, p.VarName.Value)
+}
+
 func (p *Parameter) ToXML() []string {
 	result := []string{}
 	result = append(result, p.VarType.ToXML())
@@ -8,22 +8,36 @@ import (
 )
 
 type Parser struct {
-	tokens *token.Tokens
+	tokens    *token.Tokens
+	ctx       *Context
+	loopDepth int // パース中のwhile文とfor文の入れ子の深さ
 	*Class
 	*Code
 }
 
 func NewParser(tokens *token.Tokens, className string) *Parser {
-	// シンボルテーブルをリセットしておく
-	symbol.GlobalSymbolTables.Reset(className)
+	return NewParserWithContext(tokens, NewContext(className))
+}
 
+func NewParserWithContext(tokens *token.Tokens, ctx *Context) *Parser {
 	return &Parser{
 		tokens: tokens,
+		ctx:    ctx,
 		Class:  NewClass(),
 		Code:   NewCode(),
 	}
 }
 
+func (p *Parser) Context() *Context {
+	return p.ctx
+}
+
+func (p *Parser) PrintDebugCode() {
+	if p.ctx.DebugCode {
+		p.Code.WriteDebugCode(p.ctx.DebugWriter)
+	}
+}
+
 func (p *Parser) advanceToken() *token.Token {
 	return p.tokens.Advance()
 }
@@ -43,14 +57,77 @@ func (p *Parser) Parse() (*Class, error) {
 	}
 
 	// クラス版シンボルテーブルの出力
-	symbol.GlobalSymbolTables.PrintClassSymbolTable()
+	p.ctx.PrintClassSymbolTable()
 
 	return class, nil
 }
 
-// 'class' className '{' classVarDec* subroutineDec* '}'
+// 他のクラスから参照できるように、クラスの定数宣言だけをパースして値を返す
+// 返すマップのキーはConstantKeyで作る
+// 途中でエラーになった場合も、それより前に宣言した定数は返す
+func (p *Parser) ParseConstants() (map[string]int, error) {
+	if _, err := p.parseClassStart(); err != nil {
+		return nil, errors.WithMessage(err, p.tokens.DebugForError())
+	}
+
+	constDecs, err := p.parseConstDecs()
+	if err != nil {
+		return p.declaredConstants(), errors.WithMessage(err, p.tokens.DebugForError())
+	}
+	p.Class.SetConstDecs(constDecs)
+
+	return p.declaredConstants(), nil
+}
+
+// クラス版シンボルテーブルに登録済みの定数
+func (p *Parser) declaredConstants() map[string]int {
+	result := map[string]int{}
+	for name, value := range p.ctx.ClassSymbolTable.Constants() {
+		result[ConstantKey(p.Class.ClassName.Value, name)] = value
+	}
+	return result
+}
+
+// 'class' className '{' constDec* classVarDec* subroutineDec* '}'
 // class Main { ... }
+// constDecは拡張文法でのみ書ける
 func (p *Parser) parseClass() (*Class, error) {
+	keyword, err := p.parseClassStart()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.ctx.Extended {
+		constDecs, err := p.parseConstDecs()
+		if err != nil {
+			return nil, err
+		}
+		p.Class.SetConstDecs(constDecs)
+	}
+
+	classVarDecs, err := p.parseClassVarDecs()
+	if err != nil {
+		return nil, err
+	}
+	p.Class.SetClassVarDecs(classVarDecs)
+
+	subroutineDecs, err := p.parseSubroutineDecs()
+	if err != nil {
+		return nil, err
+	}
+	p.Class.SetSubroutineDecs(subroutineDecs)
+
+	closingCurlyBracket := p.advanceToken()
+	if err := ConstClosingCurlyBracket.Check(closingCurlyBracket); err != nil {
+		return nil, err
+	}
+	p.ctx.Set(p.Class, keyword, closingCurlyBracket)
+
+	return p.Class, nil
+}
+
+// 'class' className '{'
+func (p *Parser) parseClassStart() (*token.Token, error) {
 	keyword := p.advanceToken()
 	if err := p.Class.CheckKeyword(keyword); err != nil {
 		return nil, err
@@ -66,24 +143,164 @@ func (p *Parser) parseClass() (*Class, error) {
 		return nil, err
 	}
 
-	classVarDecs, err := p.parseClassVarDecs()
-	if err != nil {
+	return keyword, nil
+}
+
+// (constDec | enumDec)*
+func (p *Parser) parseConstDecs() (*ConstDecs, error) {
+	constDecs := NewConstDecs()
+
+	for constDecs.HasConstDec(p.readFirstToken()) {
+		var item ConstDecItem
+		var err error
+		if p.readFirstToken().Value == "const" {
+			item, err = p.parseConstDec()
+		} else {
+			item, err = p.parseEnumDec()
+		}
+		if err != nil {
+			return nil, err
+		}
+		constDecs.Add(item)
+	}
+
+	return constDecs, nil
+}
+
+// 'const' ('int' | 'char' | 'boolean') varName '=' expression ';'
+// const int MAX = 10;
+func (p *Parser) parseConstDec() (*ConstDec, error) {
+	constDec := NewConstDec()
+	keyword := p.advanceToken()
+
+	if err := constDec.SetVarType(p.advanceToken()); err != nil {
 		return nil, err
 	}
-	p.Class.SetClassVarDecs(classVarDecs)
 
-	subroutineDecs, err := p.parseSubroutineDecs()
+	varName := p.advanceToken()
+	if err := constDec.SetVarName(varName); err != nil {
+		return nil, err
+	}
+
+	if err := ConstEqual.Check(p.advanceToken()); err != nil {
+		return nil, err
+	}
+
+	expression, err := p.parseExpression()
 	if err != nil {
 		return nil, err
 	}
-	p.Class.SetSubroutineDecs(subroutineDecs)
+	value, err := p.evaluateConstant(varName, expression)
+	if err != nil {
+		return nil, err
+	}
+	constDec.SetExpression(expression, value)
+
+	semicolon := p.advanceToken()
+	if err := ConstSemicolon.Check(semicolon); err != nil {
+		return nil, err
+	}
+	p.ctx.Set(constDec, keyword, semicolon)
+
+	if err := p.addConstSymbol(varName, constDec.VarType.Value, value); err != nil {
+		return nil, err
+	}
+	return constDec, nil
+}
+
+// 'enum' enumName? '{' varName ('=' expression)? (',' varName ('=' expression)?)* '}'
+// enum { UP, DOWN, LEFT = 10 }
+// enum Direction { UP, DOWN, LEFT = 10 }
+func (p *Parser) parseEnumDec() (*EnumDec, error) {
+	enumDec := NewEnumDec()
+	keyword := p.advanceToken()
+
+	if !ConstOpeningCurlyBracket.IsCheck(p.readFirstToken()) {
+		enumName := NewIdentifier("EnumName", p.advanceToken())
+		if err := enumName.Check(); err != nil {
+			return nil, err
+		}
+		enumDec.SetEnumName(enumName)
+	}
+
+	if err := ConstOpeningCurlyBracket.Check(p.advanceToken()); err != nil {
+		return nil, err
+	}
+
+	for {
+		varName := p.advanceToken()
+		name, err := NewVarNameOrError(varName)
+		if err != nil {
+			return nil, err
+		}
+		item := NewEnumItem(name)
+		item.Value = enumDec.nextValue()
+
+		if ConstEqual.IsCheck(p.readFirstToken()) {
+			p.advanceToken()
+			expression, err := p.parseExpression()
+			if err != nil {
+				return nil, err
+			}
+			value, err := p.evaluateConstant(varName, expression)
+			if err != nil {
+				return nil, err
+			}
+			item.SetExpression(expression)
+			item.Value = value
+		}
+		enumDec.Add(item)
+
+		// 後ろの要素の値の式から参照できるように、要素ごとに登録する
+		if err := p.addConstSymbol(varName, "int", item.Value); err != nil {
+			return nil, err
+		}
+
+		if !ConstComma.IsCheck(p.readFirstToken()) {
+			break
+		}
+		p.advanceToken()
+	}
 
 	closingCurlyBracket := p.advanceToken()
 	if err := ConstClosingCurlyBracket.Check(closingCurlyBracket); err != nil {
 		return nil, err
 	}
+	p.ctx.Set(enumDec, keyword, closingCurlyBracket)
 
-	return p.Class, nil
+	return enumDec, nil
+}
+
+// 定数式を計算する
+// 参照できるのはリテラルと、同じクラスで先に宣言した定数だけ
+func (p *Parser) evaluateConstant(varName *token.Token, expression *Expression) (int, error) {
+	folder := newFolder(p.ctx, expression)
+	value, ok := folder.expression(expression)
+	if !ok {
+		message := fmt.Sprintf("error evaluateConstant: not a constant expression: got = %s", varName.Debug())
+		return 0, errors.New(message)
+	}
+	folder.commit()
+	return value, nil
+}
+
+func (p *Parser) addConstSymbol(varName *token.Token, symbolType string, value int) error {
+	if _, err := p.ctx.ClassSymbolTable.Find(varName.Value); err == nil {
+		message := fmt.Sprintf("error addConstSymbol: duplicate constant: got = %s", varName.Debug())
+		return errors.New(message)
+	}
+	p.ctx.AddConstSymbol(varName.Value, symbolType, value)
+	return nil
+}
+
+// 定数への代入はエラーにする
+func (p *Parser) checkAssignable(varName *token.Token) error {
+	item, err := p.ctx.FindSymbolItem(varName.Value)
+	if err == nil && item.ScopeKind == symbol.ConstScope {
+		message := fmt.Sprintf("error checkAssignable: cannot assign to constant: got = %s", varName.Debug())
+		return errors.New(message)
+	}
+	return nil
 }
 
 // ('static' | 'field') varType varName (',' varName) ';'
@@ -121,12 +338,13 @@ func (p *Parser) parseClassVarDecs() (*ClassVarDecs, error) {
 		if err := ConstSemicolon.Check(semicolon); err != nil {
 			return nil, err
 		}
+		p.ctx.Set(classVarDec, keyword, semicolon)
 
 		// パースに成功したら要素に追加
 		classVarDecs.Add(classVarDec)
 
 		// シンボルテーブルの更新
-		classVarDec.UpdateSymbolTable()
+		classVarDec.UpdateSymbolTable(p.ctx)
 	}
 
 	return classVarDecs, nil
@@ -139,6 +357,7 @@ func (p *Parser) parseSubroutineDecs() (*SubroutineDecs, error) {
 	for subroutineDecs.hasSubroutineDec(p.readFirstToken()) {
 		keyword := NewKeyword(p.advanceToken())
 		subroutineDec := NewSubroutineDec(keyword, p.ClassName)
+		p.ctx.SetStart(subroutineDec, keyword.Token)
 
 		subroutineType := p.advanceToken()
 		if err := subroutineDec.SetSubroutineType(subroutineType); err != nil {
@@ -151,7 +370,7 @@ func (p *Parser) parseSubroutineDecs() (*SubroutineDecs, error) {
 		}
 
 		// サブルーチン用のシンボルテーブルを初期化
-		symbol.GlobalSymbolTables.ResetSubroutine(subroutineDec.SubroutineName.Value)
+		p.ctx.ResetSubroutine(subroutineDec.SubroutineName.Value)
 
 		openingRoundBracket := p.advanceToken()
 		if err := ConstOpeningRoundBracket.Check(openingRoundBracket); err != nil {
@@ -175,16 +394,18 @@ func (p *Parser) parseSubroutineDecs() (*SubroutineDecs, error) {
 			return nil, err
 		}
 		subroutineDec.SetSubroutineBody(subroutineBody)
+		p.ctx.FindSpan(subroutineDec).End = p.ctx.FindSpan(subroutineBody).End
 
 		// パースに成功したら要素に追加
 		subroutineDecs.Add(subroutineDec)
 
 		// サブルーチン版シンボルテーブルの出力
-		symbol.GlobalSymbolTables.PrintSubroutineSymbolTable()
+		p.ctx.PrintSubroutineSymbolTable()
 
 		// サブルーチンのコード生成
 		// このタイミングでコード生成しないとサブルーチンのシンボルテーブルが消えるためここで実施
-		p.AddCode(subroutineDec)
+		p.AddCode(p.ctx, subroutineDec)
+		p.ctx.saveSubroutineSymbolTable(subroutineDec)
 	}
 
 	return subroutineDecs, nil
@@ -217,7 +438,7 @@ func (p *Parser) parseParameterList() (*ParameterList, error) {
 	}
 
 	// シンボルテーブルの更新
-	parameterList.UpdateSymbolTable()
+	parameterList.UpdateSymbolTable(p.ctx)
 
 	return parameterList, nil
 }
@@ -251,6 +472,8 @@ func (p *Parser) parseSubroutineBody() (*SubroutineBody, error) {
 	if err := ConstClosingCurlyBracket.Check(closingCurlyBracket); err != nil {
 		return nil, err
 	}
+	p.ctx.Set(subroutineBody, openingCurlyBracket, closingCurlyBracket)
+	p.ctx.Set(statements, openingCurlyBracket, closingCurlyBracket)
 
 	return subroutineBody, nil
 }
@@ -286,9 +509,10 @@ func (p *Parser) parseVarDec() (*VarDec, error) {
 	if err := ConstSemicolon.Check(semicolon); err != nil {
 		return nil, err
 	}
+	p.ctx.Set(varDec, keyword, semicolon)
 
 	// シンボルテーブルの更新
-	varDec.UpdateSymbolTable()
+	varDec.UpdateSymbolTable(p.ctx)
 
 	return varDec, nil
 }
@@ -320,10 +544,150 @@ func (p *Parser) parseStatement() (Statement, error) {
 		return p.parseDoStatement()
 	case "return":
 		return p.parseReturnStatement()
-	default:
-		message := fmt.Sprintf("Invalid Statement: got = %s", keyword.Debug())
+	case "for":
+		if p.ctx.Extended {
+			return p.parseForStatement()
+		}
+	case "break", "continue":
+		if p.ctx.Extended {
+			return p.parseLoopJumpStatement()
+		}
+	}
+
+	message := fmt.Sprintf("Invalid Statement: got = %s", keyword.Debug())
+	return nil, errors.New(message)
+}
+
+// (for) '(' assignment? ';' expression? ';' assignment? ')' '{' statements '}'
+func (p *Parser) parseForStatement() (Statement, error) {
+	forStatement := NewForStatement()
+
+	keyword := p.advanceToken()
+	if err := forStatement.CheckKeyword(keyword); err != nil {
+		return nil, err
+	}
+
+	openingRoundBracket := p.advanceToken()
+	if err := ConstOpeningRoundBracket.Check(openingRoundBracket); err != nil {
+		return nil, err
+	}
+
+	if !ConstSemicolon.IsCheck(p.readFirstToken()) {
+		init, err := p.parseAssignment()
+		if err != nil {
+			return nil, err
+		}
+		forStatement.SetInit(init)
+	}
+
+	if err := ConstSemicolon.Check(p.advanceToken()); err != nil {
+		return nil, err
+	}
+
+	if !ConstSemicolon.IsCheck(p.readFirstToken()) {
+		condition, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		forStatement.SetCondition(condition)
+	}
+
+	if err := ConstSemicolon.Check(p.advanceToken()); err != nil {
+		return nil, err
+	}
+
+	if !ConstClosingRoundBracket.IsCheck(p.readFirstToken()) {
+		step, err := p.parseAssignment()
+		if err != nil {
+			return nil, err
+		}
+		forStatement.SetStep(step)
+	}
+
+	closingRoundBracket := p.advanceToken()
+	if err := ConstClosingRoundBracket.Check(closingRoundBracket); err != nil {
+		return nil, err
+	}
+
+	openingCurlyBracket := p.advanceToken()
+	if err := ConstOpeningCurlyBracket.Check(openingCurlyBracket); err != nil {
+		return nil, err
+	}
+
+	p.loopDepth++
+	statements, err := p.parseStatements()
+	p.loopDepth--
+	if err != nil {
+		return nil, err
+	}
+	forStatement.SetStatements(statements)
+
+	closingCurlyBracket := p.advanceToken()
+	if err := ConstClosingCurlyBracket.Check(closingCurlyBracket); err != nil {
+		return nil, err
+	}
+	p.ctx.Set(statements, openingCurlyBracket, closingCurlyBracket)
+	p.ctx.Set(forStatement, keyword, closingCurlyBracket)
+
+	return forStatement, nil
+}
+
+// varName ('[' expression ']')? '=' expression
+func (p *Parser) parseAssignment() (*Assignment, error) {
+	assignment := NewAssignment()
+
+	if ConstOpeningSquareBracket.IsCheck(p.readSecondToken()) {
+		array, err := p.parseArray()
+		if err != nil {
+			return nil, err
+		}
+		assignment.SetArray(array)
+	} else {
+		varName := p.advanceToken()
+		if err := assignment.SetVarName(varName); err != nil {
+			return nil, err
+		}
+		if err := p.checkAssignable(varName); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := ConstEqual.Check(p.advanceToken()); err != nil {
+		return nil, err
+	}
+
+	expression, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	assignment.SetExpression(expression)
+
+	return assignment, nil
+}
+
+// (break | continue) ';'
+// ループの外に書かれていたらエラーにする
+func (p *Parser) parseLoopJumpStatement() (Statement, error) {
+	keyword := p.advanceToken()
+	if p.loopDepth == 0 {
+		message := fmt.Sprintf("error parseLoopJumpStatement: outside loop: got = %s", keyword.Debug())
 		return nil, errors.New(message)
 	}
+
+	var statement Statement
+	if keyword.Value == "break" {
+		statement = NewBreakStatement()
+	} else {
+		statement = NewContinueStatement()
+	}
+
+	semicolon := p.advanceToken()
+	if err := ConstSemicolon.Check(semicolon); err != nil {
+		return nil, err
+	}
+	p.ctx.Set(statement, keyword, semicolon)
+
+	return statement, nil
 }
 
 // (let) varName ('[' expression ']')? '=' expression ';'
@@ -347,6 +711,9 @@ func (p *Parser) parseLetStatement() (Statement, error) {
 		if err := letStatement.SetVarName(varName); err != nil {
 			return nil, err
 		}
+		if err := p.checkAssignable(varName); err != nil {
+			return nil, err
+		}
 	}
 
 	equal := p.advanceToken()
@@ -364,6 +731,7 @@ func (p *Parser) parseLetStatement() (Statement, error) {
 	if err := ConstSemicolon.Check(semicolon); err != nil {
 		return nil, err
 	}
+	p.ctx.Set(letStatement, keyword, semicolon)
 
 	return letStatement, nil
 }
@@ -408,6 +776,8 @@ func (p *Parser) parseIfStatement() (Statement, error) {
 	if err := ConstClosingCurlyBracket.Check(closingCurlyBracket); err != nil {
 		return nil, err
 	}
+	p.ctx.Set(statements, openingCurlyBracket, closingCurlyBracket)
+	p.ctx.Set(ifStatement, keyword, closingCurlyBracket)
 
 	// else句が存在するかチェックする
 	if NewKeyword(p.readFirstToken()).Check("else") == nil {
@@ -416,6 +786,7 @@ func (p *Parser) parseIfStatement() (Statement, error) {
 			return nil, err
 		}
 		ifStatement.SetElseBlock(elseBlock)
+		p.ctx.FindSpan(ifStatement).End = p.ctx.FindSpan(elseBlock).End
 	}
 
 	return ifStatement, nil
@@ -451,7 +822,9 @@ func (p *Parser) parseWhileStatement() (Statement, error) {
 		return nil, err
 	}
 
+	p.loopDepth++
 	statements, err := p.parseStatements()
+	p.loopDepth--
 	if err != nil {
 		return nil, err
 	}
@@ -461,6 +834,8 @@ func (p *Parser) parseWhileStatement() (Statement, error) {
 	if err := ConstClosingCurlyBracket.Check(closingCurlyBracket); err != nil {
 		return nil, err
 	}
+	p.ctx.Set(statements, openingCurlyBracket, closingCurlyBracket)
+	p.ctx.Set(whileStatement, keyword, closingCurlyBracket)
 
 	return whileStatement, nil
 }
@@ -484,6 +859,7 @@ func (p *Parser) parseDoStatement() (Statement, error) {
 	if err := ConstSemicolon.Check(semicolon); err != nil {
 		return nil, err
 	}
+	p.ctx.Set(doStatement, keyword, semicolon)
 
 	return doStatement, nil
 }
@@ -509,11 +885,13 @@ func (p *Parser) parseReturnStatement() (Statement, error) {
 	if err := ConstSemicolon.Check(semicolon); err != nil {
 		return nil, err
 	}
+	p.ctx.Set(returnStatement, keyword, semicolon)
 
 	return returnStatement, nil
 }
 
 // else '{' statements '}'
+// 拡張文法では else ifStatement も書ける
 func (p *Parser) parseElseBlock() (*ElseBlock, error) {
 	elseBlock := NewElseBlock()
 
@@ -522,6 +900,17 @@ func (p *Parser) parseElseBlock() (*ElseBlock, error) {
 		return nil, err
 	}
 
+	if p.ctx.Extended && NewKeyword(p.readFirstToken()).Check("if") == nil {
+		ifStatement, err := p.parseIfStatement()
+		if err != nil {
+			return nil, err
+		}
+		elseBlock.SetElseIf(ifStatement.(*IfStatement))
+		p.ctx.SetStart(elseBlock, keyword)
+		p.ctx.FindSpan(elseBlock).End = p.ctx.FindSpan(ifStatement).End
+		return elseBlock, nil
+	}
+
 	openingCurlyBracket := p.advanceToken()
 	if err := ConstOpeningCurlyBracket.Check(openingCurlyBracket); err != nil {
 		return nil, err
@@ -537,6 +926,8 @@ func (p *Parser) parseElseBlock() (*ElseBlock, error) {
 	if err := ConstClosingCurlyBracket.Check(closingCurlyBracket); err != nil {
 		return nil, err
 	}
+	p.ctx.Set(statements, openingCurlyBracket, closingCurlyBracket)
+	p.ctx.Set(elseBlock, keyword, closingCurlyBracket)
 
 	return elseBlock, nil
 }
@@ -628,11 +1019,13 @@ func (p *Parser) parseExpressionList() (*ExpressionList, error) {
 
 // term (op term)*
 func (p *Parser) parseExpression() (*Expression, error) {
+	first := p.readFirstToken()
 	term, err := p.parseTerm()
 	if err != nil {
 		return nil, err
 	}
 	expression := NewExpression(term)
+	p.ctx.SetStart(expression, first)
 
 	if err := ConstBinaryOpFactory.Check(p.readFirstToken()); err == nil {
 		binaryOpTerms, err := p.parseBinaryOpTerms()
@@ -642,6 +1035,9 @@ func (p *Parser) parseExpression() (*Expression, error) {
 		expression.SetBinaryOpTerms(binaryOpTerms)
 	}
 
+	if p.ctx.Precedence {
+		return applyPrecedence(p.ctx, expression), nil
+	}
 	return expression, nil
 }
 
@@ -666,7 +1062,7 @@ func (p *Parser) parseBinaryOpTerms() (*BinaryOpTerms, error) {
 	return binaryOpTerms, nil
 }
 
-// integerConstant | stringConstant | keywordConstant |
+// integerConstant | stringConstant | charConstant | keywordConstant |
 // varName | subroutineCall | varName '[' expression ']' |
 // '(' expression ')' | unaryOp term
 func (p *Parser) parseTerm() (Term, error) {
@@ -676,6 +1072,8 @@ func (p *Parser) parseTerm() (Term, error) {
 		return p.parseIntegerConstant()
 	case token.TokenStringConst:
 		return p.parseStringConstant()
+	case token.TokenCharConst:
+		return p.parseCharConstant()
 	case token.TokenKeyword:
 		return p.parseKeywordConstant()
 	case token.TokenIdentifier:
@@ -703,20 +1101,38 @@ func (p *Parser) parseStringConstant() (Term, error) {
 	if err := stringConstant.Check(); err != nil {
 		return nil, err
 	}
+	if _, err := stringConstant.Chars(p.ctx); err != nil {
+		return nil, err
+	}
 	return stringConstant, nil
 }
 
+// charConstant
+func (p *Parser) parseCharConstant() (Term, error) {
+	charConstant := NewCharConstant(p.advanceToken())
+	if err := charConstant.Check(); err != nil {
+		return nil, err
+	}
+	return charConstant, nil
+}
+
 // keywordConstant
 func (p *Parser) parseKeywordConstant() (Term, error) {
 	return ConstKeywordConstantFactory.Create(p.advanceToken())
 }
 
-// varName | subroutineCall | varName '[' expression ']'
+// varName | subroutineCall | varName '[' expression ']' | className '.' varName
 func (p *Parser) parseIdentifierTerm() (Term, error) {
 	second := p.readSecondToken()
 
 	switch second.Value {
-	case ConstOpeningRoundBracket.Value, ConstPeriod.Value:
+	case ConstPeriod.Value:
+		// className '.' varName の後ろに '(' がなければ定数の参照
+		if p.ctx.Extended && !ConstOpeningRoundBracket.IsCheck(p.tokens.Peek(3)) {
+			return p.parseClassConstant()
+		}
+		return p.parseSubroutineCall()
+	case ConstOpeningRoundBracket.Value:
 		return p.parseSubroutineCall()
 	case ConstOpeningSquareBracket.Value:
 		return p.parseArray()
@@ -726,6 +1142,39 @@ func (p *Parser) parseIdentifierTerm() (Term, error) {
 	}
 }
 
+// className '.' varName
+// 自身のクラスの定数はシンボルテーブルから、他のクラスの定数はコンテキストから値を探す
+func (p *Parser) parseClassConstant() (*ClassConstant, error) {
+	className := NewClassName(p.advanceToken())
+	if err := className.Check(); err != nil {
+		return nil, err
+	}
+
+	if err := ConstPeriod.Check(p.advanceToken()); err != nil {
+		return nil, err
+	}
+
+	varName, err := NewVarNameOrError(p.advanceToken())
+	if err != nil {
+		return nil, err
+	}
+
+	key := ConstantKey(className.Value, varName.Value)
+	value, ok := p.ctx.Constants[key]
+	if className.Value == p.Class.ClassName.Value {
+		item, err := p.ctx.ClassSymbolTable.Find(varName.Value)
+		ok = err == nil && item.ScopeKind == symbol.ConstScope
+		if ok {
+			value = item.ScopeIndex
+		}
+	}
+	if !ok {
+		return nil, errors.WithStack(&UndefinedConstantError{ClassName: className.Value, Name: varName.Value})
+	}
+
+	return NewClassConstant(className, varName, value), nil
+}
+
 // '(' expression ')' | unaryOp term
 func (p *Parser) parseSymbolTerm() (Term, error) {
 	op := p.readFirstToken()
@@ -743,11 +1192,13 @@ func (p *Parser) parseSymbolTerm() (Term, error) {
 
 // unaryOp term
 func (p *Parser) parseUnaryOpTerm() (*UnaryOpTerm, error) {
-	unary, err := ConstUnaryOpFactory.Create(p.advanceToken())
+	first := p.advanceToken()
+	unary, err := ConstUnaryOpFactory.Create(first)
 	if err != nil {
 		return nil, err
 	}
 	unaryOpTerm := NewUnaryOpTerm(unary)
+	p.ctx.SetStart(unaryOpTerm, first)
 
 	term, err := p.parseTerm()
 	if err != nil {
@@ -1674,6 +1674,34 @@ func TestParserParseIntegerConstant(t *testing.T) {
 	}
 }
 
+func TestParserParseIntegerConstantOutOfRange(t *testing.T) {
+	cases := []struct {
+		desc  string
+		value string
+	}{
+		{
+			desc:  "push constantで扱えない大きさ",
+			value: "32768",
+		},
+		{
+			desc:  "intに収まらない大きさ",
+			value: "99999999999999999999",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			tokens := token.NewTokens()
+			tokens.Add([]*token.Token{token.NewToken(tc.value, token.TokenIntConst)})
+
+			parser := NewParser(tokens, "Test")
+			if _, err := parser.parseIntegerConstant(); err == nil {
+				t.Errorf("failed %s: expected error", tc.desc)
+			}
+		})
+	}
+}
+
 func TestParserParseStringConstant(t *testing.T) {
 	cases := []struct {
 		desc   string
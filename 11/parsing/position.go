@@ -0,0 +1,61 @@
+package parsing
+
+import (
+	"../token"
+)
+
+// ASTノードが対応するソースファイル上の範囲
+type Span struct {
+	Start token.Position
+	End   token.Position
+}
+
+// ASTノードごとのソースファイル上の範囲を管理
+// ノードの構造体に位置情報を持たせると、XML出力やテストの比較に影響するため別管理にしている
+type Positions struct {
+	spans map[interface{}]*Span
+}
+
+func NewPositions() *Positions {
+	return &Positions{
+		spans: map[interface{}]*Span{},
+	}
+}
+
+func (p *Positions) SetStart(node interface{}, start *token.Token) {
+	p.span(node).Start = start.Position
+}
+
+func (p *Positions) SetEnd(node interface{}, end *token.Token) {
+	p.span(node).End = end.Position
+}
+
+func (p *Positions) Set(node interface{}, start *token.Token, end *token.Token) {
+	p.SetStart(node, start)
+	p.SetEnd(node, end)
+}
+
+// ASTを書き換えるためにノードを複製した場合に、元のノードの位置を引き継ぐ
+func (p *Positions) CopySpan(dest interface{}, src interface{}) {
+	if span, ok := p.spans[src]; ok {
+		copied := *span
+		p.spans[dest] = &copied
+	}
+}
+
+// 位置情報が登録されていないノードの場合はゼロ値を返す
+func (p *Positions) FindSpan(node interface{}) *Span {
+	if span, ok := p.spans[node]; ok {
+		return span
+	}
+	return &Span{}
+}
+
+func (p *Positions) span(node interface{}) *Span {
+	span, ok := p.spans[node]
+	if !ok {
+		span = &Span{}
+		p.spans[node] = span
+	}
+	return span
+}
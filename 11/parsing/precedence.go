@@ -0,0 +1,116 @@
+package parsing
+
+// 二項演算子の優先順位（大きいほど先に計算する）
+// Jackの仕様では優先順位はなく左から順に計算するが、優先順位モードではこの順位に従う
+//   - 「*」「/」
+//   - 「+」「-」
+//   - 「<」「>」「=」
+//   - 「&」「|」
+func Precedence(op BinaryOp) int {
+	switch op.OpType() {
+	case AsteriskType, SlashType:
+		return 3
+	case PlusType, MinusType:
+		return 2
+	case LessThanType, GreaterThanType, EqualsType:
+		return 1
+	}
+	return 0
+}
+
+// 左から順に計算した場合と、優先順位に従って計算した場合で結果が変わるかどうか
+// 直前の演算子より優先順位が高い演算子があると、計算の順番が変わる
+func HasPrecedenceConflict(e *Expression) bool {
+	if e.BinaryOpTerms == nil {
+		return false
+	}
+	items := e.BinaryOpTerms.Items
+	for i := 1; i < len(items); i++ {
+		if Precedence(items[i].BinaryOp) > Precedence(items[i-1].BinaryOp) {
+			return true
+		}
+	}
+	return false
+}
+
+// 演算子の優先順位に従って組み立てた木のノード
+// 葉ならtermだけを持つ
+type precedenceNode struct {
+	term  Term
+	left  *precedenceNode
+	op    BinaryOp
+	right *precedenceNode
+}
+
+// 優先順位に従って、式をカッコを補った木に組み替える
+// 同じ優先順位の演算子は左から計算するので、左から順に計算して結果が同じ部分はそのまま残し、
+// 先に計算しなければならない右側の部分式だけを、ソースコードに現れないカッコ（Implicit）で囲む
+// たとえば「a + b * c - d」は「a + (b * c) - d」になる
+func applyPrecedence(ctx *Context, e *Expression) *Expression {
+	if !HasPrecedenceConflict(e) {
+		return e
+	}
+
+	builder := &precedenceBuilder{terms: []Term{e.Term}, ops: []BinaryOp{}}
+	for _, item := range e.BinaryOpTerms.Items {
+		builder.terms = append(builder.terms, item.Term)
+		builder.ops = append(builder.ops, item.BinaryOp)
+	}
+
+	result := builder.expression(ctx, builder.build(0), e)
+	ctx.span(result).Start = ctx.FindSpan(e).Start
+	return result
+}
+
+type precedenceBuilder struct {
+	terms []Term
+	ops   []BinaryOp // ops[i]はterms[i]とterms[i+1]の間の演算子
+	index int        // 次に読むtermの位置
+}
+
+// 優先順位がminPrecedence以上の演算子だけをまとめて木にする
+func (b *precedenceBuilder) build(minPrecedence int) *precedenceNode {
+	left := &precedenceNode{term: b.terms[b.index]}
+	b.index++
+	for b.index-1 < len(b.ops) {
+		op := b.ops[b.index-1]
+		if Precedence(op) < minPrecedence {
+			break
+		}
+		right := b.build(Precedence(op) + 1)
+		left = &precedenceNode{left: left, op: op, right: right}
+	}
+	return left
+}
+
+// 左側をたどって、左から順に計算する式にする
+func (b *precedenceBuilder) expression(ctx *Context, node *precedenceNode, origin *Expression) *Expression {
+	items := []*BinaryOpTerm{}
+	for node.term == nil {
+		items = append([]*BinaryOpTerm{NewBinaryOpTerm(node.op, b.term(ctx, node.right, origin))}, items...)
+		node = node.left
+	}
+
+	result := NewExpression(node.term)
+	if len(items) > 0 {
+		binaryOpTerms := NewBinaryOpTerms()
+		for _, item := range items {
+			binaryOpTerms.Add(item)
+		}
+		result.SetBinaryOpTerms(binaryOpTerms)
+	}
+	return result
+}
+
+func (b *precedenceBuilder) term(ctx *Context, node *precedenceNode, origin *Expression) Term {
+	if node.term != nil {
+		return node.term
+	}
+
+	// 補ったカッコの中の式は、警告を出す位置として元の式の位置を使う
+	expression := b.expression(ctx, node, origin)
+	ctx.span(expression).Start = ctx.FindSpan(origin).Start
+	grouping := NewGroupingExpression(expression)
+	grouping.Implicit = true
+	return grouping
+}
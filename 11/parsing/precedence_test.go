@@ -0,0 +1,146 @@
+package parsing
+
+import (
+	"../token"
+	"github.com/google/go-cmp/cmp"
+	"testing"
+)
+
+func newPrecedenceTestParser(source string, precedence bool) *Parser {
+	tokens := token.NewTokenizer([]string{source}).Tokenize()
+	parser := NewParser(tokens, "Test")
+	parser.ctx.Precedence = precedence
+	for _, name := range []string{"a", "b", "c", "d"} {
+		parser.ctx.AddVarSymbol(name, "int")
+	}
+	return parser
+}
+
+func TestExpressionToCodeWithPrecedence(t *testing.T) {
+	cases := []struct {
+		desc       string
+		source     string
+		precedence bool
+		want       []string
+	}{
+		{
+			desc:       "優先順位モードでは掛け算を先に計算する",
+			source:     "a + b * c - d",
+			precedence: true,
+			want: []string{
+				"push local 0",
+				"push local 1",
+				"push local 2",
+				"call Math.multiply 2",
+				"add",
+				"push local 3",
+				"sub",
+			},
+		},
+		{
+			desc:       "標準では左から順に計算する",
+			source:     "a + b * c",
+			precedence: false,
+			want: []string{
+				"push local 0",
+				"push local 1",
+				"add",
+				"push local 2",
+				"call Math.multiply 2",
+			},
+		},
+		{
+			desc:       "比較を論理演算より先に計算する",
+			source:     "a < b & c = d",
+			precedence: true,
+			want: []string{
+				"push local 0",
+				"push local 1",
+				"lt",
+				"push local 2",
+				"push local 3",
+				"eq",
+				"and",
+			},
+		},
+		{
+			desc:       "同じ優先順位の演算子は左から計算する",
+			source:     "a - b + c * d / a",
+			precedence: true,
+			want: []string{
+				"push local 0",
+				"push local 1",
+				"sub",
+				"push local 2",
+				"push local 3",
+				"call Math.multiply 2",
+				"push local 0",
+				"call Math.divide 2",
+				"add",
+			},
+		},
+		{
+			desc:       "左から順に計算しても同じ式はそのまま",
+			source:     "a * b + c",
+			precedence: true,
+			want: []string{
+				"push local 0",
+				"push local 1",
+				"call Math.multiply 2",
+				"push local 2",
+				"add",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			parser := newPrecedenceTestParser(tc.source+";", tc.precedence)
+			expression, err := parser.parseExpression()
+			if err != nil {
+				t.Fatalf("failed parseExpression: %+v", err)
+			}
+
+			got := expression.ToCode(parser.ctx)
+			if diff := cmp.Diff(got, tc.want); diff != "" {
+				t.Errorf("failed ToCode: diff (-got +want):\n%s", diff)
+			}
+
+			// 補ったカッコはソースコードに出力しない
+			if diff := cmp.Diff(expression.ToSource(), tc.source); diff != "" {
+				t.Errorf("failed ToSource: diff (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestExpressionToXMLWithPrecedence(t *testing.T) {
+	parser := newPrecedenceTestParser("a + b * c;", true)
+	expression, err := parser.parseExpression()
+	if err != nil {
+		t.Fatalf("failed parseExpression: %+v", err)
+	}
+
+	want := []string{
+		"<expression>",
+		"<term>",
+		"<identifier> a </identifier>",
+		"</term>",
+		"<symbol> + </symbol>",
+		"<term>",
+		"<expression>",
+		"<term>",
+		"<identifier> b </identifier>",
+		"</term>",
+		"<symbol> * </symbol>",
+		"<term>",
+		"<identifier> c </identifier>",
+		"</term>",
+		"</expression>",
+		"</term>",
+		"</expression>",
+	}
+	if diff := cmp.Diff(expression.ToXML(), want); diff != "" {
+		t.Errorf("failed ToXML: diff (-got +want):\n%s", diff)
+	}
+}
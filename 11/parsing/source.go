@@ -0,0 +1,173 @@
+package parsing
+
+import (
+	"../token"
+	"strings"
+)
+
+// ToSourceでJackのソースコードを出力するときのオプション
+type SourceOptions struct {
+	Indent            string // インデント一段ぶんの文字列
+	SplitDeclarations bool   // trueなら「var int x, y;」を一行一宣言に分割する
+}
+
+func NewSourceOptions() *SourceOptions {
+	return &SourceOptions{
+		Indent:            "    ",
+		SplitDeclarations: false,
+	}
+}
+
+// ASTからJackのソースコードを出力するためのプリンタ
+// ノードの位置情報をもとに、元のソースにあったコメントと空行を差し込む
+type SourcePrinter struct {
+	*SourceOptions
+	positions    *Positions
+	comments     []*token.Comment
+	lastLine     int  // 直前に出力したソース上の行番号
+	isBlockStart bool // ブロックの先頭では空行を出力しない
+}
+
+func NewSourcePrinter(options *SourceOptions, positions *Positions, comments []*token.Comment) *SourcePrinter {
+	return &SourcePrinter{
+		SourceOptions: options,
+		positions:     positions,
+		comments:      comments,
+		lastLine:      0,
+		isBlockStart:  true,
+	}
+}
+
+func (p *SourcePrinter) span(node interface{}) *Span {
+	if p.positions == nil {
+		return &Span{}
+	}
+	return p.positions.FindSpan(node)
+}
+
+// ノードの直前に出力するコメントと空行
+// 元のソースで空行があった場合は、連続していても一行だけ残す
+func (p *SourcePrinter) Leading(node interface{}) []string {
+	line := p.span(node).Start.Line
+	if line == 0 {
+		return []string{}
+	}
+
+	result := p.commentsBefore(line)
+	result = append(result, p.blankLine(line)...)
+	p.lastLine = line
+	return result
+}
+
+// ブロックを閉じる前に、ブロックの末尾に残っているコメントを出力する
+// 閉じカッコの直前の空行は出力しない
+func (p *SourcePrinter) Closing(node interface{}) []string {
+	line := p.span(node).End.Line
+	if line == 0 {
+		return []string{}
+	}
+
+	result := p.commentsBefore(line)
+	p.lastLine = line
+	return result
+}
+
+// まだ出力していないすべてのコメント
+func (p *SourcePrinter) Rest() []string {
+	result := []string{}
+	for _, comment := range p.comments {
+		result = append(result, p.blankLine(comment.Line)...)
+		result = append(result, p.formatComment(comment))
+		p.lastLine = comment.Line
+	}
+	p.comments = []*token.Comment{}
+	return result
+}
+
+func (p *SourcePrinter) commentsBefore(line int) []string {
+	result := []string{}
+	for len(p.comments) > 0 && p.comments[0].Line < line {
+		comment := p.comments[0]
+		p.comments = p.comments[1:]
+		result = append(result, p.blankLine(comment.Line)...)
+		result = append(result, p.formatComment(comment))
+		p.lastLine = comment.Line
+	}
+	return result
+}
+
+func (p *SourcePrinter) blankLine(line int) []string {
+	isBlockStart := p.isBlockStart
+	p.isBlockStart = false
+	if isBlockStart || p.lastLine == 0 || line-p.lastLine <= 1 {
+		return []string{}
+	}
+	return []string{""}
+}
+
+// 複数行コメントの二行目以降は「 *」の位置を揃える
+func (p *SourcePrinter) formatComment(comment *token.Comment) string {
+	if strings.HasPrefix(comment.Value, "*") {
+		return " " + comment.Value
+	}
+	return comment.Value
+}
+
+// ブロックを開く行にあるコードの後ろのコメント
+// 一行で書かれたブロックの場合は、ブロックの最後の文のコメントとして扱う
+func (p *SourcePrinter) Opening(node interface{}) string {
+	span := p.span(node)
+	if span.Start.Line == 0 || span.Start.Line == span.End.Line {
+		return ""
+	}
+	return p.trailingAt(span.Start.Line)
+}
+
+// ノードの最終行にあるコードの後ろのコメント
+func (p *SourcePrinter) Trailing(node interface{}) string {
+	line := p.span(node).End.Line
+	if line == 0 {
+		return ""
+	}
+	return p.trailingAt(line)
+}
+
+func (p *SourcePrinter) trailingAt(line int) string {
+	p.lastLine = line
+	if len(p.comments) > 0 && p.comments[0].Line == line && p.comments[0].Trailing {
+		comment := p.comments[0]
+		p.comments = p.comments[1:]
+		return " " + comment.Value
+	}
+	return ""
+}
+
+// ブロックの開始を記録し、ブロック先頭の空行を出力しないようにする
+func (p *SourcePrinter) OpenBlock() {
+	p.isBlockStart = true
+}
+
+// ブロックの中身を一段インデントする
+func (p *SourcePrinter) Indented(lines []string) []string {
+	result := []string{}
+	for _, line := range lines {
+		if line == "" {
+			result = append(result, line)
+		} else {
+			result = append(result, p.Indent+line)
+		}
+	}
+	return result
+}
+
+// 「{ statements }」のブロックを出力する
+// 先頭行は呼び出し元で出力済みとして、中身と閉じカッコを返す
+func (p *SourcePrinter) Block(statements *Statements) []string {
+	p.OpenBlock()
+	body := statements.ToSource(p)
+	body = append(body, p.Closing(statements)...)
+
+	result := []string{}
+	result = append(result, p.Indented(body)...)
+	return result
+}
@@ -1,7 +1,6 @@
 package parsing
 
 import (
-	"../symbol"
 	"../token"
 	"fmt"
 )
@@ -30,10 +29,21 @@ func (s *Statements) ToXML() []string {
 	return result
 }
 
-func (s *Statements) ToCode() []string {
+func (s *Statements) ToSource(p *SourcePrinter) []string {
 	result := []string{}
 	for _, item := range s.Items {
-		result = append(result, item.ToCode()...)
+		result = append(result, item.ToSource(p)...)
+	}
+	return result
+}
+
+func (s *Statements) ToCode(ctx *Context) []string {
+	result := []string{}
+	for _, item := range s.Items {
+		if ctx.DebugInfo {
+			result = append(result, lineMarker(ctx.FindSpan(item).Start.Line))
+		}
+		result = append(result, item.ToCode(ctx)...)
 	}
 	return result
 }
@@ -43,12 +53,16 @@ func (s *Statements) IsStatementKeyword(token *token.Token) bool {
 		return false
 	}
 
+	// for、break、continueは拡張文法でのみキーワードとしてトークナイズされる
 	keywords := []string{
 		"let",
 		"if",
 		"while",
 		"do",
 		"return",
+		"for",
+		"break",
+		"continue",
 	}
 
 	err := NewKeyword(token).CheckKeywordValue(keywords...)
@@ -111,19 +125,37 @@ func (l *LetStatement) ToXML() []string {
 	return result
 }
 
+// let varName = expression;
+// let varName[expression] = expression;
+func (l *LetStatement) ToSource(p *SourcePrinter) []string {
+	target := ""
+	if l.VarName != nil {
+		target = l.VarName.ToSource()
+	}
+	if l.Array != nil {
+		target = l.Array.ToSource()
+	}
+
+	result := []string{}
+	result = append(result, p.Leading(l)...)
+	line := fmt.Sprintf("%s %s %s %s%s", l.StatementKeyword.Value, target, l.Equal.Value, l.Expression.ToSource(), l.Semicolon.Value)
+	result = append(result, line+p.Trailing(l))
+	return result
+}
+
 // 配列以外：let varName = expression ;
 // 配列：let varName[expression] = expression ;
-func (l *LetStatement) ToCode() []string {
+func (l *LetStatement) ToCode(ctx *Context) []string {
 	result := []string{}
 
 	// expressionを計算する
-	result = append(result, l.Expression.ToCode()...)
+	result = append(result, l.Expression.ToCode(ctx)...)
 
 	// スタックの一番上の値を、左辺(varName)にpopする
 	if l.VarName != nil {
-		findSymbol, err := symbol.GlobalSymbolTables.Find(l.VarName.Value)
+		findSymbol, err := ctx.Find(l.VarName.Value)
 		if err != nil {
-			message := fmt.Sprintf("error LetStatement.ToCode(): GlobalSymbolTables.Find: %v", err)
+			message := fmt.Sprintf("error LetStatement.ToCode(): SymbolTables.Find: %v", err)
 			return []string{message}
 		}
 
@@ -132,15 +164,15 @@ func (l *LetStatement) ToCode() []string {
 	}
 
 	if l.Array != nil {
-		findSymbol, err := symbol.GlobalSymbolTables.Find(l.Array.VarName.Value)
+		findSymbol, err := ctx.Find(l.Array.VarName.Value)
 		if err != nil {
-			message := fmt.Sprintf("error LetStatement.ToCode(): GlobalSymbolTables.Find: %v", err)
+			message := fmt.Sprintf("error LetStatement.ToCode(): SymbolTables.Find: %v", err)
 			return []string{message}
 		}
 		// 変数のアドレスをスタックに積む
 		result = append(result, fmt.Sprintf("push %s", findSymbol))
 		// 配列添字のexpressionを計算
-		result = append(result, l.Array.Expression.ToCode()...)
+		result = append(result, l.Array.Expression.ToCode(ctx)...)
 		// 代入先の配列要素のアドレスを算出
 		result = append(result, "add")
 		// スタックの一番上の値をthatにセット
@@ -206,17 +238,47 @@ func (i *IfStatement) ToXML() []string {
 	return result
 }
 
+// if (condition) {
+// } else {
+// }
+func (i *IfStatement) ToSource(p *SourcePrinter) []string {
+	result := []string{}
+	result = append(result, p.Leading(i)...)
+	result = append(result, fmt.Sprintf("%s %s%s%s %s", i.StatementKeyword.Value, i.OpeningRoundBracket.Value, i.Expression.ToSource(), i.ClosingRoundBracket.Value, i.OpeningCurlyBracket.Value)+p.Opening(i))
+	result = append(result, p.Block(i.Statements)...)
+
+	// 「else if」は後ろのif文の先頭行を「} else」に続けて、閉じカッコは後ろのif文に任せる
+	if i.ElseBlock != nil && i.ElseBlock.ElseIf != nil {
+		elseIf := i.ElseBlock.ElseIf.ToSource(p)
+		result = append(result, fmt.Sprintf("%s %s %s", i.ClosingCurlyBracket.Value, i.ElseBlock.Keyword.Value, elseIf[0]))
+		result = append(result, elseIf[1:]...)
+		return result
+	}
+
+	if i.ElseBlock != nil {
+		result = append(result, fmt.Sprintf("%s %s %s", i.ClosingCurlyBracket.Value, i.ElseBlock.Keyword.Value, i.ElseBlock.OpeningCurlyBracket.Value)+p.Opening(i.ElseBlock))
+		result = append(result, p.Block(i.ElseBlock.Statements)...)
+	}
+
+	result = append(result, i.ClosingCurlyBracket.Value+p.Trailing(i))
+	return result
+}
+
 // if (condition) { statements }
 // else { statements }
-func (i *IfStatement) ToCode() []string {
-	id := symbol.GlobalIdGenerator.Generate()
+func (i *IfStatement) ToCode(ctx *Context) []string {
+	if ctx.OptimizationLevel >= OptimizeSpeed {
+		return i.optimizedCode(ctx)
+	}
+
+	id := ctx.Generate()
 	elseLabel := fmt.Sprintf("ELSE_START_%s", id)
 	endLabel := fmt.Sprintf("IF_END_%s", id)
 
 	result := []string{}
 
 	// if文のconditionの計算
-	result = append(result, i.Expression.ToCode()...)
+	result = append(result, i.Expression.ToCode(ctx)...)
 
 	// conditionがtrueの場合、conditionは「-1」になる
 	// しかしループを抜けるか判定するif-goto文は、ゼロ以外ならジャンプしてしまう
@@ -228,7 +290,7 @@ func (i *IfStatement) ToCode() []string {
 	result = append(result, fmt.Sprintf("if-goto %s", elseLabel))
 
 	// if句の中を実行（S1の計算）
-	result = append(result, i.Statements.ToCode()...)
+	result = append(result, i.Statements.ToCode(ctx)...)
 
 	// if文を抜けるラベルにジャンプ
 	result = append(result, fmt.Sprintf("goto %s", endLabel))
@@ -238,7 +300,7 @@ func (i *IfStatement) ToCode() []string {
 
 	// else句の中を実行（S2の計算）
 	if i.ElseBlock != nil {
-		result = append(result, i.ElseBlock.Statements.ToCode()...)
+		result = append(result, i.ElseBlock.Statements.ToCode(ctx)...)
 	}
 
 	// if文から抜けるためのラベル
@@ -251,6 +313,7 @@ type ElseBlock struct {
 	*Statements
 	*OpeningCurlyBracket
 	*ClosingCurlyBracket
+	ElseIf *IfStatement // 拡張文法の「else if」の場合のif文
 }
 
 func NewElseBlock() *ElseBlock {
@@ -265,6 +328,15 @@ func (e *ElseBlock) SetStatements(statements *Statements) {
 	e.Statements = statements
 }
 
+// 「else if」は、if文だけを含むelse句として扱う
+// コード生成は通常のelse句と同じで、XMLとソースコードの出力だけが異なる
+func (e *ElseBlock) SetElseIf(ifStatement *IfStatement) {
+	statements := NewStatements()
+	statements.AddStatement(ifStatement)
+	e.Statements = statements
+	e.ElseIf = ifStatement
+}
+
 func (e *ElseBlock) CheckElseKeyword(token *token.Token) error {
 	return NewKeyword(token).Check(e.Keyword.Value)
 }
@@ -272,6 +344,10 @@ func (e *ElseBlock) CheckElseKeyword(token *token.Token) error {
 func (e *ElseBlock) ToXML() []string {
 	result := []string{}
 	result = append(result, e.Keyword.ToXML())
+	if e.ElseIf != nil {
+		result = append(result, e.ElseIf.ToXML()...)
+		return result
+	}
 	result = append(result, e.OpeningCurlyBracket.ToXML())
 	result = append(result, e.Statements.ToXML()...)
 	result = append(result, e.ClosingCurlyBracket.ToXML())
@@ -321,9 +397,24 @@ func (w *WhileStatement) ToXML() []string {
 	return result
 }
 
+// while (condition) {
+// }
+func (w *WhileStatement) ToSource(p *SourcePrinter) []string {
+	result := []string{}
+	result = append(result, p.Leading(w)...)
+	result = append(result, fmt.Sprintf("%s %s%s%s %s", w.StatementKeyword.Value, w.OpeningRoundBracket.Value, w.Expression.ToSource(), w.ClosingRoundBracket.Value, w.OpeningCurlyBracket.Value)+p.Opening(w))
+	result = append(result, p.Block(w.Statements)...)
+	result = append(result, w.ClosingCurlyBracket.Value+p.Trailing(w))
+	return result
+}
+
 // while(condition) { statements }
-func (w *WhileStatement) ToCode() []string {
-	id := symbol.GlobalIdGenerator.Generate()
+func (w *WhileStatement) ToCode(ctx *Context) []string {
+	if ctx.OptimizationLevel >= OptimizeSpeed {
+		return w.optimizedCode(ctx)
+	}
+
+	id := ctx.Generate()
 	startLabel := fmt.Sprintf("WHILE_START_%s", id)
 	endLabel := fmt.Sprintf("WHILE_END_%s", id)
 
@@ -333,7 +424,7 @@ func (w *WhileStatement) ToCode() []string {
 	result = append(result, fmt.Sprintf("label %s", startLabel))
 
 	// while文のconditionの計算
-	result = append(result, w.Expression.ToCode()...)
+	result = append(result, w.Expression.ToCode(ctx)...)
 
 	// conditionがtrueの場合、conditionは「-1」になる
 	// しかしループを抜けるか判定するif-goto文は、ゼロ以外ならジャンプしてしまう
@@ -345,7 +436,10 @@ func (w *WhileStatement) ToCode() []string {
 	result = append(result, fmt.Sprintf("if-goto %s", endLabel))
 
 	// while文の中を実行（S1の計算）
-	result = append(result, w.Statements.ToCode()...)
+	// breakはループを抜けるラベルへ、continueはwhile文のスタートへジャンプする
+	ctx.PushLoop(endLabel, startLabel)
+	result = append(result, w.Statements.ToCode(ctx)...)
+	ctx.PopLoop()
 
 	// while文のスタートに戻る
 	result = append(result, fmt.Sprintf("goto %s", startLabel))
@@ -355,6 +449,278 @@ func (w *WhileStatement) ToCode() []string {
 	return result
 }
 
+type ForStatement struct {
+	*StatementKeyword
+	Init      *Assignment // 省略できる
+	Condition *Expression // 省略した場合は常に真
+	Step      *Assignment // 省略できる
+	*Statements
+	*OpeningRoundBracket
+	*ClosingRoundBracket
+	*OpeningCurlyBracket
+	*ClosingCurlyBracket
+}
+
+var _ Statement = (*ForStatement)(nil)
+
+func NewForStatement() *ForStatement {
+	return &ForStatement{
+		StatementKeyword:    NewStatementKeyword("for"),
+		OpeningRoundBracket: ConstOpeningRoundBracket,
+		ClosingRoundBracket: ConstClosingRoundBracket,
+		OpeningCurlyBracket: ConstOpeningCurlyBracket,
+		ClosingCurlyBracket: ConstClosingCurlyBracket,
+	}
+}
+
+func (f *ForStatement) SetInit(init *Assignment) {
+	f.Init = init
+}
+
+func (f *ForStatement) SetCondition(condition *Expression) {
+	f.Condition = condition
+}
+
+func (f *ForStatement) SetStep(step *Assignment) {
+	f.Step = step
+}
+
+func (f *ForStatement) SetStatements(statements *Statements) {
+	f.Statements = statements
+}
+
+func (f *ForStatement) ToXML() []string {
+	result := []string{}
+	result = append(result, f.OpenTag())
+	result = append(result, f.StatementKeyword.ToXML())
+	result = append(result, f.OpeningRoundBracket.ToXML())
+	if f.Init != nil {
+		result = append(result, f.Init.ToXML()...)
+	}
+	result = append(result, ConstSemicolon.ToXML())
+	if f.Condition != nil {
+		result = append(result, f.Condition.ToXML()...)
+	}
+	result = append(result, ConstSemicolon.ToXML())
+	if f.Step != nil {
+		result = append(result, f.Step.ToXML()...)
+	}
+	result = append(result, f.ClosingRoundBracket.ToXML())
+	result = append(result, f.OpeningCurlyBracket.ToXML())
+	result = append(result, f.Statements.ToXML()...)
+	result = append(result, f.ClosingCurlyBracket.ToXML())
+	result = append(result, f.CloseTag())
+	return result
+}
+
+// for (init; condition; step) {
+// }
+func (f *ForStatement) ToSource(p *SourcePrinter) []string {
+	header := ""
+	if f.Init != nil {
+		header += f.Init.ToSource()
+	}
+	header += ConstSemicolon.Value
+	if f.Condition != nil {
+		header += " " + f.Condition.ToSource()
+	}
+	header += ConstSemicolon.Value
+	if f.Step != nil {
+		header += " " + f.Step.ToSource()
+	}
+
+	result := []string{}
+	result = append(result, p.Leading(f)...)
+	result = append(result, fmt.Sprintf("%s %s%s%s %s", f.StatementKeyword.Value, f.OpeningRoundBracket.Value, header, f.ClosingRoundBracket.Value, f.OpeningCurlyBracket.Value)+p.Opening(f))
+	result = append(result, p.Block(f.Statements)...)
+	result = append(result, f.ClosingCurlyBracket.Value+p.Trailing(f))
+	return result
+}
+
+// for (init; condition; step) { statements }
+// while文と同じ形にして、continueでstepを実行してから条件の判定に戻る
+func (f *ForStatement) ToCode(ctx *Context) []string {
+	id := ctx.Generate()
+	startLabel := fmt.Sprintf("FOR_START_%s", id)
+	stepLabel := fmt.Sprintf("FOR_STEP_%s", id)
+	endLabel := fmt.Sprintf("FOR_END_%s", id)
+
+	result := []string{}
+
+	// ループに入る前に一度だけ初期化する
+	if f.Init != nil {
+		result = append(result, f.Init.ToCode(ctx)...)
+	}
+
+	result = append(result, fmt.Sprintf("label %s", startLabel))
+
+	// conditionが偽ならループを抜ける
+	if f.Condition != nil {
+		if ctx.OptimizationLevel >= OptimizeSpeed {
+			result = append(result, jumpIfFalseCode(ctx, f.Condition, endLabel)...)
+		} else {
+			result = append(result, f.Condition.ToCode(ctx)...)
+			result = append(result, "not")
+			result = append(result, fmt.Sprintf("if-goto %s", endLabel))
+		}
+	}
+
+	ctx.PushLoop(endLabel, stepLabel)
+	result = append(result, f.Statements.ToCode(ctx)...)
+	ctx.PopLoop()
+
+	result = append(result, fmt.Sprintf("label %s", stepLabel))
+	if f.Step != nil {
+		result = append(result, f.Step.ToCode(ctx)...)
+	}
+	result = append(result, fmt.Sprintf("goto %s", startLabel))
+	result = append(result, fmt.Sprintf("label %s", endLabel))
+	return result
+}
+
+// for文の初期化と更新に書く、letとセミコロンを省いた代入
+// varName = expression
+// varName[expression] = expression
+type Assignment struct {
+	*VarName
+	*Array
+	*Equal
+	*Expression
+}
+
+func NewAssignment() *Assignment {
+	return &Assignment{
+		Equal: ConstEqual,
+	}
+}
+
+func (a *Assignment) SetVarName(token *token.Token) error {
+	varName, err := NewVarNameOrError(token)
+	if err != nil {
+		return err
+	}
+
+	a.VarName = varName
+	return nil
+}
+
+func (a *Assignment) SetArray(array *Array) {
+	a.Array = array
+}
+
+func (a *Assignment) SetExpression(expression *Expression) {
+	a.Expression = expression
+}
+
+func (a *Assignment) ToXML() []string {
+	result := []string{}
+	result = append(result, "<assignment>")
+	if a.VarName != nil {
+		result = append(result, a.VarName.ToXML()...)
+	}
+	if a.Array != nil {
+		result = append(result, a.Array.ToXML()...)
+	}
+	result = append(result, a.Equal.ToXML())
+	result = append(result, a.Expression.ToXML()...)
+	result = append(result, "</assignment>")
+	return result
+}
+
+func (a *Assignment) ToSource() string {
+	target := ""
+	if a.VarName != nil {
+		target = a.VarName.ToSource()
+	}
+	if a.Array != nil {
+		target = a.Array.ToSource()
+	}
+	return fmt.Sprintf("%s %s %s", target, a.Equal.Value, a.Expression.ToSource())
+}
+
+// let文と同じコードを生成する
+func (a *Assignment) ToCode(ctx *Context) []string {
+	return a.letStatement().ToCode(ctx)
+}
+
+func (a *Assignment) letStatement() *LetStatement {
+	letStatement := NewLetStatement()
+	letStatement.VarName = a.VarName
+	letStatement.SetArray(a.Array)
+	letStatement.SetExpression(a.Expression)
+	return letStatement
+}
+
+type BreakStatement struct {
+	*StatementKeyword
+	*Semicolon
+}
+
+var _ Statement = (*BreakStatement)(nil)
+
+func NewBreakStatement() *BreakStatement {
+	return &BreakStatement{
+		StatementKeyword: NewStatementKeyword("break"),
+		Semicolon:        ConstSemicolon,
+	}
+}
+
+func (b *BreakStatement) ToXML() []string {
+	return []string{b.OpenTag(), b.StatementKeyword.ToXML(), b.Semicolon.ToXML(), b.CloseTag()}
+}
+
+// break;
+func (b *BreakStatement) ToSource(p *SourcePrinter) []string {
+	result := []string{}
+	result = append(result, p.Leading(b)...)
+	result = append(result, b.StatementKeyword.Value+b.Semicolon.Value+p.Trailing(b))
+	return result
+}
+
+// 一番内側のループを抜ける
+func (b *BreakStatement) ToCode(ctx *Context) []string {
+	loop := ctx.currentLoop()
+	if loop == nil {
+		return []string{"error BreakStatement.ToCode(): break outside loop"}
+	}
+	return []string{fmt.Sprintf("goto %s", loop.breakLabel)}
+}
+
+type ContinueStatement struct {
+	*StatementKeyword
+	*Semicolon
+}
+
+var _ Statement = (*ContinueStatement)(nil)
+
+func NewContinueStatement() *ContinueStatement {
+	return &ContinueStatement{
+		StatementKeyword: NewStatementKeyword("continue"),
+		Semicolon:        ConstSemicolon,
+	}
+}
+
+func (c *ContinueStatement) ToXML() []string {
+	return []string{c.OpenTag(), c.StatementKeyword.ToXML(), c.Semicolon.ToXML(), c.CloseTag()}
+}
+
+// continue;
+func (c *ContinueStatement) ToSource(p *SourcePrinter) []string {
+	result := []string{}
+	result = append(result, p.Leading(c)...)
+	result = append(result, c.StatementKeyword.Value+c.Semicolon.Value+p.Trailing(c))
+	return result
+}
+
+// 一番内側のループの次の繰り返しに進む
+func (c *ContinueStatement) ToCode(ctx *Context) []string {
+	loop := ctx.currentLoop()
+	if loop == nil {
+		return []string{"error ContinueStatement.ToCode(): continue outside loop"}
+	}
+	return []string{fmt.Sprintf("goto %s", loop.continueLabel)}
+}
+
 type DoStatement struct {
 	*StatementKeyword
 	*SubroutineCall
@@ -384,9 +750,18 @@ func (d *DoStatement) ToXML() []string {
 	return result
 }
 
-func (d *DoStatement) ToCode() []string {
+// do subroutineCall;
+func (d *DoStatement) ToSource(p *SourcePrinter) []string {
 	result := []string{}
-	result = append(result, d.SubroutineCall.ToCode()...)
+	result = append(result, p.Leading(d)...)
+	line := fmt.Sprintf("%s %s%s", d.StatementKeyword.Value, d.SubroutineCall.ToSource(), d.Semicolon.Value)
+	result = append(result, line+p.Trailing(d))
+	return result
+}
+
+func (d *DoStatement) ToCode(ctx *Context) []string {
+	result := []string{}
+	result = append(result, d.SubroutineCall.ToCode(ctx)...)
 	result = append(result, "pop temp 0") // doステートメントでは戻り値をpopする必要がある
 	return result
 }
@@ -424,14 +799,28 @@ func (r *ReturnStatement) ToXML() []string {
 	return result
 }
 
-func (r *ReturnStatement) ToCode() []string {
+// return expression;
+func (r *ReturnStatement) ToSource(p *SourcePrinter) []string {
+	line := r.StatementKeyword.Value
+	if r.Expression != nil {
+		line += " " + r.Expression.ToSource()
+	}
+	line += r.Semicolon.Value
+
+	result := []string{}
+	result = append(result, p.Leading(r)...)
+	result = append(result, line+p.Trailing(r))
+	return result
+}
+
+func (r *ReturnStatement) ToCode(ctx *Context) []string {
 	result := []string{}
 
 	if r.Expression == nil {
 		// void型のfunctionは常にゼロを返す
 		result = append(result, "push constant 0")
 	} else {
-		result = append(result, r.Expression.ToCode()...)
+		result = append(result, r.Expression.ToCode(ctx)...)
 	}
 
 	result = append(result, r.StatementKeyword.Value)
@@ -462,7 +851,8 @@ func (s *StatementKeyword) CloseTag() string {
 
 type Statement interface {
 	ToXML() []string
-	ToCode() []string
+	ToCode(ctx *Context) []string
+	ToSource(p *SourcePrinter) []string
 	OpenTag() string
 	CloseTag() string
 }
@@ -1,22 +1,15 @@
 package parsing
 
 import (
-	"../symbol"
 	"github.com/google/go-cmp/cmp"
 	"testing"
 )
 
-func SetupTestForToCode() {
-	// デバッグフラグの無効化
-	DebugCode = false
-	symbol.DebugSymbolTables = false
-
-	// シンボルテーブルの初期化
-	symbol.GlobalSymbolTables.Reset("Testing")
-	symbol.GlobalSymbolTables.ResetSubroutine("TestRun")
-
-	// ID生成器の初期化
-	symbol.GlobalIdGenerator.Reset()
+func SetupTestForToCode() *Context {
+	// シンボルテーブルとID生成器を初期化したコンテキストを生成
+	ctx := NewContext("Testing")
+	ctx.ResetSubroutine("TestRun")
+	return ctx
 }
 
 func TestLetStatementToCode(t *testing.T) {
@@ -89,16 +82,16 @@ func TestLetStatementToCode(t *testing.T) {
 	}
 
 	// いろいろ初期化
-	SetupTestForToCode()
+	ctx := SetupTestForToCode()
 	// シンボルテーブルのセットアップ
-	symbol.GlobalSymbolTables.AddVarSymbol("foo", "int")
-	symbol.GlobalSymbolTables.AddArgSymbol("argA", "int")
-	symbol.GlobalSymbolTables.AddVarSymbol("localA", "int")
-	symbol.GlobalSymbolTables.AddVarSymbol("array", "Array")
+	ctx.AddVarSymbol("foo", "int")
+	ctx.AddArgSymbol("argA", "int")
+	ctx.AddVarSymbol("localA", "int")
+	ctx.AddVarSymbol("array", "Array")
 
 	for _, tc := range cases {
 		t.Run(tc.desc, func(t *testing.T) {
-			got := tc.letStatement.ToCode()
+			got := tc.letStatement.ToCode(ctx)
 
 			if diff := cmp.Diff(got, tc.want); diff != "" {
 				t.Errorf("failed: diff (-got +want):\n%s", diff)
@@ -204,16 +197,16 @@ func TestIfStatementToCode(t *testing.T) {
 	}
 
 	// いろいろ初期化
-	SetupTestForToCode()
+	ctx := SetupTestForToCode()
 	// シンボルテーブルのセットアップ
-	symbol.GlobalSymbolTables.AddVarSymbol("foo", "int")
+	ctx.AddVarSymbol("foo", "int")
 
 	for _, tc := range cases {
 		t.Run(tc.desc, func(t *testing.T) {
 			// IDは毎回「1」からはじめたいので、ID生成器を初期化しておく
-			symbol.GlobalIdGenerator.Reset()
+			ctx.IdGenerator.Reset()
 
-			got := tc.ifStatement.ToCode()
+			got := tc.ifStatement.ToCode(ctx)
 
 			if diff := cmp.Diff(got, tc.want); diff != "" {
 				t.Errorf("failed: diff (-got +want):\n%s", diff)
@@ -270,16 +263,16 @@ func TestWhileStatementToCode(t *testing.T) {
 	}
 
 	// いろいろ初期化
-	SetupTestForToCode()
+	ctx := SetupTestForToCode()
 	// シンボルテーブルのセットアップ
-	symbol.GlobalSymbolTables.AddVarSymbol("foo", "int")
+	ctx.AddVarSymbol("foo", "int")
 
 	for _, tc := range cases {
 		t.Run(tc.desc, func(t *testing.T) {
 			// IDは毎回「1」からはじめたいので、ID生成器を初期化しておく
-			symbol.GlobalIdGenerator.Reset()
+			ctx.IdGenerator.Reset()
 
-			got := tc.whileStatement.ToCode()
+			got := tc.whileStatement.ToCode(ctx)
 
 			if diff := cmp.Diff(got, tc.want); diff != "" {
 				t.Errorf("failed: diff (-got +want):\n%s", diff)
@@ -364,13 +357,13 @@ func TestDoStatementToCode(t *testing.T) {
 	}
 
 	// いろいろ初期化
-	SetupTestForToCode()
+	ctx := SetupTestForToCode()
 	// シンボルテーブルのセットアップ
-	symbol.GlobalSymbolTables.AddVarSymbol("foo", "int")
+	ctx.AddVarSymbol("foo", "int")
 
 	for _, tc := range cases {
 		t.Run(tc.desc, func(t *testing.T) {
-			got := tc.doStatement.ToCode()
+			got := tc.doStatement.ToCode(ctx)
 
 			if diff := cmp.Diff(got, tc.want); diff != "" {
 				t.Errorf("failed: diff (-got +want):\n%s", diff)
@@ -432,11 +425,11 @@ func TestReturnStatementToCode(t *testing.T) {
 	}
 
 	// いろいろ初期化
-	SetupTestForToCode()
+	ctx := SetupTestForToCode()
 
 	for _, tc := range cases {
 		t.Run(tc.desc, func(t *testing.T) {
-			got := tc.returnStatement.ToCode()
+			got := tc.returnStatement.ToCode(ctx)
 
 			if diff := cmp.Diff(got, tc.want); diff != "" {
 				t.Errorf("failed: diff (-got +want):\n%s", diff)
@@ -1,7 +1,6 @@
 package parsing
 
 import (
-	"../symbol"
 	"../token"
 	"fmt"
 	"github.com/pkg/errors"
@@ -29,20 +28,38 @@ func (s *SubroutineDecs) ToXML() []string {
 	return result
 }
 
-func (s *SubroutineDecs) ToCode() []string {
+// サブルーチンの間には必ず空行を一行だけ入れる
+// hasPreviousがtrueの場合は、最初のサブルーチンの前にも空行を入れる
+func (s *SubroutineDecs) ToSource(p *SourcePrinter, hasPrevious bool) []string {
+	result := []string{}
+	for i, item := range s.Items {
+		lines := item.ToSource(p)
+		for len(lines) > 0 && lines[0] == "" {
+			lines = lines[1:]
+		}
+
+		if i > 0 || hasPrevious {
+			result = append(result, "")
+		}
+		result = append(result, lines...)
+	}
+	return result
+}
+
+func (s *SubroutineDecs) ToCode(ctx *Context) []string {
 	result := []string{}
 	for _, item := range s.Items {
-		result = append(result, item.ToCode()...)
+		result = append(result, item.ToCode(ctx)...)
 	}
 	return result
 }
 
-func (s *SubroutineDecs) ToDebugCode() []string {
+func (s *SubroutineDecs) ToDebugCode(ctx *Context) []string {
 	result := []string{}
 	for _, item := range s.Items {
 		result = append(result, "")
 		result = append(result, "==================")
-		result = append(result, item.ToCode()...)
+		result = append(result, item.ToCode(ctx)...)
 	}
 	return result
 }
@@ -117,25 +134,51 @@ func (s *SubroutineDec) ToXML() []string {
 	return result
 }
 
+// function void main() { ... }
+func (s *SubroutineDec) ToSource(p *SourcePrinter) []string {
+	result := []string{}
+	result = append(result, p.Leading(s)...)
+
+	header := fmt.Sprintf("%s %s %s%s%s%s %s",
+		s.Subroutine.Value,
+		s.SubroutineType.Value,
+		s.SubroutineName.Value,
+		s.OpeningRoundBracket.Value,
+		s.ParameterList.ToSource(),
+		s.ClosingRoundBracket.Value,
+		s.SubroutineBody.OpeningCurlyBracket.Value,
+	)
+	result = append(result, header+p.Opening(s))
+	result = append(result, s.SubroutineBody.ToSource(p)...)
+	result = append(result, s.SubroutineBody.ClosingCurlyBracket.Value+p.Trailing(s))
+	return result
+}
+
 // function Main.main 0
-func (s *SubroutineDec) ToCode() []string {
+func (s *SubroutineDec) ToCode(ctx *Context) []string {
 	classPrefix := ""
 	if s.ClassName != nil {
 		classPrefix = fmt.Sprintf("%s.", s.ClassName.Value)
 	}
 	subroutineName := s.SubroutineName.Value
-	varCount := s.SubroutineBody.VarDecsLength()
+
+	// 最適化する場合は、実行されない文と使われないローカル変数を取り除いた本体からコードを生成する
+	body := s.SubroutineBody
+	if ctx.OptimizationLevel >= OptimizeSpeed {
+		body = body.eliminateDeadCode(ctx)
+	}
+	varCount := body.VarDecsLength()
 	function := fmt.Sprintf("function %s%s %d", classPrefix, subroutineName, varCount)
 
 	switch s.Subroutine.Value {
 	case "function":
 		result := []string{function}
-		result = append(result, s.SubroutineBody.ToCode()...)
+		result = append(result, body.ToCode(ctx)...)
 		return result
 	case "constructor":
 		result := []string{function}
 		// fieldの個数をスタックにプッシュ
-		fieldLength := symbol.GlobalSymbolTables.FieldLength()
+		fieldLength := ctx.FieldLength()
 		pushField := fmt.Sprintf("push constant %d", fieldLength)
 		result = append(result, pushField)
 		// スタックの一番上にある値を引数にして、メモリ確保を実行
@@ -143,19 +186,19 @@ func (s *SubroutineDec) ToCode() []string {
 		// thisにオブジェクトのベースアドレスを設定
 		result = append(result, "pop pointer 0")
 		// オブジェクトのメモリ領域を確保したらあとはfunctionと同じ
-		result = append(result, s.SubroutineBody.ToCode()...)
+		result = append(result, body.ToCode(ctx)...)
 		return result
 	case "method":
 		result := []string{function}
 		// call側でセットした隠れ引数this（ベースアドレス）をスタックに積む
 		// なおthisは、methodの引数の後ろにつける最後の要素なので注意
-		argLength := symbol.GlobalSymbolTables.ArgLength()
+		argLength := ctx.ArgLength()
 		pushArg := fmt.Sprintf("push argument %d", argLength)
 		result = append(result, pushArg)
 		// スタックの一番上の値をthis（ベースアドレス）にセット
 		result = append(result, "pop pointer 0")
 		// 隠れ引数のthisをセットしたらあとはfunctionと同じ
-		result = append(result, s.SubroutineBody.ToCode()...)
+		result = append(result, body.ToCode(ctx)...)
 		return result
 	default:
 		return []string{fmt.Sprintf("error SubroutineDec.ToCode(): invalid keyword: %s", s.Subroutine.Value)}
@@ -221,10 +264,20 @@ func (s *SubroutineBody) ToXML() []string {
 	return result
 }
 
-func (s *SubroutineBody) ToCode() []string {
+// 開きカッコと閉じカッコは呼び出し元で出力する
+func (s *SubroutineBody) ToSource(p *SourcePrinter) []string {
+	p.OpenBlock()
+	body := []string{}
+	body = append(body, s.VarDecs.ToSource(p)...)
+	body = append(body, s.Statements.ToSource(p)...)
+	body = append(body, p.Closing(s)...)
+	return p.Indented(body)
+}
+
+func (s *SubroutineBody) ToCode(ctx *Context) []string {
 	result := []string{}
 	//result = append(result, s.VarDecs.ToCode()...)
-	result = append(result, s.Statements.ToCode()...)
+	result = append(result, s.Statements.ToCode(ctx)...)
 	return result
 }
 
@@ -257,6 +310,14 @@ func (v *VarDecs) VarDecsLength() int {
 	return result
 }
 
+func (v *VarDecs) ToSource(p *SourcePrinter) []string {
+	result := []string{}
+	for _, item := range v.Items {
+		result = append(result, item.ToSource(p)...)
+	}
+	return result
+}
+
 func (v *VarDecs) ToXML() []string {
 	result := []string{}
 	for _, item := range v.Items {
@@ -280,11 +341,11 @@ func NewVarDec() *VarDec {
 	}
 }
 
-func (v *VarDec) UpdateSymbolTable() {
+func (v *VarDec) UpdateSymbolTable(ctx *Context) {
 	varType := v.VarType.Value
-	symbol.GlobalSymbolTables.AddVarSymbol(v.VarNames.First.Value, varType)
+	ctx.AddVarSymbol(v.VarNames.First.Value, varType)
 	for _, commaAndVarName := range v.VarNames.CommaAndVarNames {
-		symbol.GlobalSymbolTables.AddVarSymbol(commaAndVarName.VarName.Value, varType)
+		ctx.AddVarSymbol(commaAndVarName.VarName.Value, varType)
 	}
 }
 
@@ -302,6 +363,16 @@ func (v *VarDec) SetVarType(token *token.Token) error {
 	return nil
 }
 
+// var int x, y;
+func (v *VarDec) ToSource(p *SourcePrinter) []string {
+	result := []string{}
+	result = append(result, p.Leading(v)...)
+	declarations := v.VarNames.ToSourceDeclarations(p, v.Keyword.Value, v.VarType.Value)
+	declarations[len(declarations)-1] += p.Trailing(v)
+	result = append(result, declarations...)
+	return result
+}
+
 func (v *VarDec) ToXML() []string {
 	result := []string{}
 	result = append(result, "<varDec>")
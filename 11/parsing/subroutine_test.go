@@ -1,7 +1,6 @@
 package parsing
 
 import (
-	"../symbol"
 	"../token"
 	"github.com/google/go-cmp/cmp"
 	"testing"
@@ -65,9 +64,12 @@ func TestSubroutineDecsToCode(t *testing.T) {
 		},
 	}
 
+	// いろいろ初期化
+	ctx := SetupTestForToCode()
+
 	for _, tc := range cases {
 		t.Run(tc.desc, func(t *testing.T) {
-			got := tc.subroutineDecs.ToCode()
+			got := tc.subroutineDecs.ToCode(ctx)
 
 			if diff := cmp.Diff(got, tc.want); diff != "" {
 				t.Errorf("failed: diff (-got +want):\n%s", diff)
@@ -165,9 +167,12 @@ func TestSubroutineDecToCode(t *testing.T) {
 		},
 	}
 
+	// いろいろ初期化
+	ctx := SetupTestForToCode()
+
 	for _, tc := range cases {
 		t.Run(tc.desc, func(t *testing.T) {
-			got := tc.subroutineDec.ToCode()
+			got := tc.subroutineDec.ToCode(ctx)
 
 			if diff := cmp.Diff(got, tc.want); diff != "" {
 				t.Errorf("failed: diff (-got +want):\n%s", diff)
@@ -229,16 +234,16 @@ func TestSubroutineDecToCodeWithSymbolTable(t *testing.T) {
 	}
 
 	// いろいろ初期化
-	SetupTestForToCode()
+	ctx := SetupTestForToCode()
 	// シンボルテーブルのセットアップ
-	symbol.GlobalSymbolTables.AddFieldSymbol("fieldA", "int")
-	symbol.GlobalSymbolTables.AddFieldSymbol("fieldB", "int")
-	symbol.GlobalSymbolTables.AddArgSymbol("foo", "int")
-	symbol.GlobalSymbolTables.AddArgSymbol("bar", "int")
+	ctx.AddFieldSymbol("fieldA", "int")
+	ctx.AddFieldSymbol("fieldB", "int")
+	ctx.AddArgSymbol("foo", "int")
+	ctx.AddArgSymbol("bar", "int")
 
 	for _, tc := range cases {
 		t.Run(tc.desc, func(t *testing.T) {
-			got := tc.subroutineDec.ToCode()
+			got := tc.subroutineDec.ToCode(ctx)
 
 			if diff := cmp.Diff(got, tc.want); diff != "" {
 				t.Errorf("failed: diff (-got +want):\n%s", diff)
@@ -0,0 +1,114 @@
+package parsing
+
+// ASTを深さ優先でたどり、各ノードでvisitを呼び出す
+// visitがfalseを返した場合は、そのノードの子はたどらない
+// 宣言（ConstDec、EnumDec、ClassVarDec、VarDec、Parameter）の変数名は参照と区別するため子としてたどらない
+func Walk(node interface{}, visit func(node interface{}) bool) {
+	if node == nil || !visit(node) {
+		return
+	}
+
+	for _, child := range children(node) {
+		Walk(child, visit)
+	}
+}
+
+func children(node interface{}) []interface{} {
+	result := []interface{}{}
+	switch n := node.(type) {
+	case *Class:
+		if n.ConstDecs != nil {
+			for _, item := range n.ConstDecs.Items {
+				result = append(result, item)
+			}
+		}
+		for _, item := range n.ClassVarDecs.Items {
+			result = append(result, item)
+		}
+		for _, item := range n.SubroutineDecs.Items {
+			result = append(result, item)
+		}
+	case *ConstDec:
+		result = append(result, n.Expression)
+	case *EnumDec:
+		for _, item := range n.Items {
+			if item.Expression != nil {
+				result = append(result, item.Expression)
+			}
+		}
+	case *SubroutineDec:
+		for _, item := range n.ParameterList.ParameterItems() {
+			result = append(result, item)
+		}
+		result = append(result, n.SubroutineBody)
+	case *SubroutineBody:
+		for _, item := range n.VarDecs.Items {
+			result = append(result, item)
+		}
+		result = append(result, n.Statements)
+	case *Statements:
+		for _, item := range n.Items {
+			result = append(result, item)
+		}
+	case *LetStatement:
+		if n.VarName != nil {
+			result = append(result, n.VarName)
+		}
+		if n.Array != nil {
+			result = append(result, n.Array)
+		}
+		result = append(result, n.Expression)
+	case *IfStatement:
+		result = append(result, n.Expression, n.Statements)
+		if n.ElseBlock != nil {
+			result = append(result, n.ElseBlock)
+		}
+	case *ElseBlock:
+		result = append(result, n.Statements)
+	case *WhileStatement:
+		result = append(result, n.Expression, n.Statements)
+	case *ForStatement:
+		if n.Init != nil {
+			result = append(result, n.Init)
+		}
+		if n.Condition != nil {
+			result = append(result, n.Condition)
+		}
+		if n.Step != nil {
+			result = append(result, n.Step)
+		}
+		result = append(result, n.Statements)
+	case *Assignment:
+		if n.VarName != nil {
+			result = append(result, n.VarName)
+		}
+		if n.Array != nil {
+			result = append(result, n.Array)
+		}
+		result = append(result, n.Expression)
+	case *DoStatement:
+		result = append(result, n.SubroutineCall)
+	case *ReturnStatement:
+		if n.Expression != nil {
+			result = append(result, n.Expression)
+		}
+	case *Expression:
+		result = append(result, n.Term)
+		if n.BinaryOpTerms != nil {
+			for _, item := range n.BinaryOpTerms.Items {
+				result = append(result, item.Term)
+			}
+		}
+	case *SubroutineCall:
+		for _, item := range n.ExpressionList.ExpressionItems() {
+			result = append(result, item)
+		}
+	case *Array:
+		result = append(result, n.VarName, n.Expression)
+	case *GroupingExpression:
+		result = append(result, n.Expression)
+	case *UnaryOpTerm:
+		result = append(result, n.Term)
+	}
+	return result
+}
@@ -0,0 +1,57 @@
+package parsing
+
+import (
+	"../token"
+	"github.com/google/go-cmp/cmp"
+	"testing"
+)
+
+func TestWalk(t *testing.T) {
+	cases := []struct {
+		desc  string
+		lines []string
+		want  []string
+	}{
+		{
+			desc: "変数の参照とサブルーチン呼び出しを出現順にたどる",
+			lines: []string{
+				"class Main {",
+				"field int x;",
+				"method void run(int a) {",
+				"var Array b;",
+				"let b[a] = -(x + a);",
+				"if (x) { do b.dispose(); } else { return a; }",
+				"while (~x) { do run(x); }",
+				"return;",
+				"}",
+				"}",
+			},
+			want: []string{"b", "a", "x", "a", "x", "b.dispose", "a", "x", "run", "x"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			tokens := token.NewTokenizer(tc.lines).Tokenize()
+			class, err := NewParser(tokens, "Main").Parse()
+			if err != nil {
+				t.Fatalf("failed Parse: %+v", err)
+			}
+
+			got := []string{}
+			Walk(class, func(node interface{}) bool {
+				switch n := node.(type) {
+				case *VarName:
+					got = append(got, n.Value)
+				case *SubroutineCall:
+					got = append(got, n.SubroutineCallName.ToSource())
+				}
+				return true
+			})
+
+			if diff := cmp.Diff(got, tc.want); diff != "" {
+				t.Errorf("failed Walk: diff (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
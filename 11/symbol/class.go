@@ -24,6 +24,24 @@ func (s *ClassSymbolTable) AddFieldSymbol(name string, symbolType string) {
 	s.Add(item)
 }
 
+// 定数はスタティック変数の領域を使わずに、値をそのままScopeIndexに持つ
+func (s *ClassSymbolTable) AddConstSymbol(name string, symbolType string, value int) {
+	scope := NewSymbolScope(ConstScope, value)
+	item := NewSymbolItem(name, symbolType, scope)
+	s.Add(item)
+}
+
+// 定数の名前と値
+func (s *ClassSymbolTable) Constants() map[string]int {
+	result := map[string]int{}
+	for _, item := range s.Items {
+		if item.ScopeKind == ConstScope {
+			result[item.SymbolName.Value] = item.ScopeIndex
+		}
+	}
+	return result
+}
+
 func (s *ClassSymbolTable) StaticLength() int {
 	return s.ClassScopeIndexer.StaticIndex
 }
@@ -2,12 +2,14 @@ package symbol
 
 import "fmt"
 
-var GlobalIdGenerator = &IdGenerator{id: 0}
-
 type IdGenerator struct {
 	id int
 }
 
+func NewIdGenerator() *IdGenerator {
+	return &IdGenerator{id: 0}
+}
+
 func (i *IdGenerator) Generate() string {
 	i.id += 1
 	return fmt.Sprintf("ID_%d", i.id)
@@ -22,6 +22,13 @@ func (s *SymbolItem) ToCode() string {
 		return fmt.Sprintf("%s %d", s.ScopeKind, s.ScopeIndex)
 	case FieldScope:
 		return fmt.Sprintf("this %d", s.ScopeIndex)
+	case ConstScope:
+		// 定数はセグメントを持たないので値をそのまま積む
+		// push constantは負の数を扱えないので、負の定数は呼び出し側でnegを付ける
+		if s.ScopeIndex < 0 {
+			return fmt.Sprintf("error SymbolItem.ToCode(): negative constant = %d (%s)", s.ScopeIndex, s.String())
+		}
+		return fmt.Sprintf("%s %d", s.ScopeKind, s.ScopeIndex)
 	default:
 		return fmt.Sprintf("error SymbolItem.ToCode(): invalid ScopeKind = %d (%s)", s.ScopeIndex, s.String())
 	}
@@ -79,6 +86,7 @@ const (
 	VarScope
 	ClassScope
 	NoneScope
+	ConstScope // ScopeIndexに定数の値を持つ
 )
 
 func (s ScopeKind) String() string {
@@ -95,6 +103,8 @@ func (s ScopeKind) String() string {
 		return "class"
 	case NoneScope:
 		return "none"
+	case ConstScope:
+		return "constant"
 	default:
 		return "invalid ScopeKind"
 	}
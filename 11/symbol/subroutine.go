@@ -24,6 +24,24 @@ func (s *SubroutineSymbolTable) AddVarSymbol(name string, symbolType string) {
 	s.Add(item)
 }
 
+// usedに含まれるローカル変数だけを残して、宣言した順に番号を振り直す
+func (s *SubroutineSymbolTable) RetainVarSymbols(used map[string]bool) {
+	items := []*SymbolItem{}
+	varIndex := 0
+	for _, item := range s.Items {
+		if item.ScopeKind == VarScope {
+			if !used[item.SymbolName.Value] {
+				continue
+			}
+			item.ScopeIndex = varIndex
+			varIndex++
+		}
+		items = append(items, item)
+	}
+	s.Items = items
+	s.SubroutineScopeIndexer.VarIndex = varIndex
+}
+
 type SubroutineScopeIndexer struct {
 	ArgIndex int
 	VarIndex int
@@ -5,10 +5,6 @@ import (
 	"github.com/pkg/errors"
 )
 
-var DebugSymbolTables = true
-
-var GlobalSymbolTables = NewSymbolTables("Uninitialized")
-
 type SymbolTables struct {
 	*ClassSymbolTable
 	*SubroutineSymbolTable
@@ -53,18 +49,6 @@ func (s *SymbolTables) ResetSubroutine(subroutineName string) {
 	s.SubroutineSymbolTable = NewSubroutineSymbolTable(subroutineName)
 }
 
-func (s *SymbolTables) PrintClassSymbolTable() {
-	if DebugSymbolTables {
-		fmt.Println(s.ClassSymbolTable.String())
-	}
-}
-
-func (s *SymbolTables) PrintSubroutineSymbolTable() {
-	if DebugSymbolTables {
-		fmt.Println(s.SubroutineSymbolTable.String())
-	}
-}
-
 type SymbolTable struct {
 	Items     []*SymbolItem
 	Name      string
@@ -0,0 +1,77 @@
+package token
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"strconv"
+	"strings"
+)
+
+// 整数定値の文字列を数値に変換する
+// 10進数のほかに、「0x4000」のような16進数と「0b1010」のような2進数を受け付ける
+func ParseInteger(value string) (int, error) {
+	base := 10
+	digits := value
+	switch {
+	case strings.HasPrefix(value, "0x"), strings.HasPrefix(value, "0X"):
+		base = 16
+		digits = value[2:]
+	case strings.HasPrefix(value, "0b"), strings.HasPrefix(value, "0B"):
+		base = 2
+		digits = value[2:]
+	}
+
+	// ParseIntは符号を受け付けるので、数字だけからなることを先に確認する
+	if digits == "" || digits[0] == '+' || digits[0] == '-' {
+		message := fmt.Sprintf("error ParseInteger: invalid integer: %s", value)
+		return 0, errors.New(message)
+	}
+	result, err := strconv.ParseInt(digits, base, 64)
+	if err != nil {
+		message := fmt.Sprintf("error ParseInteger: invalid integer: %s", value)
+		return 0, errors.New(message)
+	}
+	return int(result), nil
+}
+
+// エスケープシーケンスとHackの文字コードの対応
+// Hackの文字セットには改行が128として定義されている
+// タブは定義されていないので空白にする
+var escapeChars = map[rune]int{
+	'n':  128,
+	't':  32,
+	'"':  '"',
+	'\'': '\'',
+	'\\': '\\',
+}
+
+// Hackの文字セットに含まれる文字コードか
+// 文字列定値と文字定値に書けるのは、表示できるASCII文字（32〜126）と改行（128）だけ
+func IsHackChar(code int) bool {
+	return (code >= 32 && code <= 126) || code == 128
+}
+
+// 文字列定値と文字定値の中身を、エスケープシーケンスを解釈してHackの文字コードの列に変換する
+func DecodeChars(value string) ([]int, error) {
+	result := []int{}
+	runes := []rune(value)
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != '\\' {
+			result = append(result, int(runes[i]))
+			continue
+		}
+
+		if i+1 == len(runes) {
+			message := fmt.Sprintf("error DecodeChars: unterminated escape sequence: %s", value)
+			return nil, errors.New(message)
+		}
+		i++
+		code, ok := escapeChars[runes[i]]
+		if !ok {
+			message := fmt.Sprintf("error DecodeChars: unknown escape sequence \\%c: %s", runes[i], value)
+			return nil, errors.New(message)
+		}
+		result = append(result, code)
+	}
+	return result, nil
+}
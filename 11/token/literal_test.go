@@ -0,0 +1,93 @@
+package token
+
+import (
+	"github.com/google/go-cmp/cmp"
+	"testing"
+)
+
+func TestParseInteger(t *testing.T) {
+	cases := []struct {
+		value string
+		want  int
+		ok    bool
+	}{
+		{value: "123", want: 123, ok: true},
+		{value: "0x4000", want: 16384, ok: true},
+		{value: "0X7fff", want: 32767, ok: true},
+		{value: "0b1010", want: 10, ok: true},
+		{value: "0x", ok: false},
+		{value: "0b102", ok: false},
+		{value: "0x-1", ok: false},
+		{value: "12a", ok: false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.value, func(t *testing.T) {
+			got, err := ParseInteger(tc.value)
+			if (err == nil) != tc.ok {
+				t.Fatalf("failed ParseInteger: err = %v, want ok = %v", err, tc.ok)
+			}
+			if got != tc.want {
+				t.Errorf("failed ParseInteger: got = %d, want = %d", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDecodeChars(t *testing.T) {
+	cases := []struct {
+		desc  string
+		value string
+		want  []int
+	}{
+		{
+			desc:  "エスケープを含まない",
+			value: "Hi!",
+			want:  []int{72, 105, 33},
+		},
+		{
+			desc:  "改行はHackの文字コード128になる",
+			value: `a\nb`,
+			want:  []int{97, 128, 98},
+		},
+		{
+			desc:  "引用符とバックスラッシュとタブ",
+			value: `\"\'\\\t`,
+			want:  []int{34, 39, 92, 32},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := DecodeChars(tc.value)
+			if err != nil {
+				t.Fatalf("failed DecodeChars: %+v", err)
+			}
+			if diff := cmp.Diff(got, tc.want); diff != "" {
+				t.Errorf("failed DecodeChars: diff (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDecodeCharsError(t *testing.T) {
+	cases := []struct {
+		value string
+		want  string
+	}{
+		{value: `a\qb`, want: `error DecodeChars: unknown escape sequence \q: a\qb`},
+		{value: `a\`, want: `error DecodeChars: unterminated escape sequence: a\`},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.value, func(t *testing.T) {
+			_, err := DecodeChars(tc.value)
+			if err == nil {
+				t.Fatal("failed DecodeChars: expected error")
+			}
+			if diff := cmp.Diff(err.Error(), tc.want); diff != "" {
+				t.Errorf("failed DecodeChars: diff (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
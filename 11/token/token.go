@@ -40,6 +40,14 @@ func (t *Tokens) Second() *Token {
 	return nil
 }
 
+// 先頭からoffset番目のトークン（Peek(0)はFirst()と同じ）
+func (t *Tokens) Peek(offset int) *Token {
+	if len(t.Items) > t.HeadIndex+offset {
+		return t.Items[t.HeadIndex+offset]
+	}
+	return nil
+}
+
 func (t *Tokens) setupIndex() {
 	t.HeadIndex = 0
 }
@@ -95,6 +103,30 @@ func (t *Tokens) DebugForError() string {
 type Token struct {
 	Value     string
 	TokenType TokenType
+	Position
+}
+
+// ソースファイル上の位置（1始まり）
+// 位置情報を持たないトークンの場合はゼロ値になる
+type Position struct {
+	Line   int
+	Column int
+}
+
+func NewPosition(line int, column int) Position {
+	return Position{Line: line, Column: column}
+}
+
+// ソースファイル上のコメント
+// Trailingはコードの後ろに書かれた一行コメントの場合にtrueになる
+type Comment struct {
+	Value    string
+	Trailing bool
+	Position
+}
+
+func NewComment(value string, trailing bool, position Position) *Comment {
+	return &Comment{Value: value, Trailing: trailing, Position: position}
 }
 
 type TokenType int
@@ -106,6 +138,7 @@ const (
 	TokenIntConst
 	TokenStringConst
 	TokenIdentifier
+	TokenCharConst
 )
 
 func NewToken(value string, tokenType TokenType) *Token {
@@ -164,6 +197,11 @@ func (t *Token) CheckStringConstant() error {
 	return t.CheckTokenType(TokenStringConst, tokenName)
 }
 
+func (t *Token) CheckCharConstant() error {
+	tokenName := fmt.Sprintf("CharConstant '%s'", t.Value)
+	return t.CheckTokenType(TokenCharConst, tokenName)
+}
+
 func (t *Token) CheckTokenType(tokenType TokenType, tokenName string) error {
 	if t.TokenType == tokenType {
 		return nil
@@ -204,6 +242,8 @@ func (t *Token) tokenTypeString() string {
 		return "stringConstant"
 	case TokenIdentifier:
 		return "identifier"
+	case TokenCharConst:
+		return "charConstant"
 	default:
 		return "invalid"
 	}
@@ -1,13 +1,14 @@
 package token
 
 import (
-	"strconv"
 	"strings"
 )
 
 type Tokenizer struct {
-	lines  []string
-	tokens *Tokens
+	lines     []string
+	positions []Position
+	tokens    *Tokens
+	extended  bool
 }
 
 func NewTokenizer(lines []string) *Tokenizer {
@@ -15,14 +16,52 @@ func NewTokenizer(lines []string) *Tokenizer {
 	return &Tokenizer{lines: lines, tokens: tokens}
 }
 
+// 各行の先頭位置を指定すると、トークンにソースファイル上の位置情報を付与する
+func NewTokenizerWithPositions(lines []string, positions []Position) *Tokenizer {
+	tokenizer := NewTokenizer(lines)
+	tokenizer.positions = positions
+	return tokenizer
+}
+
+// 拡張文法のキーワード（for、break、continue）をキーワードとして扱い、文字定値とエスケープシーケンスを解釈する
+// 拡張文法を使わない場合は、これらは識別子や書かれたままの文字なので従来のソースはそのままコンパイルできる
+func (t *Tokenizer) SetExtended(extended bool) {
+	t.extended = extended
+}
+
 func (t *Tokenizer) Tokenize() *Tokens {
-	for _, line := range t.lines {
+	for i, line := range t.lines {
 		items := t.tokenizeLine(line)
+		if i < len(t.positions) {
+			t.setupPositions(line, t.positions[i], items)
+		}
 		t.tokens.Add(items)
 	}
 	return t.tokens
 }
 
+// 行内のトークンを先頭から順に探して、トークンの位置を算出する
+func (t *Tokenizer) setupPositions(line string, start Position, items []*Token) {
+	cursor := 0
+	for _, item := range items {
+		value := item.Value
+		if item.TokenType == TokenStringConst {
+			value = "\"" + value + "\""
+		}
+		if item.TokenType == TokenCharConst {
+			value = "'" + value + "'"
+		}
+
+		index := strings.Index(line[cursor:], value)
+		if index < 0 {
+			continue
+		}
+
+		item.Position = NewPosition(start.Line, start.Column+cursor+index)
+		cursor += index + len(value)
+	}
+}
+
 func (t *Tokenizer) tokenizeLine(line string) []*Token {
 	// 半角空白で文字列を分割
 	splitBySpace := t.splitBySpaces(line)
@@ -43,28 +82,63 @@ func (t *Tokenizer) tokenizeLine(line string) []*Token {
 }
 
 func (t *Tokenizer) splitBySpaces(line string) []string {
-	// 「"」が含まれない場合は文字列定値が含まれないので、何も考えず半角空白で分割する
-	if !strings.Contains(line, "\"") {
+	// 文字定値は拡張文法でだけ使える
+	quotes := "\""
+	if t.extended {
+		quotes = "\"'"
+	}
+	// 「"」と「'」が含まれない場合は文字列定値と文字定値が含まれないので、何も考えず半角空白で分割する
+	if !strings.ContainsAny(line, quotes) {
 		return strings.Fields(line)
 	}
 
-	// 「"」が含まれる場合は文字列定値が含まれる
-	// string constant内の空白は分割してはいけないため,
-	// 最初に「"」で分割して、最初と最後の要素をさらに半角空白で分割する
+	// 定値内の空白は分割してはいけないため、定値の外側だけを半角空白で分割する
 	// たとえば「foo = "test string";」は「foo」「=」「test string」「;」に分割される
+	// 定値の中の「\"」や「\'」は閉じ記号とみなさない
 	result := []string{}
-	splitByDoubleQuote := strings.Split(line, "\"")
-	result = append(result, strings.Fields(splitByDoubleQuote[0])...)
-	result = append(result, stringConstMarker+splitByDoubleQuote[1])
-	result = append(result, strings.Fields(splitByDoubleQuote[2])...)
+	start := 0
+	for i := 0; i < len(line); i++ {
+		quote := line[i]
+		if strings.IndexByte(quotes, quote) < 0 {
+			continue
+		}
+
+		result = append(result, strings.Fields(line[start:i])...)
+		end := t.closingQuote(line, i+1, quote)
+		marker := stringConstMarker
+		if quote == '\'' {
+			marker = charConstMarker
+		}
+		result = append(result, marker+line[i+1:end])
+		i = end
+		start = end + 1
+	}
+	if start < len(line) {
+		result = append(result, strings.Fields(line[start:])...)
+	}
 	return result
 }
 
+// start以降で、エスケープされていない閉じ記号の位置を返す
+// エスケープシーケンスは拡張文法でだけ使えるので、それ以外では最初の閉じ記号で閉じる
+// 閉じ記号がない場合は行末までを定値とみなす
+func (t *Tokenizer) closingQuote(line string, start int, quote byte) int {
+	for i := start; i < len(line); i++ {
+		switch {
+		case line[i] == '\\' && t.extended:
+			i++
+		case line[i] == quote:
+			return i
+		}
+	}
+	return len(line)
+}
+
 func (t *Tokenizer) splitBySymbols(line string) []string {
 	result := []string{line}
 
-	// StringConstの場合はシンボルで分割しない
-	if strings.HasPrefix(line, stringConstMarker) {
+	// StringConstとCharConstの場合はシンボルで分割しない
+	if strings.HasPrefix(line, stringConstMarker) || strings.HasPrefix(line, charConstMarker) {
 		return result
 	}
 
@@ -136,6 +210,9 @@ func (t *Tokenizer) tokenizeWord(word string) *Token {
 		return NewToken(word, TokenSymbol)
 	case t.isIntConst(word):
 		return NewToken(word, TokenIntConst)
+	case t.isCharConst(word):
+		deletedMarker := word[len(charConstMarker):]
+		return NewToken(deletedMarker, TokenCharConst)
 	case t.isStringConst(word):
 		deletedMarker := word[len(stringConstMarker):]
 		return NewToken(deletedMarker, TokenStringConst)
@@ -145,6 +222,9 @@ func (t *Tokenizer) tokenizeWord(word string) *Token {
 }
 
 func (t *Tokenizer) isKeyword(word string) bool {
+	if t.extended && t.contains(word, extendedKeywordElements) {
+		return true
+	}
 	return t.contains(word, keywordElements)
 }
 
@@ -153,7 +233,7 @@ func (t *Tokenizer) isSymbol(word string) bool {
 }
 
 func (t *Tokenizer) isIntConst(word string) bool {
-	_, err := strconv.Atoi(word)
+	_, err := ParseInteger(word)
 	return err == nil
 }
 
@@ -161,9 +241,16 @@ func (t *Tokenizer) isStringConst(word string) bool {
 	return strings.Contains(word, stringConstMarker)
 }
 
+func (t *Tokenizer) isCharConst(word string) bool {
+	return strings.HasPrefix(word, charConstMarker)
+}
+
 // 文字列定値であることを示すマーカー
 const stringConstMarker = "\"\"\""
 
+// 文字定値であることを示すマーカー
+const charConstMarker = "'''"
+
 func (t *Tokenizer) contains(value string, items []string) bool {
 	for _, item := range items {
 		if item == value {
@@ -197,6 +284,15 @@ var keywordElements = []string{
 	"return",
 }
 
+// 拡張文法でのみキーワードになる単語
+var extendedKeywordElements = []string{
+	"for",
+	"break",
+	"continue",
+	"const",
+	"enum",
+}
+
 var symbolElements = []string{
 	"{",
 	"}",
@@ -193,9 +193,10 @@ func TestTokenizerTokenizeLine(t *testing.T) {
 
 func TestTokenizerSplitBySpaces(t *testing.T) {
 	cases := []struct {
-		desc string
-		line string
-		want []string
+		desc     string
+		line     string
+		extended bool
+		want     []string
 	}{
 		{
 			desc: "文字列定値を含まない",
@@ -227,11 +228,56 @@ func TestTokenizerSplitBySpaces(t *testing.T) {
 				";",
 			},
 		},
+		{
+			desc:     "拡張文法ではエスケープした引用符を含む文字列定値",
+			line:     `do Output.printString("say \"hi\"");`,
+			extended: true,
+			want: []string{
+				"do",
+				"Output.printString(",
+				stringConstMarker + `say \"hi\"`,
+				");",
+			},
+		},
+		{
+			desc:     "拡張文法では文字定値と複数の文字列定値を含む",
+			line:     `let c = 'a'; do f("x y", ' ');`,
+			extended: true,
+			want: []string{
+				"let",
+				"c",
+				"=",
+				charConstMarker + "a",
+				";",
+				"do",
+				"f(",
+				stringConstMarker + "x y",
+				",",
+				charConstMarker + " ",
+				");",
+			},
+		},
+		{
+			desc: "拡張文法でなければ「\\」はエスケープではなく、「'」は文字定値ではない",
+			line: `let s = "C:\"; let c = 'a';`,
+			want: []string{
+				"let",
+				"s",
+				"=",
+				stringConstMarker + `C:\`,
+				";",
+				"let",
+				"c",
+				"=",
+				"'a';",
+			},
+		},
 	}
 
 	for _, tc := range cases {
 		t.Run(tc.desc, func(t *testing.T) {
 			tokenizer := NewTokenizer([]string{})
+			tokenizer.SetExtended(tc.extended)
 			got := tokenizer.splitBySpaces(tc.line)
 
 			if diff := cmp.Diff(got, tc.want); diff != "" {
@@ -470,6 +516,21 @@ func TestTokenizerTokenizeWord(t *testing.T) {
 			word: "foo",
 			want: NewToken("foo", TokenIdentifier),
 		},
+		{
+			desc: "16進数の数字定値",
+			word: "0x4000",
+			want: NewToken("0x4000", TokenIntConst),
+		},
+		{
+			desc: "2進数の数字定値",
+			word: "0b1010",
+			want: NewToken("0b1010", TokenIntConst),
+		},
+		{
+			desc: "文字定値",
+			word: charConstMarker + `\n`,
+			want: NewToken(`\n`, TokenCharConst),
+		},
 	}
 
 	for _, tc := range cases {
@@ -483,3 +544,98 @@ func TestTokenizerTokenizeWord(t *testing.T) {
 		})
 	}
 }
+
+func TestTokenizerTokenizeWordExtended(t *testing.T) {
+	cases := []struct {
+		desc     string
+		word     string
+		extended bool
+		want     *Token
+	}{
+		{
+			desc:     "拡張文法ではforはキーワード",
+			word:     "for",
+			extended: true,
+			want:     NewToken("for", TokenKeyword),
+		},
+		{
+			desc:     "拡張文法でなければforは識別子",
+			word:     "for",
+			extended: false,
+			want:     NewToken("for", TokenIdentifier),
+		},
+		{
+			desc:     "拡張文法ではcontinueはキーワード",
+			word:     "continue",
+			extended: true,
+			want:     NewToken("continue", TokenKeyword),
+		},
+		{
+			desc:     "拡張文法ではconstはキーワード",
+			word:     "const",
+			extended: true,
+			want:     NewToken("const", TokenKeyword),
+		},
+		{
+			desc:     "拡張文法でなければenumは識別子",
+			word:     "enum",
+			extended: false,
+			want:     NewToken("enum", TokenIdentifier),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			tokenizer := NewTokenizer([]string{})
+			tokenizer.SetExtended(tc.extended)
+			got := tokenizer.tokenizeWord(tc.word)
+
+			if diff := cmp.Diff(got, tc.want); diff != "" {
+				t.Errorf("failed: diff (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestTokenizerTokenizeWithPositions(t *testing.T) {
+	cases := []struct {
+		desc      string
+		lines     []string
+		positions []Position
+		want      []Position
+	}{
+		{
+			desc:      "行の開始位置からトークンの位置を算出",
+			lines:     []string{"let s = \"a b\";", "do f(x);"},
+			positions: []Position{NewPosition(3, 5), NewPosition(5, 9)},
+			want: []Position{
+				NewPosition(3, 5),
+				NewPosition(3, 9),
+				NewPosition(3, 11),
+				NewPosition(3, 13),
+				NewPosition(3, 18),
+				NewPosition(5, 9),
+				NewPosition(5, 12),
+				NewPosition(5, 13),
+				NewPosition(5, 14),
+				NewPosition(5, 15),
+				NewPosition(5, 16),
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			tokens := NewTokenizerWithPositions(tc.lines, tc.positions).Tokenize()
+
+			got := []Position{}
+			for _, token := range tokens.Items {
+				got = append(got, token.Position)
+			}
+
+			if diff := cmp.Diff(got, tc.want); diff != "" {
+				t.Errorf("failed Position: diff (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"../06/assembler"
+	"./emulator"
+	"bufio"
+	"fmt"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Hackの命令は15ビットのアドレスしか扱えないので、ROMに入る命令数には上限がある
+const MaxROMSize = 32768
+
+// OSと一緒にコンパイルしたHackのプログラム
+type Program struct {
+	ROM    []uint16
+	Labels map[string]int // アセンブラのラベルとROMアドレスの対応
+}
+
+// 11のコンパイラ、08のVMトランスレータ、06のアセンブラを順に実行して、
+// JackのプログラムをOSと一緒に.hackファイルまで変換する
+type Builder struct {
+	root            string   // リポジトリのルートディレクトリ
+	tools           string   // ビルドした各ツールの置き場所
+	compilerOptions []string // コンパイラに渡すオプション（-O1など）
+}
+
+func NewBuilder(root string, tools string) *Builder {
+	return &Builder{root: root, tools: tools, compilerOptions: []string{}}
+}
+
+func (b *Builder) SetCompilerOptions(options ...string) {
+	b.compilerOptions = options
+}
+
+var builderTools = map[string]string{
+	"compiler":   "11",
+	"translator": "08",
+	"assembler":  "06",
+}
+
+// 各ツールをビルドする
+func (b *Builder) Setup() error {
+	for name, dir := range builderTools {
+		output := filepath.Join(b.tools, name)
+		if err := b.run(b.root, "go", "build", "-o", output, "./"+dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// srcDirのJackファイルとOSのJackファイルをworkDirにコピーして変換する
+// srcDirにOSと同じ名前のクラスがある場合は、srcDirのクラスを使う
+func (b *Builder) Build(srcDir string, workDir string) (*Program, error) {
+	if err := b.Compile(srcDir, workDir, true); err != nil {
+		return nil, err
+	}
+	if err := b.run(workDir, filepath.Join(b.tools, "translator"), workDir); err != nil {
+		return nil, err
+	}
+
+	// アセンブラはカレントディレクトリに.hackファイルを書き込む
+	name := filepath.Base(strings.TrimSuffix(workDir, "/"))
+	asmFile := filepath.Join(workDir, name+".asm")
+	if err := b.run(workDir, filepath.Join(b.tools, "assembler"), asmFile); err != nil {
+		return nil, err
+	}
+
+	labels, size, err := readLabels(asmFile)
+	if err != nil {
+		return nil, err
+	}
+	if size > MaxROMSize {
+		message := fmt.Sprintf("error Build: program too large: %d instructions (max %d)", size, MaxROMSize)
+		return nil, errors.New(message)
+	}
+
+	computer, err := emulator.LoadHackFile(filepath.Join(workDir, name+".hack"))
+	if err != nil {
+		return nil, err
+	}
+	return &Program{ROM: computer.ROM, Labels: labels}, nil
+}
+
+// srcDirのJackファイルをworkDirにコピーして.vmファイルにコンパイルする
+// withOSがfalseの場合はOSのクラスを含めないので、OSの関数は全て組み込み関数で実行する
+func (b *Builder) Compile(srcDir string, workDir string, withOS bool) error {
+	if withOS {
+		if err := b.copyJackFiles(filepath.Join(b.root, "12"), workDir); err != nil {
+			return err
+		}
+	}
+	if err := b.copyJackFiles(srcDir, workDir); err != nil {
+		return err
+	}
+	args := append(append([]string{}, b.compilerOptions...), workDir)
+	return b.run(workDir, filepath.Join(b.tools, "compiler"), args...)
+}
+
+func (b *Builder) copyJackFiles(srcDir string, destDir string) error {
+	files, err := filepath.Glob(filepath.Join(srcDir, "*.jack"))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(filepath.Join(destDir, filepath.Base(file)), content, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 各ツールは進捗を標準出力に書き出すので、失敗したときだけエラーに含める
+func (b *Builder) run(dir string, name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+		if len(lines) > 10 {
+			lines = lines[len(lines)-10:]
+		}
+		return errors.Wrapf(err, "%s %s:\n%s", filepath.Base(name), strings.Join(args, " "), strings.Join(lines, "\n"))
+	}
+	return nil
+}
+
+// OSを含まない.asmファイルか.hackファイルを読み込む（04/fillなど）
+// .asmファイルはアセンブルして、ラベルも使えるようにする
+func LoadHackProgram(filename string) (*Program, error) {
+	if filepath.Ext(filename) == ".hack" {
+		computer, err := emulator.LoadHackFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		return &Program{ROM: computer.ROM, Labels: map[string]int{}}, nil
+	}
+
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	lines := []*string{}
+	for _, line := range strings.Split(string(content), "\n") {
+		line := line
+		lines = append(lines, &line)
+	}
+	hack, symbols, err := assembler.Assemble(lines)
+	if err != nil {
+		return nil, errors.WithMessage(err, filename)
+	}
+	rom, err := emulator.ParseHack(hack)
+	if err != nil {
+		return nil, errors.WithMessage(err, filename)
+	}
+	return &Program{ROM: rom, Labels: symbols.Labels()}, nil
+}
+
+// アセンブラと同じ規則でラベルのROMアドレスを求める
+func readLabels(asmFile string) (map[string]int, int, error) {
+	file, err := os.Open(asmFile)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer file.Close()
+
+	labels := map[string]int{}
+	address := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if index := strings.Index(line, "//"); index >= 0 {
+			line = line[:index]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "(") {
+			labels[line[1:len(line)-1]] = address
+			continue
+		}
+		address++
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, 0, err
+	}
+	return labels, address, nil
+}
@@ -0,0 +1,211 @@
+package debugger
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const Help = `break <address|label>     ブレークポイントを設定する (b)
+delete <address|label>    ブレークポイントを削除する (d)
+watch <address|symbol>    RAMの値が変わったら止める (w)
+unwatch <address|symbol>  ウォッチポイントを削除する
+step [n]                  n命令実行する (s)
+next                      VMのcallなら戻るまで実行し、それ以外は1命令実行する (n)
+continue [n]              ブレークポイントかウォッチポイントまで、最大n命令実行する (c)
+print <target> [count]    A、D、M、PCかRAMの値を表示する (p)
+set <target> <value>      A、D、M、PCかRAMの値を書き換える
+expect <target> <value>   値が違ったらエラーにする
+info                      レジスタとブレークポイント、ウォッチポイントを表示する (i)
+echo <text>               文字列を表示する`
+
+var commandAliases = map[string]string{
+	"b": "break",
+	"d": "delete",
+	"w": "watch",
+	"s": "step",
+	"n": "next",
+	"c": "continue",
+	"p": "print",
+	"i": "info",
+}
+
+// スクリプトを1行ずつ実行する
+// 空行と「//」以降は無視し、最初のエラーで止める
+func (d *Debugger) RunScript(src string) error {
+	for i, line := range strings.Split(src, "\n") {
+		if err := d.Execute(line); err != nil {
+			return errors.WithMessage(err, fmt.Sprintf("line %d", i+1))
+		}
+	}
+	return nil
+}
+
+// コマンドを1つ実行する
+func (d *Debugger) Execute(line string) error {
+	if index := strings.Index(line, "//"); index >= 0 {
+		line = line[:index]
+	}
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil
+	}
+	name, args := fields[0], fields[1:]
+	if alias, ok := commandAliases[name]; ok {
+		name = alias
+	}
+
+	switch name {
+	case "break", "delete", "watch", "unwatch":
+		if len(args) == 0 {
+			return errors.New(fmt.Sprintf("%s expects at least 1 argument", name))
+		}
+		for _, arg := range args {
+			if err := d.setPoint(name, arg); err != nil {
+				return err
+			}
+		}
+	case "step":
+		n, err := optionalCount(args, 1)
+		if err != nil {
+			return err
+		}
+		return d.report(d.Step(n))
+	case "next":
+		return d.report(d.Next())
+	case "continue":
+		n, err := optionalCount(args, d.MaxCycles)
+		if err != nil {
+			return err
+		}
+		return d.report(d.Continue(n))
+	case "print":
+		return d.print(args)
+	case "set", "expect":
+		if len(args) != 2 {
+			return errors.New(fmt.Sprintf("%s expects 2 arguments, but got %d", name, len(args)))
+		}
+		value, err := parseValue(args[1])
+		if err != nil {
+			return err
+		}
+		if name == "set" {
+			return d.SetValue(args[0], value)
+		}
+		return d.expect(args[0], value)
+	case "info":
+		d.info()
+	case "echo":
+		fmt.Fprintln(d.out, strings.Join(args, " "))
+	case "help":
+		fmt.Fprintln(d.out, Help)
+	default:
+		return errors.New(fmt.Sprintf("unknown command %s", name))
+	}
+	return nil
+}
+
+func (d *Debugger) setPoint(name string, target string) error {
+	switch name {
+	case "break":
+		return d.Break(target)
+	case "delete":
+		return d.Delete(target)
+	case "watch":
+		return d.Watch(target)
+	default:
+		return d.Unwatch(target)
+	}
+}
+
+func optionalCount(args []string, defaultCount int) (int, error) {
+	if len(args) == 0 {
+		return defaultCount, nil
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n <= 0 {
+		return 0, errors.New(fmt.Sprintf("invalid count %s", args[0]))
+	}
+	return n, nil
+}
+
+// 10進数の値を16ビットの符号付き整数として読む
+// 0xから始まる場合は16進数として読み、32768以上は負の数として扱う
+func parseValue(s string) (int16, error) {
+	value, err := strconv.ParseInt(s, 0, 32)
+	if err != nil || value < -32768 || value > 65535 {
+		return 0, errors.New(fmt.Sprintf("invalid value %s", s))
+	}
+	return int16(value), nil
+}
+
+// 止まった理由と、次に実行する命令を表示する
+func (d *Debugger) report(message string, err error) error {
+	if err != nil {
+		return err
+	}
+	if message != "" {
+		fmt.Fprintln(d.out, message)
+	}
+	fmt.Fprintf(d.out, "=> %s\n", d.location(d.Computer.PC))
+	return nil
+}
+
+func (d *Debugger) print(args []string) error {
+	if len(args) == 0 || len(args) > 2 {
+		return errors.New(fmt.Sprintf("print expects 1 or 2 arguments, but got %d", len(args)))
+	}
+	target := args[0]
+	count, err := optionalCount(args[1:], 1)
+	if err != nil {
+		return err
+	}
+	if count == 1 {
+		value, err := d.Value(target)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(d.out, "%s = %d\n", target, value)
+		return nil
+	}
+
+	// 個数を指定した場合は、そのアドレスから続くRAMの値を表示する
+	address, err := d.ramAddress(target)
+	if err != nil {
+		return err
+	}
+	for i := address; i < address+count && i < len(d.Computer.RAM); i++ {
+		fmt.Fprintf(d.out, "RAM[%d] = %d\n", i, d.Computer.RAM[i])
+	}
+	return nil
+}
+
+func (d *Debugger) expect(target string, want int16) error {
+	got, err := d.Value(target)
+	if err != nil {
+		return err
+	}
+	if got != want {
+		return errors.New(fmt.Sprintf("expect %s: got %d, want %d", target, got, want))
+	}
+	return nil
+}
+
+func (d *Debugger) info() {
+	c := d.Computer
+	fmt.Fprintf(d.out, "PC=%d A=%d D=%d Cycles=%d\n", c.PC, c.A, c.D, c.Cycles)
+
+	addresses := []int{}
+	for address := range d.breakpoints {
+		addresses = append(addresses, address)
+	}
+	sort.Ints(addresses)
+	for _, address := range addresses {
+		fmt.Fprintf(d.out, "Breakpoint %s\n", d.location(address))
+	}
+	for _, watchpoint := range d.watchpoints {
+		fmt.Fprintf(d.out, "Watchpoint %s (RAM[%d]) = %d\n", watchpoint.Name, watchpoint.Address, watchpoint.Value)
+	}
+}
@@ -0,0 +1,354 @@
+package debugger
+
+import (
+	"../../06/assembler"
+	"../emulator"
+	"bufio"
+	"fmt"
+	"github.com/pkg/errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// continueとnextで実行する最大命令数のデフォルト
+const DefaultMaxCycles = 10000000
+
+// VMトランスレータがcallの戻り先に付けるラベルの接頭辞
+const ReturnAddressPrefix = "RETURN-ADDRESS$"
+
+// RAMの値が変わったら止めるアドレス
+type Watchpoint struct {
+	Name    string
+	Address int
+	Value   int16
+}
+
+type label struct {
+	name    string
+	address int
+}
+
+// Hackのプログラムを命令単位で実行するデバッガ
+// ブレークポイントはROMのアドレスかラベル、ウォッチポイントはRAMのアドレスかシンボルで指定する
+// シンボルは06のアセンブラのシンボルテーブルから引くので、.hackファイルでは定義済みシンボルだけが使える
+type Debugger struct {
+	Computer    *emulator.Computer
+	MaxCycles   int
+	symbols     *assembler.SymbolTable
+	labels      []*label // ROMアドレス順
+	breakpoints map[int]bool
+	watchpoints []*Watchpoint
+	out         io.Writer
+}
+
+func NewDebugger(computer *emulator.Computer, symbols *assembler.SymbolTable, out io.Writer) *Debugger {
+	labels := []*label{}
+	for name, address := range symbols.Labels() {
+		labels = append(labels, &label{name: name, address: address})
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		if labels[i].address != labels[j].address {
+			return labels[i].address < labels[j].address
+		}
+		return labels[i].name < labels[j].name
+	})
+
+	return &Debugger{
+		Computer:    computer,
+		MaxCycles:   DefaultMaxCycles,
+		symbols:     symbols,
+		labels:      labels,
+		breakpoints: map[int]bool{},
+		watchpoints: []*Watchpoint{},
+		out:         out,
+	}
+}
+
+// .hackファイルか.asmファイルを読み込む
+// .asmファイルはアセンブルして、ラベルと変数のシンボルも使えるようにする
+func LoadFile(filename string, out io.Writer) (*Debugger, error) {
+	if filepath.Ext(filename) == ".hack" {
+		computer, err := emulator.LoadHackFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		return NewDebugger(computer, assembler.NewSymbolTable(), out), nil
+	}
+
+	lines, err := readLines(filename)
+	if err != nil {
+		return nil, err
+	}
+	hack, symbols, err := assembler.Assemble(lines)
+	if err != nil {
+		return nil, errors.WithMessage(err, filename)
+	}
+	rom, err := emulator.ParseHack(hack)
+	if err != nil {
+		return nil, errors.WithMessage(err, filename)
+	}
+	return NewDebugger(emulator.NewComputer(rom), symbols, out), nil
+}
+
+func readLines(filename string) ([]*string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer file.Close()
+
+	lines := []*string{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		lines = append(lines, &line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return lines, nil
+}
+
+// ROMのアドレスかラベルをROMのアドレスに変換する
+func (d *Debugger) romAddress(target string) (int, error) {
+	if address, err := strconv.Atoi(target); err == nil {
+		if address < 0 || address >= len(d.Computer.ROM) {
+			return 0, errors.New(fmt.Sprintf("ROM address out of range: %d", address))
+		}
+		return address, nil
+	}
+	address, ok := d.symbols.Labels()[target]
+	if !ok {
+		return 0, errors.New(fmt.Sprintf("unknown label %s", target))
+	}
+	return address, nil
+}
+
+// RAMのアドレスかシンボルをRAMのアドレスに変換する
+// ラベルはROMのアドレスなので使えない
+func (d *Debugger) ramAddress(target string) (int, error) {
+	if address, err := strconv.Atoi(target); err == nil {
+		if address < 0 || address >= emulator.RAMSize {
+			return 0, errors.New(fmt.Sprintf("RAM address out of range: %d", address))
+		}
+		return address, nil
+	}
+	if _, ok := d.symbols.Labels()[target]; ok {
+		return 0, errors.New(fmt.Sprintf("%s is a label, not a RAM address", target))
+	}
+	address, ok := d.symbols.Lookup(target)
+	if !ok {
+		return 0, errors.New(fmt.Sprintf("unknown symbol %s", target))
+	}
+	return address, nil
+}
+
+func (d *Debugger) Break(target string) error {
+	address, err := d.romAddress(target)
+	if err != nil {
+		return err
+	}
+	d.breakpoints[address] = true
+	return nil
+}
+
+func (d *Debugger) Delete(target string) error {
+	address, err := d.romAddress(target)
+	if err != nil {
+		return err
+	}
+	if !d.breakpoints[address] {
+		return errors.New(fmt.Sprintf("no breakpoint at %s", target))
+	}
+	delete(d.breakpoints, address)
+	return nil
+}
+
+func (d *Debugger) Watch(target string) error {
+	address, err := d.ramAddress(target)
+	if err != nil {
+		return err
+	}
+	d.watchpoints = append(d.watchpoints, &Watchpoint{Name: target, Address: address, Value: d.Computer.RAM[address]})
+	return nil
+}
+
+func (d *Debugger) Unwatch(target string) error {
+	address, err := d.ramAddress(target)
+	if err != nil {
+		return err
+	}
+	for i, watchpoint := range d.watchpoints {
+		if watchpoint.Address == address {
+			d.watchpoints = append(d.watchpoints[:i], d.watchpoints[i+1:]...)
+			return nil
+		}
+	}
+	return errors.New(fmt.Sprintf("no watchpoint at %s", target))
+}
+
+// 最大maxCycles命令まで実行し、止まった理由を返す
+// untilがtrueを返した場合と、maxCycles命令を実行し終えた場合は空文字列を返す
+// 最初の命令はブレークポイントで止めないので、ブレークポイントで止まった後も続けて実行できる
+func (d *Debugger) run(maxCycles int, until func() bool) (string, error) {
+	c := d.Computer
+	for i := 0; i < maxCycles; i++ {
+		if i > 0 && until != nil && until() {
+			return "", nil
+		}
+		if i > 0 && d.breakpoints[c.PC] {
+			return fmt.Sprintf("Breakpoint at %s", d.location(c.PC)), nil
+		}
+		if c.IsHalted() {
+			return fmt.Sprintf("Halted at %d", c.PC), nil
+		}
+		if err := c.Step(); err != nil {
+			return "", err
+		}
+		for _, watchpoint := range d.watchpoints {
+			value := c.RAM[watchpoint.Address]
+			if value != watchpoint.Value {
+				message := fmt.Sprintf("Watchpoint %s (RAM[%d]): %d -> %d", watchpoint.Name, watchpoint.Address, watchpoint.Value, value)
+				watchpoint.Value = value
+				return message, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// n命令だけ実行する
+// 途中でウォッチポイントの値が変わった場合は、そこで止まる
+func (d *Debugger) Step(n int) (string, error) {
+	return d.run(n, nil)
+}
+
+// 現在の命令がVMのcallの始まり（戻り先ラベルのアドレスをAにセットする命令）であれば、
+// 呼び出した関数から戻るまで実行する
+// 再帰呼び出しで同じ戻り先に着いた場合は、SPが呼び出し前より深いので区別できる
+func (d *Debugger) Next() (string, error) {
+	c := d.Computer
+	returnAddress, ok := d.callReturnAddress()
+	if !ok {
+		return d.Step(1)
+	}
+
+	sp := c.RAM[0]
+	message, err := d.run(d.MaxCycles, func() bool {
+		return c.PC == returnAddress && c.RAM[0] <= sp+1
+	})
+	if err != nil || message != "" {
+		return message, err
+	}
+	if c.PC != returnAddress {
+		return fmt.Sprintf("Stopped after %d cycles", d.MaxCycles), nil
+	}
+	return "", nil
+}
+
+func (d *Debugger) callReturnAddress() (int, bool) {
+	c := d.Computer
+	if c.IsHalted() {
+		return 0, false
+	}
+	instruction := c.ROM[c.PC]
+	if instruction&0x8000 != 0 {
+		return 0, false
+	}
+	for _, label := range d.labels {
+		if label.address == int(instruction) && strings.HasPrefix(label.name, ReturnAddressPrefix) {
+			return label.address, true
+		}
+	}
+	return 0, false
+}
+
+// ブレークポイントかウォッチポイントで止まるまで実行する
+func (d *Debugger) Continue(maxCycles int) (string, error) {
+	message, err := d.run(maxCycles, nil)
+	if err != nil || message != "" {
+		return message, err
+	}
+	return fmt.Sprintf("Stopped after %d cycles", maxCycles), nil
+}
+
+// ROMのアドレスを「387 (Sys.init+2): D=A」のように、直前のラベルからの位置と命令で書く
+func (d *Debugger) location(address int) string {
+	result := strconv.Itoa(address)
+	if name := d.labelAt(address); name != "" {
+		result += " (" + name + ")"
+	}
+	if address >= 0 && address < len(d.Computer.ROM) {
+		result += ": " + Disassemble(d.Computer.ROM[address])
+	}
+	return result
+}
+
+func (d *Debugger) labelAt(address int) string {
+	i := sort.Search(len(d.labels), func(i int) bool {
+		return d.labels[i].address > address
+	}) - 1
+	if i < 0 {
+		return ""
+	}
+	// 同じアドレスに複数のラベルがある場合は、名前順で最初のものを使う
+	for i > 0 && d.labels[i-1].address == d.labels[i].address {
+		i--
+	}
+	label := d.labels[i]
+	if label.address == address {
+		return label.name
+	}
+	return fmt.Sprintf("%s+%d", label.name, address-label.address)
+}
+
+// A、D、PCかRAMのアドレス・シンボルの値を返す
+// MはAが指すRAMの値
+func (d *Debugger) Value(target string) (int16, error) {
+	c := d.Computer
+	switch target {
+	case "A":
+		return c.A, nil
+	case "D":
+		return c.D, nil
+	case "PC":
+		return int16(c.PC), nil
+	case "M":
+		return c.RAM[uint16(c.A)%emulator.RAMSize], nil
+	}
+	address, err := d.ramAddress(target)
+	if err != nil {
+		return 0, err
+	}
+	return c.RAM[address], nil
+}
+
+func (d *Debugger) SetValue(target string, value int16) error {
+	c := d.Computer
+	switch target {
+	case "A":
+		c.A = value
+	case "D":
+		c.D = value
+	case "PC":
+		c.PC = int(value)
+	case "M":
+		c.RAM[uint16(c.A)%emulator.RAMSize] = value
+	default:
+		address, err := d.ramAddress(target)
+		if err != nil {
+			return err
+		}
+		c.RAM[address] = value
+	}
+
+	// デバッガから書き換えた値では、ウォッチポイントで止めない
+	for _, watchpoint := range d.watchpoints {
+		watchpoint.Value = c.RAM[watchpoint.Address]
+	}
+	return nil
+}
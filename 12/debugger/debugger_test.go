@@ -0,0 +1,219 @@
+package debugger
+
+import (
+	"bytes"
+	"github.com/google/go-cmp/cmp"
+	"strings"
+	"testing"
+)
+
+const (
+	multFile      = "../../04/mult/mult.asm"
+	fibonacciFile = "../../08/FunctionCalls/FibonacciElement/FibonacciElement.asm.cmp"
+)
+
+func TestDebuggerRunScript(t *testing.T) {
+	cases := []struct {
+		desc   string
+		file   string
+		script []string
+		want   []string
+	}{
+		{
+			desc: "ラベルのブレークポイントで止まる",
+			file: multFile,
+			script: []string{
+				"set R0 3",
+				"set R1 2",
+				"break END",
+				"continue",
+				"expect R2 6",
+				"info",
+			},
+			want: []string{
+				"Breakpoint at 20 (END): @20",
+				"=> 20 (END): @20",
+				"PC=20 A=20 D=0 Cycles=36",
+				"Breakpoint 20 (END): @20",
+			},
+		},
+		{
+			desc: "ウォッチポイントで値が変わるたびに止まる",
+			file: multFile,
+			script: []string{
+				"set R0 3",
+				"set R1 5",
+				"watch R2",
+				"c",
+				"c",
+				"p R2",
+				"unwatch R2",
+				"c 100",
+				"p R2",
+			},
+			want: []string{
+				"Watchpoint R2 (RAM[2]): 0 -> 3",
+				"=> 16 (LOOP+8): @1",
+				"Watchpoint R2 (RAM[2]): 3 -> 6",
+				"=> 16 (LOOP+8): @1",
+				"R2 = 6",
+				"Stopped after 100 cycles",
+				"=> 20 (END): @20",
+				"R2 = 15",
+			},
+		},
+		{
+			desc: "ステップ実行",
+			file: multFile,
+			script: []string{
+				"step",
+				"s 3",
+				"next",
+				"print A",
+				"print D",
+			},
+			want: []string{
+				"=> 1: D=M",
+				"=> 4 (INIT): @0",
+				"=> 5 (INIT+1): D=A",
+				"A = 0",
+				"D = 0",
+			},
+		},
+		{
+			desc: "callをステップオーバーすると、再帰呼び出しの途中では止まらずに戻り先で止まる",
+			file: fibonacciFile,
+			script: []string{
+				"break Sys.init",
+				"continue",
+				"step 7",
+				"next",
+				"expect SP 262",
+				"print 261",
+				"print ARG 3",
+			},
+			want: []string{
+				"Breakpoint at 380 (Sys.init): @4",
+				"=> 380 (Sys.init): @4",
+				"=> 387 (Sys.init+7): @436",
+				"=> 436 (RETURN-ADDRESS$Sys$Main.fibonacci$387): @436",
+				"261 = 3",
+				"RAM[2] = 256",
+				"RAM[3] = 3000",
+				"RAM[4] = 3010",
+			},
+		},
+		{
+			desc: "callの中のブレークポイントではステップオーバーも止まる",
+			file: fibonacciFile,
+			script: []string{
+				"break Sys.init",
+				"c",
+				"step 7",
+				"b Main$IF_TRUE",
+				"n",
+				"expect SP 279",
+				"print ARG",
+				"print 273",
+			},
+			want: []string{
+				"Breakpoint at 380 (Sys.init): @4",
+				"=> 380 (Sys.init): @4",
+				"=> 387 (Sys.init+7): @436",
+				"Breakpoint at 112 (Main$IF_TRUE): @0",
+				"=> 112 (Main$IF_TRUE): @0",
+				"ARG = 273",
+				"273 = 0",
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			var b bytes.Buffer
+			d, err := LoadFile(c.file, &b)
+			if err != nil {
+				t.Fatalf("failed LoadFile: %+v", err)
+			}
+			if err := d.RunScript(strings.Join(c.script, "\n")); err != nil {
+				t.Fatalf("failed RunScript: %+v", err)
+			}
+			got := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+			if diff := cmp.Diff(got, c.want); diff != "" {
+				t.Errorf("failed RunScript: diff (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDebuggerRunScriptError(t *testing.T) {
+	cases := []struct {
+		desc   string
+		script []string
+		want   string
+	}{
+		{
+			desc:   "期待した値と違う",
+			script: []string{"set R0 3", "", "expect R0 4"},
+			want:   "line 3: expect R0: got 3, want 4",
+		},
+		{
+			desc:   "存在しないラベル",
+			script: []string{"break NOTHING"},
+			want:   "line 1: unknown label NOTHING",
+		},
+		{
+			desc:   "ラベルはウォッチできない",
+			script: []string{"watch LOOP"},
+			want:   "line 1: LOOP is a label, not a RAM address",
+		},
+		{
+			desc:   "ROMの範囲外",
+			script: []string{"// コメント", "break 100"},
+			want:   "line 2: ROM address out of range: 100",
+		},
+		{
+			desc:   "不明なコマンド",
+			script: []string{"jump 3"},
+			want:   "line 1: unknown command jump",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			var b bytes.Buffer
+			d, err := LoadFile(multFile, &b)
+			if err != nil {
+				t.Fatalf("failed LoadFile: %+v", err)
+			}
+			err = d.RunScript(strings.Join(c.script, "\n"))
+			if err == nil {
+				t.Fatalf("failed RunScript: expected error")
+			}
+			if diff := cmp.Diff(err.Error(), c.want); diff != "" {
+				t.Errorf("failed RunScript: diff (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDisassemble(t *testing.T) {
+	cases := []struct {
+		instruction uint16
+		want        string
+	}{
+		{instruction: 0x0002, want: "@2"},
+		{instruction: 0xfc10, want: "D=M"},
+		{instruction: 0xf088, want: "M=D+M"},
+		{instruction: 0xe301, want: "D;JGT"},
+		{instruction: 0xea87, want: "0;JMP"},
+		{instruction: 0xfdf8, want: "AMD=M+1"},
+		{instruction: 0xe040, want: "0000001"},
+	}
+
+	for _, c := range cases {
+		if diff := cmp.Diff(Disassemble(c.instruction), c.want); diff != "" {
+			t.Errorf("failed Disassemble %016b: diff (-got +want):\n%s", c.instruction, diff)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package debugger
+
+import "fmt"
+
+// compのビット（a c1〜c6の7ビット）とニーモニックの対応
+// 06のアセンブラの対応表を逆に引いたもの
+var compMnemonics = map[uint16]string{
+	0x2a: "0",
+	0x3f: "1",
+	0x3a: "-1",
+	0x0c: "D",
+	0x30: "A",
+	0x70: "M",
+	0x0d: "!D",
+	0x31: "!A",
+	0x71: "!M",
+	0x0f: "-D",
+	0x33: "-A",
+	0x73: "-M",
+	0x1f: "D+1",
+	0x37: "A+1",
+	0x77: "M+1",
+	0x0e: "D-1",
+	0x32: "A-1",
+	0x72: "M-1",
+	0x02: "D+A",
+	0x42: "D+M",
+	0x13: "D-A",
+	0x53: "D-M",
+	0x07: "A-D",
+	0x47: "M-D",
+	0x00: "D&A",
+	0x40: "D&M",
+	0x15: "D|A",
+	0x55: "D|M",
+}
+
+var destMnemonics = []string{"", "M", "D", "MD", "A", "AM", "AD", "AMD"}
+
+var jumpMnemonics = []string{"", "JGT", "JEQ", "JGE", "JLT", "JNE", "JLE", "JMP"}
+
+// 命令をアセンブリのニーモニックに戻す
+// 対応するニーモニックがないcompのビットは、2進数のまま書く
+func Disassemble(instruction uint16) string {
+	if instruction&0x8000 == 0 {
+		return fmt.Sprintf("@%d", instruction)
+	}
+
+	bits := (instruction >> 6) & 0x7f
+	comp, ok := compMnemonics[bits]
+	if !ok {
+		comp = fmt.Sprintf("%07b", bits)
+	}
+	result := comp
+	if dest := destMnemonics[(instruction>>3)&0x07]; dest != "" {
+		result = dest + "=" + result
+	}
+	if jump := jumpMnemonics[instruction&0x07]; jump != "" {
+		result = result + ";" + jump
+	}
+	return result
+}
@@ -0,0 +1,226 @@
+package emulator
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/pkg/errors"
+	"os"
+	"strings"
+)
+
+const (
+	RAMSize         = 32768
+	ScreenAddress   = 16384
+	ScreenSize      = 8192 // 512x256ピクセル、1ワード16ピクセル
+	KeyboardAddress = 24576
+)
+
+// Hackコンピュータを命令セットのレベルでエミュレートする
+// 06のアセンブラが出力した.hackファイルをそのまま実行できる
+type Computer struct {
+	ROM    []uint16
+	RAM    []int16
+	A      int16
+	D      int16
+	PC     int
+	Cycles int
+}
+
+func NewComputer(rom []uint16) *Computer {
+	return &Computer{
+		ROM: rom,
+		RAM: make([]int16, RAMSize),
+	}
+}
+
+func LoadHackFile(filename string) (*Computer, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	lines := []string{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	rom, err := ParseHack(lines)
+	if err != nil {
+		return nil, errors.WithMessage(err, filename)
+	}
+	return NewComputer(rom), nil
+}
+
+// 「0000000000000010」のような16桁の2進数の行を命令として読み込む
+func ParseHack(lines []string) ([]uint16, error) {
+	rom := []uint16{}
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+		if len(trimmed) != 16 {
+			message := fmt.Sprintf("error ParseHack: line %d: expected 16 bits: got = %s", i+1, trimmed)
+			return nil, errors.New(message)
+		}
+
+		var instruction uint16
+		for _, bit := range trimmed {
+			instruction <<= 1
+			switch bit {
+			case '0':
+			case '1':
+				instruction |= 1
+			default:
+				message := fmt.Sprintf("error ParseHack: line %d: invalid bit: got = %s", i+1, trimmed)
+				return nil, errors.New(message)
+			}
+		}
+		rom = append(rom, instruction)
+	}
+	return rom, nil
+}
+
+// ROMの範囲外に到達したら停止したとみなす
+func (c *Computer) IsHalted() bool {
+	return c.PC < 0 || c.PC >= len(c.ROM)
+}
+
+func (c *Computer) Reset() {
+	c.A = 0
+	c.D = 0
+	c.PC = 0
+	c.Cycles = 0
+}
+
+// 一命令だけ実行する
+func (c *Computer) Step() error {
+	if c.IsHalted() {
+		return errors.New(fmt.Sprintf("error Step: PC out of ROM: got = %d", c.PC))
+	}
+
+	instruction := c.ROM[c.PC]
+	c.Cycles++
+
+	// A命令: 最上位ビットが0
+	if instruction&0x8000 == 0 {
+		c.A = int16(instruction)
+		c.PC++
+		return nil
+	}
+
+	// C命令: 111a cccc ccdd djjj
+	address := c.A
+	y := c.A
+	if instruction&0x1000 != 0 {
+		y = c.read(address)
+	}
+	out := ALU(c.D, y, uint16(instruction>>6)&0x3f)
+
+	// 書き込み先と分岐先は、どちらも命令実行前のAレジスタの値を使う
+	if instruction&0x08 != 0 {
+		c.write(address, out)
+	}
+	if instruction&0x20 != 0 {
+		c.A = out
+	}
+	if instruction&0x10 != 0 {
+		c.D = out
+	}
+
+	if jump(out, instruction&0x07) {
+		c.PC = int(uint16(address))
+	} else {
+		c.PC++
+	}
+	return nil
+}
+
+// 最大maxCycles命令まで実行し、untilがtrueを返したら止める
+// untilがtrueを返して止まった場合はtrueを返す
+func (c *Computer) RunUntil(until func(c *Computer) bool, maxCycles int) (bool, error) {
+	for i := 0; i < maxCycles; i++ {
+		if until != nil && until(c) {
+			return true, nil
+		}
+		if c.IsHalted() {
+			return true, nil
+		}
+		if err := c.Step(); err != nil {
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+func (c *Computer) read(address int16) int16 {
+	return c.RAM[uint16(address)%RAMSize]
+}
+
+// キーボードのメモリマップはハードウェアからの入力なので、CPUからは書き込めない
+func (c *Computer) write(address int16, value int16) {
+	index := uint16(address) % RAMSize
+	if index == KeyboardAddress {
+		return
+	}
+	c.RAM[index] = value
+}
+
+// キーボードのメモリマップに押されているキーのコードを書き込む
+// 何も押されていない場合は0
+func (c *Computer) SetKey(key int16) {
+	c.RAM[KeyboardAddress] = key
+}
+
+// スクリーンの(x, y)のピクセルが黒ならtrueを返す
+// 各ワードの最下位ビットが左端のピクセルに対応する
+func (c *Computer) Pixel(x int, y int) bool {
+	word := c.RAM[ScreenAddress+y*32+x/16]
+	return uint16(word)&(1<<uint(x%16)) != 0
+}
+
+// Hack CPUのALU
+// control: zx nx zy ny f no の6ビット
+func ALU(x int16, y int16, control uint16) int16 {
+	if control&0x20 != 0 { // zx
+		x = 0
+	}
+	if control&0x10 != 0 { // nx
+		x = ^x
+	}
+	if control&0x08 != 0 { // zy
+		y = 0
+	}
+	if control&0x04 != 0 { // ny
+		y = ^y
+	}
+
+	var out int16
+	if control&0x02 != 0 { // f
+		out = x + y
+	} else {
+		out = x & y
+	}
+
+	if control&0x01 != 0 { // no
+		out = ^out
+	}
+	return out
+}
+
+// j1 j2 j3 はそれぞれ out < 0, out = 0, out > 0 のときに分岐する
+func jump(out int16, bits uint16) bool {
+	switch {
+	case out < 0:
+		return bits&0x04 != 0
+	case out == 0:
+		return bits&0x02 != 0
+	default:
+		return bits&0x01 != 0
+	}
+}
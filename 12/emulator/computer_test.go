@@ -0,0 +1,157 @@
+package emulator
+
+import (
+	"github.com/google/go-cmp/cmp"
+	"testing"
+)
+
+func TestComputerRun(t *testing.T) {
+	// R0とR1の大きい方をR2にセットして無限ループで止まる
+	max := []string{
+		"0000000000000000", // @0
+		"1111110000010000", // D=M
+		"0000000000000001", // @1
+		"1111010011010000", // D=D-M
+		"0000000000001010", // @10
+		"1110001100000001", // D;JGT
+		"0000000000000001", // @1
+		"1111110000010000", // D=M
+		"0000000000001100", // @12
+		"1110101010000111", // 0;JMP
+		"0000000000000000", // @0
+		"1111110000010000", // D=M
+		"0000000000000010", // @2
+		"1110001100001000", // M=D
+		"0000000000001110", // @14
+		"1110101010000111", // 0;JMP
+	}
+
+	cases := []struct {
+		desc  string
+		lines []string
+		ram   map[int]int16
+		key   int16
+		loop  int // 最後の無限ループのアドレス
+		want  map[int]int16
+	}{
+		{
+			desc: "2 + 3 をR0にセット",
+			lines: []string{
+				"0000000000000010", // @2
+				"1110110000010000", // D=A
+				"0000000000000011", // @3
+				"1110000010010000", // D=D+A
+				"0000000000000000", // @0
+				"1110001100001000", // M=D
+			},
+			want: map[int]int16{0: 5},
+		},
+		{
+			desc:  "Max: R0の方が大きい",
+			lines: max,
+			ram:   map[int]int16{0: 7, 1: -3},
+			loop:  14,
+			want:  map[int]int16{2: 7},
+		},
+		{
+			desc:  "Max: R1の方が大きい",
+			lines: max,
+			ram:   map[int]int16{0: -3, 1: 12},
+			loop:  14,
+			want:  map[int]int16{2: 12},
+		},
+		{
+			desc: "書き込み先は命令実行前のAレジスタ",
+			lines: []string{
+				"0110000000000000", // @24576
+				"1111110000010000", // D=M
+				"0000000000000000", // @0
+				"1111110111101000", // AM=M+1
+				"1110001100001000", // M=D
+			},
+			ram:  map[int]int16{0: 99},
+			key:  65,
+			want: map[int]int16{0: 100, 100: 65},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			rom, err := ParseHack(tc.lines)
+			if err != nil {
+				t.Fatalf("failed ParseHack: %+v", err)
+			}
+
+			computer := NewComputer(rom)
+			for address, value := range tc.ram {
+				computer.RAM[address] = value
+			}
+			computer.SetKey(tc.key)
+
+			// 最後の無限ループに入るか、ROMの末尾に到達したら止める
+			stopped, err := computer.RunUntil(func(c *Computer) bool {
+				return tc.loop > 0 && c.PC == tc.loop
+			}, 1000)
+			if err != nil {
+				t.Fatalf("failed RunUntil: %+v", err)
+			}
+			if !stopped {
+				t.Fatalf("failed RunUntil: not stopped")
+			}
+
+			got := map[int]int16{}
+			for address := range tc.want {
+				got[address] = computer.RAM[address]
+			}
+			if diff := cmp.Diff(got, tc.want); diff != "" {
+				t.Errorf("failed RAM: diff (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestALU(t *testing.T) {
+	cases := []struct {
+		desc    string
+		control uint16
+		want    int16
+	}{
+		{desc: "0", control: 0x2a, want: 0},
+		{desc: "1", control: 0x3f, want: 1},
+		{desc: "-1", control: 0x3a, want: -1},
+		{desc: "x", control: 0x0c, want: 17},
+		{desc: "!y", control: 0x31, want: ^int16(3)},
+		{desc: "-x", control: 0x0f, want: -17},
+		{desc: "y+1", control: 0x37, want: 4},
+		{desc: "x-y", control: 0x13, want: 14},
+		{desc: "y-x", control: 0x07, want: -14},
+		{desc: "x&y", control: 0x00, want: 1},
+		{desc: "x|y", control: 0x15, want: 19},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			if got := ALU(17, 3, tc.control); got != tc.want {
+				t.Errorf("failed ALU: got = %d, want = %d", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseHackError(t *testing.T) {
+	cases := []struct {
+		desc  string
+		lines []string
+	}{
+		{desc: "桁数が足りない", lines: []string{"0101"}},
+		{desc: "2進数ではない", lines: []string{"000000000000000x"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			if _, err := ParseHack(tc.lines); err == nil {
+				t.Errorf("failed ParseHack: expected error")
+			}
+		})
+	}
+}
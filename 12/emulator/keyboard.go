@@ -0,0 +1,144 @@
+package emulator
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/pkg/errors"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 文字以外のキーのHackのキーコード
+var keyCodes = map[string]int16{
+	"space":     32,
+	"newline":   128,
+	"enter":     128,
+	"backspace": 129,
+	"left":      130,
+	"up":        131,
+	"right":     132,
+	"down":      133,
+	"home":      134,
+	"end":       135,
+	"pageup":    136,
+	"pagedown":  137,
+	"insert":    138,
+	"delete":    139,
+	"esc":       140,
+	"release":   0, // キーを離す
+}
+
+func init() {
+	for i := 1; i <= 12; i++ {
+		keyCodes[fmt.Sprintf("f%d", i)] = int16(140 + i)
+	}
+}
+
+// キーの名前をHackのキーコードにする
+// 1文字ならその文字のコード、それ以外は「left」や「f1」のような名前
+func KeyCode(name string) (int16, error) {
+	if runes := []rune(name); len(runes) == 1 {
+		if runes[0] < 32 || runes[0] > 126 {
+			return 0, errors.New(fmt.Sprintf("error KeyCode: not a printable character: %q", name))
+		}
+		return int16(runes[0]), nil
+	}
+	code, ok := keyCodes[strings.ToLower(name)]
+	if !ok {
+		return 0, errors.New(fmt.Sprintf("error KeyCode: unknown key: %s", name))
+	}
+	return code, nil
+}
+
+// 実行した命令数（VMではコマンド数）がAtに達した時点から、Keyが押されている
+// 次のイベントまで押し続け、releaseのイベントで離す
+type KeyEvent struct {
+	At  int
+	Key int16
+}
+
+// At順に並んだキーのイベント
+type KeyEvents []*KeyEvent
+
+// 「1000000 left」のように、時刻とキーの名前を1行に1つずつ書く
+// 時刻を「+200000」のように書くと、1つ前のイベントからの時間になる
+// 空行と「//」以降は無視する
+//
+//	// 左に動かしてから離す
+//	1000000 left
+//	+200000 release
+//	+500000 esc
+func ParseKeyEvents(lines []string) (KeyEvents, error) {
+	events := KeyEvents{}
+	last := 0
+	for i, line := range lines {
+		if index := strings.Index(line, "//"); index >= 0 {
+			line = line[:index]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			message := fmt.Sprintf("error ParseKeyEvents: line %d: expected <cycle> <key>: got = %s", i+1, line)
+			return nil, errors.New(message)
+		}
+
+		at, err := strconv.Atoi(strings.TrimPrefix(fields[0], "+"))
+		if err != nil || at < 0 {
+			message := fmt.Sprintf("error ParseKeyEvents: line %d: invalid cycle: %s", i+1, fields[0])
+			return nil, errors.New(message)
+		}
+		if strings.HasPrefix(fields[0], "+") {
+			at += last
+		}
+		if at < last {
+			message := fmt.Sprintf("error ParseKeyEvents: line %d: cycle must not decrease: %d < %d", i+1, at, last)
+			return nil, errors.New(message)
+		}
+		key, err := KeyCode(fields[1])
+		if err != nil {
+			return nil, errors.WithMessage(err, fmt.Sprintf("line %d", i+1))
+		}
+		events = append(events, &KeyEvent{At: at, Key: key})
+		last = at
+	}
+	return events, nil
+}
+
+func LoadKeyEventFile(filename string) (KeyEvents, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer file.Close()
+
+	lines := []string{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	events, err := ParseKeyEvents(lines)
+	if err != nil {
+		return nil, errors.WithMessage(err, filename)
+	}
+	return events, nil
+}
+
+// 時刻atに押されているキー
+// 最初のイベントより前は何も押されていない
+func (events KeyEvents) KeyAt(at int) int16 {
+	i := sort.Search(len(events), func(i int) bool {
+		return events[i].At > at
+	}) - 1
+	if i < 0 {
+		return 0
+	}
+	return events[i].Key
+}
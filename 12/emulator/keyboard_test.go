@@ -0,0 +1,94 @@
+package emulator
+
+import (
+	"github.com/google/go-cmp/cmp"
+	"testing"
+)
+
+func TestParseKeyEvents(t *testing.T) {
+	lines := []string{
+		"// コメント",
+		"100 a",
+		"",
+		"+50 release // 150",
+		"200 space",
+		"+0 LEFT",
+		"300 f12",
+		"400 enter",
+	}
+	want := KeyEvents{
+		{At: 100, Key: 97},
+		{At: 150, Key: 0},
+		{At: 200, Key: 32},
+		{At: 200, Key: 130},
+		{At: 300, Key: 152},
+		{At: 400, Key: 128},
+	}
+
+	got, err := ParseKeyEvents(lines)
+	if err != nil {
+		t.Fatalf("failed ParseKeyEvents: %+v", err)
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Fatalf("failed ParseKeyEvents: diff (-got +want):\n%s", diff)
+	}
+
+	cases := []struct {
+		at   int
+		want int16
+	}{
+		{at: 0, want: 0},
+		{at: 99, want: 0},
+		{at: 100, want: 97},
+		{at: 149, want: 97},
+		{at: 150, want: 0},
+		{at: 200, want: 130}, // 同じ時刻のイベントは最後のものを使う
+		{at: 1000, want: 128},
+	}
+	for _, tc := range cases {
+		if diff := cmp.Diff(got.KeyAt(tc.at), tc.want); diff != "" {
+			t.Errorf("failed KeyAt(%d): diff (-got +want):\n%s", tc.at, diff)
+		}
+	}
+}
+
+func TestParseKeyEventsError(t *testing.T) {
+	cases := []struct {
+		desc  string
+		lines []string
+		want  string
+	}{
+		{
+			desc:  "キーがない",
+			lines: []string{"100"},
+			want:  "error ParseKeyEvents: line 1: expected <cycle> <key>: got = 100",
+		},
+		{
+			desc:  "時刻が数ではない",
+			lines: []string{"abc a"},
+			want:  "error ParseKeyEvents: line 1: invalid cycle: abc",
+		},
+		{
+			desc:  "時刻が戻る",
+			lines: []string{"100 a", "50 b"},
+			want:  "error ParseKeyEvents: line 2: cycle must not decrease: 50 < 100",
+		},
+		{
+			desc:  "不明なキー",
+			lines: []string{"", "100 shift"},
+			want:  "line 2: error KeyCode: unknown key: shift",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			_, err := ParseKeyEvents(tc.lines)
+			if err == nil {
+				t.Fatalf("failed ParseKeyEvents: expected error")
+			}
+			if diff := cmp.Diff(err.Error(), tc.want); diff != "" {
+				t.Errorf("failed ParseKeyEvents: diff (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
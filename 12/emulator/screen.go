@@ -0,0 +1,109 @@
+package emulator
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/pkg/errors"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strings"
+)
+
+const (
+	ScreenWidth  = 512
+	ScreenHeight = 256
+)
+
+// 白を0、黒を1とするパレット
+var screenPalette = color.Palette{color.White, color.Black}
+
+// スクリーンのメモリマップを白黒の画像にする
+func (c *Computer) ScreenImage() *image.Paletted {
+	img := image.NewPaletted(image.Rect(0, 0, ScreenWidth, ScreenHeight), screenPalette)
+	for y := 0; y < ScreenHeight; y++ {
+		for x := 0; x < ScreenWidth; x++ {
+			if c.Pixel(x, y) {
+				img.SetColorIndex(x, y, 1)
+			}
+		}
+	}
+	return img
+}
+
+func (c *Computer) WritePNG(w io.Writer) error {
+	return errors.WithStack(png.Encode(w, c.ScreenImage()))
+}
+
+// バイナリ形式（P4）のPBMで書き込む
+// 各行は左端のピクセルを最上位ビットとする64バイトで、黒が1
+// Hackのスクリーンとはビットの順序が逆になる
+func (c *Computer) WritePBM(w io.Writer) error {
+	writer := bufio.NewWriter(w)
+	fmt.Fprintf(writer, "P4\n%d %d\n", ScreenWidth, ScreenHeight)
+	for y := 0; y < ScreenHeight; y++ {
+		for x := 0; x < ScreenWidth; x += 8 {
+			var b byte
+			for i := 0; i < 8; i++ {
+				b <<= 1
+				if c.Pixel(x+i, y) {
+					b |= 1
+				}
+			}
+			writer.WriteByte(b)
+		}
+	}
+	return errors.WithStack(writer.Flush())
+}
+
+// 点字の各点のビット
+// 1文字で横2ピクセル、縦4ピクセルを表す
+var brailleDots = [4][2]rune{
+	{0x01, 0x08},
+	{0x02, 0x10},
+	{0x04, 0x20},
+	{0x40, 0x80},
+}
+
+// スクリーンを点字の文字で描く（256文字x64行）
+// 黒のピクセルを点にする
+func (c *Computer) RenderBraille() string {
+	var b strings.Builder
+	for y := 0; y < ScreenHeight; y += 4 {
+		for x := 0; x < ScreenWidth; x += 2 {
+			r := rune(0x2800)
+			for dy := 0; dy < 4; dy++ {
+				for dx := 0; dx < 2; dx++ {
+					if c.Pixel(x+dx, y+dy) {
+						r |= brailleDots[dy][dx]
+					}
+				}
+			}
+			b.WriteRune(r)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// スクリーンをANSIエスケープシーケンスの色と「▀」で描く（512文字x128行）
+// 1文字で縦2ピクセルを表し、上のピクセルを文字の色、下のピクセルを背景色にする
+// 色は変わったときだけ出力し、各行の最後で元に戻す
+func (c *Computer) RenderANSI() string {
+	colors := map[bool]int{false: 7, true: 0} // 白と黒
+	var b strings.Builder
+	for y := 0; y < ScreenHeight; y += 2 {
+		foreground, background := -1, -1
+		for x := 0; x < ScreenWidth; x++ {
+			top, bottom := colors[c.Pixel(x, y)], colors[c.Pixel(x, y+1)]
+			if top != foreground || bottom != background {
+				fmt.Fprintf(&b, "\x1b[3%d;4%dm", top, bottom)
+				foreground, background = top, bottom
+			}
+			b.WriteString("▀")
+		}
+		b.WriteString("\x1b[0m\n")
+	}
+	return b.String()
+}
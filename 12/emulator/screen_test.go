@@ -0,0 +1,107 @@
+package emulator
+
+import (
+	"bytes"
+	"github.com/google/go-cmp/cmp"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+// 左上の(0, 0)と(17, 1)、右下の(511, 255)を黒にしたスクリーン
+func newScreenTestComputer() *Computer {
+	c := NewComputer([]uint16{})
+	c.RAM[ScreenAddress] = 1                   // (0, 0)
+	c.RAM[ScreenAddress+32+1] = 2              // (17, 1)
+	c.RAM[ScreenAddress+ScreenSize-1] = -32768 // (511, 255)
+	return c
+}
+
+func TestComputerWritePBM(t *testing.T) {
+	var b bytes.Buffer
+	if err := newScreenTestComputer().WritePBM(&b); err != nil {
+		t.Fatalf("failed WritePBM: %+v", err)
+	}
+
+	header := "P4\n512 256\n"
+	want := make([]byte, ScreenWidth/8*ScreenHeight)
+	want[0] = 0x80        // (0, 0)は最上位ビット
+	want[64+2] = 0x40     // (17, 1)は3バイト目の2番目のビット
+	want[len(want)-1] = 1 // (511, 255)は最下位ビット
+	if diff := cmp.Diff(b.Bytes(), append([]byte(header), want...)); diff != "" {
+		t.Errorf("failed WritePBM: diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestComputerWritePNG(t *testing.T) {
+	var b bytes.Buffer
+	c := newScreenTestComputer()
+	if err := c.WritePNG(&b); err != nil {
+		t.Fatalf("failed WritePNG: %+v", err)
+	}
+	img, err := png.Decode(&b)
+	if err != nil {
+		t.Fatalf("failed Decode: %+v", err)
+	}
+
+	cases := []struct {
+		x    int
+		y    int
+		want uint32 // 赤の値（白は0xffff、黒は0）
+	}{
+		{x: 0, y: 0, want: 0},
+		{x: 1, y: 0, want: 0xffff},
+		{x: 17, y: 1, want: 0},
+		{x: 16, y: 1, want: 0xffff},
+		{x: 511, y: 255, want: 0},
+	}
+	for _, tc := range cases {
+		r, _, _, _ := img.At(tc.x, tc.y).RGBA()
+		if diff := cmp.Diff(r, tc.want); diff != "" {
+			t.Errorf("failed WritePNG (%d, %d): diff (-got +want):\n%s", tc.x, tc.y, diff)
+		}
+	}
+}
+
+func TestComputerRenderBraille(t *testing.T) {
+	lines := strings.Split(strings.TrimSuffix(newScreenTestComputer().RenderBraille(), "\n"), "\n")
+	if diff := cmp.Diff(len(lines), ScreenHeight/4); diff != "" {
+		t.Fatalf("failed RenderBraille: diff (-got +want):\n%s", diff)
+	}
+
+	cases := []struct {
+		desc string
+		line int
+		want string
+	}{
+		{desc: "(0, 0)は左上の点、(17, 1)は9文字目の右の2段目の点", line: 0, want: "⠁⠀⠀⠀⠀⠀⠀⠀⠐⠀"},
+		{desc: "(511, 255)は最後の文字の右下の点", line: 63, want: "⠀⠀⢀"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := []rune(lines[tc.line])
+			if tc.line == 0 {
+				got = got[:10]
+			} else {
+				got = got[len(got)-3:]
+			}
+			if diff := cmp.Diff(string(got), tc.want); diff != "" {
+				t.Errorf("failed RenderBraille: diff (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestComputerRenderANSI(t *testing.T) {
+	lines := strings.Split(strings.TrimSuffix(newScreenTestComputer().RenderANSI(), "\n"), "\n")
+	if diff := cmp.Diff(len(lines), ScreenHeight/2); diff != "" {
+		t.Fatalf("failed RenderANSI: diff (-got +want):\n%s", diff)
+	}
+
+	// 1行目は(0, 0)だけ上が黒、(17, 1)だけ下が黒
+	want := "\x1b[30;47m▀\x1b[37;47m" + strings.Repeat("▀", 16) +
+		"\x1b[37;40m▀\x1b[37;47m" + strings.Repeat("▀", 494) + "\x1b[0m"
+	if diff := cmp.Diff(lines[0], want); diff != "" {
+		t.Errorf("failed RenderANSI: diff (-got +want):\n%s", diff)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"../debugger"
+	"bufio"
+	"flag"
+	"fmt"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"log"
+	"os"
+)
+
+// Hackのプログラムをデバッガで実行する
+// .asmファイルを指定した場合は、ラベルと変数のシンボルでブレークポイントとウォッチポイントを指定できる
+// -xでスクリプトを指定すると、スクリプトを実行して終了する（エラーの場合は終了コード1）
+// 指定しない場合は、標準入力からコマンドを読む
+//
+//	hackdbg ../04/mult/mult.asm
+//	hackdbg -x fib.dbg ../08/FunctionCalls/FibonacciElement/FibonacciElement.asm
+func main() {
+	if err := run(); err != nil {
+		log.Fatalf("%+v\n", err)
+	}
+}
+
+func run() error {
+	script := flag.String("x", "", "実行するスクリプト")
+	maxCycles := flag.Int("cycles", debugger.DefaultMaxCycles, "continueとnextで実行する最大命令数")
+	flag.Parse()
+	if flag.NArg() != 1 {
+		return errors.New(fmt.Sprintf("usage: %s [-x script] [-cycles N] <file.asm|file.hack>", os.Args[0]))
+	}
+
+	d, err := debugger.LoadFile(flag.Arg(0), os.Stdout)
+	if err != nil {
+		return err
+	}
+	d.MaxCycles = *maxCycles
+
+	if *script != "" {
+		src, err := ioutil.ReadFile(*script)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		return errors.WithMessage(d.RunScript(string(src)), *script)
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for {
+		fmt.Print("(hackdbg) ")
+		if !scanner.Scan() {
+			fmt.Println()
+			return errors.WithStack(scanner.Err())
+		}
+		line := scanner.Text()
+		if line == "quit" || line == "q" {
+			return nil
+		}
+		if err := d.Execute(line); err != nil {
+			fmt.Println(err)
+		}
+	}
+}
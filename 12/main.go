@@ -0,0 +1,184 @@
+package main
+
+import (
+	"./emulator"
+	"./vm"
+	"flag"
+	"fmt"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// JackのプログラムをOSと一緒にビルドして、エミュレータで実行する
+// リポジトリの各ツールをビルドするので、このディレクトリで実行する
+// -vmを指定すると.vmファイルをVMのまま実行し、OSの関数は-jackで指定したもの以外を組み込み関数で置き換える
+// ディレクトリの代わりに.asmファイルか.hackファイルを指定すると、OSを含めずにそのまま実行する
+//
+// -screenで端末にスクリーンを描き、-snapshotで指定した時刻のスクリーンを画像に保存する
+// -keysでキーを押す時刻を書いたファイル（emulator.ParseKeyEventsの形式）を指定する
+// 時刻はエミュレータでは実行した命令数、VMでは実行したコマンド数
+//
+//	go run . [-cycles N] MathTest/
+//	go run . -vm [-jack Math.multiply,Output.printInt] MathTest/
+//	go run . -cycles 600000 -keys golden/Fill.keys -screen braille ../04/fill/Fill.asm
+//	go run . -vm -keys golden/Pong.keys -snapshot 300000 -format pbm -out golden ../11/Fixture/Pong
+func main() {
+	if err := run(); err != nil {
+		log.Fatalf("%+v\n", err)
+	}
+}
+
+func run() error {
+	maxCycles := flag.Int("cycles", 100000000, "最大実行命令数")
+	useVM := flag.Bool("vm", false, "VMのまま実行する")
+	jack := flag.String("jack", "", "Jackの実装を使うOSの関数（カンマ区切り）")
+	keys := flag.String("keys", "", "キーを押す時刻を書いたファイル")
+	screen := flag.String("screen", "", "端末にスクリーンを描く方法（brailleかansi）")
+	refresh := flag.Int("refresh", 0, "端末のスクリーンを描き直す間隔（0なら終了時だけ描く）")
+	snapshot := flag.String("snapshot", "", "スクリーンを保存する時刻（カンマ区切り）")
+	format := flag.String("format", "png", "スクリーンを保存する形式（pngかpbm）")
+	out := flag.String("out", ".", "スクリーンを保存するディレクトリ")
+	flag.Parse()
+	if flag.NArg() != 1 {
+		return errors.New(fmt.Sprintf("usage: %s [-cycles N] [-vm [-jack names]] [-keys file] [-screen braille|ansi [-refresh N]] [-snapshot N,...] <dir|file.asm|file.hack>", os.Args[0]))
+	}
+
+	var events emulator.KeyEvents
+	if *keys != "" {
+		loaded, err := emulator.LoadKeyEventFile(*keys)
+		if err != nil {
+			return err
+		}
+		events = loaded
+	}
+	display, err := newDisplay(flag.Arg(0), *screen, *refresh, *snapshot, *format, *out)
+	if err != nil {
+		return err
+	}
+
+	ext := filepath.Ext(flag.Arg(0))
+	if ext == ".asm" || ext == ".hack" {
+		if *useVM {
+			return errors.New("error run: -vm expects a directory of Jack files")
+		}
+		program, err := LoadHackProgram(flag.Arg(0))
+		if err != nil {
+			return err
+		}
+		return runHack(program, *maxCycles, events, display)
+	}
+
+	root, err := filepath.Abs("..")
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempDir("", "jackos")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	builder := NewBuilder(root, tmp)
+	if err := builder.Setup(); err != nil {
+		return err
+	}
+
+	workDir := filepath.Join(tmp, "program")
+	if err := os.Mkdir(workDir, 0755); err != nil {
+		return err
+	}
+	if *useVM {
+		return runVM(builder, flag.Arg(0), workDir, *jack, *maxCycles, events, display)
+	}
+	program, err := builder.Build(flag.Arg(0), workDir)
+	if err != nil {
+		return err
+	}
+	return runHack(program, *maxCycles, events, display)
+}
+
+// スナップショットの名前には、ディレクトリかファイルの名前を使う
+func newDisplay(target string, screen string, refresh int, snapshot string, format string, out string) (*Display, error) {
+	display := NewDisplay()
+	if screen != "" {
+		render, ok := renderers[screen]
+		if !ok {
+			return nil, errors.New(fmt.Sprintf("error newDisplay: unknown screen: %s", screen))
+		}
+		display.SetTerminal(render, refresh, os.Stdout)
+	}
+
+	snapshots, err := parseSnapshots(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	if len(snapshots) > 0 {
+		name := strings.TrimSuffix(filepath.Base(target), filepath.Ext(target))
+		capture, err := snapshotWriter(out, name, format)
+		if err != nil {
+			return nil, err
+		}
+		display.SetSnapshots(snapshots, capture)
+	}
+	return display, nil
+}
+
+func runHack(program *Program, maxCycles int, events emulator.KeyEvents, display *Display) error {
+	runner := NewRunner(program, maxCycles)
+	runner.SetKeyEvents(events)
+	runner.SetOnCycle(func(c *emulator.Computer) error {
+		return display.Update(c.Cycles, c)
+	})
+	computer, err := runner.Run()
+	if err != nil {
+		return err
+	}
+	if err := display.Finish(computer.Cycles, computer); err != nil {
+		return err
+	}
+	fmt.Printf("%d命令で停止しました（ROM: %d命令）\n", computer.Cycles, len(program.ROM))
+	return nil
+}
+
+func runVM(builder *Builder, srcDir string, workDir string, jack string, maxSteps int, events emulator.KeyEvents, display *Display) error {
+	if err := builder.Compile(srcDir, workDir, true); err != nil {
+		return err
+	}
+	program, err := vm.LoadDir(workDir)
+	if err != nil {
+		return err
+	}
+	machine, err := vm.NewMachine(program)
+	if err != nil {
+		return err
+	}
+	if jack != "" {
+		for _, name := range strings.Split(jack, ",") {
+			if err := machine.UseJack(strings.TrimSpace(name)); err != nil {
+				return err
+			}
+		}
+	}
+
+	machine.SetKeyEvents(events)
+	machine.SetOnStep(func() error {
+		return display.Update(machine.Steps, machine.Computer)
+	})
+
+	halted, err := machine.Run(maxSteps)
+	if err != nil {
+		return err
+	}
+	if !halted {
+		return errors.New(fmt.Sprintf("error Run: not halted in %d steps", maxSteps))
+	}
+	if err := display.Finish(machine.Steps, machine.Computer); err != nil {
+		return err
+	}
+	fmt.Printf("%dコマンドで停止しました（VM: %dコマンド）\n", machine.Steps, len(program.Commands))
+	return nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"./vm"
+	"github.com/google/go-cmp/cmp"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// 11/Fixtureのプログラムを最適化あり（-O1）となしでコンパイルしてVMで実行し、
+// static変数、ヒープ領域、スクリーンが同じ状態で停止することを確認する
+func TestOptimizedFixtures(t *testing.T) {
+	cases := []struct {
+		dir  string
+		keys []int16
+		ram  map[int]int16 // 実行前にセットする値
+	}{
+		{dir: "Seven"},
+		{dir: "ConvertToBin", ram: map[int]int16{8000: 12345}},
+		{dir: "ComplexArrays"},
+		{
+			// 「3」個の数「10」「-20」「31」の平均を求める
+			dir:  "Average",
+			keys: []int16{51, 128, 49, 48, 128, 45, 50, 48, 128, 51, 49, 128},
+		},
+		{dir: "Square", keys: []int16{81}},
+		{dir: "SquareVersion10", keys: []int16{81}},
+		{dir: "Pong"},
+	}
+
+	optimized := NewBuilder(testBuilder.root, testBuilder.tools)
+	optimized.SetCompilerOptions("-O1")
+
+	for _, tt := range cases {
+		t.Run(tt.dir, func(t *testing.T) {
+			srcDir := filepath.Join(testBuilder.root, "11", "Fixture", tt.dir)
+			results := [][]int16{}
+			for _, builder := range []*Builder{testBuilder, optimized} {
+				workDir := testWorkDir(t)
+				defer os.RemoveAll(workDir)
+				if err := builder.Compile(srcDir, workDir, false); err != nil {
+					t.Fatalf("%+v", err)
+				}
+
+				program, err := vm.LoadDir(workDir)
+				if err != nil {
+					t.Fatalf("%+v", err)
+				}
+				machine, err := vm.NewMachine(program)
+				if err != nil {
+					t.Fatalf("%+v", err)
+				}
+				for address, value := range tt.ram {
+					machine.Computer.RAM[address] = value
+				}
+				machine.SetKeys(tt.keys)
+				halted, err := machine.Run(100000000)
+				if err != nil {
+					t.Fatalf("%+v", err)
+				}
+				if !halted {
+					t.Fatal("error Run: not halted")
+				}
+
+				// スタックは最適化によって使い方が変わるので比べない
+				ram := machine.Computer.RAM
+				results = append(results, append(append([]int16{}, ram[vm.StaticAddress:vm.StackAddress]...), ram[2048:24576]...))
+			}
+			if diff := cmp.Diff(results[1], results[0]); diff != "" {
+				t.Errorf("(-optimized +original)\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"./emulator"
+	"./vm"
+	"github.com/google/go-cmp/cmp"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var testBuilder *Builder
+
+// 各ツールのビルドには時間がかかるので、全てのテストで共有する
+func TestMain(m *testing.M) {
+	tmp, err := ioutil.TempDir("", "jackos")
+	if err != nil {
+		panic(err)
+	}
+	root, err := filepath.Abs("..")
+	if err != nil {
+		panic(err)
+	}
+	testBuilder = NewBuilder(root, tmp)
+	if err := testBuilder.Setup(); err != nil {
+		panic(err)
+	}
+	code := m.Run()
+	os.RemoveAll(tmp)
+	os.Exit(code)
+}
+
+// 各テストプログラムと、実行後のRAMとスクリーンの確認方法
+// エミュレータで実行した場合とVMで実行した場合で同じ結果になることを確認する
+type osCase struct {
+	dir   string
+	keys  []int16
+	err   string // Sys.errorで停止する場合のエラー
+	check func(t *testing.T, c *emulator.Computer)
+}
+
+var osCases = []osCase{
+	{
+		dir: "MathTest",
+		check: checkRAM(
+			6, -180, -18000, -18000, 0, 3, -3000, 0,
+			3, 181, 123, 123, 27, 32767, -3276, 32761,
+		),
+	},
+	{
+		dir:   "MemoryTest",
+		check: checkRAM(333, 334, -1, -1, -1, -1),
+	},
+	{
+		dir:   "StringTest",
+		check: checkRAM(2, 98, 99, 1, 6, 45, -12345, -32768, 12, 128, 129, 34, 3000, 98),
+	},
+	{
+		// 「x」を1文字読んだ後、「-429」と入力して9を消してから改行する
+		dir:   "KeyboardTest",
+		keys:  []int16{120, 45, 52, 50, 57, 129, 128},
+		check: checkRAM(120, -42),
+	},
+	{
+		dir:   "OutputTest",
+		check: checkOutput,
+	},
+	{
+		dir:   "ScreenTest",
+		check: checkScreen,
+	},
+	{
+		dir:   "SysTest",
+		err:   "Sys.error(6)",
+		check: checkRAM(1),
+	},
+}
+
+// RAM[8000]以降の値を確認する
+func checkRAM(want ...int16) func(t *testing.T, c *emulator.Computer) {
+	return func(t *testing.T, c *emulator.Computer) {
+		t.Helper()
+		got := make([]int16, len(want))
+		copy(got, c.RAM[8000:8000+len(want)])
+		if diff := cmp.Diff(got, want); diff != "" {
+			t.Errorf("(-got +want)\n%s", diff)
+		}
+	}
+}
+
+func checkOutput(t *testing.T, c *emulator.Computer) {
+	// 各文字のセル（11行）の各行のワードの値
+	// 偶数列の文字は下位8ビット、奇数列の文字は上位8ビットに描かれる
+	cell := func(row int, col int) []int16 {
+		values := make([]int16, 11)
+		for k := range values {
+			word := c.RAM[emulator.ScreenAddress+row*352+k*32+col/2]
+			if col%2 == 0 {
+				values[k] = word & 0xff
+			} else {
+				values[k] = int16(uint16(word) >> 8)
+			}
+		}
+		return values
+	}
+
+	cases := []struct {
+		desc string
+		row  int
+		col  int
+		want []int16
+	}{
+		{desc: "A", row: 0, col: 0, want: []int16{0, 28, 34, 34, 62, 34, 34, 34, 0, 0, 0}},
+		{desc: "バックスペースで消したB", row: 0, col: 1, want: []int16{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}},
+		{desc: "-", row: 1, col: 0, want: []int16{0, 0, 0, 0, 62, 0, 0, 0, 0, 0, 0}},
+		{desc: "7", row: 1, col: 1, want: []int16{0, 62, 32, 16, 8, 4, 4, 4, 0, 0, 0}},
+		{desc: "右下のB", row: 22, col: 63, want: []int16{0, 30, 34, 34, 30, 34, 34, 30, 0, 0, 0}},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.desc, func(t *testing.T) {
+			if diff := cmp.Diff(cell(tt.row, tt.col), tt.want); diff != "" {
+				t.Errorf("(-got +want)\n%s", diff)
+			}
+		})
+	}
+}
+
+func checkScreen(t *testing.T, c *emulator.Computer) {
+	cases := []struct {
+		desc string
+		x    int
+		y    int
+		want bool
+	}{
+		{desc: "点", x: 0, y: 0, want: true},
+		{desc: "点の隣", x: 1, y: 0, want: false},
+		{desc: "斜めの線の始点", x: 10, y: 10, want: true},
+		{desc: "斜めの線の終点", x: 40, y: 25, want: true},
+		{desc: "斜めの線の途中", x: 30, y: 20, want: true},
+		{desc: "水平線", x: 30, y: 10, want: true},
+		{desc: "水平線の外", x: 41, y: 10, want: false},
+		{desc: "長方形の角", x: 100, y: 100, want: true},
+		{desc: "長方形の内側", x: 130, y: 115, want: true},
+		{desc: "長方形の外", x: 141, y: 120, want: false},
+		{desc: "白で消した点", x: 120, y: 110, want: false},
+		{desc: "円の中心", x: 300, y: 150, want: true},
+		{desc: "円の上端", x: 300, y: 130, want: true},
+		{desc: "円の右端", x: 320, y: 150, want: true},
+		{desc: "円の外", x: 321, y: 150, want: false},
+		{desc: "白で描いた円（画面の端で切れる）", x: 511, y: 255, want: false},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.desc, func(t *testing.T) {
+			if got := c.Pixel(tt.x, tt.y); got != tt.want {
+				t.Errorf("Pixel(%d, %d): got = %v, want = %v", tt.x, tt.y, got, tt.want)
+			}
+		})
+	}
+}
+
+func checkError(t *testing.T, err error, want string) {
+	t.Helper()
+	if want == "" {
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+		return
+	}
+	if err == nil {
+		t.Fatalf("error Run: expected %s", want)
+	}
+	if diff := cmp.Diff(err.Error(), want); diff != "" {
+		t.Errorf("(-got +want)\n%s", diff)
+	}
+}
+
+func TestOS(t *testing.T) {
+	for _, tt := range osCases {
+		t.Run(tt.dir, func(t *testing.T) {
+			workDir := testWorkDir(t)
+			defer os.RemoveAll(workDir)
+
+			program, err := testBuilder.Build(tt.dir, workDir)
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			runner := NewRunner(program, 100000000)
+			runner.SetKeys(tt.keys)
+			computer, err := runner.Run()
+			checkError(t, err, tt.err)
+			tt.check(t, computer)
+		})
+	}
+}
+
+// OSの.vmファイルを含めずに、全て組み込み関数で実行する
+func TestOSVMBuiltins(t *testing.T) {
+	for _, tt := range osCases {
+		t.Run(tt.dir, func(t *testing.T) {
+			workDir := testWorkDir(t)
+			defer os.RemoveAll(workDir)
+
+			if err := testBuilder.Compile(tt.dir, workDir, false); err != nil {
+				t.Fatalf("%+v", err)
+			}
+			runVMCase(t, tt, workDir, nil)
+		})
+	}
+}
+
+// 組み込み関数を1つずつJackの実装に戻しても、全て戻しても同じ結果になる
+func TestOSVMJack(t *testing.T) {
+	for _, tt := range osCases {
+		t.Run(tt.dir, func(t *testing.T) {
+			workDir := testWorkDir(t)
+			defer os.RemoveAll(workDir)
+
+			if err := testBuilder.Compile(tt.dir, workDir, true); err != nil {
+				t.Fatalf("%+v", err)
+			}
+			runVMCase(t, tt, workDir, vm.BuiltinNames())
+			for _, name := range vm.BuiltinNames() {
+				t.Run(name, func(t *testing.T) {
+					runVMCase(t, tt, workDir, []string{name})
+				})
+			}
+		})
+	}
+}
+
+func runVMCase(t *testing.T, tt osCase, workDir string, jack []string) {
+	t.Helper()
+	program, err := vm.LoadDir(workDir)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	machine, err := vm.NewMachine(program)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	for _, name := range jack {
+		if err := machine.UseJack(name); err != nil {
+			t.Fatalf("%+v", err)
+		}
+	}
+	machine.SetKeys(tt.keys)
+
+	halted, err := machine.Run(100000000)
+	checkError(t, err, tt.err)
+	if err == nil && !halted {
+		t.Fatal("error Run: not halted")
+	}
+	tt.check(t, machine.Computer)
+}
+
+func testWorkDir(t *testing.T) string {
+	t.Helper()
+	workDir, err := ioutil.TempDir("", "program")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return workDir
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"./emulator"
+	"fmt"
+	"github.com/pkg/errors"
+)
+
+// キーボード入力のスクリプト
+// プログラムが最初にKeyboard.keyPressedを呼んだ時点から、
+// 各キーをKeyCycles命令の間押し続け、次のキーまでKeyCycles命令の間離す
+const KeyCycles = 200000
+
+// エミュレータ上でプログラムをSys.haltまで実行する
+type Runner struct {
+	program   *Program
+	maxCycles int
+	keys      []int16
+	keyStart  int                // キー入力のスクリプトを開始したサイクル数（未開始なら-1）
+	keyEvents emulator.KeyEvents // 指定した場合は、keysの代わりにサイクル数でキーを押す
+	onCycle   func(c *emulator.Computer) error
+}
+
+func NewRunner(program *Program, maxCycles int) *Runner {
+	return &Runner{program: program, maxCycles: maxCycles, keys: []int16{}, keyStart: -1}
+}
+
+func (r *Runner) SetKeys(keys []int16) {
+	r.keys = keys
+}
+
+// 実行したサイクル数に応じてキーを押す
+func (r *Runner) SetKeyEvents(events emulator.KeyEvents) {
+	r.keyEvents = events
+}
+
+// 各命令を実行する前に呼び出す関数
+// スクリーンのスナップショットなどに使う
+func (r *Runner) SetOnCycle(f func(c *emulator.Computer) error) {
+	r.onCycle = f
+}
+
+// Sys.haltに到達するか、ROMの末尾に到達したら正常に終了する
+// Sys.errorが呼ばれた場合は、そのエラーコードをエラーとして返す
+// Sys.haltのないプログラム（04/fillなど）は、maxCycles命令を実行し終えても正常に終了する
+func (r *Runner) Run() (*emulator.Computer, error) {
+	computer := emulator.NewComputer(r.program.ROM)
+	halt, hasHalt := r.program.Labels["Sys.halt"]
+	sysError, hasError := r.program.Labels["Sys.error"]
+
+	var failure error
+	stopped, err := computer.RunUntil(func(c *emulator.Computer) bool {
+		r.updateKey(c)
+		if r.onCycle != nil {
+			if err := r.onCycle(c); err != nil {
+				failure = err
+				return true
+			}
+		}
+		if hasError && c.PC == sysError {
+			// 関数の先頭ではまだARGが呼び出し元から渡された引数を指している
+			failure = errors.New(fmt.Sprintf("Sys.error(%d)", c.RAM[c.RAM[2]]))
+			return true
+		}
+		return hasHalt && c.PC == halt
+	}, r.maxCycles)
+	if err != nil {
+		return computer, err
+	}
+	if failure != nil {
+		return computer, failure
+	}
+	if !stopped && hasHalt {
+		message := fmt.Sprintf("error Run: not halted in %d cycles", r.maxCycles)
+		return computer, errors.New(message)
+	}
+	return computer, nil
+}
+
+func (r *Runner) updateKey(c *emulator.Computer) {
+	if r.keyEvents != nil {
+		c.SetKey(r.keyEvents.KeyAt(c.Cycles))
+		return
+	}
+	if len(r.keys) == 0 {
+		return
+	}
+	if r.keyStart < 0 {
+		if address, ok := r.program.Labels["Keyboard.keyPressed"]; !ok || c.PC != address {
+			return
+		}
+		r.keyStart = c.Cycles
+	}
+
+	index := (c.Cycles - r.keyStart) / KeyCycles
+	if index%2 == 1 || index/2 >= len(r.keys) {
+		c.SetKey(0)
+		return
+	}
+	c.SetKey(r.keys[index/2])
+}
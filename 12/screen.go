@@ -0,0 +1,121 @@
+package main
+
+import (
+	"./emulator"
+	"fmt"
+	"github.com/pkg/errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 端末にスクリーンを描く方法
+var renderers = map[string]func(c *emulator.Computer) string{
+	"braille": (*emulator.Computer).RenderBraille,
+	"ansi":    (*emulator.Computer).RenderANSI,
+}
+
+// スナップショットの画像の形式
+var snapshotFormats = map[string]func(c *emulator.Computer, w io.Writer) error{
+	"png": (*emulator.Computer).WritePNG,
+	"pbm": (*emulator.Computer).WritePBM,
+}
+
+// 実行中のスクリーンを端末に描いたり、指定した時刻のスナップショットを保存したりする
+// 時刻はエミュレータでは実行した命令数、VMでは実行したコマンド数
+type Display struct {
+	snapshots []int // スナップショットを保存する時刻（昇順）
+	next      int
+	capture   func(at int, c *emulator.Computer) error
+	render    func(c *emulator.Computer) string
+	refresh   int // 端末に描き直す間隔（0なら実行し終えたときだけ描く）
+	out       io.Writer
+}
+
+func NewDisplay() *Display {
+	return &Display{snapshots: []int{}}
+}
+
+func (d *Display) SetSnapshots(snapshots []int, capture func(at int, c *emulator.Computer) error) {
+	d.snapshots = append([]int{}, snapshots...)
+	sort.Ints(d.snapshots)
+	d.capture = capture
+}
+
+func (d *Display) SetTerminal(render func(c *emulator.Computer) string, refresh int, out io.Writer) {
+	d.render = render
+	d.refresh = refresh
+	d.out = out
+}
+
+// 各命令（VMではコマンド）を実行する前に呼び出す
+func (d *Display) Update(at int, c *emulator.Computer) error {
+	for d.next < len(d.snapshots) && d.snapshots[d.next] <= at {
+		if err := d.capture(d.snapshots[d.next], c); err != nil {
+			return err
+		}
+		d.next++
+	}
+	if d.render != nil && d.refresh > 0 && at%d.refresh == 0 {
+		// 前のフレームに上書きするように、カーソルを左上に戻す
+		if at == 0 {
+			fmt.Fprint(d.out, "\x1b[2J")
+		}
+		fmt.Fprint(d.out, "\x1b[H"+d.render(c))
+	}
+	return nil
+}
+
+// 実行し終えたときに呼び出す
+// 終了した時刻までのスナップショットを保存し、それより後のスナップショットが残っていたらエラーにする
+func (d *Display) Finish(at int, c *emulator.Computer) error {
+	for d.next < len(d.snapshots) && d.snapshots[d.next] <= at {
+		if err := d.capture(d.snapshots[d.next], c); err != nil {
+			return err
+		}
+		d.next++
+	}
+	if d.render != nil {
+		fmt.Fprint(d.out, d.render(c))
+	}
+	if d.next < len(d.snapshots) {
+		message := fmt.Sprintf("error Display: stopped at %d before snapshot at %d", at, d.snapshots[d.next])
+		return errors.New(message)
+	}
+	return nil
+}
+
+// 「1000000,2000000」のようなカンマ区切りの時刻
+func parseSnapshots(value string) ([]int, error) {
+	snapshots := []int{}
+	if value == "" {
+		return snapshots, nil
+	}
+	for _, s := range strings.Split(value, ",") {
+		at, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil || at < 0 {
+			return nil, errors.New(fmt.Sprintf("error parseSnapshots: invalid cycle: %s", s))
+		}
+		snapshots = append(snapshots, at)
+	}
+	return snapshots, nil
+}
+
+// スナップショットをdirに「<name>-<時刻>.<format>」の名前で保存する関数
+func snapshotWriter(dir string, name string, format string) (func(at int, c *emulator.Computer) error, error) {
+	write, ok := snapshotFormats[format]
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("error snapshotWriter: unknown format: %s", format))
+	}
+	return func(at int, c *emulator.Computer) error {
+		file, err := os.Create(filepath.Join(dir, fmt.Sprintf("%s-%d.%s", name, at, format)))
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		defer file.Close()
+		return write(c, file)
+	}, nil
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"./emulator"
+	"./vm"
+	"bytes"
+	"fmt"
+	"github.com/google/go-cmp/cmp"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// グラフィックを描くプログラムを、goldenディレクトリのキー入力で実行して、
+// 各時刻のスクリーンをゴールデンイメージと比べる
+// ゴールデンイメージはmain.goのコマンドで作り直せる
+//
+//	go run . -cycles 350000 -keys golden/Fill.keys -snapshot 200000,350000 -format pbm -out golden ../04/fill/Fill.asm
+//	go run . -vm -keys golden/Pong.keys -snapshot 20000,25355 -format pbm -out golden ../11/Fixture/Pong
+func TestScreenGolden(t *testing.T) {
+	cases := []struct {
+		name      string
+		snapshots []int
+		run       func(t *testing.T, events emulator.KeyEvents, display *Display)
+	}{
+		{
+			// キーを押している間は塗り、離すと消す（右端のピクセルは塗らない）
+			name:      "Fill",
+			snapshots: []int{200000, 350000},
+			run: func(t *testing.T, events emulator.KeyEvents, display *Display) {
+				program, err := LoadHackProgram(filepath.Join(testBuilder.root, "04", "fill", "Fill.asm"))
+				if err != nil {
+					t.Fatalf("%+v", err)
+				}
+				runGoldenHack(t, program, 350000, events, display)
+			},
+		},
+		{
+			// バットを左に動かしてから、escでゲームを終了する
+			name:      "Pong",
+			snapshots: []int{20000, 25355},
+			run: func(t *testing.T, events emulator.KeyEvents, display *Display) {
+				workDir := testWorkDir(t)
+				defer os.RemoveAll(workDir)
+				if err := testBuilder.Compile(filepath.Join(testBuilder.root, "11", "Fixture", "Pong"), workDir, false); err != nil {
+					t.Fatalf("%+v", err)
+				}
+				program, err := vm.LoadDir(workDir)
+				if err != nil {
+					t.Fatalf("%+v", err)
+				}
+				machine, err := vm.NewMachine(program)
+				if err != nil {
+					t.Fatalf("%+v", err)
+				}
+				machine.SetKeyEvents(events)
+				machine.SetOnStep(func() error {
+					return display.Update(machine.Steps, machine.Computer)
+				})
+				halted, err := machine.Run(100000000)
+				if err != nil {
+					t.Fatalf("%+v", err)
+				}
+				if !halted {
+					t.Fatal("error Run: not halted")
+				}
+				if err := display.Finish(machine.Steps, machine.Computer); err != nil {
+					t.Fatalf("%+v", err)
+				}
+			},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			events, err := emulator.LoadKeyEventFile(filepath.Join("golden", tt.name+".keys"))
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			got := map[int][]byte{}
+			display := NewDisplay()
+			display.SetSnapshots(tt.snapshots, func(at int, c *emulator.Computer) error {
+				var b bytes.Buffer
+				if err := c.WritePBM(&b); err != nil {
+					return err
+				}
+				got[at] = b.Bytes()
+				return nil
+			})
+			tt.run(t, events, display)
+
+			for _, at := range tt.snapshots {
+				filename := filepath.Join("golden", fmt.Sprintf("%s-%d.pbm", tt.name, at))
+				want, err := ioutil.ReadFile(filename)
+				if err != nil {
+					t.Fatalf("%+v", err)
+				}
+				if !bytes.Equal(got[at], want) {
+					t.Errorf("%s: %d pixels differ", filename, countPixelDiff(got[at], want))
+				}
+			}
+		})
+	}
+}
+
+// 時刻より前にプログラムが終了したスナップショットはエラーになる
+func TestScreenSnapshotAfterStop(t *testing.T) {
+	program, err := LoadHackProgram(filepath.Join(testBuilder.root, "04", "fill", "Fill.asm"))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	display := NewDisplay()
+	display.SetSnapshots([]int{500, 2000}, func(at int, c *emulator.Computer) error {
+		return nil
+	})
+	runner := NewRunner(program, 1000)
+	runner.SetOnCycle(func(c *emulator.Computer) error {
+		return display.Update(c.Cycles, c)
+	})
+	computer, err := runner.Run()
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	err = display.Finish(computer.Cycles, computer)
+	if err == nil {
+		t.Fatal("error Finish: expected error")
+	}
+	if diff := cmp.Diff(err.Error(), "error Display: stopped at 1000 before snapshot at 2000"); diff != "" {
+		t.Errorf("(-got +want)\n%s", diff)
+	}
+}
+
+func runGoldenHack(t *testing.T, program *Program, maxCycles int, events emulator.KeyEvents, display *Display) {
+	t.Helper()
+	runner := NewRunner(program, maxCycles)
+	runner.SetKeyEvents(events)
+	runner.SetOnCycle(func(c *emulator.Computer) error {
+		return display.Update(c.Cycles, c)
+	})
+	computer, err := runner.Run()
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if err := display.Finish(computer.Cycles, computer); err != nil {
+		t.Fatalf("%+v", err)
+	}
+}
+
+// PBMのデータで、値が違うピクセルの数（長さが違う場合は-1）
+func countPixelDiff(got []byte, want []byte) int {
+	if len(got) != len(want) {
+		return -1
+	}
+	count := 0
+	for i := range got {
+		for diff := got[i] ^ want[i]; diff != 0; diff &= diff - 1 {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,331 @@
+package vm
+
+import (
+	"../emulator"
+	"github.com/pkg/errors"
+	"sort"
+)
+
+// Goで実装したOSの関数
+// 引数はVMの呼び出し規約と同じ順に並び、メソッドではthisが最後の引数になる
+// voidの関数は0を返す
+//
+// 状態はJackの実装と同じstatic変数とヒープ上のオブジェクトに持つので、
+// 一部の関数だけをJackの実装に戻しても同じように動く
+// 同じクラスの関数はGoで直接呼び、他のクラスの関数はMachine.Callで呼ぶ
+type Builtin func(m *Machine, args []int16) (int16, error)
+
+var builtins = map[string]Builtin{}
+
+// 組み込み関数が使うstatic変数の数（Jackの実装での宣言順にインデックスを振る）
+var builtinStatics = map[string]int{
+	"Math":     2, // twoToThe, twoQY
+	"Memory":   2, // ram, freeList
+	"Screen":   3, // screen, bits, color
+	"Output":   7, // screen, glyphs, font, fontIndex, cursorRow, cursorCol, numberBuffer
+	"Keyboard": 1, // maxLineLength
+}
+
+func init() {
+	register := func(functions map[string]Builtin) {
+		for name, builtin := range functions {
+			builtins[name] = builtin
+		}
+	}
+	register(sysBuiltins)
+	register(memoryBuiltins)
+	register(arrayBuiltins)
+	register(mathBuiltins)
+	register(keyboardBuiltins)
+	register(stringBuiltins)
+	register(outputBuiltins)
+	register(screenBuiltins)
+}
+
+// 組み込み関数の名前の一覧
+func BuiltinNames() []string {
+	names := make([]string, 0, len(builtins))
+	for name := range builtins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// モジュールのindex番目のstatic変数
+func (m *Machine) static(module string, index int) *int16 {
+	return &m.Computer.RAM[m.statics[module]+index]
+}
+
+// Hackと同じく、アドレスは下位15ビットだけを使う
+func (m *Machine) word(address int16) *int16 {
+	return &m.Computer.RAM[int(uint16(address))%emulator.RAMSize]
+}
+
+// Sys.initがMain.mainの前に呼び出すOSの初期化関数
+var InitFunctions = []string{"Memory.init", "Math.init", "Screen.init", "Output.init", "Keyboard.init"}
+
+var sysBuiltins = map[string]Builtin{
+	"Sys.init": func(m *Machine, args []int16) (int16, error) {
+		for _, name := range append(append([]string{}, InitFunctions...), "Main.main", "Sys.halt") {
+			if _, err := m.Call(name); err != nil {
+				return 0, err
+			}
+		}
+		return 0, nil
+	},
+	"Sys.halt": func(m *Machine, args []int16) (int16, error) {
+		return 0, errHalted
+	},
+	"Sys.error": func(m *Machine, args []int16) (int16, error) {
+		return 0, sysError(args[0])
+	},
+	// 実行時間は測らないので、待たずに戻る
+	"Sys.wait": func(m *Machine, args []int16) (int16, error) {
+		if args[0] < 0 {
+			return 0, sysError(1)
+		}
+		return 0, nil
+	},
+}
+
+var memoryBuiltins = map[string]Builtin{
+	"Memory.init": func(m *Machine, args []int16) (int16, error) {
+		*m.static("Memory", 0) = 0
+		*m.static("Memory", 1) = 2048
+		*m.word(2048) = 14336
+		*m.word(2049) = 0
+		return 0, nil
+	},
+	"Memory.peek": func(m *Machine, args []int16) (int16, error) {
+		return *m.word(args[0]), nil
+	},
+	"Memory.poke": func(m *Machine, args []int16) (int16, error) {
+		*m.word(args[0]) = args[1]
+		return 0, nil
+	},
+	"Memory.alloc": func(m *Machine, args []int16) (int16, error) {
+		return memoryAlloc(m, args[0])
+	},
+	"Memory.deAlloc": func(m *Machine, args []int16) (int16, error) {
+		memoryDeAlloc(m, args[0])
+		return 0, nil
+	},
+}
+
+// Memory.jackと同じfirst-fitで、空きブロックの末尾から切り出す
+func memoryAlloc(m *Machine, size int16) (int16, error) {
+	if size < 0 {
+		return 0, sysError(5)
+	}
+	if size == 0 {
+		size = 1
+	}
+
+	freeList := m.static("Memory", 1)
+	need := size + 1
+	prev := int16(0)
+	block := *freeList
+	for block != 0 {
+		if *m.word(block) > need+1 {
+			*m.word(block) -= need
+			block += *m.word(block)
+			*m.word(block) = need
+			return block + 1, nil
+		}
+		if *m.word(block) >= need {
+			if prev == 0 {
+				*freeList = *m.word(block + 1)
+			} else {
+				*m.word(prev + 1) = *m.word(block + 1)
+			}
+			return block + 1, nil
+		}
+		prev = block
+		block = *m.word(block + 1)
+	}
+	return 0, sysError(6)
+}
+
+// アドレス順にリストへ戻し、隣り合う空きブロックとは結合する
+func memoryDeAlloc(m *Machine, object int16) {
+	freeList := m.static("Memory", 1)
+	block := object - 1
+	prev := int16(0)
+	next := *freeList
+	for next != 0 && next < block {
+		prev = next
+		next = *m.word(next + 1)
+	}
+
+	if next != 0 && block+*m.word(block) == next {
+		*m.word(block) += *m.word(next)
+		*m.word(block + 1) = *m.word(next + 1)
+	} else {
+		*m.word(block + 1) = next
+	}
+
+	if prev == 0 {
+		*freeList = block
+		return
+	}
+	if prev+*m.word(prev) == block {
+		*m.word(prev) += *m.word(block)
+		*m.word(prev + 1) = *m.word(block + 1)
+	} else {
+		*m.word(prev + 1) = block
+	}
+}
+
+var arrayBuiltins = map[string]Builtin{
+	"Array.new": func(m *Machine, args []int16) (int16, error) {
+		if args[0] < 0 {
+			return 0, sysError(2)
+		}
+		return m.Call("Memory.alloc", args[0])
+	},
+	"Array.dispose": func(m *Machine, args []int16) (int16, error) {
+		_, err := m.Call("Memory.deAlloc", args[0])
+		return 0, err
+	},
+}
+
+var mathBuiltins = map[string]Builtin{
+	"Math.init": func(m *Machine, args []int16) (int16, error) {
+		twoToThe, err := m.Call("Array.new", 16)
+		if err != nil {
+			return 0, err
+		}
+		*m.static("Math", 0) = twoToThe
+		for i := int16(0); i < 16; i++ {
+			*m.word(twoToThe + i) = int16(1 << uint(i))
+		}
+		return 0, nil
+	},
+	"Math.abs": func(m *Machine, args []int16) (int16, error) {
+		if args[0] < 0 {
+			return -args[0], nil
+		}
+		return args[0], nil
+	},
+	"Math.multiply": func(m *Machine, args []int16) (int16, error) {
+		return args[0] * args[1], nil
+	},
+	// Goの整数の割り算と同じく0に近い方に切り捨て、-32768 / -1 は-32768になる
+	"Math.divide": func(m *Machine, args []int16) (int16, error) {
+		if args[1] == 0 {
+			return 0, sysError(3)
+		}
+		return args[0] / args[1], nil
+	},
+	"Math.sqrt": func(m *Machine, args []int16) (int16, error) {
+		if args[0] < 0 {
+			return 0, sysError(4)
+		}
+		y := int16(0)
+		for y < 181 && int(y+1)*int(y+1) <= int(args[0]) {
+			y++
+		}
+		return y, nil
+	},
+	"Math.max": func(m *Machine, args []int16) (int16, error) {
+		if args[0] > args[1] {
+			return args[0], nil
+		}
+		return args[1], nil
+	},
+	"Math.min": func(m *Machine, args []int16) (int16, error) {
+		if args[0] < args[1] {
+			return args[0], nil
+		}
+		return args[1], nil
+	},
+}
+
+var keyboardBuiltins = map[string]Builtin{
+	"Keyboard.init": func(m *Machine, args []int16) (int16, error) {
+		*m.static("Keyboard", 0) = 80
+		return 0, nil
+	},
+	"Keyboard.keyPressed": func(m *Machine, args []int16) (int16, error) {
+		return m.Computer.RAM[emulator.KeyboardAddress], nil
+	},
+	// 押されるのを待たずに、スクリプトの次のキーを返す
+	"Keyboard.readKey": func(m *Machine, args []int16) (int16, error) {
+		m.keyActive = true
+		if m.keyIndex >= len(m.keys) {
+			return 0, errors.New("error Keyboard.readKey: no more keys")
+		}
+		key := m.keys[m.keyIndex]
+		m.keyDown = true
+		m.releaseKey()
+		return key, nil
+	},
+	"Keyboard.readChar": func(m *Machine, args []int16) (int16, error) {
+		c, err := m.Call("Keyboard.readKey")
+		if err != nil {
+			return 0, err
+		}
+		_, err = m.Call("Output.printChar", c)
+		return c, err
+	},
+	"Keyboard.readLine": keyboardReadLine,
+	"Keyboard.readInt": func(m *Machine, args []int16) (int16, error) {
+		line, err := keyboardReadLine(m, args)
+		if err != nil {
+			return 0, err
+		}
+		value, err := m.Call("String.intValue", line)
+		if err != nil {
+			return 0, err
+		}
+		_, err = m.Call("String.dispose", line)
+		return value, err
+	},
+}
+
+func keyboardReadLine(m *Machine, args []int16) (int16, error) {
+	maxLineLength := *m.static("Keyboard", 0)
+	if _, err := m.Call("Output.printString", args[0]); err != nil {
+		return 0, err
+	}
+	line, err := m.Call("String.new", maxLineLength)
+	if err != nil {
+		return 0, err
+	}
+
+	for {
+		c, err := m.Call("Keyboard.readKey")
+		if err != nil {
+			return 0, err
+		}
+		if c == 128 {
+			_, err := m.Call("Output.println")
+			return line, err
+		}
+
+		length, err := m.Call("String.length", line)
+		if err != nil {
+			return 0, err
+		}
+		if c == 129 {
+			if length > 0 {
+				if _, err := m.Call("String.eraseLastChar", line); err != nil {
+					return 0, err
+				}
+				if _, err := m.Call("Output.backSpace"); err != nil {
+					return 0, err
+				}
+			}
+			continue
+		}
+		if length < maxLineLength {
+			if _, err := m.Call("String.appendChar", c, line); err != nil {
+				return 0, err
+			}
+			if _, err := m.Call("Output.printChar", c); err != nil {
+				return 0, err
+			}
+		}
+	}
+}
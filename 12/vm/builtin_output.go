@@ -0,0 +1,198 @@
+package vm
+
+import (
+	"../emulator"
+)
+
+// Output.jackのstatic変数のインデックス
+const (
+	outputScreen = iota
+	outputGlyphs
+	outputFont
+	outputFontIndex
+	outputCursorRow
+	outputCursorCol
+	outputNumberBuffer
+)
+
+// Output.jackのinitFontと同じ圧縮したフォントデータ
+// 1ワードに3行分（1行5ビット、下位ビットが左端）を詰めて、1文字を3ワードで表す
+var outputFontData = []int16{
+	17983, 17969, 31, 0, 0, 0, 4228, 132, 4, 10570, 0, 0,
+	32074, 11242, 10, 6084, 16014, 4, 8803, 25668, 24, 5414, 9890, 22,
+	2180, 0, 0, 2184, 4162, 8, 8322, 4360, 2, 21632, 4782, 0,
+	4224, 4255, 0, 0, 4288, 2, 0, 31, 0, 0, 6144, 6,
+	8704, 1092, 0, 26158, 18037, 14, 4292, 4228, 14, 16942, 2184, 31,
+	4383, 17928, 14, 10632, 9193, 8, 15423, 17936, 14, 1100, 17967, 14,
+	8735, 2116, 2, 17966, 17966, 14, 17966, 8734, 6, 6336, 6336, 0,
+	6336, 4288, 2, 2184, 4161, 8, 31744, 992, 0, 8322, 4368, 2,
+	16942, 136, 4, 16942, 22198, 14, 17966, 17983, 17, 17967, 17967, 15,
+	1582, 17441, 14, 17967, 17969, 15, 1087, 1071, 31, 1087, 1071, 1,
+	1582, 17981, 30, 17969, 17983, 17, 4238, 4228, 14, 8476, 9480, 6,
+	5425, 9379, 17, 1057, 1057, 31, 22385, 17973, 17, 20017, 18229, 17,
+	17966, 17969, 14, 17967, 1071, 1, 17966, 9905, 22, 17967, 9391, 17,
+	1086, 16910, 15, 4255, 4228, 4, 17969, 17969, 14, 17969, 10801, 4,
+	17969, 22197, 10, 10801, 17732, 17, 10801, 4228, 4, 8735, 1092, 31,
+	2126, 2114, 14, 2080, 16644, 0, 8462, 8456, 14, 17732, 0, 0,
+	0, 0, 31, 8322, 0, 0, 14336, 18384, 30, 13345, 17971, 15,
+	14336, 17441, 14, 23056, 17977, 30, 14336, 2033, 14, 2636, 2119, 2,
+	30720, 17969, 14878, 13345, 17971, 17, 6148, 4228, 14, 12296, 8456, 6440,
+	9249, 5221, 9, 4230, 4228, 14, 11264, 22197, 21, 13312, 17971, 17,
+	14336, 17969, 14, 15360, 17969, 1071, 30720, 17969, 16926, 13312, 1075, 1,
+	14336, 16833, 15, 7234, 18498, 12, 17408, 26161, 22, 17408, 10801, 4,
+	17408, 22193, 10, 17408, 10378, 17, 17408, 17969, 14878, 31744, 2184, 31,
+	4232, 4226, 8, 4228, 4228, 4, 4226, 4232, 2, 2048, 277, 0,
+}
+
+var outputBuiltins = map[string]Builtin{
+	// Jackの実装と同じ順にヒープ領域を確保して、フォントを展開する
+	"Output.init": func(m *Machine, args []int16) (int16, error) {
+		*m.static("Output", outputScreen) = emulator.ScreenAddress
+		font, err := m.Call("Array.new", int16(len(outputFontData)))
+		if err != nil {
+			return 0, err
+		}
+		*m.static("Output", outputFont) = font
+		for i, word := range outputFontData {
+			*m.word(font + int16(i)) = word
+		}
+		*m.static("Output", outputFontIndex) = int16(len(outputFontData))
+
+		glyphs, err := m.Call("Array.new", int16(len(outputFontData)*3))
+		if err != nil {
+			return 0, err
+		}
+		*m.static("Output", outputGlyphs) = glyphs
+		for i, word := range outputFontData {
+			for j := 0; j < 3; j++ {
+				// フォントの左端がセルの2列目になるように1ビットずらす
+				row := ((word >> uint(j*5)) & 31) << 1
+				*m.word(glyphs + int16(i*3+j)) = row
+			}
+		}
+		if _, err := m.Call("Array.dispose", font); err != nil {
+			return 0, err
+		}
+
+		numberBuffer, err := m.Call("String.new", 6)
+		if err != nil {
+			return 0, err
+		}
+		*m.static("Output", outputNumberBuffer) = numberBuffer
+		*m.static("Output", outputCursorRow) = 0
+		*m.static("Output", outputCursorCol) = 0
+		return 0, nil
+	},
+	"Output.moveCursor": func(m *Machine, args []int16) (int16, error) {
+		i, j := args[0], args[1]
+		if i < 0 || i > 22 || j < 0 || j > 63 {
+			return 0, sysError(20)
+		}
+		*m.static("Output", outputCursorRow) = i
+		*m.static("Output", outputCursorCol) = j
+		outputDrawChar(m, ' ')
+		return 0, nil
+	},
+	"Output.printChar": func(m *Machine, args []int16) (int16, error) {
+		outputPrintChar(m, args[0])
+		return 0, nil
+	},
+	"Output.printString": func(m *Machine, args []int16) (int16, error) {
+		return 0, outputPrintString(m, args[0])
+	},
+	"Output.printInt": func(m *Machine, args []int16) (int16, error) {
+		numberBuffer := *m.static("Output", outputNumberBuffer)
+		if _, err := m.Call("String.setInt", args[0], numberBuffer); err != nil {
+			return 0, err
+		}
+		return 0, outputPrintString(m, numberBuffer)
+	},
+	"Output.println": func(m *Machine, args []int16) (int16, error) {
+		outputPrintln(m)
+		return 0, nil
+	},
+	"Output.backSpace": func(m *Machine, args []int16) (int16, error) {
+		outputBackSpace(m)
+		return 0, nil
+	},
+}
+
+// カーソル位置に文字を描く（カーソルは動かさない）
+// 1ワードに2文字分が入っていて、偶数列は下位8ビット、奇数列は上位8ビットになる
+func outputDrawChar(m *Machine, c int16) {
+	index := int16(0)
+	if c >= 32 && c <= 126 {
+		index = (c - 31) * 9
+	}
+
+	glyphs := *m.static("Output", outputGlyphs)
+	cursorCol := *m.static("Output", outputCursorCol)
+	address := *m.static("Output", outputScreen) + *m.static("Output", outputCursorRow)*352 + cursorCol/2
+	for k := int16(0); k < 11; k++ {
+		value := int16(0)
+		if k != 0 && k != 10 {
+			value = *m.word(glyphs + index + k - 1)
+		}
+		word := m.word(address + k*32)
+		if cursorCol&1 == 0 {
+			*word = *word&-256 | value
+		} else {
+			*word = *word&255 | value<<8
+		}
+	}
+}
+
+func outputPrintChar(m *Machine, c int16) {
+	switch c {
+	case 128:
+		outputPrintln(m)
+		return
+	case 129:
+		outputBackSpace(m)
+		return
+	}
+
+	outputDrawChar(m, c)
+	cursorCol := m.static("Output", outputCursorCol)
+	*cursorCol++
+	if *cursorCol == 64 {
+		outputPrintln(m)
+	}
+}
+
+func outputPrintString(m *Machine, s int16) error {
+	length, err := m.Call("String.length", s)
+	if err != nil {
+		return err
+	}
+	for i := int16(0); i < length; i++ {
+		c, err := m.Call("String.charAt", i, s)
+		if err != nil {
+			return err
+		}
+		outputPrintChar(m, c)
+	}
+	return nil
+}
+
+// 最終行の次は先頭の行に戻る
+func outputPrintln(m *Machine) {
+	*m.static("Output", outputCursorCol) = 0
+	cursorRow := m.static("Output", outputCursorRow)
+	*cursorRow++
+	if *cursorRow == 23 {
+		*cursorRow = 0
+	}
+}
+
+func outputBackSpace(m *Machine) {
+	cursorRow := m.static("Output", outputCursorRow)
+	cursorCol := m.static("Output", outputCursorCol)
+	if *cursorCol > 0 {
+		*cursorCol--
+	} else if *cursorRow > 0 {
+		*cursorRow--
+		*cursorCol = 63
+	}
+	outputDrawChar(m, ' ')
+}
@@ -0,0 +1,147 @@
+package vm
+
+import (
+	"../emulator"
+)
+
+// Screen.jackのstatic変数のインデックス
+const (
+	screenScreen = iota
+	screenBits
+	screenColor
+)
+
+var screenBuiltins = map[string]Builtin{
+	"Screen.init": func(m *Machine, args []int16) (int16, error) {
+		*m.static("Screen", screenScreen) = emulator.ScreenAddress
+		bits, err := m.Call("Array.new", 16)
+		if err != nil {
+			return 0, err
+		}
+		*m.static("Screen", screenBits) = bits
+		for i := int16(0); i < 16; i++ {
+			*m.word(bits + i) = int16(1 << uint(i))
+		}
+		*m.static("Screen", screenColor) = -1
+		return 0, nil
+	},
+	"Screen.clearScreen": func(m *Machine, args []int16) (int16, error) {
+		screen := *m.static("Screen", screenScreen)
+		for i := int16(0); i < emulator.ScreenSize; i++ {
+			*m.word(screen + i) = 0
+		}
+		return 0, nil
+	},
+	"Screen.setColor": func(m *Machine, args []int16) (int16, error) {
+		*m.static("Screen", screenColor) = args[0]
+		return 0, nil
+	},
+	"Screen.drawPixel": func(m *Machine, args []int16) (int16, error) {
+		x, y := args[0], args[1]
+		if x < 0 || x > 511 || y < 0 || y > 255 {
+			return 0, sysError(7)
+		}
+		screenDrawHorizontal(m, y, x, x)
+		return 0, nil
+	},
+	"Screen.drawLine": func(m *Machine, args []int16) (int16, error) {
+		x1, y1, x2, y2 := args[0], args[1], args[2], args[3]
+		for _, v := range []int16{x1, x2} {
+			if v < 0 || v > 511 {
+				return 0, sysError(8)
+			}
+		}
+		for _, v := range []int16{y1, y2} {
+			if v < 0 || v > 255 {
+				return 0, sysError(8)
+			}
+		}
+
+		// 常に左から右へ描く
+		if x1 > x2 {
+			x1, x2 = x2, x1
+			y1, y2 = y2, y1
+		}
+		if y1 == y2 {
+			screenDrawHorizontal(m, y1, x1, x2)
+			return 0, nil
+		}
+
+		// Screen.jackと同じ順にピクセルを選ぶ
+		dx := x2 - x1
+		dy := y2 - y1
+		yStep := int16(1)
+		if dy < 0 {
+			dy = -dy
+			yStep = -1
+		}
+		a, b, diff, y := int16(0), int16(0), int16(0), y1
+		for a <= dx && b <= dy {
+			screenDrawHorizontal(m, y, x1+a, x1+a)
+			if diff < 0 {
+				a++
+				diff += dy
+			} else {
+				b++
+				y += yStep
+				diff -= dx
+			}
+		}
+		return 0, nil
+	},
+	"Screen.drawRectangle": func(m *Machine, args []int16) (int16, error) {
+		x1, y1, x2, y2 := args[0], args[1], args[2], args[3]
+		if x1 > x2 || y1 > y2 || x1 < 0 || x2 > 511 || y1 < 0 || y2 > 255 {
+			return 0, sysError(9)
+		}
+		for y := y1; y <= y2; y++ {
+			screenDrawHorizontal(m, y, x1, x2)
+		}
+		return 0, nil
+	},
+	// スクリーンからはみ出す部分は描かない
+	"Screen.drawCircle": func(m *Machine, args []int16) (int16, error) {
+		x, y, r := args[0], args[1], args[2]
+		if x < 0 || x > 511 || y < 0 || y > 255 {
+			return 0, sysError(12)
+		}
+		if r < 0 || r > 181 {
+			return 0, sysError(13)
+		}
+
+		for dy := -r; dy <= r; dy++ {
+			if y+dy < 0 || y+dy > 255 {
+				continue
+			}
+			half, err := m.Call("Math.sqrt", r*r-dy*dy)
+			if err != nil {
+				return 0, err
+			}
+			left, err := m.Call("Math.max", x-half, 0)
+			if err != nil {
+				return 0, err
+			}
+			right, err := m.Call("Math.min", x+half, 511)
+			if err != nil {
+				return 0, err
+			}
+			screenDrawHorizontal(m, y+dy, left, right)
+		}
+		return 0, nil
+	},
+}
+
+// y行目のx1列目からx2列目までを現在の色で塗る
+func screenDrawHorizontal(m *Machine, y int16, x1 int16, x2 int16) {
+	screen := *m.static("Screen", screenScreen)
+	black := *m.static("Screen", screenColor) != 0
+	for x := x1; x <= x2; x++ {
+		word := m.word(screen + y*32 + x/16)
+		mask := int16(1 << uint(x&15))
+		if black {
+			*word |= mask
+		} else {
+			*word &^= mask
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package vm
+
+import (
+	"strconv"
+)
+
+// Stringオブジェクトのフィールド（String.jackでの宣言順）
+const (
+	stringChars = iota
+	stringSize
+	stringCapacity
+	stringFieldLength
+)
+
+var stringBuiltins = map[string]Builtin{
+	"String.new": func(m *Machine, args []int16) (int16, error) {
+		if args[0] < 0 {
+			return 0, sysError(14)
+		}
+		this, err := m.Call("Memory.alloc", stringFieldLength)
+		if err != nil {
+			return 0, err
+		}
+		if args[0] > 0 {
+			chars, err := m.Call("Array.new", args[0])
+			if err != nil {
+				return 0, err
+			}
+			*m.word(this + stringChars) = chars
+		}
+		*m.word(this + stringCapacity) = args[0]
+		*m.word(this + stringSize) = 0
+		return this, nil
+	},
+	"String.dispose": func(m *Machine, args []int16) (int16, error) {
+		this := args[0]
+		if *m.word(this + stringCapacity) > 0 {
+			if _, err := m.Call("Array.dispose", *m.word(this + stringChars)); err != nil {
+				return 0, err
+			}
+		}
+		_, err := m.Call("Memory.deAlloc", this)
+		return 0, err
+	},
+	"String.length": func(m *Machine, args []int16) (int16, error) {
+		return *m.word(args[0] + stringSize), nil
+	},
+	"String.charAt": func(m *Machine, args []int16) (int16, error) {
+		j, this := args[0], args[1]
+		if j < 0 || j >= *m.word(this + stringSize) {
+			return 0, sysError(15)
+		}
+		return *m.word(*m.word(this + stringChars) + j), nil
+	},
+	"String.setCharAt": func(m *Machine, args []int16) (int16, error) {
+		j, c, this := args[0], args[1], args[2]
+		if j < 0 || j >= *m.word(this + stringSize) {
+			return 0, sysError(16)
+		}
+		*m.word(*m.word(this + stringChars) + j) = c
+		return 0, nil
+	},
+	"String.appendChar": func(m *Machine, args []int16) (int16, error) {
+		this := args[1]
+		return this, stringAppendChar(m, this, args[0])
+	},
+	"String.eraseLastChar": func(m *Machine, args []int16) (int16, error) {
+		size := m.word(args[0] + stringSize)
+		if *size == 0 {
+			return 0, sysError(18)
+		}
+		*size--
+		return 0, nil
+	},
+	// 先頭から数字が続く部分を整数に変換する（先頭の「-」は負の数）
+	"String.intValue": func(m *Machine, args []int16) (int16, error) {
+		this := args[0]
+		chars := *m.word(this + stringChars)
+		size := *m.word(this + stringSize)
+		i := int16(0)
+		value := int16(0)
+		negative := size > 0 && *m.word(chars) == '-'
+		if negative {
+			i = 1
+		}
+		for ; i < size; i++ {
+			digit := *m.word(chars + i) - '0'
+			if digit < 0 || digit > 9 {
+				break
+			}
+			value = value*10 + digit
+		}
+		if negative {
+			return -value, nil
+		}
+		return value, nil
+	},
+	"String.setInt": func(m *Machine, args []int16) (int16, error) {
+		return 0, stringSetInt(m, args[1], args[0])
+	},
+	"String.newLine": func(m *Machine, args []int16) (int16, error) {
+		return 128, nil
+	},
+	"String.backSpace": func(m *Machine, args []int16) (int16, error) {
+		return 129, nil
+	},
+	"String.doubleQuote": func(m *Machine, args []int16) (int16, error) {
+		return 34, nil
+	},
+}
+
+func stringAppendChar(m *Machine, this int16, c int16) error {
+	size := m.word(this + stringSize)
+	if *size >= *m.word(this + stringCapacity) {
+		return sysError(17)
+	}
+	*m.word(*m.word(this + stringChars) + *size) = c
+	*size++
+	return nil
+}
+
+func stringSetInt(m *Machine, this int16, value int16) error {
+	*m.word(this + stringSize) = 0
+	for _, c := range strconv.Itoa(int(value)) {
+		if err := stringAppendChar(m, this, int16(c)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
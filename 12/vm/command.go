@@ -0,0 +1,96 @@
+package vm
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"strconv"
+	"strings"
+)
+
+type CommandType int
+
+const (
+	CommandArithmetic CommandType = iota
+	CommandPush
+	CommandPop
+	CommandLabel
+	CommandGoto
+	CommandIf
+	CommandFunction
+	CommandReturn
+	CommandCall
+)
+
+// VMの1コマンド
+// ラベルは08のVMトランスレータと同じくモジュール（ファイル）単位で区別する
+type Command struct {
+	Type   CommandType
+	Arg1   string
+	Arg2   int
+	Module string
+	raw    string
+}
+
+var commandTypes = map[string]CommandType{
+	"push":     CommandPush,
+	"pop":      CommandPop,
+	"label":    CommandLabel,
+	"goto":     CommandGoto,
+	"if-goto":  CommandIf,
+	"function": CommandFunction,
+	"call":     CommandCall,
+}
+
+var arithmeticCommands = map[string]bool{
+	"add": true, "sub": true, "neg": true,
+	"eq": true, "gt": true, "lt": true,
+	"and": true, "or": true, "not": true,
+}
+
+// コメントと前後の空白を除いた1行をパースする
+func ParseCommand(line string, module string) (*Command, error) {
+	command := &Command{Module: module, raw: line}
+	split := strings.Fields(line)
+	switch len(split) {
+	case 1:
+		if split[0] == "return" {
+			command.Type = CommandReturn
+			return command, nil
+		}
+		if !arithmeticCommands[split[0]] {
+			return nil, command.error("unknown command")
+		}
+		command.Type = CommandArithmetic
+		command.Arg1 = split[0]
+		return command, nil
+	case 2, 3:
+		commandType, ok := commandTypes[split[0]]
+		if !ok {
+			return nil, command.error("unknown command")
+		}
+		command.Type = commandType
+		command.Arg1 = split[1]
+		hasArg2 := commandType == CommandPush || commandType == CommandPop ||
+			commandType == CommandFunction || commandType == CommandCall
+		if hasArg2 != (len(split) == 3) {
+			return nil, command.error("wrong number of arguments")
+		}
+		if hasArg2 {
+			num, err := strconv.Atoi(split[2])
+			if err != nil || num < 0 {
+				return nil, command.error("invalid number")
+			}
+			command.Arg2 = num
+		}
+		return command, nil
+	}
+	return nil, command.error("wrong number of arguments")
+}
+
+func (c *Command) String() string {
+	return c.raw
+}
+
+func (c *Command) error(message string) error {
+	return errors.New(fmt.Sprintf("error ParseCommand: %s: %s: %s", message, c.Module, c.raw))
+}
@@ -0,0 +1,531 @@
+package vm
+
+import (
+	"../emulator"
+	"fmt"
+	"github.com/pkg/errors"
+	"sort"
+)
+
+// RAM上のレジスタとセグメントの位置は08のVMトランスレータと同じ
+const (
+	SP             = 0
+	LCL            = 1
+	ARG            = 2
+	THIS           = 3
+	THAT           = 4
+	TempAddress    = 5
+	StaticAddress  = 16
+	StackAddress   = 256
+	heapAddress    = 2048 // スタック領域の終わり
+	maxStaticIndex = 255
+)
+
+// キーボード入力のスクリプト
+// プログラムが最初にKeyboard.keyPressedを呼んだ時点から、
+// 各キーをKeyStepsコマンドの間押し続け、次のキーまでKeyStepsコマンドの間離す
+const KeySteps = 20000
+
+var (
+	errHalted    = errors.New("halted")
+	errStepLimit = errors.New("step limit")
+)
+
+// VMコマンドを直接実行するインタプリタ
+// RAMとスクリーンはエミュレータのものをそのまま使うので、Hackに変換して実行した場合と同じ位置に結果が残る
+// OSの関数はGoで実装した組み込み関数で置き換えられ、UseJackで1つずつJackの実装に戻せる
+type Machine struct {
+	Computer *emulator.Computer
+	Steps    int // 実行したコマンド数（組み込み関数の呼び出しは1コマンドと数える）
+
+	program   *Program
+	natives   map[string]Builtin
+	statics   map[string]int // モジュールごとのstaticセグメントの先頭アドレス
+	pc        int
+	maxSteps  int
+	keys      []int16
+	keyIndex  int
+	keyTimer  int
+	keyActive bool // キー入力のスクリプトを開始したか
+	keyDown   bool
+	keyEvents emulator.KeyEvents // 指定した場合は、実行したコマンド数でキーボードのメモリマップにキーを書き込む
+	onStep    func() error
+}
+
+func NewMachine(program *Program) (*Machine, error) {
+	// リターンアドレスはRAMに16ビットで保存するので、コマンド数には上限がある
+	if len(program.Commands) > 32767 {
+		message := fmt.Sprintf("error NewMachine: program too large: %d commands", len(program.Commands))
+		return nil, errors.New(message)
+	}
+
+	m := &Machine{
+		Computer: emulator.NewComputer([]uint16{}),
+		program:  program,
+		natives:  map[string]Builtin{},
+		statics:  map[string]int{},
+	}
+	for name, builtin := range builtins {
+		m.natives[name] = builtin
+	}
+
+	// アセンブラと同じく、staticセグメントは16番地から現れた順に割り当てる
+	// 組み込み関数はOSのクラスのstatic変数をJackの実装と共有するので、.vmファイルがなくても領域を確保する
+	address := StaticAddress
+	sizes := map[string]int{}
+	modules := append([]string{}, program.modules...)
+	for _, module := range program.modules {
+		sizes[module] = program.statics[module]
+	}
+	for _, module := range sortedKeys(builtinStatics) {
+		if _, ok := sizes[module]; !ok {
+			modules = append(modules, module)
+		}
+		if sizes[module] < builtinStatics[module] {
+			sizes[module] = builtinStatics[module]
+		}
+	}
+	for _, module := range modules {
+		m.statics[module] = address
+		address += sizes[module]
+	}
+	if address > maxStaticIndex+1 {
+		message := fmt.Sprintf("error NewMachine: too many static variables: %d", address-StaticAddress)
+		return nil, errors.New(message)
+	}
+	return m, nil
+}
+
+// 組み込み関数の代わりにJackで実装した関数を呼び出す
+func (m *Machine) UseJack(name string) error {
+	if _, ok := builtins[name]; !ok {
+		return errors.New(fmt.Sprintf("error UseJack: not a builtin: %s", name))
+	}
+	if _, ok := m.program.Functions[name]; !ok {
+		return errors.New(fmt.Sprintf("error UseJack: undefined function: %s", name))
+	}
+	delete(m.natives, name)
+	return nil
+}
+
+func (m *Machine) UseNative(name string) error {
+	builtin, ok := builtins[name]
+	if !ok {
+		return errors.New(fmt.Sprintf("error UseNative: not a builtin: %s", name))
+	}
+	m.natives[name] = builtin
+	return nil
+}
+
+func (m *Machine) SetKeys(keys []int16) {
+	m.keys = keys
+}
+
+// 実行したコマンド数に応じて、キーボードのメモリマップにキーを書き込む
+// Keyboard.keyPressedで読めるが、組み込み関数のKeyboard.readKeyはSetKeysのスクリプトを読む
+func (m *Machine) SetKeyEvents(events emulator.KeyEvents) {
+	m.keyEvents = events
+}
+
+// 各コマンドを実行する前に呼び出す関数
+// スクリーンのスナップショットなどに使う
+func (m *Machine) SetOnStep(f func() error) {
+	m.onStep = f
+}
+
+// Sys.initを呼び出して、Sys.haltが呼ばれるまで実行する
+// maxSteps以内に停止しなければfalseを返す
+// Sys.errorが呼ばれた場合は、そのエラーコードをエラーとして返す
+func (m *Machine) Run(maxSteps int) (bool, error) {
+	m.maxSteps = maxSteps
+	m.Computer.RAM[SP] = StackAddress
+	_, err := m.Call("Sys.init")
+	if err == errHalted {
+		return true, nil
+	}
+	if err == errStepLimit {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Main.mainの最初のコマンドの手前で止める
+// デバッガのように、Stepを1つずつ呼び出して実行する場合に使う
+// Sys.initが組み込み関数の場合は、Sys.initの代わりにOSの初期化関数だけを呼び出して、Main.mainを呼び出す
+// Sys.initをJackで実装した場合は、Sys.initの最初のコマンドの手前で止める
+// 最初に呼び出した関数から戻るとPCが-1になる
+func (m *Machine) Start(maxSteps int) error {
+	m.maxSteps = maxSteps
+	m.Computer.RAM[SP] = StackAddress
+	if _, ok := m.natives["Sys.init"]; !ok {
+		return m.call("Sys.init", 0, -1)
+	}
+	for _, name := range InitFunctions {
+		if _, err := m.Call(name); err != nil {
+			return err
+		}
+	}
+	return m.call("Main.main", 0, -1)
+}
+
+// 次に実行するコマンドの位置
+func (m *Machine) PC() int {
+	return m.pc
+}
+
+// モジュールのstaticセグメントの先頭アドレス
+func (m *Machine) StaticAddress(module string) (int, bool) {
+	address, ok := m.statics[module]
+	return address, ok
+}
+
+// Sys.haltが呼ばれて停止したことを表すエラーかどうか
+func IsHalted(err error) bool {
+	return err == errHalted
+}
+
+// 関数を呼び出して、戻り値を返す
+// 組み込み関数から、Jackで実装した関数を呼び出すときにも使う
+func (m *Machine) Call(name string, args ...int16) (int16, error) {
+	for _, arg := range args {
+		if err := m.push(arg); err != nil {
+			return 0, err
+		}
+	}
+	caller := m.pc
+	if err := m.call(name, len(args), -1); err != nil {
+		return 0, err
+	}
+	for m.pc != -1 {
+		if err := m.Step(); err != nil {
+			return 0, err
+		}
+	}
+	m.pc = caller
+	return m.pop()
+}
+
+func (m *Machine) Step() error {
+	if m.Steps >= m.maxSteps {
+		return errStepLimit
+	}
+	if m.pc < 0 || m.pc >= len(m.program.Commands) {
+		return errors.New(fmt.Sprintf("error Step: pc out of program: %d", m.pc))
+	}
+	if m.onStep != nil {
+		if err := m.onStep(); err != nil {
+			return err
+		}
+	}
+	m.updateKey()
+	m.Steps++
+
+	command := m.program.Commands[m.pc]
+	switch command.Type {
+	case CommandArithmetic:
+		if err := m.arithmetic(command.Arg1); err != nil {
+			return err
+		}
+	case CommandPush:
+		if command.Arg1 == "constant" {
+			if err := m.push(int16(command.Arg2)); err != nil {
+				return err
+			}
+			break
+		}
+		address, err := m.address(command)
+		if err != nil {
+			return err
+		}
+		if err := m.push(m.Computer.RAM[address]); err != nil {
+			return err
+		}
+	case CommandPop:
+		address, err := m.address(command)
+		if err != nil {
+			return err
+		}
+		value, err := m.pop()
+		if err != nil {
+			return err
+		}
+		m.Computer.RAM[address] = value
+	case CommandLabel:
+	case CommandGoto:
+		index, err := m.program.label(command.Module, command.Arg1)
+		if err != nil {
+			return err
+		}
+		m.pc = index
+		return nil
+	case CommandIf:
+		index, err := m.program.label(command.Module, command.Arg1)
+		if err != nil {
+			return err
+		}
+		value, err := m.pop()
+		if err != nil {
+			return err
+		}
+		if value != 0 {
+			m.pc = index
+			return nil
+		}
+	case CommandFunction:
+		for i := 0; i < command.Arg2; i++ {
+			if err := m.push(0); err != nil {
+				return err
+			}
+		}
+	case CommandReturn:
+		return m.returnFunction()
+	case CommandCall:
+		return m.call(command.Arg1, command.Arg2, m.pc+1)
+	}
+	m.pc++
+	return nil
+}
+
+func (m *Machine) arithmetic(operation string) error {
+	if operation == "neg" || operation == "not" {
+		top, err := m.top()
+		if err != nil {
+			return err
+		}
+		if operation == "neg" {
+			*top = -*top
+		} else {
+			*top = ^*top
+		}
+		return nil
+	}
+
+	y, err := m.pop()
+	if err != nil {
+		return err
+	}
+	x, err := m.pop()
+	if err != nil {
+		return err
+	}
+	switch operation {
+	case "add":
+		return m.push(x + y)
+	case "sub":
+		return m.push(x - y)
+	case "and":
+		return m.push(x & y)
+	case "or":
+		return m.push(x | y)
+	case "eq":
+		return m.push(truth(x == y))
+	case "gt":
+		return m.push(truth(x > y))
+	case "lt":
+		return m.push(truth(x < y))
+	}
+	return nil
+}
+
+func (m *Machine) address(command *Command) (int, error) {
+	ram := m.Computer.RAM
+	index := command.Arg2
+	var address int
+	switch command.Arg1 {
+	case "local":
+		address = int(ram[LCL]) + index
+	case "argument":
+		address = int(ram[ARG]) + index
+	case "this":
+		address = int(ram[THIS]) + index
+	case "that":
+		address = int(ram[THAT]) + index
+	case "temp":
+		if index > 7 {
+			return 0, m.commandError(command, "temp index out of range")
+		}
+		address = TempAddress + index
+	case "pointer":
+		if index > 1 {
+			return 0, m.commandError(command, "pointer index out of range")
+		}
+		address = THIS + index
+	case "static":
+		address = m.statics[command.Module] + index
+	default:
+		return 0, m.commandError(command, "unknown segment")
+	}
+	if address < 0 || address >= emulator.RAMSize {
+		return 0, m.commandError(command, fmt.Sprintf("address out of range: %d", address))
+	}
+	return address, nil
+}
+
+// 組み込み関数は引数を取り出して直接実行し、Jackの関数は08と同じフレームを積んで呼び出す
+// 戻り先が-1のときは、Goから呼び出した関数から戻ったことを表す
+func (m *Machine) call(name string, argLength int, returnAddress int) error {
+	if name == "Keyboard.keyPressed" {
+		m.keyActive = true
+	}
+
+	if builtin, ok := m.natives[name]; ok {
+		args := make([]int16, argLength)
+		for i := argLength - 1; i >= 0; i-- {
+			arg, err := m.pop()
+			if err != nil {
+				return err
+			}
+			args[i] = arg
+		}
+		value, err := builtin(m, args)
+		if err != nil {
+			return err
+		}
+		if err := m.push(value); err != nil {
+			return err
+		}
+		m.pc = returnAddress
+		return nil
+	}
+
+	index, ok := m.program.Functions[name]
+	if !ok {
+		return errors.New(fmt.Sprintf("error call: undefined function: %s", name))
+	}
+	// Hackで実行する場合と同じく、Sys.haltとSys.errorに入った時点で停止する
+	switch name {
+	case "Sys.halt":
+		return errHalted
+	case "Sys.error":
+		code, err := m.top()
+		if err != nil {
+			return err
+		}
+		return sysError(*code)
+	}
+
+	ram := m.Computer.RAM
+	for _, value := range []int16{int16(returnAddress), ram[LCL], ram[ARG], ram[THIS], ram[THAT]} {
+		if err := m.push(value); err != nil {
+			return err
+		}
+	}
+	ram[ARG] = ram[SP] - int16(argLength) - 5
+	ram[LCL] = ram[SP]
+	m.pc = index
+	return nil
+}
+
+// 呼び出し元のフレーム（LCLの手前の5ワード）と戻り値を書き込む位置（ARG）も、スタック領域の中になければならない
+func (m *Machine) returnFunction() error {
+	ram := m.Computer.RAM
+	frame := ram[LCL]
+	if frame-5 < StackAddress || frame > heapAddress || ram[ARG] < StackAddress || ram[ARG] >= heapAddress {
+		message := fmt.Sprintf("error return: frame out of stack %d..%d: LCL = %d, ARG = %d", StackAddress, heapAddress-1, frame, ram[ARG])
+		return errors.New(message)
+	}
+	returnAddress := ram[frame-5]
+	value, err := m.pop()
+	if err != nil {
+		return err
+	}
+	ram[ram[ARG]] = value
+	ram[SP] = ram[ARG] + 1
+	ram[THAT] = ram[frame-1]
+	ram[THIS] = ram[frame-2]
+	ram[ARG] = ram[frame-3]
+	ram[LCL] = ram[frame-4]
+	m.pc = int(returnAddress)
+	return nil
+}
+
+// 深い再帰でスタックがヒープまで伸びた場合や、SPが壊れた場合はRAMの範囲外を読み書きせずにエラーにする
+func (m *Machine) push(value int16) error {
+	ram := m.Computer.RAM
+	sp := ram[SP]
+	if sp < StackAddress || sp >= heapAddress {
+		return errors.New(fmt.Sprintf("error push: SP out of stack %d..%d: %d", StackAddress, heapAddress-1, sp))
+	}
+	ram[sp] = value
+	ram[SP]++
+	return nil
+}
+
+func (m *Machine) pop() (int16, error) {
+	top, err := m.top()
+	if err != nil {
+		return 0, err
+	}
+	m.Computer.RAM[SP]--
+	return *top, nil
+}
+
+// スタックの一番上の値
+func (m *Machine) top() (*int16, error) {
+	ram := m.Computer.RAM
+	sp := ram[SP]
+	if sp <= StackAddress || sp > heapAddress {
+		return nil, errors.New(fmt.Sprintf("error pop: SP out of stack %d..%d: %d", StackAddress, heapAddress-1, sp))
+	}
+	return &ram[sp-1], nil
+}
+
+func (m *Machine) updateKey() {
+	if m.keyEvents != nil {
+		m.Computer.SetKey(m.keyEvents.KeyAt(m.Steps))
+		return
+	}
+	if !m.keyActive {
+		return
+	}
+	m.keyTimer++
+	if m.keyTimer < KeySteps {
+		return
+	}
+	m.keyTimer = 0
+	if m.keyDown {
+		m.releaseKey()
+		return
+	}
+	if m.keyIndex < len(m.keys) {
+		m.keyDown = true
+		m.Computer.SetKey(m.keys[m.keyIndex])
+	}
+}
+
+// 押しているキーを離して、次のキーに進む
+func (m *Machine) releaseKey() {
+	if m.keyDown {
+		m.keyIndex++
+	}
+	m.keyDown = false
+	m.keyTimer = 0
+	m.Computer.SetKey(0)
+}
+
+func (m *Machine) commandError(command *Command, message string) error {
+	return errors.New(fmt.Sprintf("error Step: %s: %s: %s", message, command.Module, command))
+}
+
+func sysError(code int16) error {
+	return errors.New(fmt.Sprintf("Sys.error(%d)", code))
+}
+
+func truth(b bool) int16 {
+	if b {
+		return -1
+	}
+	return 0
+}
+
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,263 @@
+package vm
+
+import (
+	"github.com/google/go-cmp/cmp"
+	"testing"
+)
+
+func TestMachineRun(t *testing.T) {
+	// 結果をRAM[8000]以降に書き込む
+	setThat := []string{"push constant 8000", "pop pointer 1"}
+
+	cases := []struct {
+		desc    string
+		modules map[string][]string
+		jack    []string // 組み込み関数の代わりにJackで実装した関数を使う関数
+		want    []int16
+	}{
+		{
+			desc: "算術演算と比較",
+			modules: map[string][]string{
+				"Main": append(append([]string{"function Main.main 0"}, setThat...),
+					"push constant 7", "push constant 3", "sub", "pop that 0",
+					"push constant 7", "neg", "pop that 1",
+					"push constant 7", "push constant 3", "gt", "pop that 2",
+					"push constant 7", "push constant 3", "lt", "pop that 3",
+					"push constant 12", "push constant 10", "and", "push constant 1", "or", "not", "pop that 4",
+					"push constant 0", "return",
+				),
+			},
+			want: []int16{4, -7, -1, 0, -10},
+		},
+		{
+			desc: "ループとラベル",
+			modules: map[string][]string{
+				"Main": append(append([]string{"function Main.main 2"}, setThat...),
+					"push constant 10", "pop local 0",
+					"label LOOP",
+					"push local 0", "push local 1", "add", "pop local 1",
+					"push local 0", "push constant 1", "sub", "pop local 0",
+					"push local 0", "if-goto LOOP",
+					"push local 1", "pop that 0",
+					"push constant 0", "return",
+				),
+			},
+			want: []int16{55},
+		},
+		{
+			desc: "再帰呼び出しと組み込み関数",
+			modules: map[string][]string{
+				"Main": append(append([]string{"function Main.main 0"}, setThat...),
+					"push constant 7", "call Main.fact 1", "pop that 0",
+					"push constant 0", "return",
+					"function Main.fact 0",
+					"push argument 0", "push constant 2", "lt", "if-goto ONE",
+					"push argument 0",
+					"push argument 0", "push constant 1", "sub", "call Main.fact 1",
+					"call Math.multiply 2",
+					"return",
+					"label ONE",
+					"push constant 1", "return",
+				),
+			},
+			want: []int16{5040},
+		},
+		{
+			desc: "Jackの実装に切り替えた関数",
+			modules: map[string][]string{
+				"Main": append(append([]string{"function Main.main 0"}, setThat...),
+					"push constant 6", "push constant 7", "call Math.multiply 2", "pop that 0",
+					"push constant 0", "return",
+				),
+				"Math": {
+					"function Math.multiply 0",
+					"push argument 0", "push argument 1", "add", "return",
+				},
+			},
+			jack: []string{"Math.multiply"},
+			want: []int16{13},
+		},
+		{
+			desc: "モジュールごとのstaticセグメント",
+			modules: map[string][]string{
+				"Main": append(append([]string{"function Main.main 0"}, setThat...),
+					"push constant 1", "pop static 0",
+					"call Foo.set 0", "pop temp 0",
+					"push static 0", "pop that 0",
+					"call Foo.get 0", "pop that 1",
+					"push constant 0", "return",
+				),
+				"Foo": {
+					"function Foo.set 0", "push constant 2", "pop static 0", "push constant 0", "return",
+					"function Foo.get 0", "push static 0", "return",
+				},
+			},
+			want: []int16{1, 2},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.desc, func(t *testing.T) {
+			m := newTestMachine(t, tt.modules)
+			for _, name := range tt.jack {
+				if err := m.UseJack(name); err != nil {
+					t.Fatalf("%+v", err)
+				}
+			}
+			halted, err := m.Run(100000)
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if !halted {
+				t.Fatal("error Run: not halted")
+			}
+			got := append([]int16{}, m.Computer.RAM[8000:8000+len(tt.want)]...)
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("(-got +want)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestMachineRunError(t *testing.T) {
+	cases := []struct {
+		desc    string
+		modules map[string][]string
+		jack    []string
+		want    string
+	}{
+		{
+			desc: "0で割るとSys.error(3)",
+			modules: map[string][]string{
+				"Main": {"function Main.main 0", "push constant 1", "push constant 0", "call Math.divide 2", "return"},
+			},
+			want: "Sys.error(3)",
+		},
+		{
+			desc: "Jackで実装したSys.errorに入ると停止する",
+			modules: map[string][]string{
+				"Main": {"function Main.main 0", "push constant 9", "call Sys.error 1", "return"},
+				"Sys":  {"function Sys.error 0", "label LOOP", "goto LOOP"},
+			},
+			jack: []string{"Sys.error"},
+			want: "Sys.error(9)",
+		},
+		{
+			desc: "未定義の関数",
+			modules: map[string][]string{
+				"Main": {"function Main.main 0", "call Main.foo 0", "return"},
+			},
+			want: "error call: undefined function: Main.foo",
+		},
+		{
+			desc: "未定義のラベル",
+			modules: map[string][]string{
+				"Main": {"function Main.main 0", "goto END", "return"},
+			},
+			want: "error label: undefined label: Main$END",
+		},
+		{
+			desc: "終わらない再帰でスタックがヒープまで伸びる",
+			modules: map[string][]string{
+				"Main": {"function Main.main 0", "call Main.main 0", "return"},
+			},
+			want: "error push: SP out of stack 256..2047: 2048",
+		},
+		{
+			desc: "壊れたSPに積む",
+			modules: map[string][]string{
+				"Main": {"function Main.main 0", "push constant 0", "pop pointer 1", "push constant 30000", "pop that 0", "push constant 1", "return"},
+			},
+			want: "error push: SP out of stack 256..2047: 30000",
+		},
+		{
+			desc: "空のスタックから取り出す",
+			modules: map[string][]string{
+				"Main": {"function Main.main 0", "push constant 0", "pop pointer 1", "push constant 256", "pop that 0", "add", "return"},
+			},
+			want: "error pop: SP out of stack 256..2047: 256",
+		},
+		{
+			desc: "壊れたLCLから戻る",
+			modules: map[string][]string{
+				"Main": {"function Main.main 0", "push constant 0", "pop pointer 1", "push constant 3", "pop that 1", "push constant 0", "return"},
+			},
+			want: "error return: frame out of stack 256..2047: LCL = 3, ARG = 256",
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.desc, func(t *testing.T) {
+			m := newTestMachine(t, tt.modules)
+			for _, name := range tt.jack {
+				if err := m.UseJack(name); err != nil {
+					t.Fatalf("%+v", err)
+				}
+			}
+			_, err := m.Run(100000)
+			if err == nil {
+				t.Fatal("error Run: expected error")
+			}
+			if diff := cmp.Diff(err.Error(), tt.want); diff != "" {
+				t.Errorf("(-got +want)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestMachineRunStepLimit(t *testing.T) {
+	m := newTestMachine(t, map[string][]string{
+		"Main": {"function Main.main 0", "label LOOP", "goto LOOP"},
+	})
+	halted, err := m.Run(1000)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if halted {
+		t.Error("error Run: expected not halted")
+	}
+	if m.Steps != 1000 {
+		t.Errorf("Steps: got = %d, want = 1000", m.Steps)
+	}
+}
+
+func TestParseCommandError(t *testing.T) {
+	cases := []struct {
+		line string
+		want string
+	}{
+		{line: "mul", want: "error ParseCommand: unknown command: Main: mul"},
+		{line: "push constant", want: "error ParseCommand: wrong number of arguments: Main: push constant"},
+		{line: "goto END 1", want: "error ParseCommand: wrong number of arguments: Main: goto END 1"},
+		{line: "push constant x", want: "error ParseCommand: invalid number: Main: push constant x"},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.line, func(t *testing.T) {
+			_, err := ParseCommand(tt.line, "Main")
+			if err == nil {
+				t.Fatal("error ParseCommand: expected error")
+			}
+			if diff := cmp.Diff(err.Error(), tt.want); diff != "" {
+				t.Errorf("(-got +want)\n%s", diff)
+			}
+		})
+	}
+}
+
+func newTestMachine(t *testing.T, modules map[string][]string) *Machine {
+	t.Helper()
+	program := NewProgram()
+	for _, module := range []string{"Main", "Foo", "Math", "Sys"} {
+		if lines, ok := modules[module]; ok {
+			if err := program.Add(module, lines); err != nil {
+				t.Fatalf("%+v", err)
+			}
+		}
+	}
+	m, err := NewMachine(program)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	return m
+}
@@ -0,0 +1,119 @@
+package vm
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/pkg/errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// 複数の.vmファイルのコマンドをまとめたもの
+type Program struct {
+	Commands  []*Command
+	Functions map[string]int // 関数名とその「function」コマンドの位置
+	labels    map[string]int // 「モジュール名$ラベル名」とその位置
+	statics   map[string]int // モジュールごとのstaticセグメントの大きさ
+	modules   []string       // モジュールを追加した順
+}
+
+func NewProgram() *Program {
+	return &Program{
+		Commands:  []*Command{},
+		Functions: map[string]int{},
+		labels:    map[string]int{},
+		statics:   map[string]int{},
+		modules:   []string{},
+	}
+}
+
+// ディレクトリ内の全ての.vmファイルを読み込む
+func LoadDir(dir string) (*Program, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.vm"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return LoadFiles(files)
+}
+
+func LoadFiles(files []string) (*Program, error) {
+	program := NewProgram()
+	for _, file := range files {
+		lines, err := readLines(file)
+		if err != nil {
+			return nil, err
+		}
+		module := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+		if err := program.Add(module, lines); err != nil {
+			return nil, err
+		}
+	}
+	return program, nil
+}
+
+// モジュールのソースを追加する（コメントと空行は読み飛ばす）
+func (p *Program) Add(module string, lines []string) error {
+	if _, ok := p.statics[module]; !ok {
+		p.statics[module] = 0
+		p.modules = append(p.modules, module)
+	}
+
+	for _, line := range lines {
+		if index := strings.Index(line, "//"); index >= 0 {
+			line = line[:index]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		command, err := ParseCommand(line, module)
+		if err != nil {
+			return err
+		}
+		switch command.Type {
+		case CommandFunction:
+			if _, ok := p.Functions[command.Arg1]; ok {
+				return errors.New(fmt.Sprintf("error Add: duplicate function: %s", command.Arg1))
+			}
+			p.Functions[command.Arg1] = len(p.Commands)
+		case CommandLabel:
+			p.labels[module+"$"+command.Arg1] = len(p.Commands)
+		case CommandPush, CommandPop:
+			if command.Arg1 == "static" && command.Arg2 >= p.statics[module] {
+				p.statics[module] = command.Arg2 + 1
+			}
+		}
+		p.Commands = append(p.Commands, command)
+	}
+	return nil
+}
+
+func (p *Program) label(module string, name string) (int, error) {
+	index, ok := p.labels[module+"$"+name]
+	if !ok {
+		return 0, errors.New(fmt.Sprintf("error label: undefined label: %s$%s", module, name))
+	}
+	return index, nil
+}
+
+func readLines(file string) ([]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	lines := []string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
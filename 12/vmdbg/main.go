@@ -0,0 +1,81 @@
+package main
+
+import (
+	"../../11/jack"
+	"../vmdebugger"
+	"bufio"
+	"flag"
+	"fmt"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+)
+
+// ディレクトリのJackのプログラムを、VMのコマンド単位でデバッガで実行する
+// Jackのソースの行と関数名でブレークポイントを指定し、変数をJackの名前で表示できる
+// -xでスクリプトを指定すると、スクリプトを実行して終了する（エラーの場合は終了コード1）
+// 指定しない場合は、標準入力からコマンドを読む
+//
+//	vmdbg ../11/Fixture/Seven
+//	vmdbg -x average.dbg -input numbers.txt ../11/Fixture/Average
+func main() {
+	if err := run(); err != nil {
+		log.Fatalf("%+v\n", err)
+	}
+}
+
+func run() error {
+	script := flag.String("x", "", "実行するスクリプト")
+	maxSteps := flag.Int("steps", vmdebugger.DefaultMaxSteps, "continue、step、next、finishで実行する最大コマンド数")
+	input := flag.String("input", "", "キーボードから読む入力のファイル（改行は改行キーになる）")
+	jackFunctions := flag.String("jack", "", "Jackの実装を使うOSの関数（カンマ区切り）")
+	flag.Parse()
+	if flag.NArg() != 1 {
+		return errors.New(fmt.Sprintf("usage: %s [-x script] [-steps N] [-input file] [-jack functions] <dir>", os.Args[0]))
+	}
+
+	names := []string{}
+	if *jackFunctions != "" {
+		for _, name := range strings.Split(*jackFunctions, ",") {
+			names = append(names, strings.TrimSpace(name))
+		}
+	}
+	d, err := vmdebugger.LoadDir(flag.Arg(0), jack.NewOptions(), os.Stdout, names...)
+	if err != nil {
+		return err
+	}
+	d.MaxSteps = *maxSteps
+	if *input != "" {
+		text, err := ioutil.ReadFile(*input)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		d.SetInput(string(text))
+	}
+
+	if *script != "" {
+		src, err := ioutil.ReadFile(*script)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		return errors.WithMessage(d.RunScript(string(src)), *script)
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for {
+		fmt.Print("(vmdbg) ")
+		if !scanner.Scan() {
+			fmt.Println()
+			return errors.WithStack(scanner.Err())
+		}
+		line := scanner.Text()
+		if line == "quit" || line == "q" {
+			return nil
+		}
+		if err := d.Execute(line); err != nil {
+			fmt.Println(err)
+		}
+	}
+}
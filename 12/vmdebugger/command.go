@@ -0,0 +1,216 @@
+package vmdebugger
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const Help = `break <function|Class.jack:line>   ブレークポイントを設定する (b)
+delete <function|Class.jack:line>  ブレークポイントを削除する (d)
+step                               Jackの次の行まで実行する（呼び出した関数にも入る） (s)
+next                               Jackの次の行まで実行する（呼び出した関数には入らない） (n)
+finish                             今の関数から戻るまで実行する
+stepi [n]                          VMのコマンドをn個実行する
+continue [n]                       ブレークポイントまで、最大n個のコマンドを実行する (c)
+backtrace                          呼び出し中の関数と、各フレームのセグメントの値を表示する (bt)
+print <name|segment index>         今のフレームの変数の値を表示する (p)
+expect <name|segment index> <v>    値が違ったらエラーにする
+list                               今の行の前後のソースを表示する (l)
+info                               ブレークポイントを表示する (i)
+echo <text>                        文字列を表示する`
+
+var commandAliases = map[string]string{
+	"b":  "break",
+	"d":  "delete",
+	"s":  "step",
+	"n":  "next",
+	"c":  "continue",
+	"bt": "backtrace",
+	"p":  "print",
+	"l":  "list",
+	"i":  "info",
+}
+
+// スクリプトを1行ずつ実行する
+// 空行と「//」以降は無視し、最初のエラーで止める
+func (d *Debugger) RunScript(src string) error {
+	for i, line := range strings.Split(src, "\n") {
+		if err := d.Execute(line); err != nil {
+			return errors.WithMessage(err, fmt.Sprintf("line %d", i+1))
+		}
+	}
+	return nil
+}
+
+// コマンドを1つ実行する
+func (d *Debugger) Execute(line string) error {
+	if index := strings.Index(line, "//"); index >= 0 {
+		line = line[:index]
+	}
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil
+	}
+	name, args := fields[0], fields[1:]
+	if alias, ok := commandAliases[name]; ok {
+		name = alias
+	}
+
+	switch name {
+	case "break", "delete":
+		if len(args) == 0 {
+			return errors.New(fmt.Sprintf("%s expects at least 1 argument", name))
+		}
+		for _, arg := range args {
+			var err error
+			if name == "break" {
+				err = d.Break(arg)
+			} else {
+				err = d.Delete(arg)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	case "step":
+		return d.report(true)(d.Step())
+	case "next":
+		return d.report(true)(d.Next())
+	case "finish":
+		return d.report(true)(d.Finish())
+	case "stepi":
+		n, err := optionalCount(args, 1)
+		if err != nil {
+			return err
+		}
+		return d.report(false)(d.StepCommand(n))
+	case "continue":
+		n, err := optionalCount(args, d.MaxSteps)
+		if err != nil {
+			return err
+		}
+		return d.report(true)(d.Continue(n))
+	case "backtrace":
+		for i, frame := range d.Backtrace() {
+			for _, line := range d.formatFrame(i, frame) {
+				fmt.Fprintln(d.out, line)
+			}
+		}
+	case "print":
+		if len(args) == 0 {
+			return errors.New("print expects a variable")
+		}
+		target := strings.Join(args, " ")
+		value, err := d.Value(target)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(d.out, "%s = %d\n", target, value)
+	case "expect":
+		if len(args) < 2 {
+			return errors.New("expect expects a variable and a value")
+		}
+		return d.expect(strings.Join(args[:len(args)-1], " "), args[len(args)-1])
+	case "list":
+		d.list()
+	case "info":
+		d.info()
+	case "echo":
+		fmt.Fprintln(d.out, strings.Join(args, " "))
+	case "help":
+		fmt.Fprintln(d.out, Help)
+	default:
+		return errors.New(fmt.Sprintf("unknown command %s", name))
+	}
+	return nil
+}
+
+func optionalCount(args []string, defaultCount int) (int, error) {
+	if len(args) == 0 {
+		return defaultCount, nil
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n <= 0 {
+		return 0, errors.New(fmt.Sprintf("invalid count %s", args[0]))
+	}
+	return n, nil
+}
+
+// 止まった理由と、次に実行する位置を表示する
+// sourceがtrueならJackのソースの行を、falseならVMのコマンドを表示する
+func (d *Debugger) report(source bool) func(string, error) error {
+	return func(message string, err error) error {
+		if err != nil {
+			return err
+		}
+		if message != "" {
+			fmt.Fprintln(d.out, message)
+		}
+		if d.finished {
+			return nil
+		}
+
+		pc := d.Machine.PC()
+		f, line := d.lineAt(pc)
+		text := ""
+		if source && f != nil {
+			text = d.sourceLine(className(f.name), line)
+		}
+		if text == "" {
+			text = d.program.Commands[pc].String()
+		}
+		fmt.Fprintf(d.out, "=> %s: %s\n", d.location(pc), text)
+		return nil
+	}
+}
+
+func (d *Debugger) expect(target string, s string) error {
+	want, err := strconv.Atoi(s)
+	if err != nil {
+		return errors.New(fmt.Sprintf("invalid value %s", s))
+	}
+	got, err := d.Value(target)
+	if err != nil {
+		return err
+	}
+	if int(got) != want {
+		return errors.New(fmt.Sprintf("expect %s: got %d, want %d", target, got, want))
+	}
+	return nil
+}
+
+// 今の行の前後2行ずつのソースを表示する
+func (d *Debugger) list() {
+	if d.finished {
+		return
+	}
+	f, line := d.lineAt(d.Machine.PC())
+	if f == nil || line == 0 {
+		return
+	}
+	lines := d.sources[className(f.name)]
+	for i := line - 2; i <= line+2; i++ {
+		if i < 1 || i > len(lines) {
+			continue
+		}
+		marker := "  "
+		if i == line {
+			marker = "=>"
+		}
+		fmt.Fprintf(d.out, "%s%4d  %s\n", marker, i, lines[i-1])
+	}
+}
+
+func (d *Debugger) info() {
+	indexes := []int{}
+	for index := range d.breakpoints {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	for _, index := range indexes {
+		fmt.Fprintf(d.out, "Breakpoint %s\n", d.location(index))
+	}
+}
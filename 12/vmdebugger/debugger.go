@@ -0,0 +1,362 @@
+package vmdebugger
+
+import (
+	"../../11/jack"
+	"../../11/parsing"
+	"../vm"
+	"fmt"
+	"github.com/pkg/errors"
+	"io"
+	"math"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// continueで実行する最大コマンド数のデフォルト
+const DefaultMaxSteps = 10000000
+
+// Hackのキーボードの改行キー
+const newLineKey = 128
+
+// VMの関数と、その「function」コマンドの位置
+type function struct {
+	name  string
+	index int
+}
+
+// JackのプログラムをVMのコマンド単位で実行するデバッガ
+// コンパイラが出力したデバッグ情報を使って、VMのコマンドをJackのソースの行に、
+// セグメントのインデックスをJackの変数名に対応させる
+type Debugger struct {
+	Machine     *vm.Machine
+	MaxSteps    int
+	program     *vm.Program
+	classes     map[string]*parsing.DebugInfo
+	subroutines map[string]*parsing.DebugSubroutine // VMの関数名とデバッグ情報
+	sources     map[string][]string                 // クラス名とJackのソース
+	functions   []*function                         // 「function」コマンドの位置順
+	breakpoints map[int]bool
+	finished    bool
+	out         io.Writer
+}
+
+// デバッグ情報付きでパースしたクラスからデバッガを作る
+// OSの関数はVMの組み込み関数を使い、jackで指定した関数だけをJackの実装に戻す
+// Main.mainの最初のコマンドの手前で止まった状態から始める
+func NewDebugger(units []*jack.Unit, out io.Writer, jackFunctions ...string) (*Debugger, error) {
+	program := vm.NewProgram()
+	classes := map[string]*parsing.DebugInfo{}
+	subroutines := map[string]*parsing.DebugSubroutine{}
+	sources := map[string][]string{}
+	for _, unit := range units {
+		className := unit.Src.ClassName()
+		if err := program.Add(className, unit.Code); err != nil {
+			return nil, errors.WithMessage(err, unit.Filename)
+		}
+		classes[className] = unit.DebugInfo
+		for _, subroutine := range unit.DebugInfo.Subroutines {
+			subroutines[subroutine.Name] = subroutine
+		}
+		sources[className] = unit.Src.Org
+	}
+
+	machine, err := vm.NewMachine(program)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range jackFunctions {
+		if err := machine.UseJack(name); err != nil {
+			return nil, err
+		}
+	}
+
+	functions := []*function{}
+	for name, index := range program.Functions {
+		functions = append(functions, &function{name: name, index: index})
+	}
+	sort.Slice(functions, func(i, j int) bool {
+		return functions[i].index < functions[j].index
+	})
+
+	d := &Debugger{
+		Machine:     machine,
+		MaxSteps:    DefaultMaxSteps,
+		program:     program,
+		classes:     classes,
+		subroutines: subroutines,
+		sources:     sources,
+		functions:   functions,
+		breakpoints: map[int]bool{},
+		out:         out,
+	}
+	if err := machine.Start(math.MaxInt32); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// ディレクトリのJackファイルをデバッグ情報付きでコンパイルして、デバッガを作る
+func LoadDir(dir string, options *jack.Options, out io.Writer, jackFunctions ...string) (*Debugger, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.jack"))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	sort.Strings(files)
+
+	copied := *options
+	copied.DebugInfo = true
+	units, err := jack.ParseFiles(files, &copied)
+	if err != nil {
+		return nil, err
+	}
+	return NewDebugger(units, out, jackFunctions...)
+}
+
+// Keyboard.readKeyなどで読む入力を設定する
+// 改行はHackの改行キー（128）として読む
+func (d *Debugger) SetInput(text string) {
+	keys := []int16{}
+	for _, c := range text {
+		if c == '\n' {
+			keys = append(keys, newLineKey)
+			continue
+		}
+		keys = append(keys, int16(c))
+	}
+	d.Machine.SetKeys(keys)
+}
+
+// VMのコマンドの位置を含む関数
+func (d *Debugger) functionAt(pc int) *function {
+	i := sort.Search(len(d.functions), func(i int) bool {
+		return d.functions[i].index > pc
+	}) - 1
+	if i < 0 {
+		return nil
+	}
+	return d.functions[i]
+}
+
+// VMのコマンドの位置に対応するJackのソースの行
+// デバッグ情報がない関数では0を返す
+func (d *Debugger) lineAt(pc int) (*function, int) {
+	f := d.functionAt(pc)
+	if f == nil {
+		return nil, 0
+	}
+	subroutine, ok := d.subroutines[f.name]
+	if !ok {
+		return f, 0
+	}
+	line := 0
+	for _, debugLine := range subroutine.Lines {
+		if debugLine.Command > pc-f.index {
+			break
+		}
+		line = debugLine.Line
+	}
+	return f, line
+}
+
+// VMのコマンドの位置が、Jackのソースの行の最初のコマンドかどうか
+func (d *Debugger) isLineStart(pc int) bool {
+	f := d.functionAt(pc)
+	if f == nil {
+		return false
+	}
+	subroutine, ok := d.subroutines[f.name]
+	if !ok {
+		return false
+	}
+	for _, debugLine := range subroutine.Lines {
+		if debugLine.Command == pc-f.index {
+			return true
+		}
+	}
+	return false
+}
+
+func className(functionName string) string {
+	return strings.SplitN(functionName, ".", 2)[0]
+}
+
+// 「Main.main+3 (Main.jack:5)」のように、関数の中の位置とソースの行を書く
+func (d *Debugger) location(pc int) string {
+	f, line := d.lineAt(pc)
+	if f == nil {
+		return strconv.Itoa(pc)
+	}
+	result := f.name
+	if pc > f.index {
+		result += fmt.Sprintf("+%d", pc-f.index)
+	}
+	if line > 0 {
+		result += fmt.Sprintf(" (%s.jack:%d)", className(f.name), line)
+	}
+	return result
+}
+
+// Jackのソースの行（読み込めない場合は空）
+func (d *Debugger) sourceLine(class string, line int) string {
+	lines := d.sources[class]
+	if line < 1 || line > len(lines) {
+		return ""
+	}
+	return strings.TrimSpace(lines[line-1])
+}
+
+// ブレークポイントの位置
+// 関数名なら関数の入り口、「Main.jack:12」や「Main:12」ならその行の最初のコマンド
+// 行にコマンドがない場合は、同じクラスでそれより後ろの一番近い行を使う
+func (d *Debugger) breakpointIndex(target string) (int, error) {
+	if index, ok := d.program.Functions[target]; ok {
+		return index, nil
+	}
+
+	separator := strings.LastIndex(target, ":")
+	if separator < 0 {
+		return 0, errors.New(fmt.Sprintf("unknown function %s", target))
+	}
+	class := strings.TrimSuffix(target[:separator], ".jack")
+	line, err := strconv.Atoi(target[separator+1:])
+	if err != nil {
+		return 0, errors.New(fmt.Sprintf("invalid line %s", target))
+	}
+	info, ok := d.classes[class]
+	if !ok {
+		return 0, errors.New(fmt.Sprintf("unknown class %s", class))
+	}
+
+	found := false
+	bestLine, bestIndex := 0, 0
+	for _, subroutine := range info.Subroutines {
+		index, ok := d.program.Functions[subroutine.Name]
+		if !ok {
+			continue
+		}
+		for _, debugLine := range subroutine.Lines {
+			if debugLine.Line < line {
+				continue
+			}
+			if !found || debugLine.Line < bestLine || (debugLine.Line == bestLine && index+debugLine.Command < bestIndex) {
+				found = true
+				bestLine, bestIndex = debugLine.Line, index+debugLine.Command
+			}
+		}
+	}
+	if !found {
+		return 0, errors.New(fmt.Sprintf("no code at %s", target))
+	}
+	return bestIndex, nil
+}
+
+func (d *Debugger) Break(target string) error {
+	index, err := d.breakpointIndex(target)
+	if err != nil {
+		return err
+	}
+	d.breakpoints[index] = true
+	return nil
+}
+
+func (d *Debugger) Delete(target string) error {
+	index, err := d.breakpointIndex(target)
+	if err != nil {
+		return err
+	}
+	if !d.breakpoints[index] {
+		return errors.New(fmt.Sprintf("no breakpoint at %s", target))
+	}
+	delete(d.breakpoints, index)
+	return nil
+}
+
+// 最大maxSteps個のコマンドを実行し、止まった理由を返す
+// untilがtrueを返した場合と、maxSteps個のコマンドを実行し終えた場合は空文字列を返す
+// 最初のコマンドはブレークポイントで止めないので、ブレークポイントで止まった後も続けて実行できる
+func (d *Debugger) run(maxSteps int, until func() bool) (string, error) {
+	m := d.Machine
+	for i := 0; ; i++ {
+		// Sys.initをJackで実装していない場合は、Main.mainから戻ったら終了
+		if d.finished || m.PC() < 0 {
+			d.finished = true
+			return "Program finished", nil
+		}
+		if i > 0 && until != nil && until() {
+			return "", nil
+		}
+		if i > 0 && d.breakpoints[m.PC()] {
+			return fmt.Sprintf("Breakpoint at %s", d.location(m.PC())), nil
+		}
+		if i == maxSteps {
+			return "", nil
+		}
+		if err := m.Step(); err != nil {
+			if vm.IsHalted(err) {
+				d.finished = true
+				return "Program finished", nil
+			}
+			return "", err
+		}
+	}
+}
+
+// VMのコマンドをn個実行する
+func (d *Debugger) StepCommand(n int) (string, error) {
+	return d.run(n, nil)
+}
+
+// Jackのソースの次の行まで実行する
+// 呼び出した関数の中にも入る
+func (d *Debugger) Step() (string, error) {
+	f, line := d.lineAt(d.Machine.PC())
+	return d.run(d.MaxSteps, func() bool {
+		pc := d.Machine.PC()
+		if !d.isLineStart(pc) {
+			return false
+		}
+		current, currentLine := d.lineAt(pc)
+		return current != f || currentLine != line
+	})
+}
+
+// 呼び出した関数の中では止まらずに、Jackのソースの次の行まで実行する
+// 再帰呼び出しで同じ行に着いた場合は、LCLが呼び出し前より深いので区別できる
+func (d *Debugger) Next() (string, error) {
+	ram := d.Machine.Computer.RAM
+	f, line := d.lineAt(d.Machine.PC())
+	lcl := ram[vm.LCL]
+	return d.run(d.MaxSteps, func() bool {
+		// 関数から戻った場合は、呼び出し元の次の行で止める
+		if ram[vm.LCL] < lcl {
+			lcl = ram[vm.LCL]
+			f = nil
+		}
+		pc := d.Machine.PC()
+		if ram[vm.LCL] > lcl || !d.isLineStart(pc) {
+			return false
+		}
+		current, currentLine := d.lineAt(pc)
+		return current != f || currentLine != line
+	})
+}
+
+// 今の関数から戻るまで実行する
+func (d *Debugger) Finish() (string, error) {
+	ram := d.Machine.Computer.RAM
+	lcl := ram[vm.LCL]
+	return d.run(d.MaxSteps, func() bool {
+		return ram[vm.LCL] < lcl
+	})
+}
+
+// ブレークポイントで止まるまで実行する
+func (d *Debugger) Continue(maxSteps int) (string, error) {
+	message, err := d.run(maxSteps, nil)
+	if err != nil || message != "" {
+		return message, err
+	}
+	return fmt.Sprintf("Stopped after %d steps", maxSteps), nil
+}
@@ -0,0 +1,275 @@
+package vmdebugger
+
+import (
+	"../../11/jack"
+	"bytes"
+	"github.com/google/go-cmp/cmp"
+	"strings"
+	"testing"
+)
+
+var sources = map[string][]string{
+	"Main": {
+		"class Main {",
+		"  static int result;",
+		"  function void main() {",
+		"    var Counter c;",
+		"    let c = Counter.new(2);",
+		"    do c.add(3);",
+		"    let result = Main.sum(3);",
+		"    return;",
+		"  }",
+		"  function int sum(int n) {",
+		"    if (n = 0) {",
+		"      return 0;",
+		"    }",
+		"    return n + Main.sum(n - 1);",
+		"  }",
+		"}",
+	},
+	"Counter": {
+		"class Counter {",
+		"  static int total;",
+		"  field int count, step;",
+		"  constructor Counter new(int s) {",
+		"    let step = s;",
+		"    return this;",
+		"  }",
+		"  method void add(int n) {",
+		"    var int i;",
+		"    let i = 0;",
+		"    while (i < n) {",
+		"      let count = count + step;",
+		"      let i = i + 1;",
+		"    }",
+		"    let total = total + count;",
+		"    return;",
+		"  }",
+		"}",
+	},
+}
+
+func newTestDebugger(t *testing.T, b *bytes.Buffer) *Debugger {
+	options := jack.NewOptions()
+	options.DebugInfo = true
+	units := []*jack.Unit{}
+	for _, name := range []string{"Counter", "Main"} {
+		unit, err := jack.ParseLines(name+".jack", sources[name], options)
+		if err != nil {
+			t.Fatalf("failed ParseLines: %+v", err)
+		}
+		units = append(units, unit)
+	}
+	d, err := NewDebugger(units, b)
+	if err != nil {
+		t.Fatalf("failed NewDebugger: %+v", err)
+	}
+	return d
+}
+
+func TestDebuggerRunScript(t *testing.T) {
+	cases := []struct {
+		desc   string
+		script []string
+		want   []string
+	}{
+		{
+			desc: "関数と行のブレークポイントで止まり、変数をJackの名前で表示する",
+			script: []string{
+				"b Counter.add",
+				"b Counter.jack:14",
+				"info",
+				"c",
+				"s",
+				"s",
+				"bt",
+				"c",
+				"bt",
+				"expect count 6",
+				"expect this 1 2",
+				"p total",
+			},
+			want: []string{
+				"Breakpoint Counter.add (Counter.jack:8)",
+				"Breakpoint Counter.add+21 (Counter.jack:15)",
+				"Breakpoint at Counter.add (Counter.jack:8)",
+				"=> Counter.add (Counter.jack:8): method void add(int n) {",
+				"=> Counter.add+3 (Counter.jack:10): let i = 0;",
+				"=> Counter.add+5 (Counter.jack:11): while (i < n) {",
+				"#0 Counter.add+5 (Counter.jack:11)",
+				"  local: i=0",
+				"  argument: n=3, this=15183",
+				"  this (15183): count=0, step=2",
+				"  that (0)",
+				"  static: total=0",
+				"#1 Main.main+6 (Main.jack:6)",
+				"  local: c=15183",
+				"  this (0)",
+				"  that (0)",
+				"  static: result=0",
+				"Breakpoint at Counter.add+21 (Counter.jack:15)",
+				"=> Counter.add+21 (Counter.jack:15): let total = total + count;",
+				"#0 Counter.add+21 (Counter.jack:15)",
+				"  local: i=3",
+				"  argument: n=3, this=15183",
+				"  this (15183): count=6, step=2",
+				"  that (0)",
+				"  static: total=0",
+				"#1 Main.main+6 (Main.jack:6)",
+				"  local: c=15183",
+				"  this (0)",
+				"  that (0)",
+				"  static: result=0",
+				"total = 0",
+			},
+		},
+		{
+			desc: "stepは呼び出した関数に入り、nextは入らない",
+			script: []string{
+				"b Main.jack:5",
+				"c",
+				"s",
+				"p s",
+				"finish",
+				"n",
+				"n",
+				"p c",
+				"n",
+				"expect result 6",
+				"n",
+			},
+			want: []string{
+				"Breakpoint at Main.main+1 (Main.jack:5)",
+				"=> Main.main+1 (Main.jack:5): let c = Counter.new(2);",
+				"=> Counter.new (Counter.jack:4): constructor Counter new(int s) {",
+				"s = 2",
+				"=> Main.main+3 (Main.jack:5): let c = Counter.new(2);",
+				"=> Main.main+4 (Main.jack:6): do c.add(3);",
+				"=> Main.main+8 (Main.jack:7): let result = Main.sum(3);",
+				"c = 15183",
+				"=> Main.main+11 (Main.jack:8): return;",
+				"Program finished",
+			},
+		},
+		{
+			desc: "再帰呼び出しのフレームを遡り、finishで呼び出し元に戻る",
+			script: []string{
+				"b Main.jack:12",
+				"c",
+				"bt",
+				"finish",
+				"p n",
+				"l",
+				"stepi 2",
+				"p n",
+				"c",
+			},
+			want: []string{
+				"Breakpoint at Main.sum+6 (Main.jack:12)",
+				"=> Main.sum+6 (Main.jack:12): return 0;",
+				"#0 Main.sum+6 (Main.jack:12)",
+				"  argument: n=0",
+				"  this (0)",
+				"  that (0)",
+				"  static: result=0",
+				"#1 Main.sum+15 (Main.jack:14)",
+				"  argument: n=1",
+				"  this (0)",
+				"  that (0)",
+				"  static: result=0",
+				"#2 Main.sum+15 (Main.jack:14)",
+				"  argument: n=2",
+				"  this (0)",
+				"  that (0)",
+				"  static: result=0",
+				"#3 Main.sum+15 (Main.jack:14)",
+				"  argument: n=3",
+				"  this (0)",
+				"  that (0)",
+				"  static: result=0",
+				"#4 Main.main+9 (Main.jack:7)",
+				"  local: c=15183",
+				"  this (0)",
+				"  that (0)",
+				"  static: result=0",
+				"=> Main.sum+16 (Main.jack:14): return n + Main.sum(n - 1);",
+				"n = 1",
+				"    12        return 0;",
+				"    13      }",
+				"=>  14      return n + Main.sum(n - 1);",
+				"    15    }",
+				"    16  }",
+				"=> Main.sum+16 (Main.jack:14): add",
+				"n = 2",
+				"Program finished",
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			var b bytes.Buffer
+			d := newTestDebugger(t, &b)
+			if err := d.RunScript(strings.Join(c.script, "\n")); err != nil {
+				t.Fatalf("failed RunScript: %+v", err)
+			}
+			got := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+			if diff := cmp.Diff(got, c.want); diff != "" {
+				t.Errorf("failed RunScript: diff (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDebuggerRunScriptError(t *testing.T) {
+	cases := []struct {
+		desc   string
+		script []string
+		want   string
+	}{
+		{
+			desc:   "期待した値と違う",
+			script: []string{"b Counter.jack:14", "c", "", "expect count 5"},
+			want:   "line 4: expect count: got 6, want 5",
+		},
+		{
+			desc:   "存在しない関数",
+			script: []string{"break Main.nothing"},
+			want:   "line 1: unknown function Main.nothing",
+		},
+		{
+			desc:   "コードのない行",
+			script: []string{"// コメント", "break Main.jack:16"},
+			want:   "line 2: no code at Main.jack:16",
+		},
+		{
+			desc:   "今の関数にない変数",
+			script: []string{"print n"},
+			want:   "line 1: unknown variable n in Main.main",
+		},
+		{
+			desc:   "終了した後は変数を読めない",
+			script: []string{"continue", "print c"},
+			want:   "line 2: no frame",
+		},
+		{
+			desc:   "不明なコマンド",
+			script: []string{"jump 3"},
+			want:   "line 1: unknown command jump",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			var b bytes.Buffer
+			d := newTestDebugger(t, &b)
+			err := d.RunScript(strings.Join(c.script, "\n"))
+			if err == nil {
+				t.Fatalf("failed RunScript: expected error")
+			}
+			if diff := cmp.Diff(err.Error(), c.want); diff != "" {
+				t.Errorf("failed RunScript: diff (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package vmdebugger
+
+import (
+	"../../11/parsing"
+	"../vm"
+	"fmt"
+	"github.com/pkg/errors"
+	"strconv"
+	"strings"
+)
+
+// 呼び出し中の関数の状態
+// 呼び出し元のフレームは、08のVMトランスレータのcallがスタックに保存した
+// リターンアドレス、LCL、ARG、THIS、THATから復元する
+type Frame struct {
+	Function   string
+	PC         int // 実行中のコマンド（呼び出し元のフレームではcallコマンド）
+	Line       int // Jackのソースの行（デバッグ情報がない場合は0）
+	LCL        int
+	ARG        int
+	THIS       int
+	THAT       int
+	subroutine *parsing.DebugSubroutine
+}
+
+// 今の関数から、最初に呼び出した関数までのフレーム
+func (d *Debugger) Backtrace() []*Frame {
+	ram := d.Machine.Computer.RAM
+	pc := d.Machine.PC()
+	lcl, arg := int(ram[vm.LCL]), int(ram[vm.ARG])
+	this, that := int(ram[vm.THIS]), int(ram[vm.THAT])
+
+	frames := []*Frame{}
+	for pc >= 0 && !d.finished {
+		f, line := d.lineAt(pc)
+		if f == nil {
+			break
+		}
+		frames = append(frames, &Frame{
+			Function:   f.name,
+			PC:         pc,
+			Line:       line,
+			LCL:        lcl,
+			ARG:        arg,
+			THIS:       this,
+			THAT:       that,
+			subroutine: d.subroutines[f.name],
+		})
+
+		// フレームが壊れている場合に無限に辿らないように、スタックを遡る向きにだけ進む
+		frameLCL := lcl
+		if frameLCL-5 < vm.StackAddress || int(ram[frameLCL-4]) >= frameLCL {
+			break
+		}
+		pc = int(ram[frameLCL-5]) - 1
+		if ram[frameLCL-5] < 0 {
+			pc = -1
+		}
+		lcl, arg = int(ram[frameLCL-4]), int(ram[frameLCL-3])
+		this, that = int(ram[frameLCL-2]), int(ram[frameLCL-1])
+	}
+	return frames
+}
+
+// 変数名とRAMのアドレス
+type variable struct {
+	name    string
+	address int
+}
+
+// localとargumentの変数
+// 個数はfunctionコマンドとフレームの位置から求め、名前はデバッグ情報から付ける
+func (d *Debugger) locals(frame *Frame) []*variable {
+	index := d.program.Functions[frame.Function]
+	count := d.program.Commands[index].Arg2
+	result := []*variable{}
+	for i := 0; i < count; i++ {
+		result = append(result, &variable{name: variableName(frame.subroutine, "local", i), address: frame.LCL + i})
+	}
+	return result
+}
+
+func (d *Debugger) arguments(frame *Frame) []*variable {
+	result := []*variable{}
+	for i := 0; i < frame.LCL-5-frame.ARG; i++ {
+		result = append(result, &variable{name: variableName(frame.subroutine, "argument", i), address: frame.ARG + i})
+	}
+	return result
+}
+
+// thisのフィールドは、methodとconstructorの場合だけ名前で表示する
+func (d *Debugger) fields(frame *Frame) []*variable {
+	info := d.classes[className(frame.Function)]
+	if frame.subroutine == nil || frame.subroutine.Kind == "function" || info == nil {
+		return nil
+	}
+	result := []*variable{}
+	for i, name := range info.Fields {
+		result = append(result, &variable{name: name, address: frame.THIS + i})
+	}
+	return result
+}
+
+func (d *Debugger) statics(frame *Frame) []*variable {
+	class := className(frame.Function)
+	info := d.classes[class]
+	base, ok := d.Machine.StaticAddress(class)
+	if info == nil || !ok {
+		return nil
+	}
+	result := []*variable{}
+	for i, name := range info.Statics {
+		result = append(result, &variable{name: name, address: base + i})
+	}
+	return result
+}
+
+func variableName(subroutine *parsing.DebugSubroutine, segment string, index int) string {
+	if subroutine != nil {
+		names := subroutine.Locals
+		if segment == "argument" {
+			names = subroutine.Arguments
+		}
+		if index < len(names) && names[index] != "" {
+			return names[index]
+		}
+	}
+	return fmt.Sprintf("%s[%d]", segment, index)
+}
+
+func (d *Debugger) formatVariables(variables []*variable) string {
+	values := []string{}
+	for _, v := range variables {
+		values = append(values, fmt.Sprintf("%s=%d", v.name, d.Machine.Computer.RAM[v.address]))
+	}
+	return strings.Join(values, ", ")
+}
+
+// フレームの関数の位置と、local、argument、this、that、staticの値を書く
+//
+//	#0 Counter.add+11 (Counter.jack:12)
+//	  local: i=0
+//	  argument: n=3, this=2048
+//	  this (2048): count=0, step=2
+//	  that (0)
+//	  static: total=0
+func (d *Debugger) formatFrame(n int, frame *Frame) []string {
+	result := []string{fmt.Sprintf("#%d %s", n, d.location(frame.PC))}
+	if locals := d.locals(frame); len(locals) > 0 {
+		result = append(result, "  local: "+d.formatVariables(locals))
+	}
+	if arguments := d.arguments(frame); len(arguments) > 0 {
+		result = append(result, "  argument: "+d.formatVariables(arguments))
+	}
+	this := fmt.Sprintf("  this (%d)", frame.THIS)
+	if fields := d.fields(frame); len(fields) > 0 {
+		this += ": " + d.formatVariables(fields)
+	}
+	result = append(result, this)
+	result = append(result, fmt.Sprintf("  that (%d)", frame.THAT))
+	if statics := d.statics(frame); len(statics) > 0 {
+		result = append(result, "  static: "+d.formatVariables(statics))
+	}
+	return result
+}
+
+// 今のフレームの変数のアドレス
+// Jackの変数名か、「local 0」のようなセグメントとインデックスで指定する
+// 同じ名前はlocal、argument、フィールド、staticの順に探す
+func (d *Debugger) address(target string) (int, error) {
+	frames := d.Backtrace()
+	if len(frames) == 0 {
+		return 0, errors.New("no frame")
+	}
+	frame := frames[0]
+
+	if fields := strings.Fields(target); len(fields) == 2 {
+		index, err := strconv.Atoi(fields[1])
+		if err != nil || index < 0 {
+			return 0, errors.New(fmt.Sprintf("invalid index %s", target))
+		}
+		switch fields[0] {
+		case "local":
+			return frame.LCL + index, nil
+		case "argument":
+			return frame.ARG + index, nil
+		case "this":
+			return frame.THIS + index, nil
+		case "that":
+			return frame.THAT + index, nil
+		case "temp":
+			return vm.TempAddress + index, nil
+		case "static":
+			base, ok := d.Machine.StaticAddress(className(frame.Function))
+			if !ok {
+				return 0, errors.New(fmt.Sprintf("no static segment for %s", frame.Function))
+			}
+			return base + index, nil
+		}
+		return 0, errors.New(fmt.Sprintf("unknown segment %s", fields[0]))
+	}
+
+	for _, variables := range [][]*variable{d.locals(frame), d.arguments(frame), d.fields(frame), d.statics(frame)} {
+		for _, v := range variables {
+			if v.name == target {
+				return v.address, nil
+			}
+		}
+	}
+	return 0, errors.New(fmt.Sprintf("unknown variable %s in %s", target, frame.Function))
+}
+
+func (d *Debugger) Value(target string) (int16, error) {
+	address, err := d.address(target)
+	if err != nil {
+		return 0, err
+	}
+	if address < 0 || address >= len(d.Machine.Computer.RAM) {
+		return 0, errors.New(fmt.Sprintf("address out of range: %d", address))
+	}
+	return d.Machine.Computer.RAM[address], nil
+}